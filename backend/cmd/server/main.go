@@ -1,8 +1,11 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 
 	"github.com/tobi/contracts/backend/internal/config"
 	"github.com/tobi/contracts/backend/internal/server"
@@ -10,6 +13,16 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				slog.Error(os.Args[1]+" failed", "error", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		slog.Error("loading config", "error", err)
@@ -26,7 +39,7 @@ func main() {
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
-	db, err := store.NewBadgerStore(cfg.DBPath, logger)
+	db, err := openStore(cfg, logger)
 	if err != nil {
 		logger.Error("opening database", "error", err)
 		os.Exit(1)
@@ -39,3 +52,100 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// openStore opens the storage backend selected by DB_DRIVER.
+func openStore(cfg config.Config, logger *slog.Logger) (store.Store, error) {
+	if cfg.DBDriver != config.DBDriverSQLite {
+		return store.NewBadgerStore(cfg.DBPath, cfg.Key(), logger)
+	}
+	if err := os.MkdirAll(cfg.DBPath, 0o750); err != nil {
+		return nil, fmt.Errorf("creating data directory: %w", err)
+	}
+	return store.NewSQLiteStore(filepath.Join(cfg.DBPath, "contracts.db"), logger)
+}
+
+// commands are the maintenance subcommands. They work on a Badger database
+// the server is not running on and only read the storage settings.
+var commands = map[string]func(args []string) error{
+	"restore":    restore,
+	"encrypt":    encrypt,
+	"rotate-key": rotateKey,
+}
+
+// parseCommand loads the storage settings and parses the flags and the one
+// argument of a subcommand. -db overrides DB_PATH.
+func parseCommand(name, argName string, args []string) (config.Storage, string, error) {
+	storage, err := config.LoadStorage()
+	if err != nil {
+		return storage, "", err
+	}
+	if storage.DBDriver != config.DBDriverBadger {
+		return storage, "", fmt.Errorf("%s needs DB_DRIVER=badger", name)
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&storage.DBPath, "db", storage.DBPath, "database directory (default $DB_PATH)")
+	if err := fs.Parse(args); err != nil {
+		return storage, "", err
+	}
+	if fs.NArg() != 1 {
+		return storage, "", fmt.Errorf("usage: %s %s [-db path] <%s>", filepath.Base(os.Args[0]), name, argName)
+	}
+	return storage, fs.Arg(0), nil
+}
+
+// restore implements "server restore [-db path] <backup-file>", which loads
+// a backup file into an empty Badger database, encrypted when a key is
+// configured. An encrypted backup is decrypted with the same key.
+func restore(args []string) error {
+	storage, file, err := parseCommand("restore", "backup-file", args)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := store.RestoreBadger(storage.DBPath, storage.Key(), f, slog.Default()); err != nil {
+		return err
+	}
+	slog.Info("backup restored", "file", file, "path", storage.DBPath)
+	return nil
+}
+
+// encrypt implements "server encrypt [-db path] <target-dir>", which copies
+// the unencrypted database into the empty target directory, encrypted with
+// the configured key.
+func encrypt(args []string) error {
+	storage, target, err := parseCommand("encrypt", "target-dir", args)
+	if err != nil {
+		return err
+	}
+	if storage.Key() == nil {
+		return fmt.Errorf("set ENCRYPTION_KEY or ENCRYPTION_KEY_FILE to the key to encrypt with")
+	}
+	if err := store.EncryptBadger(storage.DBPath, target, storage.Key(), slog.Default()); err != nil {
+		return err
+	}
+	slog.Info("database encrypted", "from", storage.DBPath, "to", target)
+	return nil
+}
+
+// rotateKey implements "server rotate-key [-db path] <new-key-file>", which
+// switches the database from the configured key to the one in the file.
+func rotateKey(args []string) error {
+	storage, file, err := parseCommand("rotate-key", "new-key-file", args)
+	if err != nil {
+		return err
+	}
+	newKey, err := config.ReadKeyFile(file)
+	if err != nil {
+		return fmt.Errorf("reading new key: %w", err)
+	}
+	if err := store.RotateBadgerKey(storage.DBPath, storage.Key(), newKey, slog.Default()); err != nil {
+		return err
+	}
+	slog.Info("encryption key rotated; configure the new key before starting the server", "path", storage.DBPath)
+	return nil
+}
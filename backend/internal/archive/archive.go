@@ -0,0 +1,398 @@
+// Package archive exports everything a user owns into a single versioned
+// document and restores such documents into an account. It only talks to
+// the store.Store interface, so it works with any storage backend.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+// Version is the archive format written by Export. Restore accepts archives
+// up to this version. Version 2 renamed the contract duration amounts, which
+// version 1 stored in month-named fields.
+const Version = 2
+
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// Mode selects how Restore treats data already in the account.
+type Mode string
+
+const (
+	// ModeMerge adds the archive's records next to the existing ones and
+	// leaves the account's settings untouched.
+	ModeMerge Mode = "merge"
+	// ModeReplace deletes all existing records once the archive's records
+	// are written and applies the archive's settings.
+	ModeReplace Mode = "replace"
+)
+
+// Valid reports whether m is a known restore mode.
+func (m Mode) Valid() bool {
+	return m == ModeMerge || m == ModeReplace
+}
+
+type Archive struct {
+	Version      int                         `json:"version"`
+	ExportedAt   time.Time                   `json:"exportedAt"`
+	Settings     model.UserSettings          `json:"settings"`
+	Categories   map[string][]model.Category `json:"categories"`
+	Contracts    []model.Contract            `json:"contracts"`
+	PriceEntries []model.PriceEntry          `json:"priceEntries"`
+	Purchases    []model.Purchase            `json:"purchases"`
+	Vehicles     []model.Vehicle             `json:"vehicles"`
+	CostEntries  []model.CostEntry           `json:"costEntries"`
+}
+
+// UnmarshalJSON reads archives of every supported version.
+func (a *Archive) UnmarshalJSON(data []byte) error {
+	type plain Archive
+	if err := json.Unmarshal(data, (*plain)(a)); err != nil {
+		return err
+	}
+	if a.Version != 1 {
+		return nil
+	}
+	// ContractInput still understands the month-named fields.
+	var v1 struct {
+		Contracts []model.ContractInput `json:"contracts"`
+	}
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return err
+	}
+	for i, c := range v1.Contracts {
+		a.Contracts[i].MinimumDuration = c.MinimumDuration
+		a.Contracts[i].ExtensionDuration = c.ExtensionDuration
+		a.Contracts[i].NoticePeriod = c.NoticePeriod
+	}
+	return nil
+}
+
+// Result counts the records written by Restore.
+type Result struct {
+	Categories   int `json:"categories"`
+	Contracts    int `json:"contracts"`
+	PriceEntries int `json:"priceEntries"`
+	Purchases    int `json:"purchases"`
+	Vehicles     int `json:"vehicles"`
+	CostEntries  int `json:"costEntries"`
+}
+
+// Export collects all records owned by workspaceID together with userID's
+// settings.
+func Export(ctx context.Context, s store.Store, userID, workspaceID string, at time.Time) (Archive, error) {
+	a := Archive{
+		Version:    Version,
+		ExportedAt: at.UTC(),
+		Categories: make(map[string][]model.Category, len(model.Modules)),
+	}
+
+	settings, err := s.GetSettings(ctx, userID)
+	if err != nil {
+		return Archive{}, fmt.Errorf("settings: %w", err)
+	}
+	a.Settings = settings
+
+	for _, module := range model.Modules {
+		cats, err := s.ListCategories(ctx, workspaceID, module)
+		if err != nil {
+			return Archive{}, fmt.Errorf("%s categories: %w", module, err)
+		}
+		a.Categories[module] = nonNil(cats)
+	}
+
+	if a.Contracts, err = s.ListContracts(ctx, workspaceID); err != nil {
+		return Archive{}, fmt.Errorf("contracts: %w", err)
+	}
+	if a.PriceEntries, err = s.ListPriceEntries(ctx, workspaceID); err != nil {
+		return Archive{}, fmt.Errorf("price entries: %w", err)
+	}
+	if a.Purchases, err = s.ListPurchases(ctx, workspaceID); err != nil {
+		return Archive{}, fmt.Errorf("purchases: %w", err)
+	}
+	if a.Vehicles, err = s.ListVehicles(ctx, workspaceID); err != nil {
+		return Archive{}, fmt.Errorf("vehicles: %w", err)
+	}
+	a.CostEntries = []model.CostEntry{}
+	for _, v := range a.Vehicles {
+		costs, err := s.ListCostEntries(ctx, workspaceID, v.ID)
+		if err != nil {
+			return Archive{}, fmt.Errorf("cost entries: %w", err)
+		}
+		a.CostEntries = append(a.CostEntries, costs...)
+	}
+
+	a.Contracts = nonNil(a.Contracts)
+	a.PriceEntries = nonNil(a.PriceEntries)
+	a.Purchases = nonNil(a.Purchases)
+	a.Vehicles = nonNil(a.Vehicles)
+	return a, nil
+}
+
+// Validate checks the archive's version and that every reference points at a
+// record contained in the archive itself.
+func (a Archive) Validate() error {
+	if a.Version < 1 || a.Version > Version {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidArchive, a.Version)
+	}
+
+	catModule := make(map[uuid.UUID]string)
+	for module, cats := range a.Categories {
+		if !slices.Contains(model.Modules, module) {
+			return fmt.Errorf("%w: unknown module %q", ErrInvalidArchive, module)
+		}
+		for _, c := range cats {
+			if _, dup := catModule[c.ID]; dup {
+				return fmt.Errorf("%w: duplicate category %s", ErrInvalidArchive, c.ID)
+			}
+			catModule[c.ID] = module
+		}
+	}
+
+	contracts := make(map[uuid.UUID]bool, len(a.Contracts))
+	for _, c := range a.Contracts {
+		if catModule[c.CategoryID] != "contracts" {
+			return fmt.Errorf("%w: contract %s references unknown category %s", ErrInvalidArchive, c.ID, c.CategoryID)
+		}
+		contracts[c.ID] = true
+	}
+	for _, p := range a.PriceEntries {
+		if !contracts[p.ContractID] {
+			return fmt.Errorf("%w: price entry %s references unknown contract %s", ErrInvalidArchive, p.ID, p.ContractID)
+		}
+	}
+	for _, p := range a.Purchases {
+		if catModule[p.CategoryID] != "purchases" {
+			return fmt.Errorf("%w: purchase %s references unknown category %s", ErrInvalidArchive, p.ID, p.CategoryID)
+		}
+	}
+
+	vehicles := make(map[uuid.UUID]bool, len(a.Vehicles))
+	for _, v := range a.Vehicles {
+		vehicles[v.ID] = true
+	}
+	for _, c := range a.CostEntries {
+		if !vehicles[c.VehicleID] {
+			return fmt.Errorf("%w: cost entry %s references unknown vehicle %s", ErrInvalidArchive, c.ID, c.VehicleID)
+		}
+	}
+	return nil
+}
+
+// Restore writes the archive's records into workspaceID and, when replacing,
+// its settings into userID's account. Every record gets a new ID and
+// references are rewritten accordingly, so restoring the same archive twice
+// in merge mode never collides. The archive is validated before anything is
+// changed. If writing a record fails, the records written so far are removed
+// again and existing data is left as it was; in replace mode the existing
+// records are only deleted once all writes have succeeded.
+func Restore(ctx context.Context, s store.Store, userID, workspaceID string, a Archive, mode Mode) (Result, error) {
+	var res Result
+	if !mode.Valid() {
+		return res, fmt.Errorf("unknown restore mode %q", mode)
+	}
+	if err := a.Validate(); err != nil {
+		return res, err
+	}
+
+	var old records
+	if mode == ModeReplace {
+		var err error
+		if old, err = listRecords(ctx, s, workspaceID); err != nil {
+			return res, err
+		}
+	}
+
+	written := records{categories: make(map[string][]model.Category, len(model.Modules))}
+	res, err := write(ctx, s, workspaceID, a, &written)
+	if err != nil {
+		if rerr := purgeRecords(ctx, s, workspaceID, written); rerr != nil {
+			return Result{}, errors.Join(err, fmt.Errorf("rolling back: %w", rerr))
+		}
+		return Result{}, err
+	}
+
+	if mode == ModeReplace {
+		if err := deleteRecords(ctx, s, workspaceID, old); err != nil {
+			return res, err
+		}
+		settings, err := s.GetSettings(ctx, userID)
+		if err != nil {
+			return res, fmt.Errorf("settings: %w", err)
+		}
+		settings.RenewalDays = a.Settings.RenewalDays
+		settings.ReminderFrequency = a.Settings.ReminderFrequency
+		if err := s.UpdateSettings(ctx, userID, settings); err != nil {
+			return res, fmt.Errorf("settings: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// write creates the archive's records with new IDs and adds every category
+// and vehicle it creates to written, so a failed restore can be undone.
+func write(ctx context.Context, s store.Store, workspaceID string, a Archive, written *records) (Result, error) {
+	var res Result
+	ids := make(map[uuid.UUID]uuid.UUID)
+	remap := func(old uuid.UUID) uuid.UUID {
+		id := uuid.New()
+		ids[old] = id
+		return id
+	}
+
+	for _, module := range model.Modules {
+		for _, c := range a.Categories[module] {
+			c.ID = remap(c.ID)
+			if err := s.CreateCategory(ctx, workspaceID, module, c); err != nil {
+				return res, fmt.Errorf("category %q: %w", c.Name, err)
+			}
+			written.categories[module] = append(written.categories[module], c)
+			res.Categories++
+		}
+	}
+
+	for _, c := range a.Contracts {
+		c.ID = remap(c.ID)
+		c.CategoryID = ids[c.CategoryID]
+		if err := s.CreateContract(ctx, workspaceID, c); err != nil {
+			return res, fmt.Errorf("contract %q: %w", c.Name, err)
+		}
+		res.Contracts++
+	}
+	for _, p := range a.PriceEntries {
+		p.ID = remap(p.ID)
+		p.ContractID = ids[p.ContractID]
+		if err := s.CreatePriceEntry(ctx, workspaceID, p); err != nil {
+			return res, fmt.Errorf("price entry: %w", err)
+		}
+		res.PriceEntries++
+	}
+
+	for _, p := range a.Purchases {
+		p.ID = remap(p.ID)
+		p.CategoryID = ids[p.CategoryID]
+		if err := s.CreatePurchase(ctx, workspaceID, p); err != nil {
+			return res, fmt.Errorf("purchase %q: %w", p.ItemName, err)
+		}
+		res.Purchases++
+	}
+
+	for _, v := range a.Vehicles {
+		v.ID = remap(v.ID)
+		if err := s.CreateVehicle(ctx, workspaceID, v); err != nil {
+			return res, fmt.Errorf("vehicle %q: %w", v.Name, err)
+		}
+		written.vehicles = append(written.vehicles, v)
+		res.Vehicles++
+	}
+	for _, c := range a.CostEntries {
+		c.ID = remap(c.ID)
+		c.VehicleID = ids[c.VehicleID]
+		if err := s.CreateCostEntry(ctx, workspaceID, c); err != nil {
+			return res, fmt.Errorf("cost entry: %w", err)
+		}
+		res.CostEntries++
+	}
+	return res, nil
+}
+
+// records are the top-level records of a workspace. Contracts and purchases
+// are normally reached through their categories; they are listed for any
+// left without one.
+type records struct {
+	categories map[string][]model.Category
+	contracts  []model.Contract
+	purchases  []model.Purchase
+	vehicles   []model.Vehicle
+}
+
+func listRecords(ctx context.Context, s store.Store, workspaceID string) (records, error) {
+	r := records{categories: make(map[string][]model.Category, len(model.Modules))}
+	var err error
+	for _, module := range model.Modules {
+		if r.categories[module], err = s.ListCategories(ctx, workspaceID, module); err != nil {
+			return r, fmt.Errorf("%s categories: %w", module, err)
+		}
+	}
+	if r.contracts, err = s.ListContracts(ctx, workspaceID); err != nil {
+		return r, fmt.Errorf("contracts: %w", err)
+	}
+	if r.purchases, err = s.ListPurchases(ctx, workspaceID); err != nil {
+		return r, fmt.Errorf("purchases: %w", err)
+	}
+	if r.vehicles, err = s.ListVehicles(ctx, workspaceID); err != nil {
+		return r, fmt.Errorf("vehicles: %w", err)
+	}
+	return r, nil
+}
+
+// deleteRecords deletes r from workspaceID. Deleting categories and vehicles
+// cascades to their contracts, price entries, purchases and cost entries, so
+// records already gone with them are skipped.
+func deleteRecords(ctx context.Context, s store.Store, workspaceID string, r records) error {
+	skipGone := func(err error) error {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	for _, module := range model.Modules {
+		for _, c := range r.categories[module] {
+			if err := skipGone(s.DeleteCategory(ctx, workspaceID, module, c.ID)); err != nil {
+				return fmt.Errorf("category %q: %w", c.Name, err)
+			}
+		}
+	}
+	for _, c := range r.contracts {
+		if err := skipGone(s.DeleteContract(ctx, workspaceID, c.ID)); err != nil {
+			return fmt.Errorf("contract %q: %w", c.Name, err)
+		}
+	}
+	for _, p := range r.purchases {
+		if err := skipGone(s.DeletePurchase(ctx, workspaceID, p.ID)); err != nil {
+			return fmt.Errorf("purchase %q: %w", p.ItemName, err)
+		}
+	}
+	for _, v := range r.vehicles {
+		if err := skipGone(s.DeleteVehicle(ctx, workspaceID, v.ID)); err != nil {
+			return fmt.Errorf("vehicle %q: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// purgeRecords deletes the categories and vehicles in r together with
+// everything in them, and purges them from the trash.
+func purgeRecords(ctx context.Context, s store.Store, workspaceID string, r records) error {
+	if err := deleteRecords(ctx, s, workspaceID, r); err != nil {
+		return err
+	}
+	for _, module := range model.Modules {
+		for _, c := range r.categories[module] {
+			if err := s.PurgeFromTrash(ctx, workspaceID, model.EntityCategory, c.ID); err != nil {
+				return fmt.Errorf("category %q: %w", c.Name, err)
+			}
+		}
+	}
+	for _, v := range r.vehicles {
+		if err := s.PurgeFromTrash(ctx, workspaceID, model.EntityVehicle, v.ID); err != nil {
+			return fmt.Errorf("vehicle %q: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
@@ -0,0 +1,275 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+func newTestStore(t *testing.T) *store.BadgerStore {
+	t.Helper()
+	s, err := store.NewBadgerStore(t.TempDir(), nil, slog.Default())
+	if err != nil {
+		t.Fatalf("NewBadgerStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// seed creates one record of every kind for userID.
+func seed(t *testing.T, s store.Store, userID string) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	conCat := model.Category{ID: uuid.New(), Name: "Insurance", CreatedAt: now, UpdatedAt: now}
+	purCat := model.Category{ID: uuid.New(), Name: "Electronics", CreatedAt: now, UpdatedAt: now}
+	con := model.Contract{ID: uuid.New(), CategoryID: conCat.ID, Name: "Liability", StartDate: "2024-01-01", CreatedAt: now, UpdatedAt: now}
+	price := model.PriceEntry{ID: uuid.New(), ContractID: con.ID, Price: 9.99, EffectiveDate: "2024-01-01", CreatedAt: now}
+	pur := model.Purchase{ID: uuid.New(), CategoryID: purCat.ID, ItemName: "Laptop", CreatedAt: now, UpdatedAt: now}
+	veh := model.Vehicle{ID: uuid.New(), Name: "Car", CreatedAt: now, UpdatedAt: now}
+	cost := model.CostEntry{ID: uuid.New(), VehicleID: veh.ID, Type: model.CostTypeFuel, Date: "2024-02-01", CreatedAt: now, UpdatedAt: now}
+
+	steps := []error{
+		s.CreateCategory(ctx, userID, "contracts", conCat),
+		s.CreateCategory(ctx, userID, "purchases", purCat),
+		s.CreateContract(ctx, userID, con),
+		s.CreatePriceEntry(ctx, userID, price),
+		s.CreatePurchase(ctx, userID, pur),
+		s.CreateVehicle(ctx, userID, veh),
+		s.CreateCostEntry(ctx, userID, cost),
+		s.UpdateSettings(ctx, userID, model.UserSettings{RenewalDays: 30, ReminderFrequency: "weekly"}),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	s := newTestStore(t)
+	seed(t, s, "alice")
+	seed(t, s, "bob")
+
+	a, err := Export(context.Background(), s, "alice", "alice", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if a.Version != Version {
+		t.Errorf("version = %d, want %d", a.Version, Version)
+	}
+	if len(a.Categories["contracts"]) != 1 || len(a.Categories["purchases"]) != 1 {
+		t.Errorf("categories = %v", a.Categories)
+	}
+	if len(a.Contracts) != 1 || len(a.PriceEntries) != 1 || len(a.Purchases) != 1 || len(a.Vehicles) != 1 || len(a.CostEntries) != 1 {
+		t.Errorf("unexpected record counts: %+v", a)
+	}
+	if a.Settings.RenewalDays != 30 {
+		t.Errorf("renewalDays = %d, want 30", a.Settings.RenewalDays)
+	}
+	if err := a.Validate(); err != nil {
+		t.Errorf("exported archive does not validate: %v", err)
+	}
+}
+
+func TestRestoreMergeRemapsIDs(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	seed(t, s, "alice")
+
+	a, err := Export(ctx, s, "alice", "alice", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	// Restoring into the same account must not collide with the originals.
+	res, err := Restore(ctx, s, "alice", "alice", a, ModeMerge)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	want := Result{Categories: 2, Contracts: 1, PriceEntries: 1, Purchases: 1, Vehicles: 1, CostEntries: 1}
+	if res != want {
+		t.Errorf("result = %+v, want %+v", res, want)
+	}
+
+	contracts, _ := s.ListContracts(ctx, "alice")
+	if len(contracts) != 2 {
+		t.Fatalf("expected 2 contracts, got %d", len(contracts))
+	}
+	for _, c := range contracts {
+		if c.ID == a.Contracts[0].ID {
+			continue
+		}
+		if c.CategoryID == a.Contracts[0].CategoryID {
+			t.Error("restored contract still points at the original category")
+		}
+		if _, err := s.GetCategory(ctx, "alice", "contracts", c.CategoryID); err != nil {
+			t.Errorf("restored contract category: %v", err)
+		}
+		prices, _ := s.ListPriceEntriesByContract(ctx, "alice", c.ID)
+		if len(prices) != 1 {
+			t.Errorf("expected 1 price entry for restored contract, got %d", len(prices))
+		}
+	}
+
+	vehicles, _ := s.ListVehicles(ctx, "alice")
+	for _, v := range vehicles {
+		costs, _ := s.ListCostEntries(ctx, "alice", v.ID)
+		if len(costs) != 1 {
+			t.Errorf("vehicle %s: expected 1 cost entry, got %d", v.ID, len(costs))
+		}
+	}
+}
+
+func TestRestoreReplace(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	seed(t, s, "alice")
+	seed(t, s, "bob")
+
+	a, err := Export(ctx, s, "alice", "alice", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	a.Settings.RenewalDays = 14
+
+	if _, err := Restore(ctx, s, "bob", "bob", a, ModeReplace); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	b, err := Export(ctx, s, "bob", "bob", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(b.Contracts) != 1 || len(b.Purchases) != 1 || len(b.Vehicles) != 1 || len(b.CostEntries) != 1 || len(b.PriceEntries) != 1 {
+		t.Errorf("replace left unexpected records: %+v", b)
+	}
+	if len(b.Categories["contracts"]) != 1 || len(b.Categories["purchases"]) != 1 {
+		t.Errorf("categories = %v", b.Categories)
+	}
+	if b.Settings.RenewalDays != 14 {
+		t.Errorf("renewalDays = %d, want 14", b.Settings.RenewalDays)
+	}
+
+	// The source account is untouched.
+	if contracts, _ := s.ListContracts(ctx, "alice"); len(contracts) != 1 {
+		t.Errorf("alice contracts = %d, want 1", len(contracts))
+	}
+}
+
+func TestRestoreRejectsInvalidArchive(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	seed(t, s, "alice")
+
+	good, err := Export(ctx, s, "alice", "alice", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(a *Archive)
+	}{
+		{"future version", func(a *Archive) { a.Version = Version + 1 }},
+		{"missing version", func(a *Archive) { a.Version = 0 }},
+		{"unknown module", func(a *Archive) { a.Categories["garden"] = []model.Category{{ID: uuid.New()}} }},
+		{"dangling contract", func(a *Archive) { a.Contracts[0].CategoryID = uuid.New() }},
+		{"contract in purchase category", func(a *Archive) { a.Contracts[0].CategoryID = a.Categories["purchases"][0].ID }},
+		{"dangling price entry", func(a *Archive) { a.PriceEntries[0].ContractID = uuid.New() }},
+		{"dangling cost entry", func(a *Archive) { a.CostEntries[0].VehicleID = uuid.New() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := good
+			a.Categories = map[string][]model.Category{
+				"contracts": append([]model.Category(nil), good.Categories["contracts"]...),
+				"purchases": append([]model.Category(nil), good.Categories["purchases"]...),
+			}
+			a.Contracts = append([]model.Contract(nil), good.Contracts...)
+			a.PriceEntries = append([]model.PriceEntry(nil), good.PriceEntries...)
+			a.CostEntries = append([]model.CostEntry(nil), good.CostEntries...)
+			tt.modify(&a)
+
+			_, err := Restore(ctx, s, "alice", "alice", a, ModeReplace)
+			if !errors.Is(err, ErrInvalidArchive) {
+				t.Fatalf("expected ErrInvalidArchive, got %v", err)
+			}
+			// Nothing was deleted.
+			if contracts, _ := s.ListContracts(ctx, "alice"); len(contracts) != 1 {
+				t.Errorf("contracts = %d, want 1", len(contracts))
+			}
+		})
+	}
+}
+
+// failingStore fails every purchase write.
+type failingStore struct {
+	*store.BadgerStore
+}
+
+func (f failingStore) CreatePurchase(_ context.Context, _ string, _ model.Purchase) error {
+	return errors.New("disk full")
+}
+
+func TestRestoreReplaceFailureKeepsData(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	seed(t, s, "alice")
+	seed(t, s, "bob")
+
+	a, err := Export(ctx, s, "alice", "alice", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	a.Settings.RenewalDays = 14
+	before, err := Export(ctx, s, "bob", "bob", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	if _, err := Restore(ctx, failingStore{s}, "bob", "bob", a, ModeReplace); err == nil {
+		t.Fatal("expected error")
+	}
+
+	after, err := Export(ctx, s, "bob", "bob", time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(after.Contracts) != 1 || after.Contracts[0].ID != before.Contracts[0].ID {
+		t.Errorf("contracts = %+v, want the original", after.Contracts)
+	}
+	if len(after.Categories["contracts"]) != 1 || len(after.Categories["purchases"]) != 1 {
+		t.Errorf("categories = %v, want the originals", after.Categories)
+	}
+	if len(after.PriceEntries) != 1 || len(after.Purchases) != 1 || len(after.Vehicles) != 1 || len(after.CostEntries) != 1 {
+		t.Errorf("unexpected records after failed restore: %+v", after)
+	}
+	if after.Settings.RenewalDays != 30 {
+		t.Errorf("renewalDays = %d, want 30", after.Settings.RenewalDays)
+	}
+	if trash, _ := s.ListTrash(ctx, "bob"); len(trash) != 0 {
+		t.Errorf("trash = %+v, want empty", trash)
+	}
+}
+
+func TestUnmarshalVersion1(t *testing.T) {
+	data := `{"version": 1, "contracts": [{"name": "Gym", "minimumDurationMonths": 12, "extensionDurationMonths": 1, "noticePeriodMonths": 4, "noticePeriodUnit": "weeks"}]}`
+
+	var a Archive
+	if err := json.Unmarshal([]byte(data), &a); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	c := a.Contracts[0]
+	if c.Name != "Gym" || c.MinimumDuration != 12 || c.ExtensionDuration != 1 || c.NoticePeriod != 4 || c.NoticePeriodUnit != model.UnitWeeks {
+		t.Errorf("contract = %+v", c)
+	}
+}
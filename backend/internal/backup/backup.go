@@ -0,0 +1,209 @@
+// Package backup writes online snapshots of the database to a directory and
+// rotates them, on a schedule and on demand.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tobi/contracts/backend/internal/middleware"
+)
+
+const (
+	filePrefix = "contracts-"
+	fileSuffix = ".bak"
+	timeLayout = "20060102T150405.000Z"
+	// parseLayout also reads the names of older backups, which were written
+	// without milliseconds.
+	parseLayout = "20060102T150405.999Z"
+)
+
+// Source is a database that can stream a consistent snapshot of itself
+// while it stays in use, such as store.BadgerStore.
+type Source interface {
+	Backup(w io.Writer) error
+}
+
+// Info describes a backup file.
+type Info struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Manager struct {
+	src    Source
+	dir    string
+	keep   int
+	logger *slog.Logger
+	now    func() time.Time
+	// mu keeps scheduled and on-demand backups from running at once.
+	mu sync.Mutex
+}
+
+// New returns a Manager that writes backups of src to dir and keeps the
+// newest keep of them.
+func New(src Source, dir string, keep int, logger *slog.Logger) *Manager {
+	return &Manager{src: src, dir: dir, keep: keep, logger: logger.With("component", "backup"), now: time.Now}
+}
+
+// Start takes a backup every interval until ctx is done. The first one is
+// taken after one interval, so restarts do not pile up backups.
+func (m *Manager) Start(ctx context.Context, interval time.Duration) {
+	if latest, ok, err := m.latest(); err != nil {
+		m.logger.Error("reading backup directory", "error", err)
+	} else if ok {
+		recordMetrics(latest)
+	}
+
+	go func() {
+		m.logger.Info("backup scheduler started", "dir", m.dir, "interval", interval, "keep", m.keep)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				m.logger.Info("backup scheduler stopped")
+				return
+			case <-ticker.C:
+				if _, err := m.Run(); err != nil {
+					m.logger.Error("scheduled backup failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Run writes a new backup and removes the ones beyond the newest keep. The
+// file only appears under its final name once it is complete, and Run fails
+// rather than replace an existing backup of the same name.
+func (m *Manager) Run() (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return Info{}, fmt.Errorf("creating backup directory: %w", err)
+	}
+
+	createdAt := m.now().UTC().Truncate(time.Millisecond)
+	name := filePrefix + createdAt.Format(timeLayout) + fileSuffix
+	size, err := m.write(name)
+	if err != nil {
+		return Info{}, err
+	}
+
+	info := Info{Name: name, Size: size, CreatedAt: createdAt}
+	recordMetrics(info)
+	m.logger.Info("backup written", "name", name, "size", size)
+
+	if err := m.rotate(); err != nil {
+		m.logger.Error("removing old backups", "error", err)
+	}
+	return info, nil
+}
+
+func (m *Manager) write(name string) (int64, error) {
+	f, err := os.CreateTemp(m.dir, "."+name+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("creating backup file: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if err := m.src.Backup(f); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("writing backup: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("writing backup: %w", err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, fmt.Errorf("writing backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, fmt.Errorf("writing backup: %w", err)
+	}
+	// Unlike a rename, a link fails if the name is taken.
+	if err := os.Link(tmp, filepath.Join(m.dir, name)); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return 0, fmt.Errorf("backup %s already exists", name)
+		}
+		return 0, fmt.Errorf("writing backup: %w", err)
+	}
+	return stat.Size(), nil
+}
+
+// list returns the backups in the directory, oldest first.
+func (m *Manager) list() ([]Info, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var backups []Info
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		createdAt, err := time.Parse(parseLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Info{Name: name, Size: fi.Size(), CreatedAt: createdAt})
+	}
+	slices.SortFunc(backups, func(a, b Info) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return backups, nil
+}
+
+func (m *Manager) latest() (Info, bool, error) {
+	backups, err := m.list()
+	if err != nil || len(backups) == 0 {
+		return Info{}, false, err
+	}
+	return backups[len(backups)-1], true, nil
+}
+
+func (m *Manager) rotate() error {
+	backups, err := m.list()
+	if err != nil {
+		return err
+	}
+	for len(backups) > m.keep {
+		if err := os.Remove(filepath.Join(m.dir, backups[0].Name)); err != nil {
+			return err
+		}
+		m.logger.Info("backup removed", "name", backups[0].Name)
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func recordMetrics(info Info) {
+	middleware.BackupLastTimestamp.Set(float64(info.CreatedAt.Unix()))
+	middleware.BackupLastSize.Set(float64(info.Size))
+}
@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tobi/contracts/backend/internal/middleware"
+)
+
+type fakeSource struct {
+	data string
+	err  error
+}
+
+func (f fakeSource) Backup(w io.Writer) error {
+	if f.err != nil {
+		return f.err
+	}
+	_, err := io.WriteString(w, f.data)
+	return err
+}
+
+func newTestManager(t *testing.T, src Source, keep int) (*Manager, *time.Time) {
+	t.Helper()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := New(src, t.TempDir(), keep, slog.Default())
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestRun_WritesFileAndMetrics(t *testing.T) {
+	m, _ := newTestManager(t, fakeSource{data: "snapshot"}, 3)
+
+	info, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if info.Name != "contracts-20250301T120000.000Z.bak" || info.Size != 8 {
+		t.Errorf("info = %+v", info)
+	}
+	data, err := os.ReadFile(filepath.Join(m.dir, info.Name))
+	if err != nil || string(data) != "snapshot" {
+		t.Errorf("backup file = %q, %v", data, err)
+	}
+	if got := testutil.ToFloat64(middleware.BackupLastTimestamp); got != float64(info.CreatedAt.Unix()) {
+		t.Errorf("timestamp metric = %v", got)
+	}
+	if got := testutil.ToFloat64(middleware.BackupLastSize); got != 8 {
+		t.Errorf("size metric = %v, want 8", got)
+	}
+}
+
+func TestRun_KeepsNewest(t *testing.T) {
+	m, now := newTestManager(t, fakeSource{data: "x"}, 2)
+
+	for range 4 {
+		if _, err := m.Run(); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		*now = now.Add(24 * time.Hour)
+	}
+	// Files that are not backups are left alone.
+	if err := os.WriteFile(filepath.Join(m.dir, "notes.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	names := dirNames(t, m.dir)
+	want := []string{"contracts-20250304T120000.000Z.bak", "contracts-20250305T120000.000Z.bak", "notes.txt"}
+	if len(names) != len(want) {
+		t.Fatalf("files = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("files = %v, want %v", names, want)
+			break
+		}
+	}
+}
+
+func TestRun_FailureLeavesNoFile(t *testing.T) {
+	m, _ := newTestManager(t, fakeSource{err: errors.New("disk gone")}, 2)
+
+	if _, err := m.Run(); err == nil {
+		t.Fatal("expected error")
+	}
+	if names := dirNames(t, m.dir); len(names) != 0 {
+		t.Errorf("files = %v, want none", names)
+	}
+}
+
+func TestRun_SameSecond(t *testing.T) {
+	m, now := newTestManager(t, fakeSource{data: "first"}, 5)
+
+	first, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	*now = now.Add(300 * time.Millisecond)
+	second, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run in the same second: %v", err)
+	}
+	if first.Name == second.Name {
+		t.Fatalf("both backups are named %s", first.Name)
+	}
+
+	// A backup is never replaced, even if the clock repeats itself.
+	m.src = fakeSource{data: "second"}
+	if _, err := m.Run(); err == nil {
+		t.Fatal("expected an error for an existing name")
+	}
+	data, err := os.ReadFile(filepath.Join(m.dir, second.Name))
+	if err != nil || string(data) != "first" {
+		t.Errorf("existing backup = %q, %v", data, err)
+	}
+	if names := dirNames(t, m.dir); len(names) != 2 {
+		t.Errorf("files = %v, want the two backups", names)
+	}
+}
+
+func TestRun_RotatesOlderNames(t *testing.T) {
+	m, _ := newTestManager(t, fakeSource{data: "x"}, 1)
+
+	// Backups written before names had milliseconds.
+	if err := os.WriteFile(filepath.Join(m.dir, "contracts-20250228T120000Z.bak"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if names := dirNames(t, m.dir); len(names) != 1 || names[0] != "contracts-20250301T120000.000Z.bak" {
+		t.Errorf("files = %v, want only the new backup", names)
+	}
+}
@@ -1,21 +1,68 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"log/slog"
+	"os"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
 
+const (
+	DBDriverBadger = "badger"
+	DBDriverSQLite = "sqlite"
+)
+
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+	RegistrationClosed = "closed"
+)
+
+// Storage selects and unlocks the database. The maintenance subcommands of
+// the server only load this part of the configuration.
+type Storage struct {
+	DBPath   string `env:"DB_PATH"   envDefault:"./data"`
+	DBDriver string `env:"DB_DRIVER" envDefault:"badger"` // storage backend, "badger" or "sqlite"; both keep their files in DBPath
+	// EncryptionKey encrypts the Badger database at rest with AES-128, -192
+	// or -256 and must be 16, 24 or 32 bytes long. ENCRYPTION_KEY_FILE names
+	// a file holding the key instead, which loading reads into EncryptionKey.
+	EncryptionKey     string `env:"ENCRYPTION_KEY"`
+	EncryptionKeyFile string `env:"ENCRYPTION_KEY_FILE"`
+}
+
 type Config struct {
-	Port        int    `env:"PORT"        envDefault:"8080"`
-	DBPath      string `env:"DB_PATH"     envDefault:"./data"`
+	Port int `env:"PORT" envDefault:"8080"`
+	Storage
 	LogFormat   string `env:"LOG_FORMAT"  envDefault:"text"`
 	LogLevel    string `env:"LOG_LEVEL"   envDefault:"info"`
 	CORSOrigin  string `env:"CORS_ORIGIN"`
 	StaticDir   string `env:"STATIC_DIR"`
 	Environment string `env:"ENVIRONMENT" envDefault:"development"`
 	JWTSecret   string `env:"JWT_SECRET,required"`
+	BaseURL     string `env:"BASE_URL"` // public URL of the app, e.g. https://contracts.example.com
+	// Registration controls who may sign up: "open", "invite" (only with a
+	// workspace invitation) or "closed". The first user can always register.
+	Registration string `env:"REGISTRATION" envDefault:"open"`
+
+	// OpenID Connect sign-in, enabled by setting OIDC_ISSUER. The provider
+	// redirects back to BASE_URL/api/v1/auth/oidc/callback.
+	OIDCIssuer         string   `env:"OIDC_ISSUER"`
+	OIDCClientID       string   `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret   string   `env:"OIDC_CLIENT_SECRET"`
+	OIDCAllowedDomains []string `env:"OIDC_ALLOWED_DOMAINS"` // email domains that may sign in; empty allows any
+
+	// Online backups of the Badger database, enabled by setting BACKUP_DIR.
+	// The newest BACKUP_KEEP files are kept.
+	BackupDir      string        `env:"BACKUP_DIR"`
+	BackupInterval time.Duration `env:"BACKUP_INTERVAL" envDefault:"24h"`
+	BackupKeep     int           `env:"BACKUP_KEEP"     envDefault:"7"`
+
+	// Deleted records stay in the trash for TRASH_RETENTION before they are
+	// purged.
+	TrashRetention time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
 
 	// SMTP Configuration
 	SMTPHost     string `env:"SMTP_HOST"`
@@ -31,9 +78,97 @@ func Load() (Config, error) {
 	if err := env.Parse(&cfg); err != nil {
 		return cfg, fmt.Errorf("parsing config: %w", err)
 	}
+	if err := cfg.Storage.load(); err != nil {
+		return cfg, err
+	}
+	switch cfg.Registration {
+	case RegistrationOpen, RegistrationInvite, RegistrationClosed:
+	default:
+		return cfg, fmt.Errorf("invalid REGISTRATION %q: must be open, invite or closed", cfg.Registration)
+	}
+	if cfg.OIDCIssuer != "" && (cfg.OIDCClientID == "" || cfg.BaseURL == "") {
+		return cfg, fmt.Errorf("OIDC_ISSUER requires OIDC_CLIENT_ID and BASE_URL")
+	}
+	if cfg.BackupDir != "" {
+		if cfg.DBDriver != DBDriverBadger {
+			return cfg, fmt.Errorf("BACKUP_DIR requires DB_DRIVER=badger")
+		}
+		if cfg.BackupInterval <= 0 || cfg.BackupKeep < 1 {
+			return cfg, fmt.Errorf("BACKUP_INTERVAL and BACKUP_KEEP must be positive")
+		}
+	}
+	if cfg.TrashRetention <= 0 {
+		return cfg, fmt.Errorf("TRASH_RETENTION must be positive")
+	}
 	return cfg, nil
 }
 
+// LoadStorage reads the storage settings from the environment.
+func LoadStorage() (Storage, error) {
+	var s Storage
+	if err := env.Parse(&s); err != nil {
+		return s, fmt.Errorf("parsing config: %w", err)
+	}
+	return s, s.load()
+}
+
+func (s *Storage) load() error {
+	switch s.DBDriver {
+	case DBDriverBadger, DBDriverSQLite:
+	default:
+		return fmt.Errorf("invalid DB_DRIVER %q: must be badger or sqlite", s.DBDriver)
+	}
+	if s.EncryptionKeyFile != "" {
+		if s.EncryptionKey != "" {
+			return fmt.Errorf("set only one of ENCRYPTION_KEY and ENCRYPTION_KEY_FILE")
+		}
+		key, err := ReadKeyFile(s.EncryptionKeyFile)
+		if err != nil {
+			return fmt.Errorf("ENCRYPTION_KEY_FILE: %w", err)
+		}
+		s.EncryptionKey = string(key)
+	}
+	if s.EncryptionKey != "" {
+		if s.DBDriver != DBDriverBadger {
+			return fmt.Errorf("ENCRYPTION_KEY requires DB_DRIVER=badger")
+		}
+		if err := checkKey([]byte(s.EncryptionKey)); err != nil {
+			return fmt.Errorf("ENCRYPTION_KEY: %w", err)
+		}
+	}
+	return nil
+}
+
+// Key returns the encryption key, or nil when the database is not encrypted.
+func (s Storage) Key() []byte {
+	if s.EncryptionKey == "" {
+		return nil
+	}
+	return []byte(s.EncryptionKey)
+}
+
+// ReadKeyFile reads an encryption key from a file, ignoring a trailing
+// newline, and checks its length.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := bytes.TrimRight(data, "\r\n")
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func checkKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	}
+	return fmt.Errorf("key must be 16, 24 or 32 bytes long, not %d", len(key))
+}
+
 func (c Config) SlogLevel() slog.Level {
 	switch c.LogLevel {
 	case "debug":
@@ -0,0 +1,420 @@
+// Package csvio reads and writes spreadsheet-style CSV files. It maps header
+// names to fields, understands both decimal conventions ("1,234.56" and
+// "1.234,56") and detects the date format used in a file.
+package csvio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Kind describes how a column's values are parsed and formatted.
+type Kind int
+
+const (
+	Text Kind = iota
+	Number
+	Integer
+	Date
+)
+
+// Column is a field that can appear in a file. Name is the field's JSON name
+// and doubles as the header written on export. Aliases are former names that
+// are still read as the column.
+type Column struct {
+	Name    string
+	Kind    Kind
+	Aliases []string
+}
+
+// Options controls the file dialect. Zero values are filled in by Read and
+// NewWriter.
+type Options struct {
+	Delimiter rune
+	Decimal   rune
+}
+
+// ParseOptions parses user-supplied delimiter and decimal separator values.
+// Empty values are left for detection. "tab" and `\t` select a tab delimiter.
+func ParseOptions(delimiter, decimal string) (Options, error) {
+	var o Options
+	switch delimiter {
+	case "":
+	case "tab", `\t`, "\t":
+		o.Delimiter = '\t'
+	case ",", ";", "|":
+		o.Delimiter = rune(delimiter[0])
+	default:
+		return o, fmt.Errorf("unsupported delimiter %q", delimiter)
+	}
+	switch decimal {
+	case "":
+	case ".", ",":
+		o.Decimal = rune(decimal[0])
+	default:
+		return o, fmt.Errorf("unsupported decimal separator %q", decimal)
+	}
+	return o, nil
+}
+
+// withDefaults fills in the decimal separator: files delimited by semicolons
+// are usually written by spreadsheets using a decimal comma.
+func (o Options) withDefaults() Options {
+	if o.Delimiter == 0 {
+		o.Delimiter = ','
+	}
+	if o.Decimal == 0 {
+		o.Decimal = '.'
+		if o.Delimiter == ';' {
+			o.Decimal = ','
+		}
+	}
+	return o
+}
+
+// Record is one data row. Line is the row's line number in the file, counting
+// the header as line 1.
+type Record struct {
+	Line   int
+	Fields map[string]string
+}
+
+// Table is the result of reading a file.
+type Table struct {
+	Options    Options
+	DateLayout string
+	Records    []Record
+	columns    map[string]Kind
+}
+
+// Read parses a CSV file whose first row is a header. Headers are matched to
+// columns by name or alias, ignoring case, spaces and punctuation; mapping
+// overrides this with explicit header → column pairs, where an empty column
+// ignores the header. Headers that match no column are ignored. An unset
+// delimiter is detected from the header row.
+func Read(r io.Reader, opts Options, columns []Column, mapping map[string]string) (*Table, error) {
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+	if opts.Delimiter == 0 {
+		opts.Delimiter = detectDelimiter(br)
+	}
+	opts = opts.withDefaults()
+
+	kinds := make(map[string]Kind, len(columns))
+	byKey := make(map[string]string, len(columns))
+	aliases := make(map[string]string)
+	for _, c := range columns {
+		kinds[c.Name] = c.Kind
+		byKey[normalize(c.Name)] = c.Name
+		for _, a := range c.Aliases {
+			aliases[a] = c.Name
+			byKey[normalize(a)] = c.Name
+		}
+	}
+	explicit := make(map[string]string, len(mapping))
+	for header, field := range mapping {
+		if name, ok := aliases[field]; ok {
+			field = name
+		}
+		if _, ok := kinds[field]; !ok && field != "" {
+			return nil, fmt.Errorf("mapping: unknown field %q", field)
+		}
+		explicit[normalize(header)] = field
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = opts.Delimiter
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	fields := make([]string, len(header))
+	seen := make(map[string]bool)
+	for i, h := range header {
+		key := normalize(h)
+		field, ok := explicit[key]
+		if !ok {
+			field = byKey[key]
+		}
+		if field == "" {
+			continue
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("more than one column maps to %q", field)
+		}
+		seen[field] = true
+		fields[i] = field
+	}
+	if len(seen) == 0 {
+		return nil, errors.New("header does not contain any known column")
+	}
+
+	t := &Table{Options: opts, columns: kinds}
+	var dates []string
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rec := Record{Line: line, Fields: make(map[string]string, len(seen))}
+		empty := true
+		for i, v := range row {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			empty = false
+			rec.Fields[fields[i]] = v
+			if kinds[fields[i]] == Date {
+				dates = append(dates, v)
+			}
+		}
+		if empty {
+			continue
+		}
+		t.Records = append(t.Records, rec)
+	}
+	t.DateLayout, _ = DetectDateLayout(dates)
+	return t, nil
+}
+
+// Decode converts a record into v, which must be a pointer to a struct whose
+// JSON field names match the column names.
+func (t *Table) Decode(rec Record, v any) error {
+	m := make(map[string]any, len(rec.Fields))
+	for field, s := range rec.Fields {
+		switch t.columns[field] {
+		case Number:
+			f, err := ParseNumber(s, t.Options.Decimal)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+			m[field] = f
+		case Integer:
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("%s: invalid integer %q", field, s)
+			}
+			m[field] = n
+		case Date:
+			d, err := ParseDate(s, t.DateLayout)
+			if err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+			m[field] = d
+		default:
+			m[field] = unescapeText(s)
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// ParseNumber parses a number written with the given decimal separator. The
+// other separator is treated as a thousands separator; spaces and a leading or
+// trailing currency symbol are ignored.
+func ParseNumber(s string, decimal rune) (float64, error) {
+	clean := strings.TrimSpace(strings.Trim(s, " €$£"))
+	thousands := ","
+	if decimal == ',' {
+		thousands = "."
+	}
+	clean = strings.NewReplacer(thousands, "", " ", "", "\u00a0", "", "'", "").Replace(clean)
+	if decimal == ',' {
+		clean = strings.Replace(clean, ",", ".", 1)
+	}
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+// FormatNumber formats f with the given decimal separator and no thousands
+// separator.
+func FormatNumber(f float64, decimal rune) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if decimal == ',' {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// dateLayouts lists the recognised date formats. Day-first comes before
+// month-first, so ambiguous slash dates are read the European way.
+var dateLayouts = []string{
+	"2006-01-02",
+	"2.1.2006",
+	"2.1.06",
+	"2/1/2006",
+	"1/2/2006",
+	"2006/1/2",
+}
+
+// DetectDateLayout returns the first recognised layout that parses every
+// value.
+func DetectDateLayout(values []string) (string, bool) {
+	for _, layout := range dateLayouts {
+		ok := true
+		for _, v := range values {
+			if _, err := time.Parse(layout, v); err != nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return layout, true
+		}
+	}
+	return "", false
+}
+
+// ParseDate parses s with layout, or with the first recognised layout that
+// fits if layout is empty, and returns it as YYYY-MM-DD.
+func ParseDate(s, layout string) (string, error) {
+	layouts := dateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, l := range layouts {
+		if d, err := time.Parse(l, s); err == nil {
+			return d.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("unrecognised date %q", s)
+}
+
+// Writer writes values as CSV rows.
+type Writer struct {
+	w       *csv.Writer
+	opts    Options
+	columns []Column
+}
+
+func NewWriter(w io.Writer, opts Options, columns []Column) *Writer {
+	opts = opts.withDefaults()
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.Delimiter
+	return &Writer{w: cw, opts: opts, columns: columns}
+}
+
+// WriteHeader writes the column names.
+func (w *Writer) WriteHeader() error {
+	header := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		header[i] = c.Name
+	}
+	return w.w.Write(header)
+}
+
+// Write writes v, which must marshal to a JSON object, as one row.
+func (w *Writer) Write(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	row := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		switch val := m[c.Name].(type) {
+		case nil:
+		case float64:
+			if c.Kind == Integer {
+				row[i] = strconv.FormatInt(int64(val), 10)
+			} else {
+				row[i] = FormatNumber(val, w.opts.Decimal)
+			}
+		case string:
+			if c.Kind == Text {
+				val = escapeText(val)
+			}
+			row[i] = val
+		default:
+			row[i] = fmt.Sprint(val)
+		}
+	}
+	return w.w.Write(row)
+}
+
+// formulaPrefixes start text that spreadsheets would evaluate as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeText prefixes text that a spreadsheet would run as a formula with a
+// quote, which makes it plain text.
+func escapeText(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// unescapeText removes the quote escapeText adds.
+func unescapeText(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// Flush writes any buffered rows and reports write errors.
+func (w *Writer) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// detectDelimiter picks the most frequent of comma, semicolon and tab in the
+// first line, defaulting to comma.
+func detectDelimiter(br *bufio.Reader) rune {
+	line, _ := br.Peek(4096)
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := bytes.Count(line, []byte(string(d))); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
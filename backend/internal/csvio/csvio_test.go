@@ -0,0 +1,217 @@
+package csvio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testColumns = []Column{
+	{Name: "name", Kind: Text},
+	{Name: "price", Kind: Number},
+	{Name: "months", Kind: Integer, Aliases: []string{"durationMonths"}},
+	{Name: "startDate", Kind: Date},
+}
+
+type testRow struct {
+	Name      string   `json:"name"`
+	Price     *float64 `json:"price,omitempty"`
+	Months    int      `json:"months"`
+	StartDate string   `json:"startDate"`
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		decimal rune
+		want    float64
+	}{
+		{"12.99", '.', 12.99},
+		{"1,234.56", '.', 1234.56},
+		{"1.234,56", ',', 1234.56},
+		{"12,99 €", ',', 12.99},
+		{"$ 5", '.', 5},
+		{"-3,5", ',', -3.5},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumber(tt.in, tt.decimal)
+		if err != nil {
+			t.Errorf("ParseNumber(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseNumber(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := ParseNumber("abc", '.'); err == nil {
+		t.Error("expected error for non-numeric input")
+	}
+}
+
+func TestDetectDateLayout(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{"2025-01-31", "2024-12-01"}, "2006-01-02"},
+		{[]string{"31.01.2025", "1.2.2024"}, "2.1.2006"},
+		{[]string{"31.01.25"}, "2.1.06"},
+		{[]string{"05/06/2025"}, "2/1/2006"},
+		{[]string{"05/06/2025", "12/31/2025"}, "1/2/2006"},
+	}
+	for _, tt := range tests {
+		got, ok := DetectDateLayout(tt.values)
+		if !ok || got != tt.want {
+			t.Errorf("DetectDateLayout(%v) = %q, %v; want %q", tt.values, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := DetectDateLayout([]string{"2025-01-31", "31.01.2025"}); ok {
+		t.Error("mixed formats should not be detected")
+	}
+}
+
+func TestRead_GermanDialect(t *testing.T) {
+	in := "\xef\xbb\xbfName;Preis;Months;Start Date;Ignored\n" +
+		"Strom;1.234,56;12;31.01.2025;x\n" +
+		";;;;\n" +
+		"Gas;\"7,50\";;01.02.2025;\n"
+
+	tbl, err := Read(strings.NewReader(in), Options{}, testColumns, map[string]string{"Preis": "price"})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if tbl.Options.Delimiter != ';' || tbl.Options.Decimal != ',' {
+		t.Errorf("options = %+v, want ; and ,", tbl.Options)
+	}
+	if len(tbl.Records) != 2 {
+		t.Fatalf("expected 2 records (blank row skipped), got %d", len(tbl.Records))
+	}
+	if tbl.Records[1].Line != 4 {
+		t.Errorf("line = %d, want 4", tbl.Records[1].Line)
+	}
+
+	var row testRow
+	if err := tbl.Decode(tbl.Records[0], &row); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if row.Name != "Strom" || row.Price == nil || *row.Price != 1234.56 || row.Months != 12 || row.StartDate != "2025-01-31" {
+		t.Errorf("row = %+v", row)
+	}
+}
+
+func TestRead_Aliases(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		in      string
+		mapping map[string]string
+	}{
+		{"header", "name,Duration Months\nx,12\n", nil},
+		{"mapping", "name,Laufzeit\nx,12\n", map[string]string{"Laufzeit": "durationMonths"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tbl, err := Read(strings.NewReader(tc.in), Options{}, testColumns, tc.mapping)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			var row testRow
+			if err := tbl.Decode(tbl.Records[0], &row); err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if row.Months != 12 {
+				t.Errorf("months = %d, want 12", row.Months)
+			}
+		})
+	}
+}
+
+func TestRead_Errors(t *testing.T) {
+	if _, err := Read(strings.NewReader(""), Options{}, testColumns, nil); err == nil {
+		t.Error("expected error for empty file")
+	}
+	if _, err := Read(strings.NewReader("foo,bar\n1,2\n"), Options{}, testColumns, nil); err == nil {
+		t.Error("expected error when no column is known")
+	}
+	if _, err := Read(strings.NewReader("name\n"), Options{}, testColumns, map[string]string{"name": "nope"}); err == nil {
+		t.Error("expected error for unknown mapping target")
+	}
+	if _, err := Read(strings.NewReader("name,Name\n"), Options{}, testColumns, nil); err == nil {
+		t.Error("expected error for duplicate column")
+	}
+
+	tbl, err := Read(strings.NewReader("name,months\nx,twelve\n"), Options{}, testColumns, nil)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var row testRow
+	if err := tbl.Decode(tbl.Records[0], &row); err == nil || !strings.Contains(err.Error(), "months") {
+		t.Errorf("expected months error, got %v", err)
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	price := 1234.5
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{Delimiter: ';'}, testColumns)
+	if err := w.WriteHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(testRow{Name: "Strom; Gas", Price: &price, Months: 12, StartDate: "2025-01-31"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "name;price;months;startDate\n\"Strom; Gas\";1234,5;12;2025-01-31\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	tbl, err := Read(&buf, Options{}, testColumns, nil)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	var row testRow
+	if err := tbl.Decode(tbl.Records[0], &row); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if row.Name != "Strom; Gas" || *row.Price != price || row.Months != 12 {
+		t.Errorf("round trip = %+v", row)
+	}
+}
+
+func TestWriter_EscapesFormulas(t *testing.T) {
+	price := -5.0
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Options{}, testColumns)
+	if err := w.Write(testRow{Name: "=HYPERLINK(\"http://evil\")", Price: &price, Months: 1, StartDate: "2025-01-31"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(testRow{Name: "-plain", Price: &price, Months: 1, StartDate: "2025-01-31"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\"'=HYPERLINK(\"\"http://evil\"\")\",-5,1,2025-01-31\n'-plain,-5,1,2025-01-31\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	tbl, err := Read(strings.NewReader("name,price,months,startDate\n"+buf.String()), Options{}, testColumns, nil)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	for i, name := range []string{"=HYPERLINK(\"http://evil\")", "-plain"} {
+		var row testRow
+		if err := tbl.Decode(tbl.Records[i], &row); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if row.Name != name || *row.Price != price {
+			t.Errorf("row %d = %+v, want name %q", i, row, name)
+		}
+	}
+}
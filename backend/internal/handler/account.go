@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+	// Email confirms the deletion of accounts that have no password because
+	// they only sign in through single sign-on.
+	Email string `json:"email"`
+}
+
+// DeleteAccount deletes the caller's account once they confirm it with
+// their password. Workspaces they own are deleted with all their data,
+// also for the other members; shared workspaces they joined are left.
+func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req deleteAccountRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	if user.PasswordHash == "" {
+		if req.Email == "" || !strings.EqualFold(req.Email, user.Email) {
+			h.errorResponse(w, http.StatusUnauthorized, "email does not match")
+			return
+		}
+	} else if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		h.errorResponse(w, http.StatusUnauthorized, "password is incorrect")
+		return
+	}
+
+	if user.Admin {
+		users, err := h.store.ListUsers(r.Context())
+		if err != nil {
+			h.handleStoreError(w, err)
+			return
+		}
+		otherAdmin := slices.ContainsFunc(users, func(u model.User) bool {
+			return u.Admin && !u.Disabled && u.ID != user.ID
+		})
+		if !otherAdmin && len(users) > 1 {
+			h.errorResponse(w, http.StatusConflict, "the last admin cannot delete their account")
+			return
+		}
+	}
+
+	if err := h.deleteAccount(r.Context(), userID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.logger.Info("account deleted", "user_id", userID)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+// ListUsers returns all accounts, oldest first.
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.store.ListUsers(r.Context())
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	slices.SortFunc(users, func(a, b model.User) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	h.writeJSON(w, http.StatusOK, users)
+}
+
+type adminUserRequest struct {
+	Admin    bool `json:"admin"`
+	Disabled bool `json:"disabled"`
+}
+
+// UpdateUser sets a user's admin and disabled flags. Disabling an account
+// signs it out everywhere and revokes its API keys. Admins cannot change
+// their own flags, so there is always an admin left.
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	var req adminUserRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	user, ok := h.otherUser(w, r)
+	if !ok {
+		return
+	}
+
+	user.Admin = req.Admin
+	user.Disabled = req.Disabled
+	if err := h.store.UpdateUser(r.Context(), user); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if user.Disabled {
+		if err := h.revokeAccess(r.Context(), user.ID.String()); err != nil {
+			h.handleStoreError(w, err)
+			return
+		}
+	}
+	h.writeJSON(w, http.StatusOK, user)
+}
+
+// DeleteUser deletes an account and everything it owns.
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.otherUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.deleteAccount(r.Context(), user.ID.String()); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.logger.Info("user deleted by admin", "user_id", user.ID, "admin_id", middleware.GetUserID(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type forcedReset struct {
+	Token   string `json:"token"`
+	Emailed bool   `json:"emailed"`
+}
+
+// ForcePasswordReset invalidates a user's password, sessions and API keys,
+// as it usually follows a suspected compromise, and issues a reset link.
+// The token is returned so the admin can pass the link on when email is not
+// configured.
+func (h *Handler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.otherUser(w, r)
+	if !ok {
+		return
+	}
+
+	user.PasswordHash = ""
+	if err := h.store.UpdateUser(r.Context(), user); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if err := h.revokeAccess(r.Context(), user.ID.String()); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	token, err := h.issuePasswordReset(r, user)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	emailed := h.sendMail != nil && h.baseURL != ""
+	if emailed {
+		h.mailPasswordReset(r, user, token)
+	}
+	h.writeJSON(w, http.StatusOK, forcedReset{Token: token, Emailed: emailed})
+}
+
+// CreateBackup writes a database backup to the backup directory right away.
+func (h *Handler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	if h.backups == nil {
+		h.errorResponse(w, http.StatusNotFound, "backups are not configured")
+		return
+	}
+	info, err := h.backups.Run()
+	if err != nil {
+		h.logger.Error("backup failed", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "backup failed")
+		return
+	}
+	h.logger.Info("backup taken by admin", "name", info.Name, "admin_id", middleware.GetUserID(r.Context()))
+	h.writeJSON(w, http.StatusCreated, info)
+}
+
+// revokeAccess signs the user out everywhere and revokes their API keys.
+func (h *Handler) revokeAccess(ctx context.Context, userID string) error {
+	if err := h.revokeSessions(ctx, userID, uuid.Nil); err != nil {
+		return err
+	}
+	return h.revokeAPIKeys(ctx, userID)
+}
+
+// otherUser resolves the {id} path value to a user other than the caller.
+func (h *Handler) otherUser(w http.ResponseWriter, r *http.Request) (model.User, bool) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return model.User{}, false
+	}
+	if id.String() == middleware.GetUserID(r.Context()) {
+		h.errorResponse(w, http.StatusBadRequest, "admins cannot change their own account here")
+		return model.User{}, false
+	}
+	user, err := h.store.GetUserByID(r.Context(), id.String())
+	if err != nil {
+		h.handleStoreError(w, err)
+		return model.User{}, false
+	}
+	return user, true
+}
+
+// deleteAccount removes a user with the workspaces they own, including the
+// data of every module in them, and their memberships elsewhere.
+func (h *Handler) deleteAccount(ctx context.Context, userID string) error {
+	memberships, err := h.store.ListWorkspaceMemberships(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, m := range memberships {
+		if m.Role == model.WorkspaceRoleOwner {
+			err = h.store.DeleteWorkspace(ctx, m.WorkspaceID)
+		} else {
+			err = h.store.DeleteWorkspaceMember(ctx, m.WorkspaceID, userID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return h.store.DeleteUser(ctx, userID)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+// apiKeyPrefixLen is how much of a key is kept in clear text for display.
+const apiKeyPrefixLen = len(middleware.APIKeyPrefix) + 8
+
+type createdAPIKey struct {
+	model.APIKey
+	Key string `json:"key"`
+}
+
+// ListAPIKeys returns the user's API keys, newest first.
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.store.ListAPIKeys(r.Context(), middleware.GetUserID(r.Context()))
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	slices.SortFunc(keys, func(a, b model.APIKey) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	h.writeJSON(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey issues a new key. The key itself is only returned here.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var input model.APIKeyInput
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := input.Validate(); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := h.now().UTC()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		h.errorResponse(w, http.StatusBadRequest, "expiresAt must be in the future")
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		h.logger.Error("generating API key", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	key := middleware.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	modules := input.Modules
+	if modules == nil {
+		modules = []string{}
+	}
+	k := model.APIKey{
+		ID:        uuid.New(),
+		UserID:    middleware.GetUserID(r.Context()),
+		Name:      input.Name,
+		Prefix:    key[:apiKeyPrefixLen],
+		KeyHash:   middleware.HashAPIKey(key),
+		Access:    input.Access,
+		Modules:   modules,
+		CreatedAt: now,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := h.store.CreateAPIKey(r.Context(), k); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, createdAPIKey{APIKey: k, Key: key})
+}
+
+// DeleteAPIKey revokes a key; scripts using it stop working immediately.
+func (h *Handler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.store.DeleteAPIKey(r.Context(), middleware.GetUserID(r.Context()), id); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeAPIKeys deletes all of the user's API keys.
+func (h *Handler) revokeAPIKeys(ctx context.Context, userID string) error {
+	keys, err := h.store.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := h.store.DeleteAPIKey(ctx, userID, k.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tobi/contracts/backend/internal/archive"
+	"github.com/tobi/contracts/backend/internal/middleware"
+)
+
+// maxArchiveSize bounds the size of an uploaded restore archive.
+const maxArchiveSize = 50 << 20
+
+// ExportAccount streams an archive of the active workspace's records and the
+// user's settings as a JSON download.
+func (h *Handler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	now := h.now()
+
+	a, err := archive.Export(r.Context(), h.store, userID, workspaceID, now)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="contracts-export-%s.json"`, now.UTC().Format("2006-01-02")))
+	h.writeJSON(w, http.StatusOK, a)
+}
+
+// RestoreAccount restores an archive produced by ExportAccount. The mode
+// query parameter selects between merging into the active workspace
+// (default) and replacing its contents.
+func (h *Handler) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	mode := archive.ModeMerge
+	if m := r.URL.Query().Get("mode"); m != "" {
+		mode = archive.Mode(m)
+	}
+	if !mode.Valid() {
+		h.errorResponse(w, http.StatusBadRequest, "mode must be one of: merge, replace")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveSize)
+	defer r.Body.Close()
+	var a archive.Archive
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid archive")
+		return
+	}
+
+	res, err := archive.Restore(r.Context(), h.store, middleware.GetUserID(r.Context()), middleware.GetWorkspaceID(r.Context()), a, mode)
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalidArchive) {
+			h.errorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, res)
+}
@@ -2,11 +2,14 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/config"
+	"github.com/tobi/contracts/backend/internal/middleware"
 	"github.com/tobi/contracts/backend/internal/model"
 	"github.com/tobi/contracts/backend/internal/store"
 	"golang.org/x/crypto/bcrypt"
@@ -17,13 +20,28 @@ type authRequest struct {
 	Password string `json:"password"`
 }
 
+// authResponse carries a short-lived access token (Token, valid until
+// ExpiresAt) and the refresh token used to obtain the next one.
 type authResponse struct {
-	Token string     `json:"token"`
-	User  model.User `json:"user"`
+	Token        string     `json:"token"`
+	RefreshToken string     `json:"refreshToken"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	User         model.User `json:"user"`
+}
+
+// dummyPasswordHash is compared against when there is no real hash to check.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("no account"), bcrypt.DefaultCost)
+
+// registerRequest is an authRequest that may carry a workspace invitation,
+// which is required when registration is invite-only.
+type registerRequest struct {
+	Email           string `json:"email"`
+	Password        string `json:"password"`
+	InvitationToken string `json:"invitationToken,omitempty"`
 }
 
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
-	var req authRequest
+	var req registerRequest
 	if err := h.readJSON(r, &req); err != nil {
 		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
 		return
@@ -33,21 +51,78 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, lockedUntil, err := h.countAttempt(r.Context(), registerThrottleKeys(r))
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		h.tooManyAttempts(w, lockedUntil, "too many registrations, try again later")
+		return
+	}
+
+	// The first account is always allowed and becomes the admin, so a new
+	// installation can be set up whatever the registration mode.
+	users, err := h.store.ListUsers(r.Context())
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	first := len(users) == 0
+
+	var invitation *model.Invitation
+	if !first {
+		switch h.registration {
+		case config.RegistrationClosed:
+			h.errorResponse(w, http.StatusForbidden, "registration is closed")
+			return
+		case config.RegistrationInvite:
+			if req.InvitationToken == "" {
+				h.errorResponse(w, http.StatusForbidden, "registration requires an invitation")
+				return
+			}
+			if _, err := h.store.GetUserByEmail(r.Context(), req.Email); err == nil {
+				h.errorResponse(w, http.StatusConflict, "email already registered")
+				return
+			}
+			// Consuming the invitation up front keeps it from admitting
+			// two accounts; it is put back if this one is not created.
+			inv, err := h.store.ConsumeInvitation(r.Context(), hashToken(req.InvitationToken))
+			if errors.Is(err, store.ErrNotFound) || (err == nil && !h.now().Before(inv.ExpiresAt)) {
+				h.errorResponse(w, http.StatusBadRequest, "invalid or expired invitation")
+				return
+			}
+			if err != nil {
+				h.handleStoreError(w, err)
+				return
+			}
+			invitation = &inv
+		}
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
+		if invitation != nil {
+			h.restoreInvitation(r.Context(), *invitation)
+		}
 		h.logger.Error("hashing password", "error", err)
 		h.errorResponse(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
 	user := model.User{
-		ID:           uuid.New(),
-		Email:        req.Email,
-		PasswordHash: string(hash),
-		CreatedAt:    time.Now().UTC(),
+		ID:                  uuid.New(),
+		Email:               req.Email,
+		PasswordHash:        string(hash),
+		PersonalWorkspaceID: uuid.New(),
+		Admin:               first,
+		CreatedAt:           time.Now().UTC(),
 	}
 
-	if err := h.store.CreateUser(r.Context(), user); err != nil {
+	if err := h.createAccount(r.Context(), user); err != nil {
+		if invitation != nil {
+			h.restoreInvitation(r.Context(), *invitation)
+		}
 		if err == store.ErrConflict {
 			h.errorResponse(w, http.StatusConflict, "email already registered")
 			return
@@ -56,22 +131,61 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		h.errorResponse(w, http.StatusInternalServerError, "internal error")
 		return
 	}
-
-	h.seedDefaultCategoriesIfEmpty(r.Context(), user.ID.String(), "contracts", defaultContractCategories)
-	h.seedDefaultCategoriesIfEmpty(r.Context(), user.ID.String(), "purchases", defaultPurchaseCategories)
+	if invitation != nil {
+		m := model.WorkspaceMember{
+			WorkspaceID: invitation.WorkspaceID,
+			UserID:      user.ID.String(),
+			Role:        invitation.Role,
+			JoinedAt:    user.CreatedAt,
+		}
+		if err := h.store.SetWorkspaceMember(r.Context(), m); err != nil {
+			// The account exists, so the invitation is kept for accepting
+			// it after signing in.
+			h.logger.Error("joining invited workspace", "workspace_id", invitation.WorkspaceID, "error", err)
+			h.restoreInvitation(r.Context(), *invitation)
+		}
+	}
 
 	if h.emailClient != nil {
 		go h.sendWelcomeEmail(user.Email)
 	}
+	if h.sendMail != nil && h.baseURL != "" {
+		if err := h.sendEmailVerification(r, user, user.Email); err != nil {
+			h.logger.Error("issuing email verification", "user_id", user.ID, "error", err)
+		}
+	}
 
-	token, err := h.issueToken(user.ID.String())
+	resp, err := h.startSession(r, user)
 	if err != nil {
 		h.logger.Error("issuing token", "error", err)
 		h.errorResponse(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
-	h.writeJSON(w, http.StatusCreated, authResponse{Token: token, User: user})
+	h.writeJSON(w, http.StatusCreated, resp)
+}
+
+// createAccount stores a new user together with their personal workspace.
+func (h *Handler) createAccount(ctx context.Context, user model.User) error {
+	if err := h.store.CreateUser(ctx, user); err != nil {
+		return err
+	}
+	ws := model.Workspace{ID: user.PersonalWorkspaceID, Name: personalWorkspaceName, CreatedAt: user.CreatedAt}
+	if err := h.createWorkspace(ctx, ws, user.ID.String()); err != nil {
+		return fmt.Errorf("creating personal workspace: %w", err)
+	}
+	return nil
+}
+
+type registrationResponse struct {
+	Mode string `json:"mode"`
+	OIDC bool   `json:"oidc"`
+}
+
+// RegistrationMode tells the login page whether to offer sign-up and single
+// sign-on.
+func (h *Handler) RegistrationMode(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, registrationResponse{Mode: h.registration, OIDC: h.oidc != nil})
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -85,33 +199,70 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.store.GetUserByEmail(r.Context(), req.Email)
+	attempts, lockedUntil, err := h.countAttempt(r.Context(), loginThrottleKeys(r, req.Email))
 	if err != nil {
-		if err == store.ErrNotFound {
-			h.errorResponse(w, http.StatusUnauthorized, "invalid credentials")
-			return
-		}
+		h.handleStoreError(w, err)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		h.tooManyAttempts(w, lockedUntil, "too many failed login attempts, try again later")
+		return
+	}
+
+	user, err := h.store.GetUserByEmail(r.Context(), req.Email)
+	if err != nil && err != store.ErrNotFound {
 		h.logger.Error("looking up user", "error", err)
 		h.errorResponse(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+	// Unknown addresses and accounts without a password are checked against
+	// a dummy hash, so the response time does not tell which accounts exist.
+	hash := dummyPasswordHash
+	if err == nil && user.PasswordHash != "" {
+		hash = []byte(user.PasswordHash)
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || err != nil || user.PasswordHash == "" {
+		middleware.LoginFailures.Inc()
 		h.errorResponse(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
+	// The password is right, so only the second factor, if any, still counts.
+	h.takeBackAttempt(r.Context(), attempts)
+	if user.Disabled {
+		h.errorResponse(w, http.StatusForbidden, "account disabled")
+		return
+	}
+
+	h.seedDefaultCategoriesIfEmpty(r.Context(), user.PersonalWorkspaceID.String(), "contracts", defaultContractCategories)
+	h.seedDefaultCategoriesIfEmpty(r.Context(), user.PersonalWorkspaceID.String(), "purchases", defaultPurchaseCategories)
 
-	h.seedDefaultCategoriesIfEmpty(r.Context(), user.ID.String(), "contracts", defaultContractCategories)
-	h.seedDefaultCategoriesIfEmpty(r.Context(), user.ID.String(), "purchases", defaultPurchaseCategories)
+	twoFactor, err := h.twoFactorEnabled(r.Context(), user.ID.String())
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if twoFactor {
+		challenge, err := h.issueChallenge(user.ID.String())
+		if err != nil {
+			h.logger.Error("issuing challenge", "error", err)
+			h.errorResponse(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		// Failures are only forgotten once the second factor is passed too.
+		h.writeJSON(w, http.StatusOK, challengeResponse{TwoFactorRequired: true, ChallengeToken: challenge})
+		return
+	}
+	h.clearLoginFailures(r.Context(), req.Email)
 
-	token, err := h.issueToken(user.ID.String())
+	resp, err := h.startSession(r, user)
 	if err != nil {
 		h.logger.Error("issuing token", "error", err)
 		h.errorResponse(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, authResponse{Token: token, User: user})
+	h.writeJSON(w, http.StatusOK, resp)
 }
 
 var defaultContractCategories = []struct {
@@ -134,11 +285,11 @@ var defaultPurchaseCategories = []struct {
 	{"Household", "categoryNames.household"},
 }
 
-func (h *Handler) seedDefaultCategoriesIfEmpty(ctx context.Context, userID string, module string, defaults []struct {
+func (h *Handler) seedDefaultCategoriesIfEmpty(ctx context.Context, workspaceID string, module string, defaults []struct {
 	Name    string
 	NameKey string
 }) {
-	existing, err := h.store.ListCategories(ctx, userID, module)
+	existing, err := h.store.ListCategories(ctx, workspaceID, module)
 	if err != nil {
 		h.logger.Error("listing categories for seed check", "module", module, "error", err)
 		return
@@ -156,22 +307,12 @@ func (h *Handler) seedDefaultCategoriesIfEmpty(ctx context.Context, userID strin
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
-		if err := h.store.CreateCategory(ctx, userID, module, cat); err != nil {
+		if err := h.store.CreateCategory(ctx, workspaceID, module, cat); err != nil {
 			h.logger.Error("seeding default category", "module", module, "name", dc.Name, "error", err)
 		}
 	}
 }
 
-func (h *Handler) issueToken(userID string) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Subject:   userID,
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(h.jwtSecret)
-}
-
 func (h *Handler) sendWelcomeEmail(to string) {
 	subject := "Welcome to Contracts!"
 	body := "Hello,\n\nWelcome to Contracts! You have successfully registered your account.\n\nBest regards,\nYour Contracts Team"
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tobi/contracts/backend/internal/ical"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+type calendarFeedResponse struct {
+	Enabled   bool       `json:"enabled"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	// URL and Token are only returned right after rotation; the token
+	// itself is not stored.
+	URL   string `json:"url,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+func (h *Handler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	t, err := h.store.GetFeedToken(r.Context(), middleware.GetUserID(r.Context()))
+	if errors.Is(err, store.ErrNotFound) {
+		h.writeJSON(w, http.StatusOK, calendarFeedResponse{})
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, calendarFeedResponse{Enabled: true, CreatedAt: &t.CreatedAt})
+}
+
+// RotateCalendarFeed creates a new feed token, revoking the previous one.
+func (h *Handler) RotateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		h.logger.Error("generating feed token", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	t := model.FeedToken{TokenHash: hash, CreatedAt: h.now().UTC()}
+	if err := h.store.SetFeedToken(r.Context(), middleware.GetUserID(r.Context()), t); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, calendarFeedResponse{
+		Enabled:   true,
+		CreatedAt: &t.CreatedAt,
+		URL:       h.publicURL(r, "/api/v1/calendar/"+token+".ics"),
+		Token:     token,
+	})
+}
+
+func (h *Handler) RevokeCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteFeedToken(r.Context(), middleware.GetUserID(r.Context())); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CalendarFeed serves the cancellation deadlines of the token's owner as an
+// iCalendar feed. It is public because calendar clients cannot send a JWT;
+// the token in the path authenticates the request.
+func (h *Handler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(r.PathValue("file"), ".ics")
+	if token == "" {
+		h.errorResponse(w, http.StatusNotFound, "not found")
+		return
+	}
+	userID, err := h.store.GetUserIDByFeedToken(r.Context(), hashToken(token))
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err == nil && user.Disabled {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	settings, err := h.store.GetSettings(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	contracts, err := h.memberContracts(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	today := h.today()
+	cal := ical.Calendar{Name: "Contract deadlines"}
+	for _, c := range contracts {
+		if c.CancellationInProgress() {
+			continue
+		}
+		cd := c.CancellationDateAsOf(today)
+		if cd == nil {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", *cd)
+		if err != nil {
+			continue
+		}
+		cal.Events = append(cal.Events, contractEvent(c, date, settings.RenewalDays))
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, cal, h.now()); err != nil {
+		h.logger.Error("writing calendar feed", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="contracts.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// contractEvent builds the deadline event of a contract. It reminds the user
+// renewalDays ahead, like the reminder emails, and again a week before.
+func contractEvent(c model.Contract, deadline time.Time, renewalDays int) ical.Event {
+	var desc []string
+	if c.Company != "" {
+		desc = append(desc, "Company: "+c.Company)
+	}
+	if c.ContractNumber != "" {
+		desc = append(desc, "Contract number: "+c.ContractNumber)
+	}
+	if c.CustomerNumber != "" {
+		desc = append(desc, "Customer number: "+c.CustomerNumber)
+	}
+
+	var alarms []int
+	if renewalDays > 0 {
+		alarms = append(alarms, renewalDays)
+	}
+	if renewalDays > 7 {
+		alarms = append(alarms, 7)
+	}
+
+	return ical.Event{
+		UID:             fmt.Sprintf("%s-cancellation@contracts", c.ID),
+		Date:            deadline,
+		Summary:         "Cancellation deadline: " + c.Name,
+		Description:     strings.Join(desc, "\n"),
+		URL:             c.CustomerPortalURL,
+		AlarmDaysBefore: alarms,
+	}
+}
+
+// publicURL returns an absolute URL for path, based on the configured base
+// URL or, without one, on the request.
+func (h *Handler) publicURL(r *http.Request, path string) string {
+	if h.baseURL != "" {
+		return strings.TrimSuffix(h.baseURL, "/") + path
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + path
+}
@@ -11,7 +11,7 @@ import (
 
 func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
 	module := r.PathValue("module")
-	categories, err := h.store.ListCategories(r.Context(), middleware.GetUserID(r.Context()), module)
+	categories, err := h.store.ListCategories(r.Context(), middleware.GetWorkspaceID(r.Context()), module)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -27,7 +27,7 @@ func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cat, err := h.store.GetCategory(r.Context(), middleware.GetUserID(r.Context()), module, id)
+	cat, err := h.store.GetCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), module, id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -55,7 +55,7 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: now,
 	}
 
-	if err := h.store.CreateCategory(r.Context(), middleware.GetUserID(r.Context()), module, cat); err != nil {
+	if err := h.store.CreateCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), module, cat); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -70,7 +70,7 @@ func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := h.store.GetCategory(r.Context(), middleware.GetUserID(r.Context()), module, id)
+	existing, err := h.store.GetCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), module, id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -89,7 +89,7 @@ func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	existing.Name = input.Name
 	existing.UpdatedAt = time.Now().UTC()
 
-	if err := h.store.UpdateCategory(r.Context(), middleware.GetUserID(r.Context()), module, existing); err != nil {
+	if err := h.store.UpdateCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), module, existing); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -104,7 +104,7 @@ func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.store.DeleteCategory(r.Context(), middleware.GetUserID(r.Context()), module, id); err != nil {
+	if err := h.store.DeleteCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), module, id); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -1,8 +1,9 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/middleware"
@@ -10,12 +11,17 @@ import (
 )
 
 func (h *Handler) ListContracts(w http.ResponseWriter, r *http.Request) {
-	contracts, err := h.store.ListContracts(r.Context(), middleware.GetUserID(r.Context()))
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	contracts, err := h.store.ListContracts(r.Context(), workspaceID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
-	h.writeJSON(w, http.StatusOK, newContractViews(contracts))
+	if err := h.applyCurrentPrices(r.Context(), workspaceID, contracts); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.newContractViews(contracts))
 }
 
 func (h *Handler) ListContractsByCategory(w http.ResponseWriter, r *http.Request) {
@@ -25,12 +31,17 @@ func (h *Handler) ListContractsByCategory(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	contracts, err := h.store.ListContractsByCategory(r.Context(), middleware.GetUserID(r.Context()), catID)
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	contracts, err := h.store.ListContractsByCategory(r.Context(), workspaceID, catID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
-	h.writeJSON(w, http.StatusOK, newContractViews(contracts))
+	if err := h.applyCurrentPrices(r.Context(), workspaceID, contracts); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.newContractViews(contracts))
 }
 
 func (h *Handler) CreateContractInCategory(w http.ResponseWriter, r *http.Request) {
@@ -41,7 +52,7 @@ func (h *Handler) CreateContractInCategory(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Verify category exists (contracts module)
-	if _, err := h.store.GetCategory(r.Context(), middleware.GetUserID(r.Context()), "contracts", catID); err != nil {
+	if _, err := h.store.GetCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), "contracts", catID); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -56,7 +67,7 @@ func (h *Handler) CreateContractInCategory(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	now := time.Now().UTC()
+	now := h.now().UTC()
 	bi := input.BillingInterval
 	if bi == "" {
 		bi = model.BillingMonthly
@@ -73,21 +84,28 @@ func (h *Handler) CreateContractInCategory(w http.ResponseWriter, r *http.Reques
 		BillingInterval:         bi,
 		StartDate:               input.StartDate,
 		EndDate:                 input.EndDate,
-		MinimumDurationMonths:   input.MinimumDurationMonths,
-		ExtensionDurationMonths: input.ExtensionDurationMonths,
-		NoticePeriodMonths:      input.NoticePeriodMonths,
+		MinimumDuration:         input.MinimumDuration,
+		MinimumDurationUnit:     input.MinimumDurationUnit,
+		MinimumDurationAnchor:   input.MinimumDurationAnchor,
+		ExtensionDuration:       input.ExtensionDuration,
+		ExtensionDurationUnit:   input.ExtensionDurationUnit,
+		ExtensionDurationAnchor: input.ExtensionDurationAnchor,
+		NoticePeriod:            input.NoticePeriod,
+		NoticePeriodUnit:        input.NoticePeriodUnit,
+		NoticePeriodAnchor:      input.NoticePeriodAnchor,
 		CustomerPortalURL:       input.CustomerPortalURL,
 		PaperlessURL:            input.PaperlessURL,
 		Comments:                input.Comments,
+		Status:                  model.StatusActive,
 		CreatedAt:               now,
 		UpdatedAt:               now,
 	}
 
-	if err := h.store.CreateContract(r.Context(), middleware.GetUserID(r.Context()), con); err != nil {
+	if err := h.store.CreateContract(r.Context(), middleware.GetWorkspaceID(r.Context()), con); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
-	h.writeJSON(w, http.StatusCreated, newContractView(con))
+	h.writeJSON(w, http.StatusCreated, h.newContractView(con))
 }
 
 func (h *Handler) GetContract(w http.ResponseWriter, r *http.Request) {
@@ -97,12 +115,19 @@ func (h *Handler) GetContract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	con, err := h.store.GetContract(r.Context(), middleware.GetUserID(r.Context()), id)
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	con, err := h.store.GetContract(r.Context(), workspaceID, id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
-	h.writeJSON(w, http.StatusOK, newContractView(con))
+	history, err := h.store.ListPriceEntriesByContract(r.Context(), workspaceID, id)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	con.Price = con.PriceOn(h.now().UTC(), history)
+	h.writeJSON(w, http.StatusOK, h.newContractView(con))
 }
 
 func (h *Handler) UpdateContract(w http.ResponseWriter, r *http.Request) {
@@ -112,7 +137,8 @@ func (h *Handler) UpdateContract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := h.store.GetContract(r.Context(), middleware.GetUserID(r.Context()), id)
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	existing, err := h.store.GetContract(r.Context(), workspaceID, id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -128,12 +154,96 @@ func (h *Handler) UpdateContract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	prices, err := h.applyContractInput(r.Context(), workspaceID, &existing, input)
+	if errors.Is(err, errPriceFromHistory) {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	if err := h.store.UpdateContract(r.Context(), workspaceID, existing, prices...); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.newContractView(existing))
+}
+
+// TransitionContract moves a contract through the cancellation workflow.
+func (h *Handler) TransitionContract(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	con, err := h.store.GetContract(r.Context(), workspaceID, id)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	var input model.ContractTransitionInput
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := input.Validate(); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := h.now().UTC()
+	if err := con.Transition(input, now); err != nil {
+		h.errorResponse(w, http.StatusConflict, err.Error())
+		return
+	}
+	con.UpdatedAt = now
+
+	if err := h.store.UpdateContract(r.Context(), workspaceID, con); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	history, err := h.store.ListPriceEntriesByContract(r.Context(), workspaceID, id)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	con.Price = con.PriceOn(now, history)
+	h.writeJSON(w, http.StatusOK, h.newContractView(con))
+}
+
+func (h *Handler) DeleteContract(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.store.DeleteContract(r.Context(), middleware.GetWorkspaceID(r.Context()), id); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errPriceFromHistory refuses to clear the price of a contract whose price
+// history still sets one, as reads would keep showing the history's price.
+var errPriceFromHistory = errors.New("price cannot be cleared while the price history sets one; delete its entries instead")
+
+// applyContractInput copies the user-editable fields of input onto existing
+// and returns the price entries to store along with it: a changed price is
+// recorded in the price history instead of silently overwriting what was
+// paid before.
+func (h *Handler) applyContractInput(ctx context.Context, workspaceID string, existing *model.Contract, input model.ContractInput) ([]model.PriceEntry, error) {
 	existing.Name = input.Name
 	existing.ProductName = input.ProductName
 	existing.Company = input.Company
 	existing.ContractNumber = input.ContractNumber
 	existing.CustomerNumber = input.CustomerNumber
-	existing.Price = input.Price
 	bi := input.BillingInterval
 	if bi == "" {
 		bi = model.BillingMonthly
@@ -141,31 +251,37 @@ func (h *Handler) UpdateContract(w http.ResponseWriter, r *http.Request) {
 	existing.BillingInterval = bi
 	existing.StartDate = input.StartDate
 	existing.EndDate = input.EndDate
-	existing.MinimumDurationMonths = input.MinimumDurationMonths
-	existing.ExtensionDurationMonths = input.ExtensionDurationMonths
-	existing.NoticePeriodMonths = input.NoticePeriodMonths
+	existing.MinimumDuration = input.MinimumDuration
+	existing.MinimumDurationUnit = input.MinimumDurationUnit
+	existing.MinimumDurationAnchor = input.MinimumDurationAnchor
+	existing.ExtensionDuration = input.ExtensionDuration
+	existing.ExtensionDurationUnit = input.ExtensionDurationUnit
+	existing.ExtensionDurationAnchor = input.ExtensionDurationAnchor
+	existing.NoticePeriod = input.NoticePeriod
+	existing.NoticePeriodUnit = input.NoticePeriodUnit
+	existing.NoticePeriodAnchor = input.NoticePeriodAnchor
 	existing.CustomerPortalURL = input.CustomerPortalURL
 	existing.PaperlessURL = input.PaperlessURL
 	existing.Comments = input.Comments
-	existing.UpdatedAt = time.Now().UTC()
-
-	if err := h.store.UpdateContract(r.Context(), middleware.GetUserID(r.Context()), existing); err != nil {
-		h.handleStoreError(w, err)
-		return
-	}
-	h.writeJSON(w, http.StatusOK, newContractView(existing))
-}
+	existing.UpdatedAt = h.now().UTC()
 
-func (h *Handler) DeleteContract(w http.ResponseWriter, r *http.Request) {
-	id, err := parseUUID(r.PathValue("id"))
+	history, err := h.store.ListPriceEntriesByContract(ctx, workspaceID, existing.ID)
 	if err != nil {
-		h.errorResponse(w, http.StatusBadRequest, "invalid id")
-		return
+		return nil, err
 	}
-
-	if err := h.store.DeleteContract(r.Context(), middleware.GetUserID(r.Context()), id); err != nil {
-		h.handleStoreError(w, err)
-		return
+	now := h.now().UTC()
+	current := existing.PriceOn(now, history)
+	if input.Price == nil {
+		if len(history) > 0 && current != nil {
+			return nil, errPriceFromHistory
+		}
+		existing.Price = nil
+		return nil, nil
 	}
-	w.WriteHeader(http.StatusNoContent)
+	var prices []model.PriceEntry
+	if current == nil || *current != *input.Price {
+		prices = h.newPriceEntries(*existing, history, *input.Price, now.Format("2006-01-02"), "")
+	}
+	existing.Price = input.Price
+	return prices, nil
 }
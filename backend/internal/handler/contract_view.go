@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/middleware"
 	"github.com/tobi/contracts/backend/internal/model"
 )
@@ -16,18 +17,19 @@ type contractView struct {
 	Expired          bool    `json:"expired"`
 }
 
-func newContractView(c model.Contract) contractView {
+func (h *Handler) newContractView(c model.Contract) contractView {
+	today := h.today()
 	return contractView{
 		Contract:         c,
-		CancellationDate: c.CancellationDate(),
-		Expired:          c.IsExpired(),
+		CancellationDate: c.CancellationDateAsOf(today),
+		Expired:          c.IsExpiredAsOf(today),
 	}
 }
 
-func newContractViews(cs []model.Contract) []contractView {
+func (h *Handler) newContractViews(cs []model.Contract) []contractView {
 	out := make([]contractView, len(cs))
 	for i, c := range cs {
-		out[i] = newContractView(c)
+		out[i] = h.newContractView(c)
 	}
 	return out
 }
@@ -46,18 +48,26 @@ func (h *Handler) UpcomingRenewals(w http.ResponseWriter, r *http.Request) {
 		days = n
 	}
 
-	contracts, err := h.store.ListContracts(r.Context(), middleware.GetUserID(r.Context()))
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	contracts, err := h.store.ListContracts(r.Context(), workspaceID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
+	if err := h.applyCurrentPrices(r.Context(), workspaceID, contracts); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
 
-	today := time.Now().UTC().Truncate(24 * time.Hour)
+	today := h.today()
 	deadline := today.AddDate(0, 0, days)
 
 	var upcoming []contractView
 	for _, c := range contracts {
-		cv := newContractView(c)
+		if c.CancellationInProgress() {
+			continue
+		}
+		cv := h.newContractView(c)
 		if cv.CancellationDate == nil {
 			continue
 		}
@@ -80,3 +90,59 @@ func (h *Handler) UpcomingRenewals(w http.ResponseWriter, r *http.Request) {
 
 	h.writeJSON(w, http.StatusOK, upcoming)
 }
+
+type timelineResponse struct {
+	ContractID       uuid.UUID            `json:"contractId"`
+	Date             string               `json:"date"`
+	Until            string               `json:"until"`
+	CancellationDate *string              `json:"cancellationDate,omitempty"`
+	Boundaries       []model.TermBoundary `json:"boundaries"`
+}
+
+// ContractTimeline lists the contract's term boundaries and cancellation
+// deadlines from its start until "months" (default 24) after the reference
+// date given by the optional "date" query parameter (default: today).
+func (h *Handler) ContractTimeline(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	date := h.today()
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			h.errorResponse(w, http.StatusBadRequest, "date must be in format YYYY-MM-DD")
+			return
+		}
+		date = d
+	}
+	months := 24
+	if v := r.URL.Query().Get("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.errorResponse(w, http.StatusBadRequest, "months must be a positive integer")
+			return
+		}
+		if n > 120 {
+			n = 120
+		}
+		months = n
+	}
+
+	con, err := h.store.GetContract(r.Context(), middleware.GetWorkspaceID(r.Context()), id)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	until := date.AddDate(0, months, 0)
+	h.writeJSON(w, http.StatusOK, timelineResponse{
+		ContractID:       con.ID,
+		Date:             date.Format("2006-01-02"),
+		Until:            until.Format("2006-01-02"),
+		CancellationDate: con.CancellationDateAsOf(date),
+		Boundaries:       con.Timeline(date, until),
+	})
+}
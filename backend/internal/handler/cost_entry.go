@@ -16,7 +16,7 @@ func (h *Handler) ListCostEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries, err := h.store.ListCostEntries(r.Context(), middleware.GetUserID(r.Context()), vehicleID)
+	entries, err := h.store.ListCostEntries(r.Context(), middleware.GetWorkspaceID(r.Context()), vehicleID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -31,7 +31,7 @@ func (h *Handler) CreateCostEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := h.store.GetVehicle(r.Context(), middleware.GetUserID(r.Context()), vehicleID); err != nil {
+	if _, err := h.store.GetVehicle(r.Context(), middleware.GetWorkspaceID(r.Context()), vehicleID); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -61,7 +61,7 @@ func (h *Handler) CreateCostEntry(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   now,
 	}
 
-	if err := h.store.CreateCostEntry(r.Context(), middleware.GetUserID(r.Context()), c); err != nil {
+	if err := h.store.CreateCostEntry(r.Context(), middleware.GetWorkspaceID(r.Context()), c); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -75,7 +75,7 @@ func (h *Handler) GetCostEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c, err := h.store.GetCostEntry(r.Context(), middleware.GetUserID(r.Context()), id)
+	c, err := h.store.GetCostEntry(r.Context(), middleware.GetWorkspaceID(r.Context()), id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -90,7 +90,7 @@ func (h *Handler) UpdateCostEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := h.store.GetCostEntry(r.Context(), middleware.GetUserID(r.Context()), id)
+	existing, err := h.store.GetCostEntry(r.Context(), middleware.GetWorkspaceID(r.Context()), id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -115,7 +115,7 @@ func (h *Handler) UpdateCostEntry(w http.ResponseWriter, r *http.Request) {
 	existing.Comments = input.Comments
 	existing.UpdatedAt = time.Now().UTC()
 
-	if err := h.store.UpdateCostEntry(r.Context(), middleware.GetUserID(r.Context()), existing); err != nil {
+	if err := h.store.UpdateCostEntry(r.Context(), middleware.GetWorkspaceID(r.Context()), existing); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -129,7 +129,7 @@ func (h *Handler) DeleteCostEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.store.DeleteCostEntry(r.Context(), middleware.GetUserID(r.Context()), id); err != nil {
+	if err := h.store.DeleteCostEntry(r.Context(), middleware.GetWorkspaceID(r.Context()), id); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
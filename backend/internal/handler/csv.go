@@ -0,0 +1,413 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/csvio"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+var contractCSVColumns = []csvio.Column{
+	{Name: "category"},
+	{Name: "name"},
+	{Name: "productName"},
+	{Name: "company"},
+	{Name: "contractNumber"},
+	{Name: "customerNumber"},
+	{Name: "price", Kind: csvio.Number},
+	{Name: "billingInterval"},
+	{Name: "startDate", Kind: csvio.Date},
+	{Name: "endDate", Kind: csvio.Date},
+	{Name: "minimumDuration", Kind: csvio.Integer, Aliases: []string{"minimumDurationMonths"}},
+	{Name: "minimumDurationUnit"},
+	{Name: "minimumDurationAnchor"},
+	{Name: "extensionDuration", Kind: csvio.Integer, Aliases: []string{"extensionDurationMonths"}},
+	{Name: "extensionDurationUnit"},
+	{Name: "extensionDurationAnchor"},
+	{Name: "noticePeriod", Kind: csvio.Integer, Aliases: []string{"noticePeriodMonths"}},
+	{Name: "noticePeriodUnit"},
+	{Name: "noticePeriodAnchor"},
+	{Name: "customerPortalUrl"},
+	{Name: "paperlessUrl"},
+	{Name: "comments"},
+}
+
+// contractCSVExportColumns adds read-only columns to the export. They are
+// ignored when the file is imported again.
+var contractCSVExportColumns = slices.Concat(contractCSVColumns, []csvio.Column{
+	{Name: "status"},
+	{Name: "cancellationDate", Kind: csvio.Date},
+})
+
+var purchaseCSVColumns = []csvio.Column{
+	{Name: "category"},
+	{Name: "type"},
+	{Name: "itemName"},
+	{Name: "brand"},
+	{Name: "articleNumber"},
+	{Name: "dealer"},
+	{Name: "price", Kind: csvio.Number},
+	{Name: "purchaseDate", Kind: csvio.Date},
+	{Name: "descriptionUrl"},
+	{Name: "invoiceUrl"},
+	{Name: "handbookUrl"},
+	{Name: "consumables"},
+	{Name: "comments"},
+}
+
+var costEntryCSVColumns = []csvio.Column{
+	{Name: "date", Kind: csvio.Date},
+	{Name: "type"},
+	{Name: "description"},
+	{Name: "vendor"},
+	{Name: "amount", Kind: csvio.Number},
+	{Name: "mileage", Kind: csvio.Number},
+	{Name: "comments"},
+}
+
+type purchaseImportEntry struct {
+	Category string `json:"category"`
+	model.PurchaseInput
+}
+
+// readCSVUpload parses the multipart "file" field as CSV. The optional form
+// fields "delimiter", "decimal" and "mapping" (a JSON object of header →
+// field) control how the file is read. On failure it writes the error
+// response and returns nil.
+func (h *Handler) readCSVUpload(w http.ResponseWriter, r *http.Request, columns []csvio.Column) *csvio.Table {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid multipart form")
+		return nil
+	}
+
+	opts, err := csvio.ParseOptions(r.FormValue("delimiter"), r.FormValue("decimal"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	var mapping map[string]string
+	if m := r.FormValue("mapping"); m != "" {
+		if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+			h.errorResponse(w, http.StatusBadRequest, "mapping must be a JSON object of column header to field")
+			return nil
+		}
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "missing file field")
+		return nil
+	}
+	defer file.Close()
+
+	t, err := csvio.Read(file, opts, columns, mapping)
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid CSV: "+err.Error())
+		return nil
+	}
+	return t
+}
+
+// writeCSV streams rows as a CSV download. The optional "delimiter" and
+// "decimal" query parameters select the dialect.
+func (h *Handler) writeCSV(w http.ResponseWriter, r *http.Request, name string, columns []csvio.Column, rows []any) {
+	opts, err := csvio.ParseOptions(r.URL.Query().Get("delimiter"), r.URL.Query().Get("decimal"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, name, h.now().UTC().Format("2006-01-02")))
+	cw := csvio.NewWriter(w, opts, columns)
+	if err := cw.WriteHeader(); err != nil {
+		h.logger.Error("writing csv", "error", err)
+		return
+	}
+	for _, row := range rows {
+		if err := cw.Write(row); err != nil {
+			h.logger.Error("writing csv", "error", err)
+			return
+		}
+	}
+	if err := cw.Flush(); err != nil {
+		h.logger.Error("writing csv", "error", err)
+	}
+}
+
+// categoryFilter parses the optional "categoryId" query parameter used to
+// restrict an export to one category.
+func categoryFilter(r *http.Request) (uuid.UUID, error) {
+	v := r.URL.Query().Get("categoryId")
+	if v == "" {
+		return uuid.Nil, nil
+	}
+	return parseUUID(v)
+}
+
+// categoryNames maps category IDs to names for the given module.
+func (h *Handler) categoryNames(ctx context.Context, workspaceID, module string) (map[uuid.UUID]string, error) {
+	cats, err := h.store.ListCategories(ctx, workspaceID, module)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(cats))
+	for _, c := range cats {
+		names[c.ID] = c.Name
+	}
+	return names, nil
+}
+
+func (h *Handler) ImportContractsCSV(w http.ResponseWriter, r *http.Request) {
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	t := h.readCSVUpload(w, r, contractCSVColumns)
+	if t == nil {
+		return
+	}
+
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	imp, err := h.newContractImporter(r.Context(), workspaceID, opts)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	result := importResult{Errors: []importError{}}
+	for _, rec := range t.Records {
+		var entry contractImportEntry
+		if err := t.Decode(rec, &entry); err != nil {
+			result.Errors = append(result.Errors, importError{Row: rec.Line, Error: err.Error()})
+			continue
+		}
+		outcome, err := imp.importEntry(r.Context(), entry)
+		result.record(rec.Line, outcome, err)
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) ExportContractsCSV(w http.ResponseWriter, r *http.Request) {
+	catID, err := categoryFilter(r)
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid category id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	contracts, err := h.store.ListContracts(r.Context(), workspaceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if err := h.applyCurrentPrices(r.Context(), workspaceID, contracts); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	names, err := h.categoryNames(r.Context(), workspaceID, "contracts")
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	type row struct {
+		Category string `json:"category"`
+		contractView
+	}
+	var rows []any
+	for _, c := range contracts {
+		if catID != uuid.Nil && c.CategoryID != catID {
+			continue
+		}
+		rows = append(rows, row{Category: names[c.CategoryID], contractView: h.newContractView(c)})
+	}
+	h.writeCSV(w, r, "contracts", contractCSVExportColumns, rows)
+}
+
+func (h *Handler) ImportPurchasesCSV(w http.ResponseWriter, r *http.Request) {
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	t := h.readCSVUpload(w, r, purchaseCSVColumns)
+	if t == nil {
+		return
+	}
+
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	imp, err := h.newPurchaseImporter(r.Context(), workspaceID, opts)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	result := importResult{Errors: []importError{}}
+	for _, rec := range t.Records {
+		var entry purchaseImportEntry
+		if err := t.Decode(rec, &entry); err != nil {
+			result.Errors = append(result.Errors, importError{Row: rec.Line, Error: err.Error()})
+			continue
+		}
+		outcome, err := imp.importEntry(r.Context(), entry)
+		result.record(rec.Line, outcome, err)
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// purchaseFromImport validates an imported entry and builds the purchase it
+// describes. The category is left for the caller to resolve.
+func (h *Handler) purchaseFromImport(entry purchaseImportEntry) (model.Purchase, error) {
+	if entry.Category == "" {
+		return model.Purchase{}, errors.New("category is required")
+	}
+	if err := entry.PurchaseInput.Validate(); err != nil {
+		return model.Purchase{}, err
+	}
+
+	now := h.now().UTC()
+	p := model.Purchase{ID: uuid.New(), CreatedAt: now, UpdatedAt: now}
+	applyPurchaseInput(&p, entry.PurchaseInput)
+	return p, nil
+}
+
+func (h *Handler) ExportPurchasesCSV(w http.ResponseWriter, r *http.Request) {
+	catID, err := categoryFilter(r)
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid category id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	purchases, err := h.store.ListPurchases(r.Context(), workspaceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	names, err := h.categoryNames(r.Context(), workspaceID, "purchases")
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	type row struct {
+		Category string `json:"category"`
+		model.Purchase
+	}
+	var rows []any
+	for _, p := range purchases {
+		if catID != uuid.Nil && p.CategoryID != catID {
+			continue
+		}
+		rows = append(rows, row{Category: names[p.CategoryID], Purchase: p})
+	}
+	h.writeCSV(w, r, "purchases", purchaseCSVColumns, rows)
+}
+
+func (h *Handler) ImportCostEntriesCSV(w http.ResponseWriter, r *http.Request) {
+	vehicleID, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid vehicle id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	if _, err := h.store.GetVehicle(r.Context(), workspaceID, vehicleID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	t := h.readCSVUpload(w, r, costEntryCSVColumns)
+	if t == nil {
+		return
+	}
+
+	result := importResult{Errors: []importError{}}
+	for _, rec := range t.Records {
+		var input model.CostEntryInput
+		if err := t.Decode(rec, &input); err != nil {
+			result.Errors = append(result.Errors, importError{Row: rec.Line, Error: err.Error()})
+			continue
+		}
+		if err := input.Validate(); err != nil {
+			result.Errors = append(result.Errors, importError{Row: rec.Line, Error: err.Error()})
+			continue
+		}
+
+		now := h.now().UTC()
+		c := model.CostEntry{
+			ID:          uuid.New(),
+			VehicleID:   vehicleID,
+			Type:        input.Type,
+			Description: input.Description,
+			Vendor:      input.Vendor,
+			Amount:      input.Amount,
+			Date:        input.Date,
+			Mileage:     input.Mileage,
+			Comments:    input.Comments,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := h.store.CreateCostEntry(r.Context(), workspaceID, c); err != nil {
+			result.Errors = append(result.Errors, importError{Row: rec.Line, Error: fmt.Sprintf("failed to create cost entry: %v", err)})
+			continue
+		}
+		result.Created++
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) ExportCostEntriesCSV(w http.ResponseWriter, r *http.Request) {
+	vehicleID, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid vehicle id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	v, err := h.store.GetVehicle(r.Context(), workspaceID, vehicleID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	entries, err := h.store.ListCostEntries(r.Context(), workspaceID, vehicleID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	rows := make([]any, len(entries))
+	for i, e := range entries {
+		rows[i] = e
+	}
+	h.writeCSV(w, r, "costs-"+safeFilename(v.Name), costEntryCSVColumns, rows)
+}
+
+// safeFilename reduces s to characters that are safe in a download filename.
+func safeFilename(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			out = append(out, r)
+		case r == ' ':
+			out = append(out, '-')
+		}
+	}
+	if len(out) == 0 {
+		return "vehicle"
+	}
+	return string(out)
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const emailVerificationTTL = 24 * time.Hour
+
+type changeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// ChangeEmail mails a confirmation link to a new address. The account keeps
+// its current address until the link is opened, so a typo cannot lock the
+// user out.
+func (h *Handler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var req changeEmailRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		h.errorResponse(w, http.StatusBadRequest, "email is required")
+		return
+	}
+	if !h.canSendVerification(w) {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if user.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+			h.errorResponse(w, http.StatusUnauthorized, "password is incorrect")
+			return
+		}
+	}
+	if req.Email == user.Email {
+		h.errorResponse(w, http.StatusBadRequest, "this is already your email address")
+		return
+	}
+	if _, err := h.store.GetUserByEmail(r.Context(), req.Email); err == nil {
+		h.errorResponse(w, http.StatusConflict, "email already registered")
+		return
+	} else if !errors.Is(err, store.ErrNotFound) {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	if !h.emailLimiter.Allow(userID) {
+		h.errorResponse(w, http.StatusTooManyRequests, "too many requests")
+		return
+	}
+	if err := h.sendEmailVerification(r, user, req.Email); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResendEmailVerification mails a new link to confirm the current address.
+func (h *Handler) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	if !h.canSendVerification(w) {
+		return
+	}
+	userID := middleware.GetUserID(r.Context())
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if user.EmailVerified {
+		h.errorResponse(w, http.StatusBadRequest, "email is already verified")
+		return
+	}
+	if !h.emailLimiter.Allow(userID) {
+		h.errorResponse(w, http.StatusTooManyRequests, "too many requests")
+		return
+	}
+	if err := h.sendEmailVerification(r, user, user.Email); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) canSendVerification(w http.ResponseWriter) bool {
+	if h.sendMail == nil || h.baseURL == "" {
+		h.errorResponse(w, http.StatusServiceUnavailable, "email is not configured")
+		return false
+	}
+	return true
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// VerifyEmail confirms an address with a token from a verification email.
+// For an email change it moves the account to the new address and tells
+// the old one.
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := h.readJSON(r, &req); err != nil || req.Token == "" {
+		h.errorResponse(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	v, err := h.store.ConsumeEmailVerification(r.Context(), hashToken(req.Token))
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !h.now().Before(v.ExpiresAt)) {
+		h.errorResponse(w, http.StatusBadRequest, "invalid or expired token")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), v.UserID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	oldEmail := user.Email
+	user.Email = v.Email
+	user.EmailVerified = true
+	if err := h.store.UpdateUser(r.Context(), user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			h.errorResponse(w, http.StatusConflict, "email already registered")
+			return
+		}
+		h.handleStoreError(w, err)
+		return
+	}
+
+	if oldEmail != user.Email {
+		h.logger.Info("email address changed", "user_id", user.ID)
+		subject, body := emailChangedEmail(preferredLanguage(r), user.Email)
+		h.mailInBackground(oldEmail, subject, body)
+	}
+	h.writeJSON(w, http.StatusOK, user)
+}
+
+// sendEmailVerification issues a token confirming address for user,
+// replacing any earlier one, and mails the link to address.
+func (h *Handler) sendEmailVerification(r *http.Request, user model.User, address string) error {
+	token, err := h.issueEmailVerification(r.Context(), user, address)
+	if err != nil {
+		return err
+	}
+	link := strings.TrimSuffix(h.baseURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+	subject, body := emailVerificationEmail(preferredLanguage(r), link, address != user.Email)
+	h.mailInBackground(address, subject, body)
+	return nil
+}
+
+func (h *Handler) issueEmailVerification(ctx context.Context, user model.User, address string) (string, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := h.now().UTC()
+	v := model.EmailVerification{
+		TokenHash: hash,
+		UserID:    user.ID.String(),
+		Email:     address,
+		CreatedAt: now,
+		ExpiresAt: now.Add(emailVerificationTTL),
+	}
+	if err := h.store.SetEmailVerification(ctx, v); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (h *Handler) mailInBackground(to, subject, body string) {
+	go func() {
+		if err := h.sendMail([]string{to}, subject, body); err != nil {
+			h.logger.Error("sending email", "subject", subject, "error", err)
+		}
+	}()
+}
+
+func emailVerificationEmail(lang, link string, change bool) (subject, body string) {
+	hours := int(emailVerificationTTL.Hours())
+	if lang == "de" {
+		intro := "bitte bestätigen Sie Ihre E-Mail-Adresse für Ihr Contracts-Konto"
+		if change {
+			intro = "bitte bestätigen Sie, dass Ihr Contracts-Konto künftig diese E-Mail-Adresse verwenden soll"
+		}
+		return "E-Mail-Adresse bestätigen",
+			fmt.Sprintf("Hallo,\n\n%s:\n\n%s\n\nDer Link ist %d Stunden gültig und kann nur einmal verwendet werden. Wenn Sie das nicht angefordert haben, können Sie diese E-Mail ignorieren.\n\nViele Grüße\nIhr Contracts-Team", intro, link, hours)
+	}
+	intro := "Please confirm the email address of your Contracts account"
+	if change {
+		intro = "Please confirm that your Contracts account should use this email address from now on"
+	}
+	return "Confirm your email address",
+		fmt.Sprintf("Hello,\n\n%s:\n\n%s\n\nThe link is valid for %d hours and can be used once. If you did not request this, you can ignore this email.\n\nBest regards,\nYour Contracts Team", intro, link, hours)
+}
+
+func emailChangedEmail(lang, newAddress string) (subject, body string) {
+	if lang == "de" {
+		return "E-Mail-Adresse geändert",
+			fmt.Sprintf("Hallo,\n\ndie E-Mail-Adresse Ihres Contracts-Kontos wurde in %s geändert. Wenn Sie das nicht waren, wenden Sie sich bitte an Ihren Administrator.\n\nViele Grüße\nIhr Contracts-Team", newAddress)
+	}
+	return "Email address changed",
+		fmt.Sprintf("Hello,\n\nthe email address of your Contracts account was changed to %s. If this was not you, please contact your administrator.\n\nBest regards,\nYour Contracts Team", newAddress)
+}
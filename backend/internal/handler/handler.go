@@ -5,9 +5,14 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/backup"
+	"github.com/tobi/contracts/backend/internal/config"
 	"github.com/tobi/contracts/backend/internal/email"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/oidc"
 	"github.com/tobi/contracts/backend/internal/store"
 )
 
@@ -16,15 +21,65 @@ type Handler struct {
 	logger      *slog.Logger
 	jwtSecret   []byte
 	emailClient *email.Client
+	now         func() time.Time
+	baseURL     string
+	// registration is one of the config.Registration* modes.
+	registration string
+	// oidc is the single sign-on provider; nil when OIDC is not configured.
+	oidc        *oidc.Provider
+	oidcDomains []string
+	// backups takes database backups; nil when BACKUP_DIR is not set.
+	backups *backup.Manager
+
+	// sendMail delivers an email; nil when SMTP is not configured.
+	sendMail     func(to []string, subject, body string) error
+	resetLimiter *middleware.Limiter
+	emailLimiter *middleware.Limiter
 }
 
 func New(s store.Store, logger *slog.Logger, jwtSecret []byte, emailClient *email.Client) *Handler {
-	return &Handler{
-		store:       s,
-		logger:      logger,
-		jwtSecret:   jwtSecret,
-		emailClient: emailClient,
+	h := &Handler{
+		store:        s,
+		logger:       logger,
+		jwtSecret:    jwtSecret,
+		emailClient:  emailClient,
+		now:          time.Now,
+		registration: config.RegistrationOpen,
+		resetLimiter: middleware.NewLimiter(3, time.Hour),
+		emailLimiter: middleware.NewLimiter(3, time.Hour),
+	}
+	if emailClient != nil && emailClient.IsConfigured() {
+		h.sendMail = emailClient.Send
 	}
+	return h
+}
+
+// SetBaseURL sets the public base URL used for links that leave the app,
+// such as calendar feed URLs.
+func (h *Handler) SetBaseURL(u string) {
+	h.baseURL = u
+}
+
+// SetRegistration sets who may register; see config.Config.Registration.
+func (h *Handler) SetRegistration(mode string) {
+	h.registration = mode
+}
+
+// SetOIDC enables single sign-on with p for users whose email is in one of
+// domains, or for everyone when domains is empty.
+func (h *Handler) SetOIDC(p *oidc.Provider, domains []string) {
+	h.oidc = p
+	h.oidcDomains = domains
+}
+
+// SetBackups enables on-demand backups through the admin API.
+func (h *Handler) SetBackups(m *backup.Manager) {
+	h.backups = m
+}
+
+// today returns the current date in UTC according to the handler's clock.
+func (h *Handler) today() time.Time {
+	return h.now().UTC().Truncate(24 * time.Hour)
 }
 
 func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
@@ -4,17 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"log/slog"
+	"mime/multipart"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tobi/contracts/backend/internal/archive"
+	"github.com/tobi/contracts/backend/internal/backup"
+	"github.com/tobi/contracts/backend/internal/config"
 	"github.com/tobi/contracts/backend/internal/middleware"
 	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/oidc"
+	"github.com/tobi/contracts/backend/internal/oidc/oidctest"
 	"github.com/tobi/contracts/backend/internal/store"
+	"github.com/tobi/contracts/backend/internal/totp"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -22,18 +39,44 @@ import (
 type mockStore struct {
 	categories map[string]map[uuid.UUID]model.Category // keyed by module, then ID
 	contracts  map[uuid.UUID]model.Contract
+	prices     map[uuid.UUID]model.PriceEntry
 	users      map[string]model.User // keyed by email
 	usersById  map[string]model.User // keyed by ID
 	settings   map[string]model.UserSettings
+	feedTokens map[string]model.FeedToken // keyed by user ID
+	sessions   map[uuid.UUID]model.Session
+	resets     map[string]model.PasswordResetToken // keyed by user ID
+	twoFactor  map[string]model.TwoFactor          // keyed by user ID
+	apiKeys    map[uuid.UUID]model.APIKey
+	workspaces map[uuid.UUID]model.Workspace
+	members    map[uuid.UUID]map[string]model.WorkspaceMember // keyed by workspace, then user ID
+	invites    map[uuid.UUID]model.Invitation
+	attempts   map[string]model.LoginAttempts
+	verifies   map[string]model.EmailVerification // keyed by user ID
 }
 
 func newMockStore() *mockStore {
 	return &mockStore{
 		categories: make(map[string]map[uuid.UUID]model.Category),
 		contracts:  make(map[uuid.UUID]model.Contract),
+		prices:     make(map[uuid.UUID]model.PriceEntry),
 		users:      make(map[string]model.User),
 		usersById:  make(map[string]model.User),
 		settings:   make(map[string]model.UserSettings),
+		feedTokens: make(map[string]model.FeedToken),
+		sessions:   make(map[uuid.UUID]model.Session),
+		resets:     make(map[string]model.PasswordResetToken),
+		twoFactor:  make(map[string]model.TwoFactor),
+		apiKeys:    make(map[uuid.UUID]model.APIKey),
+		workspaces: map[uuid.UUID]model.Workspace{
+			testWorkspace.WorkspaceID: {ID: testWorkspace.WorkspaceID, Name: "Personal"},
+		},
+		members: map[uuid.UUID]map[string]model.WorkspaceMember{
+			testWorkspace.WorkspaceID: {testUserID: testWorkspace},
+		},
+		invites:  make(map[uuid.UUID]model.Invitation),
+		attempts: make(map[string]model.LoginAttempts),
+		verifies: make(map[string]model.EmailVerification),
 	}
 }
 
@@ -70,9 +113,16 @@ func (m *mockStore) GetUserByID(_ context.Context, id string) (model.User, error
 }
 
 func (m *mockStore) UpdateUser(_ context.Context, u model.User) error {
-	if _, ok := m.usersById[u.ID.String()]; !ok {
+	old, ok := m.usersById[u.ID.String()]
+	if !ok {
 		return store.ErrNotFound
 	}
+	if old.Email != u.Email {
+		if _, taken := m.users[u.Email]; taken {
+			return store.ErrConflict
+		}
+		delete(m.users, old.Email)
+	}
 	m.usersById[u.ID.String()] = u
 	m.users[u.Email] = u
 	return nil
@@ -91,6 +141,359 @@ func (m *mockStore) UpdateSettings(_ context.Context, userID string, s model.Use
 	return nil
 }
 
+func (m *mockStore) GetFeedToken(_ context.Context, userID string) (model.FeedToken, error) {
+	t, ok := m.feedTokens[userID]
+	if !ok {
+		return model.FeedToken{}, store.ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *mockStore) SetFeedToken(_ context.Context, userID string, t model.FeedToken) error {
+	m.feedTokens[userID] = t
+	return nil
+}
+
+func (m *mockStore) DeleteFeedToken(_ context.Context, userID string) error {
+	if _, ok := m.feedTokens[userID]; !ok {
+		return store.ErrNotFound
+	}
+	delete(m.feedTokens, userID)
+	return nil
+}
+
+func (m *mockStore) GetUserIDByFeedToken(_ context.Context, tokenHash string) (string, error) {
+	for userID, t := range m.feedTokens {
+		if t.TokenHash == tokenHash {
+			return userID, nil
+		}
+	}
+	return "", store.ErrNotFound
+}
+
+func (m *mockStore) GetTwoFactor(_ context.Context, userID string) (model.TwoFactor, error) {
+	t, ok := m.twoFactor[userID]
+	if !ok {
+		return model.TwoFactor{}, store.ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *mockStore) SetTwoFactor(_ context.Context, userID string, t model.TwoFactor) error {
+	m.twoFactor[userID] = t
+	return nil
+}
+
+func (m *mockStore) UseTwoFactorStep(_ context.Context, userID string, step int64) error {
+	tf, ok := m.twoFactor[userID]
+	if !ok || !tf.Enabled || step <= tf.LastUsedStep {
+		return store.ErrNotFound
+	}
+	tf.LastUsedStep = step
+	m.twoFactor[userID] = tf
+	return nil
+}
+
+func (m *mockStore) ConsumeRecoveryCode(_ context.Context, userID, codeHash string) error {
+	tf, ok := m.twoFactor[userID]
+	if !ok || !tf.Enabled {
+		return store.ErrNotFound
+	}
+	i := slices.Index(tf.RecoveryCodes, codeHash)
+	if i < 0 {
+		return store.ErrNotFound
+	}
+	tf.RecoveryCodes = slices.Delete(slices.Clone(tf.RecoveryCodes), i, i+1)
+	m.twoFactor[userID] = tf
+	return nil
+}
+
+func (m *mockStore) DeleteTwoFactor(_ context.Context, userID string) error {
+	if _, ok := m.twoFactor[userID]; !ok {
+		return store.ErrNotFound
+	}
+	delete(m.twoFactor, userID)
+	return nil
+}
+
+func (m *mockStore) SetPasswordResetToken(_ context.Context, t model.PasswordResetToken) error {
+	m.resets[t.UserID] = t
+	return nil
+}
+
+func (m *mockStore) SetEmailVerification(_ context.Context, v model.EmailVerification) error {
+	m.verifies[v.UserID] = v
+	return nil
+}
+
+func (m *mockStore) ConsumeEmailVerification(_ context.Context, tokenHash string) (model.EmailVerification, error) {
+	for userID, v := range m.verifies {
+		if v.TokenHash == tokenHash {
+			delete(m.verifies, userID)
+			return v, nil
+		}
+	}
+	return model.EmailVerification{}, store.ErrNotFound
+}
+
+func (m *mockStore) GetLoginAttempts(_ context.Context, key string) (model.LoginAttempts, error) {
+	a, ok := m.attempts[key]
+	if !ok {
+		return a, store.ErrNotFound
+	}
+	return a, nil
+}
+
+func (m *mockStore) IncrementLoginAttempts(_ context.Context, key string, now time.Time, window time.Duration, lock func(int) time.Duration) (model.LoginAttempts, error) {
+	a := m.attempts[key]
+	if !now.Before(a.ExpiresAt) {
+		a = model.LoginAttempts{Key: key}
+	}
+	if a.LockedUntil.After(now) {
+		return a, store.ErrLockedOut
+	}
+	a.Failures++
+	if d := lock(a.Failures); d > 0 {
+		a.LockedUntil = now.Add(d)
+	}
+	a.ExpiresAt = now.Add(window)
+	if a.LockedUntil.After(a.ExpiresAt) {
+		a.ExpiresAt = a.LockedUntil
+	}
+	m.attempts[key] = a
+	return a, nil
+}
+
+func (m *mockStore) DecrementLoginAttempts(_ context.Context, counted model.LoginAttempts) error {
+	a, ok := m.attempts[counted.Key]
+	if !ok || a.Failures == 0 {
+		return nil
+	}
+	a.Failures--
+	if a.LockedUntil.Equal(counted.LockedUntil) {
+		a.LockedUntil = time.Time{}
+	}
+	m.attempts[a.Key] = a
+	return nil
+}
+
+func (m *mockStore) DeleteLoginAttempts(_ context.Context, key string) error {
+	delete(m.attempts, key)
+	return nil
+}
+
+func (m *mockStore) ConsumePasswordResetToken(_ context.Context, tokenHash string) (model.PasswordResetToken, error) {
+	for userID, t := range m.resets {
+		if t.TokenHash == tokenHash {
+			delete(m.resets, userID)
+			return t, nil
+		}
+	}
+	return model.PasswordResetToken{}, store.ErrNotFound
+}
+
+func (m *mockStore) CreateSession(_ context.Context, s model.Session) error {
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *mockStore) GetSession(_ context.Context, id uuid.UUID) (model.Session, error) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return model.Session{}, store.ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *mockStore) UpdateSession(_ context.Context, s model.Session) error {
+	if _, ok := m.sessions[s.ID]; !ok {
+		return store.ErrNotFound
+	}
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *mockStore) RotateSession(_ context.Context, s model.Session, oldHash string) error {
+	current, ok := m.sessions[s.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if current.RefreshTokenHash != oldHash {
+		return store.ErrTokenReused
+	}
+	m.sessions[s.ID] = s
+	return nil
+}
+
+func (m *mockStore) ListSessions(_ context.Context, userID string) ([]model.Session, error) {
+	var out []model.Session
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) DeleteSession(_ context.Context, userID string, id uuid.UUID) error {
+	if s, ok := m.sessions[id]; !ok || s.UserID != userID {
+		return store.ErrNotFound
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *mockStore) CreateAPIKey(_ context.Context, k model.APIKey) error {
+	m.apiKeys[k.ID] = k
+	return nil
+}
+
+func (m *mockStore) GetAPIKeyByHash(_ context.Context, keyHash string) (model.APIKey, error) {
+	for _, k := range m.apiKeys {
+		if k.KeyHash == keyHash {
+			return k, nil
+		}
+	}
+	return model.APIKey{}, store.ErrNotFound
+}
+
+func (m *mockStore) UpdateAPIKey(_ context.Context, k model.APIKey) error {
+	if _, ok := m.apiKeys[k.ID]; !ok {
+		return store.ErrNotFound
+	}
+	m.apiKeys[k.ID] = k
+	return nil
+}
+
+func (m *mockStore) ListAPIKeys(_ context.Context, userID string) ([]model.APIKey, error) {
+	out := []model.APIKey{}
+	for _, k := range m.apiKeys {
+		if k.UserID == userID {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) DeleteAPIKey(_ context.Context, userID string, id uuid.UUID) error {
+	if k, ok := m.apiKeys[id]; !ok || k.UserID != userID {
+		return store.ErrNotFound
+	}
+	delete(m.apiKeys, id)
+	return nil
+}
+
+func (m *mockStore) CreateWorkspace(_ context.Context, ws model.Workspace, owner model.WorkspaceMember) error {
+	m.workspaces[ws.ID] = ws
+	m.members[ws.ID] = map[string]model.WorkspaceMember{owner.UserID: owner}
+	return nil
+}
+
+func (m *mockStore) GetWorkspace(_ context.Context, id uuid.UUID) (model.Workspace, error) {
+	ws, ok := m.workspaces[id]
+	if !ok {
+		return model.Workspace{}, store.ErrNotFound
+	}
+	return ws, nil
+}
+
+func (m *mockStore) UpdateWorkspace(_ context.Context, ws model.Workspace) error {
+	if _, ok := m.workspaces[ws.ID]; !ok {
+		return store.ErrNotFound
+	}
+	m.workspaces[ws.ID] = ws
+	return nil
+}
+
+func (m *mockStore) DeleteWorkspace(_ context.Context, id uuid.UUID) error {
+	if _, ok := m.workspaces[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(m.workspaces, id)
+	delete(m.members, id)
+	for invID, inv := range m.invites {
+		if inv.WorkspaceID == id {
+			delete(m.invites, invID)
+		}
+	}
+	return nil
+}
+
+func (m *mockStore) ListWorkspaceMemberships(_ context.Context, userID string) ([]model.WorkspaceMember, error) {
+	out := []model.WorkspaceMember{}
+	for _, members := range m.members {
+		if mem, ok := members[userID]; ok {
+			out = append(out, mem)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) GetWorkspaceMember(_ context.Context, workspaceID uuid.UUID, userID string) (model.WorkspaceMember, error) {
+	mem, ok := m.members[workspaceID][userID]
+	if !ok {
+		return model.WorkspaceMember{}, store.ErrNotFound
+	}
+	return mem, nil
+}
+
+func (m *mockStore) ListWorkspaceMembers(_ context.Context, workspaceID uuid.UUID) ([]model.WorkspaceMember, error) {
+	out := []model.WorkspaceMember{}
+	for _, mem := range m.members[workspaceID] {
+		out = append(out, mem)
+	}
+	return out, nil
+}
+
+func (m *mockStore) SetWorkspaceMember(_ context.Context, mem model.WorkspaceMember) error {
+	if _, ok := m.workspaces[mem.WorkspaceID]; !ok {
+		return store.ErrNotFound
+	}
+	m.members[mem.WorkspaceID][mem.UserID] = mem
+	return nil
+}
+
+func (m *mockStore) DeleteWorkspaceMember(_ context.Context, workspaceID uuid.UUID, userID string) error {
+	if _, ok := m.members[workspaceID][userID]; !ok {
+		return store.ErrNotFound
+	}
+	delete(m.members[workspaceID], userID)
+	return nil
+}
+
+func (m *mockStore) CreateInvitation(_ context.Context, inv model.Invitation) error {
+	m.invites[inv.ID] = inv
+	return nil
+}
+
+func (m *mockStore) ListInvitations(_ context.Context, workspaceID uuid.UUID) ([]model.Invitation, error) {
+	out := []model.Invitation{}
+	for _, inv := range m.invites {
+		if inv.WorkspaceID == workspaceID {
+			out = append(out, inv)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) DeleteInvitation(_ context.Context, workspaceID, id uuid.UUID) error {
+	if inv, ok := m.invites[id]; !ok || inv.WorkspaceID != workspaceID {
+		return store.ErrNotFound
+	}
+	delete(m.invites, id)
+	return nil
+}
+
+func (m *mockStore) ConsumeInvitation(_ context.Context, tokenHash string) (model.Invitation, error) {
+	for id, inv := range m.invites {
+		if inv.TokenHash == tokenHash {
+			delete(m.invites, id)
+			return inv, nil
+		}
+	}
+	return model.Invitation{}, store.ErrNotFound
+}
+
 func (m *mockStore) ListCategories(_ context.Context, _ string, module string) ([]model.Category, error) {
 	modCats := m.categories[module]
 	out := make([]model.Category, 0, len(modCats))
@@ -171,11 +574,14 @@ func (m *mockStore) CreateContract(_ context.Context, _ string, c model.Contract
 	return nil
 }
 
-func (m *mockStore) UpdateContract(_ context.Context, _ string, c model.Contract) error {
+func (m *mockStore) UpdateContract(_ context.Context, _ string, c model.Contract, prices ...model.PriceEntry) error {
 	if _, ok := m.contracts[c.ID]; !ok {
 		return store.ErrNotFound
 	}
 	m.contracts[c.ID] = c
+	for _, p := range prices {
+		m.prices[p.ID] = p
+	}
 	return nil
 }
 
@@ -187,6 +593,48 @@ func (m *mockStore) DeleteContract(_ context.Context, _ string, id uuid.UUID) er
 	return nil
 }
 
+func (m *mockStore) ListPriceEntries(_ context.Context, _ string) ([]model.PriceEntry, error) {
+	out := make([]model.PriceEntry, 0, len(m.prices))
+	for _, p := range m.prices {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (m *mockStore) ListPriceEntriesByContract(_ context.Context, _ string, contractID uuid.UUID) ([]model.PriceEntry, error) {
+	out := []model.PriceEntry{}
+	for _, p := range m.prices {
+		if p.ContractID == contractID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockStore) GetPriceEntry(_ context.Context, _ string, id uuid.UUID) (model.PriceEntry, error) {
+	p, ok := m.prices[id]
+	if !ok {
+		return p, store.ErrNotFound
+	}
+	return p, nil
+}
+
+func (m *mockStore) CreatePriceEntry(_ context.Context, _ string, p model.PriceEntry) error {
+	if _, ok := m.contracts[p.ContractID]; !ok {
+		return store.ErrNotFound
+	}
+	m.prices[p.ID] = p
+	return nil
+}
+
+func (m *mockStore) DeletePriceEntry(_ context.Context, _ string, id uuid.UUID) error {
+	if _, ok := m.prices[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(m.prices, id)
+	return nil
+}
+
 func (m *mockStore) ListUsers(_ context.Context) ([]model.User, error) {
 	out := make([]model.User, 0, len(m.usersById))
 	for _, u := range m.usersById {
@@ -195,6 +643,28 @@ func (m *mockStore) ListUsers(_ context.Context) ([]model.User, error) {
 	return out, nil
 }
 
+func (m *mockStore) DeleteUser(_ context.Context, id string) error {
+	u, ok := m.usersById[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	delete(m.usersById, id)
+	delete(m.users, u.Email)
+	delete(m.settings, id)
+	delete(m.twoFactor, id)
+	for sid, sess := range m.sessions {
+		if sess.UserID == id {
+			delete(m.sessions, sid)
+		}
+	}
+	for kid, k := range m.apiKeys {
+		if k.UserID == id {
+			delete(m.apiKeys, kid)
+		}
+	}
+	return nil
+}
+
 func (m *mockStore) Close() error { return nil }
 
 func (m *mockStore) ListPurchases(_ context.Context, _ string) ([]model.Purchase, error) {
@@ -230,37 +700,243 @@ func (m *mockStore) CreateCostEntry(_ context.Context, _ string, _ model.CostEnt
 func (m *mockStore) UpdateCostEntry(_ context.Context, _ string, _ model.CostEntry) error { return nil }
 func (m *mockStore) DeleteCostEntry(_ context.Context, _ string, _ uuid.UUID) error       { return nil }
 
+func (m *mockStore) ListAuditEntries(_ context.Context, _ string, _ uuid.UUID) ([]model.AuditEntry, error) {
+	return nil, nil
+}
+func (m *mockStore) ListActivity(_ context.Context, _ string, _ uuid.UUID, _ int) ([]model.AuditEntry, error) {
+	return nil, nil
+}
+
+func (m *mockStore) ListTrash(_ context.Context, _ string) ([]model.TrashItem, error) {
+	return []model.TrashItem{}, nil
+}
+
+func (m *mockStore) RestoreFromTrash(_ context.Context, _, _ string, _ uuid.UUID) error {
+	return store.ErrNotFound
+}
+
+func (m *mockStore) PurgeFromTrash(_ context.Context, _, _ string, _ uuid.UUID) error {
+	return store.ErrNotFound
+}
+
+func (m *mockStore) PurgeTrash(_ context.Context, _ time.Time) (int, error) {
+	return 0, nil
+}
+
 var testJWTSecret = []byte("test-secret-key")
 
 const testUserID = "00000000-0000-0000-0000-000000000001"
 
+var testWorkspace = model.WorkspaceMember{
+	WorkspaceID: uuid.MustParse("00000000-0000-0000-0000-0000000000a1"),
+	UserID:      testUserID,
+	Role:        model.WorkspaceRoleOwner,
+}
+
 func newTestHandler() (*Handler, *mockStore) {
 	ms := newMockStore()
 	h := New(ms, slog.Default(), testJWTSecret, nil)
 	return h, ms
 }
 
+// testStores open an empty store of each implementation.
+var testStores = []struct {
+	name string
+	open func(t *testing.T) store.Store
+}{
+	{"mock", func(*testing.T) store.Store { return newMockStore() }},
+	{"badger", func(t *testing.T) store.Store {
+		s, err := store.NewBadgerStore(t.TempDir(), nil, slog.Default())
+		if err != nil {
+			t.Fatalf("NewBadgerStore: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	}},
+	{"sqlite", func(t *testing.T) store.Store {
+		s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "contracts.db"), slog.Default())
+		if err != nil {
+			t.Fatalf("NewSQLiteStore: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	}},
+}
+
+// storeTests only go through the HTTP API and the Store interface, so they
+// run against every store implementation.
+var storeTests = []struct {
+	name string
+	run  func(t *testing.T, h *Handler)
+}{
+	{"RegisterThenLogin", testRegisterThenLogin},
+	{"Login_WrongPassword", testLogin_WrongPassword},
+	{"Register_DuplicateEmail", testRegister_DuplicateEmail},
+	{"CreateCategory_Success", testCreateCategory_Success},
+	{"CreateCategory_EmptyName", testCreateCategory_EmptyName},
+	{"CreateCategory_InvalidJSON", testCreateCategory_InvalidJSON},
+	{"CreateCategory_UnknownField", testCreateCategory_UnknownField},
+	{"GetCategory_NotFound", testGetCategory_NotFound},
+	{"GetCategory_InvalidUUID", testGetCategory_InvalidUUID},
+	{"ListCategories_Empty", testListCategories_Empty},
+	{"UpdateCategory_NotFound", testUpdateCategory_NotFound},
+	{"DeleteCategory_NotFound", testDeleteCategory_NotFound},
+	{"CreateContract_CategoryNotFound", testCreateContract_CategoryNotFound},
+	{"GetContract_NotFound", testGetContract_NotFound},
+	{"GetContract_InvalidUUID", testGetContract_InvalidUUID},
+	{"DeleteContract_NotFound", testDeleteContract_NotFound},
+	{"UpdateContract_NotFound", testUpdateContract_NotFound},
+	{"Responses_HaveJSONContentType", testResponses_HaveJSONContentType},
+	{"GetSettings_ReturnsDefaults", testGetSettings_ReturnsDefaults},
+	{"UpdateSettings_Success", testUpdateSettings_Success},
+	{"UpdateSettings_InvalidRange", testUpdateSettings_InvalidRange},
+	{"ChangePassword_MissingFields", testChangePassword_MissingFields},
+	{"UpdateSettings_InvalidReminderFrequency", testUpdateSettings_InvalidReminderFrequency},
+	{"Summary_InvalidDate", testSummary_InvalidDate},
+	{"TransitionContract_NotFound", testTransitionContract_NotFound},
+	{"ImportContractsCSV_BadRequest", testImportContractsCSV_BadRequest},
+	{"ForgotPassword_Localized", testForgotPassword_Localized},
+	{"ForgotPassword_RateLimitedPerEmail", testForgotPassword_RateLimitedPerEmail},
+	{"ResendEmailVerification", testResendEmailVerification},
+	{"VerifyEmail_ConflictAndExpiry", testVerifyEmail_ConflictAndExpiry},
+	{"OIDC_RejectsForgedState", testOIDC_RejectsForgedState},
+	{"Login_LocksOutIP", testLogin_LocksOutIP},
+	{"Login_SuccessesNotCountedAgainstIP", testLogin_SuccessesNotCountedAgainstIP},
+	{"Register_LimitedPerIP", testRegister_LimitedPerIP},
+	{"ContractHistory_NotFound", testContractHistory_NotFound},
+	{"RestoreFromTrash_BadRequest", testRestoreFromTrash_BadRequest},
+	{"DeleteCategory_Success", testDeleteCategory_Success},
+	{"CreateContract_Success", testCreateContract_Success},
+	{"CreateContract_MissingName", testCreateContract_MissingName},
+	{"CreateContract_MissingStartDate", testCreateContract_MissingStartDate},
+	{"DeleteContract_Success", testDeleteContract_Success},
+	{"CreatePriceEntry_SeedsBasePrice", testCreatePriceEntry_SeedsBasePrice},
+	{"CreatePriceEntry_FutureDateKeepsCurrentPrice", testCreatePriceEntry_FutureDateKeepsCurrentPrice},
+	{"CreatePriceEntry_InvalidInput", testCreatePriceEntry_InvalidInput},
+	{"UpdateContract_PriceChangeRecordsHistory", testUpdateContract_PriceChangeRecordsHistory},
+	{"UpdateContract_ClearPrice", testUpdateContract_ClearPrice},
+	{"DeletePriceEntry_WrongContract", testDeletePriceEntry_WrongContract},
+	{"ImportContracts_NormalizesBillingInterval", testImportContracts_NormalizesBillingInterval},
+	{"Summary_NormalizesBillingIntervals", testSummary_NormalizesBillingIntervals},
+	{"CreateContract_InvalidBillingInterval", testCreateContract_InvalidBillingInterval},
+	{"TransitionContract_Workflow", testTransitionContract_Workflow},
+	{"TransitionContract_InvalidStatus", testTransitionContract_InvalidStatus},
+	{"UpcomingRenewals_SkipsCancellationInProgress", testUpcomingRenewals_SkipsCancellationInProgress},
+	{"CreateContract_DurationUnitsAndAnchors", testCreateContract_DurationUnitsAndAnchors},
+	{"Contracts_AcceptMonthNamedDurations", testContracts_AcceptMonthNamedDurations},
+	{"UpcomingRenewals_UsesInjectedClock", testUpcomingRenewals_UsesInjectedClock},
+	{"ContractTimeline", testContractTimeline},
+	{"ContractTimeline_InvalidParams", testContractTimeline_InvalidParams},
+	{"ExportAccount", testExportAccount},
+	{"RestoreAccount_Merge", testRestoreAccount_Merge},
+	{"RestoreAccount_BadRequest", testRestoreAccount_BadRequest},
+	{"ImportContractsCSV", testImportContractsCSV},
+	{"ExportContractsCSV", testExportContractsCSV},
+	{"PreviewContractImport", testPreviewContractImport},
+	{"ImportContracts_SelectedRows", testImportContracts_SelectedRows},
+	{"ImportContracts_MatchBy", testImportContracts_MatchBy},
+	{"ImportContracts_DuplicateRowsInFile", testImportContracts_DuplicateRowsInFile},
+	{"Sessions_ListAndRevoke", testSessions_ListAndRevoke},
+	{"Workspaces_InviteAndRoles", testWorkspaces_InviteAndRoles},
+	{"Workspaces_DefaultsToPersonal", testWorkspaces_DefaultsToPersonal},
+}
+
+// persistentStoreTests need purchases, vehicles, the trash and separate
+// workspaces, which the mock does not keep, so they only run against the
+// real store implementations.
+var persistentStoreTests = []struct {
+	name string
+	run  func(t *testing.T, h *Handler)
+}{
+	{"Trash_RestoreAndPurge", testTrash_RestoreAndPurge},
+	{"Workspaces_IsolateData", testWorkspaces_IsolateData},
+	{"Purchases_CreateUpdateDelete", testPurchases_CreateUpdateDelete},
+	{"Vehicles_CostEntries", testVehicles_CostEntries},
+	{"Login_ParallelGuessesLocked", testLogin_ParallelGuessesLocked},
+}
+
+func TestStores(t *testing.T) {
+	for _, st := range testStores {
+		t.Run(st.name, func(t *testing.T) {
+			tests := storeTests
+			if st.name != "mock" {
+				tests = append(slices.Clip(tests), persistentStoreTests...)
+			}
+			for _, tc := range tests {
+				t.Run(tc.name, func(t *testing.T) {
+					tc.run(t, New(st.open(t), slog.Default(), testJWTSecret, nil))
+				})
+			}
+		})
+	}
+}
+
 func newMux(h *Handler) http.Handler {
+	return newWorkspaceDataMux(h, testWorkspace)
+}
+
+// newWorkspaceDataMux serves the data API as the test user acting in the
+// workspace of member.
+func newWorkspaceDataMux(h *Handler, member model.WorkspaceMember) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /api/v1/modules/{module}/categories", h.ListCategories)
 	mux.HandleFunc("POST /api/v1/modules/{module}/categories", h.CreateCategory)
 	mux.HandleFunc("GET /api/v1/modules/{module}/categories/{id}", h.GetCategory)
 	mux.HandleFunc("PUT /api/v1/modules/{module}/categories/{id}", h.UpdateCategory)
 	mux.HandleFunc("DELETE /api/v1/modules/{module}/categories/{id}", h.DeleteCategory)
+	mux.HandleFunc("GET /api/v1/modules/{module}/categories/{id}/history", h.CategoryHistory)
+	mux.HandleFunc("POST /api/v1/modules/{module}/categories/{id}/history/{revision}/revert", h.RevertCategory)
 	mux.HandleFunc("GET /api/v1/categories/{id}/contracts", h.ListContractsByCategory)
 	mux.HandleFunc("POST /api/v1/categories/{id}/contracts", h.CreateContractInCategory)
+	mux.HandleFunc("POST /api/v1/contracts/import/preview", h.PreviewContractImport)
+	mux.HandleFunc("POST /api/v1/contracts/import/csv", h.ImportContractsCSV)
+	mux.HandleFunc("GET /api/v1/contracts/export/csv", h.ExportContractsCSV)
 	mux.HandleFunc("GET /api/v1/contracts/upcoming-renewals", h.UpcomingRenewals)
+	mux.HandleFunc("POST /api/v1/contracts/import", h.ImportContracts)
 	mux.HandleFunc("GET /api/v1/contracts", h.ListContracts)
 	mux.HandleFunc("GET /api/v1/contracts/{id}", h.GetContract)
 	mux.HandleFunc("PUT /api/v1/contracts/{id}", h.UpdateContract)
 	mux.HandleFunc("DELETE /api/v1/contracts/{id}", h.DeleteContract)
+	mux.HandleFunc("POST /api/v1/contracts/{id}/transition", h.TransitionContract)
+	mux.HandleFunc("GET /api/v1/contracts/{id}/timeline", h.ContractTimeline)
+	mux.HandleFunc("GET /api/v1/contracts/{id}/prices", h.ListPriceEntries)
+	mux.HandleFunc("POST /api/v1/contracts/{id}/prices", h.CreatePriceEntry)
+	mux.HandleFunc("DELETE /api/v1/contracts/{id}/prices/{priceId}", h.DeletePriceEntry)
+	mux.HandleFunc("GET /api/v1/contracts/{id}/history", h.ContractHistory)
+	mux.HandleFunc("POST /api/v1/contracts/{id}/history/{revision}/revert", h.RevertContract)
+	mux.HandleFunc("GET /api/v1/categories/{id}/purchases", h.ListPurchasesByCategory)
+	mux.HandleFunc("POST /api/v1/categories/{id}/purchases", h.CreatePurchaseInCategory)
+	mux.HandleFunc("GET /api/v1/purchases/summary", h.PurchaseSummary)
+	mux.HandleFunc("GET /api/v1/purchases", h.ListPurchases)
+	mux.HandleFunc("GET /api/v1/purchases/{id}", h.GetPurchase)
+	mux.HandleFunc("PUT /api/v1/purchases/{id}", h.UpdatePurchase)
+	mux.HandleFunc("DELETE /api/v1/purchases/{id}", h.DeletePurchase)
+	mux.HandleFunc("GET /api/v1/vehicles", h.ListVehicles)
+	mux.HandleFunc("POST /api/v1/vehicles", h.CreateVehicle)
+	mux.HandleFunc("GET /api/v1/vehicles/{id}", h.GetVehicle)
+	mux.HandleFunc("DELETE /api/v1/vehicles/{id}", h.DeleteVehicle)
+	mux.HandleFunc("GET /api/v1/vehicles/{id}/costs", h.ListCostEntries)
+	mux.HandleFunc("POST /api/v1/vehicles/{id}/costs", h.CreateCostEntry)
+	mux.HandleFunc("GET /api/v1/costs/{id}", h.GetCostEntry)
+	mux.HandleFunc("PUT /api/v1/costs/{id}", h.UpdateCostEntry)
+	mux.HandleFunc("DELETE /api/v1/costs/{id}", h.DeleteCostEntry)
 	mux.HandleFunc("GET /api/v1/summary", h.Summary)
+	mux.HandleFunc("GET /api/v1/activity", h.Activity)
+	mux.HandleFunc("GET /api/v1/trash", h.ListTrash)
+	mux.HandleFunc("POST /api/v1/trash/{type}/{id}/restore", h.RestoreFromTrash)
+	mux.HandleFunc("DELETE /api/v1/trash/{type}/{id}", h.PurgeFromTrash)
 	mux.HandleFunc("GET /api/v1/settings", h.GetSettings)
 	mux.HandleFunc("PUT /api/v1/settings", h.UpdateSettings)
 	mux.HandleFunc("PUT /api/v1/settings/password", h.ChangePassword)
-	// Inject test user into context for all requests
+	mux.HandleFunc("GET /api/v1/settings/calendar-feed", h.GetCalendarFeed)
+	mux.HandleFunc("POST /api/v1/settings/calendar-feed", h.RotateCalendarFeed)
+	mux.HandleFunc("DELETE /api/v1/settings/calendar-feed", h.RevokeCalendarFeed)
+	mux.HandleFunc("GET /api/v1/export", h.ExportAccount)
+	mux.HandleFunc("POST /api/v1/restore", h.RestoreAccount)
+	// Inject test user and workspace into context for all requests
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := middleware.SetUserID(r.Context(), testUserID)
+		ctx = middleware.SetWorkspace(ctx, member)
 		mux.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -285,6 +961,8 @@ func newAuthMux(h *Handler) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /api/v1/auth/register", h.Register)
 	mux.HandleFunc("POST /api/v1/auth/login", h.Login)
+	mux.HandleFunc("POST /api/v1/auth/login/2fa", h.LoginTwoFactor)
+	mux.HandleFunc("POST /api/v1/auth/refresh", h.Refresh)
 	return mux
 }
 
@@ -308,8 +986,7 @@ func TestRegister_SeedsDefaultCategories(t *testing.T) {
 	}
 }
 
-func TestRegisterThenLogin(t *testing.T) {
-	h, _ := newTestHandler()
+func testRegisterThenLogin(t *testing.T, h *Handler) {
 	mux := newAuthMux(h)
 
 	creds := map[string]string{"email": "test@example.com", "password": "secret123"}
@@ -345,8 +1022,7 @@ func TestRegisterThenLogin(t *testing.T) {
 	}
 }
 
-func TestLogin_WrongPassword(t *testing.T) {
-	h, _ := newTestHandler()
+func testLogin_WrongPassword(t *testing.T, h *Handler) {
 	mux := newAuthMux(h)
 
 	// Register
@@ -366,8 +1042,7 @@ func TestLogin_WrongPassword(t *testing.T) {
 	}
 }
 
-func TestRegister_DuplicateEmail(t *testing.T) {
-	h, _ := newTestHandler()
+func testRegister_DuplicateEmail(t *testing.T, h *Handler) {
 	mux := newAuthMux(h)
 
 	creds := map[string]string{"email": "dup@test.com", "password": "pass"}
@@ -389,8 +1064,7 @@ func TestRegister_DuplicateEmail(t *testing.T) {
 
 // Category handler tests
 
-func TestCreateCategory_Success(t *testing.T) {
-	h, _ := newTestHandler()
+func testCreateCategory_Success(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -410,8 +1084,7 @@ func TestCreateCategory_Success(t *testing.T) {
 	}
 }
 
-func TestCreateCategory_EmptyName(t *testing.T) {
-	h, _ := newTestHandler()
+func testCreateCategory_EmptyName(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -423,8 +1096,7 @@ func TestCreateCategory_EmptyName(t *testing.T) {
 	}
 }
 
-func TestCreateCategory_InvalidJSON(t *testing.T) {
-	h, _ := newTestHandler()
+func testCreateCategory_InvalidJSON(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -436,8 +1108,7 @@ func TestCreateCategory_InvalidJSON(t *testing.T) {
 	}
 }
 
-func TestCreateCategory_UnknownField(t *testing.T) {
-	h, _ := newTestHandler()
+func testCreateCategory_UnknownField(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -450,8 +1121,7 @@ func TestCreateCategory_UnknownField(t *testing.T) {
 	}
 }
 
-func TestGetCategory_NotFound(t *testing.T) {
-	h, _ := newTestHandler()
+func testGetCategory_NotFound(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -463,8 +1133,7 @@ func TestGetCategory_NotFound(t *testing.T) {
 	}
 }
 
-func TestGetCategory_InvalidUUID(t *testing.T) {
-	h, _ := newTestHandler()
+func testGetCategory_InvalidUUID(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -476,8 +1145,7 @@ func TestGetCategory_InvalidUUID(t *testing.T) {
 	}
 }
 
-func TestListCategories_Empty(t *testing.T) {
-	h, _ := newTestHandler()
+func testListCategories_Empty(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -494,8 +1162,7 @@ func TestListCategories_Empty(t *testing.T) {
 	}
 }
 
-func TestUpdateCategory_NotFound(t *testing.T) {
-	h, _ := newTestHandler()
+func testUpdateCategory_NotFound(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -507,12 +1174,10 @@ func TestUpdateCategory_NotFound(t *testing.T) {
 	}
 }
 
-func TestDeleteCategory_Success(t *testing.T) {
-	h, ms := newTestHandler()
+func testDeleteCategory_Success(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
-	cat := model.Category{ID: uuid.New(), Name: "X"}
-	ms.addCategory("contracts", cat)
+	cat := createTestCategory(t, mux, "contracts", "X")
 
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest("DELETE", "/api/v1/modules/contracts/categories/"+cat.ID.String(), nil)
@@ -521,10 +1186,15 @@ func TestDeleteCategory_Success(t *testing.T) {
 	if rec.Code != http.StatusNoContent {
 		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
 	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/modules/contracts/categories/"+cat.ID.String(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("deleted category: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
 }
 
-func TestDeleteCategory_NotFound(t *testing.T) {
-	h, _ := newTestHandler()
+func testDeleteCategory_NotFound(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -538,19 +1208,17 @@ func TestDeleteCategory_NotFound(t *testing.T) {
 
 // Contract handler tests
 
-func TestCreateContract_Success(t *testing.T) {
-	h, ms := newTestHandler()
+func testCreateContract_Success(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
-	cat := model.Category{ID: uuid.New(), Name: "Cat"}
-	ms.addCategory("contracts", cat)
+	cat := createTestCategory(t, mux, "contracts", "Cat")
 
 	body := map[string]any{
-		"name":                    "Phone",
-		"startDate":               "2025-01-01",
-		"minimumDurationMonths":   12,
-		"extensionDurationMonths": 12,
-		"noticePeriodMonths":      3,
+		"name":              "Phone",
+		"startDate":         "2025-01-01",
+		"minimumDuration":   12,
+		"extensionDuration": 12,
+		"noticePeriod":      3,
 	}
 
 	rec := httptest.NewRecorder()
@@ -570,12 +1238,10 @@ func TestCreateContract_Success(t *testing.T) {
 	}
 }
 
-func TestCreateContract_MissingName(t *testing.T) {
-	h, ms := newTestHandler()
+func testCreateContract_MissingName(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
-	cat := model.Category{ID: uuid.New(), Name: "Cat"}
-	ms.addCategory("contracts", cat)
+	cat := createTestCategory(t, mux, "contracts", "Cat")
 
 	body := map[string]any{"startDate": "2025-01-01"}
 
@@ -588,12 +1254,10 @@ func TestCreateContract_MissingName(t *testing.T) {
 	}
 }
 
-func TestCreateContract_MissingStartDate(t *testing.T) {
-	h, ms := newTestHandler()
+func testCreateContract_MissingStartDate(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
-	cat := model.Category{ID: uuid.New(), Name: "Cat"}
-	ms.addCategory("contracts", cat)
+	cat := createTestCategory(t, mux, "contracts", "Cat")
 
 	body := map[string]any{"name": "X"}
 
@@ -606,8 +1270,7 @@ func TestCreateContract_MissingStartDate(t *testing.T) {
 	}
 }
 
-func TestCreateContract_CategoryNotFound(t *testing.T) {
-	h, _ := newTestHandler()
+func testCreateContract_CategoryNotFound(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	body := map[string]any{"name": "X", "startDate": "2025-01-01"}
@@ -621,8 +1284,7 @@ func TestCreateContract_CategoryNotFound(t *testing.T) {
 	}
 }
 
-func TestGetContract_NotFound(t *testing.T) {
-	h, _ := newTestHandler()
+func testGetContract_NotFound(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -634,8 +1296,7 @@ func TestGetContract_NotFound(t *testing.T) {
 	}
 }
 
-func TestGetContract_InvalidUUID(t *testing.T) {
-	h, _ := newTestHandler()
+func testGetContract_InvalidUUID(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -647,12 +1308,10 @@ func TestGetContract_InvalidUUID(t *testing.T) {
 	}
 }
 
-func TestDeleteContract_Success(t *testing.T) {
-	h, ms := newTestHandler()
+func testDeleteContract_Success(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
-	con := model.Contract{ID: uuid.New(), Name: "X"}
-	ms.contracts[con.ID] = con
+	con := createTestContract(t, mux, map[string]any{"name": "X", "startDate": "2025-01-01"})
 
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest("DELETE", "/api/v1/contracts/"+con.ID.String(), nil)
@@ -661,10 +1320,15 @@ func TestDeleteContract_Success(t *testing.T) {
 	if rec.Code != http.StatusNoContent {
 		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
 	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("deleted contract: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
 }
 
-func TestDeleteContract_NotFound(t *testing.T) {
-	h, _ := newTestHandler()
+func testDeleteContract_NotFound(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -676,8 +1340,7 @@ func TestDeleteContract_NotFound(t *testing.T) {
 	}
 }
 
-func TestUpdateContract_NotFound(t *testing.T) {
-	h, _ := newTestHandler()
+func testUpdateContract_NotFound(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	body := map[string]any{"name": "X", "startDate": "2025-01-01"}
@@ -691,10 +1354,250 @@ func TestUpdateContract_NotFound(t *testing.T) {
 	}
 }
 
+func testContractHistory_NotFound(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/contracts/"+uuid.New().String()+"/history", nil)
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func testRestoreFromTrash_BadRequest(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	for path, want := range map[string]int{
+		"/api/v1/trash/user/" + uuid.NewString() + "/restore":     http.StatusBadRequest,
+		"/api/v1/trash/contract/not-a-uuid/restore":               http.StatusBadRequest,
+		"/api/v1/trash/contract/" + uuid.NewString() + "/restore": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", path, nil))
+		if rec.Code != want {
+			t.Errorf("POST %s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func testTrash_RestoreAndPurge(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	con := createTestContract(t, mux, map[string]any{"name": "Gym", "startDate": "2024-01-01"})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/modules/contracts/categories/"+con.CategoryID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete category: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("contract of deleted category: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/trash", nil))
+	items := decodeJSON[[]model.TrashItem](t, rec)
+	if !slices.ContainsFunc(items, func(i model.TrashItem) bool { return i.ID == con.CategoryID }) {
+		t.Fatalf("trash = %+v, want the category", items)
+	}
+
+	// The contract cannot come back while its category is in the trash.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/trash/contract/"+con.ID.String()+"/restore", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("restore contract: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/trash/category/"+con.CategoryID.String()+"/restore", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("restore category: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("restored contract: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/contracts/"+con.ID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete contract: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/trash/contract/"+con.ID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("purge: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/trash/contract/"+con.ID.String()+"/restore", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("restore purged contract: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func testWorkspaces_IsolateData(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	con := createTestContract(t, mux, map[string]any{"name": "Gym", "startDate": "2024-01-01", "price": 20.0})
+
+	other := newWorkspaceDataMux(h, model.WorkspaceMember{WorkspaceID: uuid.New(), UserID: testUserID, Role: model.WorkspaceRoleOwner})
+	for _, tt := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/v1/contracts/" + con.ID.String(), http.StatusNotFound},
+		{"GET", "/api/v1/modules/contracts/categories/" + con.CategoryID.String(), http.StatusNotFound},
+		{"GET", "/api/v1/contracts/" + con.ID.String() + "/prices", http.StatusNotFound},
+		{"DELETE", "/api/v1/contracts/" + con.ID.String(), http.StatusNotFound},
+		{"DELETE", "/api/v1/modules/contracts/categories/" + con.CategoryID.String(), http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		other.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s in another workspace: status = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	other.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts", nil))
+	if got := decodeJSON[[]contractView](t, rec); len(got) != 0 {
+		t.Errorf("contracts in another workspace = %d, want 0", len(got))
+	}
+	rec = httptest.NewRecorder()
+	other.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/summary", nil))
+	if summary := decodeJSON[summaryResponse](t, rec); summary.TotalContracts != 0 {
+		t.Errorf("summary of another workspace = %+v", summary)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("own workspace: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+// Purchase and vehicle handler tests
+
+func testPurchases_CreateUpdateDelete(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	cat := createTestCategory(t, mux, "purchases", "Kitchen")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/categories/"+cat.ID.String()+"/purchases", jsonBody(map[string]any{
+		"itemName": "Kettle", "brand": "ACME", "price": 40.0, "purchaseDate": "2025-03-01",
+	})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	p := decodeJSON[model.Purchase](t, rec)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/v1/purchases/"+p.ID.String(), jsonBody(map[string]any{
+		"itemName": "Kettle", "brand": "ACME", "price": 35.0, "purchaseDate": "2025-03-01",
+	})))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/categories/"+cat.ID.String()+"/purchases", nil))
+	if got := decodeJSON[[]model.Purchase](t, rec); len(got) != 1 || got[0].ID != p.ID || got[0].Price == nil || *got[0].Price != 35 {
+		t.Errorf("purchases = %+v", got)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/purchases/summary", nil))
+	if summary := decodeJSON[purchaseSummaryResponse](t, rec); summary.TotalPurchases != 1 || summary.TotalSpent != 35 {
+		t.Errorf("summary = %+v, want 1 purchase for 35", summary)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/purchases/"+p.ID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/purchases/"+p.ID.String(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("deleted purchase: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func testVehicles_CostEntries(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/vehicles", jsonBody(map[string]any{
+		"name": "Van", "purchaseDate": "2024-01-01", "purchasePrice": 20000.0,
+	})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create vehicle: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	v := decodeJSON[model.Vehicle](t, rec)
+	costsPath := "/api/v1/vehicles/" + v.ID.String() + "/costs"
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", costsPath, jsonBody(map[string]any{"type": "fuel", "amount": 80.0, "date": "2025-02-01"})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create cost entry: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	fuel := decodeJSON[model.CostEntry](t, rec)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", costsPath, jsonBody(map[string]any{"type": "mileage", "date": "2025-02-01"})))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("mileage entry without mileage: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/v1/costs/"+fuel.ID.String(), jsonBody(map[string]any{"type": "fuel", "amount": 85.0, "date": "2025-02-01"})))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update cost entry: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", costsPath, nil))
+	if got := decodeJSON[[]model.CostEntry](t, rec); len(got) != 1 || got[0].Amount == nil || *got[0].Amount != 85 {
+		t.Errorf("cost entries = %+v", got)
+	}
+
+	// Deleting the vehicle takes its cost entries to the trash, and
+	// restoring it brings them back.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/vehicles/"+v.ID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete vehicle: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/costs/"+fuel.ID.String(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("cost entry of deleted vehicle: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/trash/vehicle/"+v.ID.String()+"/restore", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("restore vehicle: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/costs/"+fuel.ID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("restored cost entry: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/costs/"+fuel.ID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete cost entry: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", costsPath, nil))
+	if got := decodeJSON[[]model.CostEntry](t, rec); len(got) != 0 {
+		t.Errorf("cost entries after delete = %+v", got)
+	}
+}
+
 // Content-Type check
 
-func TestResponses_HaveJSONContentType(t *testing.T) {
-	h, _ := newTestHandler()
+func testResponses_HaveJSONContentType(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -709,8 +1612,7 @@ func TestResponses_HaveJSONContentType(t *testing.T) {
 
 // Settings handler tests
 
-func TestGetSettings_ReturnsDefaults(t *testing.T) {
-	h, _ := newTestHandler()
+func testGetSettings_ReturnsDefaults(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -755,8 +1657,7 @@ func TestGetSettings_OmitsLastReminderSent(t *testing.T) {
 	}
 }
 
-func TestUpdateSettings_Success(t *testing.T) {
-	h, _ := newTestHandler()
+func testUpdateSettings_Success(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -786,8 +1687,7 @@ func TestUpdateSettings_Success(t *testing.T) {
 	}
 }
 
-func TestUpdateSettings_InvalidRange(t *testing.T) {
-	h, _ := newTestHandler()
+func testUpdateSettings_InvalidRange(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	for _, days := range []int{0, -1, 366} {
@@ -849,8 +1749,7 @@ func TestChangePassword_WrongCurrent(t *testing.T) {
 	}
 }
 
-func TestChangePassword_MissingFields(t *testing.T) {
-	h, _ := newTestHandler()
+func testChangePassword_MissingFields(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -864,8 +1763,7 @@ func TestChangePassword_MissingFields(t *testing.T) {
 	}
 }
 
-func TestUpdateSettings_InvalidReminderFrequency(t *testing.T) {
-	h, _ := newTestHandler()
+func testUpdateSettings_InvalidReminderFrequency(t *testing.T, h *Handler) {
 	mux := newMux(h)
 
 	rec := httptest.NewRecorder()
@@ -913,3 +1811,2904 @@ func TestUpdateSettings_PreservesLastReminderSent(t *testing.T) {
 		t.Errorf("ReminderFrequency = %q, want %q", persisted.ReminderFrequency, "monthly")
 	}
 }
+
+// Price history handler tests
+
+func createTestCategory(t *testing.T, mux http.Handler, module, name string) model.Category {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/modules/"+module+"/categories", jsonBody(map[string]string{"name": name}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create category: status = %d, want %d; body: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	return decodeJSON[model.Category](t, rec)
+}
+
+func createTestContract(t *testing.T, mux http.Handler, body map[string]any) contractView {
+	t.Helper()
+	cat := createTestCategory(t, mux, "contracts", "Cat")
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/categories/"+cat.ID.String()+"/contracts", jsonBody(body))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create contract: status = %d, want %d; body: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	return decodeJSON[contractView](t, rec)
+}
+
+// storedContracts returns the contracts of the test workspace as stored.
+func storedContracts(t *testing.T, h *Handler) []model.Contract {
+	t.Helper()
+	contracts, err := h.store.ListContracts(context.Background(), testWorkspace.WorkspaceID.String())
+	if err != nil {
+		t.Fatalf("ListContracts: %v", err)
+	}
+	return contracts
+}
+
+func storedCategories(t *testing.T, h *Handler, module string) []model.Category {
+	t.Helper()
+	cats, err := h.store.ListCategories(context.Background(), testWorkspace.WorkspaceID.String(), module)
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	return cats
+}
+
+func testCreatePriceEntry_SeedsBasePrice(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{"name": "Insurance", "startDate": "2024-01-01", "price": 10.0})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/contracts/"+con.ID.String()+"/prices", jsonBody(map[string]any{
+		"price":         12.5,
+		"effectiveDate": "2025-01-01",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String()+"/prices", nil)
+	mux.ServeHTTP(rec, req)
+	entries := decodeJSON[[]model.PriceEntry](t, rec)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries (base + new), got %d", len(entries))
+	}
+	if entries[0].EffectiveDate != "2024-01-01" || entries[0].Price != 10 {
+		t.Errorf("base entry = %+v, want 10 from 2024-01-01", entries[0])
+	}
+	if entries[1].EffectiveDate != "2025-01-01" || entries[1].Price != 12.5 {
+		t.Errorf("new entry = %+v, want 12.5 from 2025-01-01", entries[1])
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String(), nil))
+	if got := decodeJSON[contractView](t, rec).Price; got == nil || *got != 12.5 {
+		t.Errorf("current price = %v, want 12.5", got)
+	}
+}
+
+func testCreatePriceEntry_FutureDateKeepsCurrentPrice(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{"name": "Gym", "startDate": "2024-01-01", "price": 20.0})
+	future := time.Now().UTC().AddDate(0, 2, 0).Format("2006-01-02")
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/contracts/"+con.ID.String()+"/prices", jsonBody(map[string]any{
+		"price":         25.0,
+		"effectiveDate": future,
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String(), nil)
+	mux.ServeHTTP(rec, req)
+	got := decodeJSON[contractView](t, rec)
+	if got.Price == nil || *got.Price != 20 {
+		t.Errorf("current price = %v, want 20", got.Price)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v1/summary?date="+future, nil)
+	mux.ServeHTTP(rec, req)
+	summary := decodeJSON[summaryResponse](t, rec)
+	if summary.TotalMonthlyAmount != 25 {
+		t.Errorf("monthly total on %s = %v, want 25", future, summary.TotalMonthlyAmount)
+	}
+}
+
+func testCreatePriceEntry_InvalidInput(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{"name": "Phone", "startDate": "2024-01-01"})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/contracts/"+con.ID.String()+"/prices", jsonBody(map[string]any{
+		"price": 5.0,
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func testUpdateContract_PriceChangeRecordsHistory(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	body := map[string]any{"name": "Insurance", "startDate": "2024-01-01", "price": 10.0}
+	con := createTestContract(t, mux, body)
+
+	body["price"] = 11.0
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/api/v1/contracts/"+con.ID.String(), jsonBody(body))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String()+"/prices", nil))
+	if entries := decodeJSON[[]model.PriceEntry](t, rec); len(entries) != 2 {
+		t.Fatalf("expected 2 price entries, got %d", len(entries))
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v1/summary?date=2024-06-01", nil)
+	mux.ServeHTTP(rec, req)
+	summary := decodeJSON[summaryResponse](t, rec)
+	if summary.TotalMonthlyAmount != 10 {
+		t.Errorf("monthly total in 2024 = %v, want 10", summary.TotalMonthlyAmount)
+	}
+}
+
+func testUpdateContract_ClearPrice(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	update := func(id uuid.UUID, body map[string]any) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/v1/contracts/"+id.String(), jsonBody(body)))
+		return rec
+	}
+
+	// Without a price history the price is simply removed.
+	body := map[string]any{"name": "Insurance", "startDate": "2024-01-01", "price": 10.0}
+	con := createTestContract(t, mux, body)
+	body["price"] = nil
+	if rec := update(con.ID, body); rec.Code != http.StatusOK || decodeJSON[contractView](t, rec).Price != nil {
+		t.Fatalf("clear: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	// With one the history sets the price, so clearing it is refused.
+	body["price"] = 11.0
+	if rec := update(con.ID, body); rec.Code != http.StatusOK {
+		t.Fatalf("set: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	body["price"] = nil
+	if rec := update(con.ID, body); rec.Code != http.StatusBadRequest {
+		t.Fatalf("clear with history: status = %d, want 400", rec.Code)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String(), nil))
+	if got := decodeJSON[contractView](t, rec); got.Price == nil || *got.Price != 11 {
+		t.Errorf("price = %v, want 11", got.Price)
+	}
+}
+
+func testDeletePriceEntry_WrongContract(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{"name": "A", "startDate": "2024-01-01"})
+	other := createTestContract(t, mux, map[string]any{"name": "B", "startDate": "2024-01-01"})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/contracts/"+other.ID.String()+"/prices", jsonBody(map[string]any{
+		"price":         1.0,
+		"effectiveDate": "2024-01-01",
+	})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create entry: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	entry := decodeJSON[model.PriceEntry](t, rec)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/v1/contracts/"+con.ID.String()+"/prices/"+entry.ID.String(), nil)
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/contracts/"+other.ID.String()+"/prices/"+entry.ID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete from own contract: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+}
+
+func testSummary_InvalidDate(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/summary?date=tomorrow", nil)
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// Import handler tests
+
+func multipartFile(t *testing.T, path, filename string, data []byte) *http.Request {
+	t.Helper()
+	return multipartForm(t, path, filename, data, nil)
+}
+
+// multipartForm builds an upload request with additional form fields.
+func multipartForm(t *testing.T, path, filename string, data []byte, fields map[string]string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	fw.Write(data)
+	mw.Close()
+
+	req := httptest.NewRequest("POST", path, &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func testImportContracts_NormalizesBillingInterval(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	data := []byte(`[
+		{"category": "Insurance", "name": "Liability", "startDate": "2024-01-01", "price": 30, "billingInterval": "Quarterly"},
+		{"category": "Insurance", "name": "Legal", "startDate": "2024-01-01", "price": 60, "billingInterval": "semi-annual"},
+		{"category": "Insurance", "name": "Setup fee", "startDate": "2024-01-01", "price": 99, "billingInterval": "once"},
+		{"category": "Insurance", "name": "Broken", "startDate": "2024-01-01", "billingInterval": "daily"}
+	]`)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartFile(t, "/api/v1/contracts/import", "contracts.json", data))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	result := decodeJSON[importResult](t, rec)
+	if result.Created != 3 {
+		t.Errorf("created = %d, want 3", result.Created)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 4 {
+		t.Errorf("errors = %+v, want one error for row 4", result.Errors)
+	}
+
+	want := map[string]model.BillingInterval{
+		"Liability": model.BillingQuarterly,
+		"Legal":     model.BillingHalfYearly,
+		"Setup fee": model.BillingOneTime,
+	}
+	for _, c := range storedContracts(t, h) {
+		if c.BillingInterval != want[c.Name] {
+			t.Errorf("%s: billingInterval = %q, want %q", c.Name, c.BillingInterval, want[c.Name])
+		}
+	}
+}
+
+func testSummary_NormalizesBillingIntervals(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	createTestContract(t, mux, map[string]any{"name": "Weekly", "startDate": "2024-01-01", "price": 3.0, "billingInterval": "weekly"})
+	createTestContract(t, mux, map[string]any{"name": "Quarterly", "startDate": "2024-01-01", "price": 30.0, "billingInterval": "quarterly"})
+	createTestContract(t, mux, map[string]any{"name": "Biennial", "startDate": "2024-01-01", "price": 240.0, "billingInterval": "biennial"})
+	createTestContract(t, mux, map[string]any{"name": "Once", "startDate": "2024-01-01", "price": 500.0, "billingInterval": "one-time"})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/summary", nil)
+	mux.ServeHTTP(rec, req)
+	summary := decodeJSON[summaryResponse](t, rec)
+
+	if want := 3.0*52 + 120 + 120; summary.TotalYearlyAmount != want {
+		t.Errorf("yearly total = %v, want %v", summary.TotalYearlyAmount, want)
+	}
+	if summary.TotalContracts != 4 {
+		t.Errorf("total contracts = %d, want 4", summary.TotalContracts)
+	}
+}
+
+func testCreateContract_InvalidBillingInterval(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	cat := createTestCategory(t, mux, "contracts", "Cat")
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/categories/"+cat.ID.String()+"/contracts", jsonBody(map[string]any{
+		"name": "X", "startDate": "2024-01-01", "billingInterval": "daily",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// Cancellation workflow handler tests
+
+func testTransitionContract_Workflow(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{"name": "Gym", "startDate": "2024-01-01"})
+	if con.Status != model.StatusActive {
+		t.Fatalf("new contract status = %q, want active", con.Status)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/contracts/"+con.ID.String()+"/transition", jsonBody(map[string]any{
+		"status":        "cancellation_confirmed",
+		"effectiveDate": "2025-12-31",
+		"reference":     "ABC-123",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	got := decodeJSON[contractView](t, rec)
+	if got.Status != model.StatusCancellationConfirmed || got.CancellationReference != "ABC-123" {
+		t.Errorf("unexpected contract after transition: %+v", got.Contract)
+	}
+	if stored, _ := h.store.GetContract(context.Background(), testWorkspace.WorkspaceID.String(), con.ID); stored.CancellationConfirmedAt == nil {
+		t.Error("expected cancellationConfirmedAt to be stored")
+	}
+
+	// confirmed → sent is not allowed
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/v1/contracts/"+con.ID.String()+"/transition", jsonBody(map[string]any{
+		"status": "cancellation_sent",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func testTransitionContract_InvalidStatus(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{"name": "Gym", "startDate": "2024-01-01"})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/contracts/"+con.ID.String()+"/transition", jsonBody(map[string]any{
+		"status": "paused",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func testTransitionContract_NotFound(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/contracts/"+uuid.New().String()+"/transition", jsonBody(map[string]any{
+		"status": "cancellation_sent",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func testUpcomingRenewals_SkipsCancellationInProgress(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	start := time.Now().UTC().AddDate(0, -11, 0).Format("2006-01-02")
+	body := map[string]any{"startDate": start, "minimumDuration": 12, "extensionDuration": 12}
+	body["name"] = "Running"
+	createTestContract(t, mux, body)
+	body["name"] = "Cancelled"
+	cancelled := createTestContract(t, mux, body)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/contracts/"+cancelled.ID.String()+"/transition", jsonBody(map[string]any{
+		"status": "cancellation_sent",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("transition status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v1/contracts/upcoming-renewals", nil)
+	mux.ServeHTTP(rec, req)
+	upcoming := decodeJSON[[]contractView](t, rec)
+	if len(upcoming) != 1 || upcoming[0].Name != "Running" {
+		t.Errorf("expected only the running contract, got %+v", upcoming)
+	}
+}
+
+func testCreateContract_DurationUnitsAndAnchors(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{
+		"name":               "Gym",
+		"startDate":          "2024-01-01",
+		"minimumDuration":    6,
+		"noticePeriod":       4,
+		"noticePeriodUnit":   "weeks",
+		"noticePeriodAnchor": "end_of_month",
+	})
+	if con.NoticePeriodUnit != model.UnitWeeks || con.NoticePeriodAnchor != model.AnchorEndOfMonth {
+		t.Errorf("notice period = %s %s, want weeks end_of_month", con.NoticePeriodUnit, con.NoticePeriodAnchor)
+	}
+	if con.CancellationDate == nil {
+		t.Fatal("expected a cancellation date")
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/api/v1/contracts/"+con.ID.String(), jsonBody(map[string]any{
+		"name":             "Gym",
+		"startDate":        "2024-01-01",
+		"noticePeriod":     2,
+		"noticePeriodUnit": "fortnights",
+	}))
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func testContracts_AcceptMonthNamedDurations(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{
+		"name":                    "Gym",
+		"startDate":               "2024-01-01",
+		"minimumDurationMonths":   6,
+		"extensionDurationMonths": 1,
+		"noticePeriodMonths":      4,
+		"noticePeriodUnit":        "weeks",
+	})
+	if con.MinimumDuration != 6 || con.ExtensionDuration != 1 || con.NoticePeriod != 4 {
+		t.Errorf("durations = %d %d %d, want 6 1 4", con.MinimumDuration, con.ExtensionDuration, con.NoticePeriod)
+	}
+
+	data := []byte(`[
+		{"category": "Sports", "name": "Club", "startDate": "2024-01-01", "noticePeriodMonths": 3},
+		{"category": "Sports", "name": "Pool", "startDate": "2024-01-01", "noticePeriod": 2, "noticePeriodMonths": 3}
+	]`)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartFile(t, "/api/v1/contracts/import", "contracts.json", data))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if result := decodeJSON[importResult](t, rec); result.Created != 2 {
+		t.Fatalf("result = %+v, want 2 created", result)
+	}
+	want := map[string]int{"Gym": 4, "Club": 3, "Pool": 2}
+	for _, c := range storedContracts(t, h) {
+		if c.NoticePeriod != want[c.Name] {
+			t.Errorf("%s: noticePeriod = %d, want %d", c.Name, c.NoticePeriod, want[c.Name])
+		}
+	}
+}
+
+// Reference date handler tests
+
+func fixedClock(date string) func() time.Time {
+	d, _ := time.Parse("2006-01-02", date)
+	return func() time.Time { return d.Add(10 * time.Hour) }
+}
+
+func testUpcomingRenewals_UsesInjectedClock(t *testing.T, h *Handler) {
+	h.now = fixedClock("2025-08-15")
+	mux := newMux(h)
+
+	// Deadline 2025-10-01 for the term ending 2025-12-31.
+	createTestContract(t, mux, map[string]any{
+		"name": "Insurance", "startDate": "2024-01-01",
+		"minimumDuration": 12, "extensionDuration": 12, "noticePeriod": 3,
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/contracts/upcoming-renewals?days=60", nil)
+	mux.ServeHTTP(rec, req)
+	upcoming := decodeJSON[[]contractView](t, rec)
+	if len(upcoming) != 1 {
+		t.Fatalf("expected 1 upcoming renewal, got %d", len(upcoming))
+	}
+	if got := *upcoming[0].CancellationDate; got != "2025-10-01" {
+		t.Errorf("cancellationDate = %s, want 2025-10-01", got)
+	}
+
+	h.now = fixedClock("2025-06-01")
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v1/contracts/upcoming-renewals?days=60", nil)
+	mux.ServeHTTP(rec, req)
+	if upcoming := decodeJSON[[]contractView](t, rec); len(upcoming) != 0 {
+		t.Errorf("expected no upcoming renewals on 2025-06-01, got %d", len(upcoming))
+	}
+}
+
+func testContractTimeline(t *testing.T, h *Handler) {
+	h.now = fixedClock("2025-02-01")
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{
+		"name": "Insurance", "startDate": "2023-01-01",
+		"minimumDuration": 12, "extensionDuration": 12, "noticePeriod": 3,
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String()+"/timeline?months=12", nil)
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	tl := decodeJSON[timelineResponse](t, rec)
+	if tl.Date != "2025-02-01" || tl.Until != "2026-02-01" {
+		t.Errorf("date/until = %s/%s, want 2025-02-01/2026-02-01", tl.Date, tl.Until)
+	}
+	if len(tl.Boundaries) != 3 {
+		t.Fatalf("expected 3 boundaries, got %+v", tl.Boundaries)
+	}
+	if !tl.Boundaries[1].Past || tl.Boundaries[2].Past {
+		t.Errorf("unexpected past flags: %+v", tl.Boundaries)
+	}
+	if tl.CancellationDate == nil || *tl.CancellationDate != "2025-10-01" {
+		t.Errorf("cancellationDate = %v, want 2025-10-01", tl.CancellationDate)
+	}
+
+	// As of a later reference date the next deadline moves on.
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String()+"/timeline?date=2027-03-01", nil)
+	mux.ServeHTTP(rec, req)
+	tl = decodeJSON[timelineResponse](t, rec)
+	if tl.CancellationDate == nil || *tl.CancellationDate != "2027-10-01" {
+		t.Errorf("cancellationDate as of 2027-03-01 = %v, want 2027-10-01", tl.CancellationDate)
+	}
+}
+
+func testContractTimeline_InvalidParams(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	con := createTestContract(t, mux, map[string]any{"name": "X", "startDate": "2024-01-01"})
+
+	for _, q := range []string{"date=01.03.2027", "months=0", "months=abc"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/v1/contracts/"+con.ID.String()+"/timeline?"+q, nil)
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", q, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// Calendar feed handler tests
+
+func newCalendarMux(h *Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/calendar/{file}", h.CalendarFeed)
+	mux.Handle("/", newMux(h))
+	return mux
+}
+
+func TestCalendarFeed_RotateServeRevoke(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = fixedClock("2025-08-15")
+	h.SetBaseURL("https://contracts.example.com/")
+	mux := newCalendarMux(h)
+	ms.usersById[testUserID] = model.User{ID: uuid.MustParse(testUserID), Email: "test@example.com"}
+	ms.settings[testUserID] = model.UserSettings{RenewalDays: 30}
+
+	createTestContract(t, mux, map[string]any{
+		"name": "Insurance", "company": "ACME", "startDate": "2024-01-01",
+		"minimumDuration": 12, "extensionDuration": 12, "noticePeriod": 3,
+	})
+	cancelled := createTestContract(t, mux, map[string]any{"name": "Old gym", "startDate": "2024-01-01"})
+	c := ms.contracts[cancelled.ID]
+	c.Status = model.StatusCancellationSent
+	ms.contracts[cancelled.ID] = c
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/settings/calendar-feed", nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("rotate: status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	feed := decodeJSON[calendarFeedResponse](t, rec)
+	if feed.URL != "https://contracts.example.com/api/v1/calendar/"+feed.Token+".ics" {
+		t.Errorf("url = %q", feed.URL)
+	}
+	if ms.feedTokens[testUserID].TokenHash == feed.Token {
+		t.Error("token must be stored hashed")
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/calendar/"+feed.Token+".ics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("feed: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("content type = %q", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"SUMMARY:Cancellation deadline: Insurance", "DTSTART;VALUE=DATE:20251001", "TRIGGER:-P30D", "TRIGGER:-P7D"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("feed missing %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Old gym") {
+		t.Error("contracts with a cancellation in progress should not be in the feed")
+	}
+
+	// Rotating again invalidates the old token.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/settings/calendar-feed", nil))
+	rotated := decodeJSON[calendarFeedResponse](t, rec)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/calendar/"+feed.Token+".ics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("old token: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/v1/settings/calendar-feed", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/calendar/"+rotated.Token+".ics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("revoked token: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/settings/calendar-feed", nil))
+	if status := decodeJSON[calendarFeedResponse](t, rec); status.Enabled {
+		t.Error("feed should be disabled after revoking")
+	}
+}
+
+// Account archive handler tests
+
+func testExportAccount(t *testing.T, h *Handler) {
+	h.now = fixedClock("2025-08-15")
+	mux := newMux(h)
+
+	con := createTestContract(t, mux, map[string]any{"name": "Netflix", "startDate": "2025-01-01", "price": 12.99})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/export", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, "contracts-export-2025-08-15.json") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	a := decodeJSON[archive.Archive](t, rec)
+	if a.Version != archive.Version {
+		t.Errorf("version = %d, want %d", a.Version, archive.Version)
+	}
+	if len(a.Contracts) != 1 || a.Contracts[0].ID != con.ID {
+		t.Errorf("contracts = %+v", a.Contracts)
+	}
+	if len(a.Categories["contracts"]) != 1 {
+		t.Errorf("categories = %+v", a.Categories)
+	}
+}
+
+func testRestoreAccount_Merge(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	createTestContract(t, mux, map[string]any{"name": "Netflix", "startDate": "2025-01-01"})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/export", nil))
+	exported := rec.Body.Bytes()
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/restore", bytes.NewReader(exported)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	res := decodeJSON[archive.Result](t, rec)
+	if res.Contracts != 1 || res.Categories != 1 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(storedContracts(t, h)) != 2 {
+		t.Errorf("expected 2 contracts after merge, got %d", len(storedContracts(t, h)))
+	}
+}
+
+func testRestoreAccount_BadRequest(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	createTestContract(t, mux, map[string]any{"name": "Netflix", "startDate": "2025-01-01"})
+
+	tests := []struct {
+		name string
+		url  string
+		body string
+	}{
+		{"unknown mode", "/api/v1/restore?mode=overwrite", `{"version":1}`},
+		{"malformed json", "/api/v1/restore", `{"version":`},
+		{"unsupported version", "/api/v1/restore?mode=replace", `{"version":99}`},
+		{"dangling reference", "/api/v1/restore?mode=replace", `{"version":1,"contracts":[{"id":"` + uuid.New().String() + `","categoryId":"` + uuid.New().String() + `","name":"x"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("POST", tt.url, strings.NewReader(tt.body)))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if len(storedContracts(t, h)) != 1 {
+				t.Errorf("contracts = %d, want 1", len(storedContracts(t, h)))
+			}
+		})
+	}
+}
+
+// CSV import/export handler tests
+
+func testImportContractsCSV(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	insurance := model.Category{ID: uuid.New(), Name: "Insurance", NameKey: "categoryNames.insurance"}
+	if err := h.store.CreateCategory(context.Background(), testWorkspace.WorkspaceID.String(), "contracts", insurance); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("Kategorie;Name;Company;Price;Billing Interval;Start Date;Notice Period Months\n" +
+		"Versicherung;Haftpflicht;ACME;1.234,56;yearly;01.01.2024;3\n" +
+		"Strom;Stadtwerke;SW;45,50;monthly;15.03.2024;1\n" +
+		";Missing category;X;1,00;monthly;01.01.2024;\n" +
+		"Strom;Bad price;X;abc;monthly;01.01.2024;\n")
+	req := multipartForm(t, "/api/v1/contracts/import/csv", "contracts.csv", data, map[string]string{
+		"mapping": `{"Kategorie": "category"}`,
+	})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	result := decodeJSON[importResult](t, rec)
+	if result.Created != 2 {
+		t.Errorf("created = %d, want 2", result.Created)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Row != 4 || result.Errors[1].Row != 5 {
+		t.Fatalf("errors = %+v, want rows 4 and 5", result.Errors)
+	}
+	if !strings.Contains(result.Errors[1].Error, "price") {
+		t.Errorf("error = %q, want price error", result.Errors[1].Error)
+	}
+
+	if len(storedCategories(t, h, "contracts")) != 2 {
+		t.Errorf("expected translated category match plus one new category, got %d", len(storedCategories(t, h, "contracts")))
+	}
+	for _, c := range storedContracts(t, h) {
+		if c.Name == "Haftpflicht" {
+			if c.Price == nil || *c.Price != 1234.56 {
+				t.Errorf("price = %v, want 1234.56", c.Price)
+			}
+			if c.StartDate != "2024-01-01" || c.BillingInterval != model.BillingYearly || c.NoticePeriod != 3 {
+				t.Errorf("contract = %+v", c)
+			}
+		}
+	}
+}
+
+func testImportContractsCSV_BadRequest(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	tests := []struct {
+		name   string
+		data   string
+		fields map[string]string
+	}{
+		{"no known columns", "foo,bar\n1,2\n", nil},
+		{"bad delimiter", "name\nx\n", map[string]string{"delimiter": "#"}},
+		{"bad decimal", "name\nx\n", map[string]string{"decimal": "x"}},
+		{"bad mapping", "name\nx\n", map[string]string{"mapping": "[1]"}},
+		{"unknown mapping field", "name\nx\n", map[string]string{"mapping": `{"name": "title"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, multipartForm(t, "/api/v1/contracts/import/csv", "c.csv", []byte(tt.data), tt.fields))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func testExportContractsCSV(t *testing.T, h *Handler) {
+	h.now = fixedClock("2025-08-15")
+	mux := newMux(h)
+	con := createTestContract(t, mux, map[string]any{
+		"name": "Strom; Gas", "startDate": "2024-01-01", "price": 45.5,
+		"minimumDuration": 12, "extensionDuration": 12, "noticePeriod": 1,
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/export/csv?delimiter=%3B&decimal=,", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("content type = %q", ct)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected header and one row, got %q", rec.Body.String())
+	}
+	if !strings.HasPrefix(lines[0], "category;name;") || !strings.HasSuffix(lines[0], ";status;cancellationDate") {
+		t.Errorf("header = %q", lines[0])
+	}
+	for _, want := range []string{`"Strom; Gas"`, ";45,5;", ";active;2025-12-01"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("row %q missing %q", lines[1], want)
+		}
+	}
+
+	// Re-importing the export as duplicates recreates the contract.
+	exported := rec.Body.Bytes()
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartForm(t, "/api/v1/contracts/import/csv", "export.csv", exported, map[string]string{"onDuplicate": "duplicate"}))
+	result := decodeJSON[importResult](t, rec)
+	if result.Created != 1 || len(result.Errors) != 0 {
+		t.Errorf("re-import result = %+v", result)
+	}
+	for _, c := range storedContracts(t, h) {
+		if c.ID != con.ID && (c.Name != con.Name || *c.Price != 45.5 || c.MinimumDuration != 12) {
+			t.Errorf("re-imported contract = %+v", c)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/contracts/export/csv?categoryId="+uuid.New().String(), nil))
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 1 {
+		t.Errorf("category filter: expected header only, got %d lines", len(lines))
+	}
+}
+
+// Import preview handler tests
+
+func testPreviewContractImport(t *testing.T, h *Handler) {
+	mux := newMux(h)
+	insurance := model.Category{ID: uuid.New(), Name: "Insurance", NameKey: "categoryNames.insurance"}
+	ctx, workspaceID := context.Background(), testWorkspace.WorkspaceID.String()
+	if err := h.store.CreateCategory(ctx, workspaceID, "contracts", insurance); err != nil {
+		t.Fatal(err)
+	}
+	price := 30.0
+	existing := model.Contract{
+		ID: uuid.New(), CategoryID: insurance.ID, Name: "Liability", Company: "ACME", ContractNumber: "A-1",
+		Price: &price, BillingInterval: model.BillingMonthly, StartDate: "2024-01-01",
+	}
+	if err := h.store.CreateContract(ctx, workspaceID, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`[
+		{"category": "Versicherung", "name": "Liability", "company": "acme", "contractNumber": "A-1", "startDate": "2024-01-01", "price": 35},
+		{"category": "Energy", "name": "Power", "company": "Stadtwerke", "contractNumber": "P-9", "startDate": "2024-01-01"},
+		{"category": "energy", "name": "Power again", "company": "Stadtwerke", "contractNumber": "P-9", "startDate": "2024-02-01"},
+		{"category": "Energy", "name": "Broken"}
+	]`)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartFile(t, "/api/v1/contracts/import/preview", "import.json", data))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	preview := decodeJSON[importPreview](t, rec)
+
+	if preview.Create != 1 || preview.Duplicates != 2 || preview.Invalid != 1 {
+		t.Errorf("counts = %d/%d/%d, want 1/2/1", preview.Create, preview.Duplicates, preview.Invalid)
+	}
+	if len(preview.NewCategories) != 1 || preview.NewCategories[0] != "Energy" {
+		t.Errorf("newCategories = %v, want [Energy]", preview.NewCategories)
+	}
+
+	dup := preview.Rows[0]
+	if dup.Action != importDuplicate || dup.DuplicateOf == nil || *dup.DuplicateOf != existing.ID {
+		t.Errorf("row 1 = %+v, want duplicate of existing contract", dup)
+	}
+	if dup.NewCategory || dup.CategoryID == nil || *dup.CategoryID != insurance.ID {
+		t.Errorf("row 1 should match the translated category, got %+v", dup)
+	}
+	if len(dup.Changes) != 2 || dup.Changes[0].Field != "company" || dup.Changes[1].Field != "price" {
+		t.Errorf("changes = %+v, want company and price", dup.Changes)
+	}
+
+	if r := preview.Rows[1]; r.Action != importCreate || !r.NewCategory {
+		t.Errorf("row 2 = %+v, want create with new category", r)
+	}
+	if r := preview.Rows[2]; r.Action != importDuplicate || r.DuplicateOfRow != 2 {
+		t.Errorf("row 3 = %+v, want duplicate of row 2", r)
+	}
+	if r := preview.Rows[3]; r.Action != importInvalid || r.Error == "" {
+		t.Errorf("row 4 = %+v, want invalid", r)
+	}
+
+	// Nothing was written.
+	if len(storedContracts(t, h)) != 1 || len(storedCategories(t, h, "contracts")) != 1 {
+		t.Errorf("preview wrote data: %d contracts, %d categories", len(storedContracts(t, h)), len(storedCategories(t, h, "contracts")))
+	}
+}
+
+func testImportContracts_SelectedRows(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	data := []byte(`[
+		{"category": "Energy", "name": "Power", "startDate": "2024-01-01"},
+		{"category": "Energy", "name": "Gas", "startDate": "2024-01-01"},
+		{"category": "Energy", "name": "Broken"}
+	]`)
+
+	req := multipartForm(t, "/api/v1/contracts/import", "import.json", data, map[string]string{"rows": "2, 3"})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	result := decodeJSON[importResult](t, rec)
+	if result.Created != 1 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Errorf("result = %+v", result)
+	}
+	for _, c := range storedContracts(t, h) {
+		if c.Name != "Gas" {
+			t.Errorf("unexpected contract %q imported", c.Name)
+		}
+	}
+
+	for _, rows := range []string{"0", "4", "x"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, multipartForm(t, "/api/v1/contracts/import", "import.json", data, map[string]string{"rows": rows}))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("rows=%q: status = %d, want %d", rows, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// Duplicate handling tests
+
+func TestImportContracts_DuplicateStrategies(t *testing.T) {
+	data := []byte(`[
+		{"category": "Energy", "name": "Power", "company": "Stadtwerke", "contractNumber": "P-9", "startDate": "2024-01-01", "price": 50},
+		{"category": "Energy", "name": "Gas", "startDate": "2024-03-01", "price": 20}
+	]`)
+
+	tests := []struct {
+		strategy  string
+		want      importResult
+		contracts int
+	}{
+		{"", importResult{Updated: 2}, 2},
+		{"update", importResult{Updated: 2}, 2},
+		{"skip", importResult{Skipped: 2}, 2},
+		{"duplicate", importResult{Created: 2}, 4},
+	}
+	for _, tt := range tests {
+		t.Run("strategy="+tt.strategy, func(t *testing.T) {
+			h, ms := newTestHandler()
+			mux := newMux(h)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, multipartFile(t, "/api/v1/contracts/import", "import.json", data))
+			if first := decodeJSON[importResult](t, rec); first.Created != 2 {
+				t.Fatalf("first import = %+v", first)
+			}
+
+			// The second import changes the price in the file.
+			changed := bytes.ReplaceAll(data, []byte(`"price": 50`), []byte(`"price": 55`))
+			rec = httptest.NewRecorder()
+			mux.ServeHTTP(rec, multipartForm(t, "/api/v1/contracts/import", "import.json", changed, map[string]string{"onDuplicate": tt.strategy}))
+			got := decodeJSON[importResult](t, rec)
+			if got.Created != tt.want.Created || got.Updated != tt.want.Updated || got.Skipped != tt.want.Skipped || len(got.Errors) != 0 {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+			if len(ms.contracts) != tt.contracts {
+				t.Errorf("contracts = %d, want %d", len(ms.contracts), tt.contracts)
+			}
+
+			if tt.strategy == "" || tt.strategy == "update" {
+				for _, c := range ms.contracts {
+					if c.Name == "Power" && *c.Price != 55 {
+						t.Errorf("price = %v, want 55", *c.Price)
+					}
+				}
+				// The original price and the change.
+				if len(ms.prices) != 2 {
+					t.Errorf("price history = %d entries, want 2", len(ms.prices))
+				}
+			}
+		})
+	}
+}
+
+func testImportContracts_MatchBy(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	first := []byte(`[{"category": "Energy", "name": "Power", "company": "Stadtwerke", "contractNumber": "P-9", "startDate": "2024-01-01"}]`)
+	renamed := []byte(`[{"category": "Energy", "name": "Electricity", "company": "Stadtwerke", "contractNumber": "P-9", "startDate": "2024-01-01"}]`)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartFile(t, "/api/v1/contracts/import", "import.json", first))
+
+	// Matching by name and start date treats the renamed contract as new.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartForm(t, "/api/v1/contracts/import", "import.json", renamed, map[string]string{"matchBy": "nameStartDate"}))
+	if got := decodeJSON[importResult](t, rec); got.Created != 1 {
+		t.Errorf("nameStartDate result = %+v, want 1 created", got)
+	}
+
+	// Matching by contract number finds it.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartForm(t, "/api/v1/contracts/import", "import.json", renamed, map[string]string{"matchBy": "contractNumber", "onDuplicate": "skip"}))
+	if got := decodeJSON[importResult](t, rec); got.Skipped != 1 {
+		t.Errorf("contractNumber result = %+v, want 1 skipped", got)
+	}
+	if len(storedContracts(t, h)) != 2 {
+		t.Errorf("contracts = %d, want 2", len(storedContracts(t, h)))
+	}
+
+	for _, params := range []map[string]string{{"matchBy": "email"}, {"onDuplicate": "merge"}} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, multipartForm(t, "/api/v1/contracts/import", "import.json", first, params))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want %d", params, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func testImportContracts_DuplicateRowsInFile(t *testing.T, h *Handler) {
+	mux := newMux(h)
+
+	data := []byte(`[
+		{"category": "Energy", "name": "Power", "startDate": "2024-01-01", "price": 50},
+		{"category": "Energy", "name": "power", "startDate": "2024-01-01", "price": 60}
+	]`)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, multipartFile(t, "/api/v1/contracts/import", "import.json", data))
+	got := decodeJSON[importResult](t, rec)
+	if got.Created != 1 || got.Updated != 1 {
+		t.Errorf("result = %+v, want 1 created and 1 updated", got)
+	}
+	if len(storedContracts(t, h)) != 1 {
+		t.Errorf("contracts = %d, want 1", len(storedContracts(t, h)))
+	}
+}
+
+// Session tests
+
+// newSessionMux serves the auth routes publicly and the session and password
+// routes behind the real auth middleware.
+func newSessionMux(h *Handler, ms middleware.AuthStore) http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/sessions", h.ListSessions)
+	api.HandleFunc("DELETE /api/v1/sessions", h.RevokeOtherSessions)
+	api.HandleFunc("DELETE /api/v1/sessions/{id}", h.RevokeSession)
+	api.HandleFunc("PUT /api/v1/settings/password", h.ChangePassword)
+	api.HandleFunc("PUT /api/v1/settings/email", h.ChangeEmail)
+	api.HandleFunc("POST /api/v1/settings/email/verification", h.ResendEmailVerification)
+	api.HandleFunc("GET /api/v1/settings/2fa", h.GetTwoFactor)
+	api.HandleFunc("POST /api/v1/settings/2fa", h.BeginTwoFactor)
+	api.HandleFunc("POST /api/v1/settings/2fa/confirm", h.ConfirmTwoFactor)
+	api.HandleFunc("DELETE /api/v1/settings/2fa", h.DisableTwoFactor)
+	api.HandleFunc("DELETE /api/v1/account", h.DeleteAccount)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/auth/", newAuthMux(h))
+	mux.Handle("/api/v1/", middleware.Auth(testJWTSecret, ms)(api))
+	return mux
+}
+
+func signIn(t *testing.T, mux http.Handler, path string, creds map[string]string) authResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", path, jsonBody(creds)))
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("%s: status = %d; body: %s", path, rec.Code, rec.Body.String())
+	}
+	return decodeJSON[authResponse](t, rec)
+}
+
+func authRequestTo(method, path, token string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestRefresh_RotatesAndDetectsReuse(t *testing.T) {
+	h, ms := newTestHandler()
+	mux := newSessionMux(h, ms)
+
+	first := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+	if first.RefreshToken == "" || !first.ExpiresAt.After(time.Now()) {
+		t.Fatalf("register response = %+v", first)
+	}
+
+	second := signIn(t, mux, "/api/v1/auth/refresh", map[string]string{"refreshToken": first.RefreshToken})
+	if second.RefreshToken == first.RefreshToken || second.User.Email != "a@b.com" {
+		t.Fatalf("refresh response = %+v", second)
+	}
+	if len(ms.sessions) != 1 {
+		t.Fatalf("sessions = %d, want 1", len(ms.sessions))
+	}
+
+	// The new access token works.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", second.Token, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list sessions: status = %d", rec.Code)
+	}
+
+	// Replaying the rotated token revokes the session.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/refresh", jsonBody(map[string]string{"refreshToken": first.RefreshToken})))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reuse: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if len(ms.sessions) != 0 {
+		t.Errorf("session should be revoked after reuse, %d left", len(ms.sessions))
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/refresh", jsonBody(map[string]string{"refreshToken": second.RefreshToken})))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after revocation: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", second.Token, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("access token of revoked session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRefresh_ConcurrentReuse(t *testing.T) {
+	for _, st := range testStores {
+		if st.name == "mock" { // the mock is not safe for concurrent use
+			continue
+		}
+		t.Run(st.name, func(t *testing.T) {
+			h := New(st.open(t), slog.Default(), testJWTSecret, nil)
+			mux := newAuthMux(h)
+			auth := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+
+			// A stolen token replayed in parallel with the real one yields
+			// at most one new pair, and the session is revoked.
+			const n = 8
+			codes := make([]int, n)
+			var wg sync.WaitGroup
+			for i := range n {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rec := httptest.NewRecorder()
+					mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/refresh", jsonBody(map[string]string{"refreshToken": auth.RefreshToken})))
+					codes[i] = rec.Code
+				}()
+			}
+			wg.Wait()
+
+			if ok := slices.Index(codes, http.StatusOK); ok >= 0 && slices.Index(codes[ok+1:], http.StatusOK) >= 0 {
+				t.Fatalf("statuses = %v, want at most one %d", codes, http.StatusOK)
+			}
+			sessionID, _, _ := strings.Cut(auth.RefreshToken, ".")
+			if _, err := h.store.GetSession(context.Background(), uuid.MustParse(sessionID)); !errors.Is(err, store.ErrNotFound) {
+				t.Errorf("session should be revoked after reuse, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRefresh_Invalid(t *testing.T) {
+	h, ms := newTestHandler()
+	mux := newSessionMux(h, ms)
+
+	auth := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+
+	for _, token := range []string{"", "garbage", uuid.NewString() + ".secret"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/refresh", jsonBody(map[string]string{"refreshToken": token})))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status = %d, want %d", token, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	// Expired sessions cannot be refreshed.
+	h.now = func() time.Time { return time.Now().Add(refreshTokenTTL + time.Minute) }
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/refresh", jsonBody(map[string]string{"refreshToken": auth.RefreshToken})))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if len(ms.sessions) != 0 {
+		t.Errorf("expired session should be deleted")
+	}
+}
+
+func testSessions_ListAndRevoke(t *testing.T, h *Handler) {
+	mux := newSessionMux(h, h.store)
+
+	creds := map[string]string{"email": "a@b.com", "password": "pass"}
+	laptop := signIn(t, mux, "/api/v1/auth/register", creds)
+	phone := signIn(t, mux, "/api/v1/auth/login", creds)
+	tablet := signIn(t, mux, "/api/v1/auth/login", creds)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", laptop.Token, nil))
+	sessions := decodeJSON[[]sessionResponse](t, rec)
+	if len(sessions) != 3 {
+		t.Fatalf("sessions = %d, want 3", len(sessions))
+	}
+	var current int
+	for _, s := range sessions {
+		if s.Current {
+			current++
+		}
+	}
+	if current != 1 {
+		t.Errorf("expected exactly one current session, got %d", current)
+	}
+
+	// Revoke the phone by ID.
+	phoneID, _, _ := strings.Cut(phone.RefreshToken, ".")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/sessions/"+phoneID, laptop.Token, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", phone.Token, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked phone: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// Revoke everything but the laptop.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/sessions", laptop.Token, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke others: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", tablet.Token, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked tablet: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", laptop.Token, nil))
+	if got := decodeJSON[[]sessionResponse](t, rec); len(got) != 1 || !got[0].Current {
+		t.Errorf("remaining sessions = %+v", got)
+	}
+
+	// Sessions of other users cannot be revoked.
+	other := model.Session{ID: uuid.New(), UserID: "someone-else", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := h.store.CreateSession(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/sessions/"+other.ID.String(), laptop.Token, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("foreign session: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestChangePassword_RevokesOtherSessions(t *testing.T) {
+	h, ms := newTestHandler()
+	mux := newSessionMux(h, ms)
+
+	creds := map[string]string{"email": "a@b.com", "password": "oldpass"}
+	current := signIn(t, mux, "/api/v1/auth/register", creds)
+	other := signIn(t, mux, "/api/v1/auth/login", creds)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("PUT", "/api/v1/settings/password", current.Token, jsonBody(map[string]string{
+		"currentPassword": "oldpass",
+		"newPassword":     "newpass",
+	})))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("change password: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", other.Token, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("other device: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", current.Token, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("current device: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+// Password reset tests
+
+type sentMail struct {
+	to      []string
+	subject string
+	body    string
+}
+
+func newResetMux(h *Handler) (http.Handler, chan sentMail) {
+	mails := make(chan sentMail, 10)
+	h.sendMail = func(to []string, subject, body string) error {
+		mails <- sentMail{to, subject, body}
+		return nil
+	}
+	h.SetBaseURL("https://contracts.example.com")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/forgot-password", h.ForgotPassword)
+	mux.HandleFunc("POST /api/v1/auth/reset-password", h.ResetPassword)
+	mux.HandleFunc("POST /api/v1/auth/verify-email", h.VerifyEmail)
+	mux.Handle("/", newSessionMux(h, h.store))
+	return mux, mails
+}
+
+// registerWithMail registers an account and takes the verification email
+// sent to it.
+func registerWithMail(t *testing.T, mux http.Handler, mails chan sentMail, creds map[string]string) (authResponse, sentMail) {
+	t.Helper()
+	auth := signIn(t, mux, "/api/v1/auth/register", creds)
+	return auth, nextMail(t, mails)
+}
+
+func nextMail(t *testing.T, mails chan sentMail) sentMail {
+	t.Helper()
+	select {
+	case m := <-mails:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("no mail sent")
+		return sentMail{}
+	}
+}
+
+func resetTokenFrom(t *testing.T, m sentMail) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(m.body, "/reset-password?token=")
+	if !ok {
+		t.Fatalf("no reset link in %q", m.body)
+	}
+	token, _, _ := strings.Cut(rest, "\n")
+	return token
+}
+
+func TestForgotPassword_SameResponseForUnknownEmail(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, mails := newResetMux(h)
+	registerWithMail(t, mux, mails, map[string]string{"email": "a@b.com", "password": "pass"})
+
+	var bodies []string
+	for _, addr := range []string{"a@b.com", "nobody@b.com"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/forgot-password", jsonBody(map[string]string{"email": addr})))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("%s: status = %d, want %d", addr, rec.Code, http.StatusAccepted)
+		}
+		bodies = append(bodies, rec.Body.String())
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("responses differ: %q vs %q", bodies[0], bodies[1])
+	}
+
+	m := nextMail(t, mails)
+	if len(m.to) != 1 || m.to[0] != "a@b.com" || !strings.Contains(m.body, "https://contracts.example.com/reset-password?token=") {
+		t.Errorf("mail = %+v", m)
+	}
+	if len(ms.resets) != 1 {
+		t.Errorf("stored reset tokens = %d, want 1", len(ms.resets))
+	}
+	select {
+	case m := <-mails:
+		t.Errorf("unexpected mail to %v", m.to)
+	default:
+	}
+}
+
+func testForgotPassword_Localized(t *testing.T, h *Handler) {
+	mux, mails := newResetMux(h)
+	registerWithMail(t, mux, mails, map[string]string{"email": "a@b.com", "password": "pass"})
+
+	req := httptest.NewRequest("POST", "/api/v1/auth/forgot-password", jsonBody(map[string]string{"email": "a@b.com"}))
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	if m := nextMail(t, mails); m.subject != "Passwort zurücksetzen" {
+		t.Errorf("subject = %q, want German", m.subject)
+	}
+}
+
+func testForgotPassword_RateLimitedPerEmail(t *testing.T, h *Handler) {
+	mux, mails := newResetMux(h)
+	registerWithMail(t, mux, mails, map[string]string{"email": "a@b.com", "password": "pass"})
+
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/forgot-password", jsonBody(map[string]string{"email": "a@b.com"})))
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("request %d: status = %d", i, rec.Code)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		nextMail(t, mails)
+	}
+	select {
+	case <-mails:
+		t.Error("expected at most 3 mails per address")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, mails := newResetMux(h)
+	creds := map[string]string{"email": "a@b.com", "password": "oldpass"}
+	session, _ := registerWithMail(t, mux, mails, creds)
+	key := model.APIKey{ID: uuid.New(), UserID: session.User.ID.String(), Name: "script", KeyHash: "hash"}
+	ms.apiKeys[key.ID] = key
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/auth/forgot-password", jsonBody(map[string]string{"email": "a@b.com"})))
+	token := resetTokenFrom(t, nextMail(t, mails))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/reset-password", jsonBody(map[string]string{"token": token, "password": "newpass"})))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	// The new password works and existing sessions and API keys are gone.
+	signIn(t, mux, "/api/v1/auth/login", map[string]string{"email": "a@b.com", "password": "newpass"})
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", session.Token, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("old session: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if _, ok := ms.apiKeys[key.ID]; ok {
+		t.Error("API key survived the reset")
+	}
+
+	// Tokens are single-use.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/reset-password", jsonBody(map[string]string{"token": token, "password": "again"})))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("reuse: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// Expired tokens are rejected.
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/auth/forgot-password", jsonBody(map[string]string{"email": "a@b.com"})))
+	token = resetTokenFrom(t, nextMail(t, mails))
+	h.now = func() time.Time { return time.Now().Add(passwordResetTTL + time.Minute) }
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/reset-password", jsonBody(map[string]string{"token": token, "password": "again"})))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expired: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if len(ms.resets) != 0 {
+		t.Errorf("expired token should be consumed")
+	}
+}
+
+// verifyTokenFrom returns the token of the verification link in m.
+func verifyTokenFrom(t *testing.T, m sentMail) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(m.body, "/verify-email?token=")
+	if !ok {
+		t.Fatalf("no verification link in %q", m.body)
+	}
+	token, _, _ := strings.Cut(rest, "\n")
+	return token
+}
+
+func verifyEmail(mux http.Handler, token string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/verify-email", jsonBody(map[string]string{"token": token})))
+	return rec
+}
+
+func TestRegister_VerifiesEmail(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, mails := newResetMux(h)
+	_, m := registerWithMail(t, mux, mails, map[string]string{"email": "a@b.com", "password": "pass"})
+	if len(m.to) != 1 || m.to[0] != "a@b.com" {
+		t.Fatalf("verification mail to %v", m.to)
+	}
+	user, _ := ms.GetUserByEmail(context.Background(), "a@b.com")
+	if user.EmailVerified {
+		t.Fatal("new account should not be verified yet")
+	}
+
+	rec := verifyEmail(mux, verifyTokenFrom(t, m))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got := decodeJSON[model.User](t, rec); !got.EmailVerified || got.Email != "a@b.com" {
+		t.Errorf("user = %+v", got)
+	}
+	if rec := verifyEmail(mux, verifyTokenFrom(t, m)); rec.Code != http.StatusBadRequest {
+		t.Errorf("reuse: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	select {
+	case m := <-mails:
+		t.Errorf("unexpected mail to %v", m.to)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func testResendEmailVerification(t *testing.T, h *Handler) {
+	mux, mails := newResetMux(h)
+	auth, first := registerWithMail(t, mux, mails, map[string]string{"email": "a@b.com", "password": "pass"})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/email/verification", auth.Token, nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("resend: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	second := nextMail(t, mails)
+
+	// Only the newest link works.
+	if rec := verifyEmail(mux, verifyTokenFrom(t, first)); rec.Code != http.StatusBadRequest {
+		t.Errorf("replaced token: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := verifyEmail(mux, verifyTokenFrom(t, second)); rec.Code != http.StatusOK {
+		t.Fatalf("verify: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/email/verification", auth.Token, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("already verified: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, mails := newResetMux(h)
+	creds := map[string]string{"email": "a@b.com", "password": "pass"}
+	auth, m := registerWithMail(t, mux, mails, creds)
+	verifyEmail(mux, verifyTokenFrom(t, m))
+	registerWithMail(t, mux, mails, map[string]string{"email": "taken@b.com", "password": "pass"})
+
+	change := func(body map[string]string) int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, authRequestTo("PUT", "/api/v1/settings/email", auth.Token, jsonBody(body)))
+		return rec.Code
+	}
+	if code := change(map[string]string{"email": "new@b.com", "password": "wrong"}); code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := change(map[string]string{"email": "taken@b.com", "password": "pass"}); code != http.StatusConflict {
+		t.Errorf("taken address: status = %d, want %d", code, http.StatusConflict)
+	}
+	if code := change(map[string]string{"email": "new@b.com", "password": "pass"}); code != http.StatusAccepted {
+		t.Fatalf("change: status = %d, want %d", code, http.StatusAccepted)
+	}
+	m = nextMail(t, mails)
+	if len(m.to) != 1 || m.to[0] != "new@b.com" {
+		t.Fatalf("confirmation mail to %v", m.to)
+	}
+
+	// Nothing changes until the new address is confirmed.
+	signIn(t, mux, "/api/v1/auth/login", creds)
+
+	if rec := verifyEmail(mux, verifyTokenFrom(t, m)); rec.Code != http.StatusOK {
+		t.Fatalf("verify: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if notice := nextMail(t, mails); len(notice.to) != 1 || notice.to[0] != "a@b.com" {
+		t.Errorf("change notice to %v", notice.to)
+	}
+	if _, err := ms.GetUserByEmail(context.Background(), "a@b.com"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("old address still resolves: %v", err)
+	}
+	signIn(t, mux, "/api/v1/auth/login", map[string]string{"email": "new@b.com", "password": "pass"})
+}
+
+func testVerifyEmail_ConflictAndExpiry(t *testing.T, h *Handler) {
+	mux, mails := newResetMux(h)
+	auth, _ := registerWithMail(t, mux, mails, map[string]string{"email": "a@b.com", "password": "pass"})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("PUT", "/api/v1/settings/email", auth.Token, jsonBody(map[string]string{"email": "new@b.com", "password": "pass"})))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("change: status = %d", rec.Code)
+	}
+	m := nextMail(t, mails)
+
+	// Someone registers the address before the link is opened.
+	registerWithMail(t, mux, mails, map[string]string{"email": "new@b.com", "password": "pass"})
+	if rec := verifyEmail(mux, verifyTokenFrom(t, m)); rec.Code != http.StatusConflict {
+		t.Errorf("taken meanwhile: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/email/verification", auth.Token, nil))
+	m = nextMail(t, mails)
+	h.now = func() time.Time { return time.Now().Add(emailVerificationTTL + time.Minute) }
+	if rec := verifyEmail(mux, verifyTokenFrom(t, m)); rec.Code != http.StatusBadRequest {
+		t.Errorf("expired: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// Two-factor authentication tests
+
+func TestTwoFactor_EnrolLoginDisable(t *testing.T) {
+	h, ms := newTestHandler()
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newSessionMux(h, ms)
+
+	creds := map[string]string{"email": "a@b.com", "password": "pass"}
+	auth := signIn(t, mux, "/api/v1/auth/register", creds)
+
+	// Enrol.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/2fa", auth.Token, nil))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("begin: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	enrol := decodeJSON[twoFactorEnrolment](t, rec)
+	if !strings.HasPrefix(enrol.URI, "otpauth://totp/Contracts:a@b.com?") {
+		t.Errorf("uri = %s", enrol.URI)
+	}
+	code := func() string {
+		c, _ := totp.Code(enrol.Secret, totp.Step(now))
+		return c
+	}
+
+	// Login is unaffected until the secret is confirmed.
+	signIn(t, mux, "/api/v1/auth/login", creds)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/2fa/confirm", auth.Token, jsonBody(map[string]string{"code": "000000"})))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("confirm with wrong code: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/2fa/confirm", auth.Token, jsonBody(map[string]string{"code": code()})))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirm: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	recovery := decodeJSON[recoveryCodesResponse](t, rec).RecoveryCodes
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("recovery codes = %d, want %d", len(recovery), recoveryCodeCount)
+	}
+	for _, hash := range ms.twoFactor[testUserIDOf(t, ms, "a@b.com")].RecoveryCodes {
+		if slices.Contains(recovery, hash) {
+			t.Error("recovery codes must be stored hashed")
+		}
+	}
+
+	// Login now asks for a second step.
+	login := func() challengeResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(creds)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("login: status = %d", rec.Code)
+		}
+		c := decodeJSON[challengeResponse](t, rec)
+		if !c.TwoFactorRequired || c.ChallengeToken == "" {
+			t.Fatalf("login response = %+v, want challenge", c)
+		}
+		return c
+	}
+	secondStep := func(challenge, code string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login/2fa", jsonBody(map[string]string{"challengeToken": challenge, "code": code})))
+		return rec
+	}
+
+	c := login()
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", c.ChallengeToken, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("challenge used as access token: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := secondStep(c.ChallengeToken, code()); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed confirmation code: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	now = now.Add(totp.Period)
+	if rec := secondStep(c.ChallengeToken, code()); rec.Code != http.StatusOK {
+		t.Fatalf("second step: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+
+	// Recovery codes work once.
+	c = login()
+	if rec := secondStep(c.ChallengeToken, strings.ToUpper(recovery[0])); rec.Code != http.StatusOK {
+		t.Errorf("recovery code: status = %d", rec.Code)
+	}
+	if rec := secondStep(c.ChallengeToken, recovery[0]); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused recovery code: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// Challenges expire.
+	now = now.Add(challengeTTL + totp.Period)
+	if rec := secondStep(c.ChallengeToken, code()); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired challenge: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	auth = signIn(t, mux, "/api/v1/auth/login/2fa", map[string]string{"challengeToken": login().ChallengeToken, "code": recovery[1]})
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/settings/2fa", auth.Token, nil))
+	if got := decodeJSON[twoFactorStatus](t, rec); !got.Enabled || got.RecoveryCodesRemaining != recoveryCodeCount-2 {
+		t.Errorf("status = %+v", got)
+	}
+
+	// Disable.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/settings/2fa", auth.Token, jsonBody(map[string]string{"code": "000000"})))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("disable with wrong code: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/settings/2fa", auth.Token, jsonBody(map[string]string{"code": code()})))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("disable: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if got := signIn(t, mux, "/api/v1/auth/login", creds); got.Token == "" {
+		t.Error("login after disabling should return a token")
+	}
+}
+
+func TestTwoFactor_BeginWhenEnabled(t *testing.T) {
+	h, ms := newTestHandler()
+	mux := newSessionMux(h, ms)
+	auth := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+	ms.twoFactor[testUserIDOf(t, ms, "a@b.com")] = model.TwoFactor{Secret: "ABC", Enabled: true}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/2fa", auth.Token, nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestTwoFactor_LocksOutAccount(t *testing.T) {
+	h, ms := newTestHandler()
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newAuthMux(h)
+
+	creds := map[string]string{"email": "a@b.com", "password": "pass"}
+	signIn(t, mux, "/api/v1/auth/register", creds)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.twoFactor[testUserIDOf(t, ms, "a@b.com")] = model.TwoFactor{Secret: secret, Enabled: true}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(creds)))
+	c := decodeJSON[challengeResponse](t, rec)
+
+	secondStep := func(code, remoteAddr string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/auth/login/2fa", jsonBody(map[string]string{"challengeToken": c.ChallengeToken, "code": code}))
+		req.RemoteAddr = remoteAddr
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Guessing codes from many addresses with the same challenge still
+	// locks the account.
+	for i := range accountThrottle.lockAfter {
+		now = now.Add(accountThrottle.delay(i))
+		if rec := secondStep("000000", fmt.Sprintf("203.0.113.%d:1234", i)); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i+1, rec.Code)
+		}
+	}
+	code, _ := totp.Code(secret, totp.Step(now))
+	rec = secondStep(code, "198.51.100.1:1234")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1800" {
+		t.Fatalf("locked: status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// Signing in with the password again does not reset the count.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(creds)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("login while locked: status = %d, want 429", rec.Code)
+	}
+}
+
+func TestTwoFactor_DisableLocksOutAccount(t *testing.T) {
+	h, ms := newTestHandler()
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newSessionMux(h, ms)
+
+	auth := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.twoFactor[auth.User.ID.String()] = model.TwoFactor{Secret: secret, Enabled: true}
+	disable := func(code string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/settings/2fa", auth.Token, jsonBody(map[string]string{"code": code})))
+		return rec
+	}
+
+	// Guessing the code with a stolen access token locks the account.
+	for i := range accountThrottle.lockAfter {
+		now = now.Add(accountThrottle.delay(i))
+		if rec := disable("000000"); rec.Code != http.StatusBadRequest {
+			t.Fatalf("failure %d: status = %d, want 400", i+1, rec.Code)
+		}
+	}
+	code, _ := totp.Code(secret, totp.Step(now))
+	if rec := disable(code); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked: status = %d, want 429", rec.Code)
+	}
+	if !ms.twoFactor[auth.User.ID.String()].Enabled {
+		t.Error("2FA was disabled while locked")
+	}
+}
+
+func TestTwoFactor_LoginRefusesDisabledAccount(t *testing.T) {
+	h, ms := newTestHandler()
+	mux := newAuthMux(h)
+
+	creds := map[string]string{"email": "a@b.com", "password": "pass"}
+	user := signIn(t, mux, "/api/v1/auth/register", creds).User
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.twoFactor[user.ID.String()] = model.TwoFactor{Secret: secret, Enabled: true}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(creds)))
+	c := decodeJSON[challengeResponse](t, rec)
+
+	// The account is disabled between the password and the code.
+	sessions := len(ms.sessions)
+	u := ms.users["a@b.com"]
+	u.Disabled = true
+	ms.UpdateUser(context.Background(), u)
+
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login/2fa", jsonBody(map[string]string{"challengeToken": c.ChallengeToken, "code": code})))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+	if len(ms.sessions) != sessions {
+		t.Error("a session was started for the disabled account")
+	}
+}
+
+func testUserIDOf(t *testing.T, st store.Store, email string) string {
+	t.Helper()
+	u, err := st.GetUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatalf("no user %s: %v", email, err)
+	}
+	return u.ID.String()
+}
+
+// API key tests
+
+func newAPIKeyMux(h *Handler, ms *mockStore) http.Handler {
+	data := http.NewServeMux()
+	data.HandleFunc("GET /api/v1/contracts", h.ListContracts)
+	data.HandleFunc("GET /api/v1/purchases", h.ListPurchases)
+	data.HandleFunc("POST /api/v1/modules/{module}/categories", h.CreateCategory)
+
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/settings/api-keys", h.ListAPIKeys)
+	api.HandleFunc("POST /api/v1/settings/api-keys", h.CreateAPIKey)
+	api.HandleFunc("DELETE /api/v1/settings/api-keys/{id}", h.DeleteAPIKey)
+	api.Handle("/api/v1/", middleware.Workspace(ms)(data))
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/auth/", newAuthMux(h))
+	mux.Handle("/api/v1/", middleware.Auth(testJWTSecret, ms)(api))
+	return mux
+}
+
+func createAPIKey(t *testing.T, mux http.Handler, token string, body map[string]any) createdAPIKey {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/api-keys", token, jsonBody(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create API key: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	return decodeJSON[createdAPIKey](t, rec)
+}
+
+func TestAPIKeys_CreateListDelete(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	mux := newAPIKeyMux(h, ms)
+	auth := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+
+	created := createAPIKey(t, mux, auth.Token, map[string]any{"name": "backup", "access": "read"})
+	if !strings.HasPrefix(created.Key, middleware.APIKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Fatalf("created key = %+v", created)
+	}
+	stored := ms.apiKeys[created.ID]
+	if stored.KeyHash == "" || strings.Contains(stored.KeyHash, created.Key) {
+		t.Errorf("stored hash = %q", stored.KeyHash)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/settings/api-keys", auth.Token, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: status = %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), created.Key) || strings.Contains(rec.Body.String(), stored.KeyHash) {
+		t.Errorf("list leaks the key: %s", rec.Body.String())
+	}
+	if list := decodeJSON[[]model.APIKey](t, rec); len(list) != 1 || list[0].Name != "backup" {
+		t.Errorf("list = %+v", list)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/settings/api-keys/"+created.ID.String(), auth.Token, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/contracts", created.Key, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("deleted key: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestAPIKeys_Validation(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	mux := newAPIKeyMux(h, ms)
+	auth := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+
+	for name, body := range map[string]map[string]any{
+		"missing name":   {"access": "read"},
+		"bad access":     {"name": "k", "access": "admin"},
+		"unknown module": {"name": "k", "access": "read", "modules": []string{"settings"}},
+		"past expiry":    {"name": "k", "access": "read", "expiresAt": time.Now().Add(-time.Hour)},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/settings/api-keys", auth.Token, jsonBody(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
+
+func TestAPIKeys_Scopes(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	mux := newAPIKeyMux(h, ms)
+	auth := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+
+	readOnly := createAPIKey(t, mux, auth.Token, map[string]any{"name": "read", "access": "read", "modules": []string{"contracts"}})
+	write := createAPIKey(t, mux, auth.Token, map[string]any{"name": "write", "access": "write"})
+	expires := time.Now().Add(time.Hour)
+	expiring := createAPIKey(t, mux, auth.Token, map[string]any{"name": "old", "access": "write", "expiresAt": expires})
+	k := ms.apiKeys[expiring.ID]
+	past := time.Now().Add(-time.Minute)
+	k.ExpiresAt = &past
+	ms.apiKeys[k.ID] = k
+
+	categoryBody := func() io.Reader { return jsonBody(map[string]string{"name": "Streaming"}) }
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   func() io.Reader
+		want   int
+	}{
+		{"read in scope", readOnly.Key, "GET", "/api/v1/contracts", nil, http.StatusOK},
+		{"read other module", readOnly.Key, "GET", "/api/v1/purchases", nil, http.StatusForbidden},
+		{"write with read-only key", readOnly.Key, "POST", "/api/v1/modules/contracts/categories", categoryBody, http.StatusForbidden},
+		{"write with write key", write.Key, "POST", "/api/v1/modules/contracts/categories", categoryBody, http.StatusCreated},
+		{"unrestricted read", write.Key, "GET", "/api/v1/purchases", nil, http.StatusOK},
+		{"settings are off limits", write.Key, "GET", "/api/v1/settings/api-keys", nil, http.StatusForbidden},
+		{"expired key", expiring.Key, "GET", "/api/v1/contracts", nil, http.StatusUnauthorized},
+		{"unknown key", middleware.APIKeyPrefix + "nope", "GET", "/api/v1/contracts", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		var body io.Reader
+		if tt.body != nil {
+			body = tt.body()
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, authRequestTo(tt.method, tt.path, tt.key, body))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d; body: %s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+
+	if ms.apiKeys[readOnly.ID].LastUsedAt == nil {
+		t.Error("last-used time was not recorded")
+	}
+	if ms.apiKeys[expiring.ID].LastUsedAt != nil {
+		t.Error("rejected key should not be marked as used")
+	}
+}
+
+// Workspace tests
+
+func newWorkspaceMux(h *Handler) http.Handler {
+	data := http.NewServeMux()
+	data.HandleFunc("GET /api/v1/contracts", h.ListContracts)
+	data.HandleFunc("POST /api/v1/modules/{module}/categories", h.CreateCategory)
+
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/workspaces", h.ListWorkspaces)
+	api.HandleFunc("POST /api/v1/workspaces", h.CreateWorkspace)
+	api.HandleFunc("DELETE /api/v1/workspaces/{id}", h.DeleteWorkspace)
+	api.HandleFunc("GET /api/v1/workspaces/{id}/members", h.ListWorkspaceMembers)
+	api.HandleFunc("PUT /api/v1/workspaces/{id}/members/{userId}", h.UpdateWorkspaceMember)
+	api.HandleFunc("DELETE /api/v1/workspaces/{id}/members/{userId}", h.RemoveWorkspaceMember)
+	api.HandleFunc("POST /api/v1/workspaces/{id}/invitations", h.CreateInvitation)
+	api.HandleFunc("POST /api/v1/invitations/accept", h.AcceptInvitation)
+	api.Handle("/api/v1/", middleware.Workspace(h.store)(data))
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/auth/", newAuthMux(h))
+	mux.Handle("/api/v1/", middleware.Auth(testJWTSecret, h.store)(api))
+	return mux
+}
+
+func testWorkspaces_InviteAndRoles(t *testing.T, h *Handler) {
+	h.now = time.Now
+	mux := newWorkspaceMux(h)
+
+	alice := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "alice@example.com", "password": "pass"}).Token
+	bob := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "bob@example.com", "password": "pass"}).Token
+	bobID := testUserIDOf(t, h.store, "bob@example.com")
+
+	do := func(method, path, token, workspace string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var r io.Reader
+		if body != nil {
+			r = jsonBody(body)
+		}
+		req := authRequestTo(method, path, token, r)
+		if workspace != "" {
+			req.Header.Set(middleware.WorkspaceHeader, workspace)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("POST", "/api/v1/workspaces", alice, "", map[string]string{"name": "Home"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create workspace: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	ws := decodeJSON[workspaceResponse](t, rec)
+	wsPath := "/api/v1/workspaces/" + ws.ID.String()
+
+	// Bob is not a member yet.
+	if rec := do("GET", "/api/v1/contracts", bob, ws.ID.String(), nil); rec.Code != http.StatusForbidden {
+		t.Errorf("non-member: status = %d, want 403", rec.Code)
+	}
+	if rec := do("POST", wsPath+"/invitations", bob, "", map[string]string{"email": "bob@example.com", "role": "viewer"}); rec.Code != http.StatusNotFound {
+		t.Errorf("non-member invite: status = %d, want 404", rec.Code)
+	}
+
+	rec = do("POST", wsPath+"/invitations", alice, "", map[string]string{"email": "bob@example.com", "role": "owner"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invite as owner: status = %d, want 400", rec.Code)
+	}
+	rec = do("POST", wsPath+"/invitations", alice, "", map[string]string{"email": "bob@example.com", "role": "viewer"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("invite: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	invite := decodeJSON[createdInvitation](t, rec)
+
+	// A member cannot accept it, and trying does not use it up.
+	if rec := do("POST", "/api/v1/invitations/accept", alice, "", map[string]string{"token": invite.Token}); rec.Code != http.StatusConflict {
+		t.Errorf("accept as member: status = %d, want 409", rec.Code)
+	}
+	rec = do("POST", "/api/v1/invitations/accept", bob, "", map[string]string{"token": invite.Token})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("accept: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("POST", "/api/v1/invitations/accept", bob, "", map[string]string{"token": invite.Token}); rec.Code != http.StatusBadRequest {
+		t.Errorf("reused invitation: status = %d, want 400", rec.Code)
+	}
+
+	rec = do("GET", "/api/v1/workspaces", bob, "", nil)
+	list := decodeJSON[[]workspaceResponse](t, rec)
+	if len(list) != 2 || !list[0].Personal || list[1].ID != ws.ID || list[1].Role != model.WorkspaceRoleViewer {
+		t.Fatalf("bob's workspaces = %+v", list)
+	}
+	rec = do("GET", wsPath+"/members", bob, "", nil)
+	if members := decodeJSON[[]memberResponse](t, rec); len(members) != 2 {
+		t.Errorf("expected 2 members, got %+v", members)
+	}
+
+	// Viewers can read but not write.
+	if rec := do("GET", "/api/v1/contracts", bob, ws.ID.String(), nil); rec.Code != http.StatusOK {
+		t.Errorf("viewer read: status = %d, want 200", rec.Code)
+	}
+	category := map[string]string{"name": "Shared"}
+	if rec := do("POST", "/api/v1/modules/contracts/categories", bob, ws.ID.String(), category); rec.Code != http.StatusForbidden {
+		t.Errorf("viewer write: status = %d, want 403", rec.Code)
+	}
+	if rec := do("PUT", wsPath+"/members/"+bobID, bob, "", map[string]string{"role": "editor"}); rec.Code != http.StatusForbidden {
+		t.Errorf("viewer promoting self: status = %d, want 403", rec.Code)
+	}
+
+	if rec := do("PUT", wsPath+"/members/"+bobID, alice, "", map[string]string{"role": "editor"}); rec.Code != http.StatusNoContent {
+		t.Fatalf("promote: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("POST", "/api/v1/modules/contracts/categories", bob, ws.ID.String(), category); rec.Code != http.StatusCreated {
+		t.Errorf("editor write: status = %d, want 201", rec.Code)
+	}
+	if rec := do("DELETE", wsPath, bob, "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("editor deleting workspace: status = %d, want 403", rec.Code)
+	}
+
+	aliceID := testUserIDOf(t, h.store, "alice@example.com")
+	if rec := do("DELETE", wsPath+"/members/"+aliceID, alice, "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("owner leaving: status = %d, want 400", rec.Code)
+	}
+	if rec := do("DELETE", wsPath+"/members/"+bobID, bob, "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("leave: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("GET", "/api/v1/contracts", bob, ws.ID.String(), nil); rec.Code != http.StatusForbidden {
+		t.Errorf("after leaving: status = %d, want 403", rec.Code)
+	}
+
+	aliceUser, _ := h.store.GetUserByEmail(context.Background(), "alice@example.com")
+	personal := aliceUser.PersonalWorkspaceID
+	if rec := do("DELETE", "/api/v1/workspaces/"+personal.String(), alice, "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("deleting personal workspace: status = %d, want 400", rec.Code)
+	}
+	if rec := do("DELETE", wsPath, alice, "", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("delete workspace: status = %d, want 204", rec.Code)
+	}
+}
+
+func testWorkspaces_DefaultsToPersonal(t *testing.T, h *Handler) {
+	h.now = time.Now
+	mux := newWorkspaceMux(h)
+
+	token := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "alice@example.com", "password": "pass"}).Token
+	user, err := h.store.GetUserByEmail(context.Background(), "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.PersonalWorkspaceID == uuid.Nil {
+		t.Fatal("register should create a personal workspace")
+	}
+	if _, err := h.store.GetWorkspaceMember(context.Background(), user.PersonalWorkspaceID, user.ID.String()); err != nil {
+		t.Fatal("user should own their personal workspace")
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/contracts", token, nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("without header: status = %d, want 200", rec.Code)
+	}
+
+	req := authRequestTo("GET", "/api/v1/contracts", token, nil)
+	req.Header.Set(middleware.WorkspaceHeader, "not-a-uuid")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid header: status = %d, want 400", rec.Code)
+	}
+}
+
+// Admin and registration tests
+
+func newAdminMux(h *Handler, ms *mockStore) http.Handler {
+	admin := http.NewServeMux()
+	admin.HandleFunc("GET /api/v1/admin/users", h.ListUsers)
+	admin.HandleFunc("PUT /api/v1/admin/users/{id}", h.UpdateUser)
+	admin.HandleFunc("DELETE /api/v1/admin/users/{id}", h.DeleteUser)
+	admin.HandleFunc("POST /api/v1/admin/users/{id}/reset-password", h.ForcePasswordReset)
+	admin.HandleFunc("POST /api/v1/admin/backups", h.CreateBackup)
+
+	api := http.NewServeMux()
+	api.Handle("/api/v1/admin/", middleware.Admin(ms)(admin))
+	api.HandleFunc("POST /api/v1/workspaces/{id}/invitations", h.CreateInvitation)
+	api.HandleFunc("POST /api/v1/settings/api-keys", h.CreateAPIKey)
+	api.HandleFunc("GET /api/v1/settings/api-keys", h.ListAPIKeys)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/auth/", newAuthMux(h))
+	mux.HandleFunc("POST /api/v1/auth/reset-password", h.ResetPassword)
+	mux.Handle("/api/v1/", middleware.Auth(testJWTSecret, ms)(api))
+	return mux
+}
+
+func TestRegister_Modes(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	h.SetRegistration(config.RegistrationClosed)
+	mux := newAdminMux(h, ms)
+
+	register := func(body map[string]string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/register", jsonBody(body)))
+		return rec
+	}
+
+	// The first account is allowed even when registration is closed.
+	rec := register(map[string]string{"email": "admin@example.com", "password": "pass"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("first user: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	admin := decodeJSON[authResponse](t, rec)
+	if !admin.User.Admin {
+		t.Error("first user should be an admin")
+	}
+	if rec := register(map[string]string{"email": "bob@example.com", "password": "pass"}); rec.Code != http.StatusForbidden {
+		t.Errorf("closed: status = %d, want 403", rec.Code)
+	}
+
+	h.SetRegistration(config.RegistrationInvite)
+	if rec := register(map[string]string{"email": "bob@example.com", "password": "pass"}); rec.Code != http.StatusForbidden {
+		t.Errorf("invite-only without token: status = %d, want 403", rec.Code)
+	}
+	if rec := register(map[string]string{"email": "bob@example.com", "password": "pass", "invitationToken": "bogus"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invite-only with unknown token: status = %d, want 400", rec.Code)
+	}
+
+	wsID := admin.User.PersonalWorkspaceID
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/workspaces/"+wsID.String()+"/invitations", admin.Token,
+		jsonBody(map[string]string{"email": "bob@example.com", "role": "viewer"})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("invite: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	invite := decodeJSON[createdInvitation](t, rec)
+
+	rec = register(map[string]string{"email": "bob@example.com", "password": "pass", "invitationToken": invite.Token})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("invite-only with token: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	bob := decodeJSON[authResponse](t, rec)
+	if bob.User.Admin {
+		t.Error("later users should not be admins")
+	}
+	if m, ok := ms.members[wsID][bob.User.ID.String()]; !ok || m.Role != model.WorkspaceRoleViewer {
+		t.Errorf("bob should have joined the inviting workspace as viewer, got %+v", m)
+	}
+}
+
+// conflictingUsers refuses every new user as if the address had just been
+// registered by someone else.
+type conflictingUsers struct{ *mockStore }
+
+func (conflictingUsers) CreateUser(context.Context, model.User) error { return store.ErrConflict }
+
+func TestRegister_KeepsInvitationWhenAccountFails(t *testing.T) {
+	ms := newMockStore()
+	ctx := context.Background()
+	ms.CreateUser(ctx, model.User{ID: uuid.New(), Email: "admin@example.com"})
+	inv := model.Invitation{ID: uuid.New(), WorkspaceID: uuid.New(), Role: model.WorkspaceRoleViewer, TokenHash: hashToken("token"), ExpiresAt: time.Now().Add(time.Hour)}
+	ms.CreateInvitation(ctx, inv)
+	h := New(conflictingUsers{ms}, slog.Default(), testJWTSecret, nil)
+	h.now = time.Now
+	h.SetRegistration(config.RegistrationInvite)
+	mux := newAuthMux(h)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/register", jsonBody(map[string]string{"email": "bob@example.com", "password": "pass", "invitationToken": "token"})))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", rec.Code)
+	}
+	if _, ok := ms.invites[inv.ID]; !ok {
+		t.Error("invitation was used up by the failed registration")
+	}
+}
+
+func TestAdmin_ManageUsers(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	mux := newAdminMux(h, ms)
+
+	admin := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "admin@example.com", "password": "pass"})
+	bob := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "bob@example.com", "password": "pass"})
+	bobPath := "/api/v1/admin/users/" + bob.User.ID.String()
+	key := createAPIKey(t, mux, bob.Token, map[string]any{"name": "script", "access": "read"})
+
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var r io.Reader
+		if body != nil {
+			r = jsonBody(body)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, authRequestTo(method, path, token, r))
+		return rec
+	}
+
+	if rec := do("GET", "/api/v1/admin/users", bob.Token, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+	rec := do("GET", "/api/v1/admin/users", admin.Token, nil)
+	if users := decodeJSON[[]model.User](t, rec); len(users) != 2 || users[0].Email != "admin@example.com" {
+		t.Fatalf("users = %+v", users)
+	}
+	if rec := do("PUT", "/api/v1/admin/users/"+admin.User.ID.String(), admin.Token, map[string]bool{"disabled": true}); rec.Code != http.StatusBadRequest {
+		t.Errorf("disabling self: status = %d, want 400", rec.Code)
+	}
+
+	// Disabling signs bob out, and neither password nor API key work.
+	if rec := do("PUT", bobPath, admin.Token, map[string]bool{"disabled": true}); rec.Code != http.StatusOK {
+		t.Fatalf("disable: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("GET", "/api/v1/settings/api-keys", bob.Token, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("disabled session: status = %d, want 401", rec.Code)
+	}
+	if rec := do("GET", "/api/v1/settings/api-keys", key.Key, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("disabled API key: status = %d, want 401", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(map[string]string{"email": "bob@example.com", "password": "pass"})))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("disabled login: status = %d, want 403", rec.Code)
+	}
+	if len(ms.apiKeys) != 0 {
+		t.Errorf("disabling should revoke bob's API keys, %d left", len(ms.apiKeys))
+	}
+	if rec := do("PUT", bobPath, admin.Token, map[string]bool{"disabled": false}); rec.Code != http.StatusOK {
+		t.Fatalf("enable: status = %d", rec.Code)
+	}
+	bob = signIn(t, mux, "/api/v1/auth/login", map[string]string{"email": "bob@example.com", "password": "pass"})
+	key = createAPIKey(t, mux, bob.Token, map[string]any{"name": "script", "access": "read"})
+
+	// A forced reset invalidates the old password and revokes API keys.
+	rec = do("POST", bobPath+"/reset-password", admin.Token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("force reset: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	reset := decodeJSON[forcedReset](t, rec)
+	if reset.Token == "" || reset.Emailed {
+		t.Errorf("force reset = %+v", reset)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(map[string]string{"email": "bob@example.com", "password": "pass"})))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("old password after forced reset: status = %d, want 401", rec.Code)
+	}
+	if rec := do("GET", "/api/v1/settings/api-keys", key.Key, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("API key after forced reset: status = %d, want 401", rec.Code)
+	}
+	if len(ms.apiKeys) != 0 {
+		t.Errorf("forced reset should revoke bob's API keys, %d left", len(ms.apiKeys))
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/reset-password", jsonBody(map[string]string{"token": reset.Token, "password": "new"})))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	signIn(t, mux, "/api/v1/auth/login", map[string]string{"email": "bob@example.com", "password": "new"})
+
+	// Deleting removes the account, its workspace and its API keys.
+	if rec := do("DELETE", bobPath, admin.Token, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := ms.usersById[bob.User.ID.String()]; ok {
+		t.Error("bob should be deleted")
+	}
+	if _, ok := ms.workspaces[bob.User.PersonalWorkspaceID]; ok {
+		t.Error("bob's personal workspace should be deleted")
+	}
+	if len(ms.apiKeys) != 0 {
+		t.Errorf("expected bob's API keys to be deleted, got %d", len(ms.apiKeys))
+	}
+	if rec := do("DELETE", bobPath, admin.Token, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("deleting twice: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestAdmin_CreateBackup(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	mux := newAdminMux(h, ms)
+
+	admin := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "admin@example.com", "password": "pass"})
+	bob := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "bob@example.com", "password": "pass"})
+	create := func(token string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, authRequestTo("POST", "/api/v1/admin/backups", token, nil))
+		return rec
+	}
+
+	if rec := create(admin.Token); rec.Code != http.StatusNotFound {
+		t.Errorf("not configured: status = %d, want 404", rec.Code)
+	}
+
+	src, err := store.NewBadgerStore(t.TempDir(), nil, slog.Default())
+	if err != nil {
+		t.Fatalf("NewBadgerStore: %v", err)
+	}
+	defer src.Close()
+	dir := t.TempDir()
+	h.SetBackups(backup.New(src, dir, 3, slog.Default()))
+
+	if rec := create(bob.Token); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status = %d, want 403", rec.Code)
+	}
+	rec := create(admin.Token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	info := decodeJSON[backup.Info](t, rec)
+	fi, err := os.Stat(filepath.Join(dir, info.Name))
+	if err != nil {
+		t.Fatalf("backup file: %v", err)
+	}
+	if fi.Size() != info.Size || info.Size == 0 {
+		t.Errorf("size = %d, file has %d bytes", info.Size, fi.Size())
+	}
+}
+
+func newOIDCMux(t *testing.T, h *Handler) (http.Handler, *oidctest.Provider) {
+	t.Helper()
+	fake := oidctest.NewProvider(t)
+	h.now = time.Now
+	h.SetBaseURL("https://contracts.example.com")
+	h.SetOIDC(oidc.New(oidc.Config{
+		Issuer:       fake.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "https://contracts.example.com" + OIDCCallbackPath,
+	}), nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/auth/oidc/login", h.OIDCLogin)
+	mux.HandleFunc("GET /api/v1/auth/oidc/callback", h.OIDCCallback)
+	mux.Handle("/", newAuthMux(h))
+	return mux, fake
+}
+
+// oidcSignIn signs in through the fake provider as its current user and
+// returns the fragment the callback redirects to the login page with.
+func oidcSignIn(t *testing.T, mux http.Handler) url.Values {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasSuffix(callback.Path, OIDCCallbackPath) {
+		t.Fatalf("authorize: redirected to %q", resp.Header.Get("Location"))
+	}
+
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound {
+		t.Fatalf("callback: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || loc.Path != "/login" {
+		t.Fatalf("callback: redirected to %q", rec.Header().Get("Location"))
+	}
+	fragment, err := url.ParseQuery(loc.Fragment)
+	if err != nil {
+		t.Fatalf("callback fragment: %v", err)
+	}
+	return fragment
+}
+
+func TestOIDC_ProvisionsAndLinks(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, fake := newOIDCMux(t, h)
+
+	// The first user is provisioned as admin and can trade the refresh
+	// token from the fragment for a normal session.
+	fake.User = oidctest.User{Subject: "sub-alice", Email: "alice@example.com", EmailVerified: true}
+	fragment := oidcSignIn(t, mux)
+	if fragment.Get("error") != "" {
+		t.Fatalf("error = %q", fragment.Get("error"))
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/refresh", jsonBody(map[string]string{"refreshToken": fragment.Get("refreshToken")})))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	resp := decodeJSON[authResponse](t, rec)
+	if resp.User.Email != "alice@example.com" || !resp.User.Admin || resp.Token == "" {
+		t.Errorf("user = %+v, want admin alice", resp.User)
+	}
+	alice := ms.users["alice@example.com"]
+	if alice.OIDCSubject != "sub-alice" || alice.PasswordHash != "" {
+		t.Errorf("provisioned user = %+v", alice)
+	}
+	if _, err := ms.GetWorkspaceMember(context.Background(), alice.PersonalWorkspaceID, alice.ID.String()); err != nil {
+		t.Errorf("personal workspace missing: %v", err)
+	}
+
+	// An existing password account is linked by email, not duplicated.
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/register", jsonBody(map[string]string{"email": "bob@example.com", "password": "pass"})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d", rec.Code)
+	}
+	bob := decodeJSON[authResponse](t, rec).User
+	bob.EmailVerified = true
+	ms.UpdateUser(context.Background(), bob)
+	fake.User = oidctest.User{Subject: "sub-bob", Email: "bob@example.com", EmailVerified: true}
+	if fragment := oidcSignIn(t, mux); fragment.Get("refreshToken") == "" {
+		t.Fatalf("link: fragment = %v", fragment)
+	}
+	if got := ms.users["bob@example.com"]; got.ID != bob.ID || got.OIDCSubject != "sub-bob" {
+		t.Errorf("linked user = %+v, want %s with subject", got, bob.ID)
+	}
+	if len(ms.usersById) != 2 {
+		t.Errorf("users = %d, want 2", len(ms.usersById))
+	}
+}
+
+func TestOIDC_Rejects(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, fake := newOIDCMux(t, h)
+
+	fake.User = oidctest.User{Subject: "sub-alice", Email: "alice@example.com", EmailVerified: true}
+	oidcSignIn(t, mux)
+
+	tests := []struct {
+		name  string
+		setup func()
+		user  oidctest.User
+		want  string
+	}{
+		{"unverified email", nil, oidctest.User{Subject: "sub-x", Email: "x@example.com"}, "email_not_verified"},
+		{"domain not allowed", func() { h.oidcDomains = []string{"corp.example"} }, oidctest.User{Subject: "sub-x", Email: "x@example.com", EmailVerified: true}, "domain_not_allowed"},
+		{"subject mismatch", nil, oidctest.User{Subject: "sub-other", Email: "alice@example.com", EmailVerified: true}, "account_mismatch"},
+		{"registration closed", func() { h.SetRegistration(config.RegistrationInvite) }, oidctest.User{Subject: "sub-x", Email: "x@example.com", EmailVerified: true}, "registration_closed"},
+		{"disabled", func() {
+			u := ms.users["alice@example.com"]
+			u.Disabled = true
+			ms.UpdateUser(context.Background(), u)
+		}, oidctest.User{Subject: "sub-alice", Email: "alice@example.com", EmailVerified: true}, "account_disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.oidcDomains = nil
+			h.SetRegistration(config.RegistrationOpen)
+			if tt.setup != nil {
+				tt.setup()
+			}
+			fake.User = tt.user
+			fragment := oidcSignIn(t, mux)
+			if got := fragment.Get("error"); got != tt.want {
+				t.Errorf("error = %q, want %q", got, tt.want)
+			}
+			if fragment.Get("refreshToken") != "" {
+				t.Error("refresh token issued")
+			}
+		})
+	}
+	if _, ok := ms.users["x@example.com"]; ok {
+		t.Error("rejected user was provisioned")
+	}
+}
+
+func TestOIDC_RequiresSecondFactor(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, fake := newOIDCMux(t, h)
+
+	creds := map[string]string{"email": "bob@example.com", "password": "pass"}
+	bob := signIn(t, mux, "/api/v1/auth/register", creds).User
+	bob.EmailVerified = true
+	ms.UpdateUser(context.Background(), bob)
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.twoFactor[bob.ID.String()] = model.TwoFactor{Secret: secret, Enabled: true}
+
+	// Signing in at the provider only gets as far as the code.
+	fake.User = oidctest.User{Subject: "sub-bob", Email: "bob@example.com", EmailVerified: true}
+	fragment := oidcSignIn(t, mux)
+	if fragment.Get("refreshToken") != "" || fragment.Get("challengeToken") == "" {
+		t.Fatalf("fragment = %v, want a challenge and no refresh token", fragment)
+	}
+
+	code, _ := totp.Code(secret, totp.Step(time.Now()))
+	resp := signIn(t, mux, "/api/v1/auth/login/2fa", map[string]string{"challengeToken": fragment.Get("challengeToken"), "code": code})
+	if resp.User.ID != bob.ID || resp.RefreshToken == "" {
+		t.Errorf("second step = %+v, want a session for bob", resp)
+	}
+}
+
+func TestOIDC_RejectsUnverifiedPasswordAccount(t *testing.T) {
+	h, ms := newTestHandler()
+	mux, fake := newOIDCMux(t, h)
+
+	// Someone registers the victim's address with a password they know.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/register", jsonBody(map[string]string{"email": "victim@example.com", "password": "attacker"})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d", rec.Code)
+	}
+
+	// The victim's first single sign-on must not land in that account.
+	fake.User = oidctest.User{Subject: "sub-victim", Email: "victim@example.com", EmailVerified: true}
+	fragment := oidcSignIn(t, mux)
+	if got := fragment.Get("error"); got != "account_unverified" {
+		t.Errorf("error = %q, want account_unverified", got)
+	}
+	if fragment.Get("refreshToken") != "" {
+		t.Error("refresh token issued")
+	}
+	if got := ms.users["victim@example.com"]; got.OIDCSubject != "" || got.EmailVerified {
+		t.Errorf("unverified account was linked: %+v", got)
+	}
+}
+
+func testOIDC_RejectsForgedState(t *testing.T, h *Handler) {
+	mux, _ := newOIDCMux(t, h)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/auth/oidc/login", nil))
+	req := httptest.NewRequest("GET", OIDCCallbackPath+"?code=abc&state=forged", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if loc := rec.Header().Get("Location"); rec.Code != http.StatusFound || !strings.HasSuffix(loc, "/login#error=oidc_failed") {
+		t.Errorf("status = %d, location = %q", rec.Code, loc)
+	}
+}
+
+func TestLogin_BackoffAndLockout(t *testing.T) {
+	h, ms := newTestHandler()
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newAuthMux(h)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/register", jsonBody(map[string]string{"email": "a@b.com", "password": "correct"})))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d", rec.Code)
+	}
+	login := func(password string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(map[string]string{"email": "a@b.com", "password": password})))
+		return rec
+	}
+	lockouts := testutil.ToFloat64(middleware.LoginLockouts.WithLabelValues("account"))
+
+	// The first failures are free; then every attempt has to wait,
+	// twice as long each time.
+	for i := 1; i <= 4; i++ {
+		if rec := login("wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i, rec.Code)
+		}
+	}
+	rec = login("correct")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("backoff: status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	now = now.Add(time.Second)
+	login("wrong")
+	if got := login("wrong").Header().Get("Retry-After"); got != "2" {
+		t.Errorf("second backoff: Retry-After = %q, want 2", got)
+	}
+
+	// Enough failures lock the account, even for the right password.
+	for i := 6; i <= 10; i++ {
+		now = now.Add(time.Minute)
+		if rec := login("wrong"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i, rec.Code)
+		}
+	}
+	rec = login("correct")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1800" {
+		t.Fatalf("lockout: status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if got := testutil.ToFloat64(middleware.LoginLockouts.WithLabelValues("account")) - lockouts; got != 1 {
+		t.Errorf("lockouts counted = %v, want 1", got)
+	}
+	if _, ok := ms.attempts["account:a@b.com"]; !ok {
+		t.Error("lockout not stored")
+	}
+
+	// Once the lock ends a successful login clears the account's failures.
+	now = now.Add(31 * time.Minute)
+	if rec := login("correct"); rec.Code != http.StatusOK {
+		t.Fatalf("after lockout: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := ms.attempts["account:a@b.com"]; ok {
+		t.Error("failures not cleared after successful login")
+	}
+}
+
+func testLogin_LocksOutIP(t *testing.T, h *Handler) {
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newAuthMux(h)
+
+	login := func(email, remoteAddr string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(map[string]string{"email": email, "password": "guess"}))
+		req.RemoteAddr = remoteAddr
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Guessing across many accounts from one address locks the address.
+	for i := range ipThrottle.lockAfter {
+		now = now.Add(ipThrottle.lockFor)
+		if rec := login(fmt.Sprintf("user%d@example.com", i), "203.0.113.7:1234"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i+1, rec.Code)
+		}
+	}
+	if rec := login("fresh@example.com", "203.0.113.7:5678"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("locked ip: status = %d, want 429", rec.Code)
+	}
+	if rec := login("fresh@example.com", "198.51.100.1:1234"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("other ip: status = %d, want 401", rec.Code)
+	}
+}
+
+func testLogin_SuccessesNotCountedAgainstIP(t *testing.T, h *Handler) {
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newAuthMux(h)
+	creds := map[string]string{"email": "a@b.com", "password": "pass"}
+	signIn(t, mux, "/api/v1/auth/register", creds)
+
+	// Many users signing in from one shared address never lock it.
+	for i := range ipThrottle.lockAfter + 1 {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(creds)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("login %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+}
+
+func testLogin_ParallelGuessesLocked(t *testing.T, h *Handler) {
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newAuthMux(h)
+	signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+
+	// Guesses sent at once are all counted before any is checked, so no
+	// more get through than one after another.
+	const n = 20
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/auth/login", jsonBody(map[string]string{"email": "a@b.com", "password": "guess"})))
+			codes[i] = rec.Code
+		}()
+	}
+	wg.Wait()
+
+	tried := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusUnauthorized:
+			tried++
+		case http.StatusTooManyRequests:
+		default:
+			t.Fatalf("status = %d, want 401 or 429", code)
+		}
+	}
+	if tried != accountThrottle.free+1 {
+		t.Errorf("%d guesses checked, want %d", tried, accountThrottle.free+1)
+	}
+}
+
+func testRegister_LimitedPerIP(t *testing.T, h *Handler) {
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	mux := newAuthMux(h)
+
+	register := func(i int, remoteAddr string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/auth/register", jsonBody(map[string]string{"email": fmt.Sprintf("user%d@example.com", i), "password": "pass"}))
+		req.RemoteAddr = remoteAddr
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range registerThrottle.lockAfter {
+		if rec := register(i, "203.0.113.7:1234"); rec.Code != http.StatusCreated {
+			t.Fatalf("registration %d: status = %d, want 201", i+1, rec.Code)
+		}
+	}
+	rec := register(100, "203.0.113.7:5678")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "3600" {
+		t.Fatalf("limited: status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := register(101, "198.51.100.1:1234"); rec.Code != http.StatusCreated {
+		t.Errorf("other ip: status = %d, want 201", rec.Code)
+	}
+	now = now.Add(registerThrottle.lockFor)
+	if rec := register(102, "203.0.113.7:1234"); rec.Code != http.StatusCreated {
+		t.Errorf("after the lock: status = %d, want 201", rec.Code)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	mux := newSessionMux(h, ms)
+
+	admin := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "admin@example.com", "password": "pass"})
+	user := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "a@b.com", "password": "pass"})
+	deleteAccount := func(token string, body map[string]string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/account", token, jsonBody(body)))
+		return rec
+	}
+
+	if rec := deleteAccount(user.Token, map[string]string{"password": "wrong"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: status = %d, want 401", rec.Code)
+	}
+	if rec := deleteAccount(admin.Token, map[string]string{"password": "pass"}); rec.Code != http.StatusConflict {
+		t.Errorf("last admin: status = %d, want 409", rec.Code)
+	}
+
+	if rec := deleteAccount(user.Token, map[string]string{"password": "pass"}); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := ms.usersById[user.User.ID.String()]; ok {
+		t.Error("user not deleted")
+	}
+	if _, ok := ms.workspaces[user.User.PersonalWorkspaceID]; ok {
+		t.Error("personal workspace not deleted")
+	}
+	for _, sess := range ms.sessions {
+		if sess.UserID == user.User.ID.String() {
+			t.Error("session survived account deletion")
+		}
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("GET", "/api/v1/sessions", user.Token, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("token after deletion: status = %d, want 401", rec.Code)
+	}
+	if _, ok := ms.usersById[admin.User.ID.String()]; !ok {
+		t.Error("other account deleted")
+	}
+}
+
+func TestDeleteAccount_WithoutPassword(t *testing.T) {
+	h, ms := newTestHandler()
+	h.now = time.Now
+	mux := newSessionMux(h, ms)
+
+	resp := signIn(t, mux, "/api/v1/auth/register", map[string]string{"email": "sso@example.com", "password": "pass"})
+	u := ms.usersById[resp.User.ID.String()]
+	u.PasswordHash = ""
+	ms.UpdateUser(context.Background(), u)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/account", resp.Token, jsonBody(map[string]string{"email": "other@example.com"})))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong email: status = %d, want 401", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, authRequestTo("DELETE", "/api/v1/account", resp.Token, jsonBody(map[string]string{"email": "SSO@example.com"})))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d; body: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -1,18 +1,14 @@
 package handler
 
-import (
-	"net/http"
-
-	"github.com/tobi/contracts/backend/internal/store"
-)
+import "net/http"
 
 func (h *Handler) Healthz(w http.ResponseWriter, _ *http.Request) {
 	h.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
 func (h *Handler) Readyz(w http.ResponseWriter, _ *http.Request) {
-	if bs, ok := h.store.(*store.BadgerStore); ok {
-		if err := bs.Healthy(); err != nil {
+	if hs, ok := h.store.(interface{ Healthy() error }); ok {
+		if err := hs.Healthy(); err != nil {
 			h.errorResponse(w, http.StatusServiceUnavailable, "db unhealthy")
 			return
 		}
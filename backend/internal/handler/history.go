@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// audited describes one kind of entity for the history and revert
+// endpoints.
+type audited[T any] struct {
+	entityType string
+	// module restricts categories to the module in the path.
+	module string
+	get    func(ctx context.Context, workspaceID string, id uuid.UUID) (T, error)
+	create func(ctx context.Context, workspaceID string, v T) error
+	update func(ctx context.Context, workspaceID string, v T) error
+	touch  func(v *T, now time.Time)
+	// view renders the reverted entity; the entity itself if nil.
+	view func(v T) any
+	// parent looks up the entity a deleted one must be restored into.
+	parent func(ctx context.Context, workspaceID string, v T) error
+}
+
+func (h *Handler) CategoryHistory(w http.ResponseWriter, r *http.Request) {
+	entityHistory(h, w, r, h.auditedCategory(r))
+}
+
+func (h *Handler) RevertCategory(w http.ResponseWriter, r *http.Request) {
+	revertEntity(h, w, r, h.auditedCategory(r))
+}
+
+func (h *Handler) ContractHistory(w http.ResponseWriter, r *http.Request) {
+	entityHistory(h, w, r, h.auditedContract())
+}
+
+func (h *Handler) RevertContract(w http.ResponseWriter, r *http.Request) {
+	revertEntity(h, w, r, h.auditedContract())
+}
+
+func (h *Handler) PurchaseHistory(w http.ResponseWriter, r *http.Request) {
+	entityHistory(h, w, r, h.auditedPurchase())
+}
+
+func (h *Handler) RevertPurchase(w http.ResponseWriter, r *http.Request) {
+	revertEntity(h, w, r, h.auditedPurchase())
+}
+
+func (h *Handler) VehicleHistory(w http.ResponseWriter, r *http.Request) {
+	entityHistory(h, w, r, h.auditedVehicle())
+}
+
+func (h *Handler) RevertVehicle(w http.ResponseWriter, r *http.Request) {
+	revertEntity(h, w, r, h.auditedVehicle())
+}
+
+func (h *Handler) CostEntryHistory(w http.ResponseWriter, r *http.Request) {
+	entityHistory(h, w, r, h.auditedCostEntry())
+}
+
+func (h *Handler) RevertCostEntry(w http.ResponseWriter, r *http.Request) {
+	revertEntity(h, w, r, h.auditedCostEntry())
+}
+
+func (h *Handler) auditedCategory(r *http.Request) audited[model.Category] {
+	module := r.PathValue("module")
+	return audited[model.Category]{
+		entityType: model.EntityCategory,
+		module:     module,
+		get: func(ctx context.Context, workspaceID string, id uuid.UUID) (model.Category, error) {
+			return h.store.GetCategory(ctx, workspaceID, module, id)
+		},
+		create: func(ctx context.Context, workspaceID string, c model.Category) error {
+			return h.store.CreateCategory(ctx, workspaceID, module, c)
+		},
+		update: func(ctx context.Context, workspaceID string, c model.Category) error {
+			return h.store.UpdateCategory(ctx, workspaceID, module, c)
+		},
+		touch: func(c *model.Category, now time.Time) { c.UpdatedAt = now },
+	}
+}
+
+func (h *Handler) auditedContract() audited[model.Contract] {
+	return audited[model.Contract]{
+		entityType: model.EntityContract,
+		get:        h.store.GetContract,
+		create:     h.store.CreateContract,
+		update: func(ctx context.Context, workspaceID string, c model.Contract) error {
+			return h.store.UpdateContract(ctx, workspaceID, c)
+		},
+		touch: func(c *model.Contract, now time.Time) { c.UpdatedAt = now },
+		view:  func(c model.Contract) any { return h.newContractView(c) },
+		parent: func(ctx context.Context, workspaceID string, c model.Contract) error {
+			_, err := h.store.GetCategory(ctx, workspaceID, "contracts", c.CategoryID)
+			return err
+		},
+	}
+}
+
+func (h *Handler) auditedPurchase() audited[model.Purchase] {
+	return audited[model.Purchase]{
+		entityType: model.EntityPurchase,
+		get:        h.store.GetPurchase,
+		create:     h.store.CreatePurchase,
+		update:     h.store.UpdatePurchase,
+		touch:      func(p *model.Purchase, now time.Time) { p.UpdatedAt = now },
+		parent: func(ctx context.Context, workspaceID string, p model.Purchase) error {
+			_, err := h.store.GetCategory(ctx, workspaceID, "purchases", p.CategoryID)
+			return err
+		},
+	}
+}
+
+func (h *Handler) auditedVehicle() audited[model.Vehicle] {
+	return audited[model.Vehicle]{
+		entityType: model.EntityVehicle,
+		get:        h.store.GetVehicle,
+		create:     h.store.CreateVehicle,
+		update:     h.store.UpdateVehicle,
+		touch:      func(v *model.Vehicle, now time.Time) { v.UpdatedAt = now },
+	}
+}
+
+func (h *Handler) auditedCostEntry() audited[model.CostEntry] {
+	return audited[model.CostEntry]{
+		entityType: model.EntityCostEntry,
+		get:        h.store.GetCostEntry,
+		create:     h.store.CreateCostEntry,
+		update:     h.store.UpdateCostEntry,
+		touch:      func(c *model.CostEntry, now time.Time) { c.UpdatedAt = now },
+		parent: func(ctx context.Context, workspaceID string, c model.CostEntry) error {
+			_, err := h.store.GetVehicle(ctx, workspaceID, c.VehicleID)
+			return err
+		},
+	}
+}
+
+// entries returns the audit entries of the entity in the path, oldest first.
+func (e audited[T]) entries(ctx context.Context, s store.Store, workspaceID string, id uuid.UUID) ([]model.AuditEntry, error) {
+	all, err := s.ListAuditEntries(ctx, workspaceID, id)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(all, func(a model.AuditEntry) bool {
+		return a.EntityType != e.entityType || a.Module != e.module
+	}), nil
+}
+
+// entityHistory lists the changes of an entity, newest first. Deleted
+// entities keep their history.
+func entityHistory[T any](h *Handler, w http.ResponseWriter, r *http.Request, e audited[T]) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	entries, err := e.entries(r.Context(), h.store, middleware.GetWorkspaceID(r.Context()), id)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if len(entries) == 0 {
+		h.errorResponse(w, http.StatusNotFound, "not found")
+		return
+	}
+	slices.Reverse(entries)
+	h.writeJSON(w, http.StatusOK, entries)
+}
+
+// revertEntity restores an entity to the state it had after the chosen
+// revision, recreating it if it was purged since. Entities in the trash have
+// to be restored from there first. The revert is itself recorded as a new
+// change.
+func revertEntity[T any](h *Handler, w http.ResponseWriter, r *http.Request, e audited[T]) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	revision, err := parseUUID(r.PathValue("revision"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid revision")
+		return
+	}
+
+	ctx := r.Context()
+	workspaceID := middleware.GetWorkspaceID(ctx)
+	entries, err := e.entries(ctx, h.store, workspaceID, id)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	i := slices.IndexFunc(entries, func(a model.AuditEntry) bool { return a.ID == revision })
+	if i < 0 {
+		h.errorResponse(w, http.StatusNotFound, "revision not found")
+		return
+	}
+	if a := entries[i].Action; a == model.AuditDelete || a == model.AuditTrash {
+		h.errorResponse(w, http.StatusBadRequest, "cannot revert to a deletion")
+		return
+	}
+
+	var current []byte
+	exists := true
+	cur, err := e.get(ctx, workspaceID, id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		exists = false
+	case err != nil:
+		h.handleStoreError(w, err)
+		return
+	default:
+		if current, err = json.Marshal(cur); err != nil {
+			h.handleStoreError(w, err)
+			return
+		}
+	}
+
+	if !exists && entries[len(entries)-1].Action == model.AuditTrash {
+		h.errorResponse(w, http.StatusConflict, "this entity is in the trash, restore it first")
+		return
+	}
+
+	state, err := model.Undo(current, entries[i+1:])
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	var v T
+	if err := json.Unmarshal(state, &v); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	e.touch(&v, h.now().UTC())
+
+	if exists {
+		err = e.update(ctx, workspaceID, v)
+	} else {
+		if e.parent != nil {
+			if err := e.parent(ctx, workspaceID, v); errors.Is(err, store.ErrNotFound) {
+				h.errorResponse(w, http.StatusConflict, "the parent of this entity no longer exists")
+				return
+			} else if err != nil {
+				h.handleStoreError(w, err)
+				return
+			}
+		}
+		err = e.create(ctx, workspaceID, v)
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	if e.view != nil {
+		h.writeJSON(w, http.StatusOK, e.view(v))
+		return
+	}
+	h.writeJSON(w, http.StatusOK, v)
+}
+
+// Activity lists the changes in the active workspace, newest first. Pass the
+// ID of the last entry as before to get the next page.
+func (h *Handler) Activity(w http.ResponseWriter, r *http.Request) {
+	var before uuid.UUID
+	if s := r.URL.Query().Get("before"); s != "" {
+		id, err := parseUUID(s)
+		if err != nil {
+			h.errorResponse(w, http.StatusBadRequest, "invalid before")
+			return
+		}
+		before = id
+	}
+	limit := defaultActivityLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxActivityLimit {
+			h.errorResponse(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxActivityLimit))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.store.ListActivity(r.Context(), middleware.GetWorkspaceID(r.Context()), before, limit)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []model.AuditEntry{}
+	}
+	h.writeJSON(w, http.StatusOK, entries)
+}
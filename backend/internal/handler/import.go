@@ -1,12 +1,14 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/middleware"
@@ -18,6 +20,19 @@ type contractImportEntry struct {
 	model.ContractInput
 }
 
+// UnmarshalJSON decodes the category next to the contract fields, which the
+// UnmarshalJSON promoted from ContractInput would drop.
+func (e *contractImportEntry) UnmarshalJSON(data []byte) error {
+	var c struct {
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	e.Category = c.Category
+	return json.Unmarshal(data, &e.ContractInput)
+}
+
 // categoryTranslations maps nameKey to known translations (all lowercase).
 // Used during import to match translated category names to existing categories.
 var categoryTranslations = map[string][]string{
@@ -28,6 +43,8 @@ var categoryTranslations = map[string][]string{
 
 type importResult struct {
 	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Skipped int           `json:"skipped"`
 	Errors  []importError `json:"errors"`
 }
 
@@ -36,37 +53,106 @@ type importError struct {
 	Error string `json:"error"`
 }
 
+// ImportContracts imports the contracts in the uploaded JSON file. Rows
+// matching an existing contract are handled according to the "onDuplicate"
+// and "matchBy" parameters. The optional "rows" form field (comma-separated,
+// 1-based) restricts the import to the rows selected after a preview.
 func (h *Handler) ImportContracts(w http.ResponseWriter, r *http.Request) {
-	userID := middleware.GetUserID(r.Context())
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+
+	entries, ok := h.readContractImportFile(w, r)
+	if !ok {
+		return
+	}
+	selected, err := parseRowSelection(r.FormValue("rows"), len(entries))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	imp, err := h.newContractImporter(r.Context(), workspaceID, opts)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
 
+	result := importResult{Errors: []importError{}}
+	for i, entry := range entries {
+		row := i + 1
+		if selected != nil && !selected[row] {
+			continue
+		}
+		outcome, err := imp.importEntry(r.Context(), entry)
+		result.record(row, outcome, err)
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// readContractImportFile parses the multipart "file" field as a JSON array of
+// import entries. On failure it writes the error response.
+func (h *Handler) readContractImportFile(w http.ResponseWriter, r *http.Request) ([]contractImportEntry, bool) {
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		h.errorResponse(w, http.StatusBadRequest, "invalid multipart form")
-		return
+		return nil, false
 	}
 
 	file, _, err := r.FormFile("file")
 	if err != nil {
 		h.errorResponse(w, http.StatusBadRequest, "missing file field")
-		return
+		return nil, false
 	}
 	defer file.Close()
 
 	data, err := io.ReadAll(file)
 	if err != nil {
 		h.errorResponse(w, http.StatusBadRequest, "failed to read file")
-		return
+		return nil, false
 	}
 
 	var entries []contractImportEntry
 	if err := json.Unmarshal(data, &entries); err != nil {
 		h.errorResponse(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
-		return
+		return nil, false
+	}
+	return entries, true
+}
+
+// parseRowSelection parses a comma-separated list of 1-based row numbers. An
+// empty selection returns nil, meaning all rows.
+func parseRowSelection(s string, rows int) (map[int]bool, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	selected := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 || n > rows {
+			return nil, fmt.Errorf("rows: invalid row %q", strings.TrimSpace(part))
+		}
+		selected[n] = true
 	}
+	return selected, nil
+}
 
-	categories, err := h.store.ListCategories(r.Context(), userID, "contracts")
+// categoryIndex resolves category names of imported rows to IDs. Names are
+// matched case-insensitively, including the English key and the known
+// translations of seeded categories.
+type categoryIndex struct {
+	module string
+	byName map[string]uuid.UUID
+}
+
+func (h *Handler) loadCategoryIndex(ctx context.Context, workspaceID, module string) (*categoryIndex, error) {
+	categories, err := h.store.ListCategories(ctx, workspaceID, module)
 	if err != nil {
-		h.handleStoreError(w, err)
-		return
+		return nil, err
 	}
 	catByName := make(map[string]uuid.UUID, len(categories))
 	for _, c := range categories {
@@ -86,70 +172,75 @@ func (h *Handler) ImportContracts(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	return &categoryIndex{module: module, byName: catByName}, nil
+}
 
-	result := importResult{Errors: []importError{}}
-	for i, entry := range entries {
-		row := i + 1
-
-		if entry.Category == "" {
-			result.Errors = append(result.Errors, importError{Row: row, Error: "category is required"})
-			continue
-		}
-		if err := entry.ContractInput.Validate(); err != nil {
-			result.Errors = append(result.Errors, importError{Row: row, Error: err.Error()})
-			continue
-		}
-
-		catID, ok := catByName[strings.ToLower(entry.Category)]
-		if !ok {
-			now := time.Now().UTC()
-			cat := model.Category{
-				ID:        uuid.New(),
-				Name:      entry.Category,
-				CreatedAt: now,
-				UpdatedAt: now,
-			}
-			if err := h.store.CreateCategory(r.Context(), userID, "contracts", cat); err != nil {
-				result.Errors = append(result.Errors, importError{Row: row, Error: fmt.Sprintf("failed to create category: %v", err)})
-				continue
-			}
-			catID = cat.ID
-			catByName[strings.ToLower(entry.Category)] = catID
-		}
+func (c *categoryIndex) lookup(name string) (uuid.UUID, bool) {
+	id, ok := c.byName[strings.ToLower(name)]
+	return id, ok
+}
 
-		now := time.Now().UTC()
-		bi := entry.BillingInterval
-		if bi == "" {
-			bi = model.BillingMonthly
-		}
-		con := model.Contract{
-			ID:                      uuid.New(),
-			CategoryID:              catID,
-			Name:                    entry.Name,
-			ProductName:             entry.ProductName,
-			Company:                 entry.Company,
-			ContractNumber:          entry.ContractNumber,
-			CustomerNumber:          entry.CustomerNumber,
-			Price:                   entry.Price,
-			BillingInterval:         bi,
-			StartDate:               entry.StartDate,
-			EndDate:                 entry.EndDate,
-			MinimumDurationMonths:   entry.MinimumDurationMonths,
-			ExtensionDurationMonths: entry.ExtensionDurationMonths,
-			NoticePeriodMonths:      entry.NoticePeriodMonths,
-			CustomerPortalURL:       entry.CustomerPortalURL,
-			PaperlessURL:            entry.PaperlessURL,
-			Comments:                entry.Comments,
-			CreatedAt:               now,
-			UpdatedAt:               now,
-		}
+// resolveCategory returns the ID of the named category, creating it if it
+// does not exist yet.
+func (h *Handler) resolveCategory(ctx context.Context, workspaceID string, cats *categoryIndex, name string) (uuid.UUID, error) {
+	if id, ok := cats.lookup(name); ok {
+		return id, nil
+	}
+	now := h.now().UTC()
+	cat := model.Category{
+		ID:        uuid.New(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.store.CreateCategory(ctx, workspaceID, cats.module, cat); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create category: %v", err)
+	}
+	cats.byName[strings.ToLower(name)] = cat.ID
+	return cat.ID, nil
+}
 
-		if err := h.store.CreateContract(r.Context(), userID, con); err != nil {
-			result.Errors = append(result.Errors, importError{Row: row, Error: fmt.Sprintf("failed to create contract: %v", err)})
-			continue
-		}
-		result.Created++
+// contractFromImport validates an imported entry and builds the contract it
+// describes. The category is left for the caller to resolve.
+func (h *Handler) contractFromImport(entry contractImportEntry) (model.Contract, error) {
+	if entry.Category == "" {
+		return model.Contract{}, errors.New("category is required")
+	}
+	bi, err := model.ParseBillingInterval(string(entry.BillingInterval))
+	if err != nil {
+		return model.Contract{}, err
+	}
+	entry.BillingInterval = bi
+	if err := entry.ContractInput.Validate(); err != nil {
+		return model.Contract{}, err
 	}
 
-	h.writeJSON(w, http.StatusOK, result)
+	now := h.now().UTC()
+	return model.Contract{
+		ID:                      uuid.New(),
+		Name:                    entry.Name,
+		ProductName:             entry.ProductName,
+		Company:                 entry.Company,
+		ContractNumber:          entry.ContractNumber,
+		CustomerNumber:          entry.CustomerNumber,
+		Price:                   entry.Price,
+		BillingInterval:         bi,
+		StartDate:               entry.StartDate,
+		EndDate:                 entry.EndDate,
+		MinimumDuration:         entry.MinimumDuration,
+		MinimumDurationUnit:     entry.MinimumDurationUnit,
+		MinimumDurationAnchor:   entry.MinimumDurationAnchor,
+		ExtensionDuration:       entry.ExtensionDuration,
+		ExtensionDurationUnit:   entry.ExtensionDurationUnit,
+		ExtensionDurationAnchor: entry.ExtensionDurationAnchor,
+		NoticePeriod:            entry.NoticePeriod,
+		NoticePeriodUnit:        entry.NoticePeriodUnit,
+		NoticePeriodAnchor:      entry.NoticePeriodAnchor,
+		CustomerPortalURL:       entry.CustomerPortalURL,
+		PaperlessURL:            entry.PaperlessURL,
+		Comments:                entry.Comments,
+		Status:                  model.StatusActive,
+		CreatedAt:               now,
+		UpdatedAt:               now,
+	}, nil
 }
@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+// duplicateStrategy decides what an import does with a row that matches an
+// existing record.
+type duplicateStrategy string
+
+const (
+	duplicateUpdate    duplicateStrategy = "update"
+	duplicateSkip      duplicateStrategy = "skip"
+	duplicateDuplicate duplicateStrategy = "duplicate"
+)
+
+// contractMatch selects the natural key imported contracts are matched by.
+type contractMatch string
+
+const (
+	// matchAuto uses company and contract number, falling back to name and
+	// start date for contracts without both.
+	matchAuto           contractMatch = "auto"
+	matchContractNumber contractMatch = "contractNumber"
+	matchNameStartDate  contractMatch = "nameStartDate"
+)
+
+type importOptions struct {
+	onDuplicate duplicateStrategy
+	matchBy     contractMatch
+}
+
+// parseImportOptions reads the "onDuplicate" (default update) and "matchBy"
+// (default auto) request parameters.
+func parseImportOptions(r *http.Request) (importOptions, error) {
+	opts := importOptions{onDuplicate: duplicateUpdate, matchBy: matchAuto}
+	switch s := duplicateStrategy(r.FormValue("onDuplicate")); s {
+	case "":
+	case duplicateUpdate, duplicateSkip, duplicateDuplicate:
+		opts.onDuplicate = s
+	default:
+		return opts, fmt.Errorf("onDuplicate must be one of: %s, %s, %s", duplicateUpdate, duplicateSkip, duplicateDuplicate)
+	}
+	switch m := contractMatch(r.FormValue("matchBy")); m {
+	case "":
+	case matchAuto, matchContractNumber, matchNameStartDate:
+		opts.matchBy = m
+	default:
+		return opts, fmt.Errorf("matchBy must be one of: %s, %s, %s", matchAuto, matchContractNumber, matchNameStartDate)
+	}
+	return opts, nil
+}
+
+// key returns c's natural key, or "" if the fields it is built from are
+// missing. Keys are case-insensitive.
+func (m contractMatch) key(c model.Contract) string {
+	switch m {
+	case matchContractNumber:
+		return naturalKey("number", c.Company, c.ContractNumber)
+	case matchNameStartDate:
+		return naturalKey("name", c.Name, c.StartDate)
+	default:
+		if k := naturalKey("number", c.Company, c.ContractNumber); k != "" {
+			return k
+		}
+		return naturalKey("name", c.Name, c.StartDate)
+	}
+}
+
+// purchaseMatchKey identifies a purchase by brand, article number and
+// purchase date. Purchases without an article number or date have no key.
+func purchaseMatchKey(p model.Purchase) string {
+	key := naturalKey("purchase", p.ArticleNumber, p.PurchaseDate)
+	if key == "" {
+		return ""
+	}
+	return key + "\x00" + strings.ToLower(strings.TrimSpace(p.Brand))
+}
+
+func naturalKey(kind string, parts ...string) string {
+	for i, p := range parts {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			return ""
+		}
+		parts[i] = p
+	}
+	return kind + "\x00" + strings.Join(parts, "\x00")
+}
+
+type importOutcome int
+
+const (
+	importCreated importOutcome = iota
+	importUpdated
+	importSkipped
+)
+
+// record adds the outcome of importing one row to the result.
+func (res *importResult) record(row int, outcome importOutcome, err error) {
+	if err != nil {
+		res.Errors = append(res.Errors, importError{Row: row, Error: err.Error()})
+		return
+	}
+	switch outcome {
+	case importCreated:
+		res.Created++
+	case importUpdated:
+		res.Updated++
+	case importSkipped:
+		res.Skipped++
+	}
+}
+
+// contractImporter creates or updates contracts row by row, matching rows
+// against existing contracts and against rows imported earlier.
+type contractImporter struct {
+	h           *Handler
+	workspaceID string
+	opts        importOptions
+	cats        *categoryIndex
+	existing    map[string]model.Contract
+}
+
+func (h *Handler) newContractImporter(ctx context.Context, workspaceID string, opts importOptions) (*contractImporter, error) {
+	cats, err := h.loadCategoryIndex(ctx, workspaceID, "contracts")
+	if err != nil {
+		return nil, err
+	}
+	contracts, err := h.store.ListContracts(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]model.Contract, len(contracts))
+	for _, c := range contracts {
+		if key := opts.matchBy.key(c); key != "" {
+			existing[key] = c
+		}
+	}
+	return &contractImporter{h: h, workspaceID: workspaceID, opts: opts, cats: cats, existing: existing}, nil
+}
+
+func (imp *contractImporter) importEntry(ctx context.Context, entry contractImportEntry) (importOutcome, error) {
+	con, err := imp.h.contractFromImport(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	key := imp.opts.matchBy.key(con)
+	match, found := imp.existing[key]
+	if found && imp.opts.onDuplicate == duplicateSkip {
+		return importSkipped, nil
+	}
+
+	catID, err := imp.h.resolveCategory(ctx, imp.workspaceID, imp.cats, entry.Category)
+	if err != nil {
+		return 0, err
+	}
+
+	if found && imp.opts.onDuplicate == duplicateUpdate {
+		match.CategoryID = catID
+		prices, err := imp.h.applyContractInput(ctx, imp.workspaceID, &match, contractInputOf(con))
+		if err != nil {
+			return 0, fmt.Errorf("failed to update contract: %v", err)
+		}
+		if err := imp.h.store.UpdateContract(ctx, imp.workspaceID, match, prices...); err != nil {
+			return 0, fmt.Errorf("failed to update contract: %v", err)
+		}
+		imp.existing[key] = match
+		return importUpdated, nil
+	}
+
+	con.CategoryID = catID
+	if err := imp.h.store.CreateContract(ctx, imp.workspaceID, con); err != nil {
+		return 0, fmt.Errorf("failed to create contract: %v", err)
+	}
+	if key != "" && !found {
+		imp.existing[key] = con
+	}
+	return importCreated, nil
+}
+
+// purchaseImporter is the purchase counterpart of contractImporter.
+type purchaseImporter struct {
+	h           *Handler
+	workspaceID string
+	opts        importOptions
+	cats        *categoryIndex
+	existing    map[string]model.Purchase
+}
+
+func (h *Handler) newPurchaseImporter(ctx context.Context, workspaceID string, opts importOptions) (*purchaseImporter, error) {
+	cats, err := h.loadCategoryIndex(ctx, workspaceID, "purchases")
+	if err != nil {
+		return nil, err
+	}
+	purchases, err := h.store.ListPurchases(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]model.Purchase, len(purchases))
+	for _, p := range purchases {
+		if key := purchaseMatchKey(p); key != "" {
+			existing[key] = p
+		}
+	}
+	return &purchaseImporter{h: h, workspaceID: workspaceID, opts: opts, cats: cats, existing: existing}, nil
+}
+
+func (imp *purchaseImporter) importEntry(ctx context.Context, entry purchaseImportEntry) (importOutcome, error) {
+	p, err := imp.h.purchaseFromImport(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	key := purchaseMatchKey(p)
+	match, found := imp.existing[key]
+	if found && imp.opts.onDuplicate == duplicateSkip {
+		return importSkipped, nil
+	}
+
+	catID, err := imp.h.resolveCategory(ctx, imp.workspaceID, imp.cats, entry.Category)
+	if err != nil {
+		return 0, err
+	}
+
+	if found && imp.opts.onDuplicate == duplicateUpdate {
+		match.CategoryID = catID
+		applyPurchaseInput(&match, entry.PurchaseInput)
+		match.UpdatedAt = imp.h.now().UTC()
+		if err := imp.h.store.UpdatePurchase(ctx, imp.workspaceID, match); err != nil {
+			return 0, fmt.Errorf("failed to update purchase: %v", err)
+		}
+		imp.existing[key] = match
+		return importUpdated, nil
+	}
+
+	p.CategoryID = catID
+	if err := imp.h.store.CreatePurchase(ctx, imp.workspaceID, p); err != nil {
+		return 0, fmt.Errorf("failed to create purchase: %v", err)
+	}
+	if key != "" && !found {
+		imp.existing[key] = p
+	}
+	return importCreated, nil
+}
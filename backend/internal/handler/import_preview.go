@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+type importAction string
+
+const (
+	importCreate    importAction = "create"
+	importDuplicate importAction = "duplicate"
+	importInvalid   importAction = "invalid"
+)
+
+type importFieldChange struct {
+	Field    string `json:"field"`
+	Current  any    `json:"current"`
+	Imported any    `json:"imported"`
+}
+
+// importPreviewRow describes what importing one row would do. Duplicates
+// point either at an existing contract (duplicateOf, with the fields that
+// differ) or at an earlier row of the same file (duplicateOfRow).
+type importPreviewRow struct {
+	Row            int                  `json:"row"`
+	Action         importAction         `json:"action"`
+	Error          string               `json:"error,omitempty"`
+	Category       string               `json:"category,omitempty"`
+	CategoryID     *uuid.UUID           `json:"categoryId,omitempty"`
+	NewCategory    bool                 `json:"newCategory"`
+	DuplicateOf    *uuid.UUID           `json:"duplicateOf,omitempty"`
+	DuplicateOfRow int                  `json:"duplicateOfRow,omitempty"`
+	Changes        []importFieldChange  `json:"changes,omitempty"`
+	Contract       *model.ContractInput `json:"contract,omitempty"`
+}
+
+type importPreview struct {
+	Rows          []importPreviewRow `json:"rows"`
+	Create        int                `json:"create"`
+	Duplicates    int                `json:"duplicates"`
+	Invalid       int                `json:"invalid"`
+	NewCategories []string           `json:"newCategories"`
+}
+
+// PreviewContractImport runs the JSON import without writing anything and
+// reports per row whether it would create a contract, duplicate an existing
+// one (matched by the "matchBy" key) or be rejected, and which categories
+// would be created. Apply the chosen rows with ImportContracts' "rows" field.
+func (h *Handler) PreviewContractImport(w http.ResponseWriter, r *http.Request) {
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+
+	entries, ok := h.readContractImportFile(w, r)
+	if !ok {
+		return
+	}
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cats, err := h.loadCategoryIndex(r.Context(), workspaceID, "contracts")
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	names, err := h.categoryNames(r.Context(), workspaceID, "contracts")
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	existing, err := h.store.ListContracts(r.Context(), workspaceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if err := h.applyCurrentPrices(r.Context(), workspaceID, existing); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	byKey := make(map[string]model.Contract, len(existing))
+	for _, c := range existing {
+		if key := opts.matchBy.key(c); key != "" {
+			byKey[key] = c
+		}
+	}
+
+	preview := importPreview{Rows: make([]importPreviewRow, 0, len(entries)), NewCategories: []string{}}
+	newCategories := make(map[string]bool)
+	seenRows := make(map[string]int)
+	for i, entry := range entries {
+		row := importPreviewRow{Row: i + 1, Category: entry.Category}
+
+		con, err := h.contractFromImport(entry)
+		if err != nil {
+			row.Action = importInvalid
+			row.Error = err.Error()
+			preview.Invalid++
+			preview.Rows = append(preview.Rows, row)
+			continue
+		}
+		input := contractInputOf(con)
+		row.Contract = &input
+
+		if id, ok := cats.lookup(entry.Category); ok {
+			row.CategoryID = &id
+			con.CategoryID = id
+		} else {
+			row.NewCategory = true
+			if lower := strings.ToLower(entry.Category); !newCategories[lower] {
+				newCategories[lower] = true
+				preview.NewCategories = append(preview.NewCategories, entry.Category)
+			}
+		}
+
+		row.Action = importCreate
+		key := opts.matchBy.key(con)
+		if match, ok := byKey[key]; ok {
+			row.Action = importDuplicate
+			row.DuplicateOf = &match.ID
+			row.Changes = diffContracts(match, con, names[match.CategoryID], entry.Category)
+		} else if first, ok := seenRows[key]; ok {
+			row.Action = importDuplicate
+			row.DuplicateOfRow = first
+		} else if key != "" {
+			seenRows[key] = row.Row
+		}
+
+		if row.Action == importDuplicate {
+			preview.Duplicates++
+		} else {
+			preview.Create++
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+
+	h.writeJSON(w, http.StatusOK, preview)
+}
+
+// contractInputOf returns the user-editable fields of c.
+func contractInputOf(c model.Contract) model.ContractInput {
+	var in model.ContractInput
+	b, _ := json.Marshal(c)
+	json.Unmarshal(b, &in)
+	return in
+}
+
+// diffContracts lists the user-editable fields whose imported value differs
+// from the current contract, in CSV column order.
+func diffContracts(current, imported model.Contract, currentCategory, importedCategory string) []importFieldChange {
+	var changes []importFieldChange
+	if current.CategoryID != imported.CategoryID {
+		changes = append(changes, importFieldChange{Field: "category", Current: currentCategory, Imported: importedCategory})
+	}
+
+	cur := fieldMap(contractInputOf(current))
+	imp := fieldMap(contractInputOf(imported))
+	for _, col := range contractCSVColumns {
+		if col.Name == "category" {
+			continue
+		}
+		if !reflect.DeepEqual(cur[col.Name], imp[col.Name]) {
+			changes = append(changes, importFieldChange{Field: col.Name, Current: cur[col.Name], Imported: imp[col.Name]})
+		}
+	}
+	return changes
+}
+
+func fieldMap(v any) map[string]any {
+	var m map[string]any
+	b, _ := json.Marshal(v)
+	json.Unmarshal(b, &m)
+	return m
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+// loginFailureWindow is how long failed logins are remembered after the
+// last one.
+const loginFailureWindow = time.Hour
+
+// throttlePolicy slows down and then locks out repeated failed logins for
+// one kind of key. After free failures each further one doubles the wait
+// before the next attempt, starting at a second; after lockAfter the key is
+// locked for lockFor.
+type throttlePolicy struct {
+	scope     string
+	free      int
+	lockAfter int
+	lockFor   time.Duration
+}
+
+var (
+	accountThrottle = throttlePolicy{scope: "account", free: 3, lockAfter: 10, lockFor: 30 * time.Minute}
+	// IP addresses may be shared by many users, so they get more attempts.
+	ipThrottle = throttlePolicy{scope: "ip", free: 20, lockAfter: 50, lockFor: 30 * time.Minute}
+	// registerThrottle counts every registration from an address, not just
+	// failed ones, and allows ten an hour.
+	registerThrottle = throttlePolicy{scope: "register", free: 9, lockAfter: 10, lockFor: time.Hour}
+)
+
+// delay returns how long a key with the given number of failures is locked.
+func (p throttlePolicy) delay(failures int) time.Duration {
+	if failures >= p.lockAfter {
+		return p.lockFor
+	}
+	if failures <= p.free {
+		return 0
+	}
+	return min(time.Second<<(failures-p.free-1), p.lockFor)
+}
+
+type throttleKey struct {
+	policy throttlePolicy
+	key    string
+}
+
+// loginThrottleKeys returns the keys a login attempt for email from r is
+// counted against.
+func loginThrottleKeys(r *http.Request, email string) []throttleKey {
+	return []throttleKey{
+		{ipThrottle, "ip:" + clientIP(r)},
+		{accountThrottle, accountThrottleKey(email)},
+	}
+}
+
+// registerThrottleKeys returns the keys a registration from r is counted
+// against.
+func registerThrottleKeys(r *http.Request) []throttleKey {
+	return []throttleKey{{registerThrottle, "register:" + clientIP(r)}}
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+// countAttempt counts an attempt against keys before it is made, so that
+// parallel attempts cannot slip past a lock, and locks the keys that have
+// made too many. If any key is locked already the attempt is refused: the
+// other keys are not charged for it and until says when to try again.
+func (h *Handler) countAttempt(ctx context.Context, keys []throttleKey) (counted []model.LoginAttempts, until time.Time, err error) {
+	now := h.now()
+	for _, k := range keys {
+		a, err := h.store.IncrementLoginAttempts(ctx, k.key, now, loginFailureWindow, k.policy.delay)
+		if errors.Is(err, store.ErrLockedOut) {
+			if a.LockedUntil.After(until) {
+				until = a.LockedUntil
+			}
+			continue
+		}
+		if err != nil {
+			h.takeBackAttempt(ctx, counted)
+			return nil, time.Time{}, err
+		}
+		if a.Failures == k.policy.lockAfter {
+			middleware.LoginLockouts.WithLabelValues(k.policy.scope).Inc()
+			h.logger.Warn("locked out", "scope", k.policy.scope, "until", a.LockedUntil)
+		}
+		counted = append(counted, a)
+	}
+	if !until.IsZero() {
+		h.takeBackAttempt(ctx, counted)
+		return nil, until, nil
+	}
+	return counted, time.Time{}, nil
+}
+
+// takeBackAttempt uncounts an attempt that succeeded, so only failed ones
+// add up. Errors are logged, as they only leave the attempt counted.
+func (h *Handler) takeBackAttempt(ctx context.Context, counted []model.LoginAttempts) {
+	for _, a := range counted {
+		if err := h.store.DecrementLoginAttempts(ctx, a); err != nil {
+			h.logger.Error("taking back login attempt", "error", err)
+		}
+	}
+}
+
+// clearLoginFailures forgets the failed logins of the account with email
+// after it signed in successfully.
+func (h *Handler) clearLoginFailures(ctx context.Context, email string) {
+	if err := h.store.DeleteLoginAttempts(ctx, accountThrottleKey(email)); err != nil {
+		h.logger.Error("clearing login attempts", "error", err)
+	}
+}
+
+// tooManyAttempts answers 429 with the seconds until until in Retry-After.
+func (h *Handler) tooManyAttempts(w http.ResponseWriter, until time.Time, message string) {
+	wait := int(math.Ceil(until.Sub(h.now()).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(wait, 1)))
+	h.errorResponse(w, http.StatusTooManyRequests, message)
+}
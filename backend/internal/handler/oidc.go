@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/config"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/oidc"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+const (
+	oidcLoginPurpose = "oidc"
+	oidcLoginTTL     = 10 * time.Minute
+	oidcCookie       = "oidc_login"
+	oidcCookiePath   = "/api/v1/auth/oidc"
+	OIDCCallbackPath = oidcCookiePath + "/callback"
+)
+
+// oidcLoginClaims carry the state, nonce and PKCE verifier of a login in
+// progress between OIDCLogin and OIDCCallback, in a signed cookie.
+type oidcLoginClaims struct {
+	Purpose  string `json:"purpose"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// OIDCLogin starts a single sign-on by redirecting to the OpenID provider.
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		h.errorResponse(w, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		h.logger.Error("generating oidc state", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		h.logger.Error("generating oidc nonce", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	verifier, challenge, err := oidc.NewVerifier()
+	if err != nil {
+		h.logger.Error("generating pkce verifier", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	authURL, err := h.oidc.AuthCodeURL(r.Context(), state, nonce, challenge)
+	if err != nil {
+		h.logger.Error("starting oidc login", "error", err)
+		h.errorResponse(w, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+
+	now := h.now()
+	claims := oidcLoginClaims{
+		Purpose:  oidcLoginPurpose,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcLoginTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.jwtSecret)
+	if err != nil {
+		h.logger.Error("signing oidc login", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	h.setOIDCCookie(w, signed, int(oidcLoginTTL.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes a single sign-on. The user is linked by verified
+// email, or provisioned when registration allows it, and then signed in
+// like after Login. A password account is only linked once its own address
+// is verified, so whoever registered an address they do not own cannot
+// catch its owner's first single sign-on. An account with two-factor
+// authentication still has to pass it, whatever the provider checked.
+//
+// The browser is sent back to the login page with the new session's
+// refresh token in the URL fragment, which never reaches a server; the
+// page trades it for an access token via Refresh. An account with a second
+// factor gets a challenge token there instead, for LoginTwoFactor, and
+// failures carry an error code.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		h.errorResponse(w, http.StatusNotFound, "single sign-on is not configured")
+		return
+	}
+	h.setOIDCCookie(w, "", -1)
+
+	user, reason := h.oidcUser(r)
+	if reason != "" {
+		h.redirectToLogin(w, r, url.Values{"error": {reason}})
+		return
+	}
+
+	h.seedDefaultCategoriesIfEmpty(r.Context(), user.PersonalWorkspaceID.String(), "contracts", defaultContractCategories)
+	h.seedDefaultCategoriesIfEmpty(r.Context(), user.PersonalWorkspaceID.String(), "purchases", defaultPurchaseCategories)
+
+	twoFactor, err := h.twoFactorEnabled(r.Context(), user.ID.String())
+	if err != nil {
+		h.logger.Error("checking two-factor authentication", "user_id", user.ID, "error", err)
+		h.redirectToLogin(w, r, url.Values{"error": {"internal"}})
+		return
+	}
+	if twoFactor {
+		challenge, err := h.issueChallenge(user.ID.String())
+		if err != nil {
+			h.logger.Error("issuing challenge", "error", err)
+			h.redirectToLogin(w, r, url.Values{"error": {"internal"}})
+			return
+		}
+		h.redirectToLogin(w, r, url.Values{"challengeToken": {challenge}})
+		return
+	}
+
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		h.logger.Error("issuing token", "error", err)
+		h.redirectToLogin(w, r, url.Values{"error": {"internal"}})
+		return
+	}
+	h.redirectToLogin(w, r, url.Values{"refreshToken": {resp.RefreshToken}})
+}
+
+// oidcUser verifies the callback and returns the user it signs in, or the
+// reason it fails.
+func (h *Handler) oidcUser(r *http.Request) (model.User, string) {
+	q := r.URL.Query()
+	if q.Get("error") != "" {
+		return model.User{}, "oidc_failed"
+	}
+
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		return model.User{}, "oidc_failed"
+	}
+	var login oidcLoginClaims
+	_, err = jwt.ParseWithClaims(cookie.Value, &login, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return h.jwtSecret, nil
+	}, jwt.WithTimeFunc(h.now))
+	if err != nil || login.Purpose != oidcLoginPurpose || login.State == "" || login.State != q.Get("state") {
+		return model.User{}, "oidc_failed"
+	}
+
+	claims, err := h.oidc.Exchange(r.Context(), q.Get("code"), login.Verifier, login.Nonce)
+	if err != nil {
+		h.logger.Warn("oidc login failed", "error", err)
+		return model.User{}, "oidc_failed"
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return model.User{}, "email_not_verified"
+	}
+	if !h.oidcDomainAllowed(claims.Email) {
+		return model.User{}, "domain_not_allowed"
+	}
+
+	ctx := r.Context()
+	user, err := h.store.GetUserByEmail(ctx, claims.Email)
+	switch {
+	case err == nil:
+		if user.OIDCSubject != claims.Subject {
+			// An address that is already linked might have been reassigned
+			// at the provider; it must not take over the account.
+			if user.OIDCSubject != "" {
+				h.logger.Warn("oidc subject mismatch", "user_id", user.ID)
+				return model.User{}, "account_mismatch"
+			}
+			if !user.EmailVerified && user.PasswordHash != "" {
+				h.logger.Warn("oidc login for unverified account", "user_id", user.ID)
+				return model.User{}, "account_unverified"
+			}
+			user.OIDCSubject = claims.Subject
+			user.EmailVerified = true
+			if err := h.store.UpdateUser(ctx, user); err != nil {
+				h.logger.Error("linking oidc account", "user_id", user.ID, "error", err)
+				return model.User{}, "internal"
+			}
+		}
+	case errors.Is(err, store.ErrNotFound):
+		users, err := h.store.ListUsers(ctx)
+		if err != nil {
+			h.logger.Error("listing users", "error", err)
+			return model.User{}, "internal"
+		}
+		first := len(users) == 0
+		if !first && h.registration != config.RegistrationOpen {
+			return model.User{}, "registration_closed"
+		}
+		user = model.User{
+			ID:                  uuid.New(),
+			Email:               claims.Email,
+			OIDCSubject:         claims.Subject,
+			EmailVerified:       true,
+			PersonalWorkspaceID: uuid.New(),
+			Admin:               first,
+			CreatedAt:           time.Now().UTC(),
+		}
+		if err := h.createAccount(ctx, user); err != nil {
+			h.logger.Error("provisioning oidc user", "error", err)
+			return model.User{}, "internal"
+		}
+		h.logger.Info("user provisioned via oidc", "user_id", user.ID)
+	default:
+		h.logger.Error("looking up user", "error", err)
+		return model.User{}, "internal"
+	}
+
+	if user.Disabled {
+		return model.User{}, "account_disabled"
+	}
+	return user, ""
+}
+
+func (h *Handler) oidcDomainAllowed(email string) bool {
+	if len(h.oidcDomains) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return slices.ContainsFunc(h.oidcDomains, func(d string) bool {
+		return strings.EqualFold(strings.TrimSpace(d), domain)
+	})
+}
+
+func (h *Handler) setOIDCCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    value,
+		Path:     oidcCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(h.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// redirectToLogin sends the browser to the frontend login page with
+// fragment as its URL fragment.
+func (h *Handler) redirectToLogin(w http.ResponseWriter, r *http.Request, fragment url.Values) {
+	http.Redirect(w, r, strings.TrimSuffix(h.baseURL, "/")+"/login#"+fragment.Encode(), http.StatusFound)
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetTTL = time.Hour
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the address belongs to an account, and each address gets
+// at most a few emails per hour. Links are only sent when a base URL is
+// configured, so a forged Host header cannot redirect them.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Email == "" {
+		h.errorResponse(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	if h.resetLimiter.Allow(strings.ToLower(req.Email)) {
+		h.sendPasswordReset(r, req.Email)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sendPasswordReset issues a token for the account with the given email, if
+// any, and mails the link. Failures are logged but not reported.
+func (h *Handler) sendPasswordReset(r *http.Request, address string) {
+	if h.sendMail == nil || h.baseURL == "" {
+		h.logger.Warn("password reset requested but email or BASE_URL is not configured")
+		return
+	}
+	user, err := h.store.GetUserByEmail(r.Context(), address)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			h.logger.Error("looking up user", "error", err)
+		}
+		return
+	}
+
+	token, err := h.issuePasswordReset(r, user)
+	if err != nil {
+		h.logger.Error("issuing reset token", "error", err)
+		return
+	}
+	h.mailPasswordReset(r, user, token)
+}
+
+// issuePasswordReset stores a new reset token for user, replacing any
+// previous one, and returns the token.
+func (h *Handler) issuePasswordReset(r *http.Request, user model.User) (string, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := h.now().UTC()
+	t := model.PasswordResetToken{
+		TokenHash: hash,
+		UserID:    user.ID.String(),
+		CreatedAt: now,
+		ExpiresAt: now.Add(passwordResetTTL),
+	}
+	if err := h.store.SetPasswordResetToken(r.Context(), t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// mailPasswordReset sends the reset link for token in the background. The
+// caller checks that email and the base URL are configured.
+func (h *Handler) mailPasswordReset(r *http.Request, user model.User, token string) {
+	link := strings.TrimSuffix(h.baseURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	subject, body := passwordResetEmail(preferredLanguage(r), link)
+	go func() {
+		if err := h.sendMail([]string{user.Email}, subject, body); err != nil {
+			h.logger.Error("sending password reset email", "user_id", user.ID, "error", err)
+		}
+	}()
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword,
+// signs the user out everywhere and revokes their API keys.
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		h.errorResponse(w, http.StatusBadRequest, "token and password are required")
+		return
+	}
+
+	t, err := h.store.ConsumePasswordResetToken(r.Context(), hashToken(req.Token))
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !h.now().Before(t.ExpiresAt)) {
+		h.errorResponse(w, http.StatusBadRequest, "invalid or expired token")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), t.UserID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		h.logger.Error("hashing password", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	user.PasswordHash = string(hash)
+	if err := h.store.UpdateUser(r.Context(), user); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if err := h.revokeAccess(r.Context(), t.UserID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// preferredLanguage returns "de" if the request's Accept-Language prefers
+// German over English, and "en" otherwise.
+func preferredLanguage(r *http.Request) string {
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		switch lang {
+		case "de", "en":
+			return lang
+		}
+	}
+	return "en"
+}
+
+func passwordResetEmail(lang, link string) (subject, body string) {
+	minutes := int(passwordResetTTL.Minutes())
+	if lang == "de" {
+		return "Passwort zurücksetzen",
+			fmt.Sprintf("Hallo,\n\nüber den folgenden Link können Sie ein neues Passwort für Ihr Contracts-Konto festlegen:\n\n%s\n\nDer Link ist %d Minuten gültig und kann nur einmal verwendet werden. Wenn Sie das nicht angefordert haben, können Sie diese E-Mail ignorieren.\n\nViele Grüße\nIhr Contracts-Team", link, minutes)
+	}
+	return "Reset your password",
+		fmt.Sprintf("Hello,\n\nUse the following link to choose a new password for your Contracts account:\n\n%s\n\nThe link is valid for %d minutes and can be used once. If you did not request this, you can ignore this email.\n\nBest regards,\nYour Contracts Team", link, minutes)
+}
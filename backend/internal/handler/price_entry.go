@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+func (h *Handler) ListPriceEntries(w http.ResponseWriter, r *http.Request) {
+	contractID, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid contract id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	if _, err := h.store.GetContract(r.Context(), workspaceID, contractID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	entries, err := h.store.ListPriceEntriesByContract(r.Context(), workspaceID, contractID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	sortPriceEntries(entries)
+	h.writeJSON(w, http.StatusOK, entries)
+}
+
+func (h *Handler) CreatePriceEntry(w http.ResponseWriter, r *http.Request) {
+	contractID, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid contract id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	con, err := h.store.GetContract(r.Context(), workspaceID, contractID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	var input model.PriceEntryInput
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := input.Validate(); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	history, err := h.store.ListPriceEntriesByContract(r.Context(), workspaceID, con.ID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	entries := h.newPriceEntries(con, history, *input.Price, input.EffectiveDate, input.Comments)
+	con.Price = con.PriceOn(h.now().UTC(), append(history, entries...))
+	con.UpdatedAt = h.now().UTC()
+	if err := h.store.UpdateContract(r.Context(), workspaceID, con, entries...); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, entries[len(entries)-1])
+}
+
+func (h *Handler) DeletePriceEntry(w http.ResponseWriter, r *http.Request) {
+	contractID, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid contract id")
+		return
+	}
+	priceID, err := parseUUID(r.PathValue("priceId"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid price id")
+		return
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	con, err := h.store.GetContract(r.Context(), workspaceID, contractID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	entry, err := h.store.GetPriceEntry(r.Context(), workspaceID, priceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if entry.ContractID != contractID {
+		h.errorResponse(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if err := h.store.DeletePriceEntry(r.Context(), workspaceID, priceID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if err := h.syncCurrentPrice(r.Context(), workspaceID, con); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newPriceEntries returns the entries that add price to the contract's price
+// history. The first entry of a contract that already has a price also
+// records that price as of the start date, so the amount paid before the
+// change is not lost.
+func (h *Handler) newPriceEntries(con model.Contract, history []model.PriceEntry, price float64, effectiveDate, comments string) []model.PriceEntry {
+	now := h.now().UTC()
+	var entries []model.PriceEntry
+	if len(history) == 0 && con.Price != nil && con.StartDate < effectiveDate {
+		entries = append(entries, model.PriceEntry{
+			ID:            uuid.New(),
+			ContractID:    con.ID,
+			Price:         *con.Price,
+			EffectiveDate: con.StartDate,
+			CreatedAt:     now,
+		})
+	}
+	return append(entries, model.PriceEntry{
+		ID:            uuid.New(),
+		ContractID:    con.ID,
+		Price:         price,
+		EffectiveDate: effectiveDate,
+		Comments:      comments,
+		CreatedAt:     now,
+	})
+}
+
+// syncCurrentPrice stores the price in effect today on the contract itself.
+func (h *Handler) syncCurrentPrice(ctx context.Context, workspaceID string, con model.Contract) error {
+	history, err := h.store.ListPriceEntriesByContract(ctx, workspaceID, con.ID)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		return nil
+	}
+	con.Price = con.PriceOn(h.now().UTC(), history)
+	con.UpdatedAt = h.now().UTC()
+	return h.store.UpdateContract(ctx, workspaceID, con)
+}
+
+// priceHistories loads all price entries of a user grouped by contract.
+func (h *Handler) priceHistories(ctx context.Context, workspaceID string) (map[uuid.UUID][]model.PriceEntry, error) {
+	entries, err := h.store.ListPriceEntries(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	byContract := make(map[uuid.UUID][]model.PriceEntry)
+	for _, e := range entries {
+		byContract[e.ContractID] = append(byContract[e.ContractID], e)
+	}
+	return byContract, nil
+}
+
+// applyCurrentPrices replaces each contract's price with the one in effect
+// today, so future-dated entries become current on their effective date.
+func (h *Handler) applyCurrentPrices(ctx context.Context, workspaceID string, contracts []model.Contract) error {
+	histories, err := h.priceHistories(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	today := h.now().UTC()
+	for i := range contracts {
+		contracts[i].Price = contracts[i].PriceOn(today, histories[contracts[i].ID])
+	}
+	return nil
+}
+
+func sortPriceEntries(entries []model.PriceEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].EffectiveDate != entries[j].EffectiveDate {
+			return entries[i].EffectiveDate < entries[j].EffectiveDate
+		}
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+}
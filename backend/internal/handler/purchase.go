@@ -10,7 +10,7 @@ import (
 )
 
 func (h *Handler) ListPurchases(w http.ResponseWriter, r *http.Request) {
-	purchases, err := h.store.ListPurchases(r.Context(), middleware.GetUserID(r.Context()))
+	purchases, err := h.store.ListPurchases(r.Context(), middleware.GetWorkspaceID(r.Context()))
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -25,7 +25,7 @@ func (h *Handler) ListPurchasesByCategory(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	purchases, err := h.store.ListPurchasesByCategory(r.Context(), middleware.GetUserID(r.Context()), catID)
+	purchases, err := h.store.ListPurchasesByCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), catID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -40,7 +40,7 @@ func (h *Handler) CreatePurchaseInCategory(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if _, err := h.store.GetCategory(r.Context(), middleware.GetUserID(r.Context()), "purchases", catID); err != nil {
+	if _, err := h.store.GetCategory(r.Context(), middleware.GetWorkspaceID(r.Context()), "purchases", catID); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -75,7 +75,7 @@ func (h *Handler) CreatePurchaseInCategory(w http.ResponseWriter, r *http.Reques
 		UpdatedAt:      now,
 	}
 
-	if err := h.store.CreatePurchase(r.Context(), middleware.GetUserID(r.Context()), p); err != nil {
+	if err := h.store.CreatePurchase(r.Context(), middleware.GetWorkspaceID(r.Context()), p); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -89,7 +89,7 @@ func (h *Handler) GetPurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := h.store.GetPurchase(r.Context(), middleware.GetUserID(r.Context()), id)
+	p, err := h.store.GetPurchase(r.Context(), middleware.GetWorkspaceID(r.Context()), id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -104,7 +104,7 @@ func (h *Handler) UpdatePurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := h.store.GetPurchase(r.Context(), middleware.GetUserID(r.Context()), id)
+	existing, err := h.store.GetPurchase(r.Context(), middleware.GetWorkspaceID(r.Context()), id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -120,21 +120,10 @@ func (h *Handler) UpdatePurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing.Type = input.Type
-	existing.ItemName = input.ItemName
-	existing.Brand = input.Brand
-	existing.ArticleNumber = input.ArticleNumber
-	existing.Dealer = input.Dealer
-	existing.Price = input.Price
-	existing.PurchaseDate = input.PurchaseDate
-	existing.DescriptionURL = input.DescriptionURL
-	existing.InvoiceURL = input.InvoiceURL
-	existing.HandbookURL = input.HandbookURL
-	existing.Consumables = input.Consumables
-	existing.Comments = input.Comments
+	applyPurchaseInput(&existing, input)
 	existing.UpdatedAt = time.Now().UTC()
 
-	if err := h.store.UpdatePurchase(r.Context(), middleware.GetUserID(r.Context()), existing); err != nil {
+	if err := h.store.UpdatePurchase(r.Context(), middleware.GetWorkspaceID(r.Context()), existing); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -148,9 +137,25 @@ func (h *Handler) DeletePurchase(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.store.DeletePurchase(r.Context(), middleware.GetUserID(r.Context()), id); err != nil {
+	if err := h.store.DeletePurchase(r.Context(), middleware.GetWorkspaceID(r.Context()), id); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// applyPurchaseInput copies the user-editable fields of input onto p.
+func applyPurchaseInput(p *model.Purchase, input model.PurchaseInput) {
+	p.Type = input.Type
+	p.ItemName = input.ItemName
+	p.Brand = input.Brand
+	p.ArticleNumber = input.ArticleNumber
+	p.Dealer = input.Dealer
+	p.Price = input.Price
+	p.PurchaseDate = input.PurchaseDate
+	p.DescriptionURL = input.DescriptionURL
+	p.InvoiceURL = input.InvoiceURL
+	p.HandbookURL = input.HandbookURL
+	p.Consumables = input.Consumables
+	p.Comments = input.Comments
+}
@@ -21,15 +21,15 @@ type purchaseSummaryResponse struct {
 }
 
 func (h *Handler) PurchaseSummary(w http.ResponseWriter, r *http.Request) {
-	userID := middleware.GetUserID(r.Context())
+	workspaceID := middleware.GetWorkspaceID(r.Context())
 
-	cats, err := h.store.ListCategories(r.Context(), userID, "purchases")
+	cats, err := h.store.ListCategories(r.Context(), workspaceID, "purchases")
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
 
-	purchases, err := h.store.ListPurchases(r.Context(), userID)
+	purchases, err := h.store.ListPurchases(r.Context(), workspaceID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
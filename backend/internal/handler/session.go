@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// startSession creates a session for the user signing in with r and returns
+// its first token pair.
+func (h *Handler) startSession(r *http.Request, user model.User) (authResponse, error) {
+	refresh, hash, err := newOpaqueToken()
+	if err != nil {
+		return authResponse{}, err
+	}
+	now := h.now().UTC()
+	sess := model.Session{
+		ID:               uuid.New(),
+		UserID:           user.ID.String(),
+		RefreshTokenHash: hash,
+		UserAgent:        r.UserAgent(),
+		IPAddress:        clientIP(r),
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL),
+	}
+	if err := h.store.CreateSession(r.Context(), sess); err != nil {
+		return authResponse{}, err
+	}
+	return h.tokenPair(sess, refresh, user)
+}
+
+// tokenPair signs an access token for sess. The refresh token handed to the
+// client is prefixed with the session ID so it can be looked up.
+func (h *Handler) tokenPair(sess model.Session, refresh string, user model.User) (authResponse, error) {
+	expires := sess.LastUsedAt.Add(accessTokenTTL)
+	claims := middleware.AccessClaims{
+		SessionID: sess.ID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sess.UserID,
+			ExpiresAt: jwt.NewNumericDate(expires),
+			IssuedAt:  jwt.NewNumericDate(sess.LastUsedAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.jwtSecret)
+	if err != nil {
+		return authResponse{}, err
+	}
+	return authResponse{
+		Token:        token,
+		RefreshToken: sess.ID.String() + "." + refresh,
+		ExpiresAt:    expires,
+		User:         user,
+	}, nil
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Refresh exchanges a refresh token for a new token pair. Every refresh token
+// works once: presenting one that was already rotated means it was copied,
+// so the session is revoked for whoever holds it. The store checks and
+// rotates the token atomically, so two concurrent requests with the same
+// token cannot both succeed.
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	idStr, secret, _ := strings.Cut(req.RefreshToken, ".")
+	id, err := parseUUID(idStr)
+	if err != nil || secret == "" {
+		h.errorResponse(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	sess, err := h.store.GetSession(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		h.errorResponse(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	now := h.now().UTC()
+	oldHash := hashToken(secret)
+	if sess.RefreshTokenHash != oldHash || sess.Expired(now) {
+		if !sess.Expired(now) {
+			h.logger.Warn("refresh token reuse, revoking session", "user_id", sess.UserID, "session_id", sess.ID)
+		}
+		h.revokeRefreshed(w, r, sess)
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), sess.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		h.errorResponse(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	refresh, hash, err := newOpaqueToken()
+	if err != nil {
+		h.logger.Error("generating refresh token", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	sess.RefreshTokenHash = hash
+	sess.UserAgent = r.UserAgent()
+	sess.IPAddress = clientIP(r)
+	sess.LastUsedAt = now
+	sess.ExpiresAt = now.Add(refreshTokenTTL)
+	err = h.store.RotateSession(r.Context(), sess, oldHash)
+	if errors.Is(err, store.ErrTokenReused) {
+		h.logger.Warn("concurrent refresh token reuse, revoking session", "user_id", sess.UserID, "session_id", sess.ID)
+		h.revokeRefreshed(w, r, sess)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		h.errorResponse(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	resp, err := h.tokenPair(sess, refresh, user)
+	if err != nil {
+		h.logger.Error("issuing token", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// revokeRefreshed deletes a session whose refresh token can no longer be
+// used and rejects the request.
+func (h *Handler) revokeRefreshed(w http.ResponseWriter, r *http.Request, sess model.Session) {
+	if err := h.store.DeleteSession(r.Context(), sess.UserID, sess.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		h.logger.Error("revoking session", "session_id", sess.ID, "error", err)
+	}
+	h.errorResponse(w, http.StatusUnauthorized, "invalid refresh token")
+}
+
+type sessionResponse struct {
+	model.Session
+	Current bool `json:"current"`
+}
+
+// ListSessions returns the user's active sessions, most recently used first.
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	sessions, err := h.activeSessions(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	current := middleware.GetSessionID(r.Context())
+	out := make([]sessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, sessionResponse{Session: s, Current: s.ID == current})
+	}
+	slices.SortFunc(out, func(a, b sessionResponse) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+// RevokeSession signs a device out. Revoking the current session logs out.
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.store.DeleteSession(r.Context(), middleware.GetUserID(r.Context()), id); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions signs out every device except the current one.
+func (h *Handler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if err := h.revokeSessions(r.Context(), userID, middleware.GetSessionID(r.Context())); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions deletes all of the user's sessions except keep.
+func (h *Handler) revokeSessions(ctx context.Context, userID string, keep uuid.UUID) error {
+	sessions, err := h.store.ListSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if s.ID == keep {
+			continue
+		}
+		if err := h.store.DeleteSession(ctx, userID, s.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// activeSessions lists the user's sessions, deleting expired ones on the way.
+func (h *Handler) activeSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	sessions, err := h.store.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := h.now()
+	active := sessions[:0]
+	for _, s := range sessions {
+		if !s.Expired(now) {
+			active = append(active, s)
+			continue
+		}
+		if err := h.store.DeleteSession(ctx, userID, s.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+	}
+	return active, nil
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
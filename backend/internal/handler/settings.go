@@ -111,5 +111,11 @@ func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sign out every other device that may know the old password.
+	if err := h.revokeSessions(r.Context(), userID, middleware.GetSessionID(r.Context())); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
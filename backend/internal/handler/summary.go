@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/middleware"
@@ -16,20 +17,40 @@ type categorySummary struct {
 }
 
 type summaryResponse struct {
+	Date               string            `json:"date"`
 	TotalContracts     int               `json:"totalContracts"`
 	TotalMonthlyAmount float64           `json:"totalMonthlyAmount"`
 	TotalYearlyAmount  float64           `json:"totalYearlyAmount"`
 	Categories         []categorySummary `json:"categories"`
 }
 
+// Summary aggregates contract costs using the prices in effect on the date
+// given by the optional "date" query parameter (default: today).
 func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
-	cats, err := h.store.ListCategories(r.Context(), middleware.GetUserID(r.Context()), "contracts")
+	date := h.today()
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			h.errorResponse(w, http.StatusBadRequest, "date must be in format YYYY-MM-DD")
+			return
+		}
+		date = d
+	}
+
+	workspaceID := middleware.GetWorkspaceID(r.Context())
+	cats, err := h.store.ListCategories(r.Context(), workspaceID, "contracts")
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	contracts, err := h.store.ListContracts(r.Context(), workspaceID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
 
-	contracts, err := h.store.ListContracts(r.Context(), middleware.GetUserID(r.Context()))
+	histories, err := h.priceHistories(r.Context(), workspaceID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -53,11 +74,13 @@ func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
 			a = &agg{}
 			byCategory[con.CategoryID] = a
 		}
+		monthly := con.MonthlyPrice(date, histories[con.ID])
+		yearly := con.YearlyPrice(date, histories[con.ID])
 		a.count++
-		a.monthlyTotal += con.MonthlyPrice()
-		a.yearlyTotal += con.YearlyPrice()
-		totalMonthly += con.MonthlyPrice()
-		totalYearly += con.YearlyPrice()
+		a.monthlyTotal += monthly
+		a.yearlyTotal += yearly
+		totalMonthly += monthly
+		totalYearly += yearly
 	}
 
 	catSummaries := make([]categorySummary, 0, len(cats))
@@ -73,6 +96,7 @@ func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.writeJSON(w, http.StatusOK, summaryResponse{
+		Date:               date.Format("2006-01-02"),
 		TotalContracts:     len(contracts),
 		TotalMonthlyAmount: totalMonthly,
 		TotalYearlyAmount:  totalYearly,
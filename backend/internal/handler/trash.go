@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+// ListTrash lists the records in the trash of the active workspace, most
+// recently deleted first.
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := h.store.ListTrash(r.Context(), middleware.GetWorkspaceID(r.Context()))
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, items)
+}
+
+// RestoreFromTrash takes a record out of the trash, together with the
+// contracts, purchases or cost entries that were deleted along with it.
+func (h *Handler) RestoreFromTrash(w http.ResponseWriter, r *http.Request) {
+	entityType, id, ok := h.trashItemPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.store.RestoreFromTrash(r.Context(), middleware.GetWorkspaceID(r.Context()), entityType, id)
+	if errors.Is(err, store.ErrConflict) {
+		h.errorResponse(w, http.StatusConflict, "its category or vehicle is in the trash, restore that first")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeFromTrash deletes a record in the trash for good.
+func (h *Handler) PurgeFromTrash(w http.ResponseWriter, r *http.Request) {
+	entityType, id, ok := h.trashItemPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.PurgeFromTrash(r.Context(), middleware.GetWorkspaceID(r.Context()), entityType, id); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) trashItemPath(w http.ResponseWriter, r *http.Request) (string, uuid.UUID, bool) {
+	entityType := r.PathValue("type")
+	if !slices.Contains(model.TrashEntityTypes, entityType) {
+		h.errorResponse(w, http.StatusBadRequest, "unknown type")
+		return "", uuid.Nil, false
+	}
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return "", uuid.Nil, false
+	}
+	return entityType, id, true
+}
@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+	"github.com/tobi/contracts/backend/internal/totp"
+)
+
+const (
+	totpIssuer         = "Contracts"
+	recoveryCodeCount  = 10
+	twoFactorChallenge = "2fa"
+	challengeTTL       = 5 * time.Minute
+)
+
+// challengeClaims identify a user who passed the password check but still
+// has to enter a second factor. They carry no session, so the auth
+// middleware does not accept them as access tokens.
+type challengeClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+type challengeResponse struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+}
+
+// twoFactorEnabled reports whether the user has to pass a second step.
+func (h *Handler) twoFactorEnabled(ctx context.Context, userID string) (bool, error) {
+	tf, err := h.store.GetTwoFactor(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return tf.Enabled, err
+}
+
+func (h *Handler) issueChallenge(userID string) (string, error) {
+	now := h.now()
+	claims := challengeClaims{
+		Purpose: twoFactorChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(challengeTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.jwtSecret)
+}
+
+type loginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+// LoginTwoFactor completes a login that Login answered with a challenge.
+// The code is either a current TOTP code or an unused recovery code. Wrong
+// codes count as failed logins of the account, so guessing codes with the
+// same challenge locks it out like guessing passwords does.
+func (h *Handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req loginTwoFactorRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var claims challengeClaims
+	_, err := jwt.ParseWithClaims(req.ChallengeToken, &claims, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return h.jwtSecret, nil
+	}, jwt.WithTimeFunc(h.now))
+	if err != nil || claims.Purpose != twoFactorChallenge || claims.Subject == "" {
+		h.errorResponse(w, http.StatusUnauthorized, "invalid challenge")
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), claims.Subject)
+	if errors.Is(err, store.ErrNotFound) {
+		h.errorResponse(w, http.StatusUnauthorized, "invalid challenge")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	// An admin may have disabled the account since the password was checked.
+	if user.Disabled {
+		h.errorResponse(w, http.StatusForbidden, "account disabled")
+		return
+	}
+
+	attempts, lockedUntil, err := h.countAttempt(r.Context(), loginThrottleKeys(r, user.Email))
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if !lockedUntil.IsZero() {
+		h.tooManyAttempts(w, lockedUntil, "too many failed login attempts, try again later")
+		return
+	}
+
+	ok, err := h.verifySecondFactor(r.Context(), claims.Subject, req.Code)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if !ok {
+		middleware.LoginFailures.Inc()
+		h.errorResponse(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+	h.takeBackAttempt(r.Context(), attempts)
+	h.clearLoginFailures(r.Context(), user.Email)
+
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		h.logger.Error("issuing token", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// verifySecondFactor checks code against the user's enabled TOTP secret and
+// recovery codes. Accepted codes are used up: the TOTP step is remembered
+// so the code cannot be replayed, and recovery codes are deleted. The store
+// does this atomically, so of two requests with the same code only one
+// succeeds.
+func (h *Handler) verifySecondFactor(ctx context.Context, userID, code string) (bool, error) {
+	tf, err := h.store.GetTwoFactor(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil || !tf.Enabled {
+		return false, err
+	}
+
+	if step, ok := totp.Verify(tf.Secret, code, h.now(), tf.LastUsedStep); ok {
+		err = h.store.UseTwoFactorStep(ctx, userID, step)
+	} else {
+		err = h.store.ConsumeRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type twoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+func (h *Handler) GetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	tf, err := h.store.GetTwoFactor(r.Context(), middleware.GetUserID(r.Context()))
+	if errors.Is(err, store.ErrNotFound) {
+		h.writeJSON(w, http.StatusOK, twoFactorStatus{})
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, twoFactorStatus{
+		Enabled:                tf.Enabled,
+		Pending:                !tf.Enabled,
+		RecoveryCodesRemaining: len(tf.RecoveryCodes),
+	})
+}
+
+type twoFactorEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// BeginTwoFactor generates a new pending secret. Starting again replaces a
+// pending secret that was never confirmed.
+func (h *Handler) BeginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	enabled, err := h.twoFactorEnabled(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if enabled {
+		h.errorResponse(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		h.logger.Error("generating TOTP secret", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	tf := model.TwoFactor{Secret: secret, CreatedAt: h.now().UTC()}
+	if err := h.store.SetTwoFactor(r.Context(), userID, tf); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, twoFactorEnrolment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Email, secret),
+	})
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// ConfirmTwoFactor enables the pending secret once the user enters a valid
+// code, and returns the recovery codes. They are only shown this once.
+func (h *Handler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorCodeRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	tf, err := h.store.GetTwoFactor(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if tf.Enabled {
+		h.errorResponse(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	step, ok := totp.Verify(tf.Secret, req.Code, h.now(), 0)
+	if !ok {
+		h.errorResponse(w, http.StatusBadRequest, "invalid code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		h.logger.Error("generating recovery codes", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	now := h.now().UTC()
+	tf.Enabled = true
+	tf.EnabledAt = &now
+	tf.LastUsedStep = step
+	tf.RecoveryCodes = hashes
+	if err := h.store.SetTwoFactor(r.Context(), userID, tf); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor turns 2FA off. An enabled configuration can only be
+// removed with a valid TOTP or recovery code; a pending one needs none.
+// Wrong codes count as failed logins of the account, so a stolen access
+// token is not enough to guess the code.
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorCodeRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	enabled, err := h.twoFactorEnabled(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if enabled {
+		user, err := h.store.GetUserByID(r.Context(), userID)
+		if err != nil {
+			h.handleStoreError(w, err)
+			return
+		}
+		throttleKeys := []throttleKey{{accountThrottle, accountThrottleKey(user.Email)}}
+		attempts, lockedUntil, err := h.countAttempt(r.Context(), throttleKeys)
+		if err != nil {
+			h.handleStoreError(w, err)
+			return
+		}
+		if !lockedUntil.IsZero() {
+			h.tooManyAttempts(w, lockedUntil, "too many failed attempts, try again later")
+			return
+		}
+
+		ok, err := h.verifySecondFactor(r.Context(), userID, req.Code)
+		if err != nil {
+			h.handleStoreError(w, err)
+			return
+		}
+		if !ok {
+			middleware.LoginFailures.Inc()
+			h.errorResponse(w, http.StatusBadRequest, "invalid code")
+			return
+		}
+		h.takeBackAttempt(r.Context(), attempts)
+	}
+	if err := h.store.DeleteTwoFactor(r.Context(), userID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns recovery codes of the form "abcd-efgh" and their
+// hashes.
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		s := strings.ToLower(recoveryEncoding.EncodeToString(b))
+		codes = append(codes, s[:4]+"-"+s[4:])
+		hashes = append(hashes, hashToken(s))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
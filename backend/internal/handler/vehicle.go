@@ -10,7 +10,7 @@ import (
 )
 
 func (h *Handler) ListVehicles(w http.ResponseWriter, r *http.Request) {
-	vehicles, err := h.store.ListVehicles(r.Context(), middleware.GetUserID(r.Context()))
+	vehicles, err := h.store.ListVehicles(r.Context(), middleware.GetWorkspaceID(r.Context()))
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -25,7 +25,7 @@ func (h *Handler) GetVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	v, err := h.store.GetVehicle(r.Context(), middleware.GetUserID(r.Context()), id)
+	v, err := h.store.GetVehicle(r.Context(), middleware.GetWorkspaceID(r.Context()), id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -65,7 +65,7 @@ func (h *Handler) CreateVehicle(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:         now,
 	}
 
-	if err := h.store.CreateVehicle(r.Context(), middleware.GetUserID(r.Context()), v); err != nil {
+	if err := h.store.CreateVehicle(r.Context(), middleware.GetWorkspaceID(r.Context()), v); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -79,7 +79,7 @@ func (h *Handler) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existing, err := h.store.GetVehicle(r.Context(), middleware.GetUserID(r.Context()), id)
+	existing, err := h.store.GetVehicle(r.Context(), middleware.GetWorkspaceID(r.Context()), id)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
@@ -111,7 +111,7 @@ func (h *Handler) UpdateVehicle(w http.ResponseWriter, r *http.Request) {
 	existing.Comments = input.Comments
 	existing.UpdatedAt = time.Now().UTC()
 
-	if err := h.store.UpdateVehicle(r.Context(), middleware.GetUserID(r.Context()), existing); err != nil {
+	if err := h.store.UpdateVehicle(r.Context(), middleware.GetWorkspaceID(r.Context()), existing); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
@@ -125,7 +125,7 @@ func (h *Handler) DeleteVehicle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.store.DeleteVehicle(r.Context(), middleware.GetUserID(r.Context()), id); err != nil {
+	if err := h.store.DeleteVehicle(r.Context(), middleware.GetWorkspaceID(r.Context()), id); err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
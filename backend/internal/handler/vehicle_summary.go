@@ -15,15 +15,15 @@ func (h *Handler) VehicleSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := middleware.GetUserID(r.Context())
+	workspaceID := middleware.GetWorkspaceID(r.Context())
 
-	vehicle, err := h.store.GetVehicle(r.Context(), userID, vehicleID)
+	vehicle, err := h.store.GetVehicle(r.Context(), workspaceID, vehicleID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
 	}
 
-	entries, err := h.store.ListCostEntries(r.Context(), userID, vehicleID)
+	entries, err := h.store.ListCostEntries(r.Context(), workspaceID, vehicleID)
 	if err != nil {
 		h.handleStoreError(w, err)
 		return
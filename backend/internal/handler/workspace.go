@@ -0,0 +1,469 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+const (
+	personalWorkspaceName = "Personal"
+	invitationTTL         = 7 * 24 * time.Hour
+)
+
+// createWorkspace stores ws owned by userID and seeds its default categories.
+func (h *Handler) createWorkspace(ctx context.Context, ws model.Workspace, userID string) error {
+	owner := model.WorkspaceMember{
+		WorkspaceID: ws.ID,
+		UserID:      userID,
+		Role:        model.WorkspaceRoleOwner,
+		JoinedAt:    ws.CreatedAt,
+	}
+	if err := h.store.CreateWorkspace(ctx, ws, owner); err != nil {
+		return err
+	}
+	h.seedDefaultCategoriesIfEmpty(ctx, ws.ID.String(), "contracts", defaultContractCategories)
+	h.seedDefaultCategoriesIfEmpty(ctx, ws.ID.String(), "purchases", defaultPurchaseCategories)
+	return nil
+}
+
+// memberContracts lists the contracts of every workspace the user belongs
+// to, for views that span workspaces such as the calendar feed.
+func (h *Handler) memberContracts(ctx context.Context, userID string) ([]model.Contract, error) {
+	memberships, err := h.store.ListWorkspaceMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var contracts []model.Contract
+	for _, m := range memberships {
+		cs, err := h.store.ListContracts(ctx, m.WorkspaceID.String())
+		if err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, cs...)
+	}
+	return contracts, nil
+}
+
+// workspaceMember resolves the {id} path value to the caller's membership.
+// Workspaces the caller does not belong to are reported as not found.
+func (h *Handler) workspaceMember(w http.ResponseWriter, r *http.Request) (model.WorkspaceMember, bool) {
+	id, err := parseUUID(r.PathValue("id"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return model.WorkspaceMember{}, false
+	}
+	m, err := h.store.GetWorkspaceMember(r.Context(), id, middleware.GetUserID(r.Context()))
+	if err != nil {
+		h.handleStoreError(w, err)
+		return model.WorkspaceMember{}, false
+	}
+	return m, true
+}
+
+// workspaceOwner is workspaceMember for actions only the owner may take.
+func (h *Handler) workspaceOwner(w http.ResponseWriter, r *http.Request) (model.WorkspaceMember, bool) {
+	m, ok := h.workspaceMember(w, r)
+	if ok && m.Role != model.WorkspaceRoleOwner {
+		h.errorResponse(w, http.StatusForbidden, "only the owner can do this")
+		return m, false
+	}
+	return m, ok
+}
+
+type workspaceResponse struct {
+	model.Workspace
+	Role     string `json:"role"`
+	Personal bool   `json:"personal"`
+}
+
+// ListWorkspaces returns the user's workspaces, personal workspace first.
+func (h *Handler) ListWorkspaces(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	memberships, err := h.store.ListWorkspaceMemberships(r.Context(), userID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	out := make([]workspaceResponse, 0, len(memberships))
+	for _, m := range memberships {
+		ws, err := h.store.GetWorkspace(r.Context(), m.WorkspaceID)
+		if err != nil {
+			h.handleStoreError(w, err)
+			return
+		}
+		out = append(out, workspaceResponse{Workspace: ws, Role: m.Role, Personal: ws.ID == user.PersonalWorkspaceID})
+	}
+	slices.SortFunc(out, func(a, b workspaceResponse) int {
+		if a.Personal != b.Personal {
+			if a.Personal {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) CreateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var input model.WorkspaceInput
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := input.Validate(); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ws := model.Workspace{ID: uuid.New(), Name: input.Name, CreatedAt: h.now().UTC()}
+	if err := h.createWorkspace(r.Context(), ws, middleware.GetUserID(r.Context())); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, workspaceResponse{Workspace: ws, Role: model.WorkspaceRoleOwner})
+}
+
+// UpdateWorkspace renames a workspace.
+func (h *Handler) UpdateWorkspace(w http.ResponseWriter, r *http.Request) {
+	var input model.WorkspaceInput
+	if err := h.readJSON(r, &input); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := input.Validate(); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	m, ok := h.workspaceOwner(w, r)
+	if !ok {
+		return
+	}
+
+	ws, err := h.store.GetWorkspace(r.Context(), m.WorkspaceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	ws.Name = input.Name
+	if err := h.store.UpdateWorkspace(r.Context(), ws); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), m.UserID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, workspaceResponse{Workspace: ws, Role: m.Role, Personal: ws.ID == user.PersonalWorkspaceID})
+}
+
+// DeleteWorkspace deletes a workspace and all of its data. The personal
+// workspace cannot be deleted.
+func (h *Handler) DeleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.workspaceOwner(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.store.GetUserByID(r.Context(), m.UserID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if m.WorkspaceID == user.PersonalWorkspaceID {
+		h.errorResponse(w, http.StatusBadRequest, "the personal workspace cannot be deleted")
+		return
+	}
+	if err := h.store.DeleteWorkspace(r.Context(), m.WorkspaceID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type memberResponse struct {
+	UserID   string    `json:"userId"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+func (h *Handler) ListWorkspaceMembers(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.workspaceMember(w, r)
+	if !ok {
+		return
+	}
+	members, err := h.store.ListWorkspaceMembers(r.Context(), m.WorkspaceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	out := make([]memberResponse, 0, len(members))
+	for _, mem := range members {
+		user, err := h.store.GetUserByID(r.Context(), mem.UserID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			h.handleStoreError(w, err)
+			return
+		}
+		out = append(out, memberResponse{UserID: mem.UserID, Email: user.Email, Role: mem.Role, JoinedAt: mem.JoinedAt})
+	}
+	slices.SortFunc(out, func(a, b memberResponse) int {
+		return a.JoinedAt.Compare(b.JoinedAt)
+	})
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+// UpdateWorkspaceMember changes a member's role between editor and viewer.
+func (h *Handler) UpdateWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	var req roleRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !model.ValidInviteRole(req.Role) {
+		h.errorResponse(w, http.StatusBadRequest, "role must be editor or viewer")
+		return
+	}
+	owner, ok := h.workspaceOwner(w, r)
+	if !ok {
+		return
+	}
+
+	m, err := h.store.GetWorkspaceMember(r.Context(), owner.WorkspaceID, r.PathValue("userId"))
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	if m.Role == model.WorkspaceRoleOwner {
+		h.errorResponse(w, http.StatusBadRequest, "the owner's role cannot be changed")
+		return
+	}
+	m.Role = req.Role
+	if err := h.store.SetWorkspaceMember(r.Context(), m); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveWorkspaceMember removes a member. The owner can remove anyone else;
+// other members can only remove themselves, which leaves the workspace.
+func (h *Handler) RemoveWorkspaceMember(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.workspaceMember(w, r)
+	if !ok {
+		return
+	}
+	userID := r.PathValue("userId")
+	if userID == caller.UserID {
+		if caller.Role == model.WorkspaceRoleOwner {
+			h.errorResponse(w, http.StatusBadRequest, "the owner cannot leave the workspace")
+			return
+		}
+	} else if caller.Role != model.WorkspaceRoleOwner {
+		h.errorResponse(w, http.StatusForbidden, "only the owner can do this")
+		return
+	}
+
+	if err := h.store.DeleteWorkspaceMember(r.Context(), caller.WorkspaceID, userID); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type invitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// createdInvitation carries the invitation token, which is only returned
+// here so the owner can pass the link on when email is not configured.
+type createdInvitation struct {
+	model.Invitation
+	Token string `json:"token"`
+}
+
+// CreateInvitation invites someone by email to join the workspace.
+func (h *Handler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	var req invitationRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Email == "" {
+		h.errorResponse(w, http.StatusBadRequest, "email is required")
+		return
+	}
+	if !model.ValidInviteRole(req.Role) {
+		h.errorResponse(w, http.StatusBadRequest, "role must be editor or viewer")
+		return
+	}
+	owner, ok := h.workspaceOwner(w, r)
+	if !ok {
+		return
+	}
+	ws, err := h.store.GetWorkspace(r.Context(), owner.WorkspaceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		h.logger.Error("generating invitation token", "error", err)
+		h.errorResponse(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	now := h.now().UTC()
+	inv := model.Invitation{
+		ID:          uuid.New(),
+		WorkspaceID: ws.ID,
+		Email:       req.Email,
+		Role:        req.Role,
+		TokenHash:   hash,
+		InvitedBy:   owner.UserID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(invitationTTL),
+	}
+	if err := h.store.CreateInvitation(r.Context(), inv); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	if h.sendMail != nil && h.baseURL != "" {
+		link := strings.TrimSuffix(h.baseURL, "/") + "/invitations/accept?token=" + url.QueryEscape(token)
+		subject, body := invitationEmail(preferredLanguage(r), ws.Name, link)
+		go func() {
+			if err := h.sendMail([]string{inv.Email}, subject, body); err != nil {
+				h.logger.Error("sending invitation email", "workspace_id", ws.ID, "error", err)
+			}
+		}()
+	}
+	h.writeJSON(w, http.StatusCreated, createdInvitation{Invitation: inv, Token: token})
+}
+
+// ListInvitations returns the workspace's pending invitations.
+func (h *Handler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.workspaceOwner(w, r)
+	if !ok {
+		return
+	}
+	invitations, err := h.store.ListInvitations(r.Context(), owner.WorkspaceID)
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	slices.SortFunc(invitations, func(a, b model.Invitation) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	h.writeJSON(w, http.StatusOK, invitations)
+}
+
+func (h *Handler) DeleteInvitation(w http.ResponseWriter, r *http.Request) {
+	owner, ok := h.workspaceOwner(w, r)
+	if !ok {
+		return
+	}
+	id, err := parseUUID(r.PathValue("invitationId"))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.store.DeleteInvitation(r.Context(), owner.WorkspaceID, id); err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// AcceptInvitation adds the signed-in user to the invitation's workspace.
+// Invitations work once, for whoever holds the link.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req acceptInvitationRequest
+	if err := h.readJSON(r, &req); err != nil {
+		h.errorResponse(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	inv, err := h.store.ConsumeInvitation(r.Context(), hashToken(req.Token))
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !h.now().Before(inv.ExpiresAt)) {
+		h.errorResponse(w, http.StatusBadRequest, "invalid or expired invitation")
+		return
+	}
+	if err != nil {
+		h.handleStoreError(w, err)
+		return
+	}
+
+	// The invitation is consumed first so it cannot be used twice at once,
+	// and put back if the caller does not join after all.
+	userID := middleware.GetUserID(r.Context())
+	if _, err := h.store.GetWorkspaceMember(r.Context(), inv.WorkspaceID, userID); err == nil {
+		h.restoreInvitation(r.Context(), inv)
+		h.errorResponse(w, http.StatusConflict, "already a member of this workspace")
+		return
+	}
+	ws, err := h.store.GetWorkspace(r.Context(), inv.WorkspaceID)
+	if err != nil {
+		h.restoreInvitation(r.Context(), inv)
+		h.handleStoreError(w, err)
+		return
+	}
+	m := model.WorkspaceMember{
+		WorkspaceID: ws.ID,
+		UserID:      userID,
+		Role:        inv.Role,
+		JoinedAt:    h.now().UTC(),
+	}
+	if err := h.store.SetWorkspaceMember(r.Context(), m); err != nil {
+		h.restoreInvitation(r.Context(), inv)
+		h.handleStoreError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, workspaceResponse{Workspace: ws, Role: m.Role})
+}
+
+// restoreInvitation puts back a consumed invitation that did not lead to a
+// join, so it can still be accepted. Errors are logged, as the request
+// fails anyway.
+func (h *Handler) restoreInvitation(ctx context.Context, inv model.Invitation) {
+	if err := h.store.CreateInvitation(ctx, inv); err != nil {
+		h.logger.Error("restoring invitation", "invitation_id", inv.ID, "error", err)
+	}
+}
+
+func invitationEmail(lang, workspace, link string) (subject, body string) {
+	days := int(invitationTTL.Hours() / 24)
+	if lang == "de" {
+		return fmt.Sprintf("Einladung zu %q", workspace),
+			fmt.Sprintf("Hallo,\n\nSie wurden eingeladen, den Bereich %q in Contracts gemeinsam zu verwalten. Über den folgenden Link können Sie die Einladung annehmen:\n\n%s\n\nDer Link ist %d Tage gültig und kann nur einmal verwendet werden.\n\nViele Grüße\nIhr Contracts-Team", workspace, link, days)
+	}
+	return fmt.Sprintf("Invitation to %q", workspace),
+		fmt.Sprintf("Hello,\n\nYou have been invited to share the workspace %q in Contracts. Use the following link to accept the invitation:\n\n%s\n\nThe link is valid for %d days and can be used once.\n\nBest regards,\nYour Contracts Team", workspace, link, days)
+}
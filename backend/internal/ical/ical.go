@@ -0,0 +1,127 @@
+// Package ical writes all-day events as an iCalendar (RFC 5545) feed.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Calendar is a named collection of events.
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day event with optional reminders.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	URL         string
+	// AlarmDaysBefore lists reminders as days before Date.
+	AlarmDaysBefore []int
+}
+
+// Write encodes cal to w. stamp is used as DTSTAMP of every event.
+func Write(w io.Writer, cal Calendar, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	l := &lineWriter{w: bw}
+
+	l.line("BEGIN:VCALENDAR")
+	l.line("VERSION:2.0")
+	l.line("PRODID:-//contracts//calendar feed//EN")
+	l.line("CALSCALE:GREGORIAN")
+	l.line("METHOD:PUBLISH")
+	if cal.Name != "" {
+		l.line("X-WR-CALNAME:" + escapeText(cal.Name))
+	}
+
+	dtstamp := stamp.UTC().Format(dateTimeFormat)
+	for _, e := range cal.Events {
+		l.line("BEGIN:VEVENT")
+		l.line("UID:" + e.UID)
+		l.line("DTSTAMP:" + dtstamp)
+		l.line("DTSTART;VALUE=DATE:" + e.Date.Format(dateFormat))
+		l.line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format(dateFormat))
+		l.line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			l.line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.URL != "" {
+			l.line("URL:" + e.URL)
+		}
+		l.line("TRANSP:TRANSPARENT")
+		for _, days := range e.AlarmDaysBefore {
+			l.line("BEGIN:VALARM")
+			l.line("ACTION:DISPLAY")
+			l.line("DESCRIPTION:" + escapeText(e.Summary))
+			l.line(fmt.Sprintf("TRIGGER:-P%dD", days))
+			l.line("END:VALARM")
+		}
+		l.line("END:VEVENT")
+	}
+
+	l.line("END:VCALENDAR")
+	if l.err != nil {
+		return l.err
+	}
+	return bw.Flush()
+}
+
+// lineWriter writes CRLF-terminated content lines, folding them at 75
+// octets without splitting UTF-8 sequences. It keeps the first error.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (l *lineWriter) line(s string) {
+	if l.err != nil {
+		return
+	}
+	_, l.err = l.w.WriteString(fold(s) + "\r\n")
+}
+
+func fold(s string) string {
+	if len(s) <= maxLineOctets {
+		return s
+	}
+	var b strings.Builder
+	limit := maxLineOctets
+	n := 0
+	for _, r := range s {
+		size := utf8.RuneLen(r)
+		if n+size > limit {
+			b.WriteString("\r\n ")
+			// Continuation lines start with a space, which counts.
+			limit = maxLineOctets - 1
+			n = 0
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
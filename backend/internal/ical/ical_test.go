@@ -0,0 +1,64 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	cal := Calendar{
+		Name: "Contracts",
+		Events: []Event{{
+			UID:             "abc@contracts",
+			Date:            time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC),
+			Summary:         "Cancel: Phone, Telco; Inc",
+			Description:     "Line one\nLine two",
+			AlarmDaysBefore: []int{30, 7},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, cal, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"VERSION:2.0\r\n",
+		"X-WR-CALNAME:Contracts\r\n",
+		"UID:abc@contracts\r\n",
+		"DTSTAMP:20250102T030405Z\r\n",
+		"DTSTART;VALUE=DATE:20250930\r\n",
+		"DTEND;VALUE=DATE:20251001\r\n",
+		`SUMMARY:Cancel: Phone\, Telco\; Inc` + "\r\n",
+		`DESCRIPTION:Line one\nLine two` + "\r\n",
+		"TRIGGER:-P30D\r\n",
+		"TRIGGER:-P7D\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VALARM"); n != 2 {
+		t.Errorf("got %d alarms, want 2", n)
+	}
+}
+
+func TestFold(t *testing.T) {
+	long := "DESCRIPTION:" + strings.Repeat("ä", 60)
+	folded := fold(long)
+	for i, line := range strings.Split(folded, "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line %d has %d octets, want <= %d", i, len(line), maxLineOctets)
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line %d does not start with a space", i)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != long {
+		t.Errorf("unfolding does not restore the line")
+	}
+}
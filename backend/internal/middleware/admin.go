@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+// AdminStore looks up users to check their admin flag.
+type AdminStore interface {
+	GetUserByID(ctx context.Context, id string) (model.User, error)
+}
+
+// Admin only lets authenticated admin users through.
+func Admin(store AdminStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := store.GetUserByID(r.Context(), GetUserID(r.Context()))
+			if err != nil {
+				writeUnauthorized(w)
+				return
+			}
+			if !user.Admin {
+				writeError(w, http.StatusForbidden, "admin access required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
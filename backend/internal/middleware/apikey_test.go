@@ -0,0 +1,27 @@
+package middleware
+
+import "testing"
+
+func TestAPIKeyModule(t *testing.T) {
+	tests := []struct {
+		path   string
+		module string
+		ok     bool
+	}{
+		{"/api/v1/contracts/123/prices", "contracts", true},
+		{"/api/v1/summary", "contracts", true},
+		{"/api/v1/categories/123/purchases", "purchases", true},
+		{"/api/v1/modules/purchases/categories", "purchases", true},
+		{"/api/v1/costs/123", "vehicles", true},
+		{"/api/v1/export", "", true},
+		{"/api/v1/settings/api-keys", "", false},
+		{"/api/v1/sessions", "", false},
+		{"/api/v1/categories", "", false},
+	}
+	for _, tt := range tests {
+		module, ok := apiKeyModule(tt.path)
+		if module != tt.module || ok != tt.ok {
+			t.Errorf("apiKeyModule(%q) = %q, %v; want %q, %v", tt.path, module, ok, tt.module, tt.ok)
+		}
+	}
+}
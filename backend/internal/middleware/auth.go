@@ -7,11 +7,32 @@ import (
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "userID"
+const (
+	userIDKey    contextKey = "userID"
+	sessionIDKey contextKey = "sessionID"
+)
+
+// AccessClaims are the claims of an access token. SessionID ties the token
+// to the session it was issued for, so revoking the session revokes it.
+type AccessClaims struct {
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// AuthStore looks up the session an access token belongs to and the API
+// keys presented instead of access tokens.
+type AuthStore interface {
+	GetUserByID(ctx context.Context, id string) (model.User, error)
+	GetSession(ctx context.Context, id uuid.UUID) (model.Session, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (model.APIKey, error)
+	UpdateAPIKey(ctx context.Context, k model.APIKey) error
+}
 
 func SetUserID(ctx context.Context, id string) context.Context {
 	return context.WithValue(ctx, userIDKey, id)
@@ -24,7 +45,21 @@ func GetUserID(ctx context.Context) string {
 	return ""
 }
 
-func Auth(secret []byte) Middleware {
+func SetSessionID(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, sessionIDKey, id)
+}
+
+// GetSessionID returns the session of the authenticated request, or uuid.Nil.
+func GetSessionID(ctx context.Context) uuid.UUID {
+	if id, ok := ctx.Value(sessionIDKey).(uuid.UUID); ok {
+		return id
+	}
+	return uuid.Nil
+}
+
+// Auth accepts requests carrying a valid access token whose session has not
+// been revoked, or an API key whose scope covers the request.
+func Auth(secret []byte, store AuthStore) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			header := r.Header.Get("Authorization")
@@ -34,31 +69,47 @@ func Auth(secret []byte) Middleware {
 			}
 
 			tokenStr := strings.TrimPrefix(header, "Bearer ")
-			token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (any, error) {
+			if strings.HasPrefix(tokenStr, APIKeyPrefix) {
+				authAPIKey(store, tokenStr, next, w, r)
+				return
+			}
+
+			var claims AccessClaims
+			token, err := jwt.ParseWithClaims(tokenStr, &claims, func(t *jwt.Token) (any, error) {
 				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, jwt.ErrSignatureInvalid
 				}
 				return secret, nil
 			})
-			if err != nil || !token.Valid {
+			if err != nil || !token.Valid || claims.Subject == "" {
 				writeUnauthorized(w)
 				return
 			}
 
-			sub, err := token.Claims.GetSubject()
-			if err != nil || sub == "" {
+			sid, err := uuid.Parse(claims.SessionID)
+			if err != nil {
+				writeUnauthorized(w)
+				return
+			}
+			sess, err := store.GetSession(r.Context(), sid)
+			if err != nil || sess.UserID != claims.Subject {
 				writeUnauthorized(w)
 				return
 			}
 
-			ctx := SetUserID(r.Context(), sub)
+			ctx := SetUserID(r.Context(), claims.Subject)
+			ctx = SetSessionID(ctx, sid)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
 func writeUnauthorized(w http.ResponseWriter) {
+	writeError(w, http.StatusUnauthorized, "unauthorized")
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-	json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
@@ -25,6 +25,29 @@ var (
 		Name: "http_active_requests",
 		Help: "Number of active HTTP requests.",
 	})
+
+	// LoginFailures and LoginLockouts are counted by the login handler.
+	LoginFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "auth_login_failures_total",
+		Help: "Total number of failed login attempts.",
+	})
+
+	LoginLockouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_lockouts_total",
+		Help: "Total number of temporary login lockouts.",
+	}, []string{"scope"})
+
+	// BackupLastTimestamp and BackupLastSize describe the newest backup file
+	// and are set by the backup manager.
+	BackupLastTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "backup_last_timestamp_seconds",
+		Help: "Unix time of the last successful backup.",
+	})
+
+	BackupLastSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "backup_last_size_bytes",
+		Help: "Size in bytes of the last successful backup.",
+	})
 )
 
 type statusRecorder struct {
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Window(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("first two events should be allowed")
+	}
+	if l.Allow("a") {
+		t.Error("third event should be limited")
+	}
+	if !l.Allow("b") {
+		t.Error("keys should be limited independently")
+	}
+
+	now = now.Add(time.Minute)
+	if !l.Allow("a") {
+		t.Error("a new window should reset the count")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(NewLimiter(1, time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	codes := make([]int, 0, 3)
+	for _, addr := range []string{"10.0.0.1:1234", "10.0.0.1:5678", "10.0.0.2:1234"} {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	want := []int{http.StatusNoContent, http.StatusTooManyRequests, http.StatusNoContent}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Errorf("request %d: status = %d, want %d", i, codes[i], want[i])
+		}
+	}
+}
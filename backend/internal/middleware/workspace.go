@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store"
+)
+
+// WorkspaceHeader selects the workspace a request works on. Without it, the
+// user's personal workspace is used.
+const WorkspaceHeader = "X-Workspace-ID"
+
+const (
+	workspaceIDKey   contextKey = "workspaceID"
+	workspaceRoleKey contextKey = "workspaceRole"
+)
+
+// WorkspaceStore looks up users and their workspace memberships.
+type WorkspaceStore interface {
+	GetUserByID(ctx context.Context, id string) (model.User, error)
+	GetWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID string) (model.WorkspaceMember, error)
+}
+
+// SetWorkspace makes m's workspace the active one. Changes made with the
+// returned context are attributed to m's user in the audit log.
+func SetWorkspace(ctx context.Context, m model.WorkspaceMember) context.Context {
+	ctx = store.WithActor(ctx, m.UserID)
+	ctx = context.WithValue(ctx, workspaceIDKey, m.WorkspaceID.String())
+	return context.WithValue(ctx, workspaceRoleKey, m.Role)
+}
+
+// GetWorkspaceID returns the active workspace, which owns the data the
+// request reads and writes.
+func GetWorkspaceID(ctx context.Context) string {
+	if id, ok := ctx.Value(workspaceIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// GetWorkspaceRole returns the user's role in the active workspace.
+func GetWorkspaceRole(ctx context.Context) string {
+	if role, ok := ctx.Value(workspaceRoleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+// Workspace resolves the active workspace of an authenticated request. Users
+// must be members of it, and viewers may only make safe requests.
+func Workspace(store WorkspaceStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := GetUserID(r.Context())
+
+			var wsID uuid.UUID
+			if header := r.Header.Get(WorkspaceHeader); header != "" {
+				id, err := uuid.Parse(header)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "invalid workspace id")
+					return
+				}
+				wsID = id
+			} else {
+				user, err := store.GetUserByID(r.Context(), userID)
+				if err != nil {
+					writeUnauthorized(w)
+					return
+				}
+				wsID = user.PersonalWorkspaceID
+			}
+
+			m, err := store.GetWorkspaceMember(r.Context(), wsID, userID)
+			if err != nil {
+				writeError(w, http.StatusForbidden, "not a member of this workspace")
+				return
+			}
+			if !m.CanWrite() && r.Method != "GET" && r.Method != "HEAD" {
+				writeError(w, http.StatusForbidden, "read-only access to this workspace")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(SetWorkspace(r.Context(), m)))
+		})
+	}
+}
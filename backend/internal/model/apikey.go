@@ -0,0 +1,69 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	APIKeyAccessRead  = "read"
+	APIKeyAccessWrite = "write"
+)
+
+// APIKeyModules lists the modules an API key can be limited to.
+var APIKeyModules = []string{"contracts", "purchases", "vehicles"}
+
+// APIKey lets scripts call the API without a password. Only the SHA-256 hash
+// of the key is stored; Prefix is kept so users can tell their keys apart.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Access     string     `json:"access"`
+	Modules    []string   `json:"modules"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// Expired reports whether the key has passed its expiry date.
+func (k APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Allows reports whether the key's scope covers a request with the given
+// method to module. Read-only keys may only make safe requests. A key
+// limited to modules cannot reach account-wide routes, passed as module "".
+func (k APIKey) Allows(method, module string) bool {
+	if k.Access != APIKeyAccessWrite && method != "GET" && method != "HEAD" {
+		return false
+	}
+	return len(k.Modules) == 0 || slices.Contains(k.Modules, module)
+}
+
+type APIKeyInput struct {
+	Name      string     `json:"name"`
+	Access    string     `json:"access"`
+	Modules   []string   `json:"modules,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (in *APIKeyInput) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	if in.Access != APIKeyAccessRead && in.Access != APIKeyAccessWrite {
+		return errors.New("access must be read or write")
+	}
+	for _, m := range in.Modules {
+		if !slices.Contains(APIKeyModules, m) {
+			return errors.New("unknown module: " + m)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions. Moving a record to the trash and back are updates of its
+// deletedAt field, recorded as trash and restore; delete means it was purged.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditTrash   = "trash"
+	AuditRestore = "restore"
+)
+
+// Entity types recorded in the audit log.
+const (
+	EntityCategory   = "category"
+	EntityContract   = "contract"
+	EntityPriceEntry = "priceEntry"
+	EntityPurchase   = "purchase"
+	EntityVehicle    = "vehicle"
+	EntityCostEntry  = "costEntry"
+)
+
+// AuditEntry records one change to a workspace's data. Entries are never
+// changed; reverting adds a new one. IDs are time-ordered and identify the
+// revision an entity had after the change.
+type AuditEntry struct {
+	ID         uuid.UUID `json:"id"`
+	ActorID    string    `json:"actorId"`
+	At         time.Time `json:"at"`
+	Action     string    `json:"action"`
+	EntityType string    `json:"entityType"`
+	EntityID   uuid.UUID `json:"entityId"`
+	// Module is the module a category belongs to; empty for other entities.
+	Module  string        `json:"module,omitempty"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is the JSON value of one field before and after a change. A
+// null value means the field was not set.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// auditIgnoredFields change on every update and would only add noise.
+var auditIgnoredFields = map[string]bool{"updatedAt": true}
+
+// Diff compares two JSON objects field by field. A nil object stands for an
+// entity that does not exist, so creating one lists all its fields.
+func Diff(before, after []byte) ([]FieldChange, error) {
+	b, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []FieldChange{}
+	for field, av := range a {
+		if bv, ok := b[field]; (!ok || !bytes.Equal(bv, av)) && !auditIgnoredFields[field] {
+			changes = append(changes, FieldChange{Field: field, Before: b[field], After: av})
+		}
+	}
+	for field, bv := range b {
+		if _, ok := a[field]; !ok && !auditIgnoredFields[field] {
+			changes = append(changes, FieldChange{Field: field, Before: bv})
+		}
+	}
+	slices.SortFunc(changes, func(x, y FieldChange) int {
+		return cmp.Compare(x.Field, y.Field)
+	})
+	return changes, nil
+}
+
+// Undo rolls the JSON of an entity back over entries, which are given
+// oldest first, by restoring the before value of each change. current is
+// nil if the entity no longer exists. Because only changed fields are
+// recorded, this also works for entities older than their first entry.
+func Undo(current []byte, entries []AuditEntry) ([]byte, error) {
+	state, err := jsonFields(current)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		for _, c := range entries[i].Changes {
+			if c.Before == nil || string(c.Before) == "null" {
+				delete(state, c.Field)
+			} else {
+				state[c.Field] = c.Before
+			}
+		}
+	}
+	return json.Marshal(state)
+}
+
+func jsonFields(data []byte) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if data == nil {
+		return fields, nil
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := []byte(`{"name":"Phone","company":"Telco","price":10,"updatedAt":"2025-01-01T00:00:00Z"}`)
+	after := []byte(`{"name":"Phone","price":12,"notes":"new","updatedAt":"2025-02-01T00:00:00Z"}`)
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	want := []FieldChange{
+		{Field: "company", Before: json.RawMessage(`"Telco"`)},
+		{Field: "notes", After: json.RawMessage(`"new"`)},
+		{Field: "price", Before: json.RawMessage(`10`), After: json.RawMessage(`12`)},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	for i, c := range changes {
+		if c.Field != want[i].Field || string(c.Before) != string(want[i].Before) || string(c.After) != string(want[i].After) {
+			t.Errorf("changes[%d] = %+v, want %+v", i, c, want[i])
+		}
+	}
+}
+
+func TestUndo(t *testing.T) {
+	v1 := []byte(`{"name":"Phone","price":10}`)
+	v2 := []byte(`{"name":"Phone XL","price":10,"notes":"upgraded"}`)
+	create, _ := Diff(nil, v1)
+	update, _ := Diff(v1, v2)
+	remove, _ := Diff(v2, nil)
+	entries := []AuditEntry{{Changes: create}, {Changes: update}, {Changes: remove}}
+
+	tests := []struct {
+		name    string
+		current []byte
+		undo    []AuditEntry
+		want    string
+	}{
+		{"nothing", v2, nil, `{"name":"Phone XL","notes":"upgraded","price":10}`},
+		{"update", v2, entries[1:2], `{"name":"Phone","price":10}`},
+		{"deleted", nil, entries[2:], `{"name":"Phone XL","notes":"upgraded","price":10}`},
+		{"deleted to first", nil, entries[1:], `{"name":"Phone","price":10}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Undo(tt.current, tt.undo)
+			if err != nil {
+				t.Fatalf("Undo: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Undo = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,12 +7,17 @@ import (
 	"github.com/google/uuid"
 )
 
+// Modules lists the modules that own categories.
+var Modules = []string{"contracts", "purchases"}
+
 type Category struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
 	NameKey   string    `json:"nameKey,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	// DeletedAt is set while the category is in the trash.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
 
 type CategoryInput struct {
@@ -1,7 +1,9 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,52 +12,192 @@ import (
 type BillingInterval string
 
 const (
-	BillingMonthly BillingInterval = "monthly"
-	BillingYearly  BillingInterval = "yearly"
+	BillingWeekly     BillingInterval = "weekly"
+	BillingMonthly    BillingInterval = "monthly"
+	BillingQuarterly  BillingInterval = "quarterly"
+	BillingHalfYearly BillingInterval = "half-yearly"
+	BillingYearly     BillingInterval = "yearly"
+	BillingBiennial   BillingInterval = "biennial"
+	BillingOneTime    BillingInterval = "one-time"
 )
 
+// BillingIntervals lists all supported intervals in ascending length.
+var BillingIntervals = []BillingInterval{
+	BillingWeekly,
+	BillingMonthly,
+	BillingQuarterly,
+	BillingHalfYearly,
+	BillingYearly,
+	BillingBiennial,
+	BillingOneTime,
+}
+
+// billingIntervalAliases maps common alternative spellings (lowercase) to
+// their canonical interval. Used when importing data from other tools.
+var billingIntervalAliases = map[string]BillingInterval{
+	"week":          BillingWeekly,
+	"month":         BillingMonthly,
+	"quarter":       BillingQuarterly,
+	"halfyearly":    BillingHalfYearly,
+	"half_yearly":   BillingHalfYearly,
+	"semiannual":    BillingHalfYearly,
+	"semi-annual":   BillingHalfYearly,
+	"semiannually":  BillingHalfYearly,
+	"semi-annually": BillingHalfYearly,
+	"year":          BillingYearly,
+	"annual":        BillingYearly,
+	"annually":      BillingYearly,
+	"biennially":    BillingBiennial,
+	"biannual":      BillingBiennial,
+	"two-yearly":    BillingBiennial,
+	"onetime":       BillingOneTime,
+	"one_time":      BillingOneTime,
+	"once":          BillingOneTime,
+}
+
+// Valid reports whether b is one of the supported intervals.
+func (b BillingInterval) Valid() bool {
+	for _, v := range BillingIntervals {
+		if b == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseBillingInterval normalizes s to a supported interval, accepting any
+// casing and the aliases in billingIntervalAliases. An empty string yields
+// BillingMonthly.
+func ParseBillingInterval(s string) (BillingInterval, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return BillingMonthly, nil
+	}
+	if b := BillingInterval(s); b.Valid() {
+		return b, nil
+	}
+	if b, ok := billingIntervalAliases[s]; ok {
+		return b, nil
+	}
+	return "", errors.New("billingInterval must be one of " + billingIntervalList())
+}
+
+func billingIntervalList() string {
+	names := make([]string, len(BillingIntervals))
+	for i, b := range BillingIntervals {
+		names[i] = "'" + string(b) + "'"
+	}
+	return strings.Join(names, ", ")
+}
+
 type Contract struct {
-	ID                      uuid.UUID       `json:"id"`
-	CategoryID              uuid.UUID       `json:"categoryId"`
-	Name                    string          `json:"name"`
-	ProductName             string          `json:"productName,omitempty"`
-	Company                 string          `json:"company,omitempty"`
-	ContractNumber          string          `json:"contractNumber,omitempty"`
-	CustomerNumber          string          `json:"customerNumber,omitempty"`
-	Price                   *float64        `json:"price,omitempty"`
-	BillingInterval         BillingInterval `json:"billingInterval"`
-	StartDate               string          `json:"startDate"`
-	EndDate                 string          `json:"endDate,omitempty"`
-	MinimumDurationMonths   int             `json:"minimumDurationMonths"`
-	ExtensionDurationMonths int             `json:"extensionDurationMonths"`
-	NoticePeriodMonths      int             `json:"noticePeriodMonths"`
-	CustomerPortalURL       string          `json:"customerPortalUrl,omitempty"`
-	PaperlessURL            string          `json:"paperlessUrl,omitempty"`
-	Comments                string          `json:"comments,omitempty"`
-	CreatedAt               time.Time       `json:"createdAt"`
-	UpdatedAt               time.Time       `json:"updatedAt"`
+	ID                        uuid.UUID       `json:"id"`
+	CategoryID                uuid.UUID       `json:"categoryId"`
+	Name                      string          `json:"name"`
+	ProductName               string          `json:"productName,omitempty"`
+	Company                   string          `json:"company,omitempty"`
+	ContractNumber            string          `json:"contractNumber,omitempty"`
+	CustomerNumber            string          `json:"customerNumber,omitempty"`
+	Price                     *float64        `json:"price,omitempty"`
+	BillingInterval           BillingInterval `json:"billingInterval"`
+	StartDate                 string          `json:"startDate"`
+	EndDate                   string          `json:"endDate,omitempty"`
+	MinimumDuration           int             `json:"minimumDuration"`
+	MinimumDurationUnit       DurationUnit    `json:"minimumDurationUnit,omitempty"`
+	MinimumDurationAnchor     DurationAnchor  `json:"minimumDurationAnchor,omitempty"`
+	ExtensionDuration         int             `json:"extensionDuration"`
+	ExtensionDurationUnit     DurationUnit    `json:"extensionDurationUnit,omitempty"`
+	ExtensionDurationAnchor   DurationAnchor  `json:"extensionDurationAnchor,omitempty"`
+	NoticePeriod              int             `json:"noticePeriod"`
+	NoticePeriodUnit          DurationUnit    `json:"noticePeriodUnit,omitempty"`
+	NoticePeriodAnchor        DurationAnchor  `json:"noticePeriodAnchor,omitempty"`
+	CustomerPortalURL         string          `json:"customerPortalUrl,omitempty"`
+	PaperlessURL              string          `json:"paperlessUrl,omitempty"`
+	Comments                  string          `json:"comments,omitempty"`
+	Status                    ContractStatus  `json:"status"`
+	CancellationSentAt        *time.Time      `json:"cancellationSentAt,omitempty"`
+	CancellationConfirmedAt   *time.Time      `json:"cancellationConfirmedAt,omitempty"`
+	TerminatedAt              *time.Time      `json:"terminatedAt,omitempty"`
+	CancellationEffectiveDate string          `json:"cancellationEffectiveDate,omitempty"`
+	CancellationReference     string          `json:"cancellationReference,omitempty"`
+	CreatedAt                 time.Time       `json:"createdAt"`
+	UpdatedAt                 time.Time       `json:"updatedAt"`
+	DeletedAt                 *time.Time      `json:"deletedAt,omitempty"` // set while the contract is in the trash
 }
 
-// MonthlyPrice returns the price normalized to a monthly amount.
-func (c *Contract) MonthlyPrice() float64 {
-	if c.Price == nil {
+// PriceOn returns the price in effect on the given date. Without a price
+// history the contract's own Price applies; otherwise the latest entry whose
+// EffectiveDate is on or before date wins. Dates before the first entry have
+// no known price.
+func (c *Contract) PriceOn(date time.Time, history []PriceEntry) *float64 {
+	if len(history) == 0 {
+		return c.Price
+	}
+	day := date.Format(dateFormat)
+	var current *PriceEntry
+	for i := range history {
+		e := &history[i]
+		if e.EffectiveDate > day {
+			continue
+		}
+		if current == nil || e.EffectiveDate > current.EffectiveDate ||
+			(e.EffectiveDate == current.EffectiveDate && e.CreatedAt.After(current.CreatedAt)) {
+			current = e
+		}
+	}
+	if current == nil {
+		return nil
+	}
+	price := current.Price
+	return &price
+}
+
+// MonthlyPrice returns the price in effect on date normalized to a monthly
+// amount. One-time payments are not recurring and count as zero.
+func (c *Contract) MonthlyPrice(date time.Time, history []PriceEntry) float64 {
+	price := c.PriceOn(date, history)
+	if price == nil {
 		return 0
 	}
-	if c.BillingInterval == BillingYearly {
-		return *c.Price / 12
+	switch c.BillingInterval {
+	case BillingWeekly:
+		return *price * 52 / 12
+	case BillingQuarterly:
+		return *price / 3
+	case BillingHalfYearly:
+		return *price / 6
+	case BillingYearly:
+		return *price / 12
+	case BillingBiennial:
+		return *price / 24
+	case BillingOneTime:
+		return 0
 	}
-	return *c.Price
+	return *price
 }
 
-// YearlyPrice returns the price normalized to a yearly amount.
-func (c *Contract) YearlyPrice() float64 {
-	if c.Price == nil {
+// YearlyPrice returns the price in effect on date normalized to a yearly
+// amount. One-time payments are not recurring and count as zero.
+func (c *Contract) YearlyPrice(date time.Time, history []PriceEntry) float64 {
+	price := c.PriceOn(date, history)
+	if price == nil {
 		return 0
 	}
-	if c.BillingInterval == BillingYearly {
-		return *c.Price
+	switch c.BillingInterval {
+	case BillingWeekly:
+		return *price * 52
+	case BillingQuarterly:
+		return *price * 4
+	case BillingHalfYearly:
+		return *price * 2
+	case BillingYearly:
+		return *price
+	case BillingBiennial:
+		return *price / 2
+	case BillingOneTime:
+		return 0
 	}
-	return *c.Price * 12
+	return *price * 12
 }
 
 type ContractInput struct {
@@ -68,14 +210,51 @@ type ContractInput struct {
 	BillingInterval         BillingInterval `json:"billingInterval"`
 	StartDate               string          `json:"startDate"`
 	EndDate                 string          `json:"endDate,omitempty"`
-	MinimumDurationMonths   int             `json:"minimumDurationMonths"`
-	ExtensionDurationMonths int             `json:"extensionDurationMonths"`
-	NoticePeriodMonths      int             `json:"noticePeriodMonths"`
+	MinimumDuration         int             `json:"minimumDuration"`
+	MinimumDurationUnit     DurationUnit    `json:"minimumDurationUnit,omitempty"`
+	MinimumDurationAnchor   DurationAnchor  `json:"minimumDurationAnchor,omitempty"`
+	ExtensionDuration       int             `json:"extensionDuration"`
+	ExtensionDurationUnit   DurationUnit    `json:"extensionDurationUnit,omitempty"`
+	ExtensionDurationAnchor DurationAnchor  `json:"extensionDurationAnchor,omitempty"`
+	NoticePeriod            int             `json:"noticePeriod"`
+	NoticePeriodUnit        DurationUnit    `json:"noticePeriodUnit,omitempty"`
+	NoticePeriodAnchor      DurationAnchor  `json:"noticePeriodAnchor,omitempty"`
 	CustomerPortalURL       string          `json:"customerPortalUrl,omitempty"`
 	PaperlessURL            string          `json:"paperlessUrl,omitempty"`
 	Comments                string          `json:"comments,omitempty"`
 }
 
+// UnmarshalJSON also accepts the month-named fields the duration amounts had
+// before they could be given in days and weeks. A new name wins over its old
+// one.
+func (c *ContractInput) UnmarshalJSON(data []byte) error {
+	type plain ContractInput
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	var legacy struct {
+		MinimumDuration         *int `json:"minimumDuration"`
+		MinimumDurationMonths   *int `json:"minimumDurationMonths"`
+		ExtensionDuration       *int `json:"extensionDuration"`
+		ExtensionDurationMonths *int `json:"extensionDurationMonths"`
+		NoticePeriod            *int `json:"noticePeriod"`
+		NoticePeriodMonths      *int `json:"noticePeriodMonths"`
+	}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	if legacy.MinimumDuration == nil && legacy.MinimumDurationMonths != nil {
+		c.MinimumDuration = *legacy.MinimumDurationMonths
+	}
+	if legacy.ExtensionDuration == nil && legacy.ExtensionDurationMonths != nil {
+		c.ExtensionDuration = *legacy.ExtensionDurationMonths
+	}
+	if legacy.NoticePeriod == nil && legacy.NoticePeriodMonths != nil {
+		c.NoticePeriod = *legacy.NoticePeriodMonths
+	}
+	return nil
+}
+
 func (c *ContractInput) Validate() error {
 	if c.Name == "" {
 		return errors.New("name is required")
@@ -83,8 +262,53 @@ func (c *ContractInput) Validate() error {
 	if c.StartDate == "" {
 		return errors.New("startDate is required")
 	}
-	if c.BillingInterval != "" && c.BillingInterval != BillingMonthly && c.BillingInterval != BillingYearly {
-		return errors.New("billingInterval must be 'monthly' or 'yearly'")
+	if c.BillingInterval != "" && !c.BillingInterval.Valid() {
+		return errors.New("billingInterval must be one of " + billingIntervalList())
+	}
+	if c.MinimumDuration < 0 || c.ExtensionDuration < 0 || c.NoticePeriod < 0 {
+		return errors.New("durations must not be negative")
+	}
+	for _, u := range []DurationUnit{c.MinimumDurationUnit, c.ExtensionDurationUnit, c.NoticePeriodUnit} {
+		if !u.Valid() {
+			return errors.New("duration units must be 'days', 'weeks' or 'months'")
+		}
+	}
+	for _, a := range []DurationAnchor{c.MinimumDurationAnchor, c.ExtensionDurationAnchor, c.NoticePeriodAnchor} {
+		if !a.Valid() {
+			return errors.New("duration anchors must be 'end_of_month', 'end_of_quarter' or 'end_of_year'")
+		}
+	}
+	return nil
+}
+
+// PriceEntry records the price of a contract from EffectiveDate onwards.
+type PriceEntry struct {
+	ID            uuid.UUID `json:"id"`
+	ContractID    uuid.UUID `json:"contractId"`
+	Price         float64   `json:"price"`
+	EffectiveDate string    `json:"effectiveDate"`
+	Comments      string    `json:"comments,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type PriceEntryInput struct {
+	Price         *float64 `json:"price"`
+	EffectiveDate string   `json:"effectiveDate"`
+	Comments      string   `json:"comments,omitempty"`
+}
+
+func (p *PriceEntryInput) Validate() error {
+	if p.Price == nil {
+		return errors.New("price is required")
+	}
+	if *p.Price < 0 {
+		return errors.New("price must not be negative")
+	}
+	if p.EffectiveDate == "" {
+		return errors.New("effectiveDate is required")
+	}
+	if _, err := time.Parse(dateFormat, p.EffectiveDate); err != nil {
+		return errors.New("effectiveDate must be in format YYYY-MM-DD")
 	}
 	return nil
 }
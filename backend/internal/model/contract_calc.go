@@ -4,7 +4,173 @@ import "time"
 
 const dateFormat = "2006-01-02"
 
+// DurationUnit is the unit of a contract duration or notice period. The
+// amounts stay in the *Months fields, whose names predate units.
+type DurationUnit string
+
+const (
+	UnitDays   DurationUnit = "days"
+	UnitWeeks  DurationUnit = "weeks"
+	UnitMonths DurationUnit = "months"
+)
+
+// Valid reports whether u is a known unit. The empty unit means months.
+func (u DurationUnit) Valid() bool {
+	switch u {
+	case "", UnitDays, UnitWeeks, UnitMonths:
+		return true
+	}
+	return false
+}
+
+// DurationAnchor rounds the end of a duration up to the end of a calendar
+// period, as in "3 months to the end of the quarter".
+type DurationAnchor string
+
+const (
+	AnchorNone         DurationAnchor = ""
+	AnchorEndOfMonth   DurationAnchor = "end_of_month"
+	AnchorEndOfQuarter DurationAnchor = "end_of_quarter"
+	AnchorEndOfYear    DurationAnchor = "end_of_year"
+)
+
+// Valid reports whether a is a known anchor. The empty anchor means none.
+func (a DurationAnchor) Valid() bool {
+	switch a {
+	case AnchorNone, AnchorEndOfMonth, AnchorEndOfQuarter, AnchorEndOfYear:
+		return true
+	}
+	return false
+}
+
+// duration is an amount in a unit with an optional anchor.
+type duration struct {
+	amount int
+	unit   DurationUnit
+	anchor DurationAnchor
+}
+
+func (c Contract) minimumDuration() duration {
+	return duration{c.MinimumDuration, c.MinimumDurationUnit, c.MinimumDurationAnchor}
+}
+
+func (c Contract) extensionDuration() duration {
+	return duration{c.ExtensionDuration, c.ExtensionDurationUnit, c.ExtensionDurationAnchor}
+}
+
+func (c Contract) noticePeriod() duration {
+	return duration{c.NoticePeriod, c.NoticePeriodUnit, c.NoticePeriodAnchor}
+}
+
+// after returns the end of a term of d starting at t, anchored if requested.
+// Term ends are exclusive: the returned day is the first day after the term.
+func (d duration) after(t time.Time) time.Time {
+	switch d.unit {
+	case UnitDays:
+		t = t.AddDate(0, 0, d.amount)
+	case UnitWeeks:
+		t = t.AddDate(0, 0, 7*d.amount)
+	default:
+		t = addMonths(t, d.amount)
+	}
+	return d.anchor.ceil(t)
+}
+
+// before returns the day the notice period d has to start at to end at t.
+func (d duration) before(t time.Time) time.Time {
+	switch d.unit {
+	case UnitDays:
+		return t.AddDate(0, 0, -d.amount)
+	case UnitWeeks:
+		return t.AddDate(0, 0, -7*d.amount)
+	default:
+		return subMonths(t, d.amount)
+	}
+}
+
+// ceil moves an exclusive term end t forward to the next period boundary, so
+// the term ends on the last day of a month, quarter or year. t is returned
+// unchanged if it already is a boundary.
+func (a DurationAnchor) ceil(t time.Time) time.Time {
+	switch a {
+	case AnchorEndOfMonth:
+		if t.Day() == 1 {
+			return t
+		}
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	case AnchorEndOfQuarter:
+		if t.Day() == 1 && (t.Month()-1)%3 == 0 {
+			return t
+		}
+		next := (t.Month()-1)/3*3 + 4
+		return time.Date(t.Year(), next, 1, 0, 0, 0, 0, time.UTC)
+	case AnchorEndOfYear:
+		if t.Day() == 1 && t.Month() == time.January {
+			return t
+		}
+		return time.Date(t.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+// next returns the first period boundary strictly after t.
+func (a DurationAnchor) next(t time.Time) time.Time {
+	return a.ceil(t.AddDate(0, 0, 1))
+}
+
+// addMonths adds n months to a term start. When the day does not exist in
+// the target month (Jan 31 + 1 month), the term ends with that month and the
+// first day of the following month is returned.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	if t.Day() > daysIn(first) {
+		return first.AddDate(0, 1, 0)
+	}
+	return first.AddDate(0, 0, t.Day()-1)
+}
+
+// subMonths subtracts n months, clamping to the last day of the target month
+// (Mar 31 - 1 month = Feb 28).
+func subMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()-time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	day := min(t.Day(), daysIn(first))
+	return first.AddDate(0, 0, day-1)
+}
+
+func daysIn(first time.Time) int {
+	return first.AddDate(0, 1, -1).Day()
+}
+
+// firstTermEnd returns the end of the minimum duration.
+func (c Contract) firstTermEnd(start time.Time) time.Time {
+	return c.noticePeriod().anchor.ceil(c.minimumDuration().after(start))
+}
+
+// nextTermEnd returns the term end following b: after the next extension,
+// or at the next notice period anchor for contracts without an extension.
+// It reports false for contracts that run on indefinitely and can end on
+// any day.
+func (c Contract) nextTermEnd(b time.Time) (time.Time, bool) {
+	notice := c.noticePeriod()
+	if ext := c.extensionDuration(); ext.amount > 0 {
+		return notice.anchor.ceil(ext.after(b)), true
+	}
+	if notice.anchor != AnchorNone {
+		return notice.anchor.next(b), true
+	}
+	return time.Time{}, false
+}
+
 func (c Contract) CancellationDate() *string {
+	return c.CancellationDateAsOf(time.Now().UTC())
+}
+
+// CancellationDateAsOf returns the cancellation deadline for the contract's
+// next possible end, as seen from the reference date ref. The contract can
+// end after the minimum duration and then after every extension. Contracts
+// without an extension run on indefinitely and can end at any time, or at
+// each notice period anchor if one is set.
+func (c Contract) CancellationDateAsOf(ref time.Time) *string {
 	if c.EndDate != "" {
 		return nil
 	}
@@ -14,32 +180,69 @@ func (c Contract) CancellationDate() *string {
 		return nil
 	}
 
-	today := time.Now().UTC().Truncate(24 * time.Hour)
-	minEnd := start.AddDate(0, c.MinimumDurationMonths, 0)
-
-	if c.ExtensionDurationMonths == 0 {
-		cancellation := minEnd.AddDate(0, -c.NoticePeriodMonths, 0)
-		if cancellation.Before(today) {
+	today := ref.Truncate(24 * time.Hour)
+	notice := c.noticePeriod()
+	periodEnd := c.firstTermEnd(start)
+	for notice.before(periodEnd).Before(today) {
+		next, ok := c.nextTermEnd(periodEnd)
+		if !ok {
 			return datePtr(today)
 		}
-		return datePtr(cancellation)
+		periodEnd = next
 	}
 
-	periodEnd := minEnd
-	for periodEnd.Before(today) {
-		periodEnd = periodEnd.AddDate(0, c.ExtensionDurationMonths, 0)
-	}
+	return datePtr(notice.before(periodEnd))
+}
 
-	cancellation := periodEnd.AddDate(0, -c.NoticePeriodMonths, 0)
-	if cancellation.Before(today) {
-		periodEnd = periodEnd.AddDate(0, c.ExtensionDurationMonths, 0)
-		cancellation = periodEnd.AddDate(0, -c.NoticePeriodMonths, 0)
+// TermBoundary is a possible end of a contract term.
+type TermBoundary struct {
+	// TermEnd is the last day of the term.
+	TermEnd string `json:"termEnd"`
+	// CancellationDate is the deadline for ending the contract at TermEnd.
+	CancellationDate string `json:"cancellationDate"`
+	// Past reports whether TermEnd is before the reference date.
+	Past bool `json:"past"`
+}
+
+// Timeline lists every term boundary of the contract from its start up to
+// until, relative to the reference date ref. A contract with an EndDate has
+// no boundaries after it.
+func (c Contract) Timeline(ref, until time.Time) []TermBoundary {
+	out := []TermBoundary{}
+	start, err := time.Parse(dateFormat, c.StartDate)
+	if err != nil {
+		return out
+	}
+	var end time.Time
+	if c.EndDate != "" {
+		if end, err = time.Parse(dateFormat, c.EndDate); err != nil {
+			return out
+		}
 	}
 
-	return datePtr(cancellation)
+	today := ref.Truncate(24 * time.Hour)
+	notice := c.noticePeriod()
+	for b, ok := c.firstTermEnd(start), true; ok; b, ok = c.nextTermEnd(b) {
+		termEnd := b.AddDate(0, 0, -1)
+		if termEnd.After(until) || (!end.IsZero() && termEnd.After(end)) {
+			break
+		}
+		out = append(out, TermBoundary{
+			TermEnd:          termEnd.Format(dateFormat),
+			CancellationDate: notice.before(b).Format(dateFormat),
+			Past:             termEnd.Before(today),
+		})
+	}
+	return out
 }
 
 func (c Contract) IsExpired() bool {
+	return c.IsExpiredAsOf(time.Now().UTC())
+}
+
+// IsExpiredAsOf reports whether the contract's EndDate is before the
+// reference date ref.
+func (c Contract) IsExpiredAsOf(ref time.Time) bool {
 	if c.EndDate == "" {
 		return false
 	}
@@ -47,8 +250,7 @@ func (c Contract) IsExpired() bool {
 	if err != nil {
 		return false
 	}
-	today := time.Now().UTC().Truncate(24 * time.Hour)
-	return end.Before(today)
+	return end.Before(ref.Truncate(24 * time.Hour))
 }
 
 func datePtr(t time.Time) *string {
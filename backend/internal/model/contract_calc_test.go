@@ -1,6 +1,8 @@
 package model
 
 import (
+	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -28,10 +30,10 @@ func TestCancellationDate_NoExtension(t *testing.T) {
 	// Start 6 months ago, 12 month minimum, 3 month notice, no extension
 	// minEnd = 6 months from now, cancellation = 3 months from now
 	c := Contract{
-		StartDate:               monthsAgo(6),
-		MinimumDurationMonths:   12,
-		NoticePeriodMonths:      3,
-		ExtensionDurationMonths: 0,
+		StartDate:         monthsAgo(6),
+		MinimumDuration:   12,
+		NoticePeriod:      3,
+		ExtensionDuration: 0,
 	}
 	got := c.CancellationDate()
 	if got == nil {
@@ -47,10 +49,10 @@ func TestCancellationDate_NoExtension_PastMinEnd(t *testing.T) {
 	// Start 24 months ago, 12 month minimum, 3 month notice, no extension
 	// minEnd = 12 months ago, cancellation = 15 months ago (past) → clamp to today
 	c := Contract{
-		StartDate:               monthsAgo(24),
-		MinimumDurationMonths:   12,
-		NoticePeriodMonths:      3,
-		ExtensionDurationMonths: 0,
+		StartDate:         monthsAgo(24),
+		MinimumDuration:   12,
+		NoticePeriod:      3,
+		ExtensionDuration: 0,
 	}
 	got := c.CancellationDate()
 	if got == nil {
@@ -66,10 +68,10 @@ func TestCancellationDate_WithExtension_FutureMinEnd(t *testing.T) {
 	// minEnd = 18 months from now (future), periodEnd = minEnd
 	// cancellation = 15 months from now
 	c := Contract{
-		StartDate:               monthsAgo(6),
-		MinimumDurationMonths:   24,
-		NoticePeriodMonths:      3,
-		ExtensionDurationMonths: 12,
+		StartDate:         monthsAgo(6),
+		MinimumDuration:   24,
+		NoticePeriod:      3,
+		ExtensionDuration: 12,
 	}
 	got := c.CancellationDate()
 	if got == nil {
@@ -87,10 +89,10 @@ func TestCancellationDate_WithExtension_PastMinEnd(t *testing.T) {
 	// periodEnd advances: -18, -6, +6 (first future)
 	// cancellation = +6 - 3 = +3
 	c := Contract{
-		StartDate:               monthsAgo(30),
-		MinimumDurationMonths:   12,
-		NoticePeriodMonths:      3,
-		ExtensionDurationMonths: 12,
+		StartDate:         monthsAgo(30),
+		MinimumDuration:   12,
+		NoticePeriod:      3,
+		ExtensionDuration: 12,
 	}
 	got := c.CancellationDate()
 	if got == nil {
@@ -108,10 +110,10 @@ func TestCancellationDate_WithExtension_NoticePastToday(t *testing.T) {
 	// periodEnd advances: -10, +2 (first future)
 	// cancellation = +2 - 3 = -1 (past!) → advance one more: +14 - 3 = +11
 	c := Contract{
-		StartDate:               monthsAgo(22),
-		MinimumDurationMonths:   12,
-		NoticePeriodMonths:      3,
-		ExtensionDurationMonths: 12,
+		StartDate:         monthsAgo(22),
+		MinimumDuration:   12,
+		NoticePeriod:      3,
+		ExtensionDuration: 12,
 	}
 	got := c.CancellationDate()
 	if got == nil {
@@ -157,3 +159,312 @@ func TestIsExpired_InvalidEndDate(t *testing.T) {
 		t.Error("contract with invalid endDate should not be expired")
 	}
 }
+
+func TestBillingInterval_NormalizedPrices(t *testing.T) {
+	date := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		interval    BillingInterval
+		price       float64
+		wantMonthly float64
+		wantYearly  float64
+	}{
+		{BillingWeekly, 12, 52, 624},
+		{BillingMonthly, 10, 10, 120},
+		{"", 10, 10, 120},
+		{BillingQuarterly, 30, 10, 120},
+		{BillingHalfYearly, 60, 10, 120},
+		{BillingYearly, 120, 10, 120},
+		{BillingBiennial, 240, 10, 120},
+		{BillingOneTime, 500, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.interval), func(t *testing.T) {
+			price := tt.price
+			c := Contract{BillingInterval: tt.interval, Price: &price}
+			if got := c.MonthlyPrice(date, nil); math.Abs(got-tt.wantMonthly) > 1e-9 {
+				t.Errorf("MonthlyPrice() = %v, want %v", got, tt.wantMonthly)
+			}
+			if got := c.YearlyPrice(date, nil); math.Abs(got-tt.wantYearly) > 1e-9 {
+				t.Errorf("YearlyPrice() = %v, want %v", got, tt.wantYearly)
+			}
+		})
+	}
+}
+
+func TestParseBillingInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    BillingInterval
+		wantErr bool
+	}{
+		{"", BillingMonthly, false},
+		{"weekly", BillingWeekly, false},
+		{"Monthly", BillingMonthly, false},
+		{" QUARTERLY ", BillingQuarterly, false},
+		{"half-yearly", BillingHalfYearly, false},
+		{"semi-annual", BillingHalfYearly, false},
+		{"annually", BillingYearly, false},
+		{"biennial", BillingBiennial, false},
+		{"one-time", BillingOneTime, false},
+		{"once", BillingOneTime, false},
+		{"daily", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseBillingInterval(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBillingInterval(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseBillingInterval(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCancellationDate_LegalPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		contract Contract
+		today    string
+		want     string
+	}{
+		{
+			name: "3 months to end of quarter, open-ended",
+			contract: Contract{
+				StartDate:          "2023-05-10",
+				NoticePeriod:       3,
+				NoticePeriodAnchor: AnchorEndOfQuarter,
+			},
+			today: "2025-02-15",
+			want:  "2025-04-01", // ends 2025-06-30
+		},
+		{
+			name: "4 weeks to end of month, open-ended",
+			contract: Contract{
+				StartDate:          "2024-03-15",
+				NoticePeriod:       4,
+				NoticePeriodUnit:   UnitWeeks,
+				NoticePeriodAnchor: AnchorEndOfMonth,
+			},
+			today: "2025-02-10",
+			want:  "2025-03-04", // ends 2025-03-31
+		},
+		{
+			name: "14 days before monthly renewal",
+			contract: Contract{
+				StartDate:         "2024-01-01",
+				MinimumDuration:   12,
+				ExtensionDuration: 1,
+				NoticePeriod:      14,
+				NoticePeriodUnit:  UnitDays,
+			},
+			today: "2025-03-20",
+			want:  "2025-04-17", // renews 2025-05-01
+		},
+		{
+			name: "24 months minimum, then 1 month notice monthly",
+			contract: Contract{
+				StartDate:         "2023-01-15",
+				MinimumDuration:   24,
+				ExtensionDuration: 1,
+				NoticePeriod:      1,
+			},
+			today: "2025-02-20",
+			want:  "2025-03-15",
+		},
+		{
+			name: "insurance year ending with the calendar year",
+			contract: Contract{
+				StartDate:             "2023-04-01",
+				MinimumDuration:       12,
+				MinimumDurationAnchor: AnchorEndOfYear,
+				ExtensionDuration:     12,
+				NoticePeriod:          3,
+			},
+			today: "2024-11-01",
+			want:  "2025-10-01", // 2024 deadline passed, next end 2025-12-31
+		},
+		{
+			name: "minimum duration in days",
+			contract: Contract{
+				StartDate:           "2025-01-01",
+				MinimumDuration:     30,
+				MinimumDurationUnit: UnitDays,
+				NoticePeriod:        7,
+				NoticePeriodUnit:    UnitDays,
+			},
+			today: "2025-01-10",
+			want:  "2025-01-24",
+		},
+		{
+			name: "month addition past the end of a short month",
+			contract: Contract{
+				StartDate:       "2025-01-31",
+				MinimumDuration: 1,
+			},
+			today: "2025-02-01",
+			want:  "2025-03-01", // term ends 2025-02-28
+		},
+		{
+			name: "notice subtraction clamps to month end",
+			contract: Contract{
+				StartDate:       "2024-03-31",
+				MinimumDuration: 12,
+				NoticePeriod:    1,
+			},
+			today: "2025-01-01",
+			want:  "2025-02-28",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			today, err := time.Parse(dateFormat, tt.today)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tt.contract.CancellationDateAsOf(today)
+			if got == nil {
+				t.Fatal("expected non-nil cancellation date")
+			}
+			if *got != tt.want {
+				t.Errorf("got %s, want %s", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationAnchor_Ceil(t *testing.T) {
+	tests := []struct {
+		anchor DurationAnchor
+		in     string
+		want   string
+	}{
+		{AnchorNone, "2025-05-10", "2025-05-10"},
+		{AnchorEndOfMonth, "2025-05-01", "2025-05-01"},
+		{AnchorEndOfMonth, "2025-05-10", "2025-06-01"},
+		{AnchorEndOfMonth, "2025-12-31", "2026-01-01"},
+		{AnchorEndOfQuarter, "2025-04-01", "2025-04-01"},
+		{AnchorEndOfQuarter, "2025-05-01", "2025-07-01"},
+		{AnchorEndOfQuarter, "2025-11-15", "2026-01-01"},
+		{AnchorEndOfYear, "2025-01-01", "2025-01-01"},
+		{AnchorEndOfYear, "2025-01-02", "2026-01-01"},
+	}
+	for _, tt := range tests {
+		in, _ := time.Parse(dateFormat, tt.in)
+		if got := tt.anchor.ceil(in).Format(dateFormat); got != tt.want {
+			t.Errorf("%q.ceil(%s) = %s, want %s", tt.anchor, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestContractInput_ValidateDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   ContractInput
+		wantErr bool
+	}{
+		{"defaults", ContractInput{}, false},
+		{"weeks to end of month", ContractInput{NoticePeriod: 4, NoticePeriodUnit: UnitWeeks, NoticePeriodAnchor: AnchorEndOfMonth}, false},
+		{"unknown unit", ContractInput{NoticePeriodUnit: "years"}, true},
+		{"unknown anchor", ContractInput{MinimumDurationAnchor: "end_of_week"}, true},
+		{"negative amount", ContractInput{ExtensionDuration: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.Name = "X"
+			tt.input.StartDate = "2025-01-01"
+			if err := tt.input.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCancellationDateAsOf_ReferenceDate(t *testing.T) {
+	c := Contract{
+		StartDate:         "2024-01-01",
+		MinimumDuration:   24,
+		ExtensionDuration: 12,
+		NoticePeriod:      3,
+	}
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"2024-06-01", "2025-10-01"},
+		{"2025-10-01", "2025-10-01"},
+		{"2025-10-02", "2026-10-01"},
+		{"2027-03-01", "2027-10-01"},
+	}
+	for _, tt := range tests {
+		got := c.CancellationDateAsOf(mustDate(t, tt.ref))
+		if got == nil || *got != tt.want {
+			t.Errorf("CancellationDateAsOf(%s) = %v, want %s", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestIsExpiredAsOf(t *testing.T) {
+	c := Contract{StartDate: "2024-01-01", EndDate: "2025-06-30"}
+	if c.IsExpiredAsOf(mustDate(t, "2025-06-30")) {
+		t.Error("contract should not be expired on its end date")
+	}
+	if !c.IsExpiredAsOf(mustDate(t, "2025-07-01")) {
+		t.Error("contract should be expired after its end date")
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	c := Contract{
+		StartDate:         "2023-01-01",
+		MinimumDuration:   12,
+		ExtensionDuration: 12,
+		NoticePeriod:      3,
+	}
+	got := c.Timeline(mustDate(t, "2025-02-01"), mustDate(t, "2026-12-31"))
+	want := []TermBoundary{
+		{TermEnd: "2023-12-31", CancellationDate: "2023-10-01", Past: true},
+		{TermEnd: "2024-12-31", CancellationDate: "2024-10-01", Past: true},
+		{TermEnd: "2025-12-31", CancellationDate: "2025-10-01", Past: false},
+		{TermEnd: "2026-12-31", CancellationDate: "2026-10-01", Past: false},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d boundaries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("boundary %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTimeline_StopsAtEndDate(t *testing.T) {
+	c := Contract{
+		StartDate:         "2023-01-01",
+		EndDate:           "2024-12-31",
+		MinimumDuration:   12,
+		ExtensionDuration: 12,
+	}
+	got := c.Timeline(mustDate(t, "2025-02-01"), mustDate(t, "2030-01-01"))
+	if len(got) != 2 || got[1].TermEnd != "2024-12-31" {
+		t.Errorf("expected boundaries up to the end date, got %+v", got)
+	}
+}
+
+func TestTimeline_OpenEndedWithAnchor(t *testing.T) {
+	c := Contract{
+		StartDate:          "2025-01-15",
+		NoticePeriod:       1,
+		NoticePeriodAnchor: AnchorEndOfQuarter,
+	}
+	got := c.Timeline(mustDate(t, "2025-01-15"), mustDate(t, "2025-12-31"))
+	ends := make([]string, len(got))
+	for i, b := range got {
+		ends[i] = b.TermEnd
+	}
+	want := []string{"2025-03-31", "2025-06-30", "2025-09-30", "2025-12-31"}
+	if strings.Join(ends, ",") != strings.Join(want, ",") {
+		t.Errorf("term ends = %v, want %v", ends, want)
+	}
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ContractStatus tracks where a contract is in its cancellation lifecycle.
+type ContractStatus string
+
+const (
+	StatusActive                ContractStatus = "active"
+	StatusCancellationSent      ContractStatus = "cancellation_sent"
+	StatusCancellationConfirmed ContractStatus = "cancellation_confirmed"
+	StatusTerminated            ContractStatus = "terminated"
+)
+
+// statusTransitions lists the statuses reachable from each status. A pending
+// cancellation can be withdrawn (back to active); termination is final.
+var statusTransitions = map[ContractStatus][]ContractStatus{
+	StatusActive:                {StatusCancellationSent, StatusCancellationConfirmed, StatusTerminated},
+	StatusCancellationSent:      {StatusActive, StatusCancellationConfirmed, StatusTerminated},
+	StatusCancellationConfirmed: {StatusActive, StatusTerminated},
+	StatusTerminated:            {},
+}
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// Valid reports whether s is a known status.
+func (s ContractStatus) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CurrentStatus returns the contract's status, treating an unset status as
+// active.
+func (c Contract) CurrentStatus() ContractStatus {
+	if c.Status == "" {
+		return StatusActive
+	}
+	return c.Status
+}
+
+// CancellationInProgress reports whether a cancellation has been sent,
+// confirmed or has already taken effect, so the contract no longer needs
+// renewal reminders.
+func (c Contract) CancellationInProgress() bool {
+	return c.CurrentStatus() != StatusActive
+}
+
+type ContractTransitionInput struct {
+	Status        ContractStatus `json:"status"`
+	EffectiveDate string         `json:"effectiveDate,omitempty"`
+	Reference     string         `json:"reference,omitempty"`
+}
+
+func (t *ContractTransitionInput) Validate() error {
+	if t.Status == "" {
+		return errors.New("status is required")
+	}
+	if !t.Status.Valid() {
+		return fmt.Errorf("unknown status %q", t.Status)
+	}
+	if t.EffectiveDate != "" {
+		if _, err := time.Parse(dateFormat, t.EffectiveDate); err != nil {
+			return errors.New("effectiveDate must be in format YYYY-MM-DD")
+		}
+	}
+	return nil
+}
+
+// Transition moves the contract to the requested status, recording the time
+// of each step. Returning to active clears all cancellation details. When the
+// contract is terminated without an end date, the effective date (or the
+// transition day) becomes its end date.
+func (c *Contract) Transition(in ContractTransitionInput, at time.Time) error {
+	from := c.CurrentStatus()
+	allowed := false
+	for _, s := range statusTransitions[from] {
+		if s == in.Status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, in.Status)
+	}
+
+	if in.Status == StatusActive {
+		c.Status = StatusActive
+		c.CancellationSentAt = nil
+		c.CancellationConfirmedAt = nil
+		c.CancellationEffectiveDate = ""
+		c.CancellationReference = ""
+		return nil
+	}
+
+	if in.EffectiveDate != "" {
+		c.CancellationEffectiveDate = in.EffectiveDate
+	}
+	if in.Reference != "" {
+		c.CancellationReference = in.Reference
+	}
+
+	switch in.Status {
+	case StatusCancellationSent:
+		c.CancellationSentAt = &at
+	case StatusCancellationConfirmed:
+		c.CancellationConfirmedAt = &at
+	case StatusTerminated:
+		c.TerminatedAt = &at
+		if c.EndDate == "" {
+			c.EndDate = c.CancellationEffectiveDate
+			if c.EndDate == "" {
+				c.EndDate = at.Format(dateFormat)
+			}
+		}
+	}
+	c.Status = in.Status
+	return nil
+}
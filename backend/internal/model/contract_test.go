@@ -0,0 +1,210 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateFormat, s)
+	if err != nil {
+		t.Fatalf("parse %s: %v", s, err)
+	}
+	return d
+}
+
+func TestPriceOn_NoHistory_UsesPrice(t *testing.T) {
+	c := Contract{Price: ptr(10)}
+	got := c.PriceOn(mustDate(t, "2025-01-01"), nil)
+	if got == nil || *got != 10 {
+		t.Fatalf("got %v, want 10", got)
+	}
+}
+
+func TestPriceOn_History(t *testing.T) {
+	c := Contract{Price: ptr(99)}
+	history := []PriceEntry{
+		{Price: 10, EffectiveDate: "2024-01-01"},
+		{Price: 12, EffectiveDate: "2025-01-01"},
+		{Price: 15, EffectiveDate: "2026-01-01"},
+	}
+
+	tests := []struct {
+		date string
+		want *float64
+	}{
+		{"2023-12-31", nil},
+		{"2024-01-01", ptr(10)},
+		{"2024-12-31", ptr(10)},
+		{"2025-01-01", ptr(12)},
+		{"2025-06-15", ptr(12)},
+		{"2027-01-01", ptr(15)},
+	}
+	for _, tt := range tests {
+		got := c.PriceOn(mustDate(t, tt.date), history)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("PriceOn(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestPriceOn_SameDay_LatestEntryWins(t *testing.T) {
+	c := Contract{}
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []PriceEntry{
+		{Price: 10, EffectiveDate: "2025-01-01", CreatedAt: created},
+		{Price: 11, EffectiveDate: "2025-01-01", CreatedAt: created.Add(time.Minute)},
+	}
+	got := c.PriceOn(mustDate(t, "2025-02-01"), history)
+	if got == nil || *got != 11 {
+		t.Fatalf("got %v, want 11", got)
+	}
+}
+
+func TestMonthlyAndYearlyPrice_UseHistory(t *testing.T) {
+	c := Contract{Price: ptr(120), BillingInterval: BillingYearly}
+	history := []PriceEntry{
+		{Price: 120, EffectiveDate: "2024-01-01"},
+		{Price: 240, EffectiveDate: "2025-01-01"},
+	}
+	if got := c.MonthlyPrice(mustDate(t, "2024-06-01"), history); got != 10 {
+		t.Errorf("MonthlyPrice 2024 = %v, want 10", got)
+	}
+	if got := c.YearlyPrice(mustDate(t, "2025-06-01"), history); got != 240 {
+		t.Errorf("YearlyPrice 2025 = %v, want 240", got)
+	}
+}
+
+func TestPriceEntryInput_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input PriceEntryInput
+		ok    bool
+	}{
+		{"valid", PriceEntryInput{Price: ptr(5), EffectiveDate: "2025-01-01"}, true},
+		{"missing price", PriceEntryInput{EffectiveDate: "2025-01-01"}, false},
+		{"negative price", PriceEntryInput{Price: ptr(-1), EffectiveDate: "2025-01-01"}, false},
+		{"missing date", PriceEntryInput{Price: ptr(5)}, false},
+		{"bad date", PriceEntryInput{Price: ptr(5), EffectiveDate: "01.01.2025"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.input.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestTransition(t *testing.T) {
+	at := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		from    ContractStatus
+		to      ContractStatus
+		wantErr bool
+	}{
+		{"unset counts as active", "", StatusCancellationSent, false},
+		{"active to sent", StatusActive, StatusCancellationSent, false},
+		{"active to confirmed", StatusActive, StatusCancellationConfirmed, false},
+		{"sent to confirmed", StatusCancellationSent, StatusCancellationConfirmed, false},
+		{"sent withdrawn", StatusCancellationSent, StatusActive, false},
+		{"confirmed to terminated", StatusCancellationConfirmed, StatusTerminated, false},
+		{"confirmed to sent", StatusCancellationConfirmed, StatusCancellationSent, true},
+		{"active to active", StatusActive, StatusActive, true},
+		{"terminated is final", StatusTerminated, StatusActive, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Contract{Status: tt.from}
+			err := c.Transition(ContractTransitionInput{Status: tt.to}, at)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTransition) {
+					t.Fatalf("expected ErrInvalidTransition, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Status != tt.to {
+				t.Errorf("status = %q, want %q", c.Status, tt.to)
+			}
+		})
+	}
+}
+
+func TestTransition_RecordsDetails(t *testing.T) {
+	sent := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	confirmed := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+	terminated := time.Date(2025, 6, 30, 9, 0, 0, 0, time.UTC)
+
+	c := Contract{Status: StatusActive}
+	if err := c.Transition(ContractTransitionInput{Status: StatusCancellationSent}, sent); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Transition(ContractTransitionInput{
+		Status:        StatusCancellationConfirmed,
+		EffectiveDate: "2025-06-30",
+		Reference:     "KND-4711",
+	}, confirmed); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Transition(ContractTransitionInput{Status: StatusTerminated}, terminated); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.CancellationSentAt == nil || !c.CancellationSentAt.Equal(sent) {
+		t.Errorf("cancellationSentAt = %v, want %v", c.CancellationSentAt, sent)
+	}
+	if c.CancellationConfirmedAt == nil || !c.CancellationConfirmedAt.Equal(confirmed) {
+		t.Errorf("cancellationConfirmedAt = %v, want %v", c.CancellationConfirmedAt, confirmed)
+	}
+	if c.TerminatedAt == nil || !c.TerminatedAt.Equal(terminated) {
+		t.Errorf("terminatedAt = %v, want %v", c.TerminatedAt, terminated)
+	}
+	if c.CancellationReference != "KND-4711" {
+		t.Errorf("reference = %q, want KND-4711", c.CancellationReference)
+	}
+	if c.EndDate != "2025-06-30" {
+		t.Errorf("endDate = %q, want effective date 2025-06-30", c.EndDate)
+	}
+}
+
+func TestTransition_WithdrawClearsDetails(t *testing.T) {
+	at := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	c := Contract{Status: StatusActive}
+	c.Transition(ContractTransitionInput{Status: StatusCancellationSent, EffectiveDate: "2025-12-31", Reference: "X"}, at)
+	if err := c.Transition(ContractTransitionInput{Status: StatusActive}, at); err != nil {
+		t.Fatal(err)
+	}
+	if c.CancellationSentAt != nil || c.CancellationEffectiveDate != "" || c.CancellationReference != "" {
+		t.Errorf("expected cancellation details cleared, got %+v", c)
+	}
+	if c.CancellationInProgress() {
+		t.Error("withdrawn contract should not have a cancellation in progress")
+	}
+}
+
+func TestContractTransitionInput_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   ContractTransitionInput
+		wantErr bool
+	}{
+		{"valid", ContractTransitionInput{Status: StatusTerminated, EffectiveDate: "2025-01-31"}, false},
+		{"missing status", ContractTransitionInput{}, true},
+		{"unknown status", ContractTransitionInput{Status: "paused"}, true},
+		{"bad date", ContractTransitionInput{Status: StatusTerminated, EffectiveDate: "31.01.2025"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.input.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
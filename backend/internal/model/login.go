@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// LoginAttempts counts recent failed logins for one key, a client IP address
+// or an account's email. Logins for the key are refused until LockedUntil,
+// and the record is forgotten at ExpiresAt.
+type LoginAttempts struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"lockedUntil"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
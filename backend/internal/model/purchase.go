@@ -8,22 +8,23 @@ import (
 )
 
 type Purchase struct {
-	ID             uuid.UUID `json:"id"`
-	CategoryID     uuid.UUID `json:"categoryId"`
-	Type           string    `json:"type,omitempty"`
-	ItemName       string    `json:"itemName"`
-	Brand          string    `json:"brand,omitempty"`
-	ArticleNumber  string    `json:"articleNumber,omitempty"`
-	Dealer         string    `json:"dealer,omitempty"`
-	Price          *float64  `json:"price,omitempty"`
-	PurchaseDate   string    `json:"purchaseDate,omitempty"`
-	DescriptionURL string    `json:"descriptionUrl,omitempty"`
-	InvoiceURL     string    `json:"invoiceUrl,omitempty"`
-	HandbookURL    string    `json:"handbookUrl,omitempty"`
-	Consumables    string    `json:"consumables,omitempty"`
-	Comments       string    `json:"comments,omitempty"`
-	CreatedAt      time.Time `json:"createdAt"`
-	UpdatedAt      time.Time `json:"updatedAt"`
+	ID             uuid.UUID  `json:"id"`
+	CategoryID     uuid.UUID  `json:"categoryId"`
+	Type           string     `json:"type,omitempty"`
+	ItemName       string     `json:"itemName"`
+	Brand          string     `json:"brand,omitempty"`
+	ArticleNumber  string     `json:"articleNumber,omitempty"`
+	Dealer         string     `json:"dealer,omitempty"`
+	Price          *float64   `json:"price,omitempty"`
+	PurchaseDate   string     `json:"purchaseDate,omitempty"`
+	DescriptionURL string     `json:"descriptionUrl,omitempty"`
+	InvoiceURL     string     `json:"invoiceUrl,omitempty"`
+	HandbookURL    string     `json:"handbookUrl,omitempty"`
+	Consumables    string     `json:"consumables,omitempty"`
+	Comments       string     `json:"comments,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	DeletedAt      *time.Time `json:"deletedAt,omitempty"` // set while the purchase is in the trash
 }
 
 type PurchaseInput struct {
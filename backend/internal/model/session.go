@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a signed-in device. Access tokens carry the session ID and are
+// only accepted while the session exists. The refresh token rotates on every
+// use; only the SHA-256 hash of the current one is stored.
+type Session struct {
+	ID               uuid.UUID `json:"id"`
+	UserID           string    `json:"-"`
+	RefreshTokenHash string    `json:"-"`
+	UserAgent        string    `json:"userAgent"`
+	IPAddress        string    `json:"ipAddress"`
+	CreatedAt        time.Time `json:"createdAt"`
+	LastUsedAt       time.Time `json:"lastUsedAt"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the session's refresh token is no longer valid.
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
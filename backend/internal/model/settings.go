@@ -19,3 +19,10 @@ type SettingsResponse struct {
 	RenewalDays       int    `json:"renewalDays"`
 	ReminderFrequency string `json:"reminderFrequency"`
 }
+
+// FeedToken grants read access to a user's calendar feed. Only the SHA-256
+// hash of the token is stored.
+type FeedToken struct {
+	TokenHash string    `json:"tokenHash"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TrashEntityTypes are the entity types that deleting moves to the trash.
+var TrashEntityTypes = []string{EntityCategory, EntityContract, EntityPurchase, EntityVehicle, EntityCostEntry}
+
+// TrashItem is a record in the trash. It is hidden everywhere else and can
+// be restored until it is purged.
+type TrashItem struct {
+	EntityType string    `json:"entityType"`
+	ID         uuid.UUID `json:"id"`
+	// Module is the module a category belongs to; empty for other entities.
+	Module string `json:"module,omitempty"`
+	Name   string `json:"name"`
+	// ParentID is the category of a contract or purchase, or the vehicle of
+	// a cost entry. Children deleted along with their parent share its
+	// DeletedAt and are restored with it.
+	ParentID  *uuid.UUID `json:"parentId,omitempty"`
+	DeletedAt time.Time  `json:"deletedAt"`
+}
+
+// The TrashItem methods describe a record as an item of the trash; ok is
+// false if it is not in the trash.
+
+func (c Category) TrashItem(module string) (item TrashItem, ok bool) {
+	if c.DeletedAt == nil {
+		return item, false
+	}
+	return TrashItem{EntityType: EntityCategory, ID: c.ID, Module: module, Name: c.Name, DeletedAt: *c.DeletedAt}, true
+}
+
+func (c Contract) TrashItem() (item TrashItem, ok bool) {
+	if c.DeletedAt == nil {
+		return item, false
+	}
+	return TrashItem{EntityType: EntityContract, ID: c.ID, Name: c.Name, ParentID: &c.CategoryID, DeletedAt: *c.DeletedAt}, true
+}
+
+func (p Purchase) TrashItem() (item TrashItem, ok bool) {
+	if p.DeletedAt == nil {
+		return item, false
+	}
+	return TrashItem{EntityType: EntityPurchase, ID: p.ID, Name: p.ItemName, ParentID: &p.CategoryID, DeletedAt: *p.DeletedAt}, true
+}
+
+func (v Vehicle) TrashItem() (item TrashItem, ok bool) {
+	if v.DeletedAt == nil {
+		return item, false
+	}
+	return TrashItem{EntityType: EntityVehicle, ID: v.ID, Name: v.Name, DeletedAt: *v.DeletedAt}, true
+}
+
+func (c CostEntry) TrashItem() (item TrashItem, ok bool) {
+	if c.DeletedAt == nil {
+		return item, false
+	}
+	name := c.Description
+	if name == "" {
+		name = c.Type
+	}
+	return TrashItem{EntityType: EntityCostEntry, ID: c.ID, Name: name, ParentID: &c.VehicleID, DeletedAt: *c.DeletedAt}, true
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// TwoFactor is a user's TOTP configuration. A secret stays pending until the
+// user proves their authenticator works by entering a code. Recovery codes
+// are stored as SHA-256 hashes and removed once used.
+type TwoFactor struct {
+	Secret        string     `json:"secret"`
+	Enabled       bool       `json:"enabled"`
+	RecoveryCodes []string   `json:"recoveryCodes"`
+	LastUsedStep  int64      `json:"lastUsedStep"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	EnabledAt     *time.Time `json:"enabledAt,omitempty"`
+}
@@ -7,8 +7,37 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"createdAt"`
+	ID                  uuid.UUID `json:"id"`
+	Email               string    `json:"email"`
+	PasswordHash        string    `json:"-"`
+	OIDCSubject         string    `json:"-"` // user ID at the OpenID provider, once linked
+	PersonalWorkspaceID uuid.UUID `json:"personalWorkspaceId"`
+	// EmailVerified is set once the user confirmed Email through a link;
+	// only verified addresses get reminders.
+	EmailVerified bool `json:"emailVerified"`
+	// Admin users manage accounts; Disabled users cannot sign in.
+	Admin     bool      `json:"admin"`
+	Disabled  bool      `json:"disabled"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PasswordResetToken lets the holder of an emailed link set a new password.
+// Only the SHA-256 hash of the token is stored, and a user has at most one.
+type PasswordResetToken struct {
+	TokenHash string    `json:"tokenHash"`
+	UserID    string    `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// EmailVerification lets the holder of an emailed link confirm that Email
+// belongs to the user. If Email is not the user's current address,
+// confirming it changes the address. Only the SHA-256 hash of the token is
+// stored, and a user has at most one.
+type EmailVerification struct {
+	TokenHash string    `json:"tokenHash"`
+	UserID    string    `json:"userId"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
 }
@@ -8,23 +8,24 @@ import (
 )
 
 type Vehicle struct {
-	ID                uuid.UUID `json:"id"`
-	Name              string    `json:"name"`
-	Make              string    `json:"make,omitempty"`
-	Model             string    `json:"model,omitempty"`
-	Year              *int      `json:"year,omitempty"`
-	LicensePlate      string    `json:"licensePlate,omitempty"`
-	PurchaseDate      string    `json:"purchaseDate,omitempty"`
-	PurchasePrice     *float64  `json:"purchasePrice,omitempty"`
-	PurchaseMileage   *float64  `json:"purchaseMileage,omitempty"`
-	TargetMileage     *float64  `json:"targetMileage,omitempty"`
-	TargetMonths      *int      `json:"targetMonths,omitempty"`
-	AnnualInsurance   *float64  `json:"annualInsurance,omitempty"`
-	AnnualTax         *float64  `json:"annualTax,omitempty"`
-	MaintenanceFactor *float64  `json:"maintenanceFactor,omitempty"`
-	Comments          string    `json:"comments,omitempty"`
-	CreatedAt         time.Time `json:"createdAt"`
-	UpdatedAt         time.Time `json:"updatedAt"`
+	ID                uuid.UUID  `json:"id"`
+	Name              string     `json:"name"`
+	Make              string     `json:"make,omitempty"`
+	Model             string     `json:"model,omitempty"`
+	Year              *int       `json:"year,omitempty"`
+	LicensePlate      string     `json:"licensePlate,omitempty"`
+	PurchaseDate      string     `json:"purchaseDate,omitempty"`
+	PurchasePrice     *float64   `json:"purchasePrice,omitempty"`
+	PurchaseMileage   *float64   `json:"purchaseMileage,omitempty"`
+	TargetMileage     *float64   `json:"targetMileage,omitempty"`
+	TargetMonths      *int       `json:"targetMonths,omitempty"`
+	AnnualInsurance   *float64   `json:"annualInsurance,omitempty"`
+	AnnualTax         *float64   `json:"annualTax,omitempty"`
+	MaintenanceFactor *float64   `json:"maintenanceFactor,omitempty"`
+	Comments          string     `json:"comments,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+	DeletedAt         *time.Time `json:"deletedAt,omitempty"` // set while the vehicle is in the trash
 }
 
 type VehicleInput struct {
@@ -81,17 +82,18 @@ var validCostTypes = map[string]bool{
 }
 
 type CostEntry struct {
-	ID          uuid.UUID `json:"id"`
-	VehicleID   uuid.UUID `json:"vehicleId"`
-	Type        string    `json:"type"`
-	Description string    `json:"description,omitempty"`
-	Vendor      string    `json:"vendor,omitempty"`
-	Amount      *float64  `json:"amount,omitempty"`
-	Date        string    `json:"date"`
-	Mileage     *float64  `json:"mileage,omitempty"`
-	Comments    string    `json:"comments,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          uuid.UUID  `json:"id"`
+	VehicleID   uuid.UUID  `json:"vehicleId"`
+	Type        string     `json:"type"`
+	Description string     `json:"description,omitempty"`
+	Vendor      string     `json:"vendor,omitempty"`
+	Amount      *float64   `json:"amount,omitempty"`
+	Date        string     `json:"date"`
+	Mileage     *float64   `json:"mileage,omitempty"`
+	Comments    string     `json:"comments,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"` // set while the cost entry is in the trash
 }
 
 type CostEntryInput struct {
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Workspace roles. Every workspace has exactly one owner, the user who
+// created it; editors can change its data and viewers can only read it.
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+)
+
+// Workspace owns categories, contracts, purchases and vehicles, and is
+// shared by its members. Every user has a personal workspace created with
+// the account.
+type Workspace struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WorkspaceInput struct {
+	Name string `json:"name"`
+}
+
+func (in *WorkspaceInput) Validate() error {
+	if in.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type WorkspaceMember struct {
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	UserID      string    `json:"userId"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joinedAt"`
+}
+
+// CanWrite reports whether the member may change the workspace's data.
+func (m WorkspaceMember) CanWrite() bool {
+	return m.Role == WorkspaceRoleOwner || m.Role == WorkspaceRoleEditor
+}
+
+// ValidInviteRole reports whether role can be given to an invited or
+// existing member. Ownership cannot be handed out.
+func ValidInviteRole(role string) bool {
+	return role == WorkspaceRoleEditor || role == WorkspaceRoleViewer
+}
+
+// Invitation asks the holder of an emailed link to join a workspace. Only
+// the SHA-256 hash of the token is stored.
+type Invitation struct {
+	ID          uuid.UUID `json:"id"`
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	TokenHash   string    `json:"-"`
+	InvitedBy   string    `json:"invitedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
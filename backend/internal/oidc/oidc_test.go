@@ -0,0 +1,109 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/tobi/contracts/backend/internal/oidc/oidctest"
+)
+
+const redirectURL = "https://app.example.com/callback"
+
+func newProvider(t *testing.T) (*oidctest.Provider, *Provider) {
+	fake := oidctest.NewProvider(t)
+	fake.User = oidctest.User{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}
+	p := New(Config{
+		Issuer:       fake.URL,
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  redirectURL,
+	})
+	return fake, p
+}
+
+// authorize follows the login URL to the provider and returns the code it
+// redirects back with.
+func authorize(t *testing.T, p *Provider, state, nonce, challenge string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if got := loc.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	return loc.Query().Get("code")
+}
+
+func TestExchange(t *testing.T) {
+	_, p := newProvider(t)
+	ctx := context.Background()
+	verifier, challenge, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := authorize(t, p, "state", "nonce", challenge)
+	claims, err := p.Exchange(ctx, code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Claims{Subject: "sub-1", Email: "alice@example.com", EmailVerified: true}
+	if claims != want {
+		t.Errorf("claims = %+v, want %+v", claims, want)
+	}
+
+	if _, err := p.Exchange(ctx, code, verifier, "nonce"); err == nil {
+		t.Error("replayed code should be rejected")
+	}
+}
+
+func TestExchange_Rejects(t *testing.T) {
+	_, p := newProvider(t)
+	ctx := context.Background()
+	verifier, challenge, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := authorize(t, p, "state", "nonce", challenge)
+	if _, err := p.Exchange(ctx, code, "wrong-verifier", "nonce"); err == nil {
+		t.Error("wrong code verifier should be rejected")
+	}
+
+	code = authorize(t, p, "state", "nonce", challenge)
+	if _, err := p.Exchange(ctx, code, verifier, "other-nonce"); err == nil {
+		t.Error("nonce mismatch should be rejected")
+	}
+}
+
+func TestExchange_UnknownClient(t *testing.T) {
+	fake, _ := newProvider(t)
+	p := New(Config{Issuer: fake.URL, ClientID: "someone-else", ClientSecret: oidctest.ClientSecret, RedirectURL: redirectURL})
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), "code", "verifier", "n"); err == nil {
+		t.Error("unknown client should be rejected")
+	}
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	fake, _ := newProvider(t)
+	p := New(Config{Issuer: fake.URL + "/", ClientID: oidctest.ClientID, RedirectURL: redirectURL})
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Error("issuer mismatch should fail discovery")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/email"
 	"github.com/tobi/contracts/backend/internal/model"
 	"github.com/tobi/contracts/backend/internal/store"
@@ -21,6 +22,7 @@ var frequencyDurations = map[string]time.Duration{
 
 type upcomingContract struct {
 	contract         model.Contract
+	history          []model.PriceEntry
 	cancellationDate string
 }
 
@@ -28,10 +30,11 @@ type Scheduler struct {
 	store  store.Store
 	email  *email.Client
 	logger *slog.Logger
+	now    func() time.Time
 }
 
 func New(s store.Store, e *email.Client, logger *slog.Logger) *Scheduler {
-	return &Scheduler{store: s, email: e, logger: logger.With("component", "reminder")}
+	return &Scheduler{store: s, email: e, logger: logger.With("component", "reminder"), now: time.Now}
 }
 
 func (s *Scheduler) Start(ctx context.Context) {
@@ -62,6 +65,9 @@ func (s *Scheduler) checkAll(ctx context.Context) {
 	}
 
 	for _, u := range users {
+		if u.Disabled || !u.EmailVerified {
+			continue
+		}
 		if err := s.checkUser(ctx, u); err != nil {
 			s.logger.Error("checking reminders for user", "userID", u.ID, "error", err)
 		}
@@ -83,21 +89,25 @@ func (s *Scheduler) checkUser(ctx context.Context, u model.User) error {
 		return nil
 	}
 
-	if !settings.LastReminderSent.IsZero() && time.Since(settings.LastReminderSent) < dur {
+	now := s.now().UTC()
+	if !settings.LastReminderSent.IsZero() && now.Sub(settings.LastReminderSent) < dur {
 		return nil
 	}
 
-	contracts, err := s.store.ListContracts(ctx, u.ID.String())
+	contracts, histories, err := s.memberContracts(ctx, u.ID.String())
 	if err != nil {
-		return fmt.Errorf("listing contracts: %w", err)
+		return err
 	}
 
-	today := time.Now().UTC().Truncate(24 * time.Hour)
+	today := now.Truncate(24 * time.Hour)
 	deadline := today.AddDate(0, 0, settings.RenewalDays)
 
 	var matches []upcomingContract
 	for _, c := range contracts {
-		cd := c.CancellationDate()
+		if c.CancellationInProgress() {
+			continue
+		}
+		cd := c.CancellationDateAsOf(today)
 		if cd == nil {
 			continue
 		}
@@ -106,7 +116,7 @@ func (s *Scheduler) checkUser(ctx context.Context, u model.User) error {
 			continue
 		}
 		if !d.Before(today) && !d.After(deadline) {
-			matches = append(matches, upcomingContract{contract: c, cancellationDate: *cd})
+			matches = append(matches, upcomingContract{contract: c, history: histories[c.ID], cancellationDate: *cd})
 		}
 	}
 
@@ -118,13 +128,13 @@ func (s *Scheduler) checkUser(ctx context.Context, u model.User) error {
 		return matches[i].cancellationDate < matches[j].cancellationDate
 	})
 
-	body := buildEmail(matches)
+	body := buildEmail(matches, today)
 
 	if err := s.email.Send([]string{u.Email}, "Upcoming contract renewals", body); err != nil {
 		return fmt.Errorf("sending email: %w", err)
 	}
 
-	settings.LastReminderSent = time.Now().UTC()
+	settings.LastReminderSent = now
 	if err := s.store.UpdateSettings(ctx, u.ID.String(), settings); err != nil {
 		return fmt.Errorf("updating last reminder sent: %w", err)
 	}
@@ -133,7 +143,7 @@ func (s *Scheduler) checkUser(ctx context.Context, u model.User) error {
 	return nil
 }
 
-func buildEmail(matches []upcomingContract) string {
+func buildEmail(matches []upcomingContract, today time.Time) string {
 	var b strings.Builder
 	b.WriteString("The following contracts have upcoming renewal deadlines:\n\n")
 
@@ -142,9 +152,54 @@ func buildEmail(matches []upcomingContract) string {
 		if m.contract.Company != "" {
 			b.WriteString(fmt.Sprintf(" (%s)", m.contract.Company))
 		}
-		b.WriteString(fmt.Sprintf(" — cancellation by %s\n", m.cancellationDate))
+		b.WriteString(fmt.Sprintf(" — cancellation by %s", m.cancellationDate))
+		if cost := formatCost(m.contract, m.history, today); cost != "" {
+			b.WriteString(fmt.Sprintf(" (%s)", cost))
+		}
+		b.WriteString("\n")
 	}
 
 	b.WriteString("\nPlease review these contracts and take action if needed.")
 	return b.String()
 }
+
+// formatCost describes the price in effect on date normalized to a yearly
+// amount, or the single payment for one-time contracts. Returns "" without a
+// price.
+func formatCost(c model.Contract, history []model.PriceEntry, date time.Time) string {
+	price := c.PriceOn(date, history)
+	if price == nil {
+		return ""
+	}
+	if c.BillingInterval == model.BillingOneTime {
+		return fmt.Sprintf("%.2f one-time", *price)
+	}
+	return fmt.Sprintf("%.2f per year", c.YearlyPrice(date, history))
+}
+
+// memberContracts lists the contracts of every workspace the user belongs to,
+// along with their price histories by contract ID.
+func (s *Scheduler) memberContracts(ctx context.Context, userID string) ([]model.Contract, map[uuid.UUID][]model.PriceEntry, error) {
+	memberships, err := s.store.ListWorkspaceMemberships(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing workspaces: %w", err)
+	}
+	var contracts []model.Contract
+	histories := make(map[uuid.UUID][]model.PriceEntry)
+	for _, m := range memberships {
+		cs, err := s.store.ListContracts(ctx, m.WorkspaceID.String())
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing contracts: %w", err)
+		}
+		contracts = append(contracts, cs...)
+
+		entries, err := s.store.ListPriceEntries(ctx, m.WorkspaceID.String())
+		if err != nil {
+			return nil, nil, fmt.Errorf("listing price entries: %w", err)
+		}
+		for _, e := range entries {
+			histories[e.ContractID] = append(histories[e.ContractID], e)
+		}
+	}
+	return contracts, histories, nil
+}
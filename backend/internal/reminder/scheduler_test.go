@@ -16,6 +16,7 @@ type mockStore struct {
 	users     []model.User
 	settings  map[string]model.UserSettings
 	contracts map[string][]model.Contract
+	prices    map[string][]model.PriceEntry
 }
 
 func (m *mockStore) CreateUser(_ context.Context, _ model.User) error { return nil }
@@ -29,6 +30,7 @@ func (m *mockStore) UpdateUser(_ context.Context, _ model.User) error { return n
 func (m *mockStore) ListUsers(_ context.Context) ([]model.User, error) {
 	return m.users, nil
 }
+func (m *mockStore) DeleteUser(_ context.Context, _ string) error { return nil }
 func (m *mockStore) GetSettings(_ context.Context, userID string) (model.UserSettings, error) {
 	s, ok := m.settings[userID]
 	if !ok {
@@ -40,6 +42,98 @@ func (m *mockStore) UpdateSettings(_ context.Context, userID string, s model.Use
 	m.settings[userID] = s
 	return nil
 }
+func (m *mockStore) GetFeedToken(_ context.Context, _ string) (model.FeedToken, error) {
+	return model.FeedToken{}, store.ErrNotFound
+}
+func (m *mockStore) SetFeedToken(_ context.Context, _ string, _ model.FeedToken) error { return nil }
+func (m *mockStore) DeleteFeedToken(_ context.Context, _ string) error                 { return nil }
+func (m *mockStore) GetUserIDByFeedToken(_ context.Context, _ string) (string, error) {
+	return "", store.ErrNotFound
+}
+func (m *mockStore) GetTwoFactor(_ context.Context, _ string) (model.TwoFactor, error) {
+	return model.TwoFactor{}, store.ErrNotFound
+}
+func (m *mockStore) SetTwoFactor(_ context.Context, _ string, _ model.TwoFactor) error { return nil }
+func (m *mockStore) DeleteTwoFactor(_ context.Context, _ string) error                 { return nil }
+func (m *mockStore) UseTwoFactorStep(_ context.Context, _ string, _ int64) error {
+	return nil
+}
+func (m *mockStore) ConsumeRecoveryCode(_ context.Context, _, _ string) error { return nil }
+func (m *mockStore) SetPasswordResetToken(_ context.Context, _ model.PasswordResetToken) error {
+	return nil
+}
+func (m *mockStore) ConsumePasswordResetToken(_ context.Context, _ string) (model.PasswordResetToken, error) {
+	return model.PasswordResetToken{}, store.ErrNotFound
+}
+func (m *mockStore) SetEmailVerification(_ context.Context, _ model.EmailVerification) error {
+	return nil
+}
+func (m *mockStore) ConsumeEmailVerification(_ context.Context, _ string) (model.EmailVerification, error) {
+	return model.EmailVerification{}, store.ErrNotFound
+}
+func (m *mockStore) GetLoginAttempts(_ context.Context, _ string) (model.LoginAttempts, error) {
+	return model.LoginAttempts{}, store.ErrNotFound
+}
+func (m *mockStore) IncrementLoginAttempts(_ context.Context, key string, _ time.Time, _ time.Duration, _ func(int) time.Duration) (model.LoginAttempts, error) {
+	return model.LoginAttempts{Key: key}, nil
+}
+func (m *mockStore) DecrementLoginAttempts(_ context.Context, _ model.LoginAttempts) error {
+	return nil
+}
+func (m *mockStore) DeleteLoginAttempts(_ context.Context, _ string) error  { return nil }
+func (m *mockStore) CreateSession(_ context.Context, _ model.Session) error { return nil }
+func (m *mockStore) GetSession(_ context.Context, _ uuid.UUID) (model.Session, error) {
+	return model.Session{}, store.ErrNotFound
+}
+func (m *mockStore) UpdateSession(_ context.Context, _ model.Session) error { return nil }
+func (m *mockStore) RotateSession(_ context.Context, _ model.Session, _ string) error {
+	return nil
+}
+func (m *mockStore) ListSessions(_ context.Context, _ string) ([]model.Session, error) {
+	return nil, nil
+}
+func (m *mockStore) DeleteSession(_ context.Context, _ string, _ uuid.UUID) error { return nil }
+func (m *mockStore) CreateAPIKey(_ context.Context, _ model.APIKey) error         { return nil }
+func (m *mockStore) GetAPIKeyByHash(_ context.Context, _ string) (model.APIKey, error) {
+	return model.APIKey{}, store.ErrNotFound
+}
+func (m *mockStore) UpdateAPIKey(_ context.Context, _ model.APIKey) error { return nil }
+func (m *mockStore) ListAPIKeys(_ context.Context, _ string) ([]model.APIKey, error) {
+	return nil, nil
+}
+func (m *mockStore) DeleteAPIKey(_ context.Context, _ string, _ uuid.UUID) error { return nil }
+func (m *mockStore) CreateWorkspace(_ context.Context, _ model.Workspace, _ model.WorkspaceMember) error {
+	return nil
+}
+func (m *mockStore) GetWorkspace(_ context.Context, _ uuid.UUID) (model.Workspace, error) {
+	return model.Workspace{}, store.ErrNotFound
+}
+func (m *mockStore) UpdateWorkspace(_ context.Context, _ model.Workspace) error { return nil }
+func (m *mockStore) DeleteWorkspace(_ context.Context, _ uuid.UUID) error       { return nil }
+
+// ListWorkspaceMemberships puts every user in a single workspace that shares
+// their ID, so contracts are keyed by user ID.
+func (m *mockStore) ListWorkspaceMemberships(_ context.Context, userID string) ([]model.WorkspaceMember, error) {
+	return []model.WorkspaceMember{{WorkspaceID: uuid.MustParse(userID), UserID: userID, Role: model.WorkspaceRoleOwner}}, nil
+}
+func (m *mockStore) GetWorkspaceMember(_ context.Context, _ uuid.UUID, _ string) (model.WorkspaceMember, error) {
+	return model.WorkspaceMember{}, store.ErrNotFound
+}
+func (m *mockStore) ListWorkspaceMembers(_ context.Context, _ uuid.UUID) ([]model.WorkspaceMember, error) {
+	return nil, nil
+}
+func (m *mockStore) SetWorkspaceMember(_ context.Context, _ model.WorkspaceMember) error { return nil }
+func (m *mockStore) DeleteWorkspaceMember(_ context.Context, _ uuid.UUID, _ string) error {
+	return nil
+}
+func (m *mockStore) CreateInvitation(_ context.Context, _ model.Invitation) error { return nil }
+func (m *mockStore) ListInvitations(_ context.Context, _ uuid.UUID) ([]model.Invitation, error) {
+	return nil, nil
+}
+func (m *mockStore) DeleteInvitation(_ context.Context, _, _ uuid.UUID) error { return nil }
+func (m *mockStore) ConsumeInvitation(_ context.Context, _ string) (model.Invitation, error) {
+	return model.Invitation{}, store.ErrNotFound
+}
 func (m *mockStore) ListCategories(_ context.Context, _ string, _ string) ([]model.Category, error) {
 	return nil, nil
 }
@@ -55,8 +149,8 @@ func (m *mockStore) UpdateCategory(_ context.Context, _ string, _ string, _ mode
 func (m *mockStore) DeleteCategory(_ context.Context, _ string, _ string, _ uuid.UUID) error {
 	return nil
 }
-func (m *mockStore) ListContracts(_ context.Context, userID string) ([]model.Contract, error) {
-	return m.contracts[userID], nil
+func (m *mockStore) ListContracts(_ context.Context, workspaceID string) ([]model.Contract, error) {
+	return m.contracts[workspaceID], nil
 }
 func (m *mockStore) ListContractsByCategory(_ context.Context, _ string, _ uuid.UUID) ([]model.Contract, error) {
 	return nil, nil
@@ -65,8 +159,23 @@ func (m *mockStore) GetContract(_ context.Context, _ string, _ uuid.UUID) (model
 	return model.Contract{}, nil
 }
 func (m *mockStore) CreateContract(_ context.Context, _ string, _ model.Contract) error { return nil }
-func (m *mockStore) UpdateContract(_ context.Context, _ string, _ model.Contract) error { return nil }
-func (m *mockStore) DeleteContract(_ context.Context, _ string, _ uuid.UUID) error      { return nil }
+func (m *mockStore) UpdateContract(_ context.Context, _ string, _ model.Contract, _ ...model.PriceEntry) error {
+	return nil
+}
+func (m *mockStore) DeleteContract(_ context.Context, _ string, _ uuid.UUID) error { return nil }
+func (m *mockStore) ListPriceEntries(_ context.Context, workspaceID string) ([]model.PriceEntry, error) {
+	return m.prices[workspaceID], nil
+}
+func (m *mockStore) ListPriceEntriesByContract(_ context.Context, _ string, _ uuid.UUID) ([]model.PriceEntry, error) {
+	return nil, nil
+}
+func (m *mockStore) GetPriceEntry(_ context.Context, _ string, _ uuid.UUID) (model.PriceEntry, error) {
+	return model.PriceEntry{}, store.ErrNotFound
+}
+func (m *mockStore) CreatePriceEntry(_ context.Context, _ string, _ model.PriceEntry) error {
+	return nil
+}
+func (m *mockStore) DeletePriceEntry(_ context.Context, _ string, _ uuid.UUID) error { return nil }
 func (m *mockStore) ListPurchases(_ context.Context, _ string) ([]model.Purchase, error) {
 	return nil, nil
 }
@@ -100,6 +209,29 @@ func (m *mockStore) CreateCostEntry(_ context.Context, _ string, _ model.CostEnt
 func (m *mockStore) UpdateCostEntry(_ context.Context, _ string, _ model.CostEntry) error { return nil }
 func (m *mockStore) DeleteCostEntry(_ context.Context, _ string, _ uuid.UUID) error       { return nil }
 
+func (m *mockStore) ListAuditEntries(_ context.Context, _ string, _ uuid.UUID) ([]model.AuditEntry, error) {
+	return nil, nil
+}
+func (m *mockStore) ListActivity(_ context.Context, _ string, _ uuid.UUID, _ int) ([]model.AuditEntry, error) {
+	return nil, nil
+}
+
+func (m *mockStore) ListTrash(_ context.Context, _ string) ([]model.TrashItem, error) {
+	return nil, nil
+}
+
+func (m *mockStore) RestoreFromTrash(_ context.Context, _, _ string, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockStore) PurgeFromTrash(_ context.Context, _, _ string, _ uuid.UUID) error {
+	return nil
+}
+
+func (m *mockStore) PurgeTrash(_ context.Context, _ time.Time) (int, error) {
+	return 0, nil
+}
+
 func (m *mockStore) Close() error { return nil }
 
 func newTestUser() model.User {
@@ -119,7 +251,7 @@ func TestCheckUser_SkipsDisabled(t *testing.T) {
 		},
 	}
 
-	sched := &Scheduler{store: ms, email: nil, logger: testLogger()}
+	sched := &Scheduler{store: ms, email: nil, logger: testLogger(), now: time.Now}
 	err := sched.checkUser(context.Background(), user)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -136,7 +268,7 @@ func TestCheckUser_SkipsEmptyFrequency(t *testing.T) {
 		},
 	}
 
-	sched := &Scheduler{store: ms, email: nil, logger: testLogger()}
+	sched := &Scheduler{store: ms, email: nil, logger: testLogger(), now: time.Now}
 	err := sched.checkUser(context.Background(), user)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -157,7 +289,7 @@ func TestCheckUser_SkipsWhenNotEnoughTimeElapsed(t *testing.T) {
 		},
 	}
 
-	sched := &Scheduler{store: ms, email: nil, logger: testLogger()}
+	sched := &Scheduler{store: ms, email: nil, logger: testLogger(), now: time.Now}
 	err := sched.checkUser(context.Background(), user)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -171,7 +303,7 @@ func TestCheckUser_DefaultSettingsSkips(t *testing.T) {
 		settings: map[string]model.UserSettings{},
 	}
 
-	sched := &Scheduler{store: ms, email: nil, logger: testLogger()}
+	sched := &Scheduler{store: ms, email: nil, logger: testLogger(), now: time.Now}
 	err := sched.checkUser(context.Background(), user)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -190,7 +322,7 @@ func TestBuildEmail_FormatsCorrectly(t *testing.T) {
 		},
 	}
 
-	body := buildEmail(matches)
+	body := buildEmail(matches, time.Now().UTC())
 
 	if !strings.Contains(body, "Phone Plan (Telco Inc)") {
 		t.Errorf("expected body to contain 'Phone Plan (Telco Inc)', got:\n%s", body)
@@ -214,7 +346,7 @@ func TestBuildEmail_SingleContract(t *testing.T) {
 		},
 	}
 
-	body := buildEmail(matches)
+	body := buildEmail(matches, time.Now().UTC())
 	lines := strings.Split(body, "\n")
 
 	found := false
@@ -232,3 +364,112 @@ func TestBuildEmail_SingleContract(t *testing.T) {
 func testLogger() *slog.Logger {
 	return slog.Default()
 }
+
+func TestBuildEmail_NormalizesPrice(t *testing.T) {
+	quarterly := 30.0
+	oneTime := 250.0
+	matches := []upcomingContract{
+		{
+			contract:         model.Contract{Name: "Insurance", Price: &quarterly, BillingInterval: model.BillingQuarterly},
+			cancellationDate: "2025-07-01",
+		},
+		{
+			contract:         model.Contract{Name: "Domain", Price: &oneTime, BillingInterval: model.BillingOneTime},
+			cancellationDate: "2025-08-01",
+		},
+	}
+
+	body := buildEmail(matches, time.Now().UTC())
+
+	if !strings.Contains(body, "- Insurance — cancellation by 2025-07-01 (120.00 per year)") {
+		t.Errorf("expected quarterly price normalized to a yearly amount, got:\n%s", body)
+	}
+	if !strings.Contains(body, "- Domain — cancellation by 2025-08-01 (250.00 one-time)") {
+		t.Errorf("expected one-time price, got:\n%s", body)
+	}
+}
+
+func TestCheckUser_SkipsCancellationInProgress(t *testing.T) {
+	user := newTestUser()
+	uid := user.ID.String()
+
+	start := time.Now().UTC().AddDate(0, -11, 0).Format("2006-01-02")
+	ms := &mockStore{
+		settings: map[string]model.UserSettings{
+			uid: {RenewalDays: 90, ReminderFrequency: "weekly"},
+		},
+		contracts: map[string][]model.Contract{
+			uid: {
+				{Name: "Sent", StartDate: start, MinimumDuration: 12, Status: model.StatusCancellationSent},
+				{Name: "Confirmed", StartDate: start, MinimumDuration: 12, Status: model.StatusCancellationConfirmed},
+			},
+		},
+	}
+
+	// A nil email client would panic if a reminder were sent.
+	sched := &Scheduler{store: ms, email: nil, logger: testLogger(), now: time.Now}
+	if err := sched.checkUser(context.Background(), user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ms.settings[uid].LastReminderSent.IsZero() {
+		t.Error("no reminder should have been sent")
+	}
+}
+
+func TestCheckUser_UsesInjectedClock(t *testing.T) {
+	user := newTestUser()
+	uid := user.ID.String()
+	lastSent := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	ms := &mockStore{
+		settings: map[string]model.UserSettings{
+			uid: {RenewalDays: 90, ReminderFrequency: "weekly", LastReminderSent: lastSent},
+		},
+		contracts: map[string][]model.Contract{
+			uid: {{Name: "Phone", StartDate: "2024-03-01", MinimumDuration: 12}},
+		},
+	}
+
+	// Two days after the last reminder a weekly reminder is not due yet, even
+	// though the contract's deadline is close. A nil email client would panic
+	// if a reminder were sent.
+	now := func() time.Time { return time.Date(2025, 1, 3, 8, 0, 0, 0, time.UTC) }
+	sched := &Scheduler{store: ms, email: nil, logger: testLogger(), now: now}
+	if err := sched.checkUser(context.Background(), user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ms.settings[uid].LastReminderSent.Equal(lastSent) {
+		t.Error("no reminder should have been sent")
+	}
+}
+
+func TestMemberContracts_UsesPriceHistory(t *testing.T) {
+	user := newTestUser()
+	uid := user.ID.String()
+	contractID := uuid.New()
+	oldPrice := 10.0
+
+	ms := &mockStore{
+		contracts: map[string][]model.Contract{
+			uid: {{ID: contractID, Name: "Internet", Price: &oldPrice, BillingInterval: model.BillingMonthly}},
+		},
+		prices: map[string][]model.PriceEntry{
+			uid: {
+				{ContractID: contractID, Price: 10, EffectiveDate: "2024-01-01"},
+				{ContractID: contractID, Price: 15, EffectiveDate: "2025-01-01"},
+			},
+		},
+	}
+
+	sched := &Scheduler{store: ms, email: nil, logger: testLogger(), now: time.Now}
+	contracts, histories, err := sched.memberContracts(context.Background(), uid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	matches := []upcomingContract{{contract: contracts[0], history: histories[contractID], cancellationDate: "2025-03-01"}}
+
+	body := buildEmail(matches, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	if !strings.Contains(body, "(180.00 per year)") {
+		t.Errorf("expected the price in effect on the date, got:\n%s", body)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/handler"
 	"github.com/tobi/contracts/backend/internal/middleware"
 	"github.com/tobi/contracts/backend/internal/model"
@@ -19,10 +22,12 @@ var testJWTSecret = []byte("integration-test-secret")
 
 const testUserID = "00000000-0000-0000-0000-000000000001"
 
+var testWorkspaceID = uuid.MustParse("00000000-0000-0000-0000-0000000000a1")
+
 func setupServer(t *testing.T) *httptest.Server {
 	t.Helper()
 	logger := slog.Default()
-	s, err := store.NewBadgerStore(t.TempDir(), logger)
+	s, err := store.NewBadgerStore(t.TempDir(), nil, logger)
 	if err != nil {
 		t.Fatalf("NewBadgerStore: %v", err)
 	}
@@ -37,29 +42,73 @@ func setupServer(t *testing.T) *httptest.Server {
 	mux.HandleFunc("GET /api/v1/modules/{module}/categories/{id}", h.GetCategory)
 	mux.HandleFunc("PUT /api/v1/modules/{module}/categories/{id}", h.UpdateCategory)
 	mux.HandleFunc("DELETE /api/v1/modules/{module}/categories/{id}", h.DeleteCategory)
+	mux.HandleFunc("GET /api/v1/modules/{module}/categories/{id}/history", h.CategoryHistory)
+	mux.HandleFunc("POST /api/v1/modules/{module}/categories/{id}/history/{revision}/revert", h.RevertCategory)
 
 	// Contract routes
 	mux.HandleFunc("GET /api/v1/categories/{id}/contracts", h.ListContractsByCategory)
 	mux.HandleFunc("POST /api/v1/categories/{id}/contracts", h.CreateContractInCategory)
+	mux.HandleFunc("POST /api/v1/contracts/import/preview", h.PreviewContractImport)
+	mux.HandleFunc("POST /api/v1/contracts/import/csv", h.ImportContractsCSV)
+	mux.HandleFunc("GET /api/v1/contracts/export/csv", h.ExportContractsCSV)
 	mux.HandleFunc("GET /api/v1/contracts/upcoming-renewals", h.UpcomingRenewals)
 	mux.HandleFunc("GET /api/v1/contracts", h.ListContracts)
 	mux.HandleFunc("GET /api/v1/contracts/{id}", h.GetContract)
 	mux.HandleFunc("PUT /api/v1/contracts/{id}", h.UpdateContract)
 	mux.HandleFunc("DELETE /api/v1/contracts/{id}", h.DeleteContract)
+	mux.HandleFunc("POST /api/v1/contracts/{id}/transition", h.TransitionContract)
+	mux.HandleFunc("GET /api/v1/contracts/{id}/timeline", h.ContractTimeline)
+	mux.HandleFunc("GET /api/v1/contracts/{id}/prices", h.ListPriceEntries)
+	mux.HandleFunc("POST /api/v1/contracts/{id}/prices", h.CreatePriceEntry)
+	mux.HandleFunc("DELETE /api/v1/contracts/{id}/prices/{priceId}", h.DeletePriceEntry)
+	mux.HandleFunc("GET /api/v1/contracts/{id}/history", h.ContractHistory)
+	mux.HandleFunc("POST /api/v1/contracts/{id}/history/{revision}/revert", h.RevertContract)
 	mux.HandleFunc("GET /api/v1/summary", h.Summary)
+	mux.HandleFunc("GET /api/v1/activity", h.Activity)
 
 	// Purchase routes
 	mux.HandleFunc("GET /api/v1/categories/{id}/purchases", h.ListPurchasesByCategory)
 	mux.HandleFunc("POST /api/v1/categories/{id}/purchases", h.CreatePurchaseInCategory)
 	mux.HandleFunc("GET /api/v1/purchases/summary", h.PurchaseSummary)
+	mux.HandleFunc("POST /api/v1/purchases/import/csv", h.ImportPurchasesCSV)
+	mux.HandleFunc("GET /api/v1/purchases/export/csv", h.ExportPurchasesCSV)
 	mux.HandleFunc("GET /api/v1/purchases", h.ListPurchases)
 	mux.HandleFunc("GET /api/v1/purchases/{id}", h.GetPurchase)
 	mux.HandleFunc("PUT /api/v1/purchases/{id}", h.UpdatePurchase)
 	mux.HandleFunc("DELETE /api/v1/purchases/{id}", h.DeletePurchase)
+	mux.HandleFunc("GET /api/v1/purchases/{id}/history", h.PurchaseHistory)
+	mux.HandleFunc("POST /api/v1/purchases/{id}/history/{revision}/revert", h.RevertPurchase)
+
+	// Vehicle routes
+	mux.HandleFunc("GET /api/v1/vehicles", h.ListVehicles)
+	mux.HandleFunc("POST /api/v1/vehicles", h.CreateVehicle)
+	mux.HandleFunc("GET /api/v1/vehicles/{id}", h.GetVehicle)
+	mux.HandleFunc("PUT /api/v1/vehicles/{id}", h.UpdateVehicle)
+	mux.HandleFunc("DELETE /api/v1/vehicles/{id}", h.DeleteVehicle)
+	mux.HandleFunc("GET /api/v1/vehicles/{id}/summary", h.VehicleSummary)
+	mux.HandleFunc("GET /api/v1/vehicles/{id}/costs", h.ListCostEntries)
+	mux.HandleFunc("POST /api/v1/vehicles/{id}/costs", h.CreateCostEntry)
+	mux.HandleFunc("POST /api/v1/vehicles/{id}/costs/import/csv", h.ImportCostEntriesCSV)
+	mux.HandleFunc("GET /api/v1/vehicles/{id}/costs/export/csv", h.ExportCostEntriesCSV)
+	mux.HandleFunc("GET /api/v1/vehicles/{id}/history", h.VehicleHistory)
+	mux.HandleFunc("POST /api/v1/vehicles/{id}/history/{revision}/revert", h.RevertVehicle)
+	mux.HandleFunc("GET /api/v1/costs/{id}", h.GetCostEntry)
+	mux.HandleFunc("PUT /api/v1/costs/{id}", h.UpdateCostEntry)
+	mux.HandleFunc("DELETE /api/v1/costs/{id}", h.DeleteCostEntry)
+	mux.HandleFunc("GET /api/v1/costs/{id}/history", h.CostEntryHistory)
+	mux.HandleFunc("POST /api/v1/costs/{id}/history/{revision}/revert", h.RevertCostEntry)
+	mux.HandleFunc("GET /api/v1/trash", h.ListTrash)
+	mux.HandleFunc("POST /api/v1/trash/{type}/{id}/restore", h.RestoreFromTrash)
+	mux.HandleFunc("DELETE /api/v1/trash/{type}/{id}", h.PurgeFromTrash)
+
+	// Account archive routes
+	mux.HandleFunc("GET /api/v1/export", h.ExportAccount)
+	mux.HandleFunc("POST /api/v1/restore", h.RestoreAccount)
 
 	// Inject test user into context (integration tests skip auth middleware)
 	wrapped := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := middleware.SetUserID(r.Context(), testUserID)
+		ctx = middleware.SetWorkspace(ctx, model.WorkspaceMember{WorkspaceID: testWorkspaceID, UserID: testUserID, Role: model.WorkspaceRoleOwner})
 		mux.ServeHTTP(w, r.WithContext(ctx))
 	})
 
@@ -145,12 +194,12 @@ func TestIntegration_FullCRUDFlow(t *testing.T) {
 
 	// Create contract in category
 	conBody := map[string]any{
-		"name":                    "Phone Plan",
-		"startDate":               "2025-01-01",
-		"minimumDurationMonths":   24,
-		"extensionDurationMonths": 12,
-		"noticePeriodMonths":      3,
-		"company":                 "ACME Telecom",
+		"name":              "Phone Plan",
+		"startDate":         "2025-01-01",
+		"minimumDuration":   24,
+		"extensionDuration": 12,
+		"noticePeriod":      3,
+		"company":           "ACME Telecom",
 	}
 	resp = doJSON(t, "POST", base+"/api/v1/categories/"+cat.ID.String()+"/contracts", conBody)
 	expectStatus(t, resp, 201)
@@ -312,11 +361,11 @@ func TestIntegration_UpcomingRenewals(t *testing.T) {
 	cat := decode[model.Category](t, resp)
 
 	conBody := map[string]any{
-		"name":                    "Phone",
-		"startDate":               "2025-01-01",
-		"minimumDurationMonths":   12,
-		"extensionDurationMonths": 12,
-		"noticePeriodMonths":      3,
+		"name":              "Phone",
+		"startDate":         "2025-01-01",
+		"minimumDuration":   12,
+		"extensionDuration": 12,
+		"noticePeriod":      3,
 	}
 	resp = doJSON(t, "POST", base+"/api/v1/categories/"+cat.ID.String()+"/contracts", conBody)
 	expectStatus(t, resp, 201)
@@ -349,11 +398,11 @@ func TestIntegration_ContractResponse_HasComputedFields(t *testing.T) {
 	cat := decode[model.Category](t, resp)
 
 	conBody := map[string]any{
-		"name":                    "Test Contract",
-		"startDate":               "2025-01-01",
-		"minimumDurationMonths":   24,
-		"extensionDurationMonths": 12,
-		"noticePeriodMonths":      3,
+		"name":              "Test Contract",
+		"startDate":         "2025-01-01",
+		"minimumDuration":   24,
+		"extensionDuration": 12,
+		"noticePeriod":      3,
 	}
 	resp = doJSON(t, "POST", base+"/api/v1/categories/"+cat.ID.String()+"/contracts", conBody)
 	expectStatus(t, resp, 201)
@@ -481,3 +530,340 @@ func TestIntegration_PurchaseCRUDFlow(t *testing.T) {
 	expectStatus(t, resp, 404)
 	resp.Body.Close()
 }
+
+func TestIntegration_ExportRestore(t *testing.T) {
+	srv := setupServer(t)
+	defer srv.Close()
+	base := srv.URL
+
+	resp := doJSON(t, "POST", base+"/api/v1/modules/contracts/categories", map[string]string{"name": "Telecom"})
+	expectStatus(t, resp, 201)
+	conCat := decode[model.Category](t, resp)
+	resp = doJSON(t, "POST", base+"/api/v1/categories/"+conCat.ID.String()+"/contracts", map[string]any{
+		"name": "Internet", "startDate": "2025-01-01", "price": 39.99, "billingInterval": "monthly",
+	})
+	expectStatus(t, resp, 201)
+	con := decode[model.Contract](t, resp)
+	resp = doJSON(t, "POST", base+"/api/v1/contracts/"+con.ID.String()+"/prices", map[string]any{
+		"price": 44.99, "effectiveDate": "2025-07-01",
+	})
+	expectStatus(t, resp, 201)
+	resp.Body.Close()
+
+	resp = doJSON(t, "POST", base+"/api/v1/modules/purchases/categories", map[string]string{"name": "PC Hardware"})
+	expectStatus(t, resp, 201)
+	purCat := decode[model.Category](t, resp)
+	resp = doJSON(t, "POST", base+"/api/v1/categories/"+purCat.ID.String()+"/purchases", map[string]any{"itemName": "Monitor"})
+	expectStatus(t, resp, 201)
+	resp.Body.Close()
+
+	// Export
+	resp = doJSON(t, "GET", base+"/api/v1/export", nil)
+	expectStatus(t, resp, 200)
+	if cd := resp.Header.Get("Content-Disposition"); cd == "" {
+		t.Error("expected Content-Disposition header")
+	}
+	exported, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("reading export: %v", err)
+	}
+
+	restore := func(mode string) map[string]int {
+		t.Helper()
+		resp, err := http.Post(base+"/api/v1/restore?mode="+mode, "application/json", bytes.NewReader(exported))
+		if err != nil {
+			t.Fatalf("restore: %v", err)
+		}
+		expectStatus(t, resp, 200)
+		return decode[map[string]int](t, resp)
+	}
+
+	// Merge duplicates everything under new IDs. The contract has its initial
+	// price plus the later change.
+	res := restore("merge")
+	if res["categories"] != 2 || res["contracts"] != 1 || res["priceEntries"] != 2 || res["purchases"] != 1 {
+		t.Errorf("merge result = %v", res)
+	}
+	resp = doJSON(t, "GET", base+"/api/v1/contracts", nil)
+	expectStatus(t, resp, 200)
+	if contracts := decode[[]model.Contract](t, resp); len(contracts) != 2 {
+		t.Fatalf("expected 2 contracts after merge, got %d", len(contracts))
+	}
+
+	// Replace leaves exactly the archive's contents.
+	restore("replace")
+	resp = doJSON(t, "GET", base+"/api/v1/contracts", nil)
+	expectStatus(t, resp, 200)
+	contracts := decode[[]model.Contract](t, resp)
+	if len(contracts) != 1 {
+		t.Fatalf("expected 1 contract after replace, got %d", len(contracts))
+	}
+	if contracts[0].ID == con.ID {
+		t.Error("restored contract should get a new ID")
+	}
+	resp = doJSON(t, "GET", base+"/api/v1/contracts/"+contracts[0].ID.String()+"/prices", nil)
+	expectStatus(t, resp, 200)
+	if prices := decode[[]model.PriceEntry](t, resp); len(prices) != 2 {
+		t.Errorf("expected 2 price entries, got %d", len(prices))
+	}
+	resp = doJSON(t, "GET", base+"/api/v1/purchases", nil)
+	expectStatus(t, resp, 200)
+	if purchases := decode[[]model.Purchase](t, resp); len(purchases) != 1 {
+		t.Errorf("expected 1 purchase after replace, got %d", len(purchases))
+	}
+}
+
+func uploadCSV(t *testing.T, url string, data string, fields map[string]string) *http.Response {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	fw, _ := mw.CreateFormFile("file", "import.csv")
+	fw.Write([]byte(data))
+	mw.Close()
+
+	resp, err := http.Post(url, mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatalf("POST %s: %v", url, err)
+	}
+	return resp
+}
+
+func TestIntegration_PurchaseCSVRoundTrip(t *testing.T) {
+	srv := setupServer(t)
+	defer srv.Close()
+	base := srv.URL
+
+	resp := uploadCSV(t, base+"/api/v1/purchases/import/csv",
+		"Category\tItem Name\tBrand\tPrice\tPurchase Date\n"+
+			"Electronics\tMonitor\tDell\t1.299,00\t03.02.2025\n"+
+			"Electronics\t\tDell\t10\t03.02.2025\n",
+		map[string]string{"delimiter": "tab", "decimal": ","})
+	expectStatus(t, resp, 200)
+	result := decode[struct {
+		Created int `json:"created"`
+		Errors  []struct {
+			Row int `json:"row"`
+		} `json:"errors"`
+	}](t, resp)
+	if result.Created != 1 || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	resp = doJSON(t, "GET", base+"/api/v1/purchases", nil)
+	expectStatus(t, resp, 200)
+	purchases := decode[[]model.Purchase](t, resp)
+	if len(purchases) != 1 || *purchases[0].Price != 1299 || purchases[0].PurchaseDate != "2025-02-03" {
+		t.Fatalf("purchases = %+v", purchases)
+	}
+
+	resp = doJSON(t, "GET", base+"/api/v1/purchases/export/csv", nil)
+	expectStatus(t, resp, 200)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	want := "category,type,itemName,brand,articleNumber,dealer,price,purchaseDate,descriptionUrl,invoiceUrl,handbookUrl,consumables,comments\n" +
+		"Electronics,,Monitor,Dell,,,1299,2025-02-03,,,,,\n"
+	if string(body) != want {
+		t.Errorf("export = %q, want %q", body, want)
+	}
+}
+
+func TestIntegration_PurchaseCSVUpsert(t *testing.T) {
+	srv := setupServer(t)
+	defer srv.Close()
+	base := srv.URL
+
+	type result struct {
+		Created int `json:"created"`
+		Updated int `json:"updated"`
+		Skipped int `json:"skipped"`
+	}
+	header := "category,itemName,brand,articleNumber,price,purchaseDate\n"
+
+	resp := uploadCSV(t, base+"/api/v1/purchases/import/csv",
+		header+"Tools,Drill,Bosch,GSR-12,99,2025-01-10\n", nil)
+	expectStatus(t, resp, 200)
+	if got := decode[result](t, resp); got.Created != 1 {
+		t.Fatalf("first import = %+v", got)
+	}
+
+	// Same brand, article number and date: updated in place.
+	resp = uploadCSV(t, base+"/api/v1/purchases/import/csv",
+		header+"Tools,Drill,bosch,gsr-12,89,2025-01-10\n", nil)
+	expectStatus(t, resp, 200)
+	if got := decode[result](t, resp); got.Updated != 1 || got.Created != 0 {
+		t.Fatalf("second import = %+v", got)
+	}
+
+	resp = uploadCSV(t, base+"/api/v1/purchases/import/csv",
+		header+"Tools,Drill,Bosch,GSR-12,79,2025-01-10\n", map[string]string{"onDuplicate": "skip"})
+	expectStatus(t, resp, 200)
+	if got := decode[result](t, resp); got.Skipped != 1 {
+		t.Fatalf("third import = %+v", got)
+	}
+
+	resp = doJSON(t, "GET", base+"/api/v1/purchases", nil)
+	expectStatus(t, resp, 200)
+	purchases := decode[[]model.Purchase](t, resp)
+	if len(purchases) != 1 || *purchases[0].Price != 89 {
+		t.Fatalf("purchases = %+v", purchases)
+	}
+}
+
+func TestIntegration_CostEntryCSVRoundTrip(t *testing.T) {
+	srv := setupServer(t)
+	defer srv.Close()
+	base := srv.URL
+
+	resp := doJSON(t, "POST", base+"/api/v1/vehicles", map[string]any{"name": "Family Car"})
+	expectStatus(t, resp, 201)
+	veh := decode[model.Vehicle](t, resp)
+
+	resp = uploadCSV(t, base+"/api/v1/vehicles/"+veh.ID.String()+"/costs/import/csv",
+		"Date;Type;Vendor;Amount;Mileage\n"+
+			"01.03.2025;fuel;Shell;65,40;12.345\n"+
+			"15.03.2025;car wash;;10;\n", nil)
+	expectStatus(t, resp, 200)
+	result := decode[map[string]any](t, resp)
+	if result["created"] != float64(1) {
+		t.Fatalf("result = %v", result)
+	}
+
+	resp = doJSON(t, "GET", base+"/api/v1/vehicles/"+veh.ID.String()+"/costs/export/csv", nil)
+	expectStatus(t, resp, 200)
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, "costs-Family-Car-") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	want := "date,type,description,vendor,amount,mileage,comments\n2025-03-01,fuel,,Shell,65.4,12345,\n"
+	if string(body) != want {
+		t.Errorf("export = %q, want %q", body, want)
+	}
+
+	resp = uploadCSV(t, base+"/api/v1/vehicles/00000000-0000-0000-0000-000000000000/costs/import/csv", "date\n", nil)
+	expectStatus(t, resp, 404)
+	resp.Body.Close()
+}
+
+func TestIntegration_HistoryAndRevert(t *testing.T) {
+	srv := setupServer(t)
+	defer srv.Close()
+	base := srv.URL
+
+	resp := doJSON(t, "POST", base+"/api/v1/modules/contracts/categories", map[string]string{"name": "Mobile"})
+	expectStatus(t, resp, 201)
+	cat := decode[model.Category](t, resp)
+
+	conBody := map[string]any{"name": "Phone", "startDate": "2025-01-01"}
+	resp = doJSON(t, "POST", base+"/api/v1/categories/"+cat.ID.String()+"/contracts", conBody)
+	expectStatus(t, resp, 201)
+	con := decode[model.Contract](t, resp)
+	conURL := base + "/api/v1/contracts/" + con.ID.String()
+
+	conBody["name"] = "Phone XL"
+	resp = doJSON(t, "PUT", conURL, conBody)
+	expectStatus(t, resp, 200)
+	resp.Body.Close()
+
+	// History is newest first and attributed to the user
+	resp = doJSON(t, "GET", conURL+"/history", nil)
+	expectStatus(t, resp, 200)
+	history := decode[[]model.AuditEntry](t, resp)
+	if len(history) != 2 || history[0].Action != model.AuditUpdate || history[1].Action != model.AuditCreate {
+		t.Fatalf("history = %+v", history)
+	}
+	if history[0].ActorID != testUserID {
+		t.Errorf("ActorID = %q, want %q", history[0].ActorID, testUserID)
+	}
+	if c := history[0].Changes; len(c) != 1 || c[0].Field != "name" || string(c[0].Before) != `"Phone"` {
+		t.Errorf("changes = %+v", c)
+	}
+	created := history[1].ID
+
+	// A contract in the trash has to be restored from there
+	resp = doJSON(t, "DELETE", conURL, nil)
+	expectStatus(t, resp, 204)
+	resp.Body.Close()
+
+	resp = doJSON(t, "POST", conURL+"/history/"+created.String()+"/revert", nil)
+	expectStatus(t, resp, 409)
+	resp.Body.Close()
+
+	// Reverting a purged contract brings it back as it was
+	resp = doJSON(t, "DELETE", base+"/api/v1/trash/contract/"+con.ID.String(), nil)
+	expectStatus(t, resp, 204)
+	resp.Body.Close()
+
+	resp = doJSON(t, "POST", conURL+"/history/"+created.String()+"/revert", nil)
+	expectStatus(t, resp, 200)
+	resp.Body.Close()
+
+	resp = doJSON(t, "GET", conURL, nil)
+	expectStatus(t, resp, 200)
+	if got := decode[model.Contract](t, resp); got.Name != "Phone" || got.CategoryID != cat.ID {
+		t.Errorf("reverted contract = %+v", got)
+	}
+
+	resp = doJSON(t, "GET", conURL+"/history", nil)
+	expectStatus(t, resp, 200)
+	history = decode[[]model.AuditEntry](t, resp)
+	if len(history) != 5 || history[0].Action != model.AuditCreate || history[1].Action != model.AuditDelete || history[2].Action != model.AuditTrash {
+		t.Fatalf("history after revert = %+v", history)
+	}
+
+	resp = doJSON(t, "POST", conURL+"/history/"+history[1].ID.String()+"/revert", nil)
+	expectStatus(t, resp, 400)
+	resp.Body.Close()
+	resp = doJSON(t, "POST", conURL+"/history/"+history[2].ID.String()+"/revert", nil)
+	expectStatus(t, resp, 400)
+	resp.Body.Close()
+
+	resp = doJSON(t, "POST", conURL+"/history/"+uuid.NewString()+"/revert", nil)
+	expectStatus(t, resp, 404)
+	resp.Body.Close()
+
+	// Without its category the contract cannot be restored
+	resp = doJSON(t, "DELETE", base+"/api/v1/modules/contracts/categories/"+cat.ID.String(), nil)
+	expectStatus(t, resp, 204)
+	resp.Body.Close()
+	resp = doJSON(t, "DELETE", base+"/api/v1/trash/category/"+cat.ID.String(), nil)
+	expectStatus(t, resp, 204)
+	resp.Body.Close()
+
+	resp = doJSON(t, "POST", conURL+"/history/"+created.String()+"/revert", nil)
+	expectStatus(t, resp, 409)
+	resp.Body.Close()
+}
+
+func TestIntegration_Activity(t *testing.T) {
+	srv := setupServer(t)
+	defer srv.Close()
+	base := srv.URL
+
+	for _, name := range []string{"A", "B", "C"} {
+		resp := doJSON(t, "POST", base+"/api/v1/modules/purchases/categories", map[string]string{"name": name})
+		expectStatus(t, resp, 201)
+		resp.Body.Close()
+	}
+
+	resp := doJSON(t, "GET", base+"/api/v1/activity?limit=2", nil)
+	expectStatus(t, resp, 200)
+	page := decode[[]model.AuditEntry](t, resp)
+	if len(page) != 2 || page[0].EntityType != model.EntityCategory || page[0].Module != "purchases" {
+		t.Fatalf("first page = %+v", page)
+	}
+
+	resp = doJSON(t, "GET", base+"/api/v1/activity?before="+page[1].ID.String(), nil)
+	expectStatus(t, resp, 200)
+	if rest := decode[[]model.AuditEntry](t, resp); len(rest) != 1 {
+		t.Errorf("next page = %+v", rest)
+	}
+
+	resp = doJSON(t, "GET", base+"/api/v1/activity?limit=0", nil)
+	expectStatus(t, resp, 400)
+	resp.Body.Close()
+}
@@ -13,12 +13,15 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tobi/contracts/backend/internal/backup"
 	"github.com/tobi/contracts/backend/internal/config"
 	"github.com/tobi/contracts/backend/internal/email"
 	"github.com/tobi/contracts/backend/internal/handler"
 	"github.com/tobi/contracts/backend/internal/middleware"
+	"github.com/tobi/contracts/backend/internal/oidc"
 	"github.com/tobi/contracts/backend/internal/reminder"
 	"github.com/tobi/contracts/backend/internal/store"
+	"github.com/tobi/contracts/backend/internal/trash"
 	"github.com/tobi/contracts/backend/internal/version"
 )
 
@@ -46,57 +49,157 @@ func (s *Server) Run() error {
 		s.logger.Info("SMTP not configured, reminder scheduler disabled")
 	}
 
+	trash.New(s.store, s.cfg.TrashRetention, s.logger).Start(shutdownCtx, time.Hour)
+
 	h := handler.New(s.store, s.logger, jwtSecret, emailClient)
+	h.SetBaseURL(s.cfg.BaseURL)
+	h.SetRegistration(s.cfg.Registration)
+	if s.cfg.OIDCIssuer != "" {
+		h.SetOIDC(oidc.New(oidc.Config{
+			Issuer:       s.cfg.OIDCIssuer,
+			ClientID:     s.cfg.OIDCClientID,
+			ClientSecret: s.cfg.OIDCClientSecret,
+			RedirectURL:  strings.TrimSuffix(s.cfg.BaseURL, "/") + handler.OIDCCallbackPath,
+		}), s.cfg.OIDCAllowedDomains)
+	}
+	if s.cfg.BackupDir != "" {
+		if src, ok := s.store.(backup.Source); ok {
+			backups := backup.New(src, s.cfg.BackupDir, s.cfg.BackupKeep, s.logger)
+			backups.Start(shutdownCtx, s.cfg.BackupInterval)
+			h.SetBackups(backups)
+		} else {
+			s.logger.Warn("storage backend does not support backups, BACKUP_DIR ignored")
+		}
+	}
 
-	// Protected API routes (require auth)
-	apiMux := http.NewServeMux()
+	// Workspace data routes (require auth and operate on the active workspace)
+	dataMux := http.NewServeMux()
 
 	// Module-scoped category routes
-	apiMux.HandleFunc("GET /api/v1/modules/{module}/categories", h.ListCategories)
-	apiMux.HandleFunc("POST /api/v1/modules/{module}/categories", h.CreateCategory)
-	apiMux.HandleFunc("GET /api/v1/modules/{module}/categories/{id}", h.GetCategory)
-	apiMux.HandleFunc("PUT /api/v1/modules/{module}/categories/{id}", h.UpdateCategory)
-	apiMux.HandleFunc("DELETE /api/v1/modules/{module}/categories/{id}", h.DeleteCategory)
+	dataMux.HandleFunc("GET /api/v1/modules/{module}/categories", h.ListCategories)
+	dataMux.HandleFunc("POST /api/v1/modules/{module}/categories", h.CreateCategory)
+	dataMux.HandleFunc("GET /api/v1/modules/{module}/categories/{id}", h.GetCategory)
+	dataMux.HandleFunc("PUT /api/v1/modules/{module}/categories/{id}", h.UpdateCategory)
+	dataMux.HandleFunc("DELETE /api/v1/modules/{module}/categories/{id}", h.DeleteCategory)
+	dataMux.HandleFunc("GET /api/v1/modules/{module}/categories/{id}/history", h.CategoryHistory)
+	dataMux.HandleFunc("POST /api/v1/modules/{module}/categories/{id}/history/{revision}/revert", h.RevertCategory)
 
 	// Contract routes
-	apiMux.HandleFunc("GET /api/v1/categories/{id}/contracts", h.ListContractsByCategory)
-	apiMux.HandleFunc("POST /api/v1/categories/{id}/contracts", h.CreateContractInCategory)
-	apiMux.HandleFunc("POST /api/v1/contracts/import", h.ImportContracts)
-	apiMux.HandleFunc("GET /api/v1/contracts/upcoming-renewals", h.UpcomingRenewals)
-	apiMux.HandleFunc("GET /api/v1/contracts", h.ListContracts)
-	apiMux.HandleFunc("GET /api/v1/contracts/{id}", h.GetContract)
-	apiMux.HandleFunc("PUT /api/v1/contracts/{id}", h.UpdateContract)
-	apiMux.HandleFunc("DELETE /api/v1/contracts/{id}", h.DeleteContract)
-	apiMux.HandleFunc("GET /api/v1/summary", h.Summary)
+	dataMux.HandleFunc("GET /api/v1/categories/{id}/contracts", h.ListContractsByCategory)
+	dataMux.HandleFunc("POST /api/v1/categories/{id}/contracts", h.CreateContractInCategory)
+	dataMux.HandleFunc("POST /api/v1/contracts/import", h.ImportContracts)
+	dataMux.HandleFunc("POST /api/v1/contracts/import/preview", h.PreviewContractImport)
+	dataMux.HandleFunc("POST /api/v1/contracts/import/csv", h.ImportContractsCSV)
+	dataMux.HandleFunc("GET /api/v1/contracts/export/csv", h.ExportContractsCSV)
+	dataMux.HandleFunc("GET /api/v1/contracts/upcoming-renewals", h.UpcomingRenewals)
+	dataMux.HandleFunc("GET /api/v1/contracts", h.ListContracts)
+	dataMux.HandleFunc("GET /api/v1/contracts/{id}", h.GetContract)
+	dataMux.HandleFunc("PUT /api/v1/contracts/{id}", h.UpdateContract)
+	dataMux.HandleFunc("DELETE /api/v1/contracts/{id}", h.DeleteContract)
+	dataMux.HandleFunc("POST /api/v1/contracts/{id}/transition", h.TransitionContract)
+	dataMux.HandleFunc("GET /api/v1/contracts/{id}/timeline", h.ContractTimeline)
+	dataMux.HandleFunc("GET /api/v1/contracts/{id}/prices", h.ListPriceEntries)
+	dataMux.HandleFunc("POST /api/v1/contracts/{id}/prices", h.CreatePriceEntry)
+	dataMux.HandleFunc("DELETE /api/v1/contracts/{id}/prices/{priceId}", h.DeletePriceEntry)
+	dataMux.HandleFunc("GET /api/v1/contracts/{id}/history", h.ContractHistory)
+	dataMux.HandleFunc("POST /api/v1/contracts/{id}/history/{revision}/revert", h.RevertContract)
+	dataMux.HandleFunc("GET /api/v1/summary", h.Summary)
+	dataMux.HandleFunc("GET /api/v1/activity", h.Activity)
 
 	// Purchase routes
-	apiMux.HandleFunc("GET /api/v1/categories/{id}/purchases", h.ListPurchasesByCategory)
-	apiMux.HandleFunc("POST /api/v1/categories/{id}/purchases", h.CreatePurchaseInCategory)
-	apiMux.HandleFunc("GET /api/v1/purchases/summary", h.PurchaseSummary)
-	apiMux.HandleFunc("GET /api/v1/purchases", h.ListPurchases)
-	apiMux.HandleFunc("GET /api/v1/purchases/{id}", h.GetPurchase)
-	apiMux.HandleFunc("PUT /api/v1/purchases/{id}", h.UpdatePurchase)
-	apiMux.HandleFunc("DELETE /api/v1/purchases/{id}", h.DeletePurchase)
+	dataMux.HandleFunc("GET /api/v1/categories/{id}/purchases", h.ListPurchasesByCategory)
+	dataMux.HandleFunc("POST /api/v1/categories/{id}/purchases", h.CreatePurchaseInCategory)
+	dataMux.HandleFunc("GET /api/v1/purchases/summary", h.PurchaseSummary)
+	dataMux.HandleFunc("POST /api/v1/purchases/import/csv", h.ImportPurchasesCSV)
+	dataMux.HandleFunc("GET /api/v1/purchases/export/csv", h.ExportPurchasesCSV)
+	dataMux.HandleFunc("GET /api/v1/purchases", h.ListPurchases)
+	dataMux.HandleFunc("GET /api/v1/purchases/{id}", h.GetPurchase)
+	dataMux.HandleFunc("PUT /api/v1/purchases/{id}", h.UpdatePurchase)
+	dataMux.HandleFunc("DELETE /api/v1/purchases/{id}", h.DeletePurchase)
+	dataMux.HandleFunc("GET /api/v1/purchases/{id}/history", h.PurchaseHistory)
+	dataMux.HandleFunc("POST /api/v1/purchases/{id}/history/{revision}/revert", h.RevertPurchase)
 
 	// Vehicle routes
-	apiMux.HandleFunc("GET /api/v1/vehicles", h.ListVehicles)
-	apiMux.HandleFunc("POST /api/v1/vehicles", h.CreateVehicle)
-	apiMux.HandleFunc("GET /api/v1/vehicles/{id}", h.GetVehicle)
-	apiMux.HandleFunc("PUT /api/v1/vehicles/{id}", h.UpdateVehicle)
-	apiMux.HandleFunc("DELETE /api/v1/vehicles/{id}", h.DeleteVehicle)
-	apiMux.HandleFunc("GET /api/v1/vehicles/{id}/summary", h.VehicleSummary)
-	apiMux.HandleFunc("GET /api/v1/vehicles/{id}/costs", h.ListCostEntries)
-	apiMux.HandleFunc("POST /api/v1/vehicles/{id}/costs", h.CreateCostEntry)
-	apiMux.HandleFunc("GET /api/v1/costs/{id}", h.GetCostEntry)
-	apiMux.HandleFunc("PUT /api/v1/costs/{id}", h.UpdateCostEntry)
-	apiMux.HandleFunc("DELETE /api/v1/costs/{id}", h.DeleteCostEntry)
+	dataMux.HandleFunc("GET /api/v1/vehicles", h.ListVehicles)
+	dataMux.HandleFunc("POST /api/v1/vehicles", h.CreateVehicle)
+	dataMux.HandleFunc("GET /api/v1/vehicles/{id}", h.GetVehicle)
+	dataMux.HandleFunc("PUT /api/v1/vehicles/{id}", h.UpdateVehicle)
+	dataMux.HandleFunc("DELETE /api/v1/vehicles/{id}", h.DeleteVehicle)
+	dataMux.HandleFunc("GET /api/v1/vehicles/{id}/summary", h.VehicleSummary)
+	dataMux.HandleFunc("GET /api/v1/vehicles/{id}/costs", h.ListCostEntries)
+	dataMux.HandleFunc("POST /api/v1/vehicles/{id}/costs", h.CreateCostEntry)
+	dataMux.HandleFunc("POST /api/v1/vehicles/{id}/costs/import/csv", h.ImportCostEntriesCSV)
+	dataMux.HandleFunc("GET /api/v1/vehicles/{id}/costs/export/csv", h.ExportCostEntriesCSV)
+	dataMux.HandleFunc("GET /api/v1/vehicles/{id}/history", h.VehicleHistory)
+	dataMux.HandleFunc("POST /api/v1/vehicles/{id}/history/{revision}/revert", h.RevertVehicle)
+	dataMux.HandleFunc("GET /api/v1/costs/{id}", h.GetCostEntry)
+	dataMux.HandleFunc("PUT /api/v1/costs/{id}", h.UpdateCostEntry)
+	dataMux.HandleFunc("DELETE /api/v1/costs/{id}", h.DeleteCostEntry)
+	dataMux.HandleFunc("GET /api/v1/costs/{id}/history", h.CostEntryHistory)
+	dataMux.HandleFunc("POST /api/v1/costs/{id}/history/{revision}/revert", h.RevertCostEntry)
+
+	// Trash routes
+	dataMux.HandleFunc("GET /api/v1/trash", h.ListTrash)
+	dataMux.HandleFunc("POST /api/v1/trash/{type}/{id}/restore", h.RestoreFromTrash)
+	dataMux.HandleFunc("DELETE /api/v1/trash/{type}/{id}", h.PurgeFromTrash)
+
+	// Workspace archive routes
+	dataMux.HandleFunc("GET /api/v1/export", h.ExportAccount)
+	dataMux.HandleFunc("POST /api/v1/restore", h.RestoreAccount)
+
+	// Account routes (require auth)
+	apiMux := http.NewServeMux()
 
 	// Settings routes
 	apiMux.HandleFunc("GET /api/v1/settings", h.GetSettings)
 	apiMux.HandleFunc("PUT /api/v1/settings", h.UpdateSettings)
 	apiMux.HandleFunc("PUT /api/v1/settings/password", h.ChangePassword)
+	apiMux.HandleFunc("PUT /api/v1/settings/email", h.ChangeEmail)
+	apiMux.HandleFunc("POST /api/v1/settings/email/verification", h.ResendEmailVerification)
+	apiMux.HandleFunc("GET /api/v1/settings/calendar-feed", h.GetCalendarFeed)
+	apiMux.HandleFunc("POST /api/v1/settings/calendar-feed", h.RotateCalendarFeed)
+	apiMux.HandleFunc("DELETE /api/v1/settings/calendar-feed", h.RevokeCalendarFeed)
+	apiMux.HandleFunc("GET /api/v1/settings/2fa", h.GetTwoFactor)
+	apiMux.HandleFunc("POST /api/v1/settings/2fa", h.BeginTwoFactor)
+	apiMux.HandleFunc("POST /api/v1/settings/2fa/confirm", h.ConfirmTwoFactor)
+	apiMux.HandleFunc("DELETE /api/v1/settings/2fa", h.DisableTwoFactor)
+	apiMux.HandleFunc("GET /api/v1/settings/api-keys", h.ListAPIKeys)
+	apiMux.HandleFunc("POST /api/v1/settings/api-keys", h.CreateAPIKey)
+	apiMux.HandleFunc("DELETE /api/v1/settings/api-keys/{id}", h.DeleteAPIKey)
+
+	// Account deletion
+	apiMux.HandleFunc("DELETE /api/v1/account", h.DeleteAccount)
+
+	// Session routes
+	apiMux.HandleFunc("GET /api/v1/sessions", h.ListSessions)
+	apiMux.HandleFunc("DELETE /api/v1/sessions", h.RevokeOtherSessions)
+	apiMux.HandleFunc("DELETE /api/v1/sessions/{id}", h.RevokeSession)
+
+	// Workspace routes
+	apiMux.HandleFunc("GET /api/v1/workspaces", h.ListWorkspaces)
+	apiMux.HandleFunc("POST /api/v1/workspaces", h.CreateWorkspace)
+	apiMux.HandleFunc("PUT /api/v1/workspaces/{id}", h.UpdateWorkspace)
+	apiMux.HandleFunc("DELETE /api/v1/workspaces/{id}", h.DeleteWorkspace)
+	apiMux.HandleFunc("GET /api/v1/workspaces/{id}/members", h.ListWorkspaceMembers)
+	apiMux.HandleFunc("PUT /api/v1/workspaces/{id}/members/{userId}", h.UpdateWorkspaceMember)
+	apiMux.HandleFunc("DELETE /api/v1/workspaces/{id}/members/{userId}", h.RemoveWorkspaceMember)
+	apiMux.HandleFunc("GET /api/v1/workspaces/{id}/invitations", h.ListInvitations)
+	apiMux.HandleFunc("POST /api/v1/workspaces/{id}/invitations", h.CreateInvitation)
+	apiMux.HandleFunc("DELETE /api/v1/workspaces/{id}/invitations/{invitationId}", h.DeleteInvitation)
+	apiMux.HandleFunc("POST /api/v1/invitations/accept", h.AcceptInvitation)
+
+	// Admin routes (require an admin account)
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("GET /api/v1/admin/users", h.ListUsers)
+	adminMux.HandleFunc("PUT /api/v1/admin/users/{id}", h.UpdateUser)
+	adminMux.HandleFunc("DELETE /api/v1/admin/users/{id}", h.DeleteUser)
+	adminMux.HandleFunc("POST /api/v1/admin/users/{id}/reset-password", h.ForcePasswordReset)
+	adminMux.HandleFunc("POST /api/v1/admin/backups", h.CreateBackup)
+	apiMux.Handle("/api/v1/admin/", middleware.Admin(s.store)(adminMux))
+
+	apiMux.Handle("/api/v1/", middleware.Workspace(s.store)(dataMux))
 
-	protectedAPI := middleware.Auth(jwtSecret)(apiMux)
+	protectedAPI := middleware.Auth(jwtSecret, s.store)(apiMux)
 
 	mux := http.NewServeMux()
 
@@ -105,7 +208,17 @@ func (s *Server) Run() error {
 	mux.HandleFunc("GET /readyz", h.Readyz)
 	mux.Handle("GET /metrics", promhttp.Handler())
 	mux.HandleFunc("POST /api/v1/auth/register", h.Register)
+	mux.HandleFunc("GET /api/v1/auth/registration", h.RegistrationMode)
 	mux.HandleFunc("POST /api/v1/auth/login", h.Login)
+	mux.Handle("POST /api/v1/auth/login/2fa", middleware.RateLimit(middleware.NewLimiter(10, 15*time.Minute))(http.HandlerFunc(h.LoginTwoFactor)))
+	mux.HandleFunc("POST /api/v1/auth/refresh", h.Refresh)
+	mux.HandleFunc("GET /api/v1/auth/oidc/login", h.OIDCLogin)
+	mux.HandleFunc("GET /api/v1/auth/oidc/callback", h.OIDCCallback)
+	resetLimit := middleware.RateLimit(middleware.NewLimiter(10, 15*time.Minute))
+	mux.Handle("POST /api/v1/auth/forgot-password", resetLimit(http.HandlerFunc(h.ForgotPassword)))
+	mux.Handle("POST /api/v1/auth/reset-password", resetLimit(http.HandlerFunc(h.ResetPassword)))
+	mux.Handle("POST /api/v1/auth/verify-email", middleware.RateLimit(middleware.NewLimiter(10, 15*time.Minute))(http.HandlerFunc(h.VerifyEmail)))
+	mux.HandleFunc("GET /api/v1/calendar/{file}", h.CalendarFeed)
 	mux.HandleFunc("GET /api/version", version.Handler)
 
 	// Mount protected API routes
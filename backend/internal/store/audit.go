@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+type actorKey struct{}
+
+// WithActor returns a context whose changes the audit log attributes to
+// the user with the given ID.
+func WithActor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+func actorFrom(ctx context.Context) string {
+	id, _ := ctx.Value(actorKey{}).(string)
+	return id
+}
+
+// newAuditEntry describes the change of an entity from before to after, the
+// JSON of the entity or nil where it does not exist. ok is false for
+// updates that changed nothing worth recording.
+func newAuditEntry(ctx context.Context, entityType, module string, id uuid.UUID, before, after []byte) (entry model.AuditEntry, ok bool, err error) {
+	changes, err := model.Diff(before, after)
+	if err != nil {
+		return entry, false, err
+	}
+	action := model.AuditUpdate
+	switch {
+	case before == nil:
+		action = model.AuditCreate
+	case after == nil:
+		action = model.AuditDelete
+	case len(changes) == 0:
+		return entry, false, nil
+	default:
+		for _, c := range changes {
+			if c.Field == "deletedAt" {
+				action = model.AuditTrash
+				if c.After == nil {
+					action = model.AuditRestore
+				}
+			}
+		}
+	}
+	entryID, err := uuid.NewV7()
+	if err != nil {
+		return entry, false, err
+	}
+	return model.AuditEntry{
+		ID:         entryID,
+		ActorID:    actorFrom(ctx),
+		At:         time.Now().UTC(),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   id,
+		Module:     module,
+		Changes:    changes,
+	}, true, nil
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Backups of an encrypted database are encrypted with the same key. The
+// stream is cut into chunks sealed with AES-GCM, so it can be written and
+// read without holding it in memory:
+//
+//	magic | chunk | chunk | ...
+//	chunk = length (uint32, big endian) | nonce | sealed data
+//
+// Each chunk is sealed with its index and whether it is the last one as
+// additional data, so chunks cannot be reordered, dropped or cut off
+// without decryption failing.
+const (
+	backupMagic     = "CTRBAK\x00\x01"
+	backupChunkSize = 64 << 10
+)
+
+var errBackupTruncated = errors.New("backup is truncated")
+
+func newBackupCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("backup cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkData(index uint64, last bool) []byte {
+	ad := binary.BigEndian.AppendUint64(nil, index)
+	if last {
+		return append(ad, 1)
+	}
+	return append(ad, 0)
+}
+
+// encryptWriter encrypts what is written to it onto w. Close writes the
+// last chunk, without which the backup cannot be read.
+type encryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	buf   []byte
+	index uint64
+}
+
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	aead, err := newBackupCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, backupMagic); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, backupChunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows, so the last
+		// chunk is always the one Close seals.
+		if len(e.buf) == backupChunkSize {
+			if err := e.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		k := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+k]
+		p = p[k:]
+	}
+	return n, nil
+}
+
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(last bool) error {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(e.buf)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := e.aead.Seal(nonce, nonce, e.buf, chunkData(e.index, last))
+	if _, err := e.w.Write(binary.BigEndian.AppendUint32(nil, uint32(len(sealed)))); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(sealed); err != nil {
+		return err
+	}
+	e.index++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// decryptReader reads the backup written by an encryptWriter from r, which
+// is positioned after the magic.
+type decryptReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	buf   []byte
+	index uint64
+	done  bool
+}
+
+func newDecryptReader(r io.Reader, key []byte) (*decryptReader, error) {
+	aead, err := newBackupCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	var length [4]byte
+	if _, err := io.ReadFull(d.r, length[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return errBackupTruncated
+		}
+		return err
+	}
+	n := int(binary.BigEndian.Uint32(length[:]))
+	nonceSize := d.aead.NonceSize()
+	if n < nonceSize+d.aead.Overhead() || n > nonceSize+backupChunkSize+d.aead.Overhead() {
+		return errors.New("backup is damaged")
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return errBackupTruncated
+		}
+		return err
+	}
+
+	nonce, data := sealed[:nonceSize], sealed[nonceSize:]
+	for _, last := range []bool{false, true} {
+		if plain, err := d.aead.Open(nil, nonce, data, chunkData(d.index, last)); err == nil {
+			d.buf, d.done = plain, last
+			d.index++
+			return nil
+		}
+	}
+	return fmt.Errorf("decrypting backup: %w, or the file is damaged", ErrWrongKey)
+}
+
+// backupReader returns a reader of the plain backup in r, decrypting it with
+// key if it is encrypted. Unencrypted backups are read as they are.
+func backupReader(r io.Reader, key []byte) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(backupMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if string(magic) != backupMagic {
+		return br, nil
+	}
+	if len(key) == 0 {
+		return nil, errors.New("backup is encrypted; set the key it was written with")
+	}
+	if _, err := br.Discard(len(backupMagic)); err != nil {
+		return nil, err
+	}
+	return newDecryptReader(br, key)
+}
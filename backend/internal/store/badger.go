@@ -1,11 +1,17 @@
 package store
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
@@ -17,10 +23,18 @@ import (
 var (
 	ErrNotFound = errors.New("not found")
 	ErrConflict = errors.New("conflict")
+	// ErrTokenReused means a refresh token was presented after it had been
+	// rotated already.
+	ErrTokenReused = errors.New("refresh token reused")
+	// ErrLockedOut means attempts for a key are refused until it is
+	// unlocked.
+	ErrLockedOut = errors.New("locked out")
 )
 
 type BadgerStore struct {
-	db     *badger.DB
+	db *badger.DB
+	// key is the encryption key of db, which Backup also encrypts with.
+	key    []byte
 	logger *slog.Logger
 	done   chan struct{}
 }
@@ -34,14 +48,41 @@ func (l *badgerLogger) Warningf(f string, v ...interface{}) { l.logger.Warn(fmt.
 func (l *badgerLogger) Infof(f string, v ...interface{})    { l.logger.Info(fmt.Sprintf(f, v...)) }
 func (l *badgerLogger) Debugf(f string, v ...interface{})   { l.logger.Debug(fmt.Sprintf(f, v...)) }
 
-func NewBadgerStore(path string, logger *slog.Logger) (*BadgerStore, error) {
+// ErrWrongKey is returned when a Badger database is opened with a different
+// encryption key than it was written with, including a key for an
+// unencrypted database or none for an encrypted one.
+var ErrWrongKey = errors.New("encryption key does not match the database")
+
+// badgerOptions returns the options for the database at path. A non-empty
+// key of 16, 24 or 32 bytes encrypts it with AES; Badger then rotates the
+// data keys it derives on its own.
+func badgerOptions(path string, key []byte, logger *slog.Logger) badger.Options {
 	opts := badger.DefaultOptions(path).
 		WithLogger(&badgerLogger{logger: logger.With("component", "badger")})
+	if len(key) > 0 {
+		opts = opts.WithEncryptionKey(key).WithIndexCacheSize(64 << 20)
+	}
+	return opts
+}
 
-	db, err := badger.Open(opts)
+func openBadger(path string, key []byte, logger *slog.Logger) (*badger.DB, error) {
+	db, err := badger.Open(badgerOptions(path, key, logger))
+	if errors.Is(err, badger.ErrEncryptionKeyMismatch) {
+		return nil, fmt.Errorf("opening badger db at %s: %w", path, ErrWrongKey)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("opening badger db: %w", err)
 	}
+	return db, nil
+}
+
+// NewBadgerStore opens the database at path, encrypted with key unless key
+// is empty, and migrates it.
+func NewBadgerStore(path string, key []byte, logger *slog.Logger) (*BadgerStore, error) {
+	db, err := openBadger(path, key, logger)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := migration.RunAll(db, logger, migration.All); err != nil {
 		db.Close()
@@ -50,6 +91,7 @@ func NewBadgerStore(path string, logger *slog.Logger) (*BadgerStore, error) {
 
 	s := &BadgerStore{
 		db:     db,
+		key:    key,
 		logger: logger,
 		done:   make(chan struct{}),
 	}
@@ -83,6 +125,123 @@ func (s *BadgerStore) Healthy() error {
 	})
 }
 
+// Backup streams a full snapshot of the database to w while it stays open
+// for reads and writes. The snapshot is taken at the moment Backup starts.
+// The snapshot of an encrypted database is encrypted with the same key.
+func (s *BadgerStore) Backup(w io.Writer) error {
+	if len(s.key) == 0 {
+		_, err := s.db.Backup(w, 0)
+		return err
+	}
+	ew, err := newEncryptWriter(w, s.key)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Backup(ew, 0); err != nil {
+		return err
+	}
+	return ew.Close()
+}
+
+// RestoreBadger loads a backup written by BadgerStore.Backup into the
+// database at path, encrypting it with key unless key is empty. An
+// encrypted backup is decrypted with key, so it must be the key the backup
+// was written with. It refuses to write into a database that already holds
+// data, so a restore never mixes two datasets. Migrations run the next time
+// the store is opened.
+func RestoreBadger(path string, key []byte, r io.Reader, logger *slog.Logger) error {
+	r, err := backupReader(r, key)
+	if err != nil {
+		return err
+	}
+	db, err := openBadger(path, key, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	empty := true
+	err = db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{})
+		defer it.Close()
+		it.Rewind()
+		empty = !it.Valid()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fmt.Errorf("database at %s is not empty", path)
+	}
+
+	if err := db.Load(r, 256); err != nil {
+		return fmt.Errorf("loading backup: %w", err)
+	}
+	return nil
+}
+
+// EncryptBadger copies the unencrypted database at src into the empty
+// directory dst, encrypted with key. src is left as it is.
+func EncryptBadger(src, dst string, key []byte, logger *slog.Logger) error {
+	db, err := openBadger(src, nil, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.Backup(pw, 0)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	err = RestoreBadger(dst, key, pr, logger)
+	// Unblock the backup if the restore gave up early.
+	pr.CloseWithError(io.ErrClosedPipe)
+	if backupErr := <-done; err == nil && backupErr != nil {
+		err = fmt.Errorf("reading %s: %w", src, backupErr)
+	}
+	return err
+}
+
+// RotateBadgerKey re-encrypts the key registry of the database at path from
+// oldKey to newKey. The data itself is encrypted with data keys kept in the
+// registry, so it is not rewritten. The database must not be open elsewhere.
+func RotateBadgerKey(path string, oldKey, newKey []byte, logger *slog.Logger) error {
+	if len(oldKey) == 0 {
+		return errors.New("database is not encrypted; encrypt it into a new directory instead")
+	}
+	if _, err := os.Stat(filepath.Join(path, badger.KeyRegistryFileName)); err != nil {
+		return fmt.Errorf("no database at %s: %w", path, err)
+	}
+	// Opening checks the old key and that no server holds the directory.
+	db, err := openBadger(path, oldKey, logger)
+	if err != nil {
+		return err
+	}
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("closing badger db: %w", err)
+	}
+
+	opts := badger.KeyRegistryOptions{
+		Dir:                           path,
+		ReadOnly:                      true,
+		EncryptionKey:                 oldKey,
+		EncryptionKeyRotationDuration: badger.DefaultOptions(path).EncryptionKeyRotationDuration,
+	}
+	kr, err := badger.OpenKeyRegistry(opts)
+	if err != nil {
+		return fmt.Errorf("opening key registry: %w", err)
+	}
+	opts.EncryptionKey = newKey
+	if err := badger.WriteKeyRegistry(kr, opts); err != nil {
+		return fmt.Errorf("writing key registry: %w", err)
+	}
+	return nil
+}
+
 // User keys
 
 func usrKey(id uuid.UUID) []byte {
@@ -93,29 +252,45 @@ func usrEmailKey(email string) []byte {
 	return []byte(fmt.Sprintf("usr_email/%s", email))
 }
 
-// storableUser includes PasswordHash for persistence (model.User has json:"-" on it).
+// storableUser includes PasswordHash and OIDCSubject for persistence
+// (model.User has json:"-" on them).
 type storableUser struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"passwordHash"`
-	CreatedAt    time.Time `json:"createdAt"`
+	ID                  uuid.UUID `json:"id"`
+	Email               string    `json:"email"`
+	PasswordHash        string    `json:"passwordHash"`
+	OIDCSubject         string    `json:"oidcSubject,omitempty"`
+	PersonalWorkspaceID uuid.UUID `json:"personalWorkspaceId"`
+	EmailVerified       bool      `json:"emailVerified,omitempty"`
+	Admin               bool      `json:"admin,omitempty"`
+	Disabled            bool      `json:"disabled,omitempty"`
+	CreatedAt           time.Time `json:"createdAt"`
 }
 
 func toStorableUser(u model.User) storableUser {
 	return storableUser{
-		ID:           u.ID,
-		Email:        u.Email,
-		PasswordHash: u.PasswordHash,
-		CreatedAt:    u.CreatedAt,
+		ID:                  u.ID,
+		Email:               u.Email,
+		PasswordHash:        u.PasswordHash,
+		OIDCSubject:         u.OIDCSubject,
+		PersonalWorkspaceID: u.PersonalWorkspaceID,
+		EmailVerified:       u.EmailVerified,
+		Admin:               u.Admin,
+		Disabled:            u.Disabled,
+		CreatedAt:           u.CreatedAt,
 	}
 }
 
 func (su storableUser) toModel() model.User {
 	return model.User{
-		ID:           su.ID,
-		Email:        su.Email,
-		PasswordHash: su.PasswordHash,
-		CreatedAt:    su.CreatedAt,
+		ID:                  su.ID,
+		Email:               su.Email,
+		PasswordHash:        su.PasswordHash,
+		OIDCSubject:         su.OIDCSubject,
+		PersonalWorkspaceID: su.PersonalWorkspaceID,
+		EmailVerified:       su.EmailVerified,
+		Admin:               su.Admin,
+		Disabled:            su.Disabled,
+		CreatedAt:           su.CreatedAt,
 	}
 }
 
@@ -163,18 +338,41 @@ func (s *BadgerStore) GetUserByID(_ context.Context, id string) (model.User, err
 	return user, err
 }
 
+// UpdateUser stores u. A changed email moves the email index in the same
+// transaction and fails with ErrConflict if the new address is taken.
 func (s *BadgerStore) UpdateUser(_ context.Context, u model.User) error {
 	data, err := json.Marshal(toStorableUser(u))
 	if err != nil {
 		return err
 	}
 	return s.db.Update(func(txn *badger.Txn) error {
-		if _, err := txn.Get(usrKey(u.ID)); err != nil {
+		item, err := txn.Get(usrKey(u.ID))
+		if err != nil {
 			if errors.Is(err, badger.ErrKeyNotFound) {
 				return ErrNotFound
 			}
 			return err
 		}
+		var old storableUser
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &old)
+		}); err != nil {
+			return err
+		}
+
+		if old.Email != u.Email {
+			if _, err := txn.Get(usrEmailKey(u.Email)); err == nil {
+				return ErrConflict
+			} else if !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			if err := txn.Delete(usrEmailKey(old.Email)); err != nil {
+				return err
+			}
+			if err := txn.Set(usrEmailKey(u.Email), []byte(u.ID.String())); err != nil {
+				return err
+			}
+		}
 		return txn.Set(usrKey(u.ID), data)
 	})
 }
@@ -207,6 +405,92 @@ func (s *BadgerStore) ListUsers(_ context.Context) ([]model.User, error) {
 	return users, nil
 }
 
+// DeleteUser removes an account with its settings, sessions, API keys and
+// other per-user records. Workspaces must be dealt with beforehand.
+func (s *BadgerStore) DeleteUser(_ context.Context, id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	prefix := []byte(fmt.Sprintf("u/%s/", id))
+	return s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(usrKey(uid))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		var su storableUser
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &su)
+		}); err != nil {
+			return err
+		}
+
+		// Collect the global indexes pointing at the user's records before
+		// deleting the records themselves.
+		keys := [][]byte{usrKey(uid), usrEmailKey(su.Email)}
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().KeyCopy(nil)
+			keys = append(keys, key)
+			rest := strings.TrimPrefix(string(key), string(prefix))
+			var index []byte
+			err := it.Item().Value(func(val []byte) error {
+				switch {
+				case rest == "feed_token":
+					var t model.FeedToken
+					if err := json.Unmarshal(val, &t); err != nil {
+						return err
+					}
+					index = feedTokenIndexKey(t.TokenHash)
+				case rest == "password_reset":
+					var t model.PasswordResetToken
+					if err := json.Unmarshal(val, &t); err != nil {
+						return err
+					}
+					index = passwordResetIndexKey(t.TokenHash)
+				case rest == "email_verification":
+					var t model.EmailVerification
+					if err := json.Unmarshal(val, &t); err != nil {
+						return err
+					}
+					index = emailVerificationIndexKey(t.TokenHash)
+				case strings.HasPrefix(rest, "session/"):
+					var ss storableSession
+					if err := json.Unmarshal(val, &ss); err != nil {
+						return err
+					}
+					index = sessionIndexKey(ss.ID)
+				case strings.HasPrefix(rest, "api_key/"):
+					var sk storableAPIKey
+					if err := json.Unmarshal(val, &sk); err != nil {
+						return err
+					}
+					index = apiKeyIndexKey(sk.KeyHash)
+				}
+				return nil
+			})
+			if err != nil {
+				it.Close()
+				return err
+			}
+			if index != nil {
+				keys = append(keys, index)
+			}
+		}
+		it.Close()
+
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func settingsKey(userID string) []byte {
 	return []byte(fmt.Sprintf("u/%s/settings", userID))
 }
@@ -238,847 +522,2599 @@ func (s *BadgerStore) UpdateSettings(_ context.Context, userID string, st model.
 	})
 }
 
-func (s *BadgerStore) GetUserByEmail(_ context.Context, email string) (model.User, error) {
-	var user model.User
+// Calendar feed tokens
+
+func feedTokenKey(userID string) []byte {
+	return []byte(fmt.Sprintf("u/%s/feed_token", userID))
+}
+
+func feedTokenIndexKey(tokenHash string) []byte {
+	return []byte(fmt.Sprintf("feed_token/%s", tokenHash))
+}
+
+func getFeedToken(txn *badger.Txn, userID string) (model.FeedToken, error) {
+	var t model.FeedToken
+	item, err := txn.Get(feedTokenKey(userID))
+	if err != nil {
+		return t, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &t)
+	})
+	return t, err
+}
+
+func (s *BadgerStore) GetFeedToken(_ context.Context, userID string) (model.FeedToken, error) {
+	var t model.FeedToken
 	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(usrEmailKey(email))
-		if err != nil {
+		var err error
+		t, err = getFeedToken(txn, userID)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// SetFeedToken replaces the user's feed token; the previous one stops working.
+func (s *BadgerStore) SetFeedToken(_ context.Context, userID string, t model.FeedToken) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		old, err := getFeedToken(txn, userID)
+		if err == nil {
+			if err := txn.Delete(feedTokenIndexKey(old.TokenHash)); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
 			return err
 		}
-		var idStr string
-		if err := item.Value(func(val []byte) error {
-			idStr = string(val)
-			return nil
-		}); err != nil {
+		if err := txn.Set(feedTokenKey(userID), data); err != nil {
 			return err
 		}
-		id, err := uuid.Parse(idStr)
+		return txn.Set(feedTokenIndexKey(t.TokenHash), []byte(userID))
+	})
+}
+
+func (s *BadgerStore) DeleteFeedToken(_ context.Context, userID string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		old, err := getFeedToken(txn, userID)
 		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
 			return err
 		}
-		uItem, err := txn.Get(usrKey(id))
+		if err := txn.Delete(feedTokenIndexKey(old.TokenHash)); err != nil {
+			return err
+		}
+		return txn.Delete(feedTokenKey(userID))
+	})
+}
+
+func (s *BadgerStore) GetUserIDByFeedToken(_ context.Context, tokenHash string) (string, error) {
+	var userID string
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(feedTokenIndexKey(tokenHash))
 		if err != nil {
 			return err
 		}
-		return uItem.Value(func(val []byte) error {
-			var su storableUser
-			if err := json.Unmarshal(val, &su); err != nil {
-				return err
-			}
-			user = su.toModel()
+		return item.Value(func(val []byte) error {
+			userID = string(val)
 			return nil
 		})
 	})
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return user, ErrNotFound
+		return "", ErrNotFound
 	}
-	return user, err
+	return userID, err
 }
 
-// Module-scoped category key helpers
-// Key format: u/{userID}/mod/{module}/cat/{categoryID}
+// Two-factor authentication
 
-func modCatKey(userID, module string, id uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/mod/%s/cat/%s", userID, module, id))
+func twoFactorKey(userID string) []byte {
+	return []byte(fmt.Sprintf("u/%s/2fa", userID))
 }
 
-func modCatPrefix(userID, module string) []byte {
-	return []byte(fmt.Sprintf("u/%s/mod/%s/cat/", userID, module))
+func (s *BadgerStore) GetTwoFactor(_ context.Context, userID string) (model.TwoFactor, error) {
+	var t model.TwoFactor
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(twoFactorKey(userID))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &t)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return t, ErrNotFound
+	}
+	return t, err
 }
 
-// Contract key helpers
-
-func conKey(userID string, id uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/con/%s", userID, id))
+func (s *BadgerStore) SetTwoFactor(_ context.Context, userID string, t model.TwoFactor) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(twoFactorKey(userID), data)
+	})
 }
 
-func conPrefix(userID string) []byte {
-	return []byte(fmt.Sprintf("u/%s/con/", userID))
+func (s *BadgerStore) UseTwoFactorStep(_ context.Context, userID string, step int64) error {
+	return s.updateTwoFactor(userID, func(t *model.TwoFactor) bool {
+		if step <= t.LastUsedStep {
+			return false
+		}
+		t.LastUsedStep = step
+		return true
+	})
 }
 
-func idxCatConKey(userID string, categoryID, contractID uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/idx/cat_con/%s/%s", userID, categoryID, contractID))
+func (s *BadgerStore) ConsumeRecoveryCode(_ context.Context, userID, codeHash string) error {
+	return s.updateTwoFactor(userID, func(t *model.TwoFactor) bool {
+		i := slices.Index(t.RecoveryCodes, codeHash)
+		if i < 0 {
+			return false
+		}
+		t.RecoveryCodes = slices.Delete(t.RecoveryCodes, i, i+1)
+		return true
+	})
 }
 
-func idxCatConPrefix(userID string, categoryID uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/idx/cat_con/%s/", userID, categoryID))
+// updateTwoFactor applies use to the user's enabled 2FA configuration and
+// saves it in the same transaction, or returns ErrNotFound if use refuses.
+// A concurrent change makes the commit fail, so a code is used only once.
+func (s *BadgerStore) updateTwoFactor(userID string, use func(t *model.TwoFactor) bool) error {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(twoFactorKey(userID))
+		if err != nil {
+			return err
+		}
+		var t model.TwoFactor
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &t)
+		}); err != nil {
+			return err
+		}
+		if !t.Enabled || !use(&t) {
+			return ErrNotFound
+		}
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		return txn.Set(twoFactorKey(userID), data)
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) || errors.Is(err, badger.ErrConflict) {
+		return ErrNotFound
+	}
+	return err
 }
 
-// Purchase key helpers
-// Key format: u/{userID}/pur/{purchaseID}
-// Index: u/{userID}/idx/cat_pur/{categoryID}/{purchaseID}
-
-func purKey(userID string, id uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/pur/%s", userID, id))
+func (s *BadgerStore) DeleteTwoFactor(_ context.Context, userID string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(twoFactorKey(userID)); err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		return txn.Delete(twoFactorKey(userID))
+	})
 }
 
-func purPrefix(userID string) []byte {
-	return []byte(fmt.Sprintf("u/%s/pur/", userID))
-}
+// Password reset tokens
 
-func idxCatPurKey(userID string, categoryID, purchaseID uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/idx/cat_pur/%s/%s", userID, categoryID, purchaseID))
+func passwordResetKey(userID string) []byte {
+	return []byte(fmt.Sprintf("u/%s/password_reset", userID))
 }
 
-func idxCatPurPrefix(userID string, categoryID uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/idx/cat_pur/%s/", userID, categoryID))
+func passwordResetIndexKey(tokenHash string) []byte {
+	return []byte(fmt.Sprintf("password_reset/%s", tokenHash))
 }
 
-// Categories (module-scoped)
-
-func (s *BadgerStore) ListCategories(_ context.Context, userID string, module string) ([]model.Category, error) {
-	var categories []model.Category
-	prefix := modCatPrefix(userID, module)
-
-	err := s.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
-
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			var cat model.Category
-			if err := it.Item().Value(func(val []byte) error {
-				return json.Unmarshal(val, &cat)
+// SetPasswordResetToken stores t, replacing the user's previous token. Both
+// keys expire with the token, so abandoned resets clean themselves up.
+func (s *BadgerStore) SetPasswordResetToken(_ context.Context, t model.PasswordResetToken) error {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(t.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(passwordResetKey(t.UserID))
+		if err == nil {
+			var old model.PasswordResetToken
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &old)
 			}); err != nil {
 				return err
 			}
-			categories = append(categories, cat)
+			if err := txn.Delete(passwordResetIndexKey(old.TokenHash)); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
 		}
-		return nil
+		if err := txn.SetEntry(badger.NewEntry(passwordResetKey(t.UserID), data).WithTTL(ttl)); err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(passwordResetIndexKey(t.TokenHash), data).WithTTL(ttl))
 	})
-	if err != nil {
-		return nil, err
-	}
-	if categories == nil {
-		categories = []model.Category{}
-	}
-	return categories, nil
 }
 
-func (s *BadgerStore) GetCategory(_ context.Context, userID string, module string, id uuid.UUID) (model.Category, error) {
-	var cat model.Category
-	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(modCatKey(userID, module, id))
+// ConsumePasswordResetToken returns and deletes the token with the given
+// hash, so each token can be used once.
+func (s *BadgerStore) ConsumePasswordResetToken(_ context.Context, tokenHash string) (model.PasswordResetToken, error) {
+	var t model.PasswordResetToken
+	err := s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(passwordResetIndexKey(tokenHash))
 		if err != nil {
 			return err
 		}
-		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &cat)
-		})
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &t)
+		}); err != nil {
+			return err
+		}
+		if err := txn.Delete(passwordResetIndexKey(tokenHash)); err != nil {
+			return err
+		}
+		return txn.Delete(passwordResetKey(t.UserID))
 	})
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return cat, ErrNotFound
+		return t, ErrNotFound
 	}
-	return cat, err
+	return t, err
 }
 
-func (s *BadgerStore) CreateCategory(_ context.Context, userID string, module string, c model.Category) error {
-	data, err := json.Marshal(c)
+// Email verifications
+
+func emailVerificationKey(userID string) []byte {
+	return []byte(fmt.Sprintf("u/%s/email_verification", userID))
+}
+
+func emailVerificationIndexKey(tokenHash string) []byte {
+	return []byte(fmt.Sprintf("email_verification/%s", tokenHash))
+}
+
+// SetEmailVerification stores v, replacing the user's previous one. Both
+// keys expire with the token.
+func (s *BadgerStore) SetEmailVerification(_ context.Context, v model.EmailVerification) error {
+	data, err := json.Marshal(v)
 	if err != nil {
 		return err
 	}
+	ttl := time.Until(v.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
 	return s.db.Update(func(txn *badger.Txn) error {
-		return txn.Set(modCatKey(userID, module, c.ID), data)
-	})
-}
-
-func (s *BadgerStore) UpdateCategory(_ context.Context, userID string, module string, c model.Category) error {
-	data, err := json.Marshal(c)
-	if err != nil {
-		return err
-	}
-	return s.db.Update(func(txn *badger.Txn) error {
-		if _, err := txn.Get(modCatKey(userID, module, c.ID)); err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrNotFound
+		item, err := txn.Get(emailVerificationKey(v.UserID))
+		if err == nil {
+			var old model.EmailVerification
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &old)
+			}); err != nil {
+				return err
 			}
+			if err := txn.Delete(emailVerificationIndexKey(old.TokenHash)); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		if err := txn.SetEntry(badger.NewEntry(emailVerificationKey(v.UserID), data).WithTTL(ttl)); err != nil {
 			return err
 		}
-		return txn.Set(modCatKey(userID, module, c.ID), data)
+		return txn.SetEntry(badger.NewEntry(emailVerificationIndexKey(v.TokenHash), data).WithTTL(ttl))
 	})
 }
 
-func (s *BadgerStore) DeleteCategory(_ context.Context, userID string, module string, id uuid.UUID) error {
-	return s.db.Update(func(txn *badger.Txn) error {
-		if _, err := txn.Get(modCatKey(userID, module, id)); err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrNotFound
-			}
+// ConsumeEmailVerification returns and deletes the verification with the
+// given token hash, so each link can be used once.
+func (s *BadgerStore) ConsumeEmailVerification(_ context.Context, tokenHash string) (model.EmailVerification, error) {
+	var v model.EmailVerification
+	err := s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(emailVerificationIndexKey(tokenHash))
+		if err != nil {
 			return err
 		}
-
-		if err := txn.Delete(modCatKey(userID, module, id)); err != nil {
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &v)
+		}); err != nil {
+			return err
+		}
+		if err := txn.Delete(emailVerificationIndexKey(tokenHash)); err != nil {
 			return err
 		}
+		return txn.Delete(emailVerificationKey(v.UserID))
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return v, ErrNotFound
+	}
+	return v, err
+}
 
-		if module == "contracts" {
-			// Delete all contracts in this category via the contract index
-			conPrefix := idxCatConPrefix(userID, id)
-			it := txn.NewIterator(badger.DefaultIteratorOptions)
+// Login attempts
 
-			var contractIDs []uuid.UUID
-			for it.Seek(conPrefix); it.ValidForPrefix(conPrefix); it.Next() {
-				key := it.Item().Key()
-				conIDStr := string(key[len(conPrefix):])
-				conID, err := uuid.Parse(conIDStr)
-				if err != nil {
-					continue
-				}
-				contractIDs = append(contractIDs, conID)
-			}
-			it.Close()
+func loginAttemptsKey(key string) []byte {
+	return []byte("login_attempts/" + key)
+}
 
-			for _, cID := range contractIDs {
-				if err := txn.Delete(conKey(userID, cID)); err != nil {
-					return err
-				}
-				if err := txn.Delete(idxCatConKey(userID, id, cID)); err != nil {
-					return err
-				}
-			}
+func (s *BadgerStore) GetLoginAttempts(_ context.Context, key string) (model.LoginAttempts, error) {
+	var a model.LoginAttempts
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(loginAttemptsKey(key))
+		if err != nil {
+			return err
 		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &a)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return a, ErrNotFound
+	}
+	return a, err
+}
 
-		if module == "purchases" {
-			// Delete all purchases in this category via the purchase index
-			purIdxPrefix := idxCatPurPrefix(userID, id)
-			it2 := txn.NewIterator(badger.DefaultIteratorOptions)
-
-			var purchaseIDs []uuid.UUID
-			for it2.Seek(purIdxPrefix); it2.ValidForPrefix(purIdxPrefix); it2.Next() {
-				key := it2.Item().Key()
-				pIDStr := string(key[len(purIdxPrefix):])
-				pID, err := uuid.Parse(pIDStr)
-				if err != nil {
-					continue
-				}
-				purchaseIDs = append(purchaseIDs, pID)
+// IncrementLoginAttempts counts an attempt for key at now in one
+// transaction, retrying when a concurrent attempt wrote the key first.
+func (s *BadgerStore) IncrementLoginAttempts(_ context.Context, key string, now time.Time, window time.Duration, lock func(failures int) time.Duration) (model.LoginAttempts, error) {
+	for {
+		var a model.LoginAttempts
+		err := s.db.Update(func(txn *badger.Txn) error {
+			cur, err := getLoginAttempts(txn, key)
+			if err != nil {
+				return err
 			}
-			it2.Close()
-
-			for _, pID := range purchaseIDs {
-				if err := txn.Delete(purKey(userID, pID)); err != nil {
-					return err
-				}
-				if err := txn.Delete(idxCatPurKey(userID, id, pID)); err != nil {
-					return err
-				}
+			if a, err = incrementAttempts(cur, key, now, window, lock); err != nil {
+				return err
 			}
+			return setLoginAttempts(txn, a)
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			return a, err
 		}
-
-		return nil
-	})
+	}
 }
 
-// Contracts
-
-func (s *BadgerStore) ListContracts(_ context.Context, userID string) ([]model.Contract, error) {
-	var contracts []model.Contract
-	prefix := conPrefix(userID)
-
-	err := s.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
-
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			var con model.Contract
-			if err := it.Item().Value(func(val []byte) error {
-				return json.Unmarshal(val, &con)
-			}); err != nil {
+// DecrementLoginAttempts takes back the attempt that IncrementLoginAttempts
+// counted as a.
+func (s *BadgerStore) DecrementLoginAttempts(_ context.Context, a model.LoginAttempts) error {
+	for {
+		err := s.db.Update(func(txn *badger.Txn) error {
+			cur, err := getLoginAttempts(txn, a.Key)
+			if err != nil || cur.Failures == 0 {
 				return err
 			}
-			contracts = append(contracts, con)
+			return setLoginAttempts(txn, decrementAttempts(cur, a))
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
 		}
-		return nil
+	}
+}
+
+// getLoginAttempts returns the zero record if key has none.
+func getLoginAttempts(txn *badger.Txn, key string) (model.LoginAttempts, error) {
+	var a model.LoginAttempts
+	item, err := txn.Get(loginAttemptsKey(key))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return a, nil
+	}
+	if err != nil {
+		return a, err
+	}
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &a)
 	})
+	return a, err
+}
+
+// setLoginAttempts stores a, which expires at a.ExpiresAt.
+func setLoginAttempts(txn *badger.Txn, a model.LoginAttempts) error {
+	data, err := json.Marshal(a)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if contracts == nil {
-		contracts = []model.Contract{}
+	ttl := time.Until(a.ExpiresAt)
+	if ttl <= 0 {
+		return nil
 	}
-	return contracts, nil
+	return txn.SetEntry(badger.NewEntry(loginAttemptsKey(a.Key), data).WithTTL(ttl))
 }
 
-func (s *BadgerStore) ListContractsByCategory(_ context.Context, userID string, categoryID uuid.UUID) ([]model.Contract, error) {
-	var contracts []model.Contract
-	prefix := idxCatConPrefix(userID, categoryID)
+// DeleteLoginAttempts forgets the failures for key; it is not an error if
+// there are none.
+func (s *BadgerStore) DeleteLoginAttempts(_ context.Context, key string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(loginAttemptsKey(key))
+	})
+}
 
-	err := s.db.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		opts.PrefetchValues = false
-		it := txn.NewIterator(opts)
-		defer it.Close()
+// Sessions
 
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			key := it.Item().Key()
-			conIDStr := string(key[len(prefix):])
-			conID, err := uuid.Parse(conIDStr)
-			if err != nil {
-				continue
-			}
+func sessionKey(userID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("u/%s/session/%s", userID, id))
+}
 
-			item, err := txn.Get(conKey(userID, conID))
-			if err != nil {
-				if errors.Is(err, badger.ErrKeyNotFound) {
-					continue
-				}
-				return err
-			}
+func sessionPrefix(userID string) []byte {
+	return []byte(fmt.Sprintf("u/%s/session/", userID))
+}
 
-			var con model.Contract
-			if err := item.Value(func(val []byte) error {
-				return json.Unmarshal(val, &con)
-			}); err != nil {
-				return err
-			}
-			contracts = append(contracts, con)
-		}
-		return nil
-	})
+func sessionIndexKey(id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("session/%s", id))
+}
+
+// storableSession includes the fields model.Session hides from JSON.
+type storableSession struct {
+	ID               uuid.UUID `json:"id"`
+	UserID           string    `json:"userId"`
+	RefreshTokenHash string    `json:"refreshTokenHash"`
+	UserAgent        string    `json:"userAgent"`
+	IPAddress        string    `json:"ipAddress"`
+	CreatedAt        time.Time `json:"createdAt"`
+	LastUsedAt       time.Time `json:"lastUsedAt"`
+	ExpiresAt        time.Time `json:"expiresAt"`
+}
+
+func toStorableSession(s model.Session) storableSession {
+	return storableSession(s)
+}
+
+func (ss storableSession) toModel() model.Session {
+	return model.Session(ss)
+}
+
+func (s *BadgerStore) CreateSession(_ context.Context, sess model.Session) error {
+	data, err := json.Marshal(toStorableSession(sess))
 	if err != nil {
-		return nil, err
-	}
-	if contracts == nil {
-		contracts = []model.Contract{}
+		return err
 	}
-	return contracts, nil
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(sessionKey(sess.UserID, sess.ID), data); err != nil {
+			return err
+		}
+		return txn.Set(sessionIndexKey(sess.ID), []byte(sess.UserID))
+	})
 }
 
-func (s *BadgerStore) GetContract(_ context.Context, userID string, id uuid.UUID) (model.Contract, error) {
-	var con model.Contract
+// GetSession looks a session up by ID alone, as refresh requests and access
+// tokens do not identify the user separately.
+func (s *BadgerStore) GetSession(_ context.Context, id uuid.UUID) (model.Session, error) {
+	var sess model.Session
 	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(conKey(userID, id))
+		item, err := txn.Get(sessionIndexKey(id))
+		if err != nil {
+			return err
+		}
+		var userID string
+		if err := item.Value(func(val []byte) error {
+			userID = string(val)
+			return nil
+		}); err != nil {
+			return err
+		}
+		item, err = txn.Get(sessionKey(userID, id))
 		if err != nil {
 			return err
 		}
 		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &con)
+			var ss storableSession
+			if err := json.Unmarshal(val, &ss); err != nil {
+				return err
+			}
+			sess = ss.toModel()
+			return nil
 		})
 	})
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return con, ErrNotFound
+		return sess, ErrNotFound
 	}
-	return con, err
+	return sess, err
 }
 
-func (s *BadgerStore) CreateContract(_ context.Context, userID string, c model.Contract) error {
-	data, err := json.Marshal(c)
+func (s *BadgerStore) UpdateSession(_ context.Context, sess model.Session) error {
+	data, err := json.Marshal(toStorableSession(sess))
 	if err != nil {
 		return err
 	}
 	return s.db.Update(func(txn *badger.Txn) error {
-		if err := txn.Set(conKey(userID, c.ID), data); err != nil {
+		if _, err := txn.Get(sessionKey(sess.UserID, sess.ID)); err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
 			return err
 		}
-		return txn.Set(idxCatConKey(userID, c.CategoryID, c.ID), []byte{})
+		return txn.Set(sessionKey(sess.UserID, sess.ID), data)
 	})
 }
 
-func (s *BadgerStore) UpdateContract(_ context.Context, userID string, c model.Contract) error {
-	data, err := json.Marshal(c)
+// RotateSession reads and compares the stored hash in the same transaction
+// that writes the new one. Of two concurrent rotations, Badger's conflict
+// detection lets only the first commit.
+func (s *BadgerStore) RotateSession(_ context.Context, sess model.Session, oldHash string) error {
+	data, err := json.Marshal(toStorableSession(sess))
 	if err != nil {
 		return err
 	}
-	return s.db.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get(conKey(userID, c.ID))
+	err = s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(sessionKey(sess.UserID, sess.ID))
 		if err != nil {
 			if errors.Is(err, badger.ErrKeyNotFound) {
 				return ErrNotFound
 			}
 			return err
 		}
-
-		var old model.Contract
+		var current storableSession
 		if err := item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &old)
+			return json.Unmarshal(val, &current)
 		}); err != nil {
 			return err
 		}
-
-		if err := txn.Set(conKey(userID, c.ID), data); err != nil {
-			return err
+		if current.RefreshTokenHash != oldHash {
+			return ErrTokenReused
 		}
+		return txn.Set(sessionKey(sess.UserID, sess.ID), data)
+	})
+	if errors.Is(err, badger.ErrConflict) {
+		return ErrTokenReused
+	}
+	return err
+}
 
-		if old.CategoryID != c.CategoryID {
-			if err := txn.Delete(idxCatConKey(userID, old.CategoryID, c.ID)); err != nil {
-				return err
-			}
-			if err := txn.Set(idxCatConKey(userID, c.CategoryID, c.ID), []byte{}); err != nil {
+func (s *BadgerStore) ListSessions(_ context.Context, userID string) ([]model.Session, error) {
+	var sessions []model.Session
+	prefix := sessionPrefix(userID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var ss storableSession
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &ss)
+			}); err != nil {
 				return err
 			}
+			sessions = append(sessions, ss.toModel())
 		}
-
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if sessions == nil {
+		sessions = []model.Session{}
+	}
+	return sessions, nil
 }
 
-func (s *BadgerStore) DeleteContract(_ context.Context, userID string, id uuid.UUID) error {
+func (s *BadgerStore) DeleteSession(_ context.Context, userID string, id uuid.UUID) error {
 	return s.db.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get(conKey(userID, id))
-		if err != nil {
+		if _, err := txn.Get(sessionKey(userID, id)); err != nil {
 			if errors.Is(err, badger.ErrKeyNotFound) {
 				return ErrNotFound
 			}
 			return err
 		}
+		if err := txn.Delete(sessionIndexKey(id)); err != nil {
+			return err
+		}
+		return txn.Delete(sessionKey(userID, id))
+	})
+}
 
-		var con model.Contract
-		if err := item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &con)
-		}); err != nil {
+// API keys
+
+func apiKeyKey(userID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("u/%s/api_key/%s", userID, id))
+}
+
+func apiKeyPrefix(userID string) []byte {
+	return []byte(fmt.Sprintf("u/%s/api_key/", userID))
+}
+
+func apiKeyIndexKey(keyHash string) []byte {
+	return []byte(fmt.Sprintf("api_key/%s", keyHash))
+}
+
+// storableAPIKey includes the fields model.APIKey hides from JSON.
+type storableAPIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     string     `json:"userId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"keyHash"`
+	Access     string     `json:"access"`
+	Modules    []string   `json:"modules"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+func (sk storableAPIKey) toModel() model.APIKey {
+	k := model.APIKey(sk)
+	if k.Modules == nil {
+		k.Modules = []string{}
+	}
+	return k
+}
+
+func getAPIKey(txn *badger.Txn, key []byte) (model.APIKey, error) {
+	var k model.APIKey
+	item, err := txn.Get(key)
+	if err != nil {
+		return k, err
+	}
+	err = item.Value(func(val []byte) error {
+		var sk storableAPIKey
+		if err := json.Unmarshal(val, &sk); err != nil {
+			return err
+		}
+		k = sk.toModel()
+		return nil
+	})
+	return k, err
+}
+
+func (s *BadgerStore) CreateAPIKey(_ context.Context, k model.APIKey) error {
+	data, err := json.Marshal(storableAPIKey(k))
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(apiKeyKey(k.UserID, k.ID), data); err != nil {
 			return err
 		}
+		return txn.Set(apiKeyIndexKey(k.KeyHash), apiKeyKey(k.UserID, k.ID))
+	})
+}
 
-		if err := txn.Delete(conKey(userID, id)); err != nil {
+// GetAPIKeyByHash looks up the key a request authenticates with.
+func (s *BadgerStore) GetAPIKeyByHash(_ context.Context, keyHash string) (model.APIKey, error) {
+	var k model.APIKey
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(apiKeyIndexKey(keyHash))
+		if err != nil {
+			return err
+		}
+		key, err := item.ValueCopy(nil)
+		if err != nil {
 			return err
 		}
-		return txn.Delete(idxCatConKey(userID, con.CategoryID, id))
+		k, err = getAPIKey(txn, key)
+		return err
 	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return k, ErrNotFound
+	}
+	return k, err
 }
 
-// Purchases
+func (s *BadgerStore) UpdateAPIKey(_ context.Context, k model.APIKey) error {
+	data, err := json.Marshal(storableAPIKey(k))
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(apiKeyKey(k.UserID, k.ID)); err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		return txn.Set(apiKeyKey(k.UserID, k.ID), data)
+	})
+}
 
-func (s *BadgerStore) ListPurchases(_ context.Context, userID string) ([]model.Purchase, error) {
-	var purchases []model.Purchase
-	prefix := purPrefix(userID)
+func (s *BadgerStore) ListAPIKeys(_ context.Context, userID string) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	prefix := apiKeyPrefix(userID)
 
 	err := s.db.View(func(txn *badger.Txn) error {
 		it := txn.NewIterator(badger.DefaultIteratorOptions)
 		defer it.Close()
 
 		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			var p model.Purchase
+			var sk storableAPIKey
 			if err := it.Item().Value(func(val []byte) error {
-				return json.Unmarshal(val, &p)
+				return json.Unmarshal(val, &sk)
 			}); err != nil {
 				return err
 			}
-			purchases = append(purchases, p)
+			keys = append(keys, sk.toModel())
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	if purchases == nil {
-		purchases = []model.Purchase{}
-	}
-	return purchases, nil
-}
-
-func (s *BadgerStore) ListPurchasesByCategory(_ context.Context, userID string, categoryID uuid.UUID) ([]model.Purchase, error) {
-	var purchases []model.Purchase
-	prefix := idxCatPurPrefix(userID, categoryID)
-
-	err := s.db.View(func(txn *badger.Txn) error {
-		opts := badger.DefaultIteratorOptions
-		opts.PrefetchValues = false
-		it := txn.NewIterator(opts)
-		defer it.Close()
-
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			key := it.Item().Key()
-			pIDStr := string(key[len(prefix):])
-			pID, err := uuid.Parse(pIDStr)
-			if err != nil {
-				continue
-			}
-
-			item, err := txn.Get(purKey(userID, pID))
-			if err != nil {
-				if errors.Is(err, badger.ErrKeyNotFound) {
-					continue
-				}
-				return err
-			}
-
-			var p model.Purchase
-			if err := item.Value(func(val []byte) error {
-				return json.Unmarshal(val, &p)
-			}); err != nil {
-				return err
-			}
-			purchases = append(purchases, p)
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
+	if keys == nil {
+		keys = []model.APIKey{}
 	}
-	if purchases == nil {
-		purchases = []model.Purchase{}
-	}
-	return purchases, nil
+	return keys, nil
 }
 
-func (s *BadgerStore) GetPurchase(_ context.Context, userID string, id uuid.UUID) (model.Purchase, error) {
-	var p model.Purchase
-	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(purKey(userID, id))
+func (s *BadgerStore) DeleteAPIKey(_ context.Context, userID string, id uuid.UUID) error {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		k, err := getAPIKey(txn, apiKeyKey(userID, id))
 		if err != nil {
 			return err
 		}
-		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &p)
-		})
-	})
-	if errors.Is(err, badger.ErrKeyNotFound) {
-		return p, ErrNotFound
-	}
-	return p, err
-}
-
-func (s *BadgerStore) CreatePurchase(_ context.Context, userID string, p model.Purchase) error {
-	data, err := json.Marshal(p)
-	if err != nil {
-		return err
-	}
-	return s.db.Update(func(txn *badger.Txn) error {
-		if err := txn.Set(purKey(userID, p.ID), data); err != nil {
+		if err := txn.Delete(apiKeyIndexKey(k.KeyHash)); err != nil {
 			return err
 		}
-		return txn.Set(idxCatPurKey(userID, p.CategoryID, p.ID), []byte{})
+		return txn.Delete(apiKeyKey(userID, id))
 	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return ErrNotFound
+	}
+	return err
 }
 
-func (s *BadgerStore) UpdatePurchase(_ context.Context, userID string, p model.Purchase) error {
-	data, err := json.Marshal(p)
-	if err != nil {
-		return err
-	}
-	return s.db.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get(purKey(userID, p.ID))
+func (s *BadgerStore) GetUserByEmail(_ context.Context, email string) (model.User, error) {
+	var user model.User
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(usrEmailKey(email))
 		if err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrNotFound
-			}
 			return err
 		}
-
-		var old model.Purchase
+		var idStr string
 		if err := item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &old)
+			idStr = string(val)
+			return nil
 		}); err != nil {
 			return err
 		}
-
-		if err := txn.Set(purKey(userID, p.ID), data); err != nil {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
 			return err
 		}
-
-		if old.CategoryID != p.CategoryID {
-			if err := txn.Delete(idxCatPurKey(userID, old.CategoryID, p.ID)); err != nil {
-				return err
-			}
-			if err := txn.Set(idxCatPurKey(userID, p.CategoryID, p.ID), []byte{}); err != nil {
+		uItem, err := txn.Get(usrKey(id))
+		if err != nil {
+			return err
+		}
+		return uItem.Value(func(val []byte) error {
+			var su storableUser
+			if err := json.Unmarshal(val, &su); err != nil {
 				return err
 			}
-		}
-
-		return nil
+			user = su.toModel()
+			return nil
+		})
 	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return user, ErrNotFound
+	}
+	return user, err
 }
 
-func (s *BadgerStore) DeletePurchase(_ context.Context, userID string, id uuid.UUID) error {
-	return s.db.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get(purKey(userID, id))
-		if err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrNotFound
-			}
-			return err
-		}
-
-		var p model.Purchase
-		if err := item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &p)
-		}); err != nil {
-			return err
-		}
+// Workspaces
+// Key format: ws/{workspaceID}, ws/{workspaceID}/member/{userID},
+// ws/{workspaceID}/invite/{invitationID}
+// Index: u/{userID}/workspace/{workspaceID}, invite/{tokenHash}
+// Workspace data lives under w/{workspaceID}/.
 
-		if err := txn.Delete(purKey(userID, id)); err != nil {
-			return err
-		}
-		return txn.Delete(idxCatPurKey(userID, p.CategoryID, id))
-	})
+func wsKey(id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("ws/%s", id))
 }
 
-// Vehicle key helpers
-// Key format: u/{userID}/veh/{vehicleID}
-
-func vehKey(userID string, id uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/veh/%s", userID, id))
+func wsMemberKey(workspaceID uuid.UUID, userID string) []byte {
+	return []byte(fmt.Sprintf("ws/%s/member/%s", workspaceID, userID))
 }
 
-func vehPrefix(userID string) []byte {
-	return []byte(fmt.Sprintf("u/%s/veh/", userID))
+func wsMemberPrefix(workspaceID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("ws/%s/member/", workspaceID))
 }
 
-// Cost entry key helpers
-// Key format: u/{userID}/cost/{costEntryID}
-// Index: u/{userID}/idx/veh_cost/{vehicleID}/{costEntryID}
+func wsInvitationKey(workspaceID, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("ws/%s/invite/%s", workspaceID, id))
+}
 
-func costKey(userID string, id uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/cost/%s", userID, id))
+func wsInvitationPrefix(workspaceID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("ws/%s/invite/", workspaceID))
 }
 
-func idxVehCostKey(userID string, vehicleID, costID uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/idx/veh_cost/%s/%s", userID, vehicleID, costID))
+func invitationIndexKey(tokenHash string) []byte {
+	return []byte(fmt.Sprintf("invite/%s", tokenHash))
 }
 
-func idxVehCostPrefix(userID string, vehicleID uuid.UUID) []byte {
-	return []byte(fmt.Sprintf("u/%s/idx/veh_cost/%s/", userID, vehicleID))
+func userWorkspaceKey(userID string, workspaceID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("u/%s/workspace/%s", userID, workspaceID))
 }
 
-// Vehicles
+func userWorkspacePrefix(userID string) []byte {
+	return []byte(fmt.Sprintf("u/%s/workspace/", userID))
+}
 
-func (s *BadgerStore) ListVehicles(_ context.Context, userID string) ([]model.Vehicle, error) {
-	var vehicles []model.Vehicle
-	prefix := vehPrefix(userID)
+func wsDataPrefix(workspaceID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/", workspaceID))
+}
 
-	err := s.db.View(func(txn *badger.Txn) error {
-		it := txn.NewIterator(badger.DefaultIteratorOptions)
-		defer it.Close()
+// storableInvitation includes the fields model.Invitation hides from JSON.
+type storableInvitation struct {
+	ID          uuid.UUID `json:"id"`
+	WorkspaceID uuid.UUID `json:"workspaceId"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	TokenHash   string    `json:"tokenHash"`
+	InvitedBy   string    `json:"invitedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
 
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			var v model.Vehicle
-			if err := it.Item().Value(func(val []byte) error {
-				return json.Unmarshal(val, &v)
-			}); err != nil {
-				return err
-			}
-			vehicles = append(vehicles, v)
-		}
-		return nil
-	})
+// CreateWorkspace stores a new workspace together with its owner.
+func (s *BadgerStore) CreateWorkspace(_ context.Context, ws model.Workspace, owner model.WorkspaceMember) error {
+	data, err := json.Marshal(ws)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if vehicles == nil {
-		vehicles = []model.Vehicle{}
+	member, err := json.Marshal(owner)
+	if err != nil {
+		return err
 	}
-	return vehicles, nil
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(wsKey(ws.ID), data); err != nil {
+			return err
+		}
+		if err := txn.Set(wsMemberKey(ws.ID, owner.UserID), member); err != nil {
+			return err
+		}
+		return txn.Set(userWorkspaceKey(owner.UserID, ws.ID), nil)
+	})
 }
 
-func (s *BadgerStore) GetVehicle(_ context.Context, userID string, id uuid.UUID) (model.Vehicle, error) {
-	var v model.Vehicle
+func (s *BadgerStore) GetWorkspace(_ context.Context, id uuid.UUID) (model.Workspace, error) {
+	var ws model.Workspace
 	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(vehKey(userID, id))
+		item, err := txn.Get(wsKey(id))
 		if err != nil {
 			return err
 		}
 		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &v)
+			return json.Unmarshal(val, &ws)
 		})
 	})
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return v, ErrNotFound
+		return ws, ErrNotFound
 	}
-	return v, err
-}
-
-func (s *BadgerStore) CreateVehicle(_ context.Context, userID string, v model.Vehicle) error {
-	data, err := json.Marshal(v)
-	if err != nil {
-		return err
-	}
-	return s.db.Update(func(txn *badger.Txn) error {
-		return txn.Set(vehKey(userID, v.ID), data)
-	})
+	return ws, err
 }
 
-func (s *BadgerStore) UpdateVehicle(_ context.Context, userID string, v model.Vehicle) error {
-	data, err := json.Marshal(v)
+func (s *BadgerStore) UpdateWorkspace(_ context.Context, ws model.Workspace) error {
+	data, err := json.Marshal(ws)
 	if err != nil {
 		return err
 	}
 	return s.db.Update(func(txn *badger.Txn) error {
-		if _, err := txn.Get(vehKey(userID, v.ID)); err != nil {
+		if _, err := txn.Get(wsKey(ws.ID)); err != nil {
 			if errors.Is(err, badger.ErrKeyNotFound) {
 				return ErrNotFound
 			}
 			return err
 		}
-		return txn.Set(vehKey(userID, v.ID), data)
+		return txn.Set(wsKey(ws.ID), data)
 	})
 }
 
-func (s *BadgerStore) DeleteVehicle(_ context.Context, userID string, id uuid.UUID) error {
-	return s.db.Update(func(txn *badger.Txn) error {
-		if _, err := txn.Get(vehKey(userID, id)); err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrNotFound
-			}
-			return err
-		}
+// DeleteWorkspace removes a workspace with its members, invitations and all
+// of its data. The data goes first, in batches, so a workspace whose
+// deletion is interrupted can still be found and deleted again.
+func (s *BadgerStore) DeleteWorkspace(_ context.Context, id uuid.UUID) error {
+	err := s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(wsKey(id))
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.deletePrefix(wsDataPrefix(id)); err != nil {
+		return err
+	}
 
-		if err := txn.Delete(vehKey(userID, id)); err != nil {
+	err = s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(wsKey(id)); err != nil {
 			return err
 		}
 
-		// Cascade delete all cost entries for this vehicle
-		idxPrefix := idxVehCostPrefix(userID, id)
+		var keys [][]byte
+		members := wsMemberPrefix(id)
+		invites := wsInvitationPrefix(id)
+		prefix := append(wsKey(id), '/')
 		it := txn.NewIterator(badger.DefaultIteratorOptions)
-
-		var costIDs []uuid.UUID
-		for it.Seek(idxPrefix); it.ValidForPrefix(idxPrefix); it.Next() {
-			key := it.Item().Key()
-			cIDStr := string(key[len(idxPrefix):])
-			cID, err := uuid.Parse(cIDStr)
-			if err != nil {
-				continue
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			key := item.KeyCopy(nil)
+			keys = append(keys, key)
+			switch {
+			case bytes.HasPrefix(key, members):
+				keys = append(keys, userWorkspaceKey(string(key[len(members):]), id))
+			case bytes.HasPrefix(key, invites):
+				var inv storableInvitation
+				if err := item.Value(func(val []byte) error {
+					return json.Unmarshal(val, &inv)
+				}); err != nil {
+					it.Close()
+					return err
+				}
+				keys = append(keys, invitationIndexKey(inv.TokenHash))
 			}
-			costIDs = append(costIDs, cID)
 		}
 		it.Close()
 
-		for _, cID := range costIDs {
-			if err := txn.Delete(costKey(userID, cID)); err != nil {
-				return err
-			}
-			if err := txn.Delete(idxVehCostKey(userID, id, cID)); err != nil {
+		for _, k := range append(keys, wsKey(id)) {
+			if err := txn.Delete(k); err != nil {
 				return err
 			}
 		}
-
 		return nil
 	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return ErrNotFound
+	}
+	return err
 }
 
-// Cost Entries
-
-func (s *BadgerStore) ListCostEntries(_ context.Context, userID string, vehicleID uuid.UUID) ([]model.CostEntry, error) {
-	var entries []model.CostEntry
-	prefix := idxVehCostPrefix(userID, vehicleID)
+// deletePrefix deletes every key starting with prefix. The deletes go
+// through a write batch, which commits as many transactions as it needs,
+// so large workspaces stay within Badger's transaction size limit.
+func (s *BadgerStore) deletePrefix(prefix []byte) error {
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
 
 	err := s.db.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = false
+		opts.Prefix = prefix
 		it := txn.NewIterator(opts)
 		defer it.Close()
 
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			key := it.Item().Key()
-			cIDStr := string(key[len(prefix):])
-			cID, err := uuid.Parse(cIDStr)
-			if err != nil {
-				continue
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := wb.Delete(it.Item().KeyCopy(nil)); err != nil {
+				return err
 			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return wb.Flush()
+}
 
-			item, err := txn.Get(costKey(userID, cID))
+// ListWorkspaceMemberships returns the user's membership in each of their
+// workspaces.
+func (s *BadgerStore) ListWorkspaceMemberships(_ context.Context, userID string) ([]model.WorkspaceMember, error) {
+	memberships := []model.WorkspaceMember{}
+	prefix := userWorkspacePrefix(userID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			id, err := uuid.Parse(string(it.Item().Key()[len(prefix):]))
 			if err != nil {
-				if errors.Is(err, badger.ErrKeyNotFound) {
-					continue
-				}
 				return err
 			}
-
-			var c model.CostEntry
-			if err := item.Value(func(val []byte) error {
-				return json.Unmarshal(val, &c)
-			}); err != nil {
+			m, err := getWorkspaceMember(txn, id, userID)
+			if err != nil {
 				return err
 			}
-			entries = append(entries, c)
+			memberships = append(memberships, m)
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	if entries == nil {
-		entries = []model.CostEntry{}
+	return memberships, nil
+}
+
+func getWorkspaceMember(txn *badger.Txn, workspaceID uuid.UUID, userID string) (model.WorkspaceMember, error) {
+	var m model.WorkspaceMember
+	item, err := txn.Get(wsMemberKey(workspaceID, userID))
+	if err != nil {
+		return m, err
 	}
-	return entries, nil
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &m)
+	})
+	return m, err
 }
 
-func (s *BadgerStore) GetCostEntry(_ context.Context, userID string, id uuid.UUID) (model.CostEntry, error) {
-	var c model.CostEntry
+func (s *BadgerStore) GetWorkspaceMember(_ context.Context, workspaceID uuid.UUID, userID string) (model.WorkspaceMember, error) {
+	var m model.WorkspaceMember
 	err := s.db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(costKey(userID, id))
-		if err != nil {
-			return err
-		}
-		return item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &c)
-		})
+		var err error
+		m, err = getWorkspaceMember(txn, workspaceID, userID)
+		return err
 	})
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return c, ErrNotFound
+		return m, ErrNotFound
 	}
-	return c, err
+	return m, err
 }
 
-func (s *BadgerStore) CreateCostEntry(_ context.Context, userID string, c model.CostEntry) error {
-	data, err := json.Marshal(c)
-	if err != nil {
-		return err
-	}
-	return s.db.Update(func(txn *badger.Txn) error {
-		if err := txn.Set(costKey(userID, c.ID), data); err != nil {
-			return err
-		}
-		return txn.Set(idxVehCostKey(userID, c.VehicleID, c.ID), []byte{})
-	})
+func (s *BadgerStore) ListWorkspaceMembers(_ context.Context, workspaceID uuid.UUID) ([]model.WorkspaceMember, error) {
+	members := []model.WorkspaceMember{}
+	prefix := wsMemberPrefix(workspaceID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var m model.WorkspaceMember
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &m)
+			}); err != nil {
+				return err
+			}
+			members = append(members, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SetWorkspaceMember adds a member to a workspace or changes their role.
+func (s *BadgerStore) SetWorkspaceMember(_ context.Context, m model.WorkspaceMember) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(wsKey(m.WorkspaceID)); err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := txn.Set(wsMemberKey(m.WorkspaceID, m.UserID), data); err != nil {
+			return err
+		}
+		return txn.Set(userWorkspaceKey(m.UserID, m.WorkspaceID), nil)
+	})
+}
+
+func (s *BadgerStore) DeleteWorkspaceMember(_ context.Context, workspaceID uuid.UUID, userID string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(wsMemberKey(workspaceID, userID)); err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := txn.Delete(userWorkspaceKey(userID, workspaceID)); err != nil {
+			return err
+		}
+		return txn.Delete(wsMemberKey(workspaceID, userID))
+	})
+}
+
+// CreateInvitation stores an invitation. Both keys expire with it.
+func (s *BadgerStore) CreateInvitation(_ context.Context, inv model.Invitation) error {
+	data, err := json.Marshal(storableInvitation(inv))
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(inv.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(wsKey(inv.WorkspaceID)); err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := txn.SetEntry(badger.NewEntry(wsInvitationKey(inv.WorkspaceID, inv.ID), data).WithTTL(ttl)); err != nil {
+			return err
+		}
+		return txn.SetEntry(badger.NewEntry(invitationIndexKey(inv.TokenHash), data).WithTTL(ttl))
+	})
+}
+
+func (s *BadgerStore) ListInvitations(_ context.Context, workspaceID uuid.UUID) ([]model.Invitation, error) {
+	invitations := []model.Invitation{}
+	prefix := wsInvitationPrefix(workspaceID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var si storableInvitation
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &si)
+			}); err != nil {
+				return err
+			}
+			invitations = append(invitations, model.Invitation(si))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (s *BadgerStore) DeleteInvitation(_ context.Context, workspaceID, id uuid.UUID) error {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(wsInvitationKey(workspaceID, id))
+		if err != nil {
+			return err
+		}
+		var si storableInvitation
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &si)
+		}); err != nil {
+			return err
+		}
+		if err := txn.Delete(invitationIndexKey(si.TokenHash)); err != nil {
+			return err
+		}
+		return txn.Delete(wsInvitationKey(workspaceID, id))
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ConsumeInvitation returns and deletes the invitation with the given token
+// hash, so each invitation can be accepted once.
+func (s *BadgerStore) ConsumeInvitation(_ context.Context, tokenHash string) (model.Invitation, error) {
+	var si storableInvitation
+	err := s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(invitationIndexKey(tokenHash))
+		if err != nil {
+			return err
+		}
+		if err := item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &si)
+		}); err != nil {
+			return err
+		}
+		if err := txn.Delete(invitationIndexKey(tokenHash)); err != nil {
+			return err
+		}
+		return txn.Delete(wsInvitationKey(si.WorkspaceID, si.ID))
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return model.Invitation(si), ErrNotFound
+	}
+	return model.Invitation(si), err
+}
+
+// Module-scoped category key helpers
+// Key format: w/{workspaceID}/mod/{module}/cat/{categoryID}
+
+func modCatKey(workspaceID, module string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/mod/%s/cat/%s", workspaceID, module, id))
+}
+
+func modCatPrefix(workspaceID, module string) []byte {
+	return []byte(fmt.Sprintf("w/%s/mod/%s/cat/", workspaceID, module))
+}
+
+// Contract key helpers
+
+func conKey(workspaceID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/con/%s", workspaceID, id))
+}
+
+func conPrefix(workspaceID string) []byte {
+	return []byte(fmt.Sprintf("w/%s/con/", workspaceID))
+}
+
+func idxCatConKey(workspaceID string, categoryID, contractID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/cat_con/%s/%s", workspaceID, categoryID, contractID))
+}
+
+func idxCatConPrefix(workspaceID string, categoryID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/cat_con/%s/", workspaceID, categoryID))
+}
+
+// Price entry key helpers
+// Key format: w/{workspaceID}/price/{priceEntryID}
+// Index: w/{workspaceID}/idx/con_price/{contractID}/{priceEntryID}
+
+func priceKey(workspaceID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/price/%s", workspaceID, id))
+}
+
+func pricePrefix(workspaceID string) []byte {
+	return []byte(fmt.Sprintf("w/%s/price/", workspaceID))
+}
+
+func idxConPriceKey(workspaceID string, contractID, priceID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/con_price/%s/%s", workspaceID, contractID, priceID))
+}
+
+func idxConPricePrefix(workspaceID string, contractID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/con_price/%s/", workspaceID, contractID))
+}
+
+// Purchase key helpers
+// Key format: w/{workspaceID}/pur/{purchaseID}
+// Index: w/{workspaceID}/idx/cat_pur/{categoryID}/{purchaseID}
+
+func purKey(workspaceID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/pur/%s", workspaceID, id))
+}
+
+func purPrefix(workspaceID string) []byte {
+	return []byte(fmt.Sprintf("w/%s/pur/", workspaceID))
+}
+
+func idxCatPurKey(workspaceID string, categoryID, purchaseID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/cat_pur/%s/%s", workspaceID, categoryID, purchaseID))
+}
+
+func idxCatPurPrefix(workspaceID string, categoryID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/cat_pur/%s/", workspaceID, categoryID))
+}
+
+// Categories (module-scoped)
+
+func (s *BadgerStore) ListCategories(_ context.Context, workspaceID string, module string) ([]model.Category, error) {
+	var categories []model.Category
+	prefix := modCatPrefix(workspaceID, module)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var cat model.Category
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &cat)
+			}); err != nil {
+				return err
+			}
+			if cat.DeletedAt != nil {
+				continue
+			}
+			categories = append(categories, cat)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if categories == nil {
+		categories = []model.Category{}
+	}
+	return categories, nil
+}
+
+func (s *BadgerStore) GetCategory(_ context.Context, workspaceID string, module string, id uuid.UUID) (model.Category, error) {
+	var cat model.Category
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(modCatKey(workspaceID, module, id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &cat)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) || cat.DeletedAt != nil {
+		return model.Category{}, ErrNotFound
+	}
+	return cat, err
+}
+
+func (s *BadgerStore) CreateCategory(ctx context.Context, workspaceID string, module string, c model.Category) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := checkFree(txn, modCatKey(workspaceID, module, c.ID)); err != nil {
+			return err
+		}
+		if err := txn.Set(modCatKey(workspaceID, module, c.ID), data); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityCategory, module, c.ID, nil, data)
+	})
+}
+
+func (s *BadgerStore) UpdateCategory(ctx context.Context, workspaceID string, module string, c model.Category) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		before, err := getLive(txn, modCatKey(workspaceID, module, c.ID))
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(modCatKey(workspaceID, module, c.ID), data); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityCategory, module, c.ID, before, data)
+	})
+}
+
+func (s *BadgerStore) DeleteCategory(ctx context.Context, workspaceID string, module string, id uuid.UUID) error {
+	return s.moveToTrash(ctx, workspaceID, model.EntityCategory, module, id)
+}
+
+// Contracts
+
+func (s *BadgerStore) ListContracts(_ context.Context, workspaceID string) ([]model.Contract, error) {
+	var contracts []model.Contract
+	prefix := conPrefix(workspaceID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var con model.Contract
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &con)
+			}); err != nil {
+				return err
+			}
+			if con.DeletedAt != nil {
+				continue
+			}
+			contracts = append(contracts, con)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if contracts == nil {
+		contracts = []model.Contract{}
+	}
+	return contracts, nil
+}
+
+func (s *BadgerStore) ListContractsByCategory(_ context.Context, workspaceID string, categoryID uuid.UUID) ([]model.Contract, error) {
+	var contracts []model.Contract
+	prefix := idxCatConPrefix(workspaceID, categoryID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().Key()
+			conIDStr := string(key[len(prefix):])
+			conID, err := uuid.Parse(conIDStr)
+			if err != nil {
+				continue
+			}
+
+			item, err := txn.Get(conKey(workspaceID, conID))
+			if err != nil {
+				if errors.Is(err, badger.ErrKeyNotFound) {
+					continue
+				}
+				return err
+			}
+
+			var con model.Contract
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &con)
+			}); err != nil {
+				return err
+			}
+			if con.DeletedAt != nil {
+				continue
+			}
+			contracts = append(contracts, con)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if contracts == nil {
+		contracts = []model.Contract{}
+	}
+	return contracts, nil
+}
+
+func (s *BadgerStore) GetContract(_ context.Context, workspaceID string, id uuid.UUID) (model.Contract, error) {
+	var con model.Contract
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(conKey(workspaceID, id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &con)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) || con.DeletedAt != nil {
+		return model.Contract{}, ErrNotFound
+	}
+	return con, err
+}
+
+func (s *BadgerStore) CreateContract(ctx context.Context, workspaceID string, c model.Contract) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := checkFree(txn, conKey(workspaceID, c.ID)); err != nil {
+			return err
+		}
+		if err := txn.Set(conKey(workspaceID, c.ID), data); err != nil {
+			return err
+		}
+		if err := txn.Set(idxCatConKey(workspaceID, c.CategoryID, c.ID), []byte{}); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityContract, "", c.ID, nil, data)
+	})
+}
+
+func (s *BadgerStore) UpdateContract(ctx context.Context, workspaceID string, c model.Contract, prices ...model.PriceEntry) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		before, err := getLive(txn, conKey(workspaceID, c.ID))
+		if err != nil {
+			return err
+		}
+		var old model.Contract
+		if err := json.Unmarshal(before, &old); err != nil {
+			return err
+		}
+
+		if err := txn.Set(conKey(workspaceID, c.ID), data); err != nil {
+			return err
+		}
+
+		if old.CategoryID != c.CategoryID {
+			if err := txn.Delete(idxCatConKey(workspaceID, old.CategoryID, c.ID)); err != nil {
+				return err
+			}
+			if err := txn.Set(idxCatConKey(workspaceID, c.CategoryID, c.ID), []byte{}); err != nil {
+				return err
+			}
+		}
+		for _, p := range prices {
+			if err := setPriceEntry(ctx, txn, workspaceID, p); err != nil {
+				return err
+			}
+		}
+
+		return recordChange(ctx, txn, workspaceID, model.EntityContract, "", c.ID, before, data)
+	})
+}
+
+func (s *BadgerStore) DeleteContract(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.moveToTrash(ctx, workspaceID, model.EntityContract, "", id)
+}
+
+// Price entries
+
+func (s *BadgerStore) ListPriceEntries(_ context.Context, workspaceID string) ([]model.PriceEntry, error) {
+	var entries []model.PriceEntry
+	prefix := pricePrefix(workspaceID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var p model.PriceEntry
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &p)
+			}); err != nil {
+				return err
+			}
+			// Price entries are hidden along with their contract.
+			if _, err := getLive(txn, conKey(workspaceID, p.ContractID)); errors.Is(err, ErrNotFound) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			entries = append(entries, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []model.PriceEntry{}
+	}
+	return entries, nil
+}
+
+func (s *BadgerStore) ListPriceEntriesByContract(_ context.Context, workspaceID string, contractID uuid.UUID) ([]model.PriceEntry, error) {
+	var entries []model.PriceEntry
+	prefix := idxConPricePrefix(workspaceID, contractID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().Key()
+			pIDStr := string(key[len(prefix):])
+			pID, err := uuid.Parse(pIDStr)
+			if err != nil {
+				continue
+			}
+
+			item, err := txn.Get(priceKey(workspaceID, pID))
+			if err != nil {
+				if errors.Is(err, badger.ErrKeyNotFound) {
+					continue
+				}
+				return err
+			}
+
+			var p model.PriceEntry
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &p)
+			}); err != nil {
+				return err
+			}
+			entries = append(entries, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []model.PriceEntry{}
+	}
+	return entries, nil
+}
+
+func (s *BadgerStore) GetPriceEntry(_ context.Context, workspaceID string, id uuid.UUID) (model.PriceEntry, error) {
+	var p model.PriceEntry
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(priceKey(workspaceID, id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &p)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return p, ErrNotFound
+	}
+	return p, err
+}
+
+func (s *BadgerStore) CreatePriceEntry(ctx context.Context, workspaceID string, p model.PriceEntry) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := getLive(txn, conKey(workspaceID, p.ContractID)); err != nil {
+			return err
+		}
+		return setPriceEntry(ctx, txn, workspaceID, p)
+	})
+}
+
+// setPriceEntry adds p to the history of its contract, which must exist.
+func setPriceEntry(ctx context.Context, txn *badger.Txn, workspaceID string, p model.PriceEntry) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := txn.Set(priceKey(workspaceID, p.ID), data); err != nil {
+		return err
+	}
+	if err := txn.Set(idxConPriceKey(workspaceID, p.ContractID, p.ID), []byte{}); err != nil {
+		return err
+	}
+	return recordChange(ctx, txn, workspaceID, model.EntityPriceEntry, "", p.ID, nil, data)
+}
+
+func (s *BadgerStore) DeletePriceEntry(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		item, err := txn.Get(priceKey(workspaceID, id))
+		if err != nil {
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		before, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		var p model.PriceEntry
+		if err := json.Unmarshal(before, &p); err != nil {
+			return err
+		}
+
+		if err := txn.Delete(priceKey(workspaceID, id)); err != nil {
+			return err
+		}
+		if err := txn.Delete(idxConPriceKey(workspaceID, p.ContractID, id)); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityPriceEntry, "", id, before, nil)
+	})
+}
+
+// deletePriceEntries removes the price history of a contract within txn.
+func deletePriceEntries(ctx context.Context, txn *badger.Txn, workspaceID string, contractID uuid.UUID) error {
+	idxPrefix := idxConPricePrefix(workspaceID, contractID)
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+
+	var priceIDs []uuid.UUID
+	for it.Seek(idxPrefix); it.ValidForPrefix(idxPrefix); it.Next() {
+		key := it.Item().Key()
+		pID, err := uuid.Parse(string(key[len(idxPrefix):]))
+		if err != nil {
+			continue
+		}
+		priceIDs = append(priceIDs, pID)
+	}
+	it.Close()
+
+	for _, pID := range priceIDs {
+		if err := deleteRecorded(ctx, txn, workspaceID, model.EntityPriceEntry, "", pID, priceKey(workspaceID, pID)); err != nil {
+			return err
+		}
+		if err := txn.Delete(idxConPriceKey(workspaceID, contractID, pID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Purchases
+
+func (s *BadgerStore) ListPurchases(_ context.Context, workspaceID string) ([]model.Purchase, error) {
+	var purchases []model.Purchase
+	prefix := purPrefix(workspaceID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var p model.Purchase
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &p)
+			}); err != nil {
+				return err
+			}
+			if p.DeletedAt != nil {
+				continue
+			}
+			purchases = append(purchases, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if purchases == nil {
+		purchases = []model.Purchase{}
+	}
+	return purchases, nil
+}
+
+func (s *BadgerStore) ListPurchasesByCategory(_ context.Context, workspaceID string, categoryID uuid.UUID) ([]model.Purchase, error) {
+	var purchases []model.Purchase
+	prefix := idxCatPurPrefix(workspaceID, categoryID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().Key()
+			pIDStr := string(key[len(prefix):])
+			pID, err := uuid.Parse(pIDStr)
+			if err != nil {
+				continue
+			}
+
+			item, err := txn.Get(purKey(workspaceID, pID))
+			if err != nil {
+				if errors.Is(err, badger.ErrKeyNotFound) {
+					continue
+				}
+				return err
+			}
+
+			var p model.Purchase
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &p)
+			}); err != nil {
+				return err
+			}
+			if p.DeletedAt != nil {
+				continue
+			}
+			purchases = append(purchases, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if purchases == nil {
+		purchases = []model.Purchase{}
+	}
+	return purchases, nil
+}
+
+func (s *BadgerStore) GetPurchase(_ context.Context, workspaceID string, id uuid.UUID) (model.Purchase, error) {
+	var p model.Purchase
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(purKey(workspaceID, id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &p)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) || p.DeletedAt != nil {
+		return model.Purchase{}, ErrNotFound
+	}
+	return p, err
+}
+
+func (s *BadgerStore) CreatePurchase(ctx context.Context, workspaceID string, p model.Purchase) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := checkFree(txn, purKey(workspaceID, p.ID)); err != nil {
+			return err
+		}
+		if err := txn.Set(purKey(workspaceID, p.ID), data); err != nil {
+			return err
+		}
+		if err := txn.Set(idxCatPurKey(workspaceID, p.CategoryID, p.ID), []byte{}); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityPurchase, "", p.ID, nil, data)
+	})
+}
+
+func (s *BadgerStore) UpdatePurchase(ctx context.Context, workspaceID string, p model.Purchase) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		before, err := getLive(txn, purKey(workspaceID, p.ID))
+		if err != nil {
+			return err
+		}
+		var old model.Purchase
+		if err := json.Unmarshal(before, &old); err != nil {
+			return err
+		}
+
+		if err := txn.Set(purKey(workspaceID, p.ID), data); err != nil {
+			return err
+		}
+
+		if old.CategoryID != p.CategoryID {
+			if err := txn.Delete(idxCatPurKey(workspaceID, old.CategoryID, p.ID)); err != nil {
+				return err
+			}
+			if err := txn.Set(idxCatPurKey(workspaceID, p.CategoryID, p.ID), []byte{}); err != nil {
+				return err
+			}
+		}
+
+		return recordChange(ctx, txn, workspaceID, model.EntityPurchase, "", p.ID, before, data)
+	})
+}
+
+func (s *BadgerStore) DeletePurchase(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.moveToTrash(ctx, workspaceID, model.EntityPurchase, "", id)
+}
+
+// Vehicle key helpers
+// Key format: w/{workspaceID}/veh/{vehicleID}
+
+func vehKey(workspaceID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/veh/%s", workspaceID, id))
+}
+
+func vehPrefix(workspaceID string) []byte {
+	return []byte(fmt.Sprintf("w/%s/veh/", workspaceID))
+}
+
+// Cost entry key helpers
+// Key format: w/{workspaceID}/cost/{costEntryID}
+// Index: w/{workspaceID}/idx/veh_cost/{vehicleID}/{costEntryID}
+
+func costKey(workspaceID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/cost/%s", workspaceID, id))
+}
+
+func costPrefix(workspaceID string) []byte {
+	return []byte(fmt.Sprintf("w/%s/cost/", workspaceID))
+}
+
+func idxVehCostKey(workspaceID string, vehicleID, costID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/veh_cost/%s/%s", workspaceID, vehicleID, costID))
+}
+
+func idxVehCostPrefix(workspaceID string, vehicleID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/veh_cost/%s/", workspaceID, vehicleID))
+}
+
+// Vehicles
+
+func (s *BadgerStore) ListVehicles(_ context.Context, workspaceID string) ([]model.Vehicle, error) {
+	var vehicles []model.Vehicle
+	prefix := vehPrefix(workspaceID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var v model.Vehicle
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &v)
+			}); err != nil {
+				return err
+			}
+			if v.DeletedAt != nil {
+				continue
+			}
+			vehicles = append(vehicles, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if vehicles == nil {
+		vehicles = []model.Vehicle{}
+	}
+	return vehicles, nil
+}
+
+func (s *BadgerStore) GetVehicle(_ context.Context, workspaceID string, id uuid.UUID) (model.Vehicle, error) {
+	var v model.Vehicle
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(vehKey(workspaceID, id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &v)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) || v.DeletedAt != nil {
+		return model.Vehicle{}, ErrNotFound
+	}
+	return v, err
+}
+
+func (s *BadgerStore) CreateVehicle(ctx context.Context, workspaceID string, v model.Vehicle) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := checkFree(txn, vehKey(workspaceID, v.ID)); err != nil {
+			return err
+		}
+		if err := txn.Set(vehKey(workspaceID, v.ID), data); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityVehicle, "", v.ID, nil, data)
+	})
+}
+
+func (s *BadgerStore) UpdateVehicle(ctx context.Context, workspaceID string, v model.Vehicle) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		before, err := getLive(txn, vehKey(workspaceID, v.ID))
+		if err != nil {
+			return err
+		}
+		if err := txn.Set(vehKey(workspaceID, v.ID), data); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityVehicle, "", v.ID, before, data)
+	})
+}
+
+func (s *BadgerStore) DeleteVehicle(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.moveToTrash(ctx, workspaceID, model.EntityVehicle, "", id)
+}
+
+// Cost Entries
+
+func (s *BadgerStore) ListCostEntries(_ context.Context, workspaceID string, vehicleID uuid.UUID) ([]model.CostEntry, error) {
+	var entries []model.CostEntry
+	prefix := idxVehCostPrefix(workspaceID, vehicleID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().Key()
+			cIDStr := string(key[len(prefix):])
+			cID, err := uuid.Parse(cIDStr)
+			if err != nil {
+				continue
+			}
+
+			item, err := txn.Get(costKey(workspaceID, cID))
+			if err != nil {
+				if errors.Is(err, badger.ErrKeyNotFound) {
+					continue
+				}
+				return err
+			}
+
+			var c model.CostEntry
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &c)
+			}); err != nil {
+				return err
+			}
+			if c.DeletedAt != nil {
+				continue
+			}
+			entries = append(entries, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []model.CostEntry{}
+	}
+	return entries, nil
+}
+
+func (s *BadgerStore) GetCostEntry(_ context.Context, workspaceID string, id uuid.UUID) (model.CostEntry, error) {
+	var c model.CostEntry
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(costKey(workspaceID, id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &c)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) || c.DeletedAt != nil {
+		return model.CostEntry{}, ErrNotFound
+	}
+	return c, err
+}
+
+func (s *BadgerStore) CreateCostEntry(ctx context.Context, workspaceID string, c model.CostEntry) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		if err := checkFree(txn, costKey(workspaceID, c.ID)); err != nil {
+			return err
+		}
+		if err := txn.Set(costKey(workspaceID, c.ID), data); err != nil {
+			return err
+		}
+		if err := txn.Set(idxVehCostKey(workspaceID, c.VehicleID, c.ID), []byte{}); err != nil {
+			return err
+		}
+		return recordChange(ctx, txn, workspaceID, model.EntityCostEntry, "", c.ID, nil, data)
+	})
 }
 
-func (s *BadgerStore) UpdateCostEntry(_ context.Context, userID string, c model.CostEntry) error {
+func (s *BadgerStore) UpdateCostEntry(ctx context.Context, workspaceID string, c model.CostEntry) error {
 	data, err := json.Marshal(c)
 	if err != nil {
 		return err
 	}
 	return s.db.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get(costKey(userID, c.ID))
+		before, err := getLive(txn, costKey(workspaceID, c.ID))
 		if err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrNotFound
-			}
 			return err
 		}
-
 		var old model.CostEntry
-		if err := item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &old)
-		}); err != nil {
+		if err := json.Unmarshal(before, &old); err != nil {
 			return err
 		}
 
-		if err := txn.Set(costKey(userID, c.ID), data); err != nil {
+		if err := txn.Set(costKey(workspaceID, c.ID), data); err != nil {
 			return err
 		}
 
 		if old.VehicleID != c.VehicleID {
-			if err := txn.Delete(idxVehCostKey(userID, old.VehicleID, c.ID)); err != nil {
+			if err := txn.Delete(idxVehCostKey(workspaceID, old.VehicleID, c.ID)); err != nil {
 				return err
 			}
-			if err := txn.Set(idxVehCostKey(userID, c.VehicleID, c.ID), []byte{}); err != nil {
+			if err := txn.Set(idxVehCostKey(workspaceID, c.VehicleID, c.ID), []byte{}); err != nil {
 				return err
 			}
 		}
 
+		return recordChange(ctx, txn, workspaceID, model.EntityCostEntry, "", c.ID, before, data)
+	})
+}
+
+func (s *BadgerStore) DeleteCostEntry(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.moveToTrash(ctx, workspaceID, model.EntityCostEntry, "", id)
+}
+
+// Trash
+//
+// Deleting a record sets its deletedAt field in place, so it keeps its key
+// and index entries until it is purged. Children moved to the trash with
+// their parent get the same deletedAt, which tells them apart from children
+// that were already in the trash when their parent is restored.
+
+// trashRecord locates a record that deleting moves to the trash.
+type trashRecord struct {
+	entityType string
+	module     string
+	id         uuid.UUID
+	key        []byte
+}
+
+func newTrashRecord(workspaceID, entityType, module string, id uuid.UUID) trashRecord {
+	r := trashRecord{entityType: entityType, module: module, id: id}
+	switch entityType {
+	case model.EntityCategory:
+		r.key = modCatKey(workspaceID, module, id)
+	case model.EntityContract:
+		r.key = conKey(workspaceID, id)
+	case model.EntityPurchase:
+		r.key = purKey(workspaceID, id)
+	case model.EntityVehicle:
+		r.key = vehKey(workspaceID, id)
+	case model.EntityCostEntry:
+		r.key = costKey(workspaceID, id)
+	}
+	return r
+}
+
+// findTrashRecord locates a record by type and ID, looking for categories
+// in every module.
+func findTrashRecord(txn *badger.Txn, workspaceID, entityType string, id uuid.UUID) (trashRecord, error) {
+	if entityType != model.EntityCategory {
+		r := newTrashRecord(workspaceID, entityType, "", id)
+		if r.key == nil {
+			return r, ErrNotFound
+		}
+		return r, nil
+	}
+	for _, module := range model.Modules {
+		r := newTrashRecord(workspaceID, entityType, module, id)
+		_, err := txn.Get(r.key)
+		if err == nil {
+			return r, nil
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return r, err
+		}
+	}
+	return trashRecord{}, ErrNotFound
+}
+
+// trashChildren returns the contracts or purchases of a category, or the
+// cost entries of a vehicle, whether or not they are in the trash.
+func trashChildren(txn *badger.Txn, workspaceID string, r trashRecord) []trashRecord {
+	var prefix []byte
+	var entityType string
+	switch {
+	case r.entityType == model.EntityCategory && r.module == "contracts":
+		prefix, entityType = idxCatConPrefix(workspaceID, r.id), model.EntityContract
+	case r.entityType == model.EntityCategory && r.module == "purchases":
+		prefix, entityType = idxCatPurPrefix(workspaceID, r.id), model.EntityPurchase
+	case r.entityType == model.EntityVehicle:
+		prefix, entityType = idxVehCostPrefix(workspaceID, r.id), model.EntityCostEntry
+	default:
+		return nil
+	}
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	var children []trashRecord
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		id, err := uuid.Parse(string(it.Item().Key()[len(prefix):]))
+		if err != nil {
+			continue
+		}
+		children = append(children, newTrashRecord(workspaceID, entityType, "", id))
+	}
+	return children
+}
+
+// getRecord returns the record stored at key and its JSON deletedAt value,
+// which is nil unless the record is in the trash.
+func getRecord(txn *badger.Txn, key []byte) (val []byte, deletedAt json.RawMessage, err error) {
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if val, err = item.ValueCopy(nil); err != nil {
+		return nil, nil, err
+	}
+	var r struct {
+		DeletedAt json.RawMessage `json:"deletedAt"`
+	}
+	if err := json.Unmarshal(val, &r); err != nil {
+		return nil, nil, err
+	}
+	if string(r.DeletedAt) == "null" {
+		r.DeletedAt = nil
+	}
+	return val, r.DeletedAt, nil
+}
+
+// getLive returns the record stored at key, failing with ErrNotFound if it
+// is in the trash.
+func getLive(txn *badger.Txn, key []byte) ([]byte, error) {
+	val, deletedAt, err := getRecord(txn, key)
+	if err == nil && deletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return val, err
+}
+
+// checkFree fails with ErrConflict if a record is stored at key, even one in
+// the trash.
+func checkFree(txn *badger.Txn, key []byte) error {
+	_, err := txn.Get(key)
+	if err == nil {
+		return ErrConflict
+	}
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// parentKey returns the key of the category of a contract or purchase, or
+// of the vehicle of a cost entry; nil for other records.
+func parentKey(workspaceID, entityType string, val []byte) ([]byte, error) {
+	var refs struct {
+		CategoryID uuid.UUID `json:"categoryId"`
+		VehicleID  uuid.UUID `json:"vehicleId"`
+	}
+	if err := json.Unmarshal(val, &refs); err != nil {
+		return nil, err
+	}
+	switch entityType {
+	case model.EntityContract:
+		return modCatKey(workspaceID, "contracts", refs.CategoryID), nil
+	case model.EntityPurchase:
+		return modCatKey(workspaceID, "purchases", refs.CategoryID), nil
+	case model.EntityCostEntry:
+		return vehKey(workspaceID, refs.VehicleID), nil
+	}
+	return nil, nil
+}
+
+// setDeletedAt sets the deletedAt field of a record to a JSON value, or
+// clears it if deletedAt is nil, and records the change.
+func setDeletedAt(ctx context.Context, txn *badger.Txn, workspaceID string, r trashRecord, before []byte, deletedAt json.RawMessage) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(before, &fields); err != nil {
+		return err
+	}
+	if deletedAt == nil {
+		delete(fields, "deletedAt")
+	} else {
+		fields["deletedAt"] = deletedAt
+	}
+	after, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := txn.Set(r.key, after); err != nil {
+		return err
+	}
+	return recordChange(ctx, txn, workspaceID, r.entityType, r.module, r.id, before, after)
+}
+
+// moveToTrash marks a record that is not in the trash, and its children that
+// are not either, as deleted now.
+func (s *BadgerStore) moveToTrash(ctx context.Context, workspaceID, entityType, module string, id uuid.UUID) error {
+	deletedAt, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	r := newTrashRecord(workspaceID, entityType, module, id)
+	return s.db.Update(func(txn *badger.Txn) error {
+		before, err := getLive(txn, r.key)
+		if err != nil {
+			return err
+		}
+		if err := setDeletedAt(ctx, txn, workspaceID, r, before, deletedAt); err != nil {
+			return err
+		}
+		for _, c := range trashChildren(txn, workspaceID, r) {
+			val, err := getLive(txn, c.key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := setDeletedAt(ctx, txn, workspaceID, c, val, deletedAt); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 }
 
-func (s *BadgerStore) DeleteCostEntry(_ context.Context, userID string, id uuid.UUID) error {
+// appendTrashed appends the records under prefix that are in the trash.
+func appendTrashed[T any](txn *badger.Txn, items []model.TrashItem, prefix []byte, item func(T) (model.TrashItem, bool)) ([]model.TrashItem, error) {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		var v T
+		if err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, &v)
+		}); err != nil {
+			return nil, err
+		}
+		if ti, ok := item(v); ok {
+			items = append(items, ti)
+		}
+	}
+	return items, nil
+}
+
+func (s *BadgerStore) ListTrash(_ context.Context, workspaceID string) ([]model.TrashItem, error) {
+	items := []model.TrashItem{}
+	err := s.db.View(func(txn *badger.Txn) error {
+		var err error
+		for _, module := range model.Modules {
+			items, err = appendTrashed(txn, items, modCatPrefix(workspaceID, module), func(c model.Category) (model.TrashItem, bool) {
+				return c.TrashItem(module)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if items, err = appendTrashed(txn, items, conPrefix(workspaceID), model.Contract.TrashItem); err != nil {
+			return err
+		}
+		if items, err = appendTrashed(txn, items, purPrefix(workspaceID), model.Purchase.TrashItem); err != nil {
+			return err
+		}
+		if items, err = appendTrashed(txn, items, vehPrefix(workspaceID), model.Vehicle.TrashItem); err != nil {
+			return err
+		}
+		items, err = appendTrashed(txn, items, costPrefix(workspaceID), model.CostEntry.TrashItem)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortTrash(items)
+	return items, nil
+}
+
+func (s *BadgerStore) RestoreFromTrash(ctx context.Context, workspaceID, entityType string, id uuid.UUID) error {
 	return s.db.Update(func(txn *badger.Txn) error {
-		item, err := txn.Get(costKey(userID, id))
+		r, err := findTrashRecord(txn, workspaceID, entityType, id)
 		if err != nil {
-			if errors.Is(err, badger.ErrKeyNotFound) {
-				return ErrNotFound
+			return err
+		}
+		before, deletedAt, err := getRecord(txn, r.key)
+		if err != nil {
+			return err
+		}
+		if deletedAt == nil {
+			return ErrNotFound
+		}
+
+		parent, err := parentKey(workspaceID, r.entityType, before)
+		if err != nil {
+			return err
+		}
+		if parent != nil {
+			if _, err := getLive(txn, parent); errors.Is(err, ErrNotFound) {
+				return ErrConflict
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if err := setDeletedAt(ctx, txn, workspaceID, r, before, nil); err != nil {
+			return err
+		}
+		for _, c := range trashChildren(txn, workspaceID, r) {
+			val, at, err := getRecord(txn, c.key)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(at, deletedAt) {
+				continue
+			}
+			if err := setDeletedAt(ctx, txn, workspaceID, c, val, nil); err != nil {
+				return err
 			}
+		}
+		return nil
+	})
+}
+
+func (s *BadgerStore) PurgeFromTrash(ctx context.Context, workspaceID, entityType string, id uuid.UUID) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		r, err := findTrashRecord(txn, workspaceID, entityType, id)
+		if err != nil {
 			return err
 		}
+		before, deletedAt, err := getRecord(txn, r.key)
+		if err != nil {
+			return err
+		}
+		if deletedAt == nil {
+			return ErrNotFound
+		}
+		return purge(ctx, txn, workspaceID, r, before)
+	})
+}
 
-		var c model.CostEntry
-		if err := item.Value(func(val []byte) error {
-			return json.Unmarshal(val, &c)
-		}); err != nil {
+// purge deletes a record for good within txn, together with its children,
+// the price history of a contract and its index entries.
+func purge(ctx context.Context, txn *badger.Txn, workspaceID string, r trashRecord, before []byte) error {
+	for _, c := range trashChildren(txn, workspaceID, r) {
+		val, _, err := getRecord(txn, c.key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := purge(ctx, txn, workspaceID, c, val); err != nil {
 			return err
 		}
+	}
 
-		if err := txn.Delete(costKey(userID, id)); err != nil {
+	var refs struct {
+		CategoryID uuid.UUID `json:"categoryId"`
+		VehicleID  uuid.UUID `json:"vehicleId"`
+	}
+	if err := json.Unmarshal(before, &refs); err != nil {
+		return err
+	}
+	var idx []byte
+	switch r.entityType {
+	case model.EntityContract:
+		idx = idxCatConKey(workspaceID, refs.CategoryID, r.id)
+		if err := deletePriceEntries(ctx, txn, workspaceID, r.id); err != nil {
+			return err
+		}
+	case model.EntityPurchase:
+		idx = idxCatPurKey(workspaceID, refs.CategoryID, r.id)
+	case model.EntityCostEntry:
+		idx = idxVehCostKey(workspaceID, refs.VehicleID, r.id)
+	}
+	if idx != nil {
+		if err := txn.Delete(idx); err != nil {
 			return err
 		}
-		return txn.Delete(idxVehCostKey(userID, c.VehicleID, id))
+	}
+
+	if err := txn.Delete(r.key); err != nil {
+		return err
+	}
+	return recordChange(ctx, txn, workspaceID, r.entityType, r.module, r.id, before, nil)
+}
+
+func (s *BadgerStore) PurgeTrash(ctx context.Context, cutoff time.Time) (int, error) {
+	var workspaceIDs []string
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		// Only the workspace records themselves are ws/{id}; their members
+		// and invitations have longer keys.
+		prefix := []byte("ws/")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			id, err := uuid.Parse(string(it.Item().Key()[len(prefix):]))
+			if err != nil {
+				continue
+			}
+			workspaceIDs = append(workspaceIDs, id.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purgeTrash(ctx, s, workspaceIDs, cutoff)
+}
+
+// Audit log key helpers
+// Key format: w/{workspaceID}/audit/{entryID}
+// Index: w/{workspaceID}/idx/audit/{entityID}/{entryID}
+// Entry IDs are time-ordered, so both sort oldest first.
+
+func auditKey(workspaceID string, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/audit/%s", workspaceID, id))
+}
+
+func auditPrefix(workspaceID string) []byte {
+	return []byte(fmt.Sprintf("w/%s/audit/", workspaceID))
+}
+
+func idxAuditKey(workspaceID string, entityID, id uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/audit/%s/%s", workspaceID, entityID, id))
+}
+
+func idxAuditPrefix(workspaceID string, entityID uuid.UUID) []byte {
+	return []byte(fmt.Sprintf("w/%s/idx/audit/%s/", workspaceID, entityID))
+}
+
+// recordChange writes the audit entry for a change of an entity within the
+// transaction that makes it.
+func recordChange(ctx context.Context, txn *badger.Txn, workspaceID, entityType, module string, id uuid.UUID, before, after []byte) error {
+	entry, ok, err := newAuditEntry(ctx, entityType, module, id, before, after)
+	if err != nil || !ok {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := txn.Set(auditKey(workspaceID, entry.ID), data); err != nil {
+		return err
+	}
+	return txn.Set(idxAuditKey(workspaceID, id, entry.ID), []byte{})
+}
+
+// deleteRecorded deletes the entity stored at key, if there is one, and
+// records it.
+func deleteRecorded(ctx context.Context, txn *badger.Txn, workspaceID, entityType, module string, id uuid.UUID, key []byte) error {
+	item, err := txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	before, err := item.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+	if err := txn.Delete(key); err != nil {
+		return err
+	}
+	return recordChange(ctx, txn, workspaceID, entityType, module, id, before, nil)
+}
+
+// ListAuditEntries returns the changes of one entity, oldest first.
+func (s *BadgerStore) ListAuditEntries(_ context.Context, workspaceID string, entityID uuid.UUID) ([]model.AuditEntry, error) {
+	entries := []model.AuditEntry{}
+	prefix := idxAuditPrefix(workspaceID, entityID)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			id, err := uuid.Parse(string(it.Item().Key()[len(prefix):]))
+			if err != nil {
+				continue
+			}
+			item, err := txn.Get(auditKey(workspaceID, id))
+			if err != nil {
+				return err
+			}
+			var e model.AuditEntry
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &e)
+			}); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// ListActivity returns up to limit changes in a workspace, newest first,
+// starting after the entry before, or with the latest if before is nil.
+func (s *BadgerStore) ListActivity(_ context.Context, workspaceID string, before uuid.UUID, limit int) ([]model.AuditEntry, error) {
+	entries := []model.AuditEntry{}
+	prefix := auditPrefix(workspaceID)
+	// Reverse iteration starts at the last key at or before the seek key.
+	seek := append(auditPrefix(workspaceID), 0xff)
+	if before != uuid.Nil {
+		seek = auditKey(workspaceID, before)
+	}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(seek); it.ValidForPrefix(prefix) && len(entries) < limit; it.Next() {
+			if bytes.Equal(it.Item().Key(), seek) {
+				continue
+			}
+			var e model.AuditEntry
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &e)
+			}); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+		}
+		return nil
 	})
+	return entries, err
 }
@@ -1,22 +1,26 @@
 package store
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/dgraph-io/badger/v4"
 	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/model"
 )
 
-const testUser = "test-user"
-const testModule = "contracts"
-
 func newTestStore(t *testing.T) *BadgerStore {
 	t.Helper()
-	s, err := NewBadgerStore(t.TempDir(), slog.Default())
+	s, err := NewBadgerStore(t.TempDir(), nil, slog.Default())
 	if err != nil {
 		t.Fatalf("NewBadgerStore: %v", err)
 	}
@@ -24,661 +28,273 @@ func newTestStore(t *testing.T) *BadgerStore {
 	return s
 }
 
-func makeCategory(name string) model.Category {
-	now := time.Now().UTC()
-	return model.Category{
-		ID:        uuid.New(),
-		Name:      name,
-		CreatedAt: now,
-		UpdatedAt: now,
-	}
-}
-
-func makeContract(categoryID uuid.UUID, name string) model.Contract {
-	now := time.Now().UTC()
-	return model.Contract{
-		ID:         uuid.New(),
-		CategoryID: categoryID,
-		Name:       name,
-		StartDate:  "2025-01-01",
-		CreatedAt:  now,
-		UpdatedAt:  now,
-	}
-}
-
-func makePurchase(categoryID uuid.UUID, name string) model.Purchase {
-	now := time.Now().UTC()
-	return model.Purchase{
-		ID:         uuid.New(),
-		CategoryID: categoryID,
-		ItemName:   name,
-		CreatedAt:  now,
-		UpdatedAt:  now,
-	}
-}
-
-// Category CRUD
-
-func TestCreateAndGetCategory(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Insurance")
-
-	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
-		t.Fatalf("CreateCategory: %v", err)
-	}
-
-	got, err := s.GetCategory(ctx, testUser, testModule, cat.ID)
-	if err != nil {
-		t.Fatalf("GetCategory: %v", err)
-	}
-	if got.Name != cat.Name {
-		t.Errorf("Name = %q, want %q", got.Name, cat.Name)
-	}
-}
-
-func TestListCategories(t *testing.T) {
+func TestDeleteWorkspace_MoreKeysThanOneTransaction(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
 
-	cats, err := s.ListCategories(ctx, testUser, testModule)
-	if err != nil {
-		t.Fatalf("ListCategories: %v", err)
-	}
-	if len(cats) != 0 {
-		t.Fatalf("expected empty list, got %d", len(cats))
-	}
-
-	for _, name := range []string{"A", "B", "C"} {
-		if err := s.CreateCategory(ctx, testUser, testModule, makeCategory(name)); err != nil {
-			t.Fatalf("CreateCategory(%s): %v", name, err)
+	ws := model.Workspace{ID: uuid.New(), Name: "Home", CreatedAt: time.Now().UTC()}
+	owner := model.WorkspaceMember{WorkspaceID: ws.ID, UserID: testUser, Role: model.WorkspaceRoleOwner}
+	if err := s.CreateWorkspace(ctx, ws, owner); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	prefix := wsDataPrefix(ws.ID)
+	n := int(s.db.MaxBatchCount()) + 100
+	wb := s.db.NewWriteBatch()
+	for i := range n {
+		if err := wb.Set([]byte(fmt.Sprintf("%sc/%08d", prefix, i)), []byte("{}")); err != nil {
+			t.Fatalf("writing keys: %v", err)
 		}
 	}
-
-	cats, err = s.ListCategories(ctx, testUser, testModule)
-	if err != nil {
-		t.Fatalf("ListCategories: %v", err)
+	if err := wb.Flush(); err != nil {
+		t.Fatalf("writing keys: %v", err)
 	}
-	if len(cats) != 3 {
-		t.Fatalf("expected 3 categories, got %d", len(cats))
-	}
-}
-
-func TestUpdateCategory(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Old")
 
-	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
-		t.Fatalf("CreateCategory: %v", err)
+	if err := s.DeleteWorkspace(ctx, ws.ID); err != nil {
+		t.Fatalf("DeleteWorkspace: %v", err)
 	}
-
-	cat.Name = "New"
-	cat.UpdatedAt = time.Now().UTC()
-	if err := s.UpdateCategory(ctx, testUser, testModule, cat); err != nil {
-		t.Fatalf("UpdateCategory: %v", err)
-	}
-
-	got, err := s.GetCategory(ctx, testUser, testModule, cat.ID)
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			return fmt.Errorf("key %s left behind", it.Item().Key())
+		}
+		return nil
+	})
 	if err != nil {
-		t.Fatalf("GetCategory: %v", err)
-	}
-	if got.Name != "New" {
-		t.Errorf("Name = %q, want %q", got.Name, "New")
+		t.Error(err)
 	}
 }
 
-func TestDeleteCategory(t *testing.T) {
+func TestBackup_RestoreIntoEmptyPath(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
-	cat := makeCategory("ToDelete")
 
+	cat := makeCategory("Insurance")
 	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
 		t.Fatalf("CreateCategory: %v", err)
 	}
-	if err := s.DeleteCategory(ctx, testUser, testModule, cat.ID); err != nil {
-		t.Fatalf("DeleteCategory: %v", err)
-	}
-
-	_, err := s.GetCategory(ctx, testUser, testModule, cat.ID)
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
-	}
-}
-
-func TestGetCategory_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	_, err := s.GetCategory(context.Background(), testUser, testModule, uuid.New())
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
-	}
-}
-
-func TestUpdateCategory_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	err := s.UpdateCategory(context.Background(), testUser, testModule, makeCategory("Ghost"))
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
-	}
-}
-
-func TestDeleteCategory_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	err := s.DeleteCategory(context.Background(), testUser, testModule, uuid.New())
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
-	}
-}
-
-// Module isolation for categories
-
-func TestCategoryModuleIsolation(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	cat := makeCategory("Insurance")
-	s.CreateCategory(ctx, testUser, "contracts", cat)
-
-	// Should not be visible under "purchases" module
-	cats, _ := s.ListCategories(ctx, testUser, "purchases")
-	if len(cats) != 0 {
-		t.Errorf("purchases module should see 0 categories, got %d", len(cats))
-	}
-
-	_, err := s.GetCategory(ctx, testUser, "purchases", cat.ID)
-	if !errors.Is(err, ErrNotFound) {
-		t.Errorf("purchases module should get ErrNotFound, got %v", err)
+	var buf bytes.Buffer
+	if err := s.Backup(&buf); err != nil {
+		t.Fatalf("Backup: %v", err)
 	}
-}
 
-// Contract CRUD
-
-func TestCreateAndGetContract(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, testModule, cat)
-
-	con := makeContract(cat.ID, "Phone Plan")
-	if err := s.CreateContract(ctx, testUser, con); err != nil {
-		t.Fatalf("CreateContract: %v", err)
+	path := t.TempDir()
+	if err := RestoreBadger(path, nil, bytes.NewReader(buf.Bytes()), slog.Default()); err != nil {
+		t.Fatalf("RestoreBadger: %v", err)
 	}
-
-	got, err := s.GetContract(ctx, testUser, con.ID)
+	restored, err := NewBadgerStore(path, nil, slog.Default())
 	if err != nil {
-		t.Fatalf("GetContract: %v", err)
-	}
-	if got.Name != con.Name {
-		t.Errorf("Name = %q, want %q", got.Name, con.Name)
+		t.Fatalf("NewBadgerStore: %v", err)
 	}
-	if got.CategoryID != cat.ID {
-		t.Errorf("CategoryID = %s, want %s", got.CategoryID, cat.ID)
+	defer restored.Close()
+	if got, err := restored.GetCategory(ctx, testUser, testModule, cat.ID); err != nil || got.Name != "Insurance" {
+		t.Errorf("restored category = %+v, %v", got, err)
 	}
 }
 
-func TestListContracts(t *testing.T) {
+func TestBackup_RestoreRefusesNonEmptyPath(t *testing.T) {
 	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, testModule, cat)
-
-	for _, name := range []string{"A", "B"} {
-		s.CreateContract(ctx, testUser, makeContract(cat.ID, name))
+	var buf bytes.Buffer
+	if err := s.Backup(&buf); err != nil {
+		t.Fatalf("Backup: %v", err)
 	}
 
-	all, err := s.ListContracts(ctx, testUser)
+	// A freshly opened store already holds its schema version.
+	path := t.TempDir()
+	existing, err := NewBadgerStore(path, nil, slog.Default())
 	if err != nil {
-		t.Fatalf("ListContracts: %v", err)
-	}
-	if len(all) != 2 {
-		t.Fatalf("expected 2 contracts, got %d", len(all))
-	}
-}
-
-func TestListContractsByCategory(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	cat1 := makeCategory("Cat1")
-	cat2 := makeCategory("Cat2")
-	s.CreateCategory(ctx, testUser, testModule, cat1)
-	s.CreateCategory(ctx, testUser, testModule, cat2)
-
-	s.CreateContract(ctx, testUser, makeContract(cat1.ID, "C1"))
-	s.CreateContract(ctx, testUser, makeContract(cat1.ID, "C2"))
-	s.CreateContract(ctx, testUser, makeContract(cat2.ID, "C3"))
-
-	list, err := s.ListContractsByCategory(ctx, testUser, cat1.ID)
-	if err != nil {
-		t.Fatalf("ListContractsByCategory: %v", err)
-	}
-	if len(list) != 2 {
-		t.Fatalf("expected 2 contracts for cat1, got %d", len(list))
+		t.Fatalf("NewBadgerStore: %v", err)
 	}
+	existing.Close()
 
-	list, err = s.ListContractsByCategory(ctx, testUser, cat2.ID)
-	if err != nil {
-		t.Fatalf("ListContractsByCategory: %v", err)
-	}
-	if len(list) != 1 {
-		t.Fatalf("expected 1 contract for cat2, got %d", len(list))
+	if err := RestoreBadger(path, nil, &buf, slog.Default()); err == nil {
+		t.Error("expected error restoring into a non-empty database")
 	}
 }
 
-func TestUpdateContract(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, testModule, cat)
-
-	con := makeContract(cat.ID, "Old")
-	s.CreateContract(ctx, testUser, con)
-
-	con.Name = "New"
-	con.UpdatedAt = time.Now().UTC()
-	if err := s.UpdateContract(ctx, testUser, con); err != nil {
-		t.Fatalf("UpdateContract: %v", err)
-	}
+var (
+	testKey  = []byte("0123456789abcdef0123456789abcdef")
+	otherKey = []byte("fedcba9876543210")
+)
 
-	got, err := s.GetContract(ctx, testUser, con.ID)
+// seedCategory writes one category to a new store at path and closes it.
+func seedCategory(t *testing.T, path string, key []byte) model.Category {
+	t.Helper()
+	s, err := NewBadgerStore(path, key, slog.Default())
 	if err != nil {
-		t.Fatalf("GetContract: %v", err)
-	}
-	if got.Name != "New" {
-		t.Errorf("Name = %q, want %q", got.Name, "New")
-	}
-}
-
-func TestDeleteContract(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, testModule, cat)
-
-	con := makeContract(cat.ID, "ToDelete")
-	s.CreateContract(ctx, testUser, con)
-
-	if err := s.DeleteContract(ctx, testUser, con.ID); err != nil {
-		t.Fatalf("DeleteContract: %v", err)
-	}
-
-	_, err := s.GetContract(ctx, testUser, con.ID)
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
-	}
-}
-
-func TestGetContract_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	_, err := s.GetContract(context.Background(), testUser, uuid.New())
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
-	}
-}
-
-func TestUpdateContract_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	cat := makeCategory("Cat")
-	s.CreateCategory(context.Background(), testUser, testModule, cat)
-	err := s.UpdateContract(context.Background(), testUser, makeContract(cat.ID, "Ghost"))
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
+		t.Fatalf("NewBadgerStore: %v", err)
 	}
-}
-
-func TestDeleteContract_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	err := s.DeleteContract(context.Background(), testUser, uuid.New())
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
+	defer s.Close()
+	cat := makeCategory("Insurance")
+	if err := s.CreateCategory(context.Background(), testUser, testModule, cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
 	}
+	return cat
 }
 
-// Cascade delete
-
-func TestDeleteCategory_CascadesContracts(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, testModule, cat)
-
-	con1 := makeContract(cat.ID, "C1")
-	con2 := makeContract(cat.ID, "C2")
-	s.CreateContract(ctx, testUser, con1)
-	s.CreateContract(ctx, testUser, con2)
-
-	if err := s.DeleteCategory(ctx, testUser, testModule, cat.ID); err != nil {
-		t.Fatalf("DeleteCategory: %v", err)
-	}
-
-	// Both contracts should be gone
-	for _, id := range []uuid.UUID{con1.ID, con2.ID} {
-		_, err := s.GetContract(ctx, testUser, id)
-		if !errors.Is(err, ErrNotFound) {
-			t.Errorf("contract %s: expected ErrNotFound, got %v", id, err)
-		}
-	}
-
-	// Index should be clean
-	list, err := s.ListContractsByCategory(ctx, testUser, cat.ID)
+func assertCategory(t *testing.T, path string, key []byte, cat model.Category) {
+	t.Helper()
+	s, err := NewBadgerStore(path, key, slog.Default())
 	if err != nil {
-		t.Fatalf("ListContractsByCategory: %v", err)
+		t.Fatalf("NewBadgerStore: %v", err)
 	}
-	if len(list) != 0 {
-		t.Errorf("expected 0 contracts after cascade, got %d", len(list))
+	defer s.Close()
+	if got, err := s.GetCategory(context.Background(), testUser, testModule, cat.ID); err != nil || got.Name != cat.Name {
+		t.Errorf("category = %+v, %v", got, err)
 	}
 }
 
-func TestDeleteCategory_CascadesPurchases(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, "purchases", cat)
-
-	p1 := makePurchase(cat.ID, "Item1")
-	p2 := makePurchase(cat.ID, "Item2")
-	s.CreatePurchase(ctx, testUser, p1)
-	s.CreatePurchase(ctx, testUser, p2)
-
-	if err := s.DeleteCategory(ctx, testUser, "purchases", cat.ID); err != nil {
-		t.Fatalf("DeleteCategory: %v", err)
-	}
+func TestEncryption_RefusesWrongKey(t *testing.T) {
+	path := t.TempDir()
+	cat := seedCategory(t, path, testKey)
 
-	for _, id := range []uuid.UUID{p1.ID, p2.ID} {
-		_, err := s.GetPurchase(ctx, testUser, id)
-		if !errors.Is(err, ErrNotFound) {
-			t.Errorf("purchase %s: expected ErrNotFound, got %v", id, err)
+	for name, key := range map[string][]byte{"other key": otherKey, "no key": nil} {
+		if _, err := NewBadgerStore(path, key, slog.Default()); !errors.Is(err, ErrWrongKey) {
+			t.Errorf("%s: err = %v, want ErrWrongKey", name, err)
 		}
 	}
+	assertCategory(t, path, testKey, cat)
 
-	list, err := s.ListPurchasesByCategory(ctx, testUser, cat.ID)
-	if err != nil {
-		t.Fatalf("ListPurchasesByCategory: %v", err)
-	}
-	if len(list) != 0 {
-		t.Errorf("expected 0 purchases after cascade, got %d", len(list))
+	plain := t.TempDir()
+	seedCategory(t, plain, nil)
+	if _, err := NewBadgerStore(plain, testKey, slog.Default()); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("key for unencrypted database: err = %v, want ErrWrongKey", err)
 	}
 }
 
-// Index consistency
-
-func TestUpdateContract_CategoryChange_UpdatesIndex(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
+func TestEncryption_DataIsNotPlaintext(t *testing.T) {
+	path := t.TempDir()
+	seedCategory(t, path, testKey)
 
-	cat1 := makeCategory("Cat1")
-	cat2 := makeCategory("Cat2")
-	s.CreateCategory(ctx, testUser, testModule, cat1)
-	s.CreateCategory(ctx, testUser, testModule, cat2)
-
-	con := makeContract(cat1.ID, "Moveable")
-	s.CreateContract(ctx, testUser, con)
-
-	con.CategoryID = cat2.ID
-	con.UpdatedAt = time.Now().UTC()
-	if err := s.UpdateContract(ctx, testUser, con); err != nil {
-		t.Fatalf("UpdateContract: %v", err)
-	}
-
-	list1, _ := s.ListContractsByCategory(ctx, testUser, cat1.ID)
-	list2, _ := s.ListContractsByCategory(ctx, testUser, cat2.ID)
-
-	if len(list1) != 0 {
-		t.Errorf("cat1 should have 0 contracts, got %d", len(list1))
-	}
-	if len(list2) != 1 {
-		t.Errorf("cat2 should have 1 contract, got %d", len(list2))
-	}
-}
-
-func TestDeleteContract_CleansIndex(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, testModule, cat)
-
-	con := makeContract(cat.ID, "C")
-	s.CreateContract(ctx, testUser, con)
-	s.DeleteContract(ctx, testUser, con.ID)
-
-	list, _ := s.ListContractsByCategory(ctx, testUser, cat.ID)
-	if len(list) != 0 {
-		t.Errorf("expected 0 contracts after delete, got %d", len(list))
-	}
-}
-
-// Purchase CRUD
-
-func TestCreateAndGetPurchase(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, "purchases", cat)
-
-	p := makePurchase(cat.ID, "GPU")
-	if err := s.CreatePurchase(ctx, testUser, p); err != nil {
-		t.Fatalf("CreatePurchase: %v", err)
-	}
-
-	got, err := s.GetPurchase(ctx, testUser, p.ID)
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(data, []byte("Insurance")) {
+			t.Errorf("%s contains the category name in plain text", d.Name())
+		}
+		return nil
+	})
 	if err != nil {
-		t.Fatalf("GetPurchase: %v", err)
-	}
-	if got.ItemName != p.ItemName {
-		t.Errorf("ItemName = %q, want %q", got.ItemName, p.ItemName)
-	}
-	if got.CategoryID != cat.ID {
-		t.Errorf("CategoryID = %s, want %s", got.CategoryID, cat.ID)
+		t.Fatal(err)
 	}
 }
 
-func TestListPurchases(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, "purchases", cat)
+func TestEncryptBadger(t *testing.T) {
+	src := t.TempDir()
+	cat := seedCategory(t, src, nil)
 
-	for _, name := range []string{"A", "B"} {
-		s.CreatePurchase(ctx, testUser, makePurchase(cat.ID, name))
+	dst := t.TempDir()
+	if err := EncryptBadger(src, dst, testKey, slog.Default()); err != nil {
+		t.Fatalf("EncryptBadger: %v", err)
 	}
+	assertCategory(t, dst, testKey, cat)
+	// The source is left unencrypted and intact.
+	assertCategory(t, src, nil, cat)
 
-	all, err := s.ListPurchases(ctx, testUser)
-	if err != nil {
-		t.Fatalf("ListPurchases: %v", err)
+	if err := EncryptBadger(src, dst, testKey, slog.Default()); err == nil {
+		t.Error("expected error encrypting into a non-empty directory")
 	}
-	if len(all) != 2 {
-		t.Fatalf("expected 2 purchases, got %d", len(all))
+	if err := EncryptBadger(dst, t.TempDir(), testKey, slog.Default()); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("encrypting an encrypted database: err = %v, want ErrWrongKey", err)
 	}
 }
 
-func TestListPurchasesByCategory(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	cat1 := makeCategory("Cat1")
-	cat2 := makeCategory("Cat2")
-	s.CreateCategory(ctx, testUser, "purchases", cat1)
-	s.CreateCategory(ctx, testUser, "purchases", cat2)
-
-	s.CreatePurchase(ctx, testUser, makePurchase(cat1.ID, "P1"))
-	s.CreatePurchase(ctx, testUser, makePurchase(cat1.ID, "P2"))
-	s.CreatePurchase(ctx, testUser, makePurchase(cat2.ID, "P3"))
-
-	list, err := s.ListPurchasesByCategory(ctx, testUser, cat1.ID)
+func TestBackup_EncryptedWithKey(t *testing.T) {
+	path := t.TempDir()
+	cat := seedCategory(t, path, testKey)
+	s, err := NewBadgerStore(path, testKey, slog.Default())
 	if err != nil {
-		t.Fatalf("ListPurchasesByCategory: %v", err)
-	}
-	if len(list) != 2 {
-		t.Fatalf("expected 2 purchases for cat1, got %d", len(list))
+		t.Fatalf("NewBadgerStore: %v", err)
 	}
-
-	list, err = s.ListPurchasesByCategory(ctx, testUser, cat2.ID)
+	var buf bytes.Buffer
+	err = s.Backup(&buf)
+	s.Close()
 	if err != nil {
-		t.Fatalf("ListPurchasesByCategory: %v", err)
+		t.Fatalf("Backup: %v", err)
 	}
-	if len(list) != 1 {
-		t.Fatalf("expected 1 purchase for cat2, got %d", len(list))
+	if bytes.Contains(buf.Bytes(), []byte("Insurance")) {
+		t.Error("backup contains the category name in plain text")
 	}
-}
 
-func TestUpdatePurchase(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, "purchases", cat)
-
-	p := makePurchase(cat.ID, "Old")
-	s.CreatePurchase(ctx, testUser, p)
-
-	p.ItemName = "New"
-	p.UpdatedAt = time.Now().UTC()
-	if err := s.UpdatePurchase(ctx, testUser, p); err != nil {
-		t.Fatalf("UpdatePurchase: %v", err)
-	}
-
-	got, err := s.GetPurchase(ctx, testUser, p.ID)
-	if err != nil {
-		t.Fatalf("GetPurchase: %v", err)
+	if err := RestoreBadger(t.TempDir(), nil, bytes.NewReader(buf.Bytes()), slog.Default()); err == nil {
+		t.Error("expected error restoring an encrypted backup without a key")
 	}
-	if got.ItemName != "New" {
-		t.Errorf("ItemName = %q, want %q", got.ItemName, "New")
+	if err := RestoreBadger(t.TempDir(), otherKey, bytes.NewReader(buf.Bytes()), slog.Default()); err == nil {
+		t.Error("expected error restoring with another key")
 	}
-}
-
-func TestDeletePurchase(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-	cat := makeCategory("Cat")
-	s.CreateCategory(ctx, testUser, "purchases", cat)
-
-	p := makePurchase(cat.ID, "ToDelete")
-	s.CreatePurchase(ctx, testUser, p)
-
-	if err := s.DeletePurchase(ctx, testUser, p.ID); err != nil {
-		t.Fatalf("DeletePurchase: %v", err)
+	truncated := buf.Bytes()[:buf.Len()-1]
+	if err := RestoreBadger(t.TempDir(), testKey, bytes.NewReader(truncated), slog.Default()); err == nil {
+		t.Error("expected error restoring a truncated backup")
 	}
 
-	_, err := s.GetPurchase(ctx, testUser, p.ID)
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
+	restored := t.TempDir()
+	if err := RestoreBadger(restored, testKey, bytes.NewReader(buf.Bytes()), slog.Default()); err != nil {
+		t.Fatalf("RestoreBadger: %v", err)
 	}
+	assertCategory(t, restored, testKey, cat)
 }
 
-func TestGetPurchase_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	_, err := s.GetPurchase(context.Background(), testUser, uuid.New())
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
-	}
-}
-
-func TestUpdatePurchase_CategoryChange_UpdatesIndex(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	cat1 := makeCategory("Cat1")
-	cat2 := makeCategory("Cat2")
-	s.CreateCategory(ctx, testUser, "purchases", cat1)
-	s.CreateCategory(ctx, testUser, "purchases", cat2)
-
-	p := makePurchase(cat1.ID, "Moveable")
-	s.CreatePurchase(ctx, testUser, p)
-
-	p.CategoryID = cat2.ID
-	p.UpdatedAt = time.Now().UTC()
-	if err := s.UpdatePurchase(ctx, testUser, p); err != nil {
-		t.Fatalf("UpdatePurchase: %v", err)
+func TestBackupCrypto_Chunks(t *testing.T) {
+	data := make([]byte, 3*backupChunkSize+100)
+	for i := range data {
+		data[i] = byte(i)
 	}
-
-	list1, _ := s.ListPurchasesByCategory(ctx, testUser, cat1.ID)
-	list2, _ := s.ListPurchasesByCategory(ctx, testUser, cat2.ID)
-
-	if len(list1) != 0 {
-		t.Errorf("cat1 should have 0 purchases, got %d", len(list1))
-	}
-	if len(list2) != 1 {
-		t.Errorf("cat2 should have 1 purchase, got %d", len(list2))
+	var buf bytes.Buffer
+	ew, err := newEncryptWriter(&buf, testKey)
+	if err != nil {
+		t.Fatal(err)
 	}
-}
-
-// User auth
-
-func TestCreateAndGetUserByEmail(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	user := model.User{
-		ID:           uuid.New(),
-		Email:        "test@example.com",
-		PasswordHash: "$2a$10$fakehashfortest",
-		CreatedAt:    time.Now().UTC(),
+	// Write in pieces that do not line up with the chunks.
+	for p := data; len(p) > 0; {
+		n := min(len(p), 7000)
+		if _, err := ew.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
 	}
-
-	if err := s.CreateUser(ctx, user); err != nil {
-		t.Fatalf("CreateUser: %v", err)
+	if err := ew.Close(); err != nil {
+		t.Fatal(err)
 	}
 
-	got, err := s.GetUserByEmail(ctx, "test@example.com")
+	r, err := backupReader(bytes.NewReader(buf.Bytes()), testKey)
 	if err != nil {
-		t.Fatalf("GetUserByEmail: %v", err)
+		t.Fatal(err)
 	}
-	if got.ID != user.ID {
-		t.Errorf("ID = %s, want %s", got.ID, user.ID)
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes, %v; want %d bytes", len(got), err, len(data))
 	}
-	if got.Email != user.Email {
-		t.Errorf("Email = %q, want %q", got.Email, user.Email)
-	}
-	if got.PasswordHash != user.PasswordHash {
-		t.Errorf("PasswordHash = %q, want %q", got.PasswordHash, user.PasswordHash)
+
+	// Dropping the last chunk leaves a stream that ends early.
+	sealedChunk := 4 + 12 + backupChunkSize + 16
+	cut := len(backupMagic) + 3*sealedChunk
+	r, _ = backupReader(bytes.NewReader(buf.Bytes()[:cut]), testKey)
+	if _, err := io.ReadAll(r); !errors.Is(err, errBackupTruncated) {
+		t.Errorf("without the last chunk: err = %v, want errBackupTruncated", err)
 	}
 }
 
-func TestCreateUser_DuplicateEmail(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	user := model.User{
-		ID:           uuid.New(),
-		Email:        "dup@example.com",
-		PasswordHash: "hash",
-		CreatedAt:    time.Now().UTC(),
-	}
-	if err := s.CreateUser(ctx, user); err != nil {
-		t.Fatalf("CreateUser: %v", err)
-	}
+func TestRotateBadgerKey(t *testing.T) {
+	path := t.TempDir()
+	cat := seedCategory(t, path, testKey)
 
-	user.ID = uuid.New()
-	err := s.CreateUser(ctx, user)
-	if !errors.Is(err, ErrConflict) {
-		t.Fatalf("expected ErrConflict, got %v", err)
+	if err := RotateBadgerKey(path, otherKey, testKey, slog.Default()); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("wrong old key: err = %v, want ErrWrongKey", err)
 	}
-}
-
-func TestGetUserByEmail_NotFound(t *testing.T) {
-	s := newTestStore(t)
-	_, err := s.GetUserByEmail(context.Background(), "nobody@test.com")
-	if !errors.Is(err, ErrNotFound) {
-		t.Fatalf("expected ErrNotFound, got %v", err)
+	if err := RotateBadgerKey(path, testKey, otherKey, slog.Default()); err != nil {
+		t.Fatalf("RotateBadgerKey: %v", err)
 	}
-}
-
-// User isolation
-
-func TestUserIsolation(t *testing.T) {
-	s := newTestStore(t)
-	ctx := context.Background()
-
-	cat := makeCategory("UserA-Cat")
-	s.CreateCategory(ctx, "user-a", testModule, cat)
-
-	cats, _ := s.ListCategories(ctx, "user-b", testModule)
-	if len(cats) != 0 {
-		t.Errorf("user-b should see 0 categories, got %d", len(cats))
+	if _, err := NewBadgerStore(path, testKey, slog.Default()); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("old key after rotation: err = %v, want ErrWrongKey", err)
 	}
+	assertCategory(t, path, otherKey, cat)
 
-	_, err := s.GetCategory(ctx, "user-b", testModule, cat.ID)
-	if !errors.Is(err, ErrNotFound) {
-		t.Errorf("user-b should get ErrNotFound, got %v", err)
+	if err := RotateBadgerKey(t.TempDir(), testKey, otherKey, slog.Default()); err == nil {
+		t.Error("expected error rotating a missing database")
 	}
 }
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"time"
+
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+// incrementAttempts counts one more attempt at now in a, the stored record
+// for key or the zero value. A key that is locked at now is not counted and
+// ErrLockedOut is returned with its record.
+func incrementAttempts(a model.LoginAttempts, key string, now time.Time, window time.Duration, lock func(failures int) time.Duration) (model.LoginAttempts, error) {
+	if !now.Before(a.ExpiresAt) {
+		a = model.LoginAttempts{Key: key}
+	}
+	if a.LockedUntil.After(now) {
+		return a, ErrLockedOut
+	}
+	a.Failures++
+	if d := lock(a.Failures); d > 0 {
+		a.LockedUntil = now.Add(d)
+	}
+	a.ExpiresAt = now.Add(window)
+	if a.LockedUntil.After(a.ExpiresAt) {
+		a.ExpiresAt = a.LockedUntil
+	}
+	return a, nil
+}
+
+// decrementAttempts takes the attempt that was counted as counted out of cur,
+// the stored record for the same key. The lock that attempt set is lifted,
+// unless a later attempt has replaced it.
+func decrementAttempts(cur, counted model.LoginAttempts) model.LoginAttempts {
+	if cur.Failures > 0 {
+		cur.Failures--
+	}
+	if cur.LockedUntil.Equal(counted.LockedUntil) {
+		cur.LockedUntil = time.Time{}
+	}
+	return cur
+}
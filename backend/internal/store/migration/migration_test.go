@@ -352,3 +352,171 @@ func TestIsContractKey(t *testing.T) {
 		}
 	}
 }
+
+// V3 tests
+
+func TestV3_SetsActiveStatus(t *testing.T) {
+	db := openTestDB(t)
+
+	putJSON(t, db, "u/alice/con/c1", map[string]any{"id": "c1", "name": "C1"})
+	putJSON(t, db, "u/alice/con/c2", map[string]any{"id": "c2", "name": "C2", "status": "terminated"})
+	putJSON(t, db, "u/alice/mod/contracts/cat/x", map[string]any{"id": "x", "name": "Cat"})
+
+	if err := v3ContractStatus(db); err != nil {
+		t.Fatalf("v3: %v", err)
+	}
+
+	if got := getJSON(t, db, "u/alice/con/c1")["status"]; got != "active" {
+		t.Errorf("c1 status = %v, want active", got)
+	}
+	if got := getJSON(t, db, "u/alice/con/c2")["status"]; got != "terminated" {
+		t.Errorf("c2 status = %v, want terminated", got)
+	}
+	if _, ok := getJSON(t, db, "u/alice/mod/contracts/cat/x")["status"]; ok {
+		t.Error("category should not get a status")
+	}
+}
+
+// V4 tests
+
+func TestV4_MovesDataIntoPersonalWorkspace(t *testing.T) {
+	db := openTestDB(t)
+
+	putJSON(t, db, "usr/alice", map[string]any{"id": "alice", "email": "a@b.com", "createdAt": "2024-01-01T00:00:00Z"})
+	putJSON(t, db, "u/alice/con/c1", map[string]any{"id": "c1", "name": "C1"})
+	putJSON(t, db, "u/alice/mod/contracts/cat/x", map[string]any{"id": "x", "name": "Cat"})
+	putJSON(t, db, "u/alice/idx/cat_con/x/c1", map[string]any{})
+	putJSON(t, db, "u/alice/settings", map[string]any{"renewalDays": 30})
+
+	if err := v4Workspaces(db); err != nil {
+		t.Fatalf("v4: %v", err)
+	}
+
+	wsID, _ := getJSON(t, db, "usr/alice")["personalWorkspaceId"].(string)
+	if wsID == "" {
+		t.Fatal("user has no personal workspace")
+	}
+	if got := getJSON(t, db, "ws/"+wsID)["name"]; got != "Personal" {
+		t.Errorf("workspace name = %v", got)
+	}
+	if got := getJSON(t, db, "ws/"+wsID+"/member/alice")["role"]; got != "owner" {
+		t.Errorf("role = %v, want owner", got)
+	}
+	for _, key := range []string{"con/c1", "mod/contracts/cat/x", "idx/cat_con/x/c1"} {
+		getJSON(t, db, "w/"+wsID+"/"+key)
+		if exists(t, db, "u/alice/"+key) {
+			t.Errorf("u/alice/%s was not removed", key)
+		}
+	}
+	if got := getJSON(t, db, "u/alice/settings")["renewalDays"]; got != float64(30) {
+		t.Errorf("settings should stay with the user, got %v", got)
+	}
+
+	// Running again leaves migrated users alone.
+	if err := v4Workspaces(db); err != nil {
+		t.Fatalf("v4 again: %v", err)
+	}
+	if got := getJSON(t, db, "usr/alice")["personalWorkspaceId"]; got != wsID {
+		t.Errorf("personal workspace changed to %v", got)
+	}
+}
+
+func exists(t *testing.T, db *badger.DB, key string) bool {
+	t.Helper()
+	err := db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(key))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("get %s: %v", key, err)
+	}
+	return true
+}
+
+func TestV5_MakesEarliestUserAdmin(t *testing.T) {
+	db := openTestDB(t)
+
+	putJSON(t, db, "usr/alice", map[string]any{"id": "alice", "createdAt": "2024-03-01T00:00:00Z"})
+	putJSON(t, db, "usr/bob", map[string]any{"id": "bob", "createdAt": "2023-06-01T00:00:00Z"})
+
+	if err := v5FirstAdmin(db); err != nil {
+		t.Fatalf("v5: %v", err)
+	}
+	if got := getJSON(t, db, "usr/bob")["admin"]; got != true {
+		t.Errorf("bob admin = %v, want true", got)
+	}
+	if got := getJSON(t, db, "usr/alice")["admin"]; got != nil {
+		t.Errorf("alice admin = %v, want unset", got)
+	}
+}
+
+func TestV5_KeepsExistingAdmin(t *testing.T) {
+	db := openTestDB(t)
+
+	putJSON(t, db, "usr/alice", map[string]any{"id": "alice", "admin": true, "createdAt": "2024-03-01T00:00:00Z"})
+	putJSON(t, db, "usr/bob", map[string]any{"id": "bob", "createdAt": "2023-06-01T00:00:00Z"})
+
+	if err := v5FirstAdmin(db); err != nil {
+		t.Fatalf("v5: %v", err)
+	}
+	if got := getJSON(t, db, "usr/bob")["admin"]; got != nil {
+		t.Errorf("bob admin = %v, want unset", got)
+	}
+}
+
+func TestV6_MarksExistingUsersVerified(t *testing.T) {
+	db := openTestDB(t)
+
+	putJSON(t, db, "usr/alice", map[string]any{"id": "alice", "email": "alice@example.com"})
+
+	if err := v6VerifiedEmails(db); err != nil {
+		t.Fatalf("v6: %v", err)
+	}
+	alice := getJSON(t, db, "usr/alice")
+	if alice["emailVerified"] != true || alice["email"] != "alice@example.com" {
+		t.Errorf("alice = %v, want verified with email kept", alice)
+	}
+}
+
+func TestV7_RenamesDurationFields(t *testing.T) {
+	db := openTestDB(t)
+
+	putJSON(t, db, "w/ws1/con/c1", map[string]any{"id": "c1", "minimumDurationMonths": 24, "extensionDurationMonths": 12, "noticePeriodMonths": 4, "noticePeriodUnit": "weeks"})
+	putJSON(t, db, "w/ws1/audit/a1", map[string]any{"entityType": "contract", "changes": []any{
+		map[string]any{"field": "name", "before": "Old", "after": "New"},
+		map[string]any{"field": "noticePeriodMonths", "before": 3, "after": 4},
+	}})
+	putJSON(t, db, "w/ws1/audit/a2", map[string]any{"entityType": "vehicle", "changes": []any{
+		map[string]any{"field": "noticePeriodMonths", "before": 3, "after": 4},
+	}})
+
+	if err := v7UnitNeutralDurations(db); err != nil {
+		t.Fatalf("v7: %v", err)
+	}
+
+	con := getJSON(t, db, "w/ws1/con/c1")
+	if con["minimumDuration"] != float64(24) || con["extensionDuration"] != float64(12) || con["noticePeriod"] != float64(4) || con["noticePeriodUnit"] != "weeks" {
+		t.Errorf("contract = %v", con)
+	}
+	for _, old := range []string{"minimumDurationMonths", "extensionDurationMonths", "noticePeriodMonths"} {
+		if _, ok := con[old]; ok {
+			t.Errorf("contract still has %s", old)
+		}
+	}
+	fields := func(key string) []any {
+		var out []any
+		for _, c := range getJSON(t, db, key)["changes"].([]any) {
+			out = append(out, c.(map[string]any)["field"])
+		}
+		return out
+	}
+	if got := fields("w/ws1/audit/a1"); got[0] != "name" || got[1] != "noticePeriod" {
+		t.Errorf("contract audit fields = %v", got)
+	}
+	if got := fields("w/ws1/audit/a2"); got[0] != "noticePeriodMonths" {
+		t.Errorf("other audit fields = %v, want them untouched", got)
+	}
+}
@@ -3,4 +3,18 @@ package migration
 var All = []Migration{
 	V1RenamePriceField,
 	V2ModuleCategories,
+	V3ContractStatus,
+	V4Workspaces,
+	V5FirstAdmin,
+	V6VerifiedEmails,
+	V7UnitNeutralDurations,
+}
+
+// SQLite lists the migrations of the SQLite store, which started out with
+// the schema the Badger migrations above lead to.
+var SQLite = []SQLMigration{
+	SQLiteV1Schema,
+	SQLiteV2AuditLog,
+	SQLiteV3Trash,
+	SQLiteV4UnitNeutralDurations,
 }
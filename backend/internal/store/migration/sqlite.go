@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// SQLMigration changes the schema of an SQLite database. Its statements run
+// in one transaction together with the version bump, so a failed migration
+// leaves the database as it was.
+type SQLMigration struct {
+	Version     uint64
+	Description string
+	SQL         string
+}
+
+// RunSQL applies the migrations newer than the database's schema version, which
+// SQLite keeps in PRAGMA user_version.
+func RunSQL(db *sql.DB, logger *slog.Logger, migrations []SQLMigration) error {
+	var current uint64
+	if err := db.QueryRow("PRAGMA user_version").Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	logger.Info("migration check", "currentVersion", current, "availableMigrations", len(migrations))
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		logger.Info("applying migration", "version", m.Version, "description", m.Description)
+
+		if err := applySQL(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+
+		logger.Info("migration applied", "version", m.Version)
+	}
+
+	return nil
+}
+
+func applySQL(db *sql.DB, m SQLMigration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return err
+	}
+	// PRAGMA arguments cannot be bound as parameters.
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.Version)); err != nil {
+		return fmt.Errorf("updating schema version to %d: %w", m.Version, err)
+	}
+	return tx.Commit()
+}
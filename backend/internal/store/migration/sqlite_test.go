@@ -0,0 +1,128 @@
+package migration
+
+import (
+	"database/sql"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func readUserVersion(t *testing.T, db *sql.DB) uint64 {
+	t.Helper()
+	var v uint64
+	if err := db.QueryRow("PRAGMA user_version").Scan(&v); err != nil {
+		t.Fatalf("reading user_version: %v", err)
+	}
+	return v
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n); err != nil {
+		t.Fatalf("looking up table %s: %v", name, err)
+	}
+	return n == 1
+}
+
+func TestRunSQL_AppliesInOrderOnce(t *testing.T) {
+	db := openTestSQLite(t)
+
+	migrations := []SQLMigration{
+		{Version: 1, Description: "create", SQL: "CREATE TABLE t (a TEXT)"},
+		{Version: 2, Description: "extend", SQL: "ALTER TABLE t ADD COLUMN b TEXT; CREATE TABLE u (c TEXT)"},
+	}
+	if err := RunSQL(db, slog.Default(), migrations); err != nil {
+		t.Fatalf("RunSQL: %v", err)
+	}
+	if v := readUserVersion(t, db); v != 2 {
+		t.Errorf("version = %d, want 2", v)
+	}
+	if !tableExists(t, db, "u") {
+		t.Error("second statement of migration 2 was not run")
+	}
+
+	// Running again must not re-apply anything, which would fail here.
+	if err := RunSQL(db, slog.Default(), migrations); err != nil {
+		t.Fatalf("second RunSQL: %v", err)
+	}
+}
+
+func TestRunSQL_StopsOnErrorAndRollsBack(t *testing.T) {
+	db := openTestSQLite(t)
+
+	migrations := []SQLMigration{
+		{Version: 1, Description: "ok", SQL: "CREATE TABLE t (a TEXT)"},
+		{Version: 2, Description: "fails", SQL: "CREATE TABLE half (a TEXT); INSERT INTO missing VALUES (1)"},
+		{Version: 3, Description: "never", SQL: "CREATE TABLE never (a TEXT)"},
+	}
+	if err := RunSQL(db, slog.Default(), migrations); err == nil {
+		t.Fatal("expected error")
+	}
+	if v := readUserVersion(t, db); v != 1 {
+		t.Errorf("version = %d, want 1 (should stop at failed migration)", v)
+	}
+	if tableExists(t, db, "half") {
+		t.Error("failed migration should have been rolled back")
+	}
+	if tableExists(t, db, "never") {
+		t.Error("later migration should not run")
+	}
+}
+
+func TestSQLite_SchemaApplies(t *testing.T) {
+	db := openTestSQLite(t)
+
+	if err := RunSQL(db, slog.Default(), SQLite); err != nil {
+		t.Fatalf("RunSQL: %v", err)
+	}
+	if v, want := readUserVersion(t, db), SQLite[len(SQLite)-1].Version; v != want {
+		t.Errorf("version = %d, want %d", v, want)
+	}
+	for _, table := range []string{"users", "workspaces", "categories", "contracts", "price_entries", "purchases", "vehicles", "cost_entries", "audit_log"} {
+		if !tableExists(t, db, table) {
+			t.Errorf("table %s missing", table)
+		}
+	}
+}
+
+func TestSQLiteV4_RenamesDurationColumns(t *testing.T) {
+	db := openTestSQLite(t)
+
+	if err := RunSQL(db, slog.Default(), SQLite[:3]); err != nil {
+		t.Fatalf("RunSQL: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO audit_log VALUES ('ws', 'a1', 'u', '', 'update', 'contract', 'c1', '', '[{"field":"noticePeriodMonths","before":3,"after":4}]')`); err != nil {
+		t.Fatalf("insert audit entry: %v", err)
+	}
+	if err := RunSQL(db, slog.Default(), SQLite); err != nil {
+		t.Fatalf("RunSQL: %v", err)
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM pragma_table_info('contracts') WHERE name IN ('minimum_duration', 'extension_duration', 'notice_period')").Scan(&n); err != nil {
+		t.Fatalf("reading columns: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("renamed columns = %d, want 3", n)
+	}
+	var changes string
+	if err := db.QueryRow("SELECT changes FROM audit_log WHERE id = 'a1'").Scan(&changes); err != nil {
+		t.Fatalf("reading audit entry: %v", err)
+	}
+	if want := `[{"field":"noticePeriod","before":3,"after":4}]`; changes != want {
+		t.Errorf("changes = %s, want %s", changes, want)
+	}
+}
@@ -0,0 +1,247 @@
+package migration
+
+// SQLiteV1Schema creates the tables of the SQLite store. Timestamps are
+// stored as fixed-width UTC text, so they sort and compare as strings.
+// Workspace data is keyed by workspace and ID; deleting a category, contract
+// or vehicle removes what belongs to it through the foreign keys.
+var SQLiteV1Schema = SQLMigration{
+	Version:     1,
+	Description: "initial schema",
+	SQL: `
+CREATE TABLE users (
+	id                    TEXT PRIMARY KEY,
+	email                 TEXT NOT NULL UNIQUE,
+	password_hash         TEXT NOT NULL,
+	oidc_subject          TEXT NOT NULL,
+	personal_workspace_id TEXT NOT NULL,
+	email_verified        INTEGER NOT NULL,
+	admin                 INTEGER NOT NULL,
+	disabled              INTEGER NOT NULL,
+	created_at            TEXT NOT NULL
+);
+
+CREATE TABLE user_settings (
+	user_id            TEXT PRIMARY KEY,
+	renewal_days       INTEGER NOT NULL,
+	reminder_frequency TEXT NOT NULL,
+	last_reminder_sent TEXT NOT NULL
+);
+
+CREATE TABLE feed_tokens (
+	user_id    TEXT PRIMARY KEY,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at TEXT NOT NULL
+);
+
+CREATE TABLE two_factor (
+	user_id        TEXT PRIMARY KEY,
+	secret         TEXT NOT NULL,
+	enabled        INTEGER NOT NULL,
+	recovery_codes TEXT NOT NULL,
+	last_used_step INTEGER NOT NULL,
+	created_at     TEXT NOT NULL,
+	enabled_at     TEXT
+);
+
+CREATE TABLE password_resets (
+	user_id    TEXT PRIMARY KEY,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at TEXT NOT NULL,
+	expires_at TEXT NOT NULL
+);
+
+CREATE TABLE email_verifications (
+	user_id    TEXT PRIMARY KEY,
+	token_hash TEXT NOT NULL UNIQUE,
+	email      TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	expires_at TEXT NOT NULL
+);
+
+CREATE TABLE login_attempts (
+	key          TEXT PRIMARY KEY,
+	failures     INTEGER NOT NULL,
+	locked_until TEXT NOT NULL,
+	expires_at   TEXT NOT NULL
+);
+
+CREATE TABLE sessions (
+	id                 TEXT PRIMARY KEY,
+	user_id            TEXT NOT NULL,
+	refresh_token_hash TEXT NOT NULL,
+	user_agent         TEXT NOT NULL,
+	ip_address         TEXT NOT NULL,
+	created_at         TEXT NOT NULL,
+	last_used_at       TEXT NOT NULL,
+	expires_at         TEXT NOT NULL
+);
+CREATE INDEX sessions_user_id ON sessions (user_id);
+
+CREATE TABLE api_keys (
+	id           TEXT PRIMARY KEY,
+	user_id      TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	prefix       TEXT NOT NULL,
+	key_hash     TEXT NOT NULL UNIQUE,
+	access       TEXT NOT NULL,
+	modules      TEXT NOT NULL,
+	created_at   TEXT NOT NULL,
+	expires_at   TEXT,
+	last_used_at TEXT
+);
+CREATE INDEX api_keys_user_id ON api_keys (user_id);
+
+CREATE TABLE workspaces (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE TABLE workspace_members (
+	workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+	user_id      TEXT NOT NULL,
+	role         TEXT NOT NULL,
+	joined_at    TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, user_id)
+);
+CREATE INDEX workspace_members_user_id ON workspace_members (user_id);
+
+CREATE TABLE invitations (
+	id           TEXT PRIMARY KEY,
+	workspace_id TEXT NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+	email        TEXT NOT NULL,
+	role         TEXT NOT NULL,
+	token_hash   TEXT NOT NULL UNIQUE,
+	invited_by   TEXT NOT NULL,
+	created_at   TEXT NOT NULL,
+	expires_at   TEXT NOT NULL
+);
+CREATE INDEX invitations_workspace_id ON invitations (workspace_id);
+
+CREATE TABLE categories (
+	workspace_id TEXT NOT NULL,
+	id           TEXT NOT NULL,
+	module       TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	name_key     TEXT NOT NULL,
+	created_at   TEXT NOT NULL,
+	updated_at   TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, id)
+);
+CREATE INDEX categories_module ON categories (workspace_id, module);
+
+CREATE TABLE contracts (
+	workspace_id                TEXT NOT NULL,
+	id                          TEXT NOT NULL,
+	category_id                 TEXT NOT NULL,
+	name                        TEXT NOT NULL,
+	product_name                TEXT NOT NULL,
+	company                     TEXT NOT NULL,
+	contract_number             TEXT NOT NULL,
+	customer_number             TEXT NOT NULL,
+	price                       REAL,
+	billing_interval            TEXT NOT NULL,
+	start_date                  TEXT NOT NULL,
+	end_date                    TEXT NOT NULL,
+	minimum_duration_months     INTEGER NOT NULL,
+	minimum_duration_unit       TEXT NOT NULL,
+	minimum_duration_anchor     TEXT NOT NULL,
+	extension_duration_months   INTEGER NOT NULL,
+	extension_duration_unit     TEXT NOT NULL,
+	extension_duration_anchor   TEXT NOT NULL,
+	notice_period_months        INTEGER NOT NULL,
+	notice_period_unit          TEXT NOT NULL,
+	notice_period_anchor        TEXT NOT NULL,
+	customer_portal_url         TEXT NOT NULL,
+	paperless_url               TEXT NOT NULL,
+	comments                    TEXT NOT NULL,
+	status                      TEXT NOT NULL,
+	cancellation_sent_at        TEXT,
+	cancellation_confirmed_at   TEXT,
+	terminated_at               TEXT,
+	cancellation_effective_date TEXT NOT NULL,
+	cancellation_reference      TEXT NOT NULL,
+	created_at                  TEXT NOT NULL,
+	updated_at                  TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, id),
+	FOREIGN KEY (workspace_id, category_id) REFERENCES categories (workspace_id, id) ON DELETE CASCADE
+);
+CREATE INDEX contracts_category_id ON contracts (workspace_id, category_id);
+
+CREATE TABLE price_entries (
+	workspace_id   TEXT NOT NULL,
+	id             TEXT NOT NULL,
+	contract_id    TEXT NOT NULL,
+	price          REAL NOT NULL,
+	effective_date TEXT NOT NULL,
+	comments       TEXT NOT NULL,
+	created_at     TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, id),
+	FOREIGN KEY (workspace_id, contract_id) REFERENCES contracts (workspace_id, id) ON DELETE CASCADE
+);
+CREATE INDEX price_entries_contract_id ON price_entries (workspace_id, contract_id);
+
+CREATE TABLE purchases (
+	workspace_id    TEXT NOT NULL,
+	id              TEXT NOT NULL,
+	category_id     TEXT NOT NULL,
+	type            TEXT NOT NULL,
+	item_name       TEXT NOT NULL,
+	brand           TEXT NOT NULL,
+	article_number  TEXT NOT NULL,
+	dealer          TEXT NOT NULL,
+	price           REAL,
+	purchase_date   TEXT NOT NULL,
+	description_url TEXT NOT NULL,
+	invoice_url     TEXT NOT NULL,
+	handbook_url    TEXT NOT NULL,
+	consumables     TEXT NOT NULL,
+	comments        TEXT NOT NULL,
+	created_at      TEXT NOT NULL,
+	updated_at      TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, id),
+	FOREIGN KEY (workspace_id, category_id) REFERENCES categories (workspace_id, id) ON DELETE CASCADE
+);
+CREATE INDEX purchases_category_id ON purchases (workspace_id, category_id);
+
+CREATE TABLE vehicles (
+	workspace_id       TEXT NOT NULL,
+	id                 TEXT NOT NULL,
+	name               TEXT NOT NULL,
+	make               TEXT NOT NULL,
+	model              TEXT NOT NULL,
+	year               INTEGER,
+	license_plate      TEXT NOT NULL,
+	purchase_date      TEXT NOT NULL,
+	purchase_price     REAL,
+	purchase_mileage   REAL,
+	target_mileage     REAL,
+	target_months      INTEGER,
+	annual_insurance   REAL,
+	annual_tax         REAL,
+	maintenance_factor REAL,
+	comments           TEXT NOT NULL,
+	created_at         TEXT NOT NULL,
+	updated_at         TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, id)
+);
+
+CREATE TABLE cost_entries (
+	workspace_id TEXT NOT NULL,
+	id           TEXT NOT NULL,
+	vehicle_id   TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	description  TEXT NOT NULL,
+	vendor       TEXT NOT NULL,
+	amount       REAL,
+	date         TEXT NOT NULL,
+	mileage      REAL,
+	comments     TEXT NOT NULL,
+	created_at   TEXT NOT NULL,
+	updated_at   TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, id),
+	FOREIGN KEY (workspace_id, vehicle_id) REFERENCES vehicles (workspace_id, id) ON DELETE CASCADE
+);
+CREATE INDEX cost_entries_vehicle_id ON cost_entries (workspace_id, vehicle_id);
+`,
+}
@@ -0,0 +1,23 @@
+package migration
+
+// SQLiteV2AuditLog adds the audit log. Entry IDs are time-ordered UUIDs, so
+// ordering by id orders by time.
+var SQLiteV2AuditLog = SQLMigration{
+	Version:     2,
+	Description: "audit log",
+	SQL: `
+CREATE TABLE audit_log (
+	workspace_id TEXT NOT NULL,
+	id           TEXT NOT NULL,
+	actor_id     TEXT NOT NULL,
+	at           TEXT NOT NULL,
+	action       TEXT NOT NULL,
+	entity_type  TEXT NOT NULL,
+	entity_id    TEXT NOT NULL,
+	module       TEXT NOT NULL,
+	changes      TEXT NOT NULL,
+	PRIMARY KEY (workspace_id, id)
+);
+CREATE INDEX audit_log_entity_id ON audit_log (workspace_id, entity_id, id);
+`,
+}
@@ -0,0 +1,15 @@
+package migration
+
+// SQLiteV3Trash adds the deleted_at column that marks records in the trash.
+// Deleting a record sets it instead of removing the row.
+var SQLiteV3Trash = SQLMigration{
+	Version:     3,
+	Description: "trash",
+	SQL: `
+ALTER TABLE categories ADD COLUMN deleted_at TEXT;
+ALTER TABLE contracts ADD COLUMN deleted_at TEXT;
+ALTER TABLE purchases ADD COLUMN deleted_at TEXT;
+ALTER TABLE vehicles ADD COLUMN deleted_at TEXT;
+ALTER TABLE cost_entries ADD COLUMN deleted_at TEXT;
+`,
+}
@@ -0,0 +1,19 @@
+package migration
+
+// SQLiteV4UnitNeutralDurations renames the month-named duration columns of
+// contracts, which also hold days and weeks, and the fields recorded for
+// them in the audit log, so reverting to an older revision still finds them.
+var SQLiteV4UnitNeutralDurations = SQLMigration{
+	Version:     4,
+	Description: "unit-neutral contract durations",
+	SQL: `
+ALTER TABLE contracts RENAME COLUMN minimum_duration_months TO minimum_duration;
+ALTER TABLE contracts RENAME COLUMN extension_duration_months TO extension_duration;
+ALTER TABLE contracts RENAME COLUMN notice_period_months TO notice_period;
+UPDATE audit_log SET changes = replace(replace(replace(changes,
+	'"field":"minimumDurationMonths"', '"field":"minimumDuration"'),
+	'"field":"extensionDurationMonths"', '"field":"extensionDuration"'),
+	'"field":"noticePeriodMonths"', '"field":"noticePeriod"')
+WHERE entity_type = 'contract';
+`,
+}
@@ -0,0 +1,75 @@
+package migration
+
+import (
+	"encoding/json"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+var V3ContractStatus = Migration{
+	Version:     3,
+	Description: "set status to active on existing contracts",
+	Run:         v3ContractStatus,
+}
+
+func v3ContractStatus(db *badger.DB) error {
+	type kv struct {
+		key []byte
+		val []byte
+	}
+	var updates []kv
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("u/")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			key := item.Key()
+
+			if !isContractKey(key) {
+				continue
+			}
+
+			err := item.Value(func(val []byte) error {
+				var doc map[string]any
+				if err := json.Unmarshal(val, &doc); err != nil {
+					return err
+				}
+				if s, _ := doc["status"].(string); s != "" {
+					return nil
+				}
+				doc["status"] = "active"
+				out, err := json.Marshal(doc)
+				if err != nil {
+					return err
+				}
+				keyCopy := make([]byte, len(key))
+				copy(keyCopy, key)
+				updates = append(updates, kv{key: keyCopy, val: out})
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	return db.Update(func(txn *badger.Txn) error {
+		for _, u := range updates {
+			if err := txn.Set(u.key, u.val); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
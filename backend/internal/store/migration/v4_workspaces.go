@@ -0,0 +1,124 @@
+package migration
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/google/uuid"
+)
+
+var V4Workspaces = Migration{
+	Version:     4,
+	Description: "move per-user data from u/{userId}/ into a personal workspace under w/{workspaceId}/",
+	Run:         v4Workspaces,
+}
+
+// workspaceSegments are the key segments under u/{userId}/ that hold data
+// now owned by a workspace. Settings, sessions and other account data stay.
+var workspaceSegments = []string{"mod", "con", "price", "pur", "veh", "cost", "idx"}
+
+func v4Workspaces(db *badger.DB) error {
+	type kv struct {
+		key []byte
+		val []byte
+	}
+	var sets []kv
+	var deletes [][]byte
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("usr/")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			var user map[string]any
+			if err := json.Unmarshal(val, &user); err != nil {
+				return err
+			}
+			if id, _ := user["personalWorkspaceId"].(string); id != "" && id != uuid.Nil.String() {
+				continue
+			}
+			userID, _ := user["id"].(string)
+			if userID == "" {
+				continue
+			}
+
+			wsID := uuid.New().String()
+			user["personalWorkspaceId"] = wsID
+			userDoc, err := json.Marshal(user)
+			if err != nil {
+				return err
+			}
+			wsDoc, err := json.Marshal(map[string]any{
+				"id":        wsID,
+				"name":      "Personal",
+				"createdAt": user["createdAt"],
+			})
+			if err != nil {
+				return err
+			}
+			memberDoc, err := json.Marshal(map[string]any{
+				"workspaceId": wsID,
+				"userId":      userID,
+				"role":        "owner",
+				"joinedAt":    user["createdAt"],
+			})
+			if err != nil {
+				return err
+			}
+			sets = append(sets,
+				kv{key: item.KeyCopy(nil), val: userDoc},
+				kv{key: []byte("ws/" + wsID), val: wsDoc},
+				kv{key: []byte("ws/" + wsID + "/member/" + userID), val: memberDoc},
+				kv{key: []byte("u/" + userID + "/workspace/" + wsID)},
+			)
+
+			dataIt := txn.NewIterator(badger.DefaultIteratorOptions)
+			userPrefix := []byte("u/" + userID + "/")
+			for dataIt.Seek(userPrefix); dataIt.ValidForPrefix(userPrefix); dataIt.Next() {
+				rest := string(dataIt.Item().Key()[len(userPrefix):])
+				segment, _, _ := strings.Cut(rest, "/")
+				if !slices.Contains(workspaceSegments, segment) {
+					continue
+				}
+				data, err := dataIt.Item().ValueCopy(nil)
+				if err != nil {
+					dataIt.Close()
+					return err
+				}
+				sets = append(sets, kv{key: []byte("w/" + wsID + "/" + rest), val: data})
+				deletes = append(deletes, dataIt.Item().KeyCopy(nil))
+			}
+			dataIt.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(sets) == 0 {
+		return nil
+	}
+
+	return db.Update(func(txn *badger.Txn) error {
+		for _, s := range sets {
+			if err := txn.Set(s.key, s.val); err != nil {
+				return err
+			}
+		}
+		for _, k := range deletes {
+			if err := txn.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
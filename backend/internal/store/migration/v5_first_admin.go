@@ -0,0 +1,60 @@
+package migration
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+var V5FirstAdmin = Migration{
+	Version:     5,
+	Description: "make the earliest registered user an admin",
+	Run:         v5FirstAdmin,
+}
+
+func v5FirstAdmin(db *badger.DB) error {
+	var firstKey []byte
+	var firstDoc map[string]any
+	var firstAt time.Time
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("usr/")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			var user map[string]any
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &user)
+			}); err != nil {
+				return err
+			}
+			if admin, _ := user["admin"].(bool); admin {
+				firstKey = nil
+				return nil
+			}
+			raw, _ := user["createdAt"].(string)
+			created, _ := time.Parse(time.RFC3339Nano, raw)
+			if firstKey == nil || created.Before(firstAt) {
+				firstKey = item.KeyCopy(nil)
+				firstDoc = user
+				firstAt = created
+			}
+		}
+		return nil
+	})
+	if err != nil || firstKey == nil {
+		return err
+	}
+
+	firstDoc["admin"] = true
+	out, err := json.Marshal(firstDoc)
+	if err != nil {
+		return err
+	}
+	return db.Update(func(txn *badger.Txn) error {
+		return txn.Set(firstKey, out)
+	})
+}
@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"encoding/json"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+var V6VerifiedEmails = Migration{
+	Version:     6,
+	Description: "treat email addresses of existing users as verified",
+	Run:         v6VerifiedEmails,
+}
+
+// v6VerifiedEmails marks existing users as verified, so accounts created
+// before email verification keep getting reminders.
+func v6VerifiedEmails(db *badger.DB) error {
+	updates := make(map[string][]byte)
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("usr/")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			var user map[string]any
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &user)
+			}); err != nil {
+				return err
+			}
+			user["emailVerified"] = true
+			out, err := json.Marshal(user)
+			if err != nil {
+				return err
+			}
+			updates[string(item.Key())] = out
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(txn *badger.Txn) error {
+		for k, v := range updates {
+			if err := txn.Set([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
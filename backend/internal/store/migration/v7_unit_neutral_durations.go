@@ -0,0 +1,90 @@
+package migration
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+var V7UnitNeutralDurations = Migration{
+	Version:     7,
+	Description: "rename the month-named contract duration fields, which also hold days and weeks",
+	Run:         v7UnitNeutralDurations,
+}
+
+// durationRenames maps the old contract duration fields to their new names.
+var durationRenames = map[string]string{
+	"minimumDurationMonths":   "minimumDuration",
+	"extensionDurationMonths": "extensionDuration",
+	"noticePeriodMonths":      "noticePeriod",
+}
+
+// v7UnitNeutralDurations renames the fields in contracts and in the changes
+// recorded for them, so reverting to an older revision still finds them.
+func v7UnitNeutralDurations(db *badger.DB) error {
+	updates := make(map[string][]byte)
+
+	err := db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte("w/")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			parts := strings.Split(string(item.Key()), "/")
+			if len(parts) != 4 || (parts[2] != "con" && parts[2] != "audit") {
+				continue
+			}
+
+			var doc map[string]any
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &doc)
+			}); err != nil {
+				return err
+			}
+			changed := false
+			if parts[2] == "con" {
+				for old, name := range durationRenames {
+					if v, ok := doc[old]; ok {
+						doc[name] = v
+						delete(doc, old)
+						changed = true
+					}
+				}
+			} else if doc["entityType"] == "contract" {
+				changes, _ := doc["changes"].([]any)
+				for _, c := range changes {
+					change, _ := c.(map[string]any)
+					field, _ := change["field"].(string)
+					if name, ok := durationRenames[field]; ok {
+						change["field"] = name
+						changed = true
+					}
+				}
+			}
+			if !changed {
+				continue
+			}
+
+			out, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			updates[string(item.Key())] = out
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(txn *badger.Txn) error {
+		for k, v := range updates {
+			if err := txn.Set([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
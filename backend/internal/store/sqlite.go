@@ -0,0 +1,1556 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
+	"github.com/tobi/contracts/backend/internal/store/migration"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteStore keeps the data in an embedded SQLite database with a
+// relational schema. It behaves like BadgerStore: records that expire in
+// Badger are filtered out by their expires_at column and deleted in the
+// background.
+type SQLiteStore struct {
+	db     *sql.DB
+	logger *slog.Logger
+	done   chan struct{}
+}
+
+// NewSQLiteStore opens or creates the database file at path and brings its
+// schema up to date.
+func NewSQLiteStore(path string, logger *slog.Logger) (*SQLiteStore, error) {
+	// Transactions take the write lock up front, so concurrent writers wait
+	// for the busy timeout instead of failing when they upgrade a read lock.
+	dsn := path + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite db: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening sqlite db: %w", err)
+	}
+
+	if err := migration.RunSQL(db, logger, migration.SQLite); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("running migrations: %w", err)
+	}
+
+	s := &SQLiteStore{
+		db:     db,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	go s.runCleanup()
+	return s, nil
+}
+
+// expiringTables hold records with an expires_at column.
+var expiringTables = []string{"password_resets", "email_verifications", "login_attempts", "invitations"}
+
+func (s *SQLiteStore) runCleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			now := formatTime(time.Now())
+			for _, table := range expiringTables {
+				if _, err := s.db.Exec("DELETE FROM "+table+" WHERE expires_at <= ?", now); err != nil {
+					s.logger.Warn("deleting expired records", "table", table, "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *SQLiteStore) Close() error {
+	close(s.done)
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Healthy() error {
+	return s.db.Ping()
+}
+
+// Helpers
+
+// sqliteTimeFormat is fixed-width UTC, so stored timestamps sort and compare
+// as text.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+func formatTimePtr(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// timeColumn scans a timestamp column into t.
+type timeColumn struct{ t *time.Time }
+
+func (c timeColumn) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("scanning %T into a timestamp", src)
+	}
+	t, err := time.Parse(sqliteTimeFormat, s)
+	if err != nil {
+		return err
+	}
+	*c.t = t
+	return nil
+}
+
+// nullTimeColumn scans a nullable timestamp column into t.
+type nullTimeColumn struct{ t **time.Time }
+
+func (c nullTimeColumn) Scan(src any) error {
+	if src == nil {
+		*c.t = nil
+		return nil
+	}
+	var t time.Time
+	if err := (timeColumn{&t}).Scan(src); err != nil {
+		return err
+	}
+	*c.t = &t
+	return nil
+}
+
+// jsonColumn scans a column holding JSON, used for string lists, into v.
+type jsonColumn struct{ v any }
+
+func (c jsonColumn) Scan(src any) error {
+	switch v := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), c.v)
+	case []byte:
+		return json.Unmarshal(v, c.v)
+	default:
+		return fmt.Errorf("scanning %T into JSON", src)
+	}
+}
+
+func jsonValue(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteError maps constraint violations to the store's errors: a taken
+// unique value is a conflict, a missing parent row is not found.
+func sqliteError(err error) error {
+	var se *sqlite.Error
+	if errors.As(err, &se) {
+		switch se.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return ErrConflict
+		case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return ErrNotFound
+		}
+	}
+	return err
+}
+
+// update runs fn in a transaction and commits it if fn succeeds.
+func (s *SQLiteStore) update(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func exec(ctx context.Context, q querier, query string, args ...any) error {
+	_, err := q.ExecContext(ctx, query, args...)
+	return sqliteError(err)
+}
+
+// execOne runs a statement that has to change a row, and reports
+// ErrNotFound if there was none.
+func execOne(ctx context.Context, q querier, query string, args ...any) error {
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return sqliteError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func queryOne[T any](ctx context.Context, q querier, scan func(rowScanner) (T, error), query string, args ...any) (T, error) {
+	v, err := scan(q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return v, ErrNotFound
+	}
+	return v, err
+}
+
+func queryAll[T any](ctx context.Context, q querier, scan func(rowScanner) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []T{}
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+// insertSQL and updateSQL build statements over a comma-separated column
+// list. Workspace data rows are keyed by workspace_id, which is not part of
+// the list.
+func insertSQL(table, columns string) string {
+	n := strings.Count(columns, ",") + 1
+	return "INSERT INTO " + table + " (" + columns + ") VALUES (" + strings.TrimSuffix(strings.Repeat("?, ", n), ", ") + ")"
+}
+
+func updateSQL(table, columns, where string) string {
+	cols := strings.Split(columns, ", ")
+	for i, c := range cols {
+		cols[i] = c + " = ?"
+	}
+	return "UPDATE " + table + " SET " + strings.Join(cols, ", ") + " WHERE " + where
+}
+
+// Users
+
+const userColumns = "id, email, password_hash, oidc_subject, personal_workspace_id, email_verified, admin, disabled, created_at"
+
+func userArgs(u model.User) []any {
+	return []any{u.ID, u.Email, u.PasswordHash, u.OIDCSubject, u.PersonalWorkspaceID, u.EmailVerified, u.Admin, u.Disabled, formatTime(u.CreatedAt)}
+}
+
+func scanUser(row rowScanner) (model.User, error) {
+	var u model.User
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.OIDCSubject, &u.PersonalWorkspaceID, &u.EmailVerified, &u.Admin, &u.Disabled, timeColumn{&u.CreatedAt})
+	return u, err
+}
+
+// perUserTables hold the records DeleteUser removes with an account.
+var perUserTables = []string{
+	"user_settings", "feed_tokens", "two_factor", "password_resets",
+	"email_verifications", "sessions", "api_keys", "workspace_members",
+}
+
+func (s *SQLiteStore) CreateUser(ctx context.Context, u model.User) error {
+	return exec(ctx, s.db, insertSQL("users", userColumns), userArgs(u)...)
+}
+
+func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (model.User, error) {
+	return queryOne(ctx, s.db, scanUser, "SELECT "+userColumns+" FROM users WHERE email = ?", email)
+}
+
+func (s *SQLiteStore) GetUserByID(ctx context.Context, id string) (model.User, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return model.User{}, ErrNotFound
+	}
+	return queryOne(ctx, s.db, scanUser, "SELECT "+userColumns+" FROM users WHERE id = ?", uid)
+}
+
+// UpdateUser stores u. A changed email fails with ErrConflict if the new
+// address is taken.
+func (s *SQLiteStore) UpdateUser(ctx context.Context, u model.User) error {
+	return execOne(ctx, s.db, updateSQL("users", userColumns, "id = ?"), append(userArgs(u), u.ID)...)
+}
+
+func (s *SQLiteStore) ListUsers(ctx context.Context) ([]model.User, error) {
+	return queryAll(ctx, s.db, scanUser, "SELECT "+userColumns+" FROM users ORDER BY id")
+}
+
+// DeleteUser removes an account with its settings, sessions, API keys and
+// other per-user records. Workspaces must be dealt with beforehand.
+func (s *SQLiteStore) DeleteUser(ctx context.Context, id string) error {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if err := execOne(ctx, tx, "DELETE FROM users WHERE id = ?", uid); err != nil {
+			return err
+		}
+		for _, table := range perUserTables {
+			if err := exec(ctx, tx, "DELETE FROM "+table+" WHERE user_id = ?", uid.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Settings
+
+func (s *SQLiteStore) GetSettings(ctx context.Context, userID string) (model.UserSettings, error) {
+	st, err := queryOne(ctx, s.db, func(row rowScanner) (model.UserSettings, error) {
+		var st model.UserSettings
+		err := row.Scan(&st.RenewalDays, &st.ReminderFrequency, timeColumn{&st.LastReminderSent})
+		return st, err
+	}, "SELECT renewal_days, reminder_frequency, last_reminder_sent FROM user_settings WHERE user_id = ?", userID)
+	if errors.Is(err, ErrNotFound) {
+		return model.DefaultUserSettings(), nil
+	}
+	return st, err
+}
+
+func (s *SQLiteStore) UpdateSettings(ctx context.Context, userID string, st model.UserSettings) error {
+	return exec(ctx, s.db, `INSERT INTO user_settings (user_id, renewal_days, reminder_frequency, last_reminder_sent) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET renewal_days = excluded.renewal_days, reminder_frequency = excluded.reminder_frequency, last_reminder_sent = excluded.last_reminder_sent`,
+		userID, st.RenewalDays, st.ReminderFrequency, formatTime(st.LastReminderSent))
+}
+
+// Calendar feed tokens
+
+func (s *SQLiteStore) GetFeedToken(ctx context.Context, userID string) (model.FeedToken, error) {
+	return queryOne(ctx, s.db, func(row rowScanner) (model.FeedToken, error) {
+		var t model.FeedToken
+		err := row.Scan(&t.TokenHash, timeColumn{&t.CreatedAt})
+		return t, err
+	}, "SELECT token_hash, created_at FROM feed_tokens WHERE user_id = ?", userID)
+}
+
+// SetFeedToken replaces the user's feed token; the previous one stops working.
+func (s *SQLiteStore) SetFeedToken(ctx context.Context, userID string, t model.FeedToken) error {
+	return exec(ctx, s.db, `INSERT INTO feed_tokens (user_id, token_hash, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		userID, t.TokenHash, formatTime(t.CreatedAt))
+}
+
+func (s *SQLiteStore) DeleteFeedToken(ctx context.Context, userID string) error {
+	return execOne(ctx, s.db, "DELETE FROM feed_tokens WHERE user_id = ?", userID)
+}
+
+func (s *SQLiteStore) GetUserIDByFeedToken(ctx context.Context, tokenHash string) (string, error) {
+	return queryOne(ctx, s.db, func(row rowScanner) (string, error) {
+		var userID string
+		err := row.Scan(&userID)
+		return userID, err
+	}, "SELECT user_id FROM feed_tokens WHERE token_hash = ?", tokenHash)
+}
+
+// Two-factor authentication
+
+func (s *SQLiteStore) GetTwoFactor(ctx context.Context, userID string) (model.TwoFactor, error) {
+	return queryOne(ctx, s.db, func(row rowScanner) (model.TwoFactor, error) {
+		var t model.TwoFactor
+		err := row.Scan(&t.Secret, &t.Enabled, jsonColumn{&t.RecoveryCodes}, &t.LastUsedStep, timeColumn{&t.CreatedAt}, nullTimeColumn{&t.EnabledAt})
+		return t, err
+	}, "SELECT secret, enabled, recovery_codes, last_used_step, created_at, enabled_at FROM two_factor WHERE user_id = ?", userID)
+}
+
+func (s *SQLiteStore) SetTwoFactor(ctx context.Context, userID string, t model.TwoFactor) error {
+	codes, err := jsonValue(t.RecoveryCodes)
+	if err != nil {
+		return err
+	}
+	return exec(ctx, s.db, `INSERT INTO two_factor (user_id, secret, enabled, recovery_codes, last_used_step, created_at, enabled_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, enabled = excluded.enabled, recovery_codes = excluded.recovery_codes,
+			last_used_step = excluded.last_used_step, created_at = excluded.created_at, enabled_at = excluded.enabled_at`,
+		userID, t.Secret, t.Enabled, codes, t.LastUsedStep, formatTime(t.CreatedAt), formatTimePtr(t.EnabledAt))
+}
+
+func (s *SQLiteStore) UseTwoFactorStep(ctx context.Context, userID string, step int64) error {
+	return execOne(ctx, s.db, "UPDATE two_factor SET last_used_step = ? WHERE user_id = ? AND enabled AND last_used_step < ?",
+		step, userID, step)
+}
+
+func (s *SQLiteStore) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		codes, err := queryOne(ctx, tx, func(row rowScanner) ([]string, error) {
+			var codes []string
+			err := row.Scan(jsonColumn{&codes})
+			return codes, err
+		}, "SELECT recovery_codes FROM two_factor WHERE user_id = ? AND enabled", userID)
+		if err != nil {
+			return err
+		}
+		i := slices.Index(codes, codeHash)
+		if i < 0 {
+			return ErrNotFound
+		}
+		v, err := jsonValue(slices.Delete(codes, i, i+1))
+		if err != nil {
+			return err
+		}
+		return exec(ctx, tx, "UPDATE two_factor SET recovery_codes = ? WHERE user_id = ?", v, userID)
+	})
+}
+
+func (s *SQLiteStore) DeleteTwoFactor(ctx context.Context, userID string) error {
+	return execOne(ctx, s.db, "DELETE FROM two_factor WHERE user_id = ?", userID)
+}
+
+// Password reset tokens
+
+// SetPasswordResetToken stores t, replacing the user's previous token. A
+// token that has already expired is not stored.
+func (s *SQLiteStore) SetPasswordResetToken(ctx context.Context, t model.PasswordResetToken) error {
+	if !t.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	return exec(ctx, s.db, `INSERT INTO password_resets (user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at, expires_at = excluded.expires_at`,
+		t.UserID, t.TokenHash, formatTime(t.CreatedAt), formatTime(t.ExpiresAt))
+}
+
+// ConsumePasswordResetToken returns and deletes the token with the given
+// hash, so each token can be used once.
+func (s *SQLiteStore) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (model.PasswordResetToken, error) {
+	var t model.PasswordResetToken
+	err := s.update(ctx, func(tx *sql.Tx) error {
+		var err error
+		t, err = queryOne(ctx, tx, func(row rowScanner) (model.PasswordResetToken, error) {
+			var t model.PasswordResetToken
+			err := row.Scan(&t.TokenHash, &t.UserID, timeColumn{&t.CreatedAt}, timeColumn{&t.ExpiresAt})
+			return t, err
+		}, "SELECT token_hash, user_id, created_at, expires_at FROM password_resets WHERE token_hash = ? AND expires_at > ?",
+			tokenHash, formatTime(time.Now()))
+		if err != nil {
+			return err
+		}
+		return exec(ctx, tx, "DELETE FROM password_resets WHERE token_hash = ?", tokenHash)
+	})
+	return t, err
+}
+
+// Email verifications
+
+// SetEmailVerification stores v, replacing the user's previous one. A
+// verification that has already expired is not stored.
+func (s *SQLiteStore) SetEmailVerification(ctx context.Context, v model.EmailVerification) error {
+	if !v.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	return exec(ctx, s.db, `INSERT INTO email_verifications (user_id, token_hash, email, created_at, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, email = excluded.email, created_at = excluded.created_at, expires_at = excluded.expires_at`,
+		v.UserID, v.TokenHash, v.Email, formatTime(v.CreatedAt), formatTime(v.ExpiresAt))
+}
+
+// ConsumeEmailVerification returns and deletes the verification with the
+// given token hash, so each link can be used once.
+func (s *SQLiteStore) ConsumeEmailVerification(ctx context.Context, tokenHash string) (model.EmailVerification, error) {
+	var v model.EmailVerification
+	err := s.update(ctx, func(tx *sql.Tx) error {
+		var err error
+		v, err = queryOne(ctx, tx, func(row rowScanner) (model.EmailVerification, error) {
+			var v model.EmailVerification
+			err := row.Scan(&v.TokenHash, &v.UserID, &v.Email, timeColumn{&v.CreatedAt}, timeColumn{&v.ExpiresAt})
+			return v, err
+		}, "SELECT token_hash, user_id, email, created_at, expires_at FROM email_verifications WHERE token_hash = ? AND expires_at > ?",
+			tokenHash, formatTime(time.Now()))
+		if err != nil {
+			return err
+		}
+		return exec(ctx, tx, "DELETE FROM email_verifications WHERE token_hash = ?", tokenHash)
+	})
+	return v, err
+}
+
+// Login attempts
+
+func (s *SQLiteStore) GetLoginAttempts(ctx context.Context, key string) (model.LoginAttempts, error) {
+	return queryOne(ctx, s.db, scanLoginAttempts, "SELECT key, failures, locked_until, expires_at FROM login_attempts WHERE key = ? AND expires_at > ?", key, formatTime(time.Now()))
+}
+
+// IncrementLoginAttempts counts an attempt for key at now in one
+// transaction.
+func (s *SQLiteStore) IncrementLoginAttempts(ctx context.Context, key string, now time.Time, window time.Duration, lock func(failures int) time.Duration) (model.LoginAttempts, error) {
+	var a model.LoginAttempts
+	err := s.update(ctx, func(tx *sql.Tx) error {
+		cur, err := queryLoginAttempts(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		if a, err = incrementAttempts(cur, key, now, window, lock); err != nil {
+			return err
+		}
+		return upsertLoginAttempts(ctx, tx, a)
+	})
+	return a, err
+}
+
+// DecrementLoginAttempts takes back the attempt that IncrementLoginAttempts
+// counted as a.
+func (s *SQLiteStore) DecrementLoginAttempts(ctx context.Context, a model.LoginAttempts) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		cur, err := queryLoginAttempts(ctx, tx, a.Key)
+		if err != nil || cur.Failures == 0 {
+			return err
+		}
+		return upsertLoginAttempts(ctx, tx, decrementAttempts(cur, a))
+	})
+}
+
+// queryLoginAttempts returns the zero record if key has none.
+func queryLoginAttempts(ctx context.Context, q querier, key string) (model.LoginAttempts, error) {
+	a, err := queryOne(ctx, q, scanLoginAttempts,
+		"SELECT key, failures, locked_until, expires_at FROM login_attempts WHERE key = ? AND expires_at > ?", key, formatTime(time.Now()))
+	if errors.Is(err, ErrNotFound) {
+		return model.LoginAttempts{}, nil
+	}
+	return a, err
+}
+
+// upsertLoginAttempts stores a, which expires at a.ExpiresAt.
+func upsertLoginAttempts(ctx context.Context, q querier, a model.LoginAttempts) error {
+	if !a.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	return exec(ctx, q, `INSERT INTO login_attempts (key, failures, locked_until, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET failures = excluded.failures, locked_until = excluded.locked_until, expires_at = excluded.expires_at`,
+		a.Key, a.Failures, formatTime(a.LockedUntil), formatTime(a.ExpiresAt))
+}
+
+func scanLoginAttempts(row rowScanner) (model.LoginAttempts, error) {
+	var a model.LoginAttempts
+	err := row.Scan(&a.Key, &a.Failures, timeColumn{&a.LockedUntil}, timeColumn{&a.ExpiresAt})
+	return a, err
+}
+
+// DeleteLoginAttempts forgets the failures for key; it is not an error if
+// there are none.
+func (s *SQLiteStore) DeleteLoginAttempts(ctx context.Context, key string) error {
+	return exec(ctx, s.db, "DELETE FROM login_attempts WHERE key = ?", key)
+}
+
+// Sessions
+
+const sessionColumns = "id, user_id, refresh_token_hash, user_agent, ip_address, created_at, last_used_at, expires_at"
+
+func sessionArgs(sess model.Session) []any {
+	return []any{sess.ID, sess.UserID, sess.RefreshTokenHash, sess.UserAgent, sess.IPAddress,
+		formatTime(sess.CreatedAt), formatTime(sess.LastUsedAt), formatTime(sess.ExpiresAt)}
+}
+
+func scanSession(row rowScanner) (model.Session, error) {
+	var sess model.Session
+	err := row.Scan(&sess.ID, &sess.UserID, &sess.RefreshTokenHash, &sess.UserAgent, &sess.IPAddress,
+		timeColumn{&sess.CreatedAt}, timeColumn{&sess.LastUsedAt}, timeColumn{&sess.ExpiresAt})
+	return sess, err
+}
+
+func (s *SQLiteStore) CreateSession(ctx context.Context, sess model.Session) error {
+	return exec(ctx, s.db, insertSQL("sessions", sessionColumns), sessionArgs(sess)...)
+}
+
+// GetSession looks a session up by ID alone, as refresh requests and access
+// tokens do not identify the user separately.
+func (s *SQLiteStore) GetSession(ctx context.Context, id uuid.UUID) (model.Session, error) {
+	return queryOne(ctx, s.db, scanSession, "SELECT "+sessionColumns+" FROM sessions WHERE id = ?", id)
+}
+
+func (s *SQLiteStore) UpdateSession(ctx context.Context, sess model.Session) error {
+	return execOne(ctx, s.db, updateSQL("sessions", sessionColumns, "id = ? AND user_id = ?"),
+		append(sessionArgs(sess), sess.ID, sess.UserID)...)
+}
+
+// RotateSession makes the hash comparison part of the update, so only one of
+// two concurrent rotations matches a row.
+func (s *SQLiteStore) RotateSession(ctx context.Context, sess model.Session, oldHash string) error {
+	err := execOne(ctx, s.db, updateSQL("sessions", sessionColumns, "id = ? AND user_id = ? AND refresh_token_hash = ?"),
+		append(sessionArgs(sess), sess.ID, sess.UserID, oldHash)...)
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if _, err := s.GetSession(ctx, sess.ID); err != nil {
+		return err
+	}
+	return ErrTokenReused
+}
+
+func (s *SQLiteStore) ListSessions(ctx context.Context, userID string) ([]model.Session, error) {
+	return queryAll(ctx, s.db, scanSession, "SELECT "+sessionColumns+" FROM sessions WHERE user_id = ? ORDER BY id", userID)
+}
+
+func (s *SQLiteStore) DeleteSession(ctx context.Context, userID string, id uuid.UUID) error {
+	return execOne(ctx, s.db, "DELETE FROM sessions WHERE id = ? AND user_id = ?", id, userID)
+}
+
+// API keys
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, access, modules, created_at, expires_at, last_used_at"
+
+func apiKeyArgs(k model.APIKey) ([]any, error) {
+	modules, err := jsonValue(k.Modules)
+	if err != nil {
+		return nil, err
+	}
+	return []any{k.ID, k.UserID, k.Name, k.Prefix, k.KeyHash, k.Access, modules,
+		formatTime(k.CreatedAt), formatTimePtr(k.ExpiresAt), formatTimePtr(k.LastUsedAt)}, nil
+}
+
+func scanAPIKey(row rowScanner) (model.APIKey, error) {
+	var k model.APIKey
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.KeyHash, &k.Access, jsonColumn{&k.Modules},
+		timeColumn{&k.CreatedAt}, nullTimeColumn{&k.ExpiresAt}, nullTimeColumn{&k.LastUsedAt})
+	if k.Modules == nil {
+		k.Modules = []string{}
+	}
+	return k, err
+}
+
+func (s *SQLiteStore) CreateAPIKey(ctx context.Context, k model.APIKey) error {
+	args, err := apiKeyArgs(k)
+	if err != nil {
+		return err
+	}
+	return exec(ctx, s.db, insertSQL("api_keys", apiKeyColumns), args...)
+}
+
+// GetAPIKeyByHash looks up the key a request authenticates with.
+func (s *SQLiteStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (model.APIKey, error) {
+	return queryOne(ctx, s.db, scanAPIKey, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", keyHash)
+}
+
+func (s *SQLiteStore) UpdateAPIKey(ctx context.Context, k model.APIKey) error {
+	args, err := apiKeyArgs(k)
+	if err != nil {
+		return err
+	}
+	return execOne(ctx, s.db, updateSQL("api_keys", apiKeyColumns, "id = ? AND user_id = ?"), append(args, k.ID, k.UserID)...)
+}
+
+func (s *SQLiteStore) ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	return queryAll(ctx, s.db, scanAPIKey, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? ORDER BY id", userID)
+}
+
+func (s *SQLiteStore) DeleteAPIKey(ctx context.Context, userID string, id uuid.UUID) error {
+	return execOne(ctx, s.db, "DELETE FROM api_keys WHERE id = ? AND user_id = ?", id, userID)
+}
+
+// Workspaces
+
+const (
+	workspaceColumns  = "id, name, created_at"
+	memberColumns     = "workspace_id, user_id, role, joined_at"
+	invitationColumns = "id, workspace_id, email, role, token_hash, invited_by, created_at, expires_at"
+)
+
+func scanWorkspace(row rowScanner) (model.Workspace, error) {
+	var ws model.Workspace
+	err := row.Scan(&ws.ID, &ws.Name, timeColumn{&ws.CreatedAt})
+	return ws, err
+}
+
+func memberArgs(m model.WorkspaceMember) []any {
+	return []any{m.WorkspaceID, m.UserID, m.Role, formatTime(m.JoinedAt)}
+}
+
+func scanMember(row rowScanner) (model.WorkspaceMember, error) {
+	var m model.WorkspaceMember
+	err := row.Scan(&m.WorkspaceID, &m.UserID, &m.Role, timeColumn{&m.JoinedAt})
+	return m, err
+}
+
+func scanInvitation(row rowScanner) (model.Invitation, error) {
+	var inv model.Invitation
+	err := row.Scan(&inv.ID, &inv.WorkspaceID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy,
+		timeColumn{&inv.CreatedAt}, timeColumn{&inv.ExpiresAt})
+	return inv, err
+}
+
+// workspaceDataTables hold a workspace's data, children before parents.
+var workspaceDataTables = []string{"price_entries", "contracts", "purchases", "categories", "cost_entries", "vehicles", "audit_log"}
+
+// CreateWorkspace stores a new workspace together with its owner.
+func (s *SQLiteStore) CreateWorkspace(ctx context.Context, ws model.Workspace, owner model.WorkspaceMember) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if err := exec(ctx, tx, insertSQL("workspaces", workspaceColumns), ws.ID, ws.Name, formatTime(ws.CreatedAt)); err != nil {
+			return err
+		}
+		return exec(ctx, tx, insertSQL("workspace_members", memberColumns), memberArgs(owner)...)
+	})
+}
+
+func (s *SQLiteStore) GetWorkspace(ctx context.Context, id uuid.UUID) (model.Workspace, error) {
+	return queryOne(ctx, s.db, scanWorkspace, "SELECT "+workspaceColumns+" FROM workspaces WHERE id = ?", id)
+}
+
+func (s *SQLiteStore) UpdateWorkspace(ctx context.Context, ws model.Workspace) error {
+	return execOne(ctx, s.db, "UPDATE workspaces SET name = ?, created_at = ? WHERE id = ?", ws.Name, formatTime(ws.CreatedAt), ws.ID)
+}
+
+// DeleteWorkspace removes a workspace with its members, invitations and all
+// of its data in one transaction.
+func (s *SQLiteStore) DeleteWorkspace(ctx context.Context, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		// Members and invitations follow through their foreign keys.
+		if err := execOne(ctx, tx, "DELETE FROM workspaces WHERE id = ?", id); err != nil {
+			return err
+		}
+		for _, table := range workspaceDataTables {
+			if err := exec(ctx, tx, "DELETE FROM "+table+" WHERE workspace_id = ?", id.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListWorkspaceMemberships returns the user's membership in each of their
+// workspaces.
+func (s *SQLiteStore) ListWorkspaceMemberships(ctx context.Context, userID string) ([]model.WorkspaceMember, error) {
+	return queryAll(ctx, s.db, scanMember, "SELECT "+memberColumns+" FROM workspace_members WHERE user_id = ? ORDER BY workspace_id", userID)
+}
+
+func (s *SQLiteStore) GetWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID string) (model.WorkspaceMember, error) {
+	return queryOne(ctx, s.db, scanMember, "SELECT "+memberColumns+" FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID)
+}
+
+func (s *SQLiteStore) ListWorkspaceMembers(ctx context.Context, workspaceID uuid.UUID) ([]model.WorkspaceMember, error) {
+	return queryAll(ctx, s.db, scanMember, "SELECT "+memberColumns+" FROM workspace_members WHERE workspace_id = ? ORDER BY user_id", workspaceID)
+}
+
+// SetWorkspaceMember adds a member to a workspace or changes their role.
+func (s *SQLiteStore) SetWorkspaceMember(ctx context.Context, m model.WorkspaceMember) error {
+	return exec(ctx, s.db, insertSQL("workspace_members", memberColumns)+
+		" ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = excluded.role, joined_at = excluded.joined_at", memberArgs(m)...)
+}
+
+func (s *SQLiteStore) DeleteWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID string) error {
+	return execOne(ctx, s.db, "DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID)
+}
+
+// CreateInvitation stores an invitation unless it has already expired.
+func (s *SQLiteStore) CreateInvitation(ctx context.Context, inv model.Invitation) error {
+	if !inv.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	return exec(ctx, s.db, insertSQL("invitations", invitationColumns), inv.ID, inv.WorkspaceID, inv.Email, inv.Role,
+		inv.TokenHash, inv.InvitedBy, formatTime(inv.CreatedAt), formatTime(inv.ExpiresAt))
+}
+
+func (s *SQLiteStore) ListInvitations(ctx context.Context, workspaceID uuid.UUID) ([]model.Invitation, error) {
+	return queryAll(ctx, s.db, scanInvitation, "SELECT "+invitationColumns+" FROM invitations WHERE workspace_id = ? AND expires_at > ? ORDER BY id",
+		workspaceID, formatTime(time.Now()))
+}
+
+func (s *SQLiteStore) DeleteInvitation(ctx context.Context, workspaceID, id uuid.UUID) error {
+	return execOne(ctx, s.db, "DELETE FROM invitations WHERE workspace_id = ? AND id = ? AND expires_at > ?", workspaceID, id, formatTime(time.Now()))
+}
+
+// ConsumeInvitation returns and deletes the invitation with the given token
+// hash, so each invitation can be accepted once.
+func (s *SQLiteStore) ConsumeInvitation(ctx context.Context, tokenHash string) (model.Invitation, error) {
+	var inv model.Invitation
+	err := s.update(ctx, func(tx *sql.Tx) error {
+		var err error
+		inv, err = queryOne(ctx, tx, scanInvitation, "SELECT "+invitationColumns+" FROM invitations WHERE token_hash = ? AND expires_at > ?",
+			tokenHash, formatTime(time.Now()))
+		if err != nil {
+			return err
+		}
+		return exec(ctx, tx, "DELETE FROM invitations WHERE id = ?", inv.ID)
+	})
+	return inv, err
+}
+
+// Categories (module-scoped)
+
+const categoryColumns = "id, name, name_key, created_at, updated_at, deleted_at"
+
+func categoryArgs(c model.Category) []any {
+	return []any{c.ID, c.Name, c.NameKey, formatTime(c.CreatedAt), formatTime(c.UpdatedAt), formatTimePtr(c.DeletedAt)}
+}
+
+func scanCategory(row rowScanner) (model.Category, error) {
+	var c model.Category
+	err := row.Scan(&c.ID, &c.Name, &c.NameKey, timeColumn{&c.CreatedAt}, timeColumn{&c.UpdatedAt}, nullTimeColumn{&c.DeletedAt})
+	return c, err
+}
+
+func (s *SQLiteStore) ListCategories(ctx context.Context, workspaceID string, module string) ([]model.Category, error) {
+	return queryAll(ctx, s.db, scanCategory, "SELECT "+categoryColumns+" FROM categories WHERE workspace_id = ? AND module = ? AND deleted_at IS NULL ORDER BY id", workspaceID, module)
+}
+
+const selectCategory = "SELECT " + categoryColumns + " FROM categories WHERE workspace_id = ? AND module = ? AND id = ? AND deleted_at IS NULL"
+
+func (s *SQLiteStore) GetCategory(ctx context.Context, workspaceID string, module string, id uuid.UUID) (model.Category, error) {
+	return queryOne(ctx, s.db, scanCategory, selectCategory, workspaceID, module, id)
+}
+
+func (s *SQLiteStore) CreateCategory(ctx context.Context, workspaceID string, module string, c model.Category) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if err := exec(ctx, tx, insertSQL("categories", "workspace_id, module, "+categoryColumns), append([]any{workspaceID, module}, categoryArgs(c)...)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityCategory, module, c.ID, nil, c)
+	})
+}
+
+func (s *SQLiteStore) UpdateCategory(ctx context.Context, workspaceID string, module string, c model.Category) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		old, err := queryOne(ctx, tx, scanCategory, selectCategory, workspaceID, module, c.ID)
+		if err != nil {
+			return err
+		}
+		if err := execOne(ctx, tx, updateSQL("categories", categoryColumns, "workspace_id = ? AND module = ? AND id = ?"),
+			append(categoryArgs(c), workspaceID, module, c.ID)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityCategory, module, c.ID, old, c)
+	})
+}
+
+// DeleteCategory moves a category to the trash along with its contracts or
+// purchases.
+func (s *SQLiteStore) DeleteCategory(ctx context.Context, workspaceID string, module string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		return moveToTrashSQL(ctx, tx, workspaceID, module, categoryTrash, id)
+	})
+}
+
+// Contracts
+
+const contractColumns = "id, category_id, name, product_name, company, contract_number, customer_number, price, billing_interval, " +
+	"start_date, end_date, minimum_duration, minimum_duration_unit, minimum_duration_anchor, " +
+	"extension_duration, extension_duration_unit, extension_duration_anchor, " +
+	"notice_period, notice_period_unit, notice_period_anchor, customer_portal_url, paperless_url, comments, status, " +
+	"cancellation_sent_at, cancellation_confirmed_at, terminated_at, cancellation_effective_date, cancellation_reference, created_at, updated_at, deleted_at"
+
+func contractArgs(c model.Contract) []any {
+	return []any{
+		c.ID, c.CategoryID, c.Name, c.ProductName, c.Company, c.ContractNumber, c.CustomerNumber, c.Price, c.BillingInterval,
+		c.StartDate, c.EndDate, c.MinimumDuration, c.MinimumDurationUnit, c.MinimumDurationAnchor,
+		c.ExtensionDuration, c.ExtensionDurationUnit, c.ExtensionDurationAnchor,
+		c.NoticePeriod, c.NoticePeriodUnit, c.NoticePeriodAnchor, c.CustomerPortalURL, c.PaperlessURL, c.Comments, c.Status,
+		formatTimePtr(c.CancellationSentAt), formatTimePtr(c.CancellationConfirmedAt), formatTimePtr(c.TerminatedAt),
+		c.CancellationEffectiveDate, c.CancellationReference, formatTime(c.CreatedAt), formatTime(c.UpdatedAt), formatTimePtr(c.DeletedAt),
+	}
+}
+
+func scanContract(row rowScanner) (model.Contract, error) {
+	var c model.Contract
+	err := row.Scan(
+		&c.ID, &c.CategoryID, &c.Name, &c.ProductName, &c.Company, &c.ContractNumber, &c.CustomerNumber, &c.Price, &c.BillingInterval,
+		&c.StartDate, &c.EndDate, &c.MinimumDuration, &c.MinimumDurationUnit, &c.MinimumDurationAnchor,
+		&c.ExtensionDuration, &c.ExtensionDurationUnit, &c.ExtensionDurationAnchor,
+		&c.NoticePeriod, &c.NoticePeriodUnit, &c.NoticePeriodAnchor, &c.CustomerPortalURL, &c.PaperlessURL, &c.Comments, &c.Status,
+		nullTimeColumn{&c.CancellationSentAt}, nullTimeColumn{&c.CancellationConfirmedAt}, nullTimeColumn{&c.TerminatedAt},
+		&c.CancellationEffectiveDate, &c.CancellationReference, timeColumn{&c.CreatedAt}, timeColumn{&c.UpdatedAt}, nullTimeColumn{&c.DeletedAt},
+	)
+	return c, err
+}
+
+func (s *SQLiteStore) ListContracts(ctx context.Context, workspaceID string) ([]model.Contract, error) {
+	return queryAll(ctx, s.db, scanContract, "SELECT "+contractColumns+" FROM contracts WHERE workspace_id = ? AND deleted_at IS NULL ORDER BY id", workspaceID)
+}
+
+func (s *SQLiteStore) ListContractsByCategory(ctx context.Context, workspaceID string, categoryID uuid.UUID) ([]model.Contract, error) {
+	return queryAll(ctx, s.db, scanContract, "SELECT "+contractColumns+" FROM contracts WHERE workspace_id = ? AND category_id = ? AND deleted_at IS NULL ORDER BY id", workspaceID, categoryID)
+}
+
+const selectContract = "SELECT " + contractColumns + " FROM contracts WHERE workspace_id = ? AND id = ? AND deleted_at IS NULL"
+
+func (s *SQLiteStore) GetContract(ctx context.Context, workspaceID string, id uuid.UUID) (model.Contract, error) {
+	return queryOne(ctx, s.db, scanContract, selectContract, workspaceID, id)
+}
+
+// CreateContract stores c, failing with ErrNotFound if its category does not
+// exist.
+func (s *SQLiteStore) CreateContract(ctx context.Context, workspaceID string, c model.Contract) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if err := exec(ctx, tx, insertSQL("contracts", "workspace_id, "+contractColumns), append([]any{workspaceID}, contractArgs(c)...)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityContract, "", c.ID, nil, c)
+	})
+}
+
+func (s *SQLiteStore) UpdateContract(ctx context.Context, workspaceID string, c model.Contract, prices ...model.PriceEntry) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		old, err := queryOne(ctx, tx, scanContract, selectContract, workspaceID, c.ID)
+		if err != nil {
+			return err
+		}
+		if err := execOne(ctx, tx, updateSQL("contracts", contractColumns, "workspace_id = ? AND id = ?"), append(contractArgs(c), workspaceID, c.ID)...); err != nil {
+			return err
+		}
+		for _, p := range prices {
+			if err := insertPriceEntry(ctx, tx, workspaceID, p); err != nil {
+				return err
+			}
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityContract, "", c.ID, old, c)
+	})
+}
+
+func (s *SQLiteStore) DeleteContract(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		return moveToTrashSQL(ctx, tx, workspaceID, "", contractTrash, id)
+	})
+}
+
+// recordContractDelete records the deletion of a contract and its price
+// history, which the foreign keys remove along with it.
+func recordContractDelete(ctx context.Context, tx *sql.Tx, workspaceID string, c model.Contract) error {
+	prices, err := queryAll(ctx, tx, scanPriceEntry, "SELECT "+priceEntryColumns+" FROM price_entries WHERE workspace_id = ? AND contract_id = ?", workspaceID, c.ID)
+	if err != nil {
+		return err
+	}
+	for _, p := range prices {
+		if err := recordSQL(ctx, tx, workspaceID, model.EntityPriceEntry, "", p.ID, p, nil); err != nil {
+			return err
+		}
+	}
+	return recordSQL(ctx, tx, workspaceID, model.EntityContract, "", c.ID, c, nil)
+}
+
+// Price entries
+
+const priceEntryColumns = "id, contract_id, price, effective_date, comments, created_at"
+
+func scanPriceEntry(row rowScanner) (model.PriceEntry, error) {
+	var p model.PriceEntry
+	err := row.Scan(&p.ID, &p.ContractID, &p.Price, &p.EffectiveDate, &p.Comments, timeColumn{&p.CreatedAt})
+	return p, err
+}
+
+// ListPriceEntries leaves out the price history of contracts in the trash.
+func (s *SQLiteStore) ListPriceEntries(ctx context.Context, workspaceID string) ([]model.PriceEntry, error) {
+	return queryAll(ctx, s.db, scanPriceEntry, "SELECT "+priceEntryColumns+" FROM price_entries WHERE workspace_id = ? "+
+		"AND contract_id IN (SELECT id FROM contracts WHERE workspace_id = ? AND deleted_at IS NULL) ORDER BY id", workspaceID, workspaceID)
+}
+
+func (s *SQLiteStore) ListPriceEntriesByContract(ctx context.Context, workspaceID string, contractID uuid.UUID) ([]model.PriceEntry, error) {
+	return queryAll(ctx, s.db, scanPriceEntry, "SELECT "+priceEntryColumns+" FROM price_entries WHERE workspace_id = ? AND contract_id = ? ORDER BY id", workspaceID, contractID)
+}
+
+const selectPriceEntry = "SELECT " + priceEntryColumns + " FROM price_entries WHERE workspace_id = ? AND id = ?"
+
+func (s *SQLiteStore) GetPriceEntry(ctx context.Context, workspaceID string, id uuid.UUID) (model.PriceEntry, error) {
+	return queryOne(ctx, s.db, scanPriceEntry, selectPriceEntry, workspaceID, id)
+}
+
+// CreatePriceEntry stores p, failing with ErrNotFound if its contract does
+// not exist or is in the trash.
+func (s *SQLiteStore) CreatePriceEntry(ctx context.Context, workspaceID string, p model.PriceEntry) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if _, err := queryOne(ctx, tx, scanContract, selectContract, workspaceID, p.ContractID); err != nil {
+			return err
+		}
+		return insertPriceEntry(ctx, tx, workspaceID, p)
+	})
+}
+
+// insertPriceEntry adds p to the history of its contract, which must exist.
+func insertPriceEntry(ctx context.Context, tx *sql.Tx, workspaceID string, p model.PriceEntry) error {
+	if err := exec(ctx, tx, insertSQL("price_entries", "workspace_id, "+priceEntryColumns),
+		workspaceID, p.ID, p.ContractID, p.Price, p.EffectiveDate, p.Comments, formatTime(p.CreatedAt)); err != nil {
+		return err
+	}
+	return recordSQL(ctx, tx, workspaceID, model.EntityPriceEntry, "", p.ID, nil, p)
+}
+
+func (s *SQLiteStore) DeletePriceEntry(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		old, err := queryOne(ctx, tx, scanPriceEntry, selectPriceEntry, workspaceID, id)
+		if err != nil {
+			return err
+		}
+		if err := execOne(ctx, tx, "DELETE FROM price_entries WHERE workspace_id = ? AND id = ?", workspaceID, id); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityPriceEntry, "", id, old, nil)
+	})
+}
+
+// Purchases
+
+const purchaseColumns = "id, category_id, type, item_name, brand, article_number, dealer, price, purchase_date, " +
+	"description_url, invoice_url, handbook_url, consumables, comments, created_at, updated_at, deleted_at"
+
+func purchaseArgs(p model.Purchase) []any {
+	return []any{
+		p.ID, p.CategoryID, p.Type, p.ItemName, p.Brand, p.ArticleNumber, p.Dealer, p.Price, p.PurchaseDate,
+		p.DescriptionURL, p.InvoiceURL, p.HandbookURL, p.Consumables, p.Comments, formatTime(p.CreatedAt), formatTime(p.UpdatedAt),
+		formatTimePtr(p.DeletedAt),
+	}
+}
+
+func scanPurchase(row rowScanner) (model.Purchase, error) {
+	var p model.Purchase
+	err := row.Scan(
+		&p.ID, &p.CategoryID, &p.Type, &p.ItemName, &p.Brand, &p.ArticleNumber, &p.Dealer, &p.Price, &p.PurchaseDate,
+		&p.DescriptionURL, &p.InvoiceURL, &p.HandbookURL, &p.Consumables, &p.Comments, timeColumn{&p.CreatedAt}, timeColumn{&p.UpdatedAt},
+		nullTimeColumn{&p.DeletedAt},
+	)
+	return p, err
+}
+
+func (s *SQLiteStore) ListPurchases(ctx context.Context, workspaceID string) ([]model.Purchase, error) {
+	return queryAll(ctx, s.db, scanPurchase, "SELECT "+purchaseColumns+" FROM purchases WHERE workspace_id = ? AND deleted_at IS NULL ORDER BY id", workspaceID)
+}
+
+func (s *SQLiteStore) ListPurchasesByCategory(ctx context.Context, workspaceID string, categoryID uuid.UUID) ([]model.Purchase, error) {
+	return queryAll(ctx, s.db, scanPurchase, "SELECT "+purchaseColumns+" FROM purchases WHERE workspace_id = ? AND category_id = ? AND deleted_at IS NULL ORDER BY id", workspaceID, categoryID)
+}
+
+const selectPurchase = "SELECT " + purchaseColumns + " FROM purchases WHERE workspace_id = ? AND id = ? AND deleted_at IS NULL"
+
+func (s *SQLiteStore) GetPurchase(ctx context.Context, workspaceID string, id uuid.UUID) (model.Purchase, error) {
+	return queryOne(ctx, s.db, scanPurchase, selectPurchase, workspaceID, id)
+}
+
+// CreatePurchase stores p, failing with ErrNotFound if its category does not
+// exist.
+func (s *SQLiteStore) CreatePurchase(ctx context.Context, workspaceID string, p model.Purchase) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if err := exec(ctx, tx, insertSQL("purchases", "workspace_id, "+purchaseColumns), append([]any{workspaceID}, purchaseArgs(p)...)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityPurchase, "", p.ID, nil, p)
+	})
+}
+
+func (s *SQLiteStore) UpdatePurchase(ctx context.Context, workspaceID string, p model.Purchase) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		old, err := queryOne(ctx, tx, scanPurchase, selectPurchase, workspaceID, p.ID)
+		if err != nil {
+			return err
+		}
+		if err := execOne(ctx, tx, updateSQL("purchases", purchaseColumns, "workspace_id = ? AND id = ?"), append(purchaseArgs(p), workspaceID, p.ID)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityPurchase, "", p.ID, old, p)
+	})
+}
+
+func (s *SQLiteStore) DeletePurchase(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		return moveToTrashSQL(ctx, tx, workspaceID, "", purchaseTrash, id)
+	})
+}
+
+// Vehicles
+
+const vehicleColumns = "id, name, make, model, year, license_plate, purchase_date, purchase_price, purchase_mileage, " +
+	"target_mileage, target_months, annual_insurance, annual_tax, maintenance_factor, comments, created_at, updated_at, deleted_at"
+
+func vehicleArgs(v model.Vehicle) []any {
+	return []any{
+		v.ID, v.Name, v.Make, v.Model, v.Year, v.LicensePlate, v.PurchaseDate, v.PurchasePrice, v.PurchaseMileage,
+		v.TargetMileage, v.TargetMonths, v.AnnualInsurance, v.AnnualTax, v.MaintenanceFactor, v.Comments,
+		formatTime(v.CreatedAt), formatTime(v.UpdatedAt), formatTimePtr(v.DeletedAt),
+	}
+}
+
+func scanVehicle(row rowScanner) (model.Vehicle, error) {
+	var v model.Vehicle
+	err := row.Scan(
+		&v.ID, &v.Name, &v.Make, &v.Model, &v.Year, &v.LicensePlate, &v.PurchaseDate, &v.PurchasePrice, &v.PurchaseMileage,
+		&v.TargetMileage, &v.TargetMonths, &v.AnnualInsurance, &v.AnnualTax, &v.MaintenanceFactor, &v.Comments,
+		timeColumn{&v.CreatedAt}, timeColumn{&v.UpdatedAt}, nullTimeColumn{&v.DeletedAt},
+	)
+	return v, err
+}
+
+func (s *SQLiteStore) ListVehicles(ctx context.Context, workspaceID string) ([]model.Vehicle, error) {
+	return queryAll(ctx, s.db, scanVehicle, "SELECT "+vehicleColumns+" FROM vehicles WHERE workspace_id = ? AND deleted_at IS NULL ORDER BY id", workspaceID)
+}
+
+const selectVehicle = "SELECT " + vehicleColumns + " FROM vehicles WHERE workspace_id = ? AND id = ? AND deleted_at IS NULL"
+
+func (s *SQLiteStore) GetVehicle(ctx context.Context, workspaceID string, id uuid.UUID) (model.Vehicle, error) {
+	return queryOne(ctx, s.db, scanVehicle, selectVehicle, workspaceID, id)
+}
+
+func (s *SQLiteStore) CreateVehicle(ctx context.Context, workspaceID string, v model.Vehicle) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if err := exec(ctx, tx, insertSQL("vehicles", "workspace_id, "+vehicleColumns), append([]any{workspaceID}, vehicleArgs(v)...)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityVehicle, "", v.ID, nil, v)
+	})
+}
+
+func (s *SQLiteStore) UpdateVehicle(ctx context.Context, workspaceID string, v model.Vehicle) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		old, err := queryOne(ctx, tx, scanVehicle, selectVehicle, workspaceID, v.ID)
+		if err != nil {
+			return err
+		}
+		if err := execOne(ctx, tx, updateSQL("vehicles", vehicleColumns, "workspace_id = ? AND id = ?"), append(vehicleArgs(v), workspaceID, v.ID)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityVehicle, "", v.ID, old, v)
+	})
+}
+
+// DeleteVehicle moves a vehicle to the trash along with its cost entries.
+func (s *SQLiteStore) DeleteVehicle(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		return moveToTrashSQL(ctx, tx, workspaceID, "", vehicleTrash, id)
+	})
+}
+
+// Cost entries
+
+const costEntryColumns = "id, vehicle_id, type, description, vendor, amount, date, mileage, comments, created_at, updated_at, deleted_at"
+
+func costEntryArgs(c model.CostEntry) []any {
+	return []any{c.ID, c.VehicleID, c.Type, c.Description, c.Vendor, c.Amount, c.Date, c.Mileage, c.Comments,
+		formatTime(c.CreatedAt), formatTime(c.UpdatedAt), formatTimePtr(c.DeletedAt)}
+}
+
+func scanCostEntry(row rowScanner) (model.CostEntry, error) {
+	var c model.CostEntry
+	err := row.Scan(&c.ID, &c.VehicleID, &c.Type, &c.Description, &c.Vendor, &c.Amount, &c.Date, &c.Mileage, &c.Comments,
+		timeColumn{&c.CreatedAt}, timeColumn{&c.UpdatedAt}, nullTimeColumn{&c.DeletedAt})
+	return c, err
+}
+
+func (s *SQLiteStore) ListCostEntries(ctx context.Context, workspaceID string, vehicleID uuid.UUID) ([]model.CostEntry, error) {
+	return queryAll(ctx, s.db, scanCostEntry, "SELECT "+costEntryColumns+" FROM cost_entries WHERE workspace_id = ? AND vehicle_id = ? AND deleted_at IS NULL ORDER BY id", workspaceID, vehicleID)
+}
+
+const selectCostEntry = "SELECT " + costEntryColumns + " FROM cost_entries WHERE workspace_id = ? AND id = ? AND deleted_at IS NULL"
+
+func (s *SQLiteStore) GetCostEntry(ctx context.Context, workspaceID string, id uuid.UUID) (model.CostEntry, error) {
+	return queryOne(ctx, s.db, scanCostEntry, selectCostEntry, workspaceID, id)
+}
+
+// CreateCostEntry stores c, failing with ErrNotFound if its vehicle does not
+// exist.
+func (s *SQLiteStore) CreateCostEntry(ctx context.Context, workspaceID string, c model.CostEntry) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		if err := exec(ctx, tx, insertSQL("cost_entries", "workspace_id, "+costEntryColumns), append([]any{workspaceID}, costEntryArgs(c)...)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityCostEntry, "", c.ID, nil, c)
+	})
+}
+
+func (s *SQLiteStore) UpdateCostEntry(ctx context.Context, workspaceID string, c model.CostEntry) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		old, err := queryOne(ctx, tx, scanCostEntry, selectCostEntry, workspaceID, c.ID)
+		if err != nil {
+			return err
+		}
+		if err := execOne(ctx, tx, updateSQL("cost_entries", costEntryColumns, "workspace_id = ? AND id = ?"), append(costEntryArgs(c), workspaceID, c.ID)...); err != nil {
+			return err
+		}
+		return recordSQL(ctx, tx, workspaceID, model.EntityCostEntry, "", c.ID, old, c)
+	})
+}
+
+func (s *SQLiteStore) DeleteCostEntry(ctx context.Context, workspaceID string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		return moveToTrashSQL(ctx, tx, workspaceID, "", costEntryTrash, id)
+	})
+}
+
+// Trash
+//
+// Deleting a record sets its deleted_at column; the queries above skip such
+// rows. Children moved to the trash with their parent get the same
+// deleted_at, which tells them apart from children that were already in the
+// trash when their parent is restored.
+
+// trashTable describes a table whose rows deleting moves to the trash.
+type trashTable[T any] struct {
+	entityType string
+	table      string
+	columns    string
+	scan       func(rowScanner) (T, error)
+	id         func(T) uuid.UUID
+	deletedAt  func(*T) **time.Time
+}
+
+var (
+	categoryTrash = trashTable[model.Category]{model.EntityCategory, "categories", categoryColumns, scanCategory,
+		func(c model.Category) uuid.UUID { return c.ID }, func(c *model.Category) **time.Time { return &c.DeletedAt }}
+	contractTrash = trashTable[model.Contract]{model.EntityContract, "contracts", contractColumns, scanContract,
+		func(c model.Contract) uuid.UUID { return c.ID }, func(c *model.Contract) **time.Time { return &c.DeletedAt }}
+	purchaseTrash = trashTable[model.Purchase]{model.EntityPurchase, "purchases", purchaseColumns, scanPurchase,
+		func(p model.Purchase) uuid.UUID { return p.ID }, func(p *model.Purchase) **time.Time { return &p.DeletedAt }}
+	vehicleTrash = trashTable[model.Vehicle]{model.EntityVehicle, "vehicles", vehicleColumns, scanVehicle,
+		func(v model.Vehicle) uuid.UUID { return v.ID }, func(v *model.Vehicle) **time.Time { return &v.DeletedAt }}
+	costEntryTrash = trashTable[model.CostEntry]{model.EntityCostEntry, "cost_entries", costEntryColumns, scanCostEntry,
+		func(c model.CostEntry) uuid.UUID { return c.ID }, func(c *model.CostEntry) **time.Time { return &c.DeletedAt }}
+)
+
+// setDeletedAtSQL sets deleted_at on the workspace's rows of t that match
+// where, or clears it if deletedAt is nil, records the change of each and
+// returns how many there were.
+func setDeletedAtSQL[T any](ctx context.Context, tx *sql.Tx, workspaceID, module string, t trashTable[T], deletedAt *time.Time, where string, args ...any) (int, error) {
+	rows, err := queryAll(ctx, tx, t.scan, "SELECT "+t.columns+" FROM "+t.table+" WHERE workspace_id = ? AND "+where, append([]any{workspaceID}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	for _, old := range rows {
+		v := old
+		*t.deletedAt(&v) = deletedAt
+		if err := exec(ctx, tx, "UPDATE "+t.table+" SET deleted_at = ? WHERE workspace_id = ? AND id = ?", formatTimePtr(deletedAt), workspaceID, t.id(old)); err != nil {
+			return 0, err
+		}
+		if err := recordSQL(ctx, tx, workspaceID, t.entityType, module, t.id(old), old, v); err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+// setChildrenDeletedAtSQL does the same for the contracts and purchases of a
+// category or the cost entries of a vehicle.
+func setChildrenDeletedAtSQL(ctx context.Context, tx *sql.Tx, workspaceID, entityType string, id uuid.UUID, deletedAt *time.Time, where string, args ...any) error {
+	args = append([]any{id}, args...)
+	switch entityType {
+	case model.EntityCategory:
+		if _, err := setDeletedAtSQL(ctx, tx, workspaceID, "", contractTrash, deletedAt, "category_id = ? AND "+where, args...); err != nil {
+			return err
+		}
+		_, err := setDeletedAtSQL(ctx, tx, workspaceID, "", purchaseTrash, deletedAt, "category_id = ? AND "+where, args...)
+		return err
+	case model.EntityVehicle:
+		_, err := setDeletedAtSQL(ctx, tx, workspaceID, "", costEntryTrash, deletedAt, "vehicle_id = ? AND "+where, args...)
+		return err
+	}
+	return nil
+}
+
+// moveToTrashSQL marks a row that is not in the trash, and its children that
+// are not either, as deleted now.
+func moveToTrashSQL[T any](ctx context.Context, tx *sql.Tx, workspaceID, module string, t trashTable[T], id uuid.UUID) error {
+	now := time.Now().UTC()
+	where, args := "id = ? AND deleted_at IS NULL", []any{id}
+	if module != "" {
+		where, args = "module = ? AND "+where, []any{module, id}
+	}
+	n, err := setDeletedAtSQL(ctx, tx, workspaceID, module, t, &now, where, args...)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return setChildrenDeletedAtSQL(ctx, tx, workspaceID, t.entityType, id, &now, "deleted_at IS NULL")
+}
+
+func trashedSQL[T any](ctx context.Context, tx *sql.Tx, workspaceID string, t trashTable[T], id uuid.UUID) (T, error) {
+	return queryOne(ctx, tx, t.scan, "SELECT "+t.columns+" FROM "+t.table+" WHERE workspace_id = ? AND id = ? AND deleted_at IS NOT NULL", workspaceID, id)
+}
+
+// restoreSQL takes a row out of the trash together with the children that
+// went there with it. parent, if set, fails with ErrConflict while the row's
+// category or vehicle is in the trash.
+func restoreSQL[T any](ctx context.Context, tx *sql.Tx, workspaceID, module string, t trashTable[T], id uuid.UUID, parent func(T) error) error {
+	old, err := trashedSQL(ctx, tx, workspaceID, t, id)
+	if err != nil {
+		return err
+	}
+	if parent != nil {
+		if err := parent(old); err != nil {
+			return err
+		}
+	}
+	deletedAt := *t.deletedAt(&old)
+	if _, err := setDeletedAtSQL(ctx, tx, workspaceID, module, t, nil, "id = ?", id); err != nil {
+		return err
+	}
+	return setChildrenDeletedAtSQL(ctx, tx, workspaceID, t.entityType, id, nil, "deleted_at = ?", formatTime(*deletedAt))
+}
+
+// liveSQL fails with ErrConflict unless the row of table with the given ID
+// exists and is not in the trash.
+func liveSQL(ctx context.Context, tx *sql.Tx, workspaceID, table string, id uuid.UUID) error {
+	_, err := queryOne(ctx, tx, func(row rowScanner) (uuid.UUID, error) {
+		var id uuid.UUID
+		err := row.Scan(&id)
+		return id, err
+	}, "SELECT id FROM "+table+" WHERE workspace_id = ? AND id = ? AND deleted_at IS NULL", workspaceID, id)
+	if errors.Is(err, ErrNotFound) {
+		return ErrConflict
+	}
+	return err
+}
+
+func categoryModule(ctx context.Context, tx *sql.Tx, workspaceID string, id uuid.UUID) (string, error) {
+	return queryOne(ctx, tx, func(row rowScanner) (string, error) {
+		var module string
+		err := row.Scan(&module)
+		return module, err
+	}, "SELECT module FROM categories WHERE workspace_id = ? AND id = ?", workspaceID, id)
+}
+
+func appendTrashedSQL[T any](ctx context.Context, q querier, items []model.TrashItem, scan func(rowScanner) (T, error), item func(T) (model.TrashItem, bool), query string, args ...any) ([]model.TrashItem, error) {
+	rows, err := queryAll(ctx, q, scan, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range rows {
+		if ti, ok := item(v); ok {
+			items = append(items, ti)
+		}
+	}
+	return items, nil
+}
+
+func (s *SQLiteStore) ListTrash(ctx context.Context, workspaceID string) ([]model.TrashItem, error) {
+	items := []model.TrashItem{}
+	var err error
+	for _, module := range model.Modules {
+		items, err = appendTrashedSQL(ctx, s.db, items, scanCategory, func(c model.Category) (model.TrashItem, bool) {
+			return c.TrashItem(module)
+		}, "SELECT "+categoryColumns+" FROM categories WHERE workspace_id = ? AND module = ? AND deleted_at IS NOT NULL", workspaceID, module)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if items, err = appendTrashedSQL(ctx, s.db, items, scanContract, model.Contract.TrashItem,
+		"SELECT "+contractColumns+" FROM contracts WHERE workspace_id = ? AND deleted_at IS NOT NULL", workspaceID); err != nil {
+		return nil, err
+	}
+	if items, err = appendTrashedSQL(ctx, s.db, items, scanPurchase, model.Purchase.TrashItem,
+		"SELECT "+purchaseColumns+" FROM purchases WHERE workspace_id = ? AND deleted_at IS NOT NULL", workspaceID); err != nil {
+		return nil, err
+	}
+	if items, err = appendTrashedSQL(ctx, s.db, items, scanVehicle, model.Vehicle.TrashItem,
+		"SELECT "+vehicleColumns+" FROM vehicles WHERE workspace_id = ? AND deleted_at IS NOT NULL", workspaceID); err != nil {
+		return nil, err
+	}
+	if items, err = appendTrashedSQL(ctx, s.db, items, scanCostEntry, model.CostEntry.TrashItem,
+		"SELECT "+costEntryColumns+" FROM cost_entries WHERE workspace_id = ? AND deleted_at IS NOT NULL", workspaceID); err != nil {
+		return nil, err
+	}
+	sortTrash(items)
+	return items, nil
+}
+
+func (s *SQLiteStore) RestoreFromTrash(ctx context.Context, workspaceID, entityType string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		switch entityType {
+		case model.EntityCategory:
+			module, err := categoryModule(ctx, tx, workspaceID, id)
+			if err != nil {
+				return err
+			}
+			return restoreSQL(ctx, tx, workspaceID, module, categoryTrash, id, nil)
+		case model.EntityContract:
+			return restoreSQL(ctx, tx, workspaceID, "", contractTrash, id, func(c model.Contract) error {
+				return liveSQL(ctx, tx, workspaceID, "categories", c.CategoryID)
+			})
+		case model.EntityPurchase:
+			return restoreSQL(ctx, tx, workspaceID, "", purchaseTrash, id, func(p model.Purchase) error {
+				return liveSQL(ctx, tx, workspaceID, "categories", p.CategoryID)
+			})
+		case model.EntityVehicle:
+			return restoreSQL(ctx, tx, workspaceID, "", vehicleTrash, id, nil)
+		case model.EntityCostEntry:
+			return restoreSQL(ctx, tx, workspaceID, "", costEntryTrash, id, func(c model.CostEntry) error {
+				return liveSQL(ctx, tx, workspaceID, "vehicles", c.VehicleID)
+			})
+		}
+		return ErrNotFound
+	})
+}
+
+// PurgeFromTrash deletes a record in the trash for good. The foreign keys
+// remove the contracts or purchases of a category, the price history of a
+// contract and the cost entries of a vehicle with it; each of them is
+// recorded as deleted.
+func (s *SQLiteStore) PurgeFromTrash(ctx context.Context, workspaceID, entityType string, id uuid.UUID) error {
+	return s.update(ctx, func(tx *sql.Tx) error {
+		switch entityType {
+		case model.EntityCategory:
+			module, err := categoryModule(ctx, tx, workspaceID, id)
+			if err != nil {
+				return err
+			}
+			old, err := trashedSQL(ctx, tx, workspaceID, categoryTrash, id)
+			if err != nil {
+				return err
+			}
+			contracts, err := queryAll(ctx, tx, scanContract, "SELECT "+contractColumns+" FROM contracts WHERE workspace_id = ? AND category_id = ?", workspaceID, id)
+			if err != nil {
+				return err
+			}
+			for _, c := range contracts {
+				if err := recordContractDelete(ctx, tx, workspaceID, c); err != nil {
+					return err
+				}
+			}
+			purchases, err := queryAll(ctx, tx, scanPurchase, "SELECT "+purchaseColumns+" FROM purchases WHERE workspace_id = ? AND category_id = ?", workspaceID, id)
+			if err != nil {
+				return err
+			}
+			for _, p := range purchases {
+				if err := recordSQL(ctx, tx, workspaceID, model.EntityPurchase, "", p.ID, p, nil); err != nil {
+					return err
+				}
+			}
+			if err := execOne(ctx, tx, "DELETE FROM categories WHERE workspace_id = ? AND id = ?", workspaceID, id); err != nil {
+				return err
+			}
+			return recordSQL(ctx, tx, workspaceID, model.EntityCategory, module, id, old, nil)
+
+		case model.EntityContract:
+			old, err := trashedSQL(ctx, tx, workspaceID, contractTrash, id)
+			if err != nil {
+				return err
+			}
+			if err := recordContractDelete(ctx, tx, workspaceID, old); err != nil {
+				return err
+			}
+			return execOne(ctx, tx, "DELETE FROM contracts WHERE workspace_id = ? AND id = ?", workspaceID, id)
+
+		case model.EntityPurchase:
+			old, err := trashedSQL(ctx, tx, workspaceID, purchaseTrash, id)
+			if err != nil {
+				return err
+			}
+			if err := execOne(ctx, tx, "DELETE FROM purchases WHERE workspace_id = ? AND id = ?", workspaceID, id); err != nil {
+				return err
+			}
+			return recordSQL(ctx, tx, workspaceID, model.EntityPurchase, "", id, old, nil)
+
+		case model.EntityVehicle:
+			old, err := trashedSQL(ctx, tx, workspaceID, vehicleTrash, id)
+			if err != nil {
+				return err
+			}
+			costs, err := queryAll(ctx, tx, scanCostEntry, "SELECT "+costEntryColumns+" FROM cost_entries WHERE workspace_id = ? AND vehicle_id = ?", workspaceID, id)
+			if err != nil {
+				return err
+			}
+			for _, c := range costs {
+				if err := recordSQL(ctx, tx, workspaceID, model.EntityCostEntry, "", c.ID, c, nil); err != nil {
+					return err
+				}
+			}
+			if err := execOne(ctx, tx, "DELETE FROM vehicles WHERE workspace_id = ? AND id = ?", workspaceID, id); err != nil {
+				return err
+			}
+			return recordSQL(ctx, tx, workspaceID, model.EntityVehicle, "", id, old, nil)
+
+		case model.EntityCostEntry:
+			old, err := trashedSQL(ctx, tx, workspaceID, costEntryTrash, id)
+			if err != nil {
+				return err
+			}
+			if err := execOne(ctx, tx, "DELETE FROM cost_entries WHERE workspace_id = ? AND id = ?", workspaceID, id); err != nil {
+				return err
+			}
+			return recordSQL(ctx, tx, workspaceID, model.EntityCostEntry, "", id, old, nil)
+		}
+		return ErrNotFound
+	})
+}
+
+func (s *SQLiteStore) PurgeTrash(ctx context.Context, cutoff time.Time) (int, error) {
+	var selects []string
+	var args []any
+	for _, table := range []string{"categories", "contracts", "purchases", "vehicles", "cost_entries"} {
+		selects = append(selects, "SELECT workspace_id FROM "+table+" WHERE deleted_at < ?")
+		args = append(args, formatTime(cutoff))
+	}
+	workspaceIDs, err := queryAll(ctx, s.db, func(row rowScanner) (string, error) {
+		var id string
+		err := row.Scan(&id)
+		return id, err
+	}, strings.Join(selects, " UNION "), args...)
+	if err != nil {
+		return 0, err
+	}
+	return purgeTrash(ctx, s, workspaceIDs, cutoff)
+}
+
+// Audit log
+
+const auditColumns = "id, actor_id, at, action, entity_type, entity_id, module, changes"
+
+func scanAuditEntry(row rowScanner) (model.AuditEntry, error) {
+	var e model.AuditEntry
+	err := row.Scan(&e.ID, &e.ActorID, timeColumn{&e.At}, &e.Action, &e.EntityType, &e.EntityID, &e.Module, jsonColumn{&e.Changes})
+	return e, err
+}
+
+// recordSQL writes the audit entry for a change of an entity from before to
+// after within tx. Either is nil where the entity does not exist.
+func recordSQL(ctx context.Context, tx *sql.Tx, workspaceID, entityType, module string, id uuid.UUID, before, after any) error {
+	var b, a []byte
+	var err error
+	if before != nil {
+		if b, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if a, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+	entry, ok, err := newAuditEntry(ctx, entityType, module, id, b, a)
+	if err != nil || !ok {
+		return err
+	}
+	changes, err := jsonValue(entry.Changes)
+	if err != nil {
+		return err
+	}
+	return exec(ctx, tx, insertSQL("audit_log", "workspace_id, "+auditColumns),
+		workspaceID, entry.ID, entry.ActorID, formatTime(entry.At), entry.Action, entry.EntityType, entry.EntityID, entry.Module, changes)
+}
+
+// ListAuditEntries returns the changes of one entity, oldest first.
+func (s *SQLiteStore) ListAuditEntries(ctx context.Context, workspaceID string, entityID uuid.UUID) ([]model.AuditEntry, error) {
+	return queryAll(ctx, s.db, scanAuditEntry, "SELECT "+auditColumns+" FROM audit_log WHERE workspace_id = ? AND entity_id = ? ORDER BY id", workspaceID, entityID)
+}
+
+// ListActivity returns up to limit changes in a workspace, newest first,
+// starting after the entry before, or with the latest if before is nil.
+func (s *SQLiteStore) ListActivity(ctx context.Context, workspaceID string, before uuid.UUID, limit int) ([]model.AuditEntry, error) {
+	if before == uuid.Nil {
+		return queryAll(ctx, s.db, scanAuditEntry, "SELECT "+auditColumns+" FROM audit_log WHERE workspace_id = ? ORDER BY id DESC LIMIT ?", workspaceID, limit)
+	}
+	return queryAll(ctx, s.db, scanAuditEntry, "SELECT "+auditColumns+" FROM audit_log WHERE workspace_id = ? AND id < ? ORDER BY id DESC LIMIT ?", workspaceID, before, limit)
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "contracts.db"), slog.Default())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteStore_ReopenKeepsData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contracts.db")
+	ctx := context.Background()
+
+	s, err := NewSQLiteStore(path, slog.Default())
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	cat := makeCategory("Insurance")
+	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	s.Close()
+
+	// Opening again must not re-run the migrations.
+	s, err = NewSQLiteStore(path, slog.Default())
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer s.Close()
+	got, err := s.GetCategory(ctx, testUser, testModule, cat.ID)
+	if err != nil || got.Name != "Insurance" || !got.CreatedAt.Equal(cat.CreatedAt) {
+		t.Errorf("GetCategory = %+v, %v", got, err)
+	}
+}
+
+func TestSQLiteStore_ForeignKeys(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	if err := s.CreateContract(ctx, testUser, makeContract(uuid.New(), "Orphan")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("contract without category: expected ErrNotFound, got %v", err)
+	}
+	if err := s.CreatePurchase(ctx, testUser, makePurchase(uuid.New(), "Orphan")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("purchase without category: expected ErrNotFound, got %v", err)
+	}
+	entry := model.CostEntry{ID: uuid.New(), VehicleID: uuid.New(), Type: model.CostTypeFuel, Date: "2025-01-01"}
+	if err := s.CreateCostEntry(ctx, testUser, entry); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cost entry without vehicle: expected ErrNotFound, got %v", err)
+	}
+
+	// Categories belong to one workspace; another one cannot reference them.
+	cat := makeCategory("Insurance")
+	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	if err := s.CreateContract(ctx, "other-workspace", makeContract(cat.ID, "Elsewhere")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("contract in another workspace: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteStore_DeleteVehicleCascadesCostEntries(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	v := model.Vehicle{ID: uuid.New(), Name: "Car", CreatedAt: now, UpdatedAt: now}
+	if err := s.CreateVehicle(ctx, testUser, v); err != nil {
+		t.Fatalf("CreateVehicle: %v", err)
+	}
+	amount := 50.0
+	entry := model.CostEntry{ID: uuid.New(), VehicleID: v.ID, Type: model.CostTypeFuel, Amount: &amount, Date: "2025-01-01", CreatedAt: now, UpdatedAt: now}
+	if err := s.CreateCostEntry(ctx, testUser, entry); err != nil {
+		t.Fatalf("CreateCostEntry: %v", err)
+	}
+	if got, err := s.GetCostEntry(ctx, testUser, entry.ID); err != nil || got.Amount == nil || *got.Amount != amount || got.Mileage != nil {
+		t.Fatalf("GetCostEntry = %+v, %v", got, err)
+	}
+
+	if err := s.DeleteVehicle(ctx, testUser, v.ID); err != nil {
+		t.Fatalf("DeleteVehicle: %v", err)
+	}
+	if _, err := s.GetCostEntry(ctx, testUser, entry.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cost entry of deleted vehicle: expected ErrNotFound, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/tobi/contracts/backend/internal/model"
@@ -13,41 +14,135 @@ type Store interface {
 	GetUserByID(ctx context.Context, id string) (model.User, error)
 	UpdateUser(ctx context.Context, u model.User) error
 	ListUsers(ctx context.Context) ([]model.User, error)
+	DeleteUser(ctx context.Context, id string) error
 
 	GetSettings(ctx context.Context, userID string) (model.UserSettings, error)
 	UpdateSettings(ctx context.Context, userID string, s model.UserSettings) error
 
-	ListCategories(ctx context.Context, userID string, module string) ([]model.Category, error)
-	GetCategory(ctx context.Context, userID string, module string, id uuid.UUID) (model.Category, error)
-	CreateCategory(ctx context.Context, userID string, module string, c model.Category) error
-	UpdateCategory(ctx context.Context, userID string, module string, c model.Category) error
-	DeleteCategory(ctx context.Context, userID string, module string, id uuid.UUID) error
-
-	ListContracts(ctx context.Context, userID string) ([]model.Contract, error)
-	ListContractsByCategory(ctx context.Context, userID string, categoryID uuid.UUID) ([]model.Contract, error)
-	GetContract(ctx context.Context, userID string, id uuid.UUID) (model.Contract, error)
-	CreateContract(ctx context.Context, userID string, c model.Contract) error
-	UpdateContract(ctx context.Context, userID string, c model.Contract) error
-	DeleteContract(ctx context.Context, userID string, id uuid.UUID) error
-
-	ListPurchases(ctx context.Context, userID string) ([]model.Purchase, error)
-	ListPurchasesByCategory(ctx context.Context, userID string, categoryID uuid.UUID) ([]model.Purchase, error)
-	GetPurchase(ctx context.Context, userID string, id uuid.UUID) (model.Purchase, error)
-	CreatePurchase(ctx context.Context, userID string, p model.Purchase) error
-	UpdatePurchase(ctx context.Context, userID string, p model.Purchase) error
-	DeletePurchase(ctx context.Context, userID string, id uuid.UUID) error
-
-	ListVehicles(ctx context.Context, userID string) ([]model.Vehicle, error)
-	GetVehicle(ctx context.Context, userID string, id uuid.UUID) (model.Vehicle, error)
-	CreateVehicle(ctx context.Context, userID string, v model.Vehicle) error
-	UpdateVehicle(ctx context.Context, userID string, v model.Vehicle) error
-	DeleteVehicle(ctx context.Context, userID string, id uuid.UUID) error
-
-	ListCostEntries(ctx context.Context, userID string, vehicleID uuid.UUID) ([]model.CostEntry, error)
-	GetCostEntry(ctx context.Context, userID string, id uuid.UUID) (model.CostEntry, error)
-	CreateCostEntry(ctx context.Context, userID string, c model.CostEntry) error
-	UpdateCostEntry(ctx context.Context, userID string, c model.CostEntry) error
-	DeleteCostEntry(ctx context.Context, userID string, id uuid.UUID) error
+	GetFeedToken(ctx context.Context, userID string) (model.FeedToken, error)
+	SetFeedToken(ctx context.Context, userID string, t model.FeedToken) error
+	DeleteFeedToken(ctx context.Context, userID string) error
+	GetUserIDByFeedToken(ctx context.Context, tokenHash string) (string, error)
+
+	GetTwoFactor(ctx context.Context, userID string) (model.TwoFactor, error)
+	SetTwoFactor(ctx context.Context, userID string, t model.TwoFactor) error
+	DeleteTwoFactor(ctx context.Context, userID string) error
+	// UseTwoFactorStep records step as the last used TOTP step if 2FA is
+	// enabled and step is newer than the last one, and ConsumeRecoveryCode
+	// removes an unused recovery code. Both check and write in one
+	// transaction and return ErrNotFound if the code cannot be used.
+	UseTwoFactorStep(ctx context.Context, userID string, step int64) error
+	ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) error
+
+	SetPasswordResetToken(ctx context.Context, t model.PasswordResetToken) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (model.PasswordResetToken, error)
+
+	SetEmailVerification(ctx context.Context, v model.EmailVerification) error
+	ConsumeEmailVerification(ctx context.Context, tokenHash string) (model.EmailVerification, error)
+
+	GetLoginAttempts(ctx context.Context, key string) (model.LoginAttempts, error)
+	// IncrementLoginAttempts counts an attempt for key at now and returns
+	// the updated record, read and written in one transaction. lock says how
+	// long a key is locked after the given number of attempts, and the
+	// record is kept for window after the last one or until the lock ends.
+	// A key that is locked at now is not counted; its record is returned
+	// with ErrLockedOut.
+	IncrementLoginAttempts(ctx context.Context, key string, now time.Time, window time.Duration, lock func(failures int) time.Duration) (model.LoginAttempts, error)
+	// DecrementLoginAttempts takes back the attempt IncrementLoginAttempts
+	// counted as a, lifting the lock it set.
+	DecrementLoginAttempts(ctx context.Context, a model.LoginAttempts) error
+	DeleteLoginAttempts(ctx context.Context, key string) error
+
+	CreateSession(ctx context.Context, s model.Session) error
+	GetSession(ctx context.Context, id uuid.UUID) (model.Session, error)
+	UpdateSession(ctx context.Context, s model.Session) error
+	// RotateSession stores s only if the session's refresh token hash is
+	// still oldHash, checked and written in one transaction. Otherwise it
+	// returns ErrTokenReused.
+	RotateSession(ctx context.Context, s model.Session, oldHash string) error
+	ListSessions(ctx context.Context, userID string) ([]model.Session, error)
+	DeleteSession(ctx context.Context, userID string, id uuid.UUID) error
+
+	CreateAPIKey(ctx context.Context, k model.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (model.APIKey, error)
+	UpdateAPIKey(ctx context.Context, k model.APIKey) error
+	ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID string, id uuid.UUID) error
+
+	CreateWorkspace(ctx context.Context, ws model.Workspace, owner model.WorkspaceMember) error
+	GetWorkspace(ctx context.Context, id uuid.UUID) (model.Workspace, error)
+	UpdateWorkspace(ctx context.Context, ws model.Workspace) error
+	DeleteWorkspace(ctx context.Context, id uuid.UUID) error
+	ListWorkspaceMemberships(ctx context.Context, userID string) ([]model.WorkspaceMember, error)
+	GetWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID string) (model.WorkspaceMember, error)
+	ListWorkspaceMembers(ctx context.Context, workspaceID uuid.UUID) ([]model.WorkspaceMember, error)
+	SetWorkspaceMember(ctx context.Context, m model.WorkspaceMember) error
+	DeleteWorkspaceMember(ctx context.Context, workspaceID uuid.UUID, userID string) error
+
+	CreateInvitation(ctx context.Context, inv model.Invitation) error
+	ListInvitations(ctx context.Context, workspaceID uuid.UUID) ([]model.Invitation, error)
+	DeleteInvitation(ctx context.Context, workspaceID, id uuid.UUID) error
+	ConsumeInvitation(ctx context.Context, tokenHash string) (model.Invitation, error)
+
+	ListCategories(ctx context.Context, workspaceID string, module string) ([]model.Category, error)
+	GetCategory(ctx context.Context, workspaceID string, module string, id uuid.UUID) (model.Category, error)
+	CreateCategory(ctx context.Context, workspaceID string, module string, c model.Category) error
+	UpdateCategory(ctx context.Context, workspaceID string, module string, c model.Category) error
+	DeleteCategory(ctx context.Context, workspaceID string, module string, id uuid.UUID) error
+
+	ListContracts(ctx context.Context, workspaceID string) ([]model.Contract, error)
+	ListContractsByCategory(ctx context.Context, workspaceID string, categoryID uuid.UUID) ([]model.Contract, error)
+	GetContract(ctx context.Context, workspaceID string, id uuid.UUID) (model.Contract, error)
+	CreateContract(ctx context.Context, workspaceID string, c model.Contract) error
+	// UpdateContract stores c and adds prices to its price history in the
+	// same transaction.
+	UpdateContract(ctx context.Context, workspaceID string, c model.Contract, prices ...model.PriceEntry) error
+	DeleteContract(ctx context.Context, workspaceID string, id uuid.UUID) error
+
+	ListPriceEntries(ctx context.Context, workspaceID string) ([]model.PriceEntry, error)
+	ListPriceEntriesByContract(ctx context.Context, workspaceID string, contractID uuid.UUID) ([]model.PriceEntry, error)
+	GetPriceEntry(ctx context.Context, workspaceID string, id uuid.UUID) (model.PriceEntry, error)
+	CreatePriceEntry(ctx context.Context, workspaceID string, p model.PriceEntry) error
+	DeletePriceEntry(ctx context.Context, workspaceID string, id uuid.UUID) error
+
+	ListPurchases(ctx context.Context, workspaceID string) ([]model.Purchase, error)
+	ListPurchasesByCategory(ctx context.Context, workspaceID string, categoryID uuid.UUID) ([]model.Purchase, error)
+	GetPurchase(ctx context.Context, workspaceID string, id uuid.UUID) (model.Purchase, error)
+	CreatePurchase(ctx context.Context, workspaceID string, p model.Purchase) error
+	UpdatePurchase(ctx context.Context, workspaceID string, p model.Purchase) error
+	DeletePurchase(ctx context.Context, workspaceID string, id uuid.UUID) error
+
+	ListVehicles(ctx context.Context, workspaceID string) ([]model.Vehicle, error)
+	GetVehicle(ctx context.Context, workspaceID string, id uuid.UUID) (model.Vehicle, error)
+	CreateVehicle(ctx context.Context, workspaceID string, v model.Vehicle) error
+	UpdateVehicle(ctx context.Context, workspaceID string, v model.Vehicle) error
+	DeleteVehicle(ctx context.Context, workspaceID string, id uuid.UUID) error
+
+	ListCostEntries(ctx context.Context, workspaceID string, vehicleID uuid.UUID) ([]model.CostEntry, error)
+	GetCostEntry(ctx context.Context, workspaceID string, id uuid.UUID) (model.CostEntry, error)
+	CreateCostEntry(ctx context.Context, workspaceID string, c model.CostEntry) error
+	UpdateCostEntry(ctx context.Context, workspaceID string, c model.CostEntry) error
+	DeleteCostEntry(ctx context.Context, workspaceID string, id uuid.UUID) error
+
+	// Deleting a category, contract, purchase, vehicle or cost entry moves it
+	// to the trash, together with the contracts or purchases of a category
+	// and the cost entries of a vehicle. The methods above treat trashed
+	// records as gone. Restoring and purging take one of
+	// model.TrashEntityTypes and fail with ErrNotFound for records that are
+	// not in the trash; restoring fails with ErrConflict while the record's
+	// category or vehicle is still in the trash.
+	ListTrash(ctx context.Context, workspaceID string) ([]model.TrashItem, error)
+	RestoreFromTrash(ctx context.Context, workspaceID, entityType string, id uuid.UUID) error
+	PurgeFromTrash(ctx context.Context, workspaceID, entityType string, id uuid.UUID) error
+	// PurgeTrash purges the records of all workspaces that were moved to the
+	// trash before cutoff and returns how many it purged, not counting the
+	// children that went with their category or vehicle.
+	PurgeTrash(ctx context.Context, cutoff time.Time) (int, error)
+
+	// Every create, update and delete of the data above is recorded in the
+	// workspace's audit log, attributed to the actor set with WithActor.
+	ListAuditEntries(ctx context.Context, workspaceID string, entityID uuid.UUID) ([]model.AuditEntry, error)
+	ListActivity(ctx context.Context, workspaceID string, before uuid.UUID, limit int) ([]model.AuditEntry, error)
 
 	Close() error
 }
@@ -0,0 +1,1734 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+const testUser = "test-user"
+const testModule = "contracts"
+
+// backends open an empty store of each implementation.
+var backends = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"badger", func(t *testing.T) Store { return newTestStore(t) }},
+	{"sqlite", func(t *testing.T) Store { return newTestSQLiteStore(t) }},
+}
+
+// conformance lists the behaviour every Store implementation shares.
+var conformance = []struct {
+	name string
+	run  func(t *testing.T, s Store)
+}{
+	{"CreateAndGetCategory", testCreateAndGetCategory},
+	{"ListCategories", testListCategories},
+	{"UpdateCategory", testUpdateCategory},
+	{"DeleteCategory", testDeleteCategory},
+	{"GetCategory_NotFound", testGetCategory_NotFound},
+	{"UpdateCategory_NotFound", testUpdateCategory_NotFound},
+	{"DeleteCategory_NotFound", testDeleteCategory_NotFound},
+	{"CategoryModuleIsolation", testCategoryModuleIsolation},
+	{"CreateAndGetContract", testCreateAndGetContract},
+	{"ListContracts", testListContracts},
+	{"ListContractsByCategory", testListContractsByCategory},
+	{"UpdateContract", testUpdateContract},
+	{"DeleteContract", testDeleteContract},
+	{"GetContract_NotFound", testGetContract_NotFound},
+	{"UpdateContract_NotFound", testUpdateContract_NotFound},
+	{"DeleteContract_NotFound", testDeleteContract_NotFound},
+	{"DeleteCategory_CascadesContracts", testDeleteCategory_CascadesContracts},
+	{"DeleteCategory_CascadesPurchases", testDeleteCategory_CascadesPurchases},
+	{"UpdateContract_CategoryChange_UpdatesIndex", testUpdateContract_CategoryChange_UpdatesIndex},
+	{"DeleteContract_CleansIndex", testDeleteContract_CleansIndex},
+	{"CreateAndListPriceEntries", testCreateAndListPriceEntries},
+	{"CreatePriceEntry_ContractNotFound", testCreatePriceEntry_ContractNotFound},
+	{"UpdateContract_WithPriceEntries", testUpdateContract_WithPriceEntries},
+	{"DeletePriceEntry", testDeletePriceEntry},
+	{"DeleteContract_CascadesPriceEntries", testDeleteContract_CascadesPriceEntries},
+	{"DeleteCategory_CascadesPriceEntries", testDeleteCategory_CascadesPriceEntries},
+	{"CreateAndGetPurchase", testCreateAndGetPurchase},
+	{"ListPurchases", testListPurchases},
+	{"ListPurchasesByCategory", testListPurchasesByCategory},
+	{"UpdatePurchase", testUpdatePurchase},
+	{"DeletePurchase", testDeletePurchase},
+	{"GetPurchase_NotFound", testGetPurchase_NotFound},
+	{"UpdatePurchase_CategoryChange_UpdatesIndex", testUpdatePurchase_CategoryChange_UpdatesIndex},
+	{"CreateAndGetUserByEmail", testCreateAndGetUserByEmail},
+	{"CreateUser_DuplicateEmail", testCreateUser_DuplicateEmail},
+	{"UpdateUser_EmailChangeMovesIndex", testUpdateUser_EmailChangeMovesIndex},
+	{"EmailVerification_SetConsume", testEmailVerification_SetConsume},
+	{"GetUserByEmail_NotFound", testGetUserByEmail_NotFound},
+	{"UserIsolation", testUserIsolation},
+	{"FeedToken_SetGetRotateDelete", testFeedToken_SetGetRotateDelete},
+	{"Session_CRUD", testSession_CRUD},
+	{"Session_RotateOnce", testSession_RotateOnce},
+	{"TwoFactor_CodesWorkOnce", testTwoFactor_CodesWorkOnce},
+	{"PasswordResetToken_SetConsume", testPasswordResetToken_SetConsume},
+	{"LoginAttempts_IncrementGetDelete", testLoginAttempts_IncrementGetDelete},
+	{"LoginAttempts_ConcurrentIncrements", testLoginAttempts_ConcurrentIncrements},
+	{"APIKey_CRUD", testAPIKey_CRUD},
+	{"Workspace_MembersAndInvitations", testWorkspace_MembersAndInvitations},
+	{"DeleteWorkspace_RemovesData", testDeleteWorkspace_RemovesData},
+	{"DeleteUser_RemovesAccountRecords", testDeleteUser_RemovesAccountRecords},
+	{"Audit_RecordsChanges", testAudit_RecordsChanges},
+	{"Audit_RecordsCascadingDeletes", testAudit_RecordsCascadingDeletes},
+	{"Audit_ActivityPages", testAudit_ActivityPages},
+	{"Trash_HidesDeleted", testTrash_HidesDeleted},
+	{"Trash_RestoresWithChildren", testTrash_RestoresWithChildren},
+	{"Trash_Purge", testTrash_Purge},
+	{"Trash_PurgeTrashBeforeCutoff", testTrash_PurgeTrashBeforeCutoff},
+}
+
+func TestConformance(t *testing.T) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			for _, c := range conformance {
+				t.Run(c.name, func(t *testing.T) {
+					c.run(t, b.open(t))
+				})
+			}
+		})
+	}
+}
+
+func makeCategory(name string) model.Category {
+	now := time.Now().UTC()
+	return model.Category{
+		ID:        uuid.New(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func makeContract(categoryID uuid.UUID, name string) model.Contract {
+	now := time.Now().UTC()
+	return model.Contract{
+		ID:         uuid.New(),
+		CategoryID: categoryID,
+		Name:       name,
+		StartDate:  "2025-01-01",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+func makePurchase(categoryID uuid.UUID, name string) model.Purchase {
+	now := time.Now().UTC()
+	return model.Purchase{
+		ID:         uuid.New(),
+		CategoryID: categoryID,
+		ItemName:   name,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// Category CRUD
+
+func testCreateAndGetCategory(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Insurance")
+
+	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+
+	got, err := s.GetCategory(ctx, testUser, testModule, cat.ID)
+	if err != nil {
+		t.Fatalf("GetCategory: %v", err)
+	}
+	if got.Name != cat.Name {
+		t.Errorf("Name = %q, want %q", got.Name, cat.Name)
+	}
+}
+
+func testListCategories(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cats, err := s.ListCategories(ctx, testUser, testModule)
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	if len(cats) != 0 {
+		t.Fatalf("expected empty list, got %d", len(cats))
+	}
+
+	for _, name := range []string{"A", "B", "C"} {
+		if err := s.CreateCategory(ctx, testUser, testModule, makeCategory(name)); err != nil {
+			t.Fatalf("CreateCategory(%s): %v", name, err)
+		}
+	}
+
+	cats, err = s.ListCategories(ctx, testUser, testModule)
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	if len(cats) != 3 {
+		t.Fatalf("expected 3 categories, got %d", len(cats))
+	}
+}
+
+func testUpdateCategory(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Old")
+
+	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+
+	cat.Name = "New"
+	cat.UpdatedAt = time.Now().UTC()
+	if err := s.UpdateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Fatalf("UpdateCategory: %v", err)
+	}
+
+	got, err := s.GetCategory(ctx, testUser, testModule, cat.ID)
+	if err != nil {
+		t.Fatalf("GetCategory: %v", err)
+	}
+	if got.Name != "New" {
+		t.Errorf("Name = %q, want %q", got.Name, "New")
+	}
+}
+
+func testDeleteCategory(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("ToDelete")
+
+	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	if err := s.DeleteCategory(ctx, testUser, testModule, cat.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+
+	_, err := s.GetCategory(ctx, testUser, testModule, cat.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testGetCategory_NotFound(t *testing.T, s Store) {
+	_, err := s.GetCategory(context.Background(), testUser, testModule, uuid.New())
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testUpdateCategory_NotFound(t *testing.T, s Store) {
+	err := s.UpdateCategory(context.Background(), testUser, testModule, makeCategory("Ghost"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testDeleteCategory_NotFound(t *testing.T, s Store) {
+	err := s.DeleteCategory(context.Background(), testUser, testModule, uuid.New())
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// Module isolation for categories
+
+func testCategoryModuleIsolation(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cat := makeCategory("Insurance")
+	s.CreateCategory(ctx, testUser, "contracts", cat)
+
+	// Should not be visible under "purchases" module
+	cats, _ := s.ListCategories(ctx, testUser, "purchases")
+	if len(cats) != 0 {
+		t.Errorf("purchases module should see 0 categories, got %d", len(cats))
+	}
+
+	_, err := s.GetCategory(ctx, testUser, "purchases", cat.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("purchases module should get ErrNotFound, got %v", err)
+	}
+}
+
+// Contract CRUD
+
+func testCreateAndGetContract(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+
+	con := makeContract(cat.ID, "Phone Plan")
+	if err := s.CreateContract(ctx, testUser, con); err != nil {
+		t.Fatalf("CreateContract: %v", err)
+	}
+
+	got, err := s.GetContract(ctx, testUser, con.ID)
+	if err != nil {
+		t.Fatalf("GetContract: %v", err)
+	}
+	if got.Name != con.Name {
+		t.Errorf("Name = %q, want %q", got.Name, con.Name)
+	}
+	if got.CategoryID != cat.ID {
+		t.Errorf("CategoryID = %s, want %s", got.CategoryID, cat.ID)
+	}
+}
+
+func testListContracts(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+
+	for _, name := range []string{"A", "B"} {
+		s.CreateContract(ctx, testUser, makeContract(cat.ID, name))
+	}
+
+	all, err := s.ListContracts(ctx, testUser)
+	if err != nil {
+		t.Fatalf("ListContracts: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 contracts, got %d", len(all))
+	}
+}
+
+func testListContractsByCategory(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cat1 := makeCategory("Cat1")
+	cat2 := makeCategory("Cat2")
+	s.CreateCategory(ctx, testUser, testModule, cat1)
+	s.CreateCategory(ctx, testUser, testModule, cat2)
+
+	s.CreateContract(ctx, testUser, makeContract(cat1.ID, "C1"))
+	s.CreateContract(ctx, testUser, makeContract(cat1.ID, "C2"))
+	s.CreateContract(ctx, testUser, makeContract(cat2.ID, "C3"))
+
+	list, err := s.ListContractsByCategory(ctx, testUser, cat1.ID)
+	if err != nil {
+		t.Fatalf("ListContractsByCategory: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 contracts for cat1, got %d", len(list))
+	}
+
+	list, err = s.ListContractsByCategory(ctx, testUser, cat2.ID)
+	if err != nil {
+		t.Fatalf("ListContractsByCategory: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected 1 contract for cat2, got %d", len(list))
+	}
+}
+
+func testUpdateContract(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+
+	con := makeContract(cat.ID, "Old")
+	s.CreateContract(ctx, testUser, con)
+
+	con.Name = "New"
+	con.UpdatedAt = time.Now().UTC()
+	if err := s.UpdateContract(ctx, testUser, con); err != nil {
+		t.Fatalf("UpdateContract: %v", err)
+	}
+
+	got, err := s.GetContract(ctx, testUser, con.ID)
+	if err != nil {
+		t.Fatalf("GetContract: %v", err)
+	}
+	if got.Name != "New" {
+		t.Errorf("Name = %q, want %q", got.Name, "New")
+	}
+}
+
+func testDeleteContract(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+
+	con := makeContract(cat.ID, "ToDelete")
+	s.CreateContract(ctx, testUser, con)
+
+	if err := s.DeleteContract(ctx, testUser, con.ID); err != nil {
+		t.Fatalf("DeleteContract: %v", err)
+	}
+
+	_, err := s.GetContract(ctx, testUser, con.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testGetContract_NotFound(t *testing.T, s Store) {
+	_, err := s.GetContract(context.Background(), testUser, uuid.New())
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testUpdateContract_NotFound(t *testing.T, s Store) {
+	cat := makeCategory("Cat")
+	s.CreateCategory(context.Background(), testUser, testModule, cat)
+	err := s.UpdateContract(context.Background(), testUser, makeContract(cat.ID, "Ghost"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testDeleteContract_NotFound(t *testing.T, s Store) {
+	err := s.DeleteContract(context.Background(), testUser, uuid.New())
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// Cascade delete
+
+func testDeleteCategory_CascadesContracts(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+
+	con1 := makeContract(cat.ID, "C1")
+	con2 := makeContract(cat.ID, "C2")
+	s.CreateContract(ctx, testUser, con1)
+	s.CreateContract(ctx, testUser, con2)
+
+	if err := s.DeleteCategory(ctx, testUser, testModule, cat.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+
+	// Both contracts should be gone
+	for _, id := range []uuid.UUID{con1.ID, con2.ID} {
+		_, err := s.GetContract(ctx, testUser, id)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("contract %s: expected ErrNotFound, got %v", id, err)
+		}
+	}
+
+	// Index should be clean
+	list, err := s.ListContractsByCategory(ctx, testUser, cat.ID)
+	if err != nil {
+		t.Fatalf("ListContractsByCategory: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("expected 0 contracts after cascade, got %d", len(list))
+	}
+}
+
+func testDeleteCategory_CascadesPurchases(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, "purchases", cat)
+
+	p1 := makePurchase(cat.ID, "Item1")
+	p2 := makePurchase(cat.ID, "Item2")
+	s.CreatePurchase(ctx, testUser, p1)
+	s.CreatePurchase(ctx, testUser, p2)
+
+	if err := s.DeleteCategory(ctx, testUser, "purchases", cat.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+
+	for _, id := range []uuid.UUID{p1.ID, p2.ID} {
+		_, err := s.GetPurchase(ctx, testUser, id)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("purchase %s: expected ErrNotFound, got %v", id, err)
+		}
+	}
+
+	list, err := s.ListPurchasesByCategory(ctx, testUser, cat.ID)
+	if err != nil {
+		t.Fatalf("ListPurchasesByCategory: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("expected 0 purchases after cascade, got %d", len(list))
+	}
+}
+
+// Index consistency
+
+func testUpdateContract_CategoryChange_UpdatesIndex(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cat1 := makeCategory("Cat1")
+	cat2 := makeCategory("Cat2")
+	s.CreateCategory(ctx, testUser, testModule, cat1)
+	s.CreateCategory(ctx, testUser, testModule, cat2)
+
+	con := makeContract(cat1.ID, "Moveable")
+	s.CreateContract(ctx, testUser, con)
+
+	con.CategoryID = cat2.ID
+	con.UpdatedAt = time.Now().UTC()
+	if err := s.UpdateContract(ctx, testUser, con); err != nil {
+		t.Fatalf("UpdateContract: %v", err)
+	}
+
+	list1, _ := s.ListContractsByCategory(ctx, testUser, cat1.ID)
+	list2, _ := s.ListContractsByCategory(ctx, testUser, cat2.ID)
+
+	if len(list1) != 0 {
+		t.Errorf("cat1 should have 0 contracts, got %d", len(list1))
+	}
+	if len(list2) != 1 {
+		t.Errorf("cat2 should have 1 contract, got %d", len(list2))
+	}
+}
+
+func testDeleteContract_CleansIndex(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+
+	con := makeContract(cat.ID, "C")
+	s.CreateContract(ctx, testUser, con)
+	s.DeleteContract(ctx, testUser, con.ID)
+
+	list, _ := s.ListContractsByCategory(ctx, testUser, cat.ID)
+	if len(list) != 0 {
+		t.Errorf("expected 0 contracts after delete, got %d", len(list))
+	}
+}
+
+// Price entries
+
+func makePriceEntry(contractID uuid.UUID, price float64, effective string) model.PriceEntry {
+	return model.PriceEntry{
+		ID:            uuid.New(),
+		ContractID:    contractID,
+		Price:         price,
+		EffectiveDate: effective,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+func testCreateAndListPriceEntries(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con1 := makeContract(cat.ID, "C1")
+	con2 := makeContract(cat.ID, "C2")
+	s.CreateContract(ctx, testUser, con1)
+	s.CreateContract(ctx, testUser, con2)
+
+	for _, p := range []model.PriceEntry{
+		makePriceEntry(con1.ID, 10, "2024-01-01"),
+		makePriceEntry(con1.ID, 12, "2025-01-01"),
+		makePriceEntry(con2.ID, 30, "2024-01-01"),
+	} {
+		if err := s.CreatePriceEntry(ctx, testUser, p); err != nil {
+			t.Fatalf("CreatePriceEntry: %v", err)
+		}
+	}
+
+	byContract, err := s.ListPriceEntriesByContract(ctx, testUser, con1.ID)
+	if err != nil {
+		t.Fatalf("ListPriceEntriesByContract: %v", err)
+	}
+	if len(byContract) != 2 {
+		t.Errorf("expected 2 entries for con1, got %d", len(byContract))
+	}
+
+	all, err := s.ListPriceEntries(ctx, testUser)
+	if err != nil {
+		t.Fatalf("ListPriceEntries: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(all))
+	}
+}
+
+func testCreatePriceEntry_ContractNotFound(t *testing.T, s Store) {
+	err := s.CreatePriceEntry(context.Background(), testUser, makePriceEntry(uuid.New(), 1, "2025-01-01"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testUpdateContract_WithPriceEntries(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con := makeContract(cat.ID, "C1")
+	s.CreateContract(ctx, testUser, con)
+
+	con.Name = "Renamed"
+	prices := []model.PriceEntry{makePriceEntry(con.ID, 10, "2024-01-01"), makePriceEntry(con.ID, 12, "2025-01-01")}
+	if err := s.UpdateContract(ctx, testUser, con, prices...); err != nil {
+		t.Fatalf("UpdateContract: %v", err)
+	}
+	if got, _ := s.GetContract(ctx, testUser, con.ID); got.Name != "Renamed" {
+		t.Errorf("name = %q, want Renamed", got.Name)
+	}
+	if got, _ := s.ListPriceEntriesByContract(ctx, testUser, con.ID); len(got) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(got))
+	}
+
+	// A failed update adds no entries either.
+	missing := makeContract(cat.ID, "Missing")
+	if err := s.UpdateContract(ctx, testUser, missing, makePriceEntry(missing.ID, 5, "2025-01-01")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if got, _ := s.ListPriceEntries(ctx, testUser); len(got) != 2 {
+		t.Errorf("expected 2 entries after failed update, got %d", len(got))
+	}
+}
+
+func testDeletePriceEntry(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con := makeContract(cat.ID, "C")
+	s.CreateContract(ctx, testUser, con)
+	p := makePriceEntry(con.ID, 10, "2025-01-01")
+	s.CreatePriceEntry(ctx, testUser, p)
+
+	if err := s.DeletePriceEntry(ctx, testUser, p.ID); err != nil {
+		t.Fatalf("DeletePriceEntry: %v", err)
+	}
+	if _, err := s.GetPriceEntry(ctx, testUser, p.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	list, _ := s.ListPriceEntriesByContract(ctx, testUser, con.ID)
+	if len(list) != 0 {
+		t.Errorf("expected 0 entries after delete, got %d", len(list))
+	}
+}
+
+func testDeleteContract_CascadesPriceEntries(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con := makeContract(cat.ID, "C")
+	s.CreateContract(ctx, testUser, con)
+	p := makePriceEntry(con.ID, 10, "2025-01-01")
+	s.CreatePriceEntry(ctx, testUser, p)
+
+	if err := s.DeleteContract(ctx, testUser, con.ID); err != nil {
+		t.Fatalf("DeleteContract: %v", err)
+	}
+	// The price history stays with the contract in the trash.
+	if all, _ := s.ListPriceEntries(ctx, testUser); len(all) != 0 {
+		t.Errorf("expected price entries of a trashed contract to be hidden, got %d", len(all))
+	}
+	if err := s.PurgeFromTrash(ctx, testUser, model.EntityContract, con.ID); err != nil {
+		t.Fatalf("PurgeFromTrash: %v", err)
+	}
+	if _, err := s.GetPriceEntry(ctx, testUser, p.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected price entry to be deleted, got %v", err)
+	}
+}
+
+func testDeleteCategory_CascadesPriceEntries(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con := makeContract(cat.ID, "C")
+	s.CreateContract(ctx, testUser, con)
+	s.CreatePriceEntry(ctx, testUser, makePriceEntry(con.ID, 10, "2025-01-01"))
+
+	if err := s.DeleteCategory(ctx, testUser, testModule, cat.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+	all, _ := s.ListPriceEntries(ctx, testUser)
+	if len(all) != 0 {
+		t.Errorf("expected 0 price entries after cascade, got %d", len(all))
+	}
+}
+
+// Purchase CRUD
+
+func testCreateAndGetPurchase(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, "purchases", cat)
+
+	p := makePurchase(cat.ID, "GPU")
+	if err := s.CreatePurchase(ctx, testUser, p); err != nil {
+		t.Fatalf("CreatePurchase: %v", err)
+	}
+
+	got, err := s.GetPurchase(ctx, testUser, p.ID)
+	if err != nil {
+		t.Fatalf("GetPurchase: %v", err)
+	}
+	if got.ItemName != p.ItemName {
+		t.Errorf("ItemName = %q, want %q", got.ItemName, p.ItemName)
+	}
+	if got.CategoryID != cat.ID {
+		t.Errorf("CategoryID = %s, want %s", got.CategoryID, cat.ID)
+	}
+}
+
+func testListPurchases(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, "purchases", cat)
+
+	for _, name := range []string{"A", "B"} {
+		s.CreatePurchase(ctx, testUser, makePurchase(cat.ID, name))
+	}
+
+	all, err := s.ListPurchases(ctx, testUser)
+	if err != nil {
+		t.Fatalf("ListPurchases: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 purchases, got %d", len(all))
+	}
+}
+
+func testListPurchasesByCategory(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cat1 := makeCategory("Cat1")
+	cat2 := makeCategory("Cat2")
+	s.CreateCategory(ctx, testUser, "purchases", cat1)
+	s.CreateCategory(ctx, testUser, "purchases", cat2)
+
+	s.CreatePurchase(ctx, testUser, makePurchase(cat1.ID, "P1"))
+	s.CreatePurchase(ctx, testUser, makePurchase(cat1.ID, "P2"))
+	s.CreatePurchase(ctx, testUser, makePurchase(cat2.ID, "P3"))
+
+	list, err := s.ListPurchasesByCategory(ctx, testUser, cat1.ID)
+	if err != nil {
+		t.Fatalf("ListPurchasesByCategory: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 purchases for cat1, got %d", len(list))
+	}
+
+	list, err = s.ListPurchasesByCategory(ctx, testUser, cat2.ID)
+	if err != nil {
+		t.Fatalf("ListPurchasesByCategory: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected 1 purchase for cat2, got %d", len(list))
+	}
+}
+
+func testUpdatePurchase(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, "purchases", cat)
+
+	p := makePurchase(cat.ID, "Old")
+	s.CreatePurchase(ctx, testUser, p)
+
+	p.ItemName = "New"
+	p.UpdatedAt = time.Now().UTC()
+	if err := s.UpdatePurchase(ctx, testUser, p); err != nil {
+		t.Fatalf("UpdatePurchase: %v", err)
+	}
+
+	got, err := s.GetPurchase(ctx, testUser, p.ID)
+	if err != nil {
+		t.Fatalf("GetPurchase: %v", err)
+	}
+	if got.ItemName != "New" {
+		t.Errorf("ItemName = %q, want %q", got.ItemName, "New")
+	}
+}
+
+func testDeletePurchase(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, "purchases", cat)
+
+	p := makePurchase(cat.ID, "ToDelete")
+	s.CreatePurchase(ctx, testUser, p)
+
+	if err := s.DeletePurchase(ctx, testUser, p.ID); err != nil {
+		t.Fatalf("DeletePurchase: %v", err)
+	}
+
+	_, err := s.GetPurchase(ctx, testUser, p.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testGetPurchase_NotFound(t *testing.T, s Store) {
+	_, err := s.GetPurchase(context.Background(), testUser, uuid.New())
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func testUpdatePurchase_CategoryChange_UpdatesIndex(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cat1 := makeCategory("Cat1")
+	cat2 := makeCategory("Cat2")
+	s.CreateCategory(ctx, testUser, "purchases", cat1)
+	s.CreateCategory(ctx, testUser, "purchases", cat2)
+
+	p := makePurchase(cat1.ID, "Moveable")
+	s.CreatePurchase(ctx, testUser, p)
+
+	p.CategoryID = cat2.ID
+	p.UpdatedAt = time.Now().UTC()
+	if err := s.UpdatePurchase(ctx, testUser, p); err != nil {
+		t.Fatalf("UpdatePurchase: %v", err)
+	}
+
+	list1, _ := s.ListPurchasesByCategory(ctx, testUser, cat1.ID)
+	list2, _ := s.ListPurchasesByCategory(ctx, testUser, cat2.ID)
+
+	if len(list1) != 0 {
+		t.Errorf("cat1 should have 0 purchases, got %d", len(list1))
+	}
+	if len(list2) != 1 {
+		t.Errorf("cat2 should have 1 purchase, got %d", len(list2))
+	}
+}
+
+// User auth
+
+func testCreateAndGetUserByEmail(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	user := model.User{
+		ID:           uuid.New(),
+		Email:        "test@example.com",
+		PasswordHash: "$2a$10$fakehashfortest",
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	got, err := s.GetUserByEmail(ctx, "test@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("ID = %s, want %s", got.ID, user.ID)
+	}
+	if got.Email != user.Email {
+		t.Errorf("Email = %q, want %q", got.Email, user.Email)
+	}
+	if got.PasswordHash != user.PasswordHash {
+		t.Errorf("PasswordHash = %q, want %q", got.PasswordHash, user.PasswordHash)
+	}
+}
+
+func testCreateUser_DuplicateEmail(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	user := model.User{
+		ID:           uuid.New(),
+		Email:        "dup@example.com",
+		PasswordHash: "hash",
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	user.ID = uuid.New()
+	err := s.CreateUser(ctx, user)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+}
+
+func testUpdateUser_EmailChangeMovesIndex(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	user := model.User{ID: uuid.New(), Email: "old@example.com", CreatedAt: time.Now().UTC()}
+	other := model.User{ID: uuid.New(), Email: "other@example.com", CreatedAt: time.Now().UTC()}
+	for _, u := range []model.User{user, other} {
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	user.Email = "other@example.com"
+	if err := s.UpdateUser(ctx, user); !errors.Is(err, ErrConflict) {
+		t.Fatalf("taken address: expected ErrConflict, got %v", err)
+	}
+
+	user.Email = "new@example.com"
+	user.EmailVerified = true
+	if err := s.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if _, err := s.GetUserByEmail(ctx, "old@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("old address: expected ErrNotFound, got %v", err)
+	}
+	got, err := s.GetUserByEmail(ctx, "new@example.com")
+	if err != nil || got.ID != user.ID || !got.EmailVerified {
+		t.Errorf("new address: got %+v, %v", got, err)
+	}
+}
+
+func testEmailVerification_SetConsume(t *testing.T, s Store) {
+	ctx := context.Background()
+	uid := uuid.New().String()
+	now := time.Now().UTC()
+
+	first := model.EmailVerification{TokenHash: "first", UserID: uid, Email: "a@example.com", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	second := first
+	second.TokenHash = "second"
+	for _, v := range []model.EmailVerification{first, second} {
+		if err := s.SetEmailVerification(ctx, v); err != nil {
+			t.Fatalf("SetEmailVerification: %v", err)
+		}
+	}
+
+	if _, err := s.ConsumeEmailVerification(ctx, "first"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("replaced token: expected ErrNotFound, got %v", err)
+	}
+	got, err := s.ConsumeEmailVerification(ctx, "second")
+	if err != nil || got.Email != "a@example.com" || got.UserID != uid {
+		t.Fatalf("ConsumeEmailVerification = %+v, %v", got, err)
+	}
+	if _, err := s.ConsumeEmailVerification(ctx, "second"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second consume: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testGetUserByEmail_NotFound(t *testing.T, s Store) {
+	_, err := s.GetUserByEmail(context.Background(), "nobody@test.com")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// User isolation
+
+func testUserIsolation(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	cat := makeCategory("UserA-Cat")
+	s.CreateCategory(ctx, "user-a", testModule, cat)
+
+	cats, _ := s.ListCategories(ctx, "user-b", testModule)
+	if len(cats) != 0 {
+		t.Errorf("user-b should see 0 categories, got %d", len(cats))
+	}
+
+	_, err := s.GetCategory(ctx, "user-b", testModule, cat.ID)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("user-b should get ErrNotFound, got %v", err)
+	}
+}
+
+// Calendar feed tokens
+
+func testFeedToken_SetGetRotateDelete(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	if _, err := s.GetFeedToken(ctx, testUser); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before creation, got %v", err)
+	}
+
+	first := model.FeedToken{TokenHash: "hash-1", CreatedAt: time.Now().UTC()}
+	if err := s.SetFeedToken(ctx, testUser, first); err != nil {
+		t.Fatalf("SetFeedToken: %v", err)
+	}
+	uid, err := s.GetUserIDByFeedToken(ctx, "hash-1")
+	if err != nil || uid != testUser {
+		t.Fatalf("GetUserIDByFeedToken = %q, %v; want %q", uid, err, testUser)
+	}
+
+	// Rotation drops the old index entry.
+	if err := s.SetFeedToken(ctx, testUser, model.FeedToken{TokenHash: "hash-2", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("SetFeedToken: %v", err)
+	}
+	if _, err := s.GetUserIDByFeedToken(ctx, "hash-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("old token should be gone, got %v", err)
+	}
+	got, err := s.GetFeedToken(ctx, testUser)
+	if err != nil || got.TokenHash != "hash-2" {
+		t.Fatalf("GetFeedToken = %+v, %v; want hash-2", got, err)
+	}
+
+	if err := s.DeleteFeedToken(ctx, testUser); err != nil {
+		t.Fatalf("DeleteFeedToken: %v", err)
+	}
+	if _, err := s.GetUserIDByFeedToken(ctx, "hash-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted token should be gone, got %v", err)
+	}
+	if err := s.DeleteFeedToken(ctx, testUser); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second delete: expected ErrNotFound, got %v", err)
+	}
+}
+
+// Sessions
+
+func testSession_CRUD(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sess := model.Session{
+		ID:               uuid.New(),
+		UserID:           testUser,
+		RefreshTokenHash: "hash-1",
+		UserAgent:        "test",
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(time.Hour),
+	}
+	if err := s.CreateSession(ctx, sess); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	got, err := s.GetSession(ctx, sess.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got.UserID != testUser || got.RefreshTokenHash != "hash-1" || !got.ExpiresAt.Equal(sess.ExpiresAt) {
+		t.Errorf("GetSession = %+v", got)
+	}
+
+	sess.RefreshTokenHash = "hash-2"
+	if err := s.UpdateSession(ctx, sess); err != nil {
+		t.Fatalf("UpdateSession: %v", err)
+	}
+	list, err := s.ListSessions(ctx, testUser)
+	if err != nil || len(list) != 1 || list[0].RefreshTokenHash != "hash-2" {
+		t.Fatalf("ListSessions = %+v, %v", list, err)
+	}
+	if other, _ := s.ListSessions(ctx, "user-b"); len(other) != 0 {
+		t.Errorf("user-b should have no sessions, got %d", len(other))
+	}
+
+	if err := s.DeleteSession(ctx, "user-b", sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting another user's session: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteSession(ctx, testUser, sess.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := s.GetSession(ctx, sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted session: expected ErrNotFound, got %v", err)
+	}
+	if err := s.UpdateSession(ctx, sess); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating deleted session: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testSession_RotateOnce(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sess := model.Session{ID: uuid.New(), UserID: testUser, RefreshTokenHash: "hash-0", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := s.CreateSession(ctx, sess); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	// Of many concurrent rotations of the same token, exactly one wins.
+	const n = 10
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			next := sess
+			next.RefreshTokenHash = fmt.Sprintf("hash-%d", i+1)
+			errs[i] = s.RotateSession(ctx, next, "hash-0")
+		}()
+	}
+	wg.Wait()
+
+	winner := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			winner = i + 1
+		case !errors.Is(err, ErrTokenReused):
+			t.Errorf("rotation %d: expected ErrTokenReused, got %v", i, err)
+		}
+	}
+	if n-countErrors(errs) != 1 {
+		t.Fatalf("%d rotations succeeded, want 1", n-countErrors(errs))
+	}
+	got, err := s.GetSession(ctx, sess.ID)
+	if err != nil || got.RefreshTokenHash != fmt.Sprintf("hash-%d", winner) {
+		t.Errorf("GetSession = %+v, %v; want the winner's hash", got, err)
+	}
+
+	sess.ID = uuid.New()
+	if err := s.RotateSession(ctx, sess, "hash-0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing session: expected ErrNotFound, got %v", err)
+	}
+}
+
+func countErrors(errs []error) int {
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func testTwoFactor_CodesWorkOnce(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	tf := model.TwoFactor{Secret: "SECRET", RecoveryCodes: []string{"code-1", "code-2"}, LastUsedStep: 5, CreatedAt: time.Now().UTC()}
+	if err := s.SetTwoFactor(ctx, testUser, tf); err != nil {
+		t.Fatalf("SetTwoFactor: %v", err)
+	}
+	// Pending secrets cannot be used.
+	if err := s.UseTwoFactorStep(ctx, testUser, 6); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pending step: expected ErrNotFound, got %v", err)
+	}
+	tf.Enabled = true
+	if err := s.SetTwoFactor(ctx, testUser, tf); err != nil {
+		t.Fatalf("SetTwoFactor: %v", err)
+	}
+
+	// Of many concurrent uses of the same code, exactly one wins.
+	const n = 10
+	steps := make([]error, n)
+	codes := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			steps[i] = s.UseTwoFactorStep(ctx, testUser, 6)
+		}()
+		go func() {
+			defer wg.Done()
+			codes[i] = s.ConsumeRecoveryCode(ctx, testUser, "code-1")
+		}()
+	}
+	wg.Wait()
+	for name, errs := range map[string][]error{"step": steps, "recovery code": codes} {
+		if n-countErrors(errs) != 1 {
+			t.Errorf("%s used %d times, want 1", name, n-countErrors(errs))
+		}
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+			}
+		}
+	}
+
+	got, err := s.GetTwoFactor(ctx, testUser)
+	if err != nil || got.LastUsedStep != 6 || !slices.Equal(got.RecoveryCodes, []string{"code-2"}) {
+		t.Errorf("GetTwoFactor = %+v, %v", got, err)
+	}
+	if err := s.UseTwoFactorStep(ctx, testUser, 5); !errors.Is(err, ErrNotFound) {
+		t.Errorf("older step: expected ErrNotFound, got %v", err)
+	}
+	if err := s.ConsumeRecoveryCode(ctx, "user-b", "code-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("other user: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testPasswordResetToken_SetConsume(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Hour).UTC()
+	if err := s.SetPasswordResetToken(ctx, model.PasswordResetToken{TokenHash: "hash-1", UserID: testUser, ExpiresAt: expires}); err != nil {
+		t.Fatalf("SetPasswordResetToken: %v", err)
+	}
+	// A new token replaces the previous one.
+	if err := s.SetPasswordResetToken(ctx, model.PasswordResetToken{TokenHash: "hash-2", UserID: testUser, ExpiresAt: expires}); err != nil {
+		t.Fatalf("SetPasswordResetToken: %v", err)
+	}
+	if _, err := s.ConsumePasswordResetToken(ctx, "hash-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("replaced token: expected ErrNotFound, got %v", err)
+	}
+
+	got, err := s.ConsumePasswordResetToken(ctx, "hash-2")
+	if err != nil || got.UserID != testUser {
+		t.Fatalf("ConsumePasswordResetToken = %+v, %v", got, err)
+	}
+	if _, err := s.ConsumePasswordResetToken(ctx, "hash-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second use: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testLoginAttempts_IncrementGetDelete(t *testing.T, s Store) {
+	ctx := context.Background()
+	const key = "account:a@b.com"
+	lockAfterTwo := func(failures int) time.Duration {
+		if failures >= 2 {
+			return 30 * time.Minute
+		}
+		return 0
+	}
+
+	if _, err := s.GetLoginAttempts(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	a, err := s.IncrementLoginAttempts(ctx, key, now, time.Hour, lockAfterTwo)
+	if err != nil || a.Failures != 1 || !a.LockedUntil.IsZero() || !a.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("first IncrementLoginAttempts = %+v, %v", a, err)
+	}
+	a, err = s.IncrementLoginAttempts(ctx, key, now, time.Hour, lockAfterTwo)
+	if err != nil || a.Failures != 2 || !a.LockedUntil.Equal(now.Add(30*time.Minute)) {
+		t.Fatalf("second IncrementLoginAttempts = %+v, %v", a, err)
+	}
+
+	// A locked key is not counted any further.
+	locked, err := s.IncrementLoginAttempts(ctx, key, now.Add(time.Minute), time.Hour, lockAfterTwo)
+	if !errors.Is(err, ErrLockedOut) || locked.Failures != 2 || !locked.LockedUntil.Equal(a.LockedUntil) {
+		t.Fatalf("locked IncrementLoginAttempts = %+v, %v; want ErrLockedOut", locked, err)
+	}
+	got, err := s.GetLoginAttempts(ctx, key)
+	if err != nil || got.Failures != 2 || !got.LockedUntil.Equal(a.LockedUntil) {
+		t.Fatalf("GetLoginAttempts = %+v, %v", got, err)
+	}
+
+	// Taking the attempt back lifts the lock it set.
+	if err := s.DecrementLoginAttempts(ctx, a); err != nil {
+		t.Fatalf("DecrementLoginAttempts: %v", err)
+	}
+	got, err = s.GetLoginAttempts(ctx, key)
+	if err != nil || got.Failures != 1 || !got.LockedUntil.IsZero() {
+		t.Fatalf("after decrement: GetLoginAttempts = %+v, %v", got, err)
+	}
+
+	if err := s.DeleteLoginAttempts(ctx, key); err != nil {
+		t.Fatalf("DeleteLoginAttempts: %v", err)
+	}
+	if _, err := s.GetLoginAttempts(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("after delete: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteLoginAttempts(ctx, key); err != nil {
+		t.Errorf("deleting missing attempts: %v", err)
+	}
+	if err := s.DecrementLoginAttempts(ctx, a); err != nil {
+		t.Errorf("decrementing missing attempts: %v", err)
+	}
+}
+
+func testLoginAttempts_ConcurrentIncrements(t *testing.T, s Store) {
+	ctx := context.Background()
+	const key = "ip:192.0.2.1"
+	now := time.Now().UTC()
+
+	// Every concurrent attempt is counted once, and only the first three
+	// get past a key that locks after three.
+	lockAfterThree := func(failures int) time.Duration {
+		if failures >= 3 {
+			return time.Hour
+		}
+		return 0
+	}
+	const n = 10
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = s.IncrementLoginAttempts(ctx, key, now, time.Hour, lockAfterThree)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, ErrLockedOut) {
+			t.Errorf("attempt %d: expected ErrLockedOut, got %v", i, err)
+		}
+	}
+	if allowed := n - countErrors(errs); allowed != 3 {
+		t.Errorf("%d attempts allowed, want 3", allowed)
+	}
+	got, err := s.GetLoginAttempts(ctx, key)
+	if err != nil || got.Failures != 3 {
+		t.Errorf("GetLoginAttempts = %+v, %v; want 3 failures", got, err)
+	}
+}
+
+func testAPIKey_CRUD(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	k := model.APIKey{
+		ID:        uuid.New(),
+		UserID:    testUser,
+		Name:      "backup script",
+		Prefix:    "ck_abcdefgh",
+		KeyHash:   "hash-1",
+		Access:    model.APIKeyAccessRead,
+		Modules:   []string{"contracts"},
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if err := s.CreateAPIKey(ctx, k); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	got, err := s.GetAPIKeyByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("GetAPIKeyByHash: %v", err)
+	}
+	if got.ID != k.ID || got.UserID != testUser || got.KeyHash != "hash-1" || !slices.Equal(got.Modules, k.Modules) {
+		t.Errorf("GetAPIKeyByHash = %+v", got)
+	}
+	if _, err := s.GetAPIKeyByHash(ctx, "hash-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown hash: expected ErrNotFound, got %v", err)
+	}
+
+	used := time.Now().UTC().Truncate(time.Second)
+	k.LastUsedAt = &used
+	if err := s.UpdateAPIKey(ctx, k); err != nil {
+		t.Fatalf("UpdateAPIKey: %v", err)
+	}
+	list, err := s.ListAPIKeys(ctx, testUser)
+	if err != nil || len(list) != 1 || list[0].LastUsedAt == nil || !list[0].LastUsedAt.Equal(used) {
+		t.Fatalf("ListAPIKeys = %+v, %v", list, err)
+	}
+	if other, _ := s.ListAPIKeys(ctx, "user-b"); len(other) != 0 {
+		t.Errorf("user-b should have no API keys, got %d", len(other))
+	}
+
+	if err := s.DeleteAPIKey(ctx, "user-b", k.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting another user's key: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteAPIKey(ctx, testUser, k.ID); err != nil {
+		t.Fatalf("DeleteAPIKey: %v", err)
+	}
+	if _, err := s.GetAPIKeyByHash(ctx, "hash-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted key: expected ErrNotFound, got %v", err)
+	}
+	if err := s.UpdateAPIKey(ctx, k); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating deleted key: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testWorkspace_MembersAndInvitations(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	ws := model.Workspace{ID: uuid.New(), Name: "Home", CreatedAt: time.Now().UTC()}
+	owner := model.WorkspaceMember{WorkspaceID: ws.ID, UserID: testUser, Role: model.WorkspaceRoleOwner}
+	if err := s.CreateWorkspace(ctx, ws, owner); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	if got, err := s.GetWorkspace(ctx, ws.ID); err != nil || got.Name != "Home" {
+		t.Fatalf("GetWorkspace = %+v, %v", got, err)
+	}
+
+	inv := model.Invitation{
+		ID:          uuid.New(),
+		WorkspaceID: ws.ID,
+		Email:       "bob@example.com",
+		Role:        model.WorkspaceRoleViewer,
+		TokenHash:   "invite-hash",
+		InvitedBy:   testUser,
+		CreatedAt:   time.Now().UTC(),
+		ExpiresAt:   time.Now().UTC().Add(time.Hour),
+	}
+	if err := s.CreateInvitation(ctx, inv); err != nil {
+		t.Fatalf("CreateInvitation: %v", err)
+	}
+	if list, err := s.ListInvitations(ctx, ws.ID); err != nil || len(list) != 1 {
+		t.Fatalf("ListInvitations = %+v, %v", list, err)
+	}
+	got, err := s.ConsumeInvitation(ctx, "invite-hash")
+	if err != nil || got.ID != inv.ID || got.TokenHash != "invite-hash" {
+		t.Fatalf("ConsumeInvitation = %+v, %v", got, err)
+	}
+	if _, err := s.ConsumeInvitation(ctx, "invite-hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second consume: expected ErrNotFound, got %v", err)
+	}
+
+	viewer := model.WorkspaceMember{WorkspaceID: ws.ID, UserID: "user-b", Role: got.Role}
+	if err := s.SetWorkspaceMember(ctx, viewer); err != nil {
+		t.Fatalf("SetWorkspaceMember: %v", err)
+	}
+	if m, err := s.GetWorkspaceMember(ctx, ws.ID, "user-b"); err != nil || m.Role != model.WorkspaceRoleViewer {
+		t.Fatalf("GetWorkspaceMember = %+v, %v", m, err)
+	}
+	if members, _ := s.ListWorkspaceMembers(ctx, ws.ID); len(members) != 2 {
+		t.Errorf("expected 2 members, got %d", len(members))
+	}
+	if ms, _ := s.ListWorkspaceMemberships(ctx, "user-b"); len(ms) != 1 || ms[0].WorkspaceID != ws.ID {
+		t.Errorf("ListWorkspaceMemberships(user-b) = %+v", ms)
+	}
+
+	if err := s.DeleteWorkspaceMember(ctx, ws.ID, "user-b"); err != nil {
+		t.Fatalf("DeleteWorkspaceMember: %v", err)
+	}
+	if _, err := s.GetWorkspaceMember(ctx, ws.ID, "user-b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("removed member: expected ErrNotFound, got %v", err)
+	}
+	if ms, _ := s.ListWorkspaceMemberships(ctx, "user-b"); len(ms) != 0 {
+		t.Errorf("removed member should have no workspaces, got %d", len(ms))
+	}
+}
+
+func testDeleteWorkspace_RemovesData(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	ws := model.Workspace{ID: uuid.New(), Name: "Home", CreatedAt: time.Now().UTC()}
+	owner := model.WorkspaceMember{WorkspaceID: ws.ID, UserID: testUser, Role: model.WorkspaceRoleOwner}
+	if err := s.CreateWorkspace(ctx, ws, owner); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	cat := makeCategory("Insurance")
+	if err := s.CreateCategory(ctx, ws.ID.String(), "contracts", cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	if err := s.CreateContract(ctx, ws.ID.String(), makeContract(cat.ID, "Car")); err != nil {
+		t.Fatalf("CreateContract: %v", err)
+	}
+
+	if err := s.DeleteWorkspace(ctx, ws.ID); err != nil {
+		t.Fatalf("DeleteWorkspace: %v", err)
+	}
+	if _, err := s.GetWorkspace(ctx, ws.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted workspace: expected ErrNotFound, got %v", err)
+	}
+	if ms, _ := s.ListWorkspaceMemberships(ctx, testUser); len(ms) != 0 {
+		t.Errorf("expected no memberships, got %d", len(ms))
+	}
+	if cs, _ := s.ListContracts(ctx, ws.ID.String()); len(cs) != 0 {
+		t.Errorf("expected workspace contracts to be removed, got %d", len(cs))
+	}
+	if err := s.DeleteWorkspace(ctx, ws.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: expected ErrNotFound, got %v", err)
+	}
+}
+
+func testDeleteUser_RemovesAccountRecords(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	user := model.User{ID: uuid.New(), Email: "gone@example.com", Admin: true, CreatedAt: time.Now().UTC()}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if got, _ := s.GetUserByID(ctx, user.ID.String()); !got.Admin {
+		t.Error("admin flag was not stored")
+	}
+	id := user.ID.String()
+	sess := model.Session{ID: uuid.New(), UserID: id, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := s.CreateSession(ctx, sess); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := s.SetFeedToken(ctx, id, model.FeedToken{TokenHash: "feed-hash"}); err != nil {
+		t.Fatalf("SetFeedToken: %v", err)
+	}
+	if err := s.CreateAPIKey(ctx, model.APIKey{ID: uuid.New(), UserID: id, KeyHash: "key-hash"}); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+
+	if err := s.DeleteUser(ctx, id); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := s.GetUserByID(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUserByID: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetUserByEmail(ctx, user.Email); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUserByEmail: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetSession(ctx, sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetSession: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetUserIDByFeedToken(ctx, "feed-hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUserIDByFeedToken: expected ErrNotFound, got %v", err)
+	}
+	if _, err := s.GetAPIKeyByHash(ctx, "key-hash"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetAPIKeyByHash: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteUser(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: expected ErrNotFound, got %v", err)
+	}
+	// The email address can be registered again.
+	if err := s.CreateUser(ctx, model.User{ID: uuid.New(), Email: user.Email}); err != nil {
+		t.Errorf("re-registering: %v", err)
+	}
+}
+
+// Audit log
+
+func testAudit_RecordsChanges(t *testing.T, s Store) {
+	ctx := WithActor(context.Background(), "alice")
+	cat := makeCategory("Cat")
+	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Fatalf("CreateCategory: %v", err)
+	}
+	con := makeContract(cat.ID, "Phone")
+	if err := s.CreateContract(ctx, testUser, con); err != nil {
+		t.Fatalf("CreateContract: %v", err)
+	}
+
+	// An update that only touches UpdatedAt is not recorded.
+	con.UpdatedAt = con.UpdatedAt.Add(time.Minute)
+	if err := s.UpdateContract(ctx, testUser, con); err != nil {
+		t.Fatalf("UpdateContract: %v", err)
+	}
+	con.Name = "Mobile"
+	if err := s.UpdateContract(ctx, testUser, con); err != nil {
+		t.Fatalf("UpdateContract: %v", err)
+	}
+	if err := s.DeleteContract(ctx, testUser, con.ID); err != nil {
+		t.Fatalf("DeleteContract: %v", err)
+	}
+	if err := s.PurgeFromTrash(ctx, testUser, model.EntityContract, con.ID); err != nil {
+		t.Fatalf("PurgeFromTrash: %v", err)
+	}
+
+	entries, err := s.ListAuditEntries(ctx, testUser, con.ID)
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+		if e.ActorID != "alice" || e.EntityType != model.EntityContract || e.EntityID != con.ID {
+			t.Errorf("entry = %+v", e)
+		}
+	}
+	if !slices.Equal(actions, []string{model.AuditCreate, model.AuditUpdate, model.AuditTrash, model.AuditDelete}) {
+		t.Fatalf("actions = %v", actions)
+	}
+	if c := entries[1].Changes; len(c) != 1 || c[0].Field != "name" || string(c[0].Before) != `"Phone"` || string(c[0].After) != `"Mobile"` {
+		t.Errorf("update changes = %+v", c)
+	}
+
+	cats, err := s.ListAuditEntries(ctx, testUser, cat.ID)
+	if err != nil || len(cats) != 1 || cats[0].Module != testModule {
+		t.Errorf("category entries = %+v, %v", cats, err)
+	}
+}
+
+func testAudit_RecordsCascadingDeletes(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con := makeContract(cat.ID, "Phone")
+	s.CreateContract(ctx, testUser, con)
+	price := makePriceEntry(con.ID, 10, "2025-01-01")
+	s.CreatePriceEntry(ctx, testUser, price)
+
+	if err := s.DeleteCategory(ctx, testUser, testModule, cat.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+	if err := s.PurgeFromTrash(ctx, testUser, model.EntityCategory, cat.ID); err != nil {
+		t.Fatalf("PurgeFromTrash: %v", err)
+	}
+
+	// Price entries are not moved to the trash, only purged.
+	for id, want := range map[uuid.UUID][]string{
+		cat.ID:   {model.AuditCreate, model.AuditTrash, model.AuditDelete},
+		con.ID:   {model.AuditCreate, model.AuditTrash, model.AuditDelete},
+		price.ID: {model.AuditCreate, model.AuditDelete},
+	} {
+		entries, err := s.ListAuditEntries(ctx, testUser, id)
+		if err != nil {
+			t.Fatalf("ListAuditEntries: %v", err)
+		}
+		var actions []string
+		for _, e := range entries {
+			actions = append(actions, e.Action)
+		}
+		if !slices.Equal(actions, want) {
+			t.Errorf("actions of %s = %v, want %v", id, actions, want)
+		}
+	}
+}
+
+func testAudit_ActivityPages(t *testing.T, s Store) {
+	ctx := context.Background()
+	var ids []uuid.UUID
+	for _, name := range []string{"A", "B", "C"} {
+		cat := makeCategory(name)
+		s.CreateCategory(ctx, testUser, testModule, cat)
+		ids = append(ids, cat.ID)
+	}
+	s.CreateCategory(ctx, "other-workspace", testModule, makeCategory("Other"))
+
+	first, err := s.ListActivity(ctx, testUser, uuid.Nil, 2)
+	if err != nil {
+		t.Fatalf("ListActivity: %v", err)
+	}
+	if len(first) != 2 || first[0].EntityID != ids[2] || first[1].EntityID != ids[1] {
+		t.Fatalf("first page = %+v", first)
+	}
+	next, err := s.ListActivity(ctx, testUser, first[1].ID, 2)
+	if err != nil {
+		t.Fatalf("ListActivity: %v", err)
+	}
+	if len(next) != 1 || next[0].EntityID != ids[0] {
+		t.Fatalf("second page = %+v", next)
+	}
+}
+
+// Trash
+
+func testTrash_HidesDeleted(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con := makeContract(cat.ID, "Phone")
+	s.CreateContract(ctx, testUser, con)
+
+	if err := s.DeleteContract(ctx, testUser, con.ID); err != nil {
+		t.Fatalf("DeleteContract: %v", err)
+	}
+	if _, err := s.GetContract(ctx, testUser, con.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetContract: expected ErrNotFound, got %v", err)
+	}
+	if list, _ := s.ListContracts(ctx, testUser); len(list) != 0 {
+		t.Errorf("ListContracts = %d, want 0", len(list))
+	}
+	if list, _ := s.ListContractsByCategory(ctx, testUser, cat.ID); len(list) != 0 {
+		t.Errorf("ListContractsByCategory = %d, want 0", len(list))
+	}
+	if err := s.UpdateContract(ctx, testUser, con); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateContract: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteContract(ctx, testUser, con.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: expected ErrNotFound, got %v", err)
+	}
+	if err := s.CreateContract(ctx, testUser, con); !errors.Is(err, ErrConflict) {
+		t.Errorf("recreating: expected ErrConflict, got %v", err)
+	}
+
+	items, err := s.ListTrash(ctx, testUser)
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	if len(items) != 1 || items[0].EntityType != model.EntityContract || items[0].ID != con.ID ||
+		items[0].Name != "Phone" || items[0].ParentID == nil || *items[0].ParentID != cat.ID {
+		t.Errorf("trash = %+v", items)
+	}
+	if other, _ := s.ListTrash(ctx, "other-workspace"); len(other) != 0 {
+		t.Errorf("trash of another workspace = %+v", other)
+	}
+}
+
+func testTrash_RestoresWithChildren(t *testing.T, s Store) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	v := model.Vehicle{ID: uuid.New(), Name: "Car", CreatedAt: now, UpdatedAt: now}
+	s.CreateVehicle(ctx, testUser, v)
+	fuel := model.CostEntry{ID: uuid.New(), VehicleID: v.ID, Type: "fuel", CreatedAt: now, UpdatedAt: now}
+	tyres := model.CostEntry{ID: uuid.New(), VehicleID: v.ID, Type: "tyres", CreatedAt: now, UpdatedAt: now}
+	s.CreateCostEntry(ctx, testUser, fuel)
+	s.CreateCostEntry(ctx, testUser, tyres)
+
+	// tyres was deleted on its own and stays in the trash.
+	if err := s.DeleteCostEntry(ctx, testUser, tyres.ID); err != nil {
+		t.Fatalf("DeleteCostEntry: %v", err)
+	}
+	if err := s.DeleteVehicle(ctx, testUser, v.ID); err != nil {
+		t.Fatalf("DeleteVehicle: %v", err)
+	}
+	if items, _ := s.ListTrash(ctx, testUser); len(items) != 3 {
+		t.Fatalf("trash = %+v, want 3 items", items)
+	}
+
+	if err := s.RestoreFromTrash(ctx, testUser, model.EntityCostEntry, fuel.ID); !errors.Is(err, ErrConflict) {
+		t.Errorf("restoring with the vehicle in the trash: expected ErrConflict, got %v", err)
+	}
+	if err := s.RestoreFromTrash(ctx, testUser, model.EntityVehicle, v.ID); err != nil {
+		t.Fatalf("RestoreFromTrash: %v", err)
+	}
+	if _, err := s.GetVehicle(ctx, testUser, v.ID); err != nil {
+		t.Errorf("GetVehicle: %v", err)
+	}
+	costs, _ := s.ListCostEntries(ctx, testUser, v.ID)
+	if len(costs) != 1 || costs[0].ID != fuel.ID || costs[0].DeletedAt != nil {
+		t.Errorf("cost entries = %+v, want only fuel", costs)
+	}
+	items, _ := s.ListTrash(ctx, testUser)
+	if len(items) != 1 || items[0].ID != tyres.ID {
+		t.Errorf("trash = %+v, want only tyres", items)
+	}
+	if err := s.RestoreFromTrash(ctx, testUser, model.EntityVehicle, v.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring twice: expected ErrNotFound, got %v", err)
+	}
+
+	entries, _ := s.ListAuditEntries(ctx, testUser, fuel.ID)
+	if n := len(entries); n != 3 || entries[1].Action != model.AuditTrash || entries[2].Action != model.AuditRestore {
+		t.Errorf("fuel entries = %+v", entries)
+	}
+}
+
+func testTrash_Purge(t *testing.T, s Store) {
+	ctx := context.Background()
+	cat := makeCategory("Cat")
+	s.CreateCategory(ctx, testUser, testModule, cat)
+	con := makeContract(cat.ID, "Phone")
+	s.CreateContract(ctx, testUser, con)
+	price := makePriceEntry(con.ID, 10, "2025-01-01")
+	s.CreatePriceEntry(ctx, testUser, price)
+
+	if err := s.PurgeFromTrash(ctx, testUser, model.EntityCategory, cat.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("purging a live category: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteCategory(ctx, testUser, testModule, cat.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+	if err := s.PurgeFromTrash(ctx, testUser, model.EntityCategory, cat.ID); err != nil {
+		t.Fatalf("PurgeFromTrash: %v", err)
+	}
+
+	if items, _ := s.ListTrash(ctx, testUser); len(items) != 0 {
+		t.Errorf("trash = %+v, want empty", items)
+	}
+	if _, err := s.GetPriceEntry(ctx, testUser, price.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetPriceEntry: expected ErrNotFound, got %v", err)
+	}
+	if err := s.RestoreFromTrash(ctx, testUser, model.EntityContract, con.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring a purged contract: expected ErrNotFound, got %v", err)
+	}
+	// Purged records can be created again.
+	if err := s.CreateCategory(ctx, testUser, testModule, cat); err != nil {
+		t.Errorf("CreateCategory after purge: %v", err)
+	}
+}
+
+func testTrash_PurgeTrashBeforeCutoff(t *testing.T, s Store) {
+	ctx := context.Background()
+	ws := model.Workspace{ID: uuid.New(), Name: "Home", CreatedAt: time.Now().UTC()}
+	owner := model.WorkspaceMember{WorkspaceID: ws.ID, UserID: testUser, Role: model.WorkspaceRoleOwner}
+	if err := s.CreateWorkspace(ctx, ws, owner); err != nil {
+		t.Fatalf("CreateWorkspace: %v", err)
+	}
+	workspaceID := ws.ID.String()
+
+	cat := makeCategory("Tools")
+	s.CreateCategory(ctx, workspaceID, "purchases", cat)
+	s.CreatePurchase(ctx, workspaceID, makePurchase(cat.ID, "Drill"))
+	kept := makeCategory("Kept")
+	s.CreateCategory(ctx, workspaceID, "purchases", kept)
+	if err := s.DeleteCategory(ctx, workspaceID, "purchases", cat.ID); err != nil {
+		t.Fatalf("DeleteCategory: %v", err)
+	}
+
+	if n, err := s.PurgeTrash(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("PurgeTrash before the deletion = %d, %v; want 0", n, err)
+	}
+	if items, _ := s.ListTrash(ctx, workspaceID); len(items) != 2 {
+		t.Fatalf("trash = %+v, want the category and its purchase", items)
+	}
+
+	// The purchase goes with its category and is not counted.
+	if n, err := s.PurgeTrash(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("PurgeTrash after the deletion = %d, %v; want 1", n, err)
+	}
+	if items, _ := s.ListTrash(ctx, workspaceID); len(items) != 0 {
+		t.Errorf("trash = %+v, want empty", items)
+	}
+	if _, err := s.GetCategory(ctx, workspaceID, "purchases", kept.ID); err != nil {
+		t.Errorf("live category: %v", err)
+	}
+}
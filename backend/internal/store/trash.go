@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/tobi/contracts/backend/internal/model"
+)
+
+// sortTrash orders trash items by when they were deleted, newest first.
+func sortTrash(items []model.TrashItem) {
+	slices.SortStableFunc(items, func(a, b model.TrashItem) int {
+		return b.DeletedAt.Compare(a.DeletedAt)
+	})
+}
+
+// purgeTrash purges the items of the given workspaces that were moved to the
+// trash before cutoff. Children purged along with their parent are not
+// counted separately.
+func purgeTrash(ctx context.Context, s Store, workspaceIDs []string, cutoff time.Time) (int, error) {
+	n := 0
+	for _, workspaceID := range workspaceIDs {
+		items, err := s.ListTrash(ctx, workspaceID)
+		if err != nil {
+			return n, err
+		}
+		for _, item := range items {
+			if !item.DeletedAt.Before(cutoff) {
+				continue
+			}
+			err := s.PurgeFromTrash(ctx, workspaceID, item.EntityType, item.ID)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key from RFC 6238 appendix B.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists eight-digit codes; six-digit codes are their last six.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := Code(rfcSecret, Step(now))
+
+	step, ok := Verify(rfcSecret, code, now, 0)
+	if !ok || step != Step(now) {
+		t.Fatalf("Verify = %d, %v", step, ok)
+	}
+	if _, ok := Verify(rfcSecret, code, now.Add(Period), 0); !ok {
+		t.Error("code from the previous step should be accepted")
+	}
+	if _, ok := Verify(rfcSecret, code, now.Add(3*Period), 0); ok {
+		t.Error("code from three steps ago should be rejected")
+	}
+	if _, ok := Verify(rfcSecret, code, now, step); ok {
+		t.Error("replayed code should be rejected")
+	}
+	if _, ok := Verify(rfcSecret, "12345", now, 0); ok {
+		t.Error("short code should be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Contracts", "a@b.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Contracts:a@b.com?") || !strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Contracts") {
+		t.Errorf("URI = %s", uri)
+	}
+}
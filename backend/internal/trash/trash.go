@@ -0,0 +1,66 @@
+// Package trash purges records that have been in the trash for longer than
+// the retention period.
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Store is the part of store.Store the purger needs.
+type Store interface {
+	PurgeTrash(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+type Purger struct {
+	store     Store
+	retention time.Duration
+	logger    *slog.Logger
+	now       func() time.Time
+}
+
+// New returns a Purger that purges records moved to the trash more than
+// retention ago.
+func New(s Store, retention time.Duration, logger *slog.Logger) *Purger {
+	return &Purger{store: s, retention: retention, logger: logger.With("component", "trash"), now: time.Now}
+}
+
+// Start purges the trash right away and then every interval until ctx is
+// done.
+func (p *Purger) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		p.logger.Info("trash purger started", "retention", p.retention, "interval", interval)
+		p.purge(ctx)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				p.logger.Info("trash purger stopped")
+				return
+			case <-ticker.C:
+				p.purge(ctx)
+			}
+		}
+	}()
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	n, err := p.Run(ctx)
+	if err != nil {
+		p.logger.Error("purging trash", "error", err)
+		return
+	}
+	if n > 0 {
+		p.logger.Info("purged trash", "count", n)
+	}
+}
+
+// Run purges the records whose retention period is over and returns how
+// many it purged.
+func (p *Purger) Run(ctx context.Context) (int, error) {
+	return p.store.PurgeTrash(ctx, p.now().Add(-p.retention))
+}
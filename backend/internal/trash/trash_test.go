@@ -0,0 +1,43 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	cutoff time.Time
+	n      int
+	err    error
+}
+
+func (f *fakeStore) PurgeTrash(_ context.Context, cutoff time.Time) (int, error) {
+	f.cutoff = cutoff
+	return f.n, f.err
+}
+
+func TestRun_PurgesBeforeRetention(t *testing.T) {
+	s := &fakeStore{n: 3}
+	p := New(s, 30*24*time.Hour, slog.Default())
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	n, err := p.Run(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("Run = %d, %v; want 3", n, err)
+	}
+	if want := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC); !s.cutoff.Equal(want) {
+		t.Errorf("cutoff = %v, want %v", s.cutoff, want)
+	}
+}
+
+func TestRun_ReturnsStoreError(t *testing.T) {
+	p := New(&fakeStore{err: errors.New("disk gone")}, time.Hour, slog.Default())
+
+	if _, err := p.Run(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,153 @@
+import { useState } from "react"
+import { useTranslation } from "react-i18next"
+import { toast } from "sonner"
+import { format } from "date-fns"
+import { useQuery, useQueryClient } from "@tanstack/react-query"
+import { createApiKey, deleteApiKey, listApiKeys } from "@/lib/settings-repository"
+import type { ApiKeyAccess } from "@/types/settings"
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
+import { Input } from "@/components/ui/input"
+import { Label } from "@/components/ui/label"
+import { Button } from "@/components/ui/button"
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select"
+
+const API_KEYS_KEY = ["settings", "api-keys"] as const
+const MODULES = ["contracts", "purchases", "vehicles"] as const
+const MODULE_LABELS: Record<(typeof MODULES)[number], string> = {
+  contracts: "nav.contracts",
+  purchases: "nav.purchases",
+  vehicles: "nav.auto",
+}
+
+export function ApiKeysCard() {
+  const { t } = useTranslation()
+  const qc = useQueryClient()
+  const { data: keys } = useQuery({ queryKey: API_KEYS_KEY, queryFn: listApiKeys })
+
+  const [name, setName] = useState("")
+  const [access, setAccess] = useState<ApiKeyAccess>("read")
+  const [modules, setModules] = useState<string[]>([])
+  const [expires, setExpires] = useState("")
+  const [newKey, setNewKey] = useState<string | null>(null)
+  const [busy, setBusy] = useState(false)
+
+  async function run(action: () => Promise<void>) {
+    setBusy(true)
+    try {
+      await action()
+      await qc.invalidateQueries({ queryKey: API_KEYS_KEY })
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("settings.apiKeyFailed"))
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  const toggleModule = (m: string) =>
+    setModules((prev) => (prev.includes(m) ? prev.filter((x) => x !== m) : [...prev, m]))
+
+  const handleCreate = (e: React.FormEvent) => {
+    e.preventDefault()
+    run(async () => {
+      const created = await createApiKey({
+        name,
+        access,
+        modules: modules.length > 0 ? modules : undefined,
+        expiresAt: expires ? new Date(`${expires}T23:59:59`).toISOString() : undefined,
+      })
+      setNewKey(created.key)
+      setName("")
+      setModules([])
+      setExpires("")
+    })
+  }
+
+  const handleRevoke = (id: string) => run(async () => {
+    await deleteApiKey(id)
+    toast.success(t("settings.apiKeyRevoked"))
+  })
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle>{t("settings.apiKeys")}</CardTitle>
+        <CardDescription>{t("settings.apiKeysDescription")}</CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-6">
+        {newKey && (
+          <div className="space-y-2">
+            <p className="text-sm text-muted-foreground">{t("settings.apiKeyCreated")}</p>
+            <p className="break-all font-mono text-sm">{newKey}</p>
+          </div>
+        )}
+
+        {keys && keys.length === 0 && (
+          <p className="text-sm text-muted-foreground">{t("settings.apiKeysEmpty")}</p>
+        )}
+        {keys && keys.length > 0 && (
+          <ul className="divide-y">
+            {keys.map((k) => (
+              <li key={k.id} className="flex items-center justify-between gap-4 py-2">
+                <div className="text-sm">
+                  <p className="font-medium">
+                    {k.name} <span className="font-mono text-muted-foreground">{k.prefix}…</span>
+                  </p>
+                  <p className="text-muted-foreground">
+                    {k.access === "write" ? t("settings.apiKeyAccessWrite") : t("settings.apiKeyAccessRead")}
+                    {k.modules.length > 0 && ` · ${k.modules.map((m) => t(MODULE_LABELS[m as keyof typeof MODULE_LABELS] ?? m)).join(", ")}`}
+                    {k.expiresAt && ` · ${t("settings.apiKeyExpiresOn", { date: format(new Date(k.expiresAt), "yyyy-MM-dd") })}`}
+                    {" · "}
+                    {k.lastUsedAt
+                      ? t("settings.apiKeyLastUsed", { date: format(new Date(k.lastUsedAt), "yyyy-MM-dd HH:mm") })
+                      : t("settings.apiKeyNeverUsed")}
+                  </p>
+                </div>
+                <Button variant="outline" size="sm" onClick={() => handleRevoke(k.id)} disabled={busy}>
+                  {t("settings.apiKeyRevoke")}
+                </Button>
+              </li>
+            ))}
+          </ul>
+        )}
+
+        <form onSubmit={handleCreate} className="space-y-4 max-w-sm">
+          <div className="space-y-2">
+            <Label htmlFor="apiKeyName">{t("settings.apiKeyName")}</Label>
+            <Input id="apiKeyName" value={name} onChange={(e) => setName(e.target.value)} required />
+          </div>
+          <div className="space-y-2">
+            <Label>{t("settings.apiKeyAccess")}</Label>
+            <Select value={access} onValueChange={(v) => setAccess(v as ApiKeyAccess)}>
+              <SelectTrigger className="w-48">
+                <SelectValue />
+              </SelectTrigger>
+              <SelectContent>
+                <SelectItem value="read">{t("settings.apiKeyAccessRead")}</SelectItem>
+                <SelectItem value="write">{t("settings.apiKeyAccessWrite")}</SelectItem>
+              </SelectContent>
+            </Select>
+          </div>
+          <div className="space-y-2">
+            <Label>{t("settings.apiKeyModules")}</Label>
+            <div className="flex flex-wrap gap-4">
+              {MODULES.map((m) => (
+                <label key={m} className="flex items-center gap-2 text-sm">
+                  <input type="checkbox" checked={modules.includes(m)} onChange={() => toggleModule(m)} />
+                  {t(MODULE_LABELS[m])}
+                </label>
+              ))}
+            </div>
+            <p className="text-sm text-muted-foreground">{t("settings.apiKeyModulesHint")}</p>
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="apiKeyExpires">{t("settings.apiKeyExpires")}</Label>
+            <Input id="apiKeyExpires" type="date" value={expires} onChange={(e) => setExpires(e.target.value)} />
+          </div>
+          <Button type="submit" disabled={busy}>
+            {t("settings.apiKeyCreate")}
+          </Button>
+        </form>
+      </CardContent>
+    </Card>
+  )
+}
@@ -26,9 +26,9 @@ const defaultValues: ContractFormData = {
   name: "",
   startDate: new Date().toISOString().slice(0, 10),
   billingInterval: "monthly",
-  minimumDurationMonths: 12,
-  extensionDurationMonths: 12,
-  noticePeriodMonths: 3,
+  minimumDuration: 12,
+  extensionDuration: 12,
+  noticePeriod: 3,
 }
 
 export function ContractDialog({ open, onOpenChange, contract, onSubmit }: ContractDialogProps) {
@@ -51,9 +51,15 @@ export function ContractDialog({ open, onOpenChange, contract, onSubmit }: Contr
           billingInterval: contract.billingInterval,
           startDate: contract.startDate,
           endDate: contract.endDate,
-          minimumDurationMonths: contract.minimumDurationMonths,
-          extensionDurationMonths: contract.extensionDurationMonths,
-          noticePeriodMonths: contract.noticePeriodMonths,
+          minimumDuration: contract.minimumDuration,
+          minimumDurationUnit: contract.minimumDurationUnit,
+          minimumDurationAnchor: contract.minimumDurationAnchor,
+          extensionDuration: contract.extensionDuration,
+          extensionDurationUnit: contract.extensionDurationUnit,
+          extensionDurationAnchor: contract.extensionDurationAnchor,
+          noticePeriod: contract.noticePeriod,
+          noticePeriodUnit: contract.noticePeriodUnit,
+          noticePeriodAnchor: contract.noticePeriodAnchor,
           customerPortalUrl: contract.customerPortalUrl,
           paperlessUrl: contract.paperlessUrl,
           comments: contract.comments,
@@ -46,8 +46,42 @@ export function FormFieldRenderer<T extends FieldValues>({ config, control }: Fo
                   <SelectValue />
                 </SelectTrigger>
                 <SelectContent>
+                  <SelectItem value="weekly">{t("fields.billingWeekly")}</SelectItem>
                   <SelectItem value="monthly">{t("fields.billingMonthly")}</SelectItem>
+                  <SelectItem value="quarterly">{t("fields.billingQuarterly")}</SelectItem>
+                  <SelectItem value="half-yearly">{t("fields.billingHalfYearly")}</SelectItem>
                   <SelectItem value="yearly">{t("fields.billingYearly")}</SelectItem>
+                  <SelectItem value="biennial">{t("fields.billingBiennial")}</SelectItem>
+                  <SelectItem value="one-time">{t("fields.billingOneTime")}</SelectItem>
+                </SelectContent>
+              </Select>
+            ) : config.type === "durationUnit" ? (
+              <Select
+                value={(field.value as string) ?? "months"}
+                onValueChange={field.onChange}
+              >
+                <SelectTrigger>
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="days">{t("fields.unitDays")}</SelectItem>
+                  <SelectItem value="weeks">{t("fields.unitWeeks")}</SelectItem>
+                  <SelectItem value="months">{t("fields.unitMonths")}</SelectItem>
+                </SelectContent>
+              </Select>
+            ) : config.type === "durationAnchor" ? (
+              <Select
+                value={(field.value as string) ?? "none"}
+                onValueChange={(v) => field.onChange(v === "none" ? undefined : v)}
+              >
+                <SelectTrigger>
+                  <SelectValue />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="none">{t("fields.anchorNone")}</SelectItem>
+                  <SelectItem value="end_of_month">{t("fields.anchorEndOfMonth")}</SelectItem>
+                  <SelectItem value="end_of_quarter">{t("fields.anchorEndOfQuarter")}</SelectItem>
+                  <SelectItem value="end_of_year">{t("fields.anchorEndOfYear")}</SelectItem>
                 </SelectContent>
               </Select>
             ) : config.type === "textarea" ? (
@@ -26,7 +26,21 @@ const tableColumns = contractFields
   .filter((f) => f.showInTable)
   .sort((a, b) => a.tableOrder - b.tableOrder)
 
-const detailFields = contractFields.filter((f) => !f.showInTable)
+const detailFields = contractFields.filter(
+  (f) => !f.showInTable && f.type !== "durationUnit" && f.type !== "durationAnchor",
+)
+
+const durationUnitLabel: Record<string, string> = {
+  days: "fields.unitDays",
+  weeks: "fields.unitWeeks",
+  months: "common.months",
+}
+
+const durationAnchorLabel: Record<string, string> = {
+  end_of_month: "fields.anchorEndOfMonth",
+  end_of_quarter: "fields.anchorEndOfQuarter",
+  end_of_year: "fields.anchorEndOfYear",
+}
 
 interface ContractsTableProps {
   contracts: Contract[]
@@ -35,16 +49,29 @@ interface ContractsTableProps {
   getRowClassName?: (contract: Contract) => string | undefined
 }
 
+const billingIntervalSuffix: Record<string, string> = {
+  weekly: "common.perWeek",
+  monthly: "common.perMonth",
+  quarterly: "common.perQuarter",
+  "half-yearly": "common.perHalfYear",
+  yearly: "common.perYear",
+  biennial: "common.perTwoYears",
+  "one-time": "common.oneTime",
+}
+
 function formatCellValue(contract: Contract, key: string, currency: string, t: (key: string) => string): string {
   const value = contract[key as keyof Contract]
   if (value === undefined || value === null || value === "") return "-"
   if (key === "price") {
-    const interval = contract.billingInterval === "yearly" ? t("common.perYear") : t("common.perMonth")
+    const interval = t(billingIntervalSuffix[contract.billingInterval] ?? "common.perMonth")
     return `${Number(value).toFixed(2)} ${currency} ${interval}`
   }
   if (key === "startDate" || key === "endDate") return format(new Date(value as string), "yyyy-MM-dd")
-  if (key === "minimumDurationMonths" || key === "extensionDurationMonths" || key === "noticePeriodMonths") {
-    return `${value} ${t("common.months")}`
+  if (key === "minimumDuration" || key === "extensionDuration" || key === "noticePeriod") {
+    const unit = contract[`${key}Unit` as keyof Contract] as string | undefined
+    const anchor = contract[`${key}Anchor` as keyof Contract] as string | undefined
+    const text = `${value} ${t(durationUnitLabel[unit ?? "months"] ?? "common.months")}`
+    return anchor ? `${text} → ${t(durationAnchorLabel[anchor])}` : text
   }
   return String(value)
 }
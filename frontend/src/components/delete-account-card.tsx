@@ -0,0 +1,100 @@
+import { useState } from "react"
+import { useTranslation } from "react-i18next"
+import { toast } from "sonner"
+import { useAuth } from "@/hooks/use-auth"
+import { deleteAccount, exportWorkspace } from "@/lib/settings-repository"
+import { DeleteConfirmDialog } from "@/components/delete-confirm-dialog"
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
+import { Input } from "@/components/ui/input"
+import { Label } from "@/components/ui/label"
+import { Button } from "@/components/ui/button"
+
+export function DeleteAccountCard() {
+  const { t } = useTranslation()
+  const { logout } = useAuth()
+  // Accounts that only use single sign-on have no password and confirm
+  // with their email address instead.
+  const [withEmail, setWithEmail] = useState(false)
+  const [confirmation, setConfirmation] = useState("")
+  const [confirmOpen, setConfirmOpen] = useState(false)
+  const [busy, setBusy] = useState(false)
+
+  async function handleExport() {
+    try {
+      const data = await exportWorkspace()
+      const url = URL.createObjectURL(new Blob([JSON.stringify(data, null, 2)], { type: "application/json" }))
+      const a = document.createElement("a")
+      a.href = url
+      a.download = `contracts-export-${new Date().toISOString().slice(0, 10)}.json`
+      a.click()
+      URL.revokeObjectURL(url)
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("settings.exportFailed"))
+    }
+  }
+
+  async function handleDelete() {
+    setBusy(true)
+    try {
+      await deleteAccount(withEmail ? { email: confirmation } : { password: confirmation })
+      logout()
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("settings.deleteAccountFailed"))
+      setBusy(false)
+    }
+  }
+
+  return (
+    <Card className="border-destructive/50">
+      <CardHeader>
+        <CardTitle>{t("settings.deleteAccount")}</CardTitle>
+        <CardDescription>{t("settings.deleteAccountDescription")}</CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <Button type="button" variant="outline" onClick={handleExport}>
+          {t("settings.exportBeforeDelete")}
+        </Button>
+        <form
+          className="max-w-sm space-y-2"
+          onSubmit={(e) => {
+            e.preventDefault()
+            setConfirmOpen(true)
+          }}
+        >
+          <Label htmlFor="delete-account-confirmation">
+            {withEmail ? t("auth.email") : t("settings.currentPassword")}
+          </Label>
+          <Input
+            id="delete-account-confirmation"
+            type={withEmail ? "email" : "password"}
+            value={confirmation}
+            onChange={(e) => setConfirmation(e.target.value)}
+            required
+            autoComplete={withEmail ? "email" : "current-password"}
+          />
+          <button
+            type="button"
+            className="text-xs text-muted-foreground hover:underline"
+            onClick={() => {
+              setWithEmail(!withEmail)
+              setConfirmation("")
+            }}
+          >
+            {withEmail ? t("settings.deleteWithPassword") : t("settings.deleteWithEmail")}
+          </button>
+          <div>
+            <Button type="submit" variant="destructive" disabled={busy || !confirmation}>
+              {t("settings.deleteAccount")}
+            </Button>
+          </div>
+        </form>
+      </CardContent>
+      <DeleteConfirmDialog
+        open={confirmOpen}
+        onOpenChange={setConfirmOpen}
+        description={t("settings.deleteAccountConfirm")}
+        onConfirm={handleDelete}
+      />
+    </Card>
+  )
+}
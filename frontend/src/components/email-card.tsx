@@ -0,0 +1,95 @@
+import { useState } from "react"
+import { useTranslation } from "react-i18next"
+import { toast } from "sonner"
+import { useAuth } from "@/hooks/use-auth"
+import { changeEmail, resendEmailVerification } from "@/lib/settings-repository"
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
+import { Badge } from "@/components/ui/badge"
+import { Input } from "@/components/ui/input"
+import { Label } from "@/components/ui/label"
+import { Button } from "@/components/ui/button"
+
+export function EmailCard() {
+  const { t } = useTranslation()
+  const { user } = useAuth()
+  const [email, setEmail] = useState("")
+  const [password, setPassword] = useState("")
+  const [busy, setBusy] = useState(false)
+
+  async function handleChange(e: React.FormEvent) {
+    e.preventDefault()
+    setBusy(true)
+    try {
+      await changeEmail(email, password)
+      toast.success(t("settings.emailChangeSent", { email }))
+      setEmail("")
+      setPassword("")
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("settings.emailChangeFailed"))
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  async function handleResend() {
+    setBusy(true)
+    try {
+      await resendEmailVerification()
+      toast.success(t("settings.emailVerificationSent"))
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("settings.emailChangeFailed"))
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle>{t("settings.email")}</CardTitle>
+        <CardDescription>{t("settings.emailDescription")}</CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        <div className="flex flex-wrap items-center gap-2 text-sm">
+          <span className="font-medium">{user?.email}</span>
+          {user?.emailVerified ? (
+            <Badge variant="secondary">{t("settings.emailVerified")}</Badge>
+          ) : (
+            <>
+              <Badge variant="outline">{t("settings.emailUnverified")}</Badge>
+              <Button type="button" variant="link" size="sm" onClick={handleResend} disabled={busy}>
+                {t("settings.emailResend")}
+              </Button>
+            </>
+          )}
+        </div>
+        <form onSubmit={handleChange} className="max-w-sm space-y-4">
+          <div className="space-y-2">
+            <Label htmlFor="new-email">{t("settings.newEmail")}</Label>
+            <Input
+              id="new-email"
+              type="email"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              required
+              autoComplete="email"
+            />
+          </div>
+          <div className="space-y-2">
+            <Label htmlFor="email-password">{t("settings.currentPassword")}</Label>
+            <Input
+              id="email-password"
+              type="password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              autoComplete="current-password"
+            />
+          </div>
+          <Button type="submit" disabled={busy || !email}>
+            {t("settings.emailChange")}
+          </Button>
+        </form>
+      </CardContent>
+    </Card>
+  )
+}
@@ -173,6 +173,11 @@ export function ImportDialog({ open, onOpenChange }: ImportDialogProps) {
             <p className="text-sm">
               {t("import.createdCount", { count: result.created })}
             </p>
+            {(result.updated > 0 || result.skipped > 0) && (
+              <p className="text-sm">
+                {t("import.updatedSkipped", { updated: result.updated, skipped: result.skipped })}
+              </p>
+            )}
             {result.errors.length > 0 && (
               <div className="space-y-1">
                 <p className="text-sm font-medium text-destructive">{t("import.errorsTitle")}</p>
@@ -3,7 +3,9 @@ import { Link, useMatchRoute } from "@tanstack/react-router"
 import { useTranslation } from "react-i18next"
 import { useCategories } from "@/hooks/use-categories"
 import { useVehicles } from "@/hooks/use-vehicles"
+import { useAuth } from "@/hooks/use-auth"
 import { cn } from "@/lib/utils"
+import { WorkspaceSwitcher } from "@/components/workspace-switcher"
 import { Collapsible, CollapsibleContent, CollapsibleTrigger } from "@/components/ui/collapsible"
 import { ChevronRight, Home } from "lucide-react"
 
@@ -61,11 +63,14 @@ export function Sidebar() {
   const { data: contractCategories = [] } = useCategories("contracts")
   const { data: purchaseCategories = [] } = useCategories("purchases")
   const { data: vehicles = [] } = useVehicles()
+  const { user } = useAuth()
   const matchRoute = useMatchRoute()
 
   return (
     <aside className="w-64 shrink-0 border-r bg-background">
       <nav className="flex flex-col gap-1 p-4">
+        <WorkspaceSwitcher />
+
         <Link
           to="/"
           className={cn(
@@ -167,6 +172,17 @@ export function Sidebar() {
           >
             {t("nav.settings")}
           </Link>
+          {user?.admin && (
+            <Link
+              to="/admin"
+              className={cn(
+                "rounded-md px-3 py-2 text-sm font-medium transition-colors hover:bg-accent",
+                matchRoute({ to: "/admin" }) && "bg-accent",
+              )}
+            >
+              {t("nav.admin")}
+            </Link>
+          )}
         </SidebarSection>
       </nav>
     </aside>
@@ -0,0 +1,125 @@
+import { useState } from "react"
+import { useTranslation } from "react-i18next"
+import { toast } from "sonner"
+import { useQuery, useQueryClient } from "@tanstack/react-query"
+import {
+  beginTwoFactor,
+  confirmTwoFactor,
+  disableTwoFactor,
+  getTwoFactor,
+} from "@/lib/settings-repository"
+import type { TwoFactorEnrolment } from "@/types/settings"
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
+import { Input } from "@/components/ui/input"
+import { Label } from "@/components/ui/label"
+import { Button } from "@/components/ui/button"
+
+const TWO_FACTOR_KEY = ["settings", "2fa"] as const
+
+export function TwoFactorCard() {
+  const { t } = useTranslation()
+  const qc = useQueryClient()
+  const { data: status } = useQuery({ queryKey: TWO_FACTOR_KEY, queryFn: getTwoFactor })
+
+  const [enrolment, setEnrolment] = useState<TwoFactorEnrolment | null>(null)
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null)
+  const [code, setCode] = useState("")
+  const [busy, setBusy] = useState(false)
+
+  async function run(action: () => Promise<void>) {
+    setBusy(true)
+    try {
+      await action()
+      setCode("")
+      await qc.invalidateQueries({ queryKey: TWO_FACTOR_KEY })
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("settings.twoFactorFailed"))
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  const handleBegin = () => run(async () => {
+    setRecoveryCodes(null)
+    setEnrolment(await beginTwoFactor())
+  })
+
+  const handleConfirm = (e: React.FormEvent) => {
+    e.preventDefault()
+    run(async () => {
+      const res = await confirmTwoFactor(code)
+      setEnrolment(null)
+      setRecoveryCodes(res.recoveryCodes)
+      toast.success(t("settings.twoFactorEnabled"))
+    })
+  }
+
+  const handleDisable = (e: React.FormEvent) => {
+    e.preventDefault()
+    run(async () => {
+      await disableTwoFactor(code)
+      setRecoveryCodes(null)
+      toast.success(t("settings.twoFactorDisabled"))
+    })
+  }
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle>{t("settings.twoFactor")}</CardTitle>
+        <CardDescription>{t("settings.twoFactorDescription")}</CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-4">
+        {recoveryCodes && (
+          <div className="space-y-2">
+            <p className="text-sm font-medium">{t("settings.recoveryCodesTitle")}</p>
+            <p className="text-sm text-muted-foreground">{t("settings.recoveryCodesHint")}</p>
+            <ul className="grid max-w-sm grid-cols-2 gap-1 font-mono text-sm">
+              {recoveryCodes.map((c) => <li key={c}>{c}</li>)}
+            </ul>
+          </div>
+        )}
+
+        {status?.enabled ? (
+          <form onSubmit={handleDisable} className="space-y-4 max-w-sm">
+            <p className="text-sm">
+              {t("settings.twoFactorOn", { count: status.recoveryCodesRemaining })}
+            </p>
+            <div className="space-y-2">
+              <Label htmlFor="disableCode">{t("auth.twoFactorCode")}</Label>
+              <Input id="disableCode" value={code} onChange={(e) => setCode(e.target.value)} required />
+            </div>
+            <Button type="submit" variant="destructive" disabled={busy}>
+              {t("settings.twoFactorDisable")}
+            </Button>
+          </form>
+        ) : enrolment ? (
+          <form onSubmit={handleConfirm} className="space-y-4 max-w-sm">
+            <p className="text-sm text-muted-foreground">{t("settings.twoFactorScan")}</p>
+            <a href={enrolment.uri} className="block break-all font-mono text-sm underline">
+              {enrolment.secret}
+            </a>
+            <div className="space-y-2">
+              <Label htmlFor="confirmCode">{t("auth.twoFactorCode")}</Label>
+              <Input
+                id="confirmCode"
+                value={code}
+                onChange={(e) => setCode(e.target.value)}
+                required
+                autoComplete="one-time-code"
+                inputMode="numeric"
+              />
+            </div>
+            <Button type="submit" disabled={busy}>
+              {t("settings.twoFactorConfirm")}
+            </Button>
+          </form>
+        ) : (
+          <Button onClick={handleBegin} disabled={busy}>
+            {t("settings.twoFactorEnable")}
+          </Button>
+        )}
+      </CardContent>
+    </Card>
+  )
+}
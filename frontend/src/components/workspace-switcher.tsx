@@ -0,0 +1,32 @@
+import { useNavigate } from "@tanstack/react-router"
+import { useTranslation } from "react-i18next"
+import { useActiveWorkspace } from "@/hooks/use-workspaces"
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select"
+
+export function WorkspaceSwitcher() {
+  const { t } = useTranslation()
+  const navigate = useNavigate()
+  const { workspaces, active, switchTo } = useActiveWorkspace()
+
+  if (workspaces.length < 2 || !active) return null
+
+  const handleChange = (id: string) => {
+    switchTo(id)
+    navigate({ to: "/" })
+  }
+
+  return (
+    <Select value={active.id} onValueChange={handleChange}>
+      <SelectTrigger className="w-full" aria-label={t("workspace.switch")}>
+        <SelectValue />
+      </SelectTrigger>
+      <SelectContent>
+        {workspaces.map((w) => (
+          <SelectItem key={w.id} value={w.id}>
+            {w.personal ? t("workspace.personal") : w.name}
+          </SelectItem>
+        ))}
+      </SelectContent>
+    </Select>
+  )
+}
@@ -0,0 +1,215 @@
+import { useState } from "react"
+import { useTranslation } from "react-i18next"
+import { toast } from "sonner"
+import { useQuery, useQueryClient } from "@tanstack/react-query"
+import {
+  createInvitation,
+  createWorkspace,
+  deleteInvitation,
+  deleteWorkspace,
+  listInvitations,
+  listMembers,
+  removeMember,
+  updateMember,
+} from "@/lib/workspace-repository"
+import { WORKSPACES_KEY, useActiveWorkspace } from "@/hooks/use-workspaces"
+import { useAuth } from "@/hooks/use-auth"
+import type { WorkspaceRole } from "@/types/workspace"
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
+import { Input } from "@/components/ui/input"
+import { Label } from "@/components/ui/label"
+import { Button } from "@/components/ui/button"
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select"
+
+const ROLE_LABELS: Record<WorkspaceRole, string> = {
+  owner: "workspace.roleOwner",
+  editor: "workspace.roleEditor",
+  viewer: "workspace.roleViewer",
+}
+
+export function WorkspacesCard() {
+  const { t } = useTranslation()
+  const qc = useQueryClient()
+  const { user } = useAuth()
+  const { active, switchTo } = useActiveWorkspace()
+  const isOwner = active?.role === "owner"
+
+  const { data: members } = useQuery({
+    queryKey: [...WORKSPACES_KEY, active?.id, "members"],
+    queryFn: () => listMembers(active!.id),
+    enabled: !!active,
+  })
+  const { data: invitations } = useQuery({
+    queryKey: [...WORKSPACES_KEY, active?.id, "invitations"],
+    queryFn: () => listInvitations(active!.id),
+    enabled: isOwner,
+  })
+
+  const [name, setName] = useState("")
+  const [email, setEmail] = useState("")
+  const [role, setRole] = useState<WorkspaceRole>("editor")
+  const [inviteLink, setInviteLink] = useState<string | null>(null)
+  const [busy, setBusy] = useState(false)
+
+  async function run(action: () => Promise<void>) {
+    setBusy(true)
+    try {
+      await action()
+      await qc.invalidateQueries({ queryKey: WORKSPACES_KEY })
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("workspace.failed"))
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  const handleCreate = (e: React.FormEvent) => {
+    e.preventDefault()
+    run(async () => {
+      const ws = await createWorkspace(name)
+      setName("")
+      await qc.invalidateQueries({ queryKey: WORKSPACES_KEY })
+      switchTo(ws.id)
+    })
+  }
+
+  const handleInvite = (e: React.FormEvent) => {
+    e.preventDefault()
+    if (!active) return
+    run(async () => {
+      const inv = await createInvitation(active.id, email, role)
+      setInviteLink(`${window.location.origin}/invitations/accept?token=${encodeURIComponent(inv.token)}`)
+      setEmail("")
+      toast.success(t("workspace.invited"))
+    })
+  }
+
+  const handleRemove = (userId: string) => run(async () => {
+    if (!active) return
+    await removeMember(active.id, userId)
+    if (userId === user?.id) switchTo("")
+  })
+
+  const handleDelete = () => run(async () => {
+    if (!active || !window.confirm(t("workspace.deleteConfirm", { name: active.name }))) return
+    await deleteWorkspace(active.id)
+    switchTo("")
+  })
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle>{t("workspace.title")}</CardTitle>
+        <CardDescription>{t("workspace.description")}</CardDescription>
+      </CardHeader>
+      <CardContent className="space-y-6">
+        {active && (
+          <div className="space-y-2">
+            <p className="text-sm font-medium">
+              {active.personal ? t("workspace.personal") : active.name}
+              <span className="text-muted-foreground"> · {t(ROLE_LABELS[active.role])}</span>
+            </p>
+            {members && (
+              <ul className="divide-y">
+                {members.map((m) => (
+                  <li key={m.userId} className="flex items-center justify-between gap-4 py-2 text-sm">
+                    <span>{m.email}</span>
+                    <div className="flex items-center gap-2">
+                      {isOwner && m.role !== "owner" ? (
+                        <Select
+                          value={m.role}
+                          onValueChange={(v) => run(() => updateMember(active.id, m.userId, v as WorkspaceRole))}
+                        >
+                          <SelectTrigger className="w-32">
+                            <SelectValue />
+                          </SelectTrigger>
+                          <SelectContent>
+                            <SelectItem value="editor">{t("workspace.roleEditor")}</SelectItem>
+                            <SelectItem value="viewer">{t("workspace.roleViewer")}</SelectItem>
+                          </SelectContent>
+                        </Select>
+                      ) : (
+                        <span className="text-muted-foreground">{t(ROLE_LABELS[m.role])}</span>
+                      )}
+                      {isOwner && m.role !== "owner" && (
+                        <Button variant="outline" size="sm" onClick={() => handleRemove(m.userId)} disabled={busy}>
+                          {t("workspace.remove")}
+                        </Button>
+                      )}
+                    </div>
+                  </li>
+                ))}
+              </ul>
+            )}
+            {!isOwner && user && (
+              <Button variant="outline" size="sm" onClick={() => handleRemove(user.id)} disabled={busy}>
+                {t("workspace.leave")}
+              </Button>
+            )}
+            {isOwner && !active.personal && (
+              <Button variant="destructive" size="sm" onClick={handleDelete} disabled={busy}>
+                {t("workspace.delete")}
+              </Button>
+            )}
+          </div>
+        )}
+
+        {isOwner && active && (
+          <div className="space-y-4">
+            {invitations && invitations.length > 0 && (
+              <ul className="divide-y">
+                {invitations.map((inv) => (
+                  <li key={inv.id} className="flex items-center justify-between gap-4 py-2 text-sm">
+                    <span>
+                      {inv.email} <span className="text-muted-foreground">· {t(ROLE_LABELS[inv.role])}</span>
+                    </span>
+                    <Button variant="outline" size="sm" onClick={() => run(() => deleteInvitation(active.id, inv.id))} disabled={busy}>
+                      {t("workspace.withdraw")}
+                    </Button>
+                  </li>
+                ))}
+              </ul>
+            )}
+            {inviteLink && (
+              <div className="space-y-2">
+                <p className="text-sm text-muted-foreground">{t("workspace.inviteLink")}</p>
+                <p className="break-all font-mono text-sm">{inviteLink}</p>
+              </div>
+            )}
+            <form onSubmit={handleInvite} className="space-y-4 max-w-sm">
+              <div className="space-y-2">
+                <Label htmlFor="inviteEmail">{t("workspace.inviteEmail")}</Label>
+                <Input id="inviteEmail" type="email" value={email} onChange={(e) => setEmail(e.target.value)} required />
+              </div>
+              <div className="space-y-2">
+                <Label>{t("workspace.role")}</Label>
+                <Select value={role} onValueChange={(v) => setRole(v as WorkspaceRole)}>
+                  <SelectTrigger className="w-48">
+                    <SelectValue />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="editor">{t("workspace.roleEditor")}</SelectItem>
+                    <SelectItem value="viewer">{t("workspace.roleViewer")}</SelectItem>
+                  </SelectContent>
+                </Select>
+              </div>
+              <Button type="submit" disabled={busy}>
+                {t("workspace.invite")}
+              </Button>
+            </form>
+          </div>
+        )}
+
+        <form onSubmit={handleCreate} className="space-y-4 max-w-sm">
+          <div className="space-y-2">
+            <Label htmlFor="workspaceName">{t("workspace.newName")}</Label>
+            <Input id="workspaceName" value={name} onChange={(e) => setName(e.target.value)} required />
+          </div>
+          <Button type="submit" disabled={busy}>
+            {t("workspace.create")}
+          </Button>
+        </form>
+      </CardContent>
+    </Card>
+  )
+}
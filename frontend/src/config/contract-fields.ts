@@ -1,4 +1,4 @@
-export type FieldType = "text" | "number" | "date" | "url" | "textarea" | "billingInterval"
+export type FieldType = "text" | "number" | "date" | "url" | "textarea" | "billingInterval" | "durationUnit" | "durationAnchor"
 
 export interface FieldConfig {
   key: string
@@ -19,9 +19,15 @@ export const contractFields: FieldConfig[] = [
   { key: "billingInterval", type: "billingInterval", i18nKey: "fields.billingInterval", required: true, showInTable: false, tableOrder: -1 },
   { key: "startDate", type: "date", i18nKey: "fields.startDate", required: true, showInTable: true, tableOrder: 4 },
   { key: "endDate", type: "date", i18nKey: "fields.endDate", required: false, showInTable: false, tableOrder: -1 },
-  { key: "minimumDurationMonths", type: "number", i18nKey: "fields.minimumDuration", required: true, showInTable: false, tableOrder: -1 },
-  { key: "extensionDurationMonths", type: "number", i18nKey: "fields.extensionDuration", required: true, showInTable: false, tableOrder: -1 },
-  { key: "noticePeriodMonths", type: "number", i18nKey: "fields.noticePeriod", required: true, showInTable: false, tableOrder: -1 },
+  { key: "minimumDuration", type: "number", i18nKey: "fields.minimumDuration", required: true, showInTable: false, tableOrder: -1 },
+  { key: "minimumDurationUnit", type: "durationUnit", i18nKey: "fields.minimumDurationUnit", required: false, showInTable: false, tableOrder: -1 },
+  { key: "minimumDurationAnchor", type: "durationAnchor", i18nKey: "fields.minimumDurationAnchor", required: false, showInTable: false, tableOrder: -1 },
+  { key: "extensionDuration", type: "number", i18nKey: "fields.extensionDuration", required: true, showInTable: false, tableOrder: -1 },
+  { key: "extensionDurationUnit", type: "durationUnit", i18nKey: "fields.extensionDurationUnit", required: false, showInTable: false, tableOrder: -1 },
+  { key: "extensionDurationAnchor", type: "durationAnchor", i18nKey: "fields.extensionDurationAnchor", required: false, showInTable: false, tableOrder: -1 },
+  { key: "noticePeriod", type: "number", i18nKey: "fields.noticePeriod", required: true, showInTable: false, tableOrder: -1 },
+  { key: "noticePeriodUnit", type: "durationUnit", i18nKey: "fields.noticePeriodUnit", required: false, showInTable: false, tableOrder: -1 },
+  { key: "noticePeriodAnchor", type: "durationAnchor", i18nKey: "fields.noticePeriodAnchor", required: false, showInTable: false, tableOrder: -1 },
   { key: "customerPortalUrl", type: "url", i18nKey: "fields.customerPortalUrl", required: false, showInTable: false, tableOrder: -1 },
   { key: "paperlessUrl", type: "url", i18nKey: "fields.paperlessUrl", required: false, showInTable: false, tableOrder: -1 },
   { key: "comments", type: "textarea", i18nKey: "fields.comments", required: false, showInTable: false, tableOrder: -1 },
@@ -1,8 +1,14 @@
 import { createContext, useContext, useState, useCallback } from "react"
 import type { ReactNode } from "react"
-import { getToken, setToken, clearToken } from "@/lib/api"
-import { login as apiLogin, register as apiRegister } from "@/lib/auth-repository"
-import type { AuthUser, LoginData, RegisterData } from "@/types/auth"
+import { getToken, setToken, setRefreshToken, clearToken } from "@/lib/api"
+import {
+  completeSingleSignOn as apiCompleteSingleSignOn,
+  login as apiLogin,
+  loginTwoFactor as apiLoginTwoFactor,
+  logout as apiLogout,
+  register as apiRegister,
+} from "@/lib/auth-repository"
+import type { AuthResponse, AuthUser, LoginData, RegisterData } from "@/types/auth"
 
 const USER_KEY = "user"
 
@@ -18,8 +24,13 @@ function loadUser(): AuthUser | null {
 interface AuthContextValue {
   user: AuthUser | null
   token: string | null
-  login: (data: LoginData) => Promise<void>
+  // login resolves to a challenge token when a second factor is required.
+  login: (data: LoginData) => Promise<string | null>
+  completeTwoFactor: (challengeToken: string, code: string) => Promise<void>
+  completeSingleSignOn: (refreshToken: string) => Promise<void>
   register: (data: RegisterData) => Promise<void>
+  // updateUser replaces the stored user, e.g. after an email change.
+  updateUser: (user: AuthUser) => void
   logout: () => void
   isAuthenticated: boolean
 }
@@ -30,23 +41,42 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   const [user, setUser] = useState<AuthUser | null>(loadUser)
   const [token, setTokenState] = useState<string | null>(getToken)
 
-  const login = useCallback(async (data: LoginData) => {
-    const res = await apiLogin(data)
+  const signIn = useCallback((res: AuthResponse) => {
     setToken(res.token)
+    setRefreshToken(res.refreshToken)
     localStorage.setItem(USER_KEY, JSON.stringify(res.user))
     setTokenState(res.token)
     setUser(res.user)
   }, [])
 
+  const login = useCallback(async (data: LoginData) => {
+    const res = await apiLogin(data)
+    if ("twoFactorRequired" in res) {
+      return res.challengeToken
+    }
+    signIn(res)
+    return null
+  }, [signIn])
+
+  const completeTwoFactor = useCallback(async (challengeToken: string, code: string) => {
+    signIn(await apiLoginTwoFactor(challengeToken, code))
+  }, [signIn])
+
+  const completeSingleSignOn = useCallback(async (refreshToken: string) => {
+    signIn(await apiCompleteSingleSignOn(refreshToken))
+  }, [signIn])
+
   const register = useCallback(async (data: RegisterData) => {
-    const res = await apiRegister(data)
-    setToken(res.token)
-    localStorage.setItem(USER_KEY, JSON.stringify(res.user))
-    setTokenState(res.token)
-    setUser(res.user)
+    signIn(await apiRegister(data))
+  }, [signIn])
+
+  const updateUser = useCallback((next: AuthUser) => {
+    localStorage.setItem(USER_KEY, JSON.stringify(next))
+    setUser(next)
   }, [])
 
   const logout = useCallback(() => {
+    void apiLogout()
     clearToken()
     localStorage.removeItem(USER_KEY)
     setTokenState(null)
@@ -59,7 +89,10 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       user,
       token,
       login,
+      completeTwoFactor,
+      completeSingleSignOn,
       register,
+      updateUser,
       logout,
       isAuthenticated: !!token,
     }}>
@@ -0,0 +1,34 @@
+import { useCallback } from "react"
+import { useQuery, useQueryClient } from "@tanstack/react-query"
+import { getWorkspaceId, setWorkspaceId } from "@/lib/api"
+import { listWorkspaces } from "@/lib/workspace-repository"
+
+export const WORKSPACES_KEY = ["workspaces"] as const
+
+export function useWorkspaces() {
+  return useQuery({
+    queryKey: WORKSPACES_KEY,
+    queryFn: listWorkspaces,
+  })
+}
+
+// useActiveWorkspace returns the workspace data requests go to, falling back
+// to the personal workspace when the stored one is gone.
+export function useActiveWorkspace() {
+  const qc = useQueryClient()
+  const { data: workspaces = [] } = useWorkspaces()
+  const storedId = getWorkspaceId()
+  const active = workspaces.find((w) => w.id === storedId) ?? workspaces.find((w) => w.personal)
+
+  // switchTo activates a workspace; "" goes back to the personal one.
+  const switchTo = useCallback(
+    (id: string) => {
+      const personal = workspaces.find((w) => w.personal)
+      setWorkspaceId(id && id !== personal?.id ? id : null)
+      qc.resetQueries({ predicate: (q) => q.queryKey[0] !== WORKSPACES_KEY[0] })
+    },
+    [qc, workspaces],
+  )
+
+  return { workspaces, active, switchTo }
+}
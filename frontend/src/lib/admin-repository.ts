@@ -0,0 +1,18 @@
+import type { AuthUser } from "@/types/auth"
+import { del, get, post, put } from "./api"
+
+export async function listUsers(): Promise<AuthUser[]> {
+  return get<AuthUser[]>("/admin/users")
+}
+
+export async function updateUser(id: string, data: { admin: boolean; disabled: boolean }): Promise<AuthUser> {
+  return put<AuthUser>(`/admin/users/${id}`, data)
+}
+
+export async function deleteUser(id: string): Promise<void> {
+  return del(`/admin/users/${id}`)
+}
+
+export async function forcePasswordReset(id: string): Promise<{ token: string; emailed: boolean }> {
+  return post<{ token: string; emailed: boolean }>(`/admin/users/${id}/reset-password`, {})
+}
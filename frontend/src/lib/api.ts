@@ -1,5 +1,7 @@
 const BASE = "/api/v1"
 const TOKEN_KEY = "token"
+const REFRESH_TOKEN_KEY = "refreshToken"
+const WORKSPACE_KEY = "workspace"
 
 export function getToken(): string | null {
   return localStorage.getItem(TOKEN_KEY)
@@ -9,8 +11,58 @@ export function setToken(token: string): void {
   localStorage.setItem(TOKEN_KEY, token)
 }
 
+export function getRefreshToken(): string | null {
+  return localStorage.getItem(REFRESH_TOKEN_KEY)
+}
+
+export function setRefreshToken(token: string): void {
+  localStorage.setItem(REFRESH_TOKEN_KEY, token)
+}
+
 export function clearToken(): void {
   localStorage.removeItem(TOKEN_KEY)
+  localStorage.removeItem(REFRESH_TOKEN_KEY)
+  localStorage.removeItem(WORKSPACE_KEY)
+}
+
+// getWorkspaceId returns the active workspace. Without one, the server uses
+// the user's personal workspace.
+export function getWorkspaceId(): string | null {
+  return localStorage.getItem(WORKSPACE_KEY)
+}
+
+export function setWorkspaceId(id: string | null): void {
+  if (id) {
+    localStorage.setItem(WORKSPACE_KEY, id)
+  } else {
+    localStorage.removeItem(WORKSPACE_KEY)
+  }
+}
+
+let refreshing: Promise<boolean> | null = null
+
+// refreshAccessToken exchanges the refresh token for a new token pair.
+// Concurrent callers share one request, since each refresh token works once.
+function refreshAccessToken(): Promise<boolean> {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const refreshToken = getRefreshToken()
+      if (!refreshToken) return false
+      const res = await fetch(`${BASE}/auth/refresh`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ refreshToken }),
+      })
+      if (!res.ok) return false
+      const data = await res.json()
+      setToken(data.token)
+      setRefreshToken(data.refreshToken)
+      return true
+    })().finally(() => {
+      refreshing = null
+    })
+  }
+  return refreshing
 }
 
 export class ApiError extends Error {
@@ -23,7 +75,7 @@ export class ApiError extends Error {
   }
 }
 
-async function request<T>(method: string, path: string, body?: unknown): Promise<T> {
+async function request<T>(method: string, path: string, body?: unknown, retry = true): Promise<T> {
   const headers: Record<string, string> = {}
   if (body !== undefined) {
     headers["Content-Type"] = "application/json"
@@ -32,6 +84,10 @@ async function request<T>(method: string, path: string, body?: unknown): Promise
   if (token) {
     headers["Authorization"] = `Bearer ${token}`
   }
+  const workspaceId = getWorkspaceId()
+  if (workspaceId) {
+    headers["X-Workspace-ID"] = workspaceId
+  }
 
   const res = await fetch(`${BASE}${path}`, {
     method,
@@ -40,6 +96,9 @@ async function request<T>(method: string, path: string, body?: unknown): Promise
   })
 
   if (res.status === 401) {
+    if (retry && (await refreshAccessToken())) {
+      return request<T>(method, path, body, false)
+    }
     clearToken()
     window.location.href = "/login"
     throw new ApiError(401, "unauthorized")
@@ -66,6 +125,6 @@ export function put<T>(path: string, body: unknown): Promise<T> {
   return request<T>("PUT", path, body)
 }
 
-export function del(path: string): Promise<void> {
-  return request<void>("DELETE", path)
+export function del(path: string, body?: unknown): Promise<void> {
+  return request<void>("DELETE", path, body)
 }
@@ -1,8 +1,10 @@
-import type { AuthResponse, LoginData, RegisterData } from "@/types/auth"
+import type { AuthOptions, AuthResponse, AuthUser, LoginChallenge, LoginData, RegisterData } from "@/types/auth"
+import i18n from "@/i18n"
+import { getRefreshToken, getToken } from "./api"
 
 const BASE = "/api/v1"
 
-export async function login(data: LoginData): Promise<AuthResponse> {
+export async function login(data: LoginData): Promise<AuthResponse | LoginChallenge> {
   const res = await fetch(`${BASE}/auth/login`, {
     method: "POST",
     headers: { "Content-Type": "application/json" },
@@ -15,6 +17,44 @@ export async function login(data: LoginData): Promise<AuthResponse> {
   return res.json()
 }
 
+export async function loginTwoFactor(challengeToken: string, code: string): Promise<AuthResponse> {
+  const res = await fetch(`${BASE}/auth/login/2fa`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ challengeToken, code }),
+  })
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}))
+    throw new Error(body.error ?? "Login failed")
+  }
+  return res.json()
+}
+
+export async function getAuthOptions(): Promise<AuthOptions> {
+  const res = await fetch(`${BASE}/auth/registration`)
+  if (!res.ok) return { mode: "open", oidc: false }
+  return res.json()
+}
+
+// SINGLE_SIGN_ON_URL starts an OpenID Connect login. The server sends the
+// browser back to /login with a refresh token or an error code in the hash.
+export const SINGLE_SIGN_ON_URL = `${BASE}/auth/oidc/login`
+
+// completeSingleSignOn trades the refresh token from a single sign-on for a
+// token pair.
+export async function completeSingleSignOn(refreshToken: string): Promise<AuthResponse> {
+  const res = await fetch(`${BASE}/auth/refresh`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ refreshToken }),
+  })
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}))
+    throw new Error(body.error ?? "Login failed")
+  }
+  return res.json()
+}
+
 export async function register(data: RegisterData): Promise<AuthResponse> {
   const res = await fetch(`${BASE}/auth/register`, {
     method: "POST",
@@ -27,3 +67,57 @@ export async function register(data: RegisterData): Promise<AuthResponse> {
   }
   return res.json()
 }
+
+// logout revokes the current session. The session ID is the part of the
+// refresh token before the dot.
+export async function logout(): Promise<void> {
+  const token = getToken()
+  const sessionId = getRefreshToken()?.split(".")[0]
+  if (!token || !sessionId) return
+  await fetch(`${BASE}/sessions/${sessionId}`, {
+    method: "DELETE",
+    headers: { Authorization: `Bearer ${token}` },
+    keepalive: true,
+  }).catch(() => undefined)
+}
+
+// forgotPassword asks for a reset link. The server answers the same way
+// whether or not the address has an account.
+export async function forgotPassword(email: string): Promise<void> {
+  const res = await fetch(`${BASE}/auth/forgot-password`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json", "Accept-Language": i18n.language },
+    body: JSON.stringify({ email }),
+  })
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}))
+    throw new Error(body.error ?? "Request failed")
+  }
+}
+
+// verifyEmail confirms an address with the token from a verification email
+// and returns the updated user.
+export async function verifyEmail(token: string): Promise<AuthUser> {
+  const res = await fetch(`${BASE}/auth/verify-email`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ token }),
+  })
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}))
+    throw new Error(body.error ?? "Verification failed")
+  }
+  return res.json()
+}
+
+export async function resetPassword(token: string, password: string): Promise<void> {
+  const res = await fetch(`${BASE}/auth/reset-password`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ token, password }),
+  })
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}))
+    throw new Error(body.error ?? "Password reset failed")
+  }
+}
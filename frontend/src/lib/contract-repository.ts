@@ -1,9 +1,11 @@
 import type { Contract, ContractFormData } from "@/types/contract"
 import type { Summary } from "@/types/summary"
-import { del, get, getToken, post, put } from "./api"
+import { del, get, getToken, getWorkspaceId, post, put } from "./api"
 
 export interface ImportResult {
   created: number
+  updated: number
+  skipped: number
   errors: { row: number; error: string }[]
 }
 
@@ -47,6 +49,10 @@ export async function importContracts(file: File): Promise<ImportResult> {
   if (token) {
     headers["Authorization"] = `Bearer ${token}`
   }
+  const workspaceId = getWorkspaceId()
+  if (workspaceId) {
+    headers["X-Workspace-ID"] = workspaceId
+  }
   const res = await fetch("/api/v1/contracts/import", {
     method: "POST",
     headers,
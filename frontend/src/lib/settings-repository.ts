@@ -1,5 +1,5 @@
-import type { Settings } from "@/types/settings"
-import { get, put } from "./api"
+import type { ApiKey, ApiKeyInput, Settings, TwoFactorEnrolment, TwoFactorStatus } from "@/types/settings"
+import { del, get, post, put } from "./api"
 
 export async function getSettings(): Promise<Settings> {
   return get<Settings>("/settings")
@@ -12,3 +12,52 @@ export async function updateSettings(data: Settings): Promise<Settings> {
 export async function changePassword(currentPassword: string, newPassword: string): Promise<void> {
   return put<void>("/settings/password", { currentPassword, newPassword })
 }
+
+// changeEmail mails a confirmation link to a new address. The account keeps
+// its current address until the link is opened.
+export async function changeEmail(email: string, password: string): Promise<void> {
+  return put<void>("/settings/email", { email, password })
+}
+
+export async function resendEmailVerification(): Promise<void> {
+  return post<void>("/settings/email/verification", {})
+}
+
+// deleteAccount deletes the signed-in account. It is confirmed with the
+// password, or with the email address for accounts without one.
+export async function deleteAccount(confirmation: { password?: string; email?: string }): Promise<void> {
+  return del("/account", confirmation)
+}
+
+// exportWorkspace returns the archive of the active workspace.
+export async function exportWorkspace(): Promise<unknown> {
+  return get<unknown>("/export")
+}
+
+export async function getTwoFactor(): Promise<TwoFactorStatus> {
+  return get<TwoFactorStatus>("/settings/2fa")
+}
+
+export async function beginTwoFactor(): Promise<TwoFactorEnrolment> {
+  return post<TwoFactorEnrolment>("/settings/2fa", {})
+}
+
+export async function confirmTwoFactor(code: string): Promise<{ recoveryCodes: string[] }> {
+  return post<{ recoveryCodes: string[] }>("/settings/2fa/confirm", { code })
+}
+
+export async function disableTwoFactor(code: string): Promise<void> {
+  return del("/settings/2fa", { code })
+}
+
+export async function listApiKeys(): Promise<ApiKey[]> {
+  return get<ApiKey[]>("/settings/api-keys")
+}
+
+export async function createApiKey(data: ApiKeyInput): Promise<ApiKey & { key: string }> {
+  return post<ApiKey & { key: string }>("/settings/api-keys", data)
+}
+
+export async function deleteApiKey(id: string): Promise<void> {
+  return del(`/settings/api-keys/${id}`)
+}
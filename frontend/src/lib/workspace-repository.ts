@@ -0,0 +1,46 @@
+import type { Invitation, Workspace, WorkspaceMember, WorkspaceRole } from "@/types/workspace"
+import { del, get, post, put } from "./api"
+
+export async function listWorkspaces(): Promise<Workspace[]> {
+  return get<Workspace[]>("/workspaces")
+}
+
+export async function createWorkspace(name: string): Promise<Workspace> {
+  return post<Workspace>("/workspaces", { name })
+}
+
+export async function renameWorkspace(id: string, name: string): Promise<Workspace> {
+  return put<Workspace>(`/workspaces/${id}`, { name })
+}
+
+export async function deleteWorkspace(id: string): Promise<void> {
+  return del(`/workspaces/${id}`)
+}
+
+export async function listMembers(id: string): Promise<WorkspaceMember[]> {
+  return get<WorkspaceMember[]>(`/workspaces/${id}/members`)
+}
+
+export async function updateMember(id: string, userId: string, role: WorkspaceRole): Promise<void> {
+  return put<void>(`/workspaces/${id}/members/${userId}`, { role })
+}
+
+export async function removeMember(id: string, userId: string): Promise<void> {
+  return del(`/workspaces/${id}/members/${userId}`)
+}
+
+export async function listInvitations(id: string): Promise<Invitation[]> {
+  return get<Invitation[]>(`/workspaces/${id}/invitations`)
+}
+
+export async function createInvitation(id: string, email: string, role: WorkspaceRole): Promise<Invitation & { token: string }> {
+  return post<Invitation & { token: string }>(`/workspaces/${id}/invitations`, { email, role })
+}
+
+export async function deleteInvitation(id: string, invitationId: string): Promise<void> {
+  return del(`/workspaces/${id}/invitations/${invitationId}`)
+}
+
+export async function acceptInvitation(token: string): Promise<Workspace> {
+  return post<Workspace>("/invitations/accept", { token })
+}
@@ -17,13 +17,16 @@ function RootLayout() {
   const { isAuthenticated, logout } = useAuth()
   const matchRoute = useMatchRoute()
   const navigate = useNavigate()
-  const isLoginPage = matchRoute({ to: "/login" })
+  const isLoginPage = matchRoute({ to: "/login" }) || matchRoute({ to: "/reset-password" }) || matchRoute({ to: "/verify-email" })
+  const invitation = matchRoute({ to: "/invitations/accept" })
+    ? new URLSearchParams(window.location.search).get("token") ?? undefined
+    : undefined
 
   useEffect(() => {
     if (!isAuthenticated && !isLoginPage) {
-      navigate({ to: "/login" })
+      navigate({ to: "/login", search: { invitation } })
     }
-  }, [isAuthenticated, isLoginPage, navigate])
+  }, [isAuthenticated, isLoginPage, invitation, navigate])
 
   if (isLoginPage) {
     return <Outlet />
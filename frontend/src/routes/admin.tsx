@@ -0,0 +1,121 @@
+import { useState } from "react"
+import { createRoute } from "@tanstack/react-router"
+import { useTranslation } from "react-i18next"
+import { toast } from "sonner"
+import { format } from "date-fns"
+import { useQuery, useQueryClient } from "@tanstack/react-query"
+import { usePageTitle } from "@/hooks/use-page-title"
+import { useAuth } from "@/hooks/use-auth"
+import { rootRoute } from "./__root"
+import { deleteUser, forcePasswordReset, listUsers, updateUser } from "@/lib/admin-repository"
+import type { AuthUser } from "@/types/auth"
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
+import { Button } from "@/components/ui/button"
+
+const USERS_KEY = ["admin", "users"] as const
+
+export const adminRoute = createRoute({
+  getParentRoute: () => rootRoute,
+  path: "/admin",
+  component: AdminPage,
+})
+
+function AdminPage() {
+  const { t } = useTranslation()
+  const { user: me } = useAuth()
+  const qc = useQueryClient()
+  usePageTitle(t("admin.title"), t("app.title"))
+
+  const { data: users } = useQuery({ queryKey: USERS_KEY, queryFn: listUsers, enabled: !!me?.admin })
+  const [resetLink, setResetLink] = useState<string | null>(null)
+  const [busy, setBusy] = useState(false)
+
+  async function run(action: () => Promise<void>) {
+    setBusy(true)
+    try {
+      await action()
+      await qc.invalidateQueries({ queryKey: USERS_KEY })
+    } catch (err) {
+      toast.error(err instanceof Error ? err.message : t("admin.failed"))
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  const toggle = (u: AuthUser, field: "admin" | "disabled") =>
+    run(async () => {
+      await updateUser(u.id, { admin: u.admin, disabled: u.disabled, [field]: !u[field] })
+    })
+
+  const handleReset = (u: AuthUser) =>
+    run(async () => {
+      const res = await forcePasswordReset(u.id)
+      if (res.emailed) {
+        setResetLink(null)
+        toast.success(t("admin.resetEmailed", { email: u.email }))
+      } else {
+        setResetLink(`${window.location.origin}/reset-password?token=${encodeURIComponent(res.token)}`)
+      }
+    })
+
+  const handleDelete = (u: AuthUser) =>
+    run(async () => {
+      if (!window.confirm(t("admin.deleteConfirm", { email: u.email }))) return
+      await deleteUser(u.id)
+      toast.success(t("admin.deleted"))
+    })
+
+  if (!me?.admin) {
+    return <p className="text-sm text-muted-foreground">{t("admin.forbidden")}</p>
+  }
+
+  return (
+    <div className="space-y-6">
+      <h1 className="text-2xl font-bold">{t("admin.title")}</h1>
+      <Card>
+        <CardHeader>
+          <CardTitle>{t("admin.users")}</CardTitle>
+          <CardDescription>{t("admin.usersDescription")}</CardDescription>
+        </CardHeader>
+        <CardContent className="space-y-4">
+          {resetLink && (
+            <div className="space-y-2">
+              <p className="text-sm text-muted-foreground">{t("admin.resetLink")}</p>
+              <p className="break-all font-mono text-sm">{resetLink}</p>
+            </div>
+          )}
+          <ul className="divide-y">
+            {users?.map((u) => (
+              <li key={u.id} className="flex flex-wrap items-center justify-between gap-4 py-2">
+                <div className="text-sm">
+                  <p className="font-medium">{u.email}</p>
+                  <p className="text-muted-foreground">
+                    {t("admin.registered", { date: format(new Date(u.createdAt), "yyyy-MM-dd") })}
+                    {u.admin && ` · ${t("admin.admin")}`}
+                    {u.disabled && ` · ${t("admin.disabled")}`}
+                  </p>
+                </div>
+                {u.id !== me.id && (
+                  <div className="flex flex-wrap gap-2">
+                    <Button variant="outline" size="sm" onClick={() => toggle(u, "admin")} disabled={busy}>
+                      {u.admin ? t("admin.revokeAdmin") : t("admin.makeAdmin")}
+                    </Button>
+                    <Button variant="outline" size="sm" onClick={() => toggle(u, "disabled")} disabled={busy}>
+                      {u.disabled ? t("admin.enable") : t("admin.disable")}
+                    </Button>
+                    <Button variant="outline" size="sm" onClick={() => handleReset(u)} disabled={busy}>
+                      {t("admin.forceReset")}
+                    </Button>
+                    <Button variant="destructive" size="sm" onClick={() => handleDelete(u)} disabled={busy}>
+                      {t("admin.delete")}
+                    </Button>
+                  </div>
+                )}
+              </li>
+            ))}
+          </ul>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
@@ -0,0 +1,63 @@
+import { useState } from "react"
+import { createRoute, useNavigate } from "@tanstack/react-router"
+import { useTranslation } from "react-i18next"
+import { useQueryClient } from "@tanstack/react-query"
+import { usePageTitle } from "@/hooks/use-page-title"
+import { WORKSPACES_KEY, useActiveWorkspace } from "@/hooks/use-workspaces"
+import { rootRoute } from "./__root"
+import { acceptInvitation } from "@/lib/workspace-repository"
+import { Button } from "@/components/ui/button"
+import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card"
+
+export const acceptInvitationRoute = createRoute({
+  getParentRoute: () => rootRoute,
+  path: "/invitations/accept",
+  validateSearch: (search: Record<string, unknown>) => ({
+    token: typeof search.token === "string" ? search.token : "",
+  }),
+  component: AcceptInvitationPage,
+})
+
+function AcceptInvitationPage() {
+  const { t } = useTranslation()
+  const { token } = acceptInvitationRoute.useSearch()
+  const navigate = useNavigate()
+  const qc = useQueryClient()
+  const { switchTo } = useActiveWorkspace()
+  usePageTitle(t("workspace.acceptTitle"), t("app.title"))
+
+  const [error, setError] = useState("")
+  const [loading, setLoading] = useState(false)
+
+  async function handleAccept() {
+    setError("")
+    setLoading(true)
+    try {
+      const ws = await acceptInvitation(token)
+      await qc.invalidateQueries({ queryKey: WORKSPACES_KEY })
+      switchTo(ws.id)
+      navigate({ to: "/" })
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "An error occurred")
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="mx-auto max-w-sm">
+      <Card>
+        <CardHeader>
+          <CardTitle className="text-xl">{t("workspace.acceptTitle")}</CardTitle>
+        </CardHeader>
+        <CardContent className="space-y-4">
+          <p className="text-sm text-muted-foreground">{t("workspace.acceptDescription")}</p>
+          {error && <p className="text-sm text-destructive">{error}</p>}
+          <Button className="w-full" onClick={handleAccept} disabled={loading || !token}>
+            {t("workspace.accept")}
+          </Button>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
@@ -1,10 +1,12 @@
-import { useState } from "react"
+import { useEffect, useState } from "react"
 import { createRoute, useNavigate } from "@tanstack/react-router"
 import { useTranslation } from "react-i18next"
+import { useQuery } from "@tanstack/react-query"
 import { usePageTitle } from "@/hooks/use-page-title"
 import { Eye, EyeOff } from "lucide-react"
 import { rootRoute } from "./__root"
 import { useAuth } from "@/hooks/use-auth"
+import { forgotPassword, getAuthOptions, SINGLE_SIGN_ON_URL } from "@/lib/auth-repository"
 import { Button } from "@/components/ui/button"
 import { Input } from "@/components/ui/input"
 import { Label } from "@/components/ui/label"
@@ -13,10 +15,14 @@ import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card"
 export const loginRoute = createRoute({
   getParentRoute: () => rootRoute,
   path: "/login",
+  // invitation carries a workspace invitation token through sign-in.
+  validateSearch: (search: Record<string, unknown>): { invitation?: string } => ({
+    invitation: typeof search.invitation === "string" ? search.invitation : undefined,
+  }),
   component: LoginPage,
 })
 
-function PasswordInput({
+export function PasswordInput({
   id,
   value,
   onChange,
@@ -57,10 +63,21 @@ function PasswordInput({
 
 function LoginPage() {
   const { t } = useTranslation()
-  const { login, register } = useAuth()
+  const { login, completeTwoFactor, completeSingleSignOn, register } = useAuth()
   const navigate = useNavigate()
+  const { invitation } = loginRoute.useSearch()
+  const { data: authOptions = { mode: "open", oidc: false } } = useQuery({
+    queryKey: ["auth", "registration"],
+    queryFn: getAuthOptions,
+  })
+  const registrationMode = authOptions.mode
+  const canRegister = registrationMode === "open" || (registrationMode === "invite" && !!invitation)
 
   const [isRegister, setIsRegister] = useState(false)
+  const [isForgot, setIsForgot] = useState(false)
+  const [resetSent, setResetSent] = useState(false)
+  const [challengeToken, setChallengeToken] = useState<string | null>(null)
+  const [code, setCode] = useState("")
   usePageTitle(isRegister ? t("auth.register") : t("auth.login"), t("app.title"))
   const [email, setEmail] = useState("")
   const [password, setPassword] = useState("")
@@ -68,6 +85,31 @@ function LoginPage() {
   const [error, setError] = useState("")
   const [loading, setLoading] = useState(false)
 
+  // A single sign-on returns here with its result in the hash. Accounts
+  // with two-factor authentication get a challenge for the code instead.
+  useEffect(() => {
+    const result = new URLSearchParams(window.location.hash.slice(1))
+    const refreshToken = result.get("refreshToken")
+    const challenge = result.get("challengeToken")
+    const ssoError = result.get("error")
+    if (!refreshToken && !challenge && !ssoError) return
+    window.history.replaceState(null, "", window.location.pathname + window.location.search)
+
+    if (ssoError) {
+      setError(t(`auth.sso.errors.${ssoError}`, { defaultValue: t("auth.sso.errors.oidc_failed") }))
+      return
+    }
+    if (challenge) {
+      setChallengeToken(challenge)
+      return
+    }
+    setLoading(true)
+    completeSingleSignOn(refreshToken!)
+      .then(() => navigate({ to: "/" }))
+      .catch((err) => setError(err instanceof Error ? err.message : "An error occurred"))
+      .finally(() => setLoading(false))
+  }, [completeSingleSignOn, navigate, t])
+
   async function handleSubmit(e: React.FormEvent) {
     e.preventDefault()
     setError("")
@@ -80,10 +122,28 @@ function LoginPage() {
     setLoading(true)
 
     try {
-      if (isRegister) {
-        await register({ email, password })
+      if (isForgot) {
+        await forgotPassword(email)
+        setResetSent(true)
+        return
+      }
+      if (challengeToken) {
+        await completeTwoFactor(challengeToken, code)
+      } else if (isRegister) {
+        // Registering with an invitation joins its workspace right away.
+        await register({ email, password, invitationToken: invitation })
+        navigate({ to: "/" })
+        return
       } else {
-        await login({ email, password })
+        const challenge = await login({ email, password })
+        if (challenge) {
+          setChallengeToken(challenge)
+          return
+        }
+      }
+      if (invitation) {
+        navigate({ to: "/invitations/accept", search: { token: invitation } })
+        return
       }
       navigate({ to: "/" })
     } catch (err) {
@@ -98,7 +158,7 @@ function LoginPage() {
       <Card className="w-full max-w-sm">
         <CardHeader>
           <CardTitle className="text-center text-xl">
-            {isRegister ? t("auth.registerTitle") : t("auth.loginTitle")}
+            {isForgot ? t("auth.forgotTitle") : isRegister ? t("auth.registerTitle") : t("auth.loginTitle")}
           </CardTitle>
         </CardHeader>
         <CardContent>
@@ -112,19 +172,37 @@ function LoginPage() {
                 value={email}
                 onChange={(e) => setEmail(e.target.value)}
                 required
+                disabled={!!challengeToken}
                 autoComplete="email"
               />
             </div>
-            <div className="space-y-2">
-              <Label htmlFor="password">{t("auth.password")}</Label>
-              <PasswordInput
-                id="password"
-                placeholder={t("auth.passwordPlaceholder")}
-                value={password}
-                onChange={setPassword}
-                autoComplete={isRegister ? "new-password" : "current-password"}
-              />
-            </div>
+            {challengeToken && (
+              <div className="space-y-2">
+                <Label htmlFor="code">{t("auth.twoFactorCode")}</Label>
+                <Input
+                  id="code"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  required
+                  autoFocus
+                  autoComplete="one-time-code"
+                  inputMode="numeric"
+                />
+                <p className="text-xs text-muted-foreground">{t("auth.twoFactorHint")}</p>
+              </div>
+            )}
+            {!isForgot && !challengeToken && (
+              <div className="space-y-2">
+                <Label htmlFor="password">{t("auth.password")}</Label>
+                <PasswordInput
+                  id="password"
+                  placeholder={t("auth.passwordPlaceholder")}
+                  value={password}
+                  onChange={setPassword}
+                  autoComplete={isRegister ? "new-password" : "current-password"}
+                />
+              </div>
+            )}
 
             {isRegister && (
               <div className="space-y-2">
@@ -142,23 +220,57 @@ function LoginPage() {
             {error && (
               <p className="text-sm text-destructive">{error}</p>
             )}
+            {resetSent && (
+              <p className="text-sm text-muted-foreground">{t("auth.resetSent")}</p>
+            )}
 
-            <Button type="submit" className="w-full" disabled={loading}>
-              {isRegister ? t("auth.register") : t("auth.login")}
+            <Button type="submit" className="w-full" disabled={loading || resetSent}>
+              {isForgot ? t("auth.sendResetLink") : isRegister ? t("auth.register") : t("auth.login")}
             </Button>
           </form>
 
-          <button
-            type="button"
-            className="mt-4 w-full text-center text-sm text-muted-foreground hover:underline"
-            onClick={() => {
-              setIsRegister(!isRegister)
-              setError("")
-              setConfirmPassword("")
-            }}
-          >
-            {isRegister ? t("auth.switchToLogin") : t("auth.switchToRegister")}
-          </button>
+          {authOptions.oidc && !isForgot && !challengeToken && (
+            <Button
+              type="button"
+              variant="outline"
+              className="mt-4 w-full"
+              disabled={loading}
+              onClick={() => {
+                window.location.href = SINGLE_SIGN_ON_URL
+              }}
+            >
+              {t("auth.sso.button")}
+            </Button>
+          )}
+
+          {!isRegister && (
+            <button
+              type="button"
+              className="mt-4 w-full text-center text-sm text-muted-foreground hover:underline"
+              onClick={() => {
+                setIsForgot(!isForgot)
+                setResetSent(false)
+                setError("")
+              }}
+            >
+              {isForgot ? t("auth.backToLogin") : t("auth.forgotPassword")}
+            </button>
+          )}
+
+          {(canRegister || isRegister) && (
+            <button
+              type="button"
+              className="mt-4 w-full text-center text-sm text-muted-foreground hover:underline"
+              onClick={() => {
+                setIsRegister(!isRegister)
+                setIsForgot(false)
+                setError("")
+                setConfirmPassword("")
+              }}
+            >
+              {isRegister ? t("auth.switchToLogin") : t("auth.switchToRegister")}
+            </button>
+          )}
         </CardContent>
       </Card>
     </div>
@@ -0,0 +1,99 @@
+import { useState } from "react"
+import { createRoute, Link, useNavigate } from "@tanstack/react-router"
+import { useTranslation } from "react-i18next"
+import { usePageTitle } from "@/hooks/use-page-title"
+import { rootRoute } from "./__root"
+import { PasswordInput } from "./login"
+import { resetPassword } from "@/lib/auth-repository"
+import { Button } from "@/components/ui/button"
+import { Label } from "@/components/ui/label"
+import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card"
+
+export const resetPasswordRoute = createRoute({
+  getParentRoute: () => rootRoute,
+  path: "/reset-password",
+  validateSearch: (search: Record<string, unknown>) => ({
+    token: typeof search.token === "string" ? search.token : "",
+  }),
+  component: ResetPasswordPage,
+})
+
+function ResetPasswordPage() {
+  const { t } = useTranslation()
+  const { token } = resetPasswordRoute.useSearch()
+  const navigate = useNavigate()
+  usePageTitle(t("auth.resetTitle"), t("app.title"))
+
+  const [password, setPassword] = useState("")
+  const [confirmPassword, setConfirmPassword] = useState("")
+  const [error, setError] = useState("")
+  const [loading, setLoading] = useState(false)
+
+  async function handleSubmit(e: React.FormEvent) {
+    e.preventDefault()
+    setError("")
+    if (password !== confirmPassword) {
+      setError(t("auth.passwordMismatch"))
+      return
+    }
+
+    setLoading(true)
+    try {
+      await resetPassword(token, password)
+      navigate({ to: "/login" })
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "An error occurred")
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-background px-4">
+      <Card className="w-full max-w-sm">
+        <CardHeader>
+          <CardTitle className="text-center text-xl">{t("auth.resetTitle")}</CardTitle>
+        </CardHeader>
+        <CardContent>
+          <form onSubmit={handleSubmit} className="space-y-4">
+            <div className="space-y-2">
+              <Label htmlFor="password">{t("auth.password")}</Label>
+              <PasswordInput
+                id="password"
+                placeholder={t("auth.passwordPlaceholder")}
+                value={password}
+                onChange={setPassword}
+                autoComplete="new-password"
+              />
+            </div>
+            <div className="space-y-2">
+              <Label htmlFor="confirmPassword">{t("auth.confirmPassword")}</Label>
+              <PasswordInput
+                id="confirmPassword"
+                placeholder={t("auth.confirmPasswordPlaceholder")}
+                value={confirmPassword}
+                onChange={setConfirmPassword}
+                autoComplete="new-password"
+              />
+            </div>
+
+            {error && (
+              <p className="text-sm text-destructive">{error}</p>
+            )}
+
+            <Button type="submit" className="w-full" disabled={loading || !token}>
+              {t("auth.resetSubmit")}
+            </Button>
+          </form>
+
+          <Link
+            to="/login"
+            className="mt-4 block w-full text-center text-sm text-muted-foreground hover:underline"
+          >
+            {t("auth.backToLogin")}
+          </Link>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
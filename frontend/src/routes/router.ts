@@ -9,7 +9,11 @@ import { purchasesCategoryRoute } from "./purchases.categories.$categoryId"
 import { autoIndexRoute } from "./auto.index"
 import { autoVehicleDetailRoute } from "./auto.vehicles.$vehicleId"
 import { loginRoute } from "./login"
+import { resetPasswordRoute } from "./reset-password"
+import { verifyEmailRoute } from "./verify-email"
 import { settingsRoute } from "./settings"
+import { acceptInvitationRoute } from "./invitations.accept"
+import { adminRoute } from "./admin"
 
 const routeTree = rootRoute.addChildren([
   indexRoute,
@@ -21,7 +25,11 @@ const routeTree = rootRoute.addChildren([
   autoIndexRoute,
   autoVehicleDetailRoute,
   loginRoute,
+  resetPasswordRoute,
+  verifyEmailRoute,
   settingsRoute,
+  acceptInvitationRoute,
+  adminRoute,
 ])
 
 export const router = createRouter({ routeTree })
@@ -4,6 +4,11 @@ import { useTranslation } from "react-i18next"
 import { usePageTitle } from "@/hooks/use-page-title"
 import { toast } from "sonner"
 import { rootRoute } from "./__root"
+import { TwoFactorCard } from "@/components/two-factor-card"
+import { EmailCard } from "@/components/email-card"
+import { ApiKeysCard } from "@/components/api-keys-card"
+import { WorkspacesCard } from "@/components/workspaces-card"
+import { DeleteAccountCard } from "@/components/delete-account-card"
 import { useSettings, useUpdateSettings, useChangePassword } from "@/hooks/use-settings"
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/components/ui/card"
 import { Input } from "@/components/ui/input"
@@ -155,6 +160,16 @@ function SettingsPage() {
           </form>
         </CardContent>
       </Card>
+
+      <EmailCard />
+
+      <TwoFactorCard />
+
+      <ApiKeysCard />
+
+      <WorkspacesCard />
+
+      <DeleteAccountCard />
     </div>
   )
 }
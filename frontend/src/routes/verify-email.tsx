@@ -0,0 +1,69 @@
+import { useEffect, useRef, useState } from "react"
+import { createRoute, Link } from "@tanstack/react-router"
+import { useTranslation } from "react-i18next"
+import { usePageTitle } from "@/hooks/use-page-title"
+import { useAuth } from "@/hooks/use-auth"
+import { rootRoute } from "./__root"
+import { verifyEmail } from "@/lib/auth-repository"
+import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card"
+
+export const verifyEmailRoute = createRoute({
+  getParentRoute: () => rootRoute,
+  path: "/verify-email",
+  validateSearch: (search: Record<string, unknown>) => ({
+    token: typeof search.token === "string" ? search.token : "",
+  }),
+  component: VerifyEmailPage,
+})
+
+function VerifyEmailPage() {
+  const { t } = useTranslation()
+  const { token } = verifyEmailRoute.useSearch()
+  const { user, isAuthenticated, updateUser } = useAuth()
+  usePageTitle(t("auth.verifyEmailTitle"), t("app.title"))
+
+  const [status, setStatus] = useState<"pending" | "done" | "failed">(token ? "pending" : "failed")
+  const [error, setError] = useState("")
+  // Tokens work once, so the request must not repeat when the page renders
+  // again.
+  const started = useRef(false)
+
+  useEffect(() => {
+    if (!token || started.current) return
+    started.current = true
+    verifyEmail(token)
+      .then((verified) => {
+        if (user?.id === verified.id) {
+          updateUser({ ...user, email: verified.email, emailVerified: true })
+        }
+        setStatus("done")
+      })
+      .catch((err) => {
+        setError(err instanceof Error ? err.message : "")
+        setStatus("failed")
+      })
+  }, [token, user, updateUser])
+
+  return (
+    <div className="flex min-h-screen items-center justify-center bg-background px-4">
+      <Card className="w-full max-w-sm">
+        <CardHeader>
+          <CardTitle className="text-center text-xl">{t("auth.verifyEmailTitle")}</CardTitle>
+        </CardHeader>
+        <CardContent className="space-y-4 text-center text-sm">
+          {status === "pending" && <p className="text-muted-foreground">{t("auth.verifyEmailPending")}</p>}
+          {status === "done" && <p>{t("auth.verifyEmailDone")}</p>}
+          {status === "failed" && (
+            <p className="text-destructive">{error || t("auth.verifyEmailFailed")}</p>
+          )}
+          <Link
+            to={isAuthenticated ? "/settings" : "/login"}
+            className="block w-full text-muted-foreground hover:underline"
+          >
+            {isAuthenticated ? t("auth.verifyEmailToSettings") : t("auth.backToLogin")}
+          </Link>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
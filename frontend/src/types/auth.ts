@@ -6,15 +6,36 @@ export interface LoginData {
 export interface RegisterData {
   email: string
   password: string
+  invitationToken?: string
+}
+
+export type RegistrationMode = "open" | "invite" | "closed"
+
+// AuthOptions tell the login page which ways to sign in and up it offers.
+export interface AuthOptions {
+  mode: RegistrationMode
+  oidc: boolean
 }
 
 export interface AuthUser {
   id: string
   email: string
+  emailVerified: boolean
+  admin: boolean
+  disabled: boolean
   createdAt: string
 }
 
+// LoginChallenge is returned by login when the account has two-factor
+// authentication enabled; complete it with a code.
+export interface LoginChallenge {
+  twoFactorRequired: true
+  challengeToken: string
+}
+
 export interface AuthResponse {
   token: string
+  refreshToken: string
+  expiresAt: string
   user: AuthUser
 }
@@ -1,8 +1,22 @@
 import { z } from "zod/v4"
 
-export const billingIntervalSchema = z.enum(["monthly", "yearly"])
+export const billingIntervalSchema = z.enum([
+  "weekly",
+  "monthly",
+  "quarterly",
+  "half-yearly",
+  "yearly",
+  "biennial",
+  "one-time",
+])
 export type BillingInterval = z.infer<typeof billingIntervalSchema>
 
+export const durationUnitSchema = z.enum(["days", "weeks", "months"])
+export type DurationUnit = z.infer<typeof durationUnitSchema>
+
+export const durationAnchorSchema = z.enum(["end_of_month", "end_of_quarter", "end_of_year"])
+export type DurationAnchor = z.infer<typeof durationAnchorSchema>
+
 export const contractSchema = z.object({
   id: z.string().uuid(),
   categoryId: z.string().uuid(),
@@ -15,9 +29,15 @@ export const contractSchema = z.object({
   billingInterval: billingIntervalSchema,
   startDate: z.string().date(),
   endDate: z.string().date().optional(),
-  minimumDurationMonths: z.number().int().nonnegative(),
-  extensionDurationMonths: z.number().int().nonnegative(),
-  noticePeriodMonths: z.number().int().nonnegative(),
+  minimumDuration: z.number().int().nonnegative(),
+  minimumDurationUnit: durationUnitSchema.optional(),
+  minimumDurationAnchor: durationAnchorSchema.optional(),
+  extensionDuration: z.number().int().nonnegative(),
+  extensionDurationUnit: durationUnitSchema.optional(),
+  extensionDurationAnchor: durationAnchorSchema.optional(),
+  noticePeriod: z.number().int().nonnegative(),
+  noticePeriodUnit: durationUnitSchema.optional(),
+  noticePeriodAnchor: durationAnchorSchema.optional(),
   customerPortalUrl: z.string().url().optional().or(z.literal("")),
   paperlessUrl: z.string().url().optional().or(z.literal("")),
   comments: z.string().optional(),
@@ -39,9 +59,15 @@ export const contractFormSchema = z.object({
   billingInterval: billingIntervalSchema,
   startDate: z.string().date(),
   endDate: z.string().date().optional(),
-  minimumDurationMonths: z.number().int().nonnegative(),
-  extensionDurationMonths: z.number().int().nonnegative(),
-  noticePeriodMonths: z.number().int().nonnegative(),
+  minimumDuration: z.number().int().nonnegative(),
+  minimumDurationUnit: durationUnitSchema.optional(),
+  minimumDurationAnchor: durationAnchorSchema.optional(),
+  extensionDuration: z.number().int().nonnegative(),
+  extensionDurationUnit: durationUnitSchema.optional(),
+  extensionDurationAnchor: durationAnchorSchema.optional(),
+  noticePeriod: z.number().int().nonnegative(),
+  noticePeriodUnit: durationUnitSchema.optional(),
+  noticePeriodAnchor: durationAnchorSchema.optional(),
   customerPortalUrl: z.string().url().optional().or(z.literal("")),
   paperlessUrl: z.string().url().optional().or(z.literal("")),
   comments: z.string().optional(),
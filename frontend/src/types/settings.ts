@@ -2,3 +2,34 @@ export type Settings = {
   renewalDays: number
   reminderFrequency: string
 }
+
+export type TwoFactorStatus = {
+  enabled: boolean
+  pending: boolean
+  recoveryCodesRemaining: number
+}
+
+export type TwoFactorEnrolment = {
+  secret: string
+  uri: string
+}
+
+export type ApiKeyAccess = "read" | "write"
+
+export type ApiKey = {
+  id: string
+  name: string
+  prefix: string
+  access: ApiKeyAccess
+  modules: string[]
+  createdAt: string
+  expiresAt?: string
+  lastUsedAt?: string
+}
+
+export type ApiKeyInput = {
+  name: string
+  access: ApiKeyAccess
+  modules?: string[]
+  expiresAt?: string
+}
@@ -0,0 +1,26 @@
+export type WorkspaceRole = "owner" | "editor" | "viewer"
+
+export type Workspace = {
+  id: string
+  name: string
+  createdAt: string
+  role: WorkspaceRole
+  personal: boolean
+}
+
+export type WorkspaceMember = {
+  userId: string
+  email: string
+  role: WorkspaceRole
+  joinedAt: string
+}
+
+export type Invitation = {
+  id: string
+  workspaceId: string
+  email: string
+  role: WorkspaceRole
+  invitedBy: string
+  createdAt: string
+  expiresAt: string
+}